@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBatchRequests bounds the calls of one POST /rpc/batch
+const MaxBatchRequests = 50
+
+// batchCall is one call of a POST /rpc/batch request
+type batchCall struct {
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+	Target string                 `json:"target"` // Optional target process (defaults to "broker")
+	// Optional call timeout in seconds (0 uses the configured RPC timeout)
+	Timeout int `json:"timeout"`
+}
+
+// batchResult wraps the outcome of one call in the envelope of version,
+// without its api_version: {retcode, message, payload} in v1 and {ok, data,
+// error} in v2. v1 reports a failed call with the HTTP status its error code
+// maps to as retcode, so every failure has a non-zero retcode.
+func batchResult(version APIVersion, result rpcResult) gin.H {
+	if version == APIVersionV2 {
+		if result.errCode != "" {
+			return gin.H{"ok": false, "data": nil, "error": &v2Error{Code: result.errCode, Message: result.message}}
+		}
+		return gin.H{"ok": true, "data": result.payload}
+	}
+	if result.errCode != "" {
+		status, ok := v2StatusCodes[result.errCode]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		return gin.H{"retcode": status, "message": result.message, "payload": nil}
+	}
+	return gin.H{"retcode": 0, "message": "success", "payload": result.payload}
+}
+
+// registerBatchHandler registers POST /rpc/batch, which forwards several
+// calls in one HTTP round trip. Each call is forwarded like a POST /rpc call
+// and its outcome is reported on its own, in the order of the request, so
+// one failing call does not fail the others. With parallel set, the calls
+// run concurrently.
+func registerBatchHandler(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.POST("/rpc/batch", func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+
+		var request struct {
+			Requests []batchCall `json:"requests" binding:"required"`
+			Parallel bool        `json:"parallel"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			common.Warn(LogMsgRequestParsingError, err)
+			httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+			return
+		}
+		if len(request.Requests) == 0 || len(request.Requests) > MaxBatchRequests {
+			httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: a batch holds 1 to %d requests, got %d", MaxBatchRequests, len(request.Requests)))
+			return
+		}
+
+		version := apiVersionOf(c)
+		results := make([]gin.H, len(request.Requests))
+		run := func(i int) {
+			call := request.Requests[i]
+			if call.Method == "" {
+				results[i] = batchResult(version, rpcResult{errCode: ErrCodeInvalidRequest, message: "Invalid request: method is required"})
+				return
+			}
+			timeout, err := callTimeout(call.Timeout)
+			if err != nil {
+				results[i] = batchResult(version, rpcResult{errCode: ErrCodeInvalidRequest, message: fmt.Sprintf("Invalid request: %v", err)})
+				return
+			}
+			target := call.Target
+			if target == "" {
+				target = "broker"
+			}
+			common.Info(LogMsgRPCInvokeStarted, target, call.Method)
+			results[i] = batchResult(version, forwardRPC(c, rpcClient, target, call.Method, call.Params, timeout))
+		}
+
+		common.Info(LogMsgRPCBatchStarted, len(request.Requests), request.Parallel)
+		if request.Parallel {
+			var wg sync.WaitGroup
+			for i := range request.Requests {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					run(i)
+				}(i)
+			}
+			wg.Wait()
+		} else {
+			for i := range request.Requests {
+				run(i)
+			}
+		}
+
+		httpSuccessResponse(c, gin.H{"results": results})
+		common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, http.StatusOK)
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestRPCBatch_PreservesOrder(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestRPCBatch_PreservesOrder", nil, func(t *testing.T, tx *gorm.DB) {
+		local := subprocess.New("local")
+		local.RegisterHandler("RPCCountCVEs", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{"count": 150})
+		})
+		meta := subprocess.New("meta")
+		meta.RegisterHandler("RPCGetSessionStatus", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			// Answer late so that parallel calls finish out of order
+			time.Sleep(20 * time.Millisecond)
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{"has_session": false})
+		})
+		meta.RegisterHandler("RPCStopSession", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewErrorResponse(msg, "no active session"), nil
+		})
+
+		sp := subprocess.New("access")
+		rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(io.Discard, "", common.InfoLevel), 200*time.Millisecond)
+		sp.SetOutput(&routingWriter{client: rpcClient, backends: map[string]*subprocess.Subprocess{"local": local, "meta": meta}})
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		registerHandlers(r.Group("/restful"), rpcClient)
+		post := func(path, body string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+			return w
+		}
+
+		calls := `[
+			{"method": "RPCGetSessionStatus", "target": "meta"},
+			{"method": "RPCCountCVEs", "target": "local"},
+			{"method": "RPCStopSession", "target": "meta"},
+			{"target": "local"}
+		]`
+		for _, parallel := range []bool{false, true} {
+			w := post("/restful/rpc/batch", fmt.Sprintf(`{"requests": %s, "parallel": %v}`, calls, parallel))
+			var resp struct {
+				Retcode int `json:"retcode"`
+				Payload struct {
+					Results []struct {
+						Retcode int                    `json:"retcode"`
+						Message string                 `json:"message"`
+						Payload map[string]interface{} `json:"payload"`
+					} `json:"results"`
+				} `json:"payload"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			results := resp.Payload.Results
+			if w.Code != http.StatusOK || resp.Retcode != 0 || len(results) != 4 {
+				t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+			}
+			if results[0].Retcode != 0 || results[0].Payload["has_session"] != false {
+				t.Errorf("parallel=%v: unexpected session status result %+v", parallel, results[0])
+			}
+			if results[1].Retcode != 0 || results[1].Payload["count"] != float64(150) {
+				t.Errorf("parallel=%v: unexpected count result %+v", parallel, results[1])
+			}
+			if results[2].Retcode != http.StatusUnprocessableEntity || results[2].Message != "no active session" {
+				t.Errorf("parallel=%v: expected the backend error, got %+v", parallel, results[2])
+			}
+			if results[3].Retcode != http.StatusBadRequest {
+				t.Errorf("parallel=%v: expected a missing method rejected, got %+v", parallel, results[3])
+			}
+		}
+
+		// v2 results carry ok, data and error
+		w := post("/restful/v2/rpc/batch", `{"requests": [{"method": "RPCCountCVEs", "target": "local"}, {"method": "RPCStopSession", "target": "meta"}]}`)
+		var v2 struct {
+			OK   bool `json:"ok"`
+			Data struct {
+				Results []v2Envelope `json:"results"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &v2); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		if !v2.OK || len(v2.Data.Results) != 2 || !v2.Data.Results[0].OK || v2.Data.Results[1].OK || v2.Data.Results[1].Error.Code != ErrCodeBackendError {
+			t.Errorf("Unexpected v2 response %s", w.Body.String())
+		}
+
+		if w := post("/restful/rpc/batch", `{"requests": []}`); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected an empty batch rejected, got %d", w.Code)
+		}
+		tooMany := bytes.NewBufferString(`{"requests": [`)
+		for i := 0; i <= MaxBatchRequests; i++ {
+			if i > 0 {
+				tooMany.WriteString(",")
+			}
+			tooMany.WriteString(`{"method": "RPCCountCVEs", "target": "local"}`)
+		}
+		tooMany.WriteString(`]}`)
+		if w := post("/restful/rpc/batch", tooMany.String()); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected a batch over %d requests rejected, got %d", MaxBatchRequests, w.Code)
+		}
+	})
+}
@@ -1,9 +1,16 @@
 package main
 
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
 // These variables are injected at build time via ldflags
 var (
 	buildServerAddr = "0.0.0.0:8080" // Default server address, can be overridden with -ldflags "-X main.buildServerAddr=0.0.0.0:9090"
 	buildStaticDir  = "website"      // Default static directory, can be overridden with -ldflags "-X main.buildStaticDir=dist"
+	buildAdminToken = ""             // Bearer token for admin endpoints such as /logs; empty disables them
 )
 
 // DefaultServerAddr returns the default server address based on build configuration
@@ -15,3 +22,48 @@ func DefaultServerAddr() string {
 func DefaultStaticDir() string {
 	return buildStaticDir
 }
+
+// DefaultAdminToken returns the bearer token guarding admin endpoints. The
+// ACCESS_ADMIN_TOKEN environment variable overrides the build-time value.
+func DefaultAdminToken() string {
+	if token := os.Getenv("ACCESS_ADMIN_TOKEN"); token != "" {
+		return token
+	}
+	return buildAdminToken
+}
+
+// Defaults of the broker reconnection, see ReconnectConfig
+const (
+	defaultReconnectInitialBackoff = 100 * time.Millisecond
+	defaultReconnectMaxBackoff     = 30 * time.Second
+	defaultReadyTimeout            = 5 * time.Second
+)
+
+// DefaultReconnectConfig returns the broker reconnection settings.
+// ACCESS_RECONNECT=false disables reconnecting; ACCESS_RECONNECT_INITIAL_BACKOFF,
+// ACCESS_RECONNECT_MAX_BACKOFF and ACCESS_READY_TIMEOUT take Go durations.
+// Invalid values fall back to the defaults.
+func DefaultReconnectConfig() ReconnectConfig {
+	cfg := ReconnectConfig{
+		Enabled:        true,
+		InitialBackoff: envDuration("ACCESS_RECONNECT_INITIAL_BACKOFF", defaultReconnectInitialBackoff),
+		MaxBackoff:     envDuration("ACCESS_RECONNECT_MAX_BACKOFF", defaultReconnectMaxBackoff),
+		ReadyTimeout:   envDuration("ACCESS_READY_TIMEOUT", defaultReadyTimeout),
+	}
+	if enabled, err := strconv.ParseBool(os.Getenv("ACCESS_RECONNECT")); err == nil {
+		cfg.Enabled = enabled
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	return cfg
+}
+
+// envDuration returns the positive duration in the environment variable
+// name, or def when it is unset or invalid
+func envDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
@@ -30,6 +30,11 @@ const (
 	LogMsgRPCPendingRequestRemoved = "[ACCESS] Removed pending RPC request: correlationID=%s"
 	LogMsgRPCResponseReceived      = "[ACCESS] Received RPC response: correlationID=%s, type=%s"
 	LogMsgRPCChannelSignal         = "[ACCESS] Signaling RPC response channel: correlationID=%s"
+	LogMsgBrokerDisconnected       = "[ACCESS] Broker connection lost (%v), failed %d in-flight requests"
+	LogMsgBrokerReconnectFailed    = "[ACCESS] Reconnect to broker failed (attempt %d): %v, retrying in %v"
+	LogMsgBrokerReconnected        = "[ACCESS] Reconnected to broker after %d attempts"
+	LogMsgEventUnsubscribed        = "[ACCESS] Dropped %s event of unknown subscription %s"
+	LogMsgEventDropped             = "[ACCESS] Subscription %s fell behind, dropped its oldest event"
 
 	// Server Operations Log Messages
 	LogMsgServerStarting          = "[ACCESS] Starting HTTP server on address: %s"
@@ -52,10 +57,30 @@ const (
 	LogMsgRPCForwardingParams   = "[ACCESS] RPC forwarding with params: %v"
 	LogMsgRPCForwardingComplete = "[ACCESS] RPC forwarding completed: method=%s, target=%s"
 	LogMsgRPCForwardingError    = "[ACCESS] RPC forwarding error: %v"
+	LogMsgRPCBatchStarted       = "[ACCESS] RPC batch forwarding %d requests, parallel=%v"
 	LogMsgRPCResponseParsing    = "[ACCESS] Parsing RPC response payload"
 	LogMsgRPCResponseParsed     = "[ACCESS] RPC response parsed successfully"
 	LogMsgRPCResponseParseError = "[ACCESS] Error parsing RPC response: %v"
 
+	// Metrics Log Messages
+	LogMsgMetricsServiceError = "[ACCESS] Failed to collect handler stats from %s: %s"
+
+	// Health Log Messages
+	LogMsgHealthCheckUnhealthy = "[ACCESS] Full health check found unhealthy processes among %d"
+
+	// Info Log Messages
+	LogMsgInfoCatalogsError = "[ACCESS] Failed to collect catalog versions: %v"
+
+	// Log Tail Log Messages
+	LogMsgAdminAuthRejected = "[ACCESS] Rejected unauthenticated request to %s"
+	LogMsgLogFollowStarted  = "[ACCESS] Following log of %s"
+	LogMsgLogFollowStopped  = "[ACCESS] Stopped following log of %s: %v"
+
+	// Session Event Stream Log Messages
+	LogMsgSessionStreamStarted     = "[ACCESS] Streaming session events: subscription %s"
+	LogMsgSessionStreamStopped     = "[ACCESS] Stopped streaming session events: subscription %s: %s"
+	LogMsgSessionUnsubscribeFailed = "[ACCESS] Failed to cancel session events subscription %s: %v"
+
 	// Static File Serving Log Messages
 	LogMsgStaticFileServing  = "[ACCESS] Serving static files from directory: %s"
 	LogMsgStaticFileNotFound = "[ACCESS] Static file not found, serving index.html for SPA: %s"
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serveRPC posts body to path on a router backed by rpcClient
+func serveRPC(rpcClient *RPCClient, path, accept, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerHandlers(r.Group("/restful"), rpcClient)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestEnvelope_V1IsDefault(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestEnvelope_V1IsDefault", nil, func(t *testing.T, tx *gorm.DB) {
+		rpcClient, _ := newRPCClientWithResponse(subprocess.MessageTypeResponse, map[string]string{"id": "CVE-1"}, "")
+		w := serveRPC(rpcClient, "/restful/rpc", "", `{"method":"x"}`)
+
+		if w.Header().Get(apiVersionHeader) != string(APIVersionV1) {
+			t.Errorf("Expected %s header v1, got %q", apiVersionHeader, w.Header().Get(apiVersionHeader))
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		if resp["retcode"].(float64) != 0 || resp["payload"] == nil {
+			t.Errorf("Expected legacy envelope, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestEnvelope_V2SuccessByPathAndAccept(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestEnvelope_V2SuccessByPathAndAccept", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, tc := range []struct{ path, accept string }{
+			{"/restful/v2/rpc", ""},
+			{"/restful/rpc", mediaTypeV2},
+		} {
+			rpcClient, _ := newRPCClientWithResponse(subprocess.MessageTypeResponse, map[string]string{"id": "CVE-1"}, "")
+			w := serveRPC(rpcClient, tc.path, tc.accept, `{"method":"x"}`)
+
+			var resp v2Envelope
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			if w.Code != http.StatusOK || !resp.OK || resp.APIVersion != APIVersionV2 || resp.Error != nil {
+				t.Errorf("%s (Accept %q): unexpected v2 response %d %s", tc.path, tc.accept, w.Code, w.Body.String())
+			}
+			if data, ok := resp.Data.(map[string]interface{}); !ok || data["id"] != "CVE-1" {
+				t.Errorf("%s: data = %v, want backend payload", tc.path, resp.Data)
+			}
+		}
+	})
+}
+
+func TestEnvelope_V2Errors(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestEnvelope_V2Errors", nil, func(t *testing.T, tx *gorm.DB) {
+		rpcClient, _ := newRPCClientWithResponse(subprocess.MessageTypeError, nil, "boom")
+
+		w := serveRPC(rpcClient, "/restful/v2/rpc", "", `{"method":"x"}`)
+		var resp v2Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		if w.Code != http.StatusUnprocessableEntity || resp.OK || resp.Error == nil ||
+			resp.Error.Code != ErrCodeBackendError || resp.Error.Message != "boom" {
+			t.Errorf("Unexpected backend error response %d %s", w.Code, w.Body.String())
+		}
+
+		w = serveRPC(rpcClient, "/restful/v2/rpc", "", `{}`)
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		if w.Code != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != ErrCodeInvalidRequest {
+			t.Errorf("Unexpected invalid request response %d %s", w.Code, w.Body.String())
+		}
+	})
+}
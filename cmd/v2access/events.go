@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sessionStatusEvent and sessionEndEvent are the IDs of the event
+	// messages meta pushes to session event subscribers
+	sessionStatusEvent = "session_status"
+	sessionEndEvent    = "session_end"
+)
+
+// sessionEventsKeepAlive is how often an idle session event stream sends a
+// comment, so that proxies do not time it out
+const sessionEventsKeepAlive = 15 * time.Second
+
+// sessionSubscriptionSeq numbers the session event subscriptions of this
+// process
+var sessionSubscriptionSeq atomic.Uint64
+
+// sessionEventPayload is the payload of meta's session events
+type sessionEventPayload struct {
+	Seq    int             `json:"seq"`
+	Status json.RawMessage `json:"status"`
+}
+
+// registerSessionEventsHandler registers GET /events/session, a server-sent
+// event stream of the status of a run: the one named by ?session_id=, or else
+// the active CVE run. It sends a "status" event with the current status, then
+// another whenever the state or counts of the run change, and an "end" event
+// with the final status when the run terminates, after which it closes. If
+// there is no such run, or it already terminated, only the "end" event is
+// sent.
+func registerSessionEventsHandler(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.GET("/events/session", func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+
+		// Subscribe locally first, so that no event is missed
+		id := fmt.Sprintf("sse-%d-%d", time.Now().UnixNano(), sessionSubscriptionSeq.Add(1))
+		events := rpcClient.Subscribe(id)
+
+		rpcCtx, cancel := context.WithTimeout(rpcContext(c), rpcClient.rpcTimeout)
+		response, err := rpcClient.InvokeRPCWithTarget(rpcCtx, "meta", "RPCSubscribeSessionEvents", map[string]interface{}{
+			"subscription_id": id,
+			"session_id":      c.Query("session_id"),
+		})
+		cancel()
+		if err != nil {
+			rpcClient.Unsubscribe(id)
+			httpErrorResponse(c, http.StatusBadGateway, ErrCodeRPCFailed, fmt.Sprintf("failed to subscribe to session events: %v", err))
+			return
+		}
+		if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+			rpcClient.Unsubscribe(id)
+			httpErrorResponse(c, http.StatusServiceUnavailable, ErrCodeBackendError, errMsg)
+			return
+		}
+		var subscription struct {
+			Subscribed bool            `json:"subscribed"`
+			Status     json.RawMessage `json:"status"`
+		}
+		if err := subprocess.UnmarshalPayload(response, &subscription); err != nil {
+			rpcClient.Unsubscribe(id)
+			httpErrorResponse(c, http.StatusBadGateway, ErrCodeBadResponse, fmt.Sprintf("failed to parse response: %v", err))
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+
+		if !subscription.Subscribed {
+			rpcClient.Unsubscribe(id)
+			c.SSEvent("end", subscription.Status)
+			c.Writer.Flush()
+			return
+		}
+		c.SSEvent("status", subscription.Status)
+		c.Writer.Flush()
+
+		common.Info(LogMsgSessionStreamStarted, id)
+		ended, reason := streamSessionEvents(c, events)
+		common.Info(LogMsgSessionStreamStopped, id, reason)
+
+		rpcClient.Unsubscribe(id)
+		if !ended {
+			// Meta drops the subscription by itself only when the run ends
+			unsubscribeSessionEvents(rpcContext(c), rpcClient, id)
+		}
+	})
+}
+
+// streamSessionEvents relays the events of a subscription until the run
+// ends, the client disconnects or the subscription is closed, returning
+// whether the run ended and why it stopped
+func streamSessionEvents(c *gin.Context, events <-chan *subprocess.Message) (ended bool, reason string) {
+	ctx := c.Request.Context()
+	keepAlive := time.NewTicker(sessionEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	lastSeq := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return false, "client disconnected"
+		case <-keepAlive.C:
+			io.WriteString(c.Writer, ": keepalive\n\n")
+			c.Writer.Flush()
+		case msg, ok := <-events:
+			if !ok {
+				c.SSEvent("error", "broker connection lost")
+				c.Writer.Flush()
+				return false, "broker connection lost"
+			}
+			var event sessionEventPayload
+			if err := subprocess.UnmarshalPayload(msg, &event); err != nil {
+				common.Warn(LogMsgRPCResponseParseError, err)
+				continue
+			}
+			switch msg.ID {
+			case sessionEndEvent:
+				c.SSEvent("end", event.Status)
+				c.Writer.Flush()
+				return true, "session ended"
+			case sessionStatusEvent:
+				// Events are handled concurrently and may arrive out of order
+				if event.Seq <= lastSeq {
+					continue
+				}
+				lastSeq = event.Seq
+				c.SSEvent("status", event.Status)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// unsubscribeSessionEvents cancels a subscription in meta. The stream is
+// already gone, so ctx must not be cancelled with the HTTP request.
+func unsubscribeSessionEvents(ctx context.Context, rpcClient *RPCClient, id string) {
+	ctx, cancel := context.WithTimeout(ctx, rpcClient.rpcTimeout)
+	defer cancel()
+	response, err := rpcClient.InvokeRPCWithTarget(ctx, "meta", "RPCUnsubscribeSessionEvents", map[string]interface{}{
+		"subscription_id": id,
+	})
+	if err == nil {
+		if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+			err = fmt.Errorf("%s", errMsg)
+		}
+	}
+	if err != nil {
+		common.Warn(LogMsgSessionUnsubscribeFailed, id, err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newSessionEventsRouter serves /restful/v2/events/session backed by a meta
+// service that answers subscriptions with subscribed and reports the
+// subscribe and unsubscribe calls
+func newSessionEventsRouter(subscribed bool) (*gin.Engine, *RPCClient, chan string, chan string) {
+	subscribes, unsubscribes := make(chan string, 4), make(chan string, 4)
+	subscriptionID := func(msg *subprocess.Message) string {
+		var req struct {
+			SubscriptionID string `json:"subscription_id"`
+		}
+		subprocess.UnmarshalPayload(msg, &req)
+		return req.SubscriptionID
+	}
+	meta := subprocess.New("meta")
+	meta.RegisterHandler("RPCSubscribeSessionEvents", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		subscribes <- subscriptionID(msg)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"subscribed": subscribed,
+			"status":     map[string]interface{}{"session_id": "run-1", "fetched_count": 0},
+		})
+	})
+	meta.RegisterHandler("RPCUnsubscribeSessionEvents", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		unsubscribes <- subscriptionID(msg)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{"unsubscribed": true})
+	})
+
+	sp := subprocess.New("access")
+	rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel), 200*time.Millisecond)
+	sp.SetOutput(&routingWriter{client: rpcClient, backends: map[string]*subprocess.Subprocess{"meta": meta}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerSessionEventsHandler(r.Group("/restful/v2", withAPIVersion(APIVersionV2)), rpcClient)
+	return r, rpcClient, subscribes, unsubscribes
+}
+
+// pushSessionEvent delivers an event of meta to the access RPC client
+func pushSessionEvent(rpcClient *RPCClient, id, eventID string, seq, fetched int) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"seq":    seq,
+		"status": map[string]interface{}{"session_id": "run-1", "fetched_count": fetched},
+	})
+	rpcClient.handleEvent(context.Background(), &subprocess.Message{
+		Type:          subprocess.MessageTypeEvent,
+		ID:            eventID,
+		CorrelationID: id,
+		Payload:       payload,
+	})
+}
+
+// readSSE returns the "event:data" lines of a stream until it closes
+func readSSE(body *bufio.Scanner, n int) []string {
+	var got []string
+	event := ""
+	for len(got) < n && body.Scan() {
+		line := body.Text()
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+			got = append(got, event+" "+data)
+		}
+	}
+	return got
+}
+
+func TestSessionEvents_StreamsUntilTheRunEnds(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSessionEvents_StreamsUntilTheRunEnds", nil, func(t *testing.T, tx *gorm.DB) {
+		r, rpcClient, subscribes, unsubscribes := newSessionEventsRouter(true)
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/restful/v2/events/session?session_id=run-1")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			t.Fatalf("Expected an event stream, got %q", ct)
+		}
+		id := <-subscribes
+
+		pushSessionEvent(rpcClient, id, sessionStatusEvent, 2, 20)
+		// Delivered late; dropped
+		pushSessionEvent(rpcClient, id, sessionStatusEvent, 1, 10)
+		pushSessionEvent(rpcClient, "someone-else", sessionStatusEvent, 3, 99)
+		pushSessionEvent(rpcClient, id, sessionEndEvent, 3, 30)
+
+		body := bufio.NewScanner(resp.Body)
+		got := readSSE(body, 10)
+		want := []string{
+			`status {"fetched_count":0,"session_id":"run-1"}`,
+			`status {"fetched_count":20,"session_id":"run-1"}`,
+			`end {"fetched_count":30,"session_id":"run-1"}`,
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected events %q, got %q", want, got)
+		}
+
+		// Meta drops the subscription of an ended run by itself
+		select {
+		case id := <-unsubscribes:
+			t.Errorf("Expected no unsubscribe after the run ended, got %s", id)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func TestSessionEvents_ClientDisconnectUnsubscribes(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSessionEvents_ClientDisconnectUnsubscribes", nil, func(t *testing.T, tx *gorm.DB) {
+		r, _, subscribes, unsubscribes := newSessionEventsRouter(true)
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/restful/v2/events/session", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if got := readSSE(bufio.NewScanner(resp.Body), 1); len(got) != 1 {
+			t.Fatalf("Expected the initial status, got %q", got)
+		}
+		id := <-subscribes
+		cancel()
+		resp.Body.Close()
+
+		select {
+		case got := <-unsubscribes:
+			if got != id {
+				t.Errorf("Expected %s to be unsubscribed, got %s", id, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the subscription to be cancelled in meta")
+		}
+	})
+}
+
+func TestSessionEvents_NoRunEndsImmediately(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSessionEvents_NoRunEndsImmediately", nil, func(t *testing.T, tx *gorm.DB) {
+		r, rpcClient, _, _ := newSessionEventsRouter(false)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/restful/v2/events/session", nil))
+
+		got := readSSE(bufio.NewScanner(w.Body), 10)
+		if len(got) != 1 || !strings.HasPrefix(got[0], "end ") {
+			t.Errorf("Expected only the end event, got %q", got)
+		}
+		rpcClient.subsMu.Lock()
+		defer rpcClient.subsMu.Unlock()
+		if len(rpcClient.subs) != 0 {
+			t.Errorf("Expected no subscriptions left, got %d", len(rpcClient.subs))
+		}
+	})
+}
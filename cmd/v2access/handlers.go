@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 	"github.com/gin-gonic/gin"
 )
 
-// HTTP response helpers for reducing boilerplate in handlers
+// HTTP response helpers for reducing boilerplate in handlers. Both serialize
+// in the envelope version negotiated for the request (see envelope.go).
 
-// httpErrorResponse sends an error response with given code and message.
-func httpErrorResponse(c *gin.Context, code int, message string) {
+// httpErrorResponse sends an error response. v1 clients get code as both HTTP
+// status and retcode; v2 clients get the HTTP status mapped from errCode.
+func httpErrorResponse(c *gin.Context, code int, errCode, message string) {
+	if apiVersionOf(c) == APIVersionV2 {
+		status, ok := v2StatusCodes[errCode]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, v2Envelope{
+			APIVersion: APIVersionV2,
+			Error:      &v2Error{Code: errCode, Message: message},
+		})
+		return
+	}
 	c.JSON(code, gin.H{
 		"retcode": code,
 		"message": message,
@@ -23,6 +39,14 @@ func httpErrorResponse(c *gin.Context, code int, message string) {
 
 // httpSuccessResponse sends a success response with given payload.
 func httpSuccessResponse(c *gin.Context, payload interface{}) {
+	if apiVersionOf(c) == APIVersionV2 {
+		c.JSON(http.StatusOK, v2Envelope{
+			APIVersion: APIVersionV2,
+			OK:         true,
+			Data:       payload,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"retcode": 0,
 		"message": "success",
@@ -30,16 +54,49 @@ func httpSuccessResponse(c *gin.Context, payload interface{}) {
 	})
 }
 
-// registerHandlers registers REST endpoints on provided router group
+// registerHandlers registers REST endpoints on provided router group. The
+// unversioned routes serve v1 unless the Accept header asks for v2; the
+// same routes under /v2 always serve v2.
 func registerHandlers(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.Use(withTraceID())
+	registerVersionedHandlers(restful.Group("", withAPIVersion("")), rpcClient)
+	registerVersionedHandlers(restful.Group("/v2", withAPIVersion(APIVersionV2)), rpcClient)
+}
+
+// registerVersionedHandlers registers the endpoints shared by every API version
+func registerVersionedHandlers(restful *gin.RouterGroup, rpcClient *RPCClient) {
 	// Health check endpoint
 	restful.GET("/health", func(c *gin.Context) {
 		common.Debug(LogMsgHealthCheckReceived)
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
-		})
+		status := gin.H{"status": "ok"}
+		if rpcClient != nil {
+			status["broker_connected"] = rpcClient.Ready()
+		}
+		if apiVersionOf(c) == APIVersionV2 {
+			httpSuccessResponse(c, status)
+			return
+		}
+		c.JSON(http.StatusOK, status)
 	})
 
+	// Liveness of every process managed by the broker
+	registerFullHealthHandler(restful, rpcClient)
+
+	// Per-handler call counters aggregated across services
+	registerMetricsHandler(restful, rpcClient)
+
+	// Build version and loaded catalog versions
+	registerInfoHandler(restful, rpcClient)
+
+	// Log tail of the backend services, admin only
+	registerLogsHandler(restful, rpcClient, DefaultAdminToken())
+
+	// Job progress pushed as server-sent events
+	registerSessionEventsHandler(restful, rpcClient)
+
+	// Several forwarded RPC calls in one round trip
+	registerBatchHandler(restful, rpcClient)
+
 	// Generic RPC forwarding endpoint
 	restful.POST("/rpc", func(c *gin.Context) {
 		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
@@ -49,11 +106,19 @@ func registerHandlers(restful *gin.RouterGroup, rpcClient *RPCClient) {
 			Method string                 `json:"method" binding:"required"`
 			Params map[string]interface{} `json:"params"`
 			Target string                 `json:"target"` // Optional target process (defaults to "broker")
+			// Optional call timeout in seconds (0 uses the configured RPC timeout)
+			Timeout int `json:"timeout"`
 		}
 
 		if err := c.ShouldBindJSON(&request); err != nil {
 			common.Warn(LogMsgRequestParsingError, err)
-			httpErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+			httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
+			common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, http.StatusBadRequest)
+			return
+		}
+		timeout, err := callTimeout(request.Timeout)
+		if err != nil {
+			httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid request: %v", err))
 			common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, http.StatusBadRequest)
 			return
 		}
@@ -79,64 +144,98 @@ func registerHandlers(restful *gin.RouterGroup, rpcClient *RPCClient) {
 		case <-requestCtx.Done():
 			err := requestCtx.Err()
 			common.Error("HTTP request context already canceled before RPC call: %v", err)
-			httpErrorResponse(c, http.StatusOK, fmt.Sprintf("Request context canceled: %v", err))
+			httpErrorResponse(c, http.StatusOK, ErrCodeCanceled, fmt.Sprintf("Request context canceled: %v", err))
 			return
 		default:
 			// Context is not done, proceed with RPC
 		}
 
-		// Create a separate context for the RPC call to avoid cancellation from HTTP context
-		// This prevents the RPC call from being canceled when the HTTP client disconnects
-		rpcCtx, cancel := context.WithTimeout(context.Background(), rpcClient.rpcTimeout)
-		defer cancel()
-
-		response, err := rpcClient.InvokeRPCWithTarget(rpcCtx, target, request.Method, request.Params)
-		common.Debug(LogMsgRPCInvokeCompleted, target, request.Method)
-
-		// Log context state after RPC call completes
-		select {
-		case <-requestCtx.Done():
-			err := requestCtx.Err()
-			common.Warn("HTTP request context canceled after RPC call: %v", err)
-		default:
-			// Context is still active
-		}
-
-		if err != nil {
-			common.Error(LogMsgRPCForwardingError, err)
-			httpErrorResponse(c, http.StatusOK, fmt.Sprintf("RPC error: %v", err))
+		result := forwardRPC(c, rpcClient, target, request.Method, request.Params, timeout)
+		if result.errCode != "" {
+			httpErrorResponse(c, http.StatusOK, result.errCode, result.message)
 			common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
 			return
 		}
 
-		// Check response type using subprocess helper
-		if isError, errMsg := subprocess.IsErrorResponse(response); isError {
-			common.Warn("RPC response is an error: %s", errMsg)
-			httpErrorResponse(c, http.StatusOK, errMsg)
-			common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
-			return
-		}
-
-		// Parse payload
-		var payload interface{}
-		if response.Payload != nil {
-			common.Debug(LogMsgRPCResponseParsing)
-			if err := subprocess.UnmarshalFast(response.Payload, &payload); err != nil {
-				common.Error(LogMsgRPCResponseParseError, err)
-				httpErrorResponse(c, http.StatusOK, fmt.Sprintf("Failed to parse response: %v", err))
-				common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
-				return
-			}
-			common.Debug(LogMsgRPCResponseParsed)
-		}
-
 		// Return success response
-		httpSuccessResponse(c, payload)
+		httpSuccessResponse(c, result.payload)
 		common.Info(LogMsgRPCForwardingComplete, request.Method, target)
 		common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, http.StatusOK)
 	})
 }
 
+// rpcResult is the outcome of a forwarded RPC: the decoded payload, or the
+// error code and message to report
+type rpcResult struct {
+	payload interface{}
+	errCode string
+	message string
+}
+
+// callTimeout converts the timeout field of a forwarded call, in seconds, to
+// the call's timeout; zero means the configured RPC timeout
+func callTimeout(seconds int) (time.Duration, error) {
+	maxSeconds := int(rpc.MaxRPCTimeout / time.Second)
+	if seconds < 0 || seconds > maxSeconds {
+		return 0, fmt.Errorf("timeout must be 0 to %d seconds, got %d", maxSeconds, seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// forwardRPC invokes method on target for the /rpc endpoints and classifies
+// the outcome. The call gets its own timeout, the configured RPC timeout
+// unless timeout is set, and is not tied to the HTTP request context, so it
+// is not canceled when the client disconnects.
+func forwardRPC(c *gin.Context, rpcClient *RPCClient, target, method string, params map[string]interface{}, timeout time.Duration) rpcResult {
+	requestCtx := c.Request.Context()
+	ctxTimeout := timeout
+	if ctxTimeout == 0 {
+		ctxTimeout = rpcClient.rpcTimeout
+	}
+	rpcCtx, cancel := context.WithTimeout(rpcContext(c), ctxTimeout)
+	defer cancel()
+
+	response, err := rpcClient.InvokeRPCWithTimeout(rpcCtx, target, method, params, timeout)
+	common.Debug(LogMsgRPCInvokeCompleted, target, method)
+
+	// Log context state after RPC call completes
+	select {
+	case <-requestCtx.Done():
+		err := requestCtx.Err()
+		common.Warn("HTTP request context canceled after RPC call: %v", err)
+	default:
+		// Context is still active
+	}
+
+	if err != nil {
+		common.Error(LogMsgRPCForwardingError, err)
+		code := ErrCodeRPCFailed
+		if errors.Is(err, rpc.ErrConnectionReset) || errors.Is(err, ErrBrokerNotReady) {
+			code = ErrCodeConnectionReset
+		}
+		return rpcResult{errCode: code, message: fmt.Sprintf("RPC error: %v", err)}
+	}
+
+	// Check response type using subprocess helper
+	if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+		common.Warn("RPC response is an error: %s", errMsg)
+		return rpcResult{errCode: ErrCodeBackendError, message: errMsg}
+	}
+
+	// Parse payload, keeping numbers exact: counts above 2^53 would be
+	// rounded if decoded into float64
+	var payload interface{}
+	if response.Payload != nil {
+		common.Debug(LogMsgRPCResponseParsing)
+		if err := subprocess.UnmarshalUseNumber(response.Payload, &payload); err != nil {
+			common.Error(LogMsgRPCResponseParseError, err)
+			return rpcResult{errCode: ErrCodeBadResponse, message: fmt.Sprintf("Failed to parse response: %v", err)}
+		}
+		common.Debug(LogMsgRPCResponseParsed)
+	}
+	return rpcResult{payload: payload}
+}
+
 // MockRPCClient is a mock implementation of RPCClient for testing
 // Add methods as needed to simulate behavior
 type MockRPCClient struct{}
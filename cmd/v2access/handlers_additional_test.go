@@ -28,6 +28,7 @@ type responseWriter struct {
 	errMessage  string
 	buf         bytes.Buffer
 	lastRequest *subprocess.Message
+	delay       time.Duration // reply this late, asynchronously
 }
 
 func (w *responseWriter) Write(p []byte) (int, error) {
@@ -63,6 +64,10 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 				}
 			}
 		}
+		if w.delay > 0 {
+			time.AfterFunc(w.delay, func() { w.client.handleResponse(context.Background(), resp) })
+			continue
+		}
 		w.client.handleResponse(context.Background(), resp)
 	}
 	return len(p), nil
@@ -222,3 +227,48 @@ func TestRPCHandler_DefaultTargetBroker(t *testing.T) {
 	})
 
 }
+
+// Test integers above 2^53 are forwarded without float64 rounding
+func TestRPCHandler_LargeIntegersExact(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestRPCHandler_LargeIntegersExact", nil, func(t *testing.T, tx *gorm.DB) {
+		rpcClient, _ := newRPCClientWithResponse(subprocess.MessageTypeResponse, []byte(`{"total":{"total_sent":9007199254740993},"ratio":0.5}`), "")
+		w := serveRPC(rpcClient, "/restful/rpc", "", `{"method":"RPCGetMessageStats"}`)
+
+		if !strings.Contains(w.Body.String(), `"total_sent":9007199254740993`) {
+			t.Errorf("expected exact large count in response, got %s", w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"ratio":0.5`) {
+			t.Errorf("expected fractional value preserved, got %s", w.Body.String())
+		}
+	})
+
+}
+
+// Test the timeout field overrides the configured RPC timeout of a call
+func TestRPCHandler_CallTimeout(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestRPCHandler_CallTimeout", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := subprocess.New("test-client")
+		rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel), 100*time.Millisecond)
+		sp.SetOutput(&responseWriter{client: rpcClient, respType: subprocess.MessageTypeResponse, payload: map[string]bool{"ok": true}, delay: 300 * time.Millisecond})
+
+		var resp map[string]interface{}
+		w := serveRPC(rpcClient, "/restful/rpc", "", `{"method":"RPCFetchCVEs","target":"remote"}`)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp["retcode"] == float64(0) {
+			t.Fatalf("expected the call to time out with the configured timeout, got %s", w.Body.String())
+		}
+
+		w = serveRPC(rpcClient, "/restful/rpc", "", `{"method":"RPCFetchCVEs","target":"remote","timeout":2}`)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp["retcode"] != float64(0) {
+			t.Fatalf("expected the call to succeed within its own timeout, got %s", w.Body.String())
+		}
+
+		for _, body := range []string{`{"method":"x","timeout":-1}`, `{"method":"x","timeout":601}`} {
+			if w := serveRPC(rpcClient, "/restful/rpc", "", body); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %s, got %d: %s", body, w.Code, w.Body.String())
+			}
+		}
+	})
+
+}
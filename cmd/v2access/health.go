@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/gin-gonic/gin"
+)
+
+// registerFullHealthHandler registers GET /health/full, which reports the
+// liveness and ping latency of every process managed by the broker (see the
+// broker's RPCHealthCheck). The optional timeout_ms query parameter bounds
+// how long each process may take to answer.
+func registerFullHealthHandler(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.GET("/health/full", func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+
+		var params map[string]interface{}
+		if raw := c.Query("timeout_ms"); raw != "" {
+			timeoutMs, err := strconv.Atoi(raw)
+			if err != nil || timeoutMs < 0 {
+				httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("invalid timeout_ms: %q", raw))
+				return
+			}
+			params = map[string]interface{}{"timeout_ms": timeoutMs}
+		}
+
+		rpcCtx, cancel := context.WithTimeout(rpcContext(c), rpcClient.rpcTimeout)
+		defer cancel()
+
+		response, err := rpcClient.InvokeRPCWithTarget(rpcCtx, "broker", "RPCHealthCheck", params)
+		if err != nil {
+			httpErrorResponse(c, http.StatusBadGateway, ErrCodeRPCFailed, fmt.Sprintf("RPC error: %v", err))
+			return
+		}
+		if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+			httpErrorResponse(c, http.StatusServiceUnavailable, ErrCodeBackendError, errMsg)
+			return
+		}
+		var report proc.HealthCheckResponse
+		if err := subprocess.UnmarshalPayload(response, &report); err != nil {
+			httpErrorResponse(c, http.StatusBadGateway, ErrCodeBadResponse, fmt.Sprintf("failed to parse response: %v", err))
+			return
+		}
+		if !report.Healthy {
+			common.Warn(LogMsgHealthCheckUnhealthy, len(report.Processes))
+		}
+
+		httpSuccessResponse(c, report)
+		common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestFullHealth_ReportsProcesses(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestFullHealth_ReportsProcesses", nil, func(t *testing.T, tx *gorm.DB) {
+		var timeoutMs interface{}
+		broker := subprocess.New("broker")
+		broker.RegisterHandler("RPCHealthCheck", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			var params map[string]interface{}
+			subprocess.UnmarshalPayload(msg, &params)
+			timeoutMs = params["timeout_ms"]
+			return subprocess.NewSuccessResponse(msg, proc.HealthCheckResponse{
+				Healthy: false,
+				Processes: map[string]proc.ProcessHealth{
+					"local":  {Alive: true, LatencyMs: 0.4},
+					"remote": {Alive: false, LatencyMs: 50, LastError: "timeout waiting for response from remote"},
+				},
+			})
+		})
+
+		get := func(path string, backends map[string]*subprocess.Subprocess) *httptest.ResponseRecorder {
+			sp := subprocess.New("access")
+			rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(io.Discard, "", common.InfoLevel), 200*time.Millisecond)
+			sp.SetOutput(&routingWriter{client: rpcClient, backends: backends})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			registerHandlers(r.Group("/restful"), rpcClient)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w
+		}
+
+		w := get("/restful/v2/health/full?timeout_ms=50", map[string]*subprocess.Subprocess{"broker": broker})
+		var resp struct {
+			OK   bool                     `json:"ok"`
+			Data proc.HealthCheckResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		if w.Code != http.StatusOK || !resp.OK {
+			t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		if resp.Data.Healthy || len(resp.Data.Processes) != 2 || !resp.Data.Processes["local"].Alive || resp.Data.Processes["remote"].LastError == "" {
+			t.Errorf("Unexpected report %+v", resp.Data)
+		}
+		if timeoutMs != float64(50) {
+			t.Errorf("Expected timeout_ms forwarded to the broker, got %v", timeoutMs)
+		}
+
+		if w := get("/restful/v2/health/full?timeout_ms=soon", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid timeout, got %d", w.Code)
+		}
+		// Without an answer from the broker the endpoint fails instead of
+		// reporting every process healthy
+		if w := get("/restful/v2/health/full", nil); w.Code == http.StatusOK {
+			t.Errorf("Expected an error without the broker, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/gin-gonic/gin"
+)
+
+// infoReport is the payload of GET /info
+type infoReport struct {
+	Version  string            `json:"version"`
+	Catalogs []catalog.Version `json:"catalogs"`
+	// Error reports why the catalogs could not be collected
+	Error string `json:"error,omitempty"`
+}
+
+// fetchCatalogVersions invokes RPCGetCatalogVersions on the local service
+func fetchCatalogVersions(ctx context.Context, rpcClient *RPCClient) ([]catalog.Version, error) {
+	response, err := rpcClient.InvokeRPCWithTarget(ctx, "local", "RPCGetCatalogVersions", nil)
+	if err != nil {
+		return nil, fmt.Errorf("RPC error: %v", err)
+	}
+	if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+		return nil, fmt.Errorf("%s", errMsg)
+	}
+	var result struct {
+		Catalogs []catalog.Version `json:"catalogs"`
+	}
+	if err := subprocess.UnmarshalPayload(response, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return result.Catalogs, nil
+}
+
+// registerInfoHandler registers GET /info, which reports the build version
+// and the loaded CWE, CAPEC and ATT&CK catalog versions
+func registerInfoHandler(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.GET("/info", func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+
+		// As with /rpc, the call is not tied to the HTTP request context
+		rpcCtx, cancel := context.WithTimeout(rpcContext(c), rpcClient.rpcTimeout)
+		defer cancel()
+
+		// The version is still worth reporting when local does not answer
+		report := infoReport{Version: common.Version(), Catalogs: []catalog.Version{}}
+		if catalogs, err := fetchCatalogVersions(rpcCtx, rpcClient); err != nil {
+			common.Warn(LogMsgInfoCatalogsError, err)
+			report.Error = err.Error()
+		} else {
+			report.Catalogs = catalogs
+		}
+
+		httpSuccessResponse(c, report)
+		common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
+	})
+}
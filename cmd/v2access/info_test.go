@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestInfo_ReportsCatalogVersions(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestInfo_ReportsCatalogVersions", nil, func(t *testing.T, tx *gorm.DB) {
+		local := subprocess.New("local")
+		local.RegisterHandler("RPCGetCatalogVersions", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"catalogs": []catalog.Version{
+					{Taxonomy: catalog.TaxonomyCAPEC, Version: "3.9", VersionSource: catalog.VersionSourceCatalog, Source: "assets/capec.xml", ImportedAt: 1700000000, EntryCount: 559},
+				},
+			})
+		})
+
+		get := func(backends map[string]*subprocess.Subprocess) infoReport {
+			sp := subprocess.New("access")
+			rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(io.Discard, "", common.InfoLevel), 200*time.Millisecond)
+			sp.SetOutput(&routingWriter{client: rpcClient, backends: backends})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			registerHandlers(r.Group("/restful"), rpcClient)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/restful/v2/info", nil))
+
+			var resp struct {
+				OK   bool       `json:"ok"`
+				Data infoReport `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			if w.Code != http.StatusOK || !resp.OK {
+				t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+			}
+			return resp.Data
+		}
+
+		report := get(map[string]*subprocess.Subprocess{"local": local})
+		if report.Version == "" || report.Error != "" {
+			t.Errorf("Expected a version and no error, got %+v", report)
+		}
+		if len(report.Catalogs) != 1 || report.Catalogs[0].Version != "3.9" || report.Catalogs[0].EntryCount != 559 {
+			t.Errorf("Unexpected catalogs %+v", report.Catalogs)
+		}
+
+		// Without the local service the version is still reported
+		report = get(nil)
+		if report.Version == "" || report.Error == "" || len(report.Catalogs) != 0 {
+			t.Errorf("Expected the version and an error, got %+v", report)
+		}
+	})
+}
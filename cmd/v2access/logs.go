@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/gin-gonic/gin"
+)
+
+// logFollowInterval is how often follow mode polls a service for new lines
+const logFollowInterval = time.Second
+
+// requireAdminToken returns middleware that admits only requests carrying
+// "Authorization: Bearer <token>". With no token configured the guarded
+// endpoints are disabled rather than left open, since logs can contain
+// sensitive data.
+func requireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			httpErrorResponse(c, http.StatusForbidden, ErrCodeForbidden, "admin endpoints are disabled: no admin token configured")
+			c.Abort()
+			return
+		}
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			common.Warn(LogMsgAdminAuthRejected, c.Request.URL.Path)
+			httpErrorResponse(c, http.StatusUnauthorized, ErrCodeUnauthorized, "missing or invalid admin token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tailServiceLog invokes RPCTailLog on a service
+func tailServiceLog(ctx context.Context, rpcClient *RPCClient, service string, req subprocess.TailLogRequest) (*subprocess.TailLogResponse, error) {
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcClient.rpcTimeout)
+	defer cancel()
+
+	response, err := rpcClient.InvokeRPCWithTarget(rpcCtx, service, subprocess.RPCTailLog, req)
+	if err != nil {
+		return nil, fmt.Errorf("RPC error: %v", err)
+	}
+	if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+		return nil, fmt.Errorf("%s", errMsg)
+	}
+	var out subprocess.TailLogResponse
+	if err := subprocess.UnmarshalPayload(response, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return &out, nil
+}
+
+// registerLogsHandler registers GET /logs/:service, which returns the last
+// ?lines= lines (default 100) of a service's log file. With ?follow=true the
+// lines are sent as server-sent events, followed by new lines as they are
+// written, until the client disconnects.
+func registerLogsHandler(restful *gin.RouterGroup, rpcClient *RPCClient, token string) {
+	restful.GET("/logs/:service", requireAdminToken(token), func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+		service := c.Param("service")
+
+		req := subprocess.TailLogRequest{Lines: subprocess.DefaultTailLogLines}
+		if param := c.Query("lines"); param != "" {
+			lines, err := strconv.Atoi(param)
+			if err != nil || lines <= 0 {
+				httpErrorResponse(c, http.StatusBadRequest, ErrCodeInvalidRequest, "lines must be a positive integer")
+				return
+			}
+			req.Lines = lines
+		}
+
+		// As with /rpc, a one-shot tail is not tied to the HTTP request context
+		tail, err := tailServiceLog(rpcContext(c), rpcClient, service, req)
+		if err != nil {
+			httpErrorResponse(c, http.StatusBadGateway, ErrCodeRPCFailed, fmt.Sprintf("failed to tail log of %s: %v", service, err))
+			return
+		}
+
+		if c.Query("follow") != "true" {
+			httpSuccessResponse(c, tail)
+			common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, http.StatusOK)
+			return
+		}
+		followServiceLog(c, rpcClient, service, tail)
+	})
+}
+
+// followServiceLog streams the initial tail and then polls for new lines,
+// emitting a "log" event per line and a "rotated" event when the log file
+// was rotated. Each poll reads the current file, so rotation is picked up.
+func followServiceLog(c *gin.Context, rpcClient *RPCClient, service string, tail *subprocess.TailLogResponse) {
+	common.Info(LogMsgLogFollowStarted, service)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	emit := func(tail *subprocess.TailLogResponse) {
+		if tail.Rotated {
+			c.SSEvent("rotated", tail.Path)
+		}
+		for _, line := range tail.Lines {
+			c.SSEvent("log", line)
+		}
+		c.Writer.Flush()
+	}
+	emit(tail)
+
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			common.Info(LogMsgLogFollowStopped, service, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		offset := tail.Offset
+		next, err := tailServiceLog(ctx, rpcClient, service, subprocess.TailLogRequest{Lines: subprocess.MaxTailLogLines, Offset: &offset})
+		if err != nil {
+			if ctx.Err() == nil {
+				c.SSEvent("error", err.Error())
+				c.Writer.Flush()
+			}
+			common.Info(LogMsgLogFollowStopped, service, err)
+			return
+		}
+		tail = next
+		emit(tail)
+	}
+}
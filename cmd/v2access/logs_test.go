@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newLogsRouter serves /restful/v2/logs backed by a local service logging to
+// a file in dir
+func newLogsRouter(t *testing.T, token string) (*gin.Engine, string) {
+	logFile := filepath.Join(t.TempDir(), "local.log")
+	os.WriteFile(logFile, []byte("first\nsecond\nthird\n"), 0644)
+	local := subprocess.New("local")
+	local.SetLogFile(logFile)
+
+	sp := subprocess.New("access")
+	rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel), 200*time.Millisecond)
+	sp.SetOutput(&routingWriter{client: rpcClient, backends: map[string]*subprocess.Subprocess{"local": local}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerLogsHandler(r.Group("/restful/v2", withAPIVersion(APIVersionV2)), rpcClient, token)
+	return r, logFile
+}
+
+func TestLogs_RequiresAdminToken(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestLogs_RequiresAdminToken", nil, func(t *testing.T, tx *gorm.DB) {
+		r, _ := newLogsRouter(t, "s3cret")
+		for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/restful/v2/logs/local", nil)
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Authorization %q: status %d, want 401", auth, w.Code)
+			}
+		}
+
+		disabled, _ := newLogsRouter(t, "")
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/restful/v2/logs/local", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		disabled.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected logs to be disabled without a configured token, got %d", w.Code)
+		}
+	})
+}
+
+func TestLogs_TailsServiceLog(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestLogs_TailsServiceLog", nil, func(t *testing.T, tx *gorm.DB) {
+		r, _ := newLogsRouter(t, "s3cret")
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/restful/v2/logs/local?lines=2", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			OK   bool                       `json:"ok"`
+			Data subprocess.TailLogResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		if strings.Join(resp.Data.Lines, ",") != "second,third" || resp.Data.Service != "local" {
+			t.Errorf("Unexpected tail %+v", resp.Data)
+		}
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/restful/v2/logs/local?lines=-1", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected a bad lines value to be rejected, got %d", w.Code)
+		}
+	})
+}
+
+func TestLogs_FollowStreamsNewLines(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestLogs_FollowStreamsNewLines", nil, func(t *testing.T, tx *gorm.DB) {
+		r, logFile := newLogsRouter(t, "s3cret")
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/restful/v2/logs/local?lines=1&follow=true", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			t.Fatalf("Expected an event stream, got %q", ct)
+		}
+
+		f, _ := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString("fourth\n")
+		f.Close()
+
+		var got []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() && len(got) < 2 {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				got = append(got, data)
+			}
+		}
+		if strings.Join(got, ",") != "third,fourth" {
+			t.Errorf("Expected the tail followed by the new line, got %q", got)
+		}
+	})
+}
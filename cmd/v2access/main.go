@@ -42,6 +42,16 @@ Available REST Endpoints:
     Request:  {"method": "RPCGetCVE", "target": "meta", "params": {"cve_id": "CVE-2021-44228"}}
     Response: {"retcode": 0, "message": "success", "payload": {"id": "CVE-2021-44228", ...}}
 
+ 3. GET /restful/v2/health, POST /restful/v2/rpc
+    Description: The same endpoints serialized with the v2 envelope
+    Response:
+    - api_version (string): "v2"
+    - ok (bool): true for success
+    - data (object): Response data from the backend service
+    - error (object, on failure): code and message
+    The unversioned routes also serve v2 when the Accept header contains
+    application/vnd.v2e.v2+json. See service.md for the full comparison.
+
 Notes:
 ------
 - All RPC requests are forwarded through the broker for security and routing
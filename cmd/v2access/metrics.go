@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMetricsTargets are the services /metrics collects handler stats from
+// when the request does not name them
+var defaultMetricsTargets = []string{"remote", "local", "meta", "sysmon"}
+
+// serviceMethodStats is one method's counters tagged with the service serving it
+type serviceMethodStats struct {
+	Service string `json:"service"`
+	subprocess.HandlerStats
+}
+
+// metricsTotals sums the counters of every collected service
+type metricsTotals struct {
+	Calls  int64 `json:"calls"`
+	Errors int64 `json:"errors"`
+}
+
+// metricsReport is the payload of GET /metrics
+type metricsReport struct {
+	Services map[string]subprocess.HandlerStatsReport `json:"services"`
+	Methods  []serviceMethodStats                     `json:"methods"`
+	Totals   metricsTotals                            `json:"totals"`
+	Errors   map[string]string                        `json:"errors,omitempty"`
+}
+
+// collectHandlerStats asks every target for its handler stats in parallel.
+// A service that fails to answer is reported under Errors rather than
+// failing the whole report.
+func collectHandlerStats(ctx context.Context, rpcClient *RPCClient, targets []string) metricsReport {
+	report := metricsReport{
+		Services: make(map[string]subprocess.HandlerStatsReport),
+		Methods:  make([]serviceMethodStats, 0),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			stats, err := fetchHandlerStats(ctx, rpcClient, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if report.Errors == nil {
+					report.Errors = make(map[string]string)
+				}
+				report.Errors[target] = err.Error()
+				return
+			}
+			report.Services[target] = stats
+			for _, h := range stats.Handlers {
+				report.Methods = append(report.Methods, serviceMethodStats{Service: target, HandlerStats: h})
+				report.Totals.Calls += h.Calls
+				report.Totals.Errors += h.Errors
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Methods, func(i, j int) bool {
+		a, b := report.Methods[i], report.Methods[j]
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Method < b.Method
+	})
+	return report
+}
+
+// fetchHandlerStats invokes RPCGetHandlerStats on one service
+func fetchHandlerStats(ctx context.Context, rpcClient *RPCClient, target string) (subprocess.HandlerStatsReport, error) {
+	var stats subprocess.HandlerStatsReport
+	response, err := rpcClient.InvokeRPCWithTarget(ctx, target, subprocess.RPCGetHandlerStats, nil)
+	if err != nil {
+		return stats, fmt.Errorf("RPC error: %v", err)
+	}
+	if isError, errMsg := subprocess.IsErrorResponse(response); isError {
+		return stats, fmt.Errorf("%s", errMsg)
+	}
+	if err := subprocess.UnmarshalPayload(response, &stats); err != nil {
+		return stats, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return stats, nil
+}
+
+// metricsTargets returns the services named by the comma-separated
+// ?services= query parameter, or the defaults
+func metricsTargets(c *gin.Context) []string {
+	param := c.Query("services")
+	if param == "" {
+		return defaultMetricsTargets
+	}
+	var targets []string
+	for _, s := range strings.Split(param, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			targets = append(targets, s)
+		}
+	}
+	return targets
+}
+
+// registerMetricsHandler registers GET /metrics, which aggregates the
+// per-handler call counters of the backend services
+func registerMetricsHandler(restful *gin.RouterGroup, rpcClient *RPCClient) {
+	restful.GET("/metrics", func(c *gin.Context) {
+		common.Debug(LogMsgHTTPRequestReceived, c.Request.Method, c.Request.URL.Path)
+
+		// As with /rpc, the fan-out is not tied to the HTTP request context
+		rpcCtx, cancel := context.WithTimeout(rpcContext(c), rpcClient.rpcTimeout)
+		defer cancel()
+
+		report := collectHandlerStats(rpcCtx, rpcClient, metricsTargets(c))
+		for target, errMsg := range report.Errors {
+			common.Warn(LogMsgMetricsServiceError, target, errMsg)
+		}
+
+		httpSuccessResponse(c, report)
+		common.Debug(LogMsgHTTPRequestProcessed, c.Request.Method, c.Request.URL.Path, 200)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// routingWriter delivers requests sent by the access subprocess to the backend
+// subprocess named by their target and feeds the replies back to the client
+type routingWriter struct {
+	client   *RPCClient
+	backends map[string]*subprocess.Subprocess
+	buf      bytes.Buffer
+}
+
+func (w *routingWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			w.buf.Write(line)
+			return len(p), nil
+		}
+		var msg subprocess.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return len(p), err
+		}
+		backend, ok := w.backends[msg.Target]
+		if !ok {
+			// Unknown services never answer
+			continue
+		}
+		resp, err := backend.HandleMessage(context.Background(), &msg)
+		if err != nil {
+			resp = subprocess.NewErrorResponse(&msg, err.Error())
+		}
+		resp.ID, resp.CorrelationID = msg.ID, msg.CorrelationID
+		w.client.handleResponse(context.Background(), resp)
+	}
+}
+
+func TestMetrics_AggregatesHandlerStats(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestMetrics_AggregatesHandlerStats", nil, func(t *testing.T, tx *gorm.DB) {
+		ok := func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(msg, nil)
+		}
+		local := subprocess.New("local")
+		local.RegisterHandler("RPCGetCVE", ok)
+		meta := subprocess.New("meta")
+		meta.RegisterHandler("RPCStartSession", ok)
+		meta.RegisterHandler("RPCGetSessionStatus", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewErrorResponse(msg, "no active session"), nil
+		})
+		for i := 0; i < 3; i++ {
+			local.HandleMessage(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCVE"})
+		}
+		meta.HandleMessage(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCStartSession"})
+		meta.HandleMessage(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetSessionStatus"})
+
+		sp := subprocess.New("access")
+		rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel), 200*time.Millisecond)
+		sp.SetOutput(&routingWriter{client: rpcClient, backends: map[string]*subprocess.Subprocess{"local": local, "meta": meta}})
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		registerHandlers(r.Group("/restful"), rpcClient)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/restful/v2/metrics?services=local,meta,remote", nil))
+
+		var resp struct {
+			OK   bool          `json:"ok"`
+			Data metricsReport `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		report := resp.Data
+		if w.Code != http.StatusOK || !resp.OK {
+			t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+		}
+		if len(report.Services) != 2 || report.Services["meta"].Service != "meta" {
+			t.Errorf("Expected stats from local and meta, got %+v", report.Services)
+		}
+		if report.Totals.Calls != 5 || report.Totals.Errors != 1 {
+			t.Errorf("Totals = %+v, want 5 calls and 1 error", report.Totals)
+		}
+		if len(report.Methods) != 3 || report.Methods[0].Service != "local" || report.Methods[0].Method != "RPCGetCVE" || report.Methods[0].Calls != 3 {
+			t.Errorf("Unexpected methods %+v", report.Methods)
+		}
+		if report.Errors["remote"] == "" {
+			t.Errorf("Expected the unreachable remote service to be reported, got %v", report.Errors)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// readMessage reads the next message of type typ the client sent the broker
+func readMessage(t *testing.T, r *bufio.Reader, typ subprocess.MessageType) *subprocess.Message {
+	t.Helper()
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("failed to read from client: %v", err)
+		}
+		var msg subprocess.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("failed to decode client message %q: %v", line, err)
+		}
+		if msg.Type == typ {
+			return &msg
+		}
+	}
+}
+
+func TestRPCClient_ReconnectsAfterBrokerRestart(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestRPCClient_ReconnectsAfterBrokerRestart", nil, func(t *testing.T, tx *gorm.DB) {
+		// Keep the socket path short enough for sun_path
+		dir, err := os.MkdirTemp("", "v2a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		socketPath := filepath.Join(dir, "access.sock")
+
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer ln.Close()
+
+		dial := udsDialer("access", socketPath)
+		sp, err := dial()
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		c := NewRPCClientWithSubprocess(sp, logger, 5*time.Second)
+		c.EnableReconnect(dial, ReconnectConfig{
+			Enabled:        true,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     50 * time.Millisecond,
+			ReadyTimeout:   5 * time.Second,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.Run(ctx)
+
+		// The broker goes away while a request is in flight
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(conn)
+		readMessage(t, r, subprocess.MessageTypeEvent)
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := c.InvokeRPC(context.Background(), "RPCGetCVE", nil)
+			errCh <- err
+		}()
+		readMessage(t, r, subprocess.MessageTypeRequest)
+		conn.Close()
+
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, rpc.ErrConnectionReset) {
+				t.Fatalf("expected connection reset, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("in-flight request was not failed")
+		}
+
+		// The client reconnects and serves requests again
+		conn, err = ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		r = bufio.NewReader(conn)
+		readMessage(t, r, subprocess.MessageTypeEvent)
+
+		go func() {
+			_, err := c.InvokeRPC(context.Background(), "RPCGetCVE", nil)
+			errCh <- err
+		}()
+		req := readMessage(t, r, subprocess.MessageTypeRequest)
+		resp, _ := json.Marshal(&subprocess.Message{
+			Type:          subprocess.MessageTypeResponse,
+			ID:            req.ID,
+			CorrelationID: req.CorrelationID,
+			Payload:       json.RawMessage(`{}`),
+		})
+		if _, err := conn.Write(append(resp, '\n')); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatalf("expected the request to succeed after reconnecting, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("request after reconnecting got no response")
+		}
+		if !c.Ready() {
+			t.Error("expected the client to be ready")
+		}
+	})
+}
+
+func TestRPCClient_ReadyTimeout(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRPCClient_ReadyTimeout", nil, func(t *testing.T, tx *gorm.DB) {
+		c := NewRPCClient("test-access", time.Second)
+		c.reconnect.ReadyTimeout = 20 * time.Millisecond
+		if !c.Ready() {
+			t.Fatal("expected a new client to be ready")
+		}
+
+		// Simulate a lost connection that is not restored
+		c.ready = make(chan struct{})
+		if c.Ready() {
+			t.Error("expected a disconnected client not to be ready")
+		}
+		if _, err := c.InvokeRPC(context.Background(), "RPCGetCVE", nil); !errors.Is(err, ErrBrokerNotReady) {
+			t.Errorf("expected ErrBrokerNotReady, got %v", err)
+		}
+	})
+}
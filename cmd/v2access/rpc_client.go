@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
@@ -11,14 +14,46 @@ import (
 	"github.com/cyw0ng95/v2e/pkg/rpc"
 )
 
+// ErrBrokerNotReady is returned when the broker connection is not
+// re-established within the configured ready timeout
+var ErrBrokerNotReady = errors.New("broker connection not ready")
+
+// ReconnectConfig controls how the client recovers from a lost broker
+// connection. Reconnect attempts back off exponentially from InitialBackoff
+// up to MaxBackoff; requests made while disconnected wait up to ReadyTimeout
+// for the connection to return.
+type ReconnectConfig struct {
+	Enabled        bool
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	ReadyTimeout   time.Duration
+}
+
 // RPCClient handles RPC communication with the broker
 type RPCClient struct {
+	// mu guards sp, client and ready, which are replaced on reconnect
+	mu     sync.RWMutex
 	sp     *subprocess.Subprocess
 	client *rpc.Client // Use the common RPC client
+	// ready is closed while the broker connection is up
+	ready chan struct{}
 	// per-client RPC timeout (configurable)
 	rpcTimeout time.Duration
+	logger     *common.Logger
+
+	// dial opens a new broker connection; nil disables reconnecting
+	dial      func() (*subprocess.Subprocess, error)
+	reconnect ReconnectConfig
+
+	// subs are the event subscriptions by correlation ID (see Subscribe)
+	subsMu sync.Mutex
+	subs   map[string]chan *subprocess.Message
 }
 
+// eventSubscriptionBuffer is the number of undelivered events a subscription
+// holds before the oldest is dropped
+const eventSubscriptionBuffer = 16
+
 // NewRPCClient creates a new RPC client for broker communication
 func NewRPCClient(processID string, rpcTimeout time.Duration) *RPCClient {
 	// Use deterministic UDS path based on build-time base path and process ID
@@ -27,38 +62,43 @@ func NewRPCClient(processID string, rpcTimeout time.Duration) *RPCClient {
 
 	// Only attempt to create a UDS-backed subprocess if the socket exists
 	// to avoid forcing process exit when no broker listener is present
+	useUDS := false
 	if _, err := os.Stat(socketPath); err == nil {
 		sp = subprocess.NewWithUDS(processID, socketPath)
+		useUDS = true
 	} else {
 		// Fallback to stdio subprocess (useful for tests that don't set up a socket)
 		sp = subprocess.New(processID)
 	}
 
-	// Create a dummy logger for the common RPC client
+	// Create the logger of the RPC client, adjusted by RPCSetLogLevel
 	logger := common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel)
 
-	client := &RPCClient{
-		sp:         sp,
-		client:     rpc.NewClient(sp, logger, rpcTimeout),
-		rpcTimeout: rpcTimeout,
+	client := NewRPCClientWithSubprocess(sp, logger, rpcTimeout)
+	if useUDS {
+		client.EnableReconnect(udsDialer(processID, socketPath), DefaultReconnectConfig())
 	}
-
-	// rpc.Client already registers its own Response and Error handlers internally
-	// No need to register duplicate handlers here
-
 	return client
 }
 
 // NewRPCClientWithSubprocess creates a new RPC client using an existing subprocess instance
 func NewRPCClientWithSubprocess(sp *subprocess.Subprocess, logger *common.Logger, rpcTimeout time.Duration) *RPCClient {
+	ready := make(chan struct{})
+	close(ready)
 	client := &RPCClient{
 		sp:         sp,
 		client:     rpc.NewClient(sp, logger, rpcTimeout),
+		ready:      ready,
 		rpcTimeout: rpcTimeout,
+		logger:     logger,
+		subs:       make(map[string]chan *subprocess.Message),
 	}
 
-	// The common rpc.Client already registers its own handlers for response and error messages
-	// No need to register additional handlers here
+	// The common rpc.Client already registers its own handlers for response
+	// and error messages; unsolicited events go to the subscriptions
+	sp.RegisterHandler(string(subprocess.MessageTypeEvent), client.handleEvent)
+	// RPCSetLogLevel adjusts the client's logger along with the default one
+	sp.SetLogger(logger)
 
 	return client
 }
@@ -68,25 +108,239 @@ func (c *RPCClient) InvokeRPC(ctx context.Context, method string, params interfa
 	return c.InvokeRPCWithTarget(ctx, "broker", method, params)
 }
 
-// InvokeRPCWithTarget invokes an RPC method on a specific target process and waits for response
+// EnableReconnect makes Run reconnect through dial when the broker
+// connection is lost instead of returning
+func (c *RPCClient) EnableReconnect(dial func() (*subprocess.Subprocess, error), cfg ReconnectConfig) {
+	c.dial = dial
+	c.reconnect = cfg
+}
+
+// udsDialer dials the broker's socket for processID
+func udsDialer(processID, socketPath string) func() (*subprocess.Subprocess, error) {
+	return func() (*subprocess.Subprocess, error) {
+		return subprocess.DialUDS(processID, socketPath)
+	}
+}
+
+// InvokeRPCWithTarget invokes an RPC method on a specific target process and waits for response.
+// Requests in flight when the broker connection drops fail with rpc.ErrConnectionReset.
 func (c *RPCClient) InvokeRPCWithTarget(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
+	return c.InvokeRPCWithTimeout(ctx, target, method, params, 0)
+}
+
+// InvokeRPCWithTimeout is InvokeRPCWithTarget with the call timing out after
+// timeout instead of the client's RPC timeout; zero uses the client's timeout
+// (see rpc.Client.InvokeRPCWithTimeout)
+func (c *RPCClient) InvokeRPCWithTimeout(ctx context.Context, target, method string, params interface{}, timeout time.Duration) (*subprocess.Message, error) {
+	client, err := c.readyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 	// Use the common client's InvokeRPC method
-	return c.client.InvokeRPC(ctx, target, method, params)
+	return client.InvokeRPCWithTimeout(ctx, target, method, params, timeout)
+}
+
+// Ready reports whether the broker connection is up
+func (c *RPCClient) Ready() bool {
+	c.mu.RLock()
+	ready := c.ready
+	c.mu.RUnlock()
+	select {
+	case <-ready:
+		return true
+	default:
+		return false
+	}
 }
 
-// Run starts the RPC client message processing
+// readyClient returns the current rpc.Client, waiting up to the ready
+// timeout while a lost broker connection is being re-established
+func (c *RPCClient) readyClient(ctx context.Context) (*rpc.Client, error) {
+	c.mu.RLock()
+	ready, client := c.ready, c.client
+	c.mu.RUnlock()
+
+	select {
+	case <-ready:
+		return client, nil
+	default:
+	}
+
+	timer := time.NewTimer(c.reconnect.ReadyTimeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+	case <-timer.C:
+		return nil, ErrBrokerNotReady
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client, nil
+}
+
+// Run starts the RPC client message processing. When reconnecting is
+// enabled, a lost broker connection fails the in-flight requests with
+// rpc.ErrConnectionReset and is re-established with backoff; Run then
+// returns only when ctx is done or reconnecting gives up.
 func (c *RPCClient) Run(ctx context.Context) error {
-	return c.sp.Run()
+	for {
+		c.mu.RLock()
+		sp := c.sp
+		c.mu.RUnlock()
+
+		err := sp.Run()
+		if c.dial == nil || !c.reconnect.Enabled {
+			// No response can arrive any more
+			c.mu.RLock()
+			client := c.client
+			c.mu.RUnlock()
+			client.FailPending(rpc.ErrConnectionReset)
+			return err
+		}
+		c.disconnect(sp, err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		next, err := c.redial(ctx)
+		if err != nil {
+			return err
+		}
+		c.connect(next)
+	}
+}
+
+// disconnect closes the readiness gate, fails the requests that were waiting
+// on the lost connection and ends the event subscriptions
+func (c *RPCClient) disconnect(sp *subprocess.Subprocess, cause error) {
+	c.mu.Lock()
+	client := c.client
+	c.ready = make(chan struct{})
+	c.mu.Unlock()
+
+	_ = sp.CloseTransport()
+	if cause == nil {
+		cause = io.EOF
+	}
+	failed := client.FailPending(rpc.ErrConnectionReset)
+	c.closeSubscriptions()
+	c.logger.Warn(LogMsgBrokerDisconnected, cause, failed)
+}
+
+// redial dials the broker until it succeeds or ctx is done, doubling the
+// wait after each failure up to the maximum backoff
+func (c *RPCClient) redial(ctx context.Context) (*subprocess.Subprocess, error) {
+	backoff := c.reconnect.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		sp, err := c.dial()
+		if err == nil {
+			c.logger.Info(LogMsgBrokerReconnected, attempt)
+			return sp, nil
+		}
+		c.logger.Warn(LogMsgBrokerReconnectFailed, attempt, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > c.reconnect.MaxBackoff {
+			backoff = c.reconnect.MaxBackoff
+		}
+	}
+}
+
+// connect swaps in the new connection, registering the response and event
+// handlers on it, and opens the readiness gate
+func (c *RPCClient) connect(sp *subprocess.Subprocess) {
+	client := rpc.NewClient(sp, c.logger, c.rpcTimeout)
+	sp.RegisterHandler(string(subprocess.MessageTypeEvent), c.handleEvent)
+	sp.SetLogger(c.logger)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sp = sp
+	c.client = client
+	close(c.ready)
+}
+
+// Subscribe returns a channel receiving the event messages whose correlation
+// ID is id, until Unsubscribe is called or the broker connection is lost,
+// which close it. Events are expected to be snapshots: when the subscriber
+// falls behind, the oldest undelivered event is dropped.
+func (c *RPCClient) Subscribe(id string) <-chan *subprocess.Message {
+	events := make(chan *subprocess.Message, eventSubscriptionBuffer)
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if old, exists := c.subs[id]; exists {
+		close(old)
+	}
+	c.subs[id] = events
+	return events
+}
+
+// Unsubscribe ends a subscription, closing its channel
+func (c *RPCClient) Unsubscribe(id string) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if events, exists := c.subs[id]; exists {
+		close(events)
+		delete(c.subs, id)
+	}
+}
+
+// closeSubscriptions ends every subscription
+func (c *RPCClient) closeSubscriptions() {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	for id, events := range c.subs {
+		close(events)
+		delete(c.subs, id)
+	}
+}
+
+// handleEvent delivers an event message to the subscription named by its
+// correlation ID. Events nobody subscribed to are dropped.
+func (c *RPCClient) handleEvent(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	events, exists := c.subs[msg.CorrelationID]
+	if !exists {
+		c.logger.Debug(LogMsgEventUnsubscribed, msg.ID, msg.CorrelationID)
+		return nil, nil
+	}
+	for {
+		select {
+		case events <- msg:
+			return nil, nil
+		default:
+		}
+		// Full: make room by dropping the oldest event
+		select {
+		case <-events:
+			c.logger.Debug(LogMsgEventDropped, msg.CorrelationID)
+		default:
+		}
+	}
 }
 
 // handleResponse handles response messages (for test compatibility)
 // This delegates to the common RPC client's HandleResponse method
 func (c *RPCClient) handleResponse(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
-	return c.client.HandleResponse(ctx, msg)
+	c.mu.RLock()
+	client := c.client
+	c.mu.RUnlock()
+	return client.HandleResponse(ctx, msg)
 }
 
 // handleError handles error messages (for test compatibility)
 // This delegates to the common RPC client's HandleError method
 func (c *RPCClient) handleError(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
-	return c.client.HandleError(ctx, msg)
+	c.mu.RLock()
+	client := c.client
+	c.mu.RUnlock()
+	return client.HandleError(ctx, msg)
 }
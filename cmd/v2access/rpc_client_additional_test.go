@@ -39,3 +39,20 @@ func TestInvokeRPCWithTimeout(t *testing.T) {
 	})
 
 }
+
+func TestRPCClient_LoggerFollowsSetLogLevel(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRPCClient_LoggerFollowsSetLogLevel", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := subprocess.New("test-client")
+		logger := common.NewLogger(os.Stderr, "[ACCESS] ", common.InfoLevel)
+		NewRPCClientWithSubprocess(sp, logger, time.Second)
+
+		defer common.SetLevel(common.GetLevel())
+		previous := sp.SetLogLevel(common.DebugLevel)
+		if previous != common.InfoLevel {
+			t.Fatalf("expected the client's info level as previous, got %v", previous)
+		}
+		if logger.GetLevel() != common.DebugLevel {
+			t.Fatalf("expected the client's logger at debug, got %v", logger.GetLevel())
+		}
+	})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -48,6 +49,10 @@ func runAccess() {
 	rpcClient := NewRPCClientWithSubprocess(sp, logger, rpcTimeout)
 	logger.Info(LogMsgRPCClientCreated, rpcTimeout)
 
+	// Survive broker restarts: redial the same socket StandardStartup used
+	socketPath := fmt.Sprintf("%s_%s.sock", subprocess.DefaultProcUDSBasePath(), processID)
+	rpcClient.EnableReconnect(udsDialer(processID, socketPath), DefaultReconnectConfig())
+
 	// Start RPC client in background
 	go func() {
 		logger.Info(LogMsgRPCClientStarting)
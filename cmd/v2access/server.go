@@ -49,7 +49,7 @@ func setupRouter(rpcClient *RPCClient, _ int, staticDir string) *gin.Engine {
 		router.NoRoute(func(c *gin.Context) {
 			// Do not handle API routes here
 			if strings.HasPrefix(c.Request.URL.Path, "/restful") {
-				c.JSON(http.StatusNotFound, gin.H{"retcode": 404, "message": "not found", "payload": nil})
+				httpErrorResponse(c, http.StatusNotFound, ErrCodeNotFound, "not found")
 				return
 			}
 
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/gin-gonic/gin"
+)
+
+// validTraceID matches the trace IDs accepted from clients; anything else
+// is replaced with a generated one
+var validTraceID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// withTraceID returns middleware that assigns every request a trace ID: the
+// one sent in the X-Trace-ID header if it is well-formed, else a new one. The
+// ID is echoed in the response header and carried by the request context, so
+// that every RPC made for the request is tagged with it.
+func withTraceID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := c.GetHeader(proc.TraceIDHeader)
+		if !validTraceID.MatchString(traceID) {
+			traceID = proc.NewTraceID()
+		}
+		c.Header(proc.TraceIDHeader, traceID)
+		c.Request = c.Request.WithContext(proc.WithTraceID(c.Request.Context(), traceID))
+		c.Next()
+	}
+}
+
+// rpcContext returns the base context of the RPCs made for a request: it
+// carries the trace ID of the request but, as with /rpc, is not cancelled
+// when the HTTP client disconnects
+func rpcContext(c *gin.Context) context.Context {
+	return context.WithoutCancel(c.Request.Context())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestTraceID_PropagatedToBackends(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestTraceID_PropagatedToBackends", nil, func(t *testing.T, tx *gorm.DB) {
+		seen := make(chan string, 4)
+		local := subprocess.New("local")
+		local.RegisterHandler("RPCGetCatalogVersions", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			seen <- proc.TraceIDFromContext(ctx)
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{"catalogs": []interface{}{}})
+		})
+
+		sp := subprocess.New("access")
+		rpcClient := NewRPCClientWithSubprocess(sp, common.NewLogger(io.Discard, "", common.InfoLevel), 200*time.Millisecond)
+		sp.SetOutput(&routingWriter{client: rpcClient, backends: map[string]*subprocess.Subprocess{"local": local}})
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		registerHandlers(r.Group("/restful"), rpcClient)
+		get := func(traceID string) (string, string) {
+			req := httptest.NewRequest(http.MethodGet, "/restful/v2/info", nil)
+			if traceID != "" {
+				req.Header.Set(proc.TraceIDHeader, traceID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+			}
+			return w.Header().Get(proc.TraceIDHeader), <-seen
+		}
+
+		// A well-formed client trace ID is kept
+		header, backend := get("client-trace_1")
+		if header != "client-trace_1" || backend != "client-trace_1" {
+			t.Errorf("Expected the client trace ID end to end, got header %q backend %q", header, backend)
+		}
+
+		// Otherwise one is generated
+		generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+		for _, traceID := range []string{"", "not a trace id"} {
+			header, backend := get(traceID)
+			if !generated.MatchString(header) || backend != header {
+				t.Errorf("Expected a generated trace ID for %q, got header %q backend %q", traceID, header, backend)
+			}
+		}
+	})
+}
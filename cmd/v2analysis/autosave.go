@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+
+	analysisfsm "github.com/cyw0ng95/v2e/pkg/analysis/fsm"
+	analysisstorage "github.com/cyw0ng95/v2e/pkg/analysis/storage"
+)
+
+// DefaultGraphAutoSaveInterval is how often the graph is saved when
+// GRAPH_AUTOSAVE_INTERVAL is unset
+const DefaultGraphAutoSaveInterval = 5 * time.Minute
+
+// graphAutoSaveIntervalFromEnv returns the auto-save interval from
+// GRAPH_AUTOSAVE_INTERVAL, a Go duration such as "1m"; zero disables
+// auto-save. An invalid value is logged and the default is used.
+func graphAutoSaveIntervalFromEnv(logger *common.Logger) time.Duration {
+	value := os.Getenv("GRAPH_AUTOSAVE_INTERVAL")
+	if value == "" {
+		return DefaultGraphAutoSaveInterval
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logger.Warn("Invalid GRAPH_AUTOSAVE_INTERVAL %q, using %v", value, DefaultGraphAutoSaveInterval)
+		return DefaultGraphAutoSaveInterval
+	}
+	return d
+}
+
+// graphCounts are the node and edge counts of the graph when it was last
+// saved or loaded
+type graphCounts struct {
+	nodes int
+	edges int
+}
+
+// currentCounts returns the node and edge counts of the graph
+func (s *AnalysisService) currentCounts() graphCounts {
+	return graphCounts{nodes: s.graph.NodeCount(), edges: s.graph.EdgeCount()}
+}
+
+// saveGraph saves the graph through the graph FSM persist states and records
+// the saved counts. Saves are serialized by persistMu; the caller must hold it.
+func (s *AnalysisService) saveGraph() (*analysisstorage.GraphMetadata, error) {
+	graphFSM := s.analyzeFSM.GetGraphFSM()
+	if err := graphFSM.StartPersist(); err != nil {
+		return nil, fmt.Errorf("failed to start persistence: %w", err)
+	}
+
+	counts := s.currentCounts()
+	if err := s.graphStore.SaveGraph(s.graph); err != nil {
+		graphFSM.FailPersist(err)
+		return nil, fmt.Errorf("failed to save graph: %w", err)
+	}
+	s.savedCounts = counts
+
+	if err := graphFSM.CompletePersist(); err != nil {
+		return nil, fmt.Errorf("failed to complete persistence: %w", err)
+	}
+	return s.graphStore.GetMetadata()
+}
+
+// autoSave saves the graph if its node or edge count changed since the last
+// save. It is skipped outside the maintenance window, while another save runs
+// or while the graph FSM is building or analyzing, and retried at the next
+// tick. It reports whether the graph was saved.
+func (s *AnalysisService) autoSave() bool {
+	if !s.window.Allowed() {
+		return false
+	}
+	if !s.persistMu.TryLock() {
+		return false
+	}
+	defer s.persistMu.Unlock()
+
+	if s.currentCounts() == s.savedCounts {
+		return false
+	}
+	switch state := s.analyzeFSM.GetGraphFSM().GetState(); state {
+	case analysisfsm.GraphBuilding, analysisfsm.GraphAnalyzing, analysisfsm.GraphPersisting:
+		s.logger.Debug("Graph auto-save deferred: graph is %s", state)
+		return false
+	}
+
+	metadata, err := s.saveGraph()
+	if err != nil {
+		s.logger.Warn("Graph auto-save failed: %v", err)
+		return false
+	}
+	s.logger.Info("Graph auto-saved (nodes: %d, edges: %d)", metadata.NodeCount, metadata.EdgeCount)
+	return true
+}
+
+// StartAutoSave saves the graph every interval while it changes, until Close.
+// A non-positive interval disables auto-save.
+func (s *AnalysisService) StartAutoSave(interval time.Duration) {
+	if interval <= 0 {
+		s.logger.Info("Graph auto-save disabled")
+		return
+	}
+	s.autoSaveStop = make(chan struct{})
+	s.autoSaveDone = make(chan struct{})
+	go func() {
+		defer close(s.autoSaveDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.autoSave()
+			case <-s.autoSaveStop:
+				return
+			}
+		}
+	}()
+	s.logger.Info("Graph auto-save enabled with interval %v", interval)
+}
+
+// stopAutoSave stops the auto-save loop, if started, and waits for a running
+// save to finish
+func (s *AnalysisService) stopAutoSave() {
+	if s.autoSaveStop == nil {
+		return
+	}
+	close(s.autoSaveStop)
+	<-s.autoSaveDone
+	s.autoSaveStop = nil
+}
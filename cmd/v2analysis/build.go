@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Graph build types. Builds are coalesced per type: while a build of a type
+// runs, further triggers of that type attach to it instead of starting a
+// second one that would race it and add the same nodes twice.
+const (
+	BuildTypeCVEGraph    = "cve_graph"
+	BuildTypeFullRebuild = "full_rebuild"
+	BuildTypeReindex     = "reindex"
+)
+
+// What a trigger does when a build of its type is already running
+const (
+	// OnConflictAttach waits for the running build and returns its result
+	OnConflictAttach = "attach"
+	// OnConflictReject returns at once with the running build's handle
+	OnConflictReject = "reject"
+)
+
+// MsgBuildInProgress is returned to triggers that attached to a running build
+const MsgBuildInProgress = "build in progress, attached to existing"
+
+// graphBuild is one run of a graph build, shared by every trigger that
+// attached to it. Its ID is the progress handle given to callers.
+type graphBuild struct {
+	ID        string
+	Type      string
+	StartedAt time.Time
+
+	nodesAdded atomic.Int64
+	edgesAdded atomic.Int64
+	attached   atomic.Int64
+
+	done       chan struct{}
+	finishedAt time.Time
+	result     map[string]interface{}
+	err        error
+}
+
+// GraphBuildStatus is the progress of a build as reported to callers
+type GraphBuildStatus struct {
+	BuildID    string     `json:"build_id"`
+	BuildType  string     `json:"build_type"`
+	State      string     `json:"state"` // running, completed or failed
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	NodesAdded int64      `json:"nodes_added"`
+	EdgesAdded int64      `json:"edges_added"`
+	Attached   int64      `json:"attached"` // Triggers that joined the build after it started
+	Error      string     `json:"error,omitempty"`
+}
+
+// Status returns a snapshot of the build's progress
+func (b *graphBuild) Status() GraphBuildStatus {
+	status := GraphBuildStatus{
+		BuildID:    b.ID,
+		BuildType:  b.Type,
+		State:      "running",
+		StartedAt:  b.StartedAt,
+		NodesAdded: b.nodesAdded.Load(),
+		EdgesAdded: b.edgesAdded.Load(),
+		Attached:   b.attached.Load(),
+	}
+	select {
+	case <-b.done:
+		finishedAt := b.finishedAt
+		status.FinishedAt = &finishedAt
+		status.State = "completed"
+		if b.err != nil {
+			status.State = "failed"
+			status.Error = b.err.Error()
+		}
+	default:
+	}
+	return status
+}
+
+// buildGroup runs at most one build per build type at a time. It remembers
+// the last finished build of each type so a handle can still be resolved
+// once its build is over.
+type buildGroup struct {
+	mu       sync.Mutex
+	seq      uint64
+	running  map[string]*graphBuild
+	finished map[string]*graphBuild
+}
+
+func newBuildGroup() *buildGroup {
+	return &buildGroup{
+		running:  make(map[string]*graphBuild),
+		finished: make(map[string]*graphBuild),
+	}
+}
+
+// start returns the running build of the given type, or starts fn as a new
+// one. started reports whether the caller started the build; otherwise it
+// attached to an existing one. fn runs detached from the caller's
+// cancellation, since other callers may be waiting on its result.
+func (g *buildGroup) start(ctx context.Context, buildType string, fn func(ctx context.Context, b *graphBuild) (map[string]interface{}, error)) (b *graphBuild, started bool) {
+	g.mu.Lock()
+	if b, ok := g.running[buildType]; ok {
+		b.attached.Add(1)
+		g.mu.Unlock()
+		return b, false
+	}
+	g.seq++
+	b = &graphBuild{
+		ID:        fmt.Sprintf("%s-%d-%d", buildType, time.Now().UnixNano(), g.seq),
+		Type:      buildType,
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	g.running[buildType] = b
+	g.mu.Unlock()
+
+	go func() {
+		result, err := fn(context.WithoutCancel(ctx), b)
+
+		g.mu.Lock()
+		b.result, b.err, b.finishedAt = result, err, time.Now()
+		delete(g.running, buildType)
+		g.finished[buildType] = b
+		g.mu.Unlock()
+		close(b.done)
+	}()
+	return b, true
+}
+
+// wait blocks until the build finishes or ctx is done
+func (b *graphBuild) wait(ctx context.Context) (map[string]interface{}, error) {
+	select {
+	case <-b.done:
+		return b.result, b.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// get returns a running or last finished build by its handle
+func (g *buildGroup) get(buildID string) (*graphBuild, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, builds := range []map[string]*graphBuild{g.running, g.finished} {
+		for _, b := range builds {
+			if b.ID == buildID {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// latest returns the running build of the given type, or the last finished one
+func (g *buildGroup) latest(buildType string) (*graphBuild, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok := g.running[buildType]; ok {
+		return b, true
+	}
+	b, ok := g.finished[buildType]
+	return b, ok
+}
+
+// list returns the running builds of every type
+func (g *buildGroup) list() []GraphBuildStatus {
+	g.mu.Lock()
+	builds := make([]*graphBuild, 0, len(g.running))
+	for _, b := range g.running {
+		builds = append(builds, b)
+	}
+	g.mu.Unlock()
+
+	statuses := make([]GraphBuildStatus, 0, len(builds))
+	for _, b := range builds {
+		statuses = append(statuses, b.Status())
+	}
+	return statuses
+}
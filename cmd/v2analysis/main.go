@@ -19,8 +19,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
 	"github.com/cyw0ng95/v2e/pkg/graph"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 	"github.com/cyw0ng95/v2e/pkg/rpc"
@@ -38,6 +44,28 @@ type AnalysisService struct {
 	analyzeFSM  analysisfsm.AnalyzeFSM
 	graphStore  *analysisstorage.GraphStore
 	graphDBPath string
+	// exportDir is where RPCExportGraph writes its files
+	exportDir string
+	builds    *buildGroup
+	// persistMu serializes graph saves and loads
+	persistMu sync.Mutex
+	// savedCounts are the graph counts at the last save or load; auto-save
+	// skips the graph while they are unchanged
+	savedCounts  graphCounts
+	autoSaveStop chan struct{}
+	autoSaveDone chan struct{}
+	// window gates auto-saves to the maintenance window; nil means always open
+	window *maintenance.Window
+	// listCVEs queries the local service for CVEs; replaced in tests
+	listCVEs func(ctx context.Context, params interface{}) (*subprocess.Message, error)
+	// listCAPECs queries the local service for CAPECs; replaced in tests
+	listCAPECs func(ctx context.Context, params interface{}) (*subprocess.Message, error)
+	// listASVS queries the local service for ASVS requirements; replaced in tests
+	listASVS func(ctx context.Context, params interface{}) (*subprocess.Message, error)
+	// listCWEs queries the local service for CWEs; replaced in tests
+	listCWEs func(ctx context.Context, params interface{}) (*subprocess.Message, error)
+	// listATTACKs queries the local service for ATT&CK techniques; replaced in tests
+	listATTACKs func(ctx context.Context, params interface{}) (*subprocess.Message, error)
 }
 
 // NewAnalysisService creates a new analysis service
@@ -58,6 +86,23 @@ func NewAnalysisService(rpcClient *rpc.Client, logger *common.Logger, graphDBPat
 		analyzeFSM:  analyzeFSM,
 		graphStore:  graphStore,
 		graphDBPath: graphDBPath,
+		exportDir:   filepath.Join(filepath.Dir(graphDBPath), "graph_exports"),
+		builds:      newBuildGroup(),
+	}
+	service.listCVEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+		return service.rpcClient.InvokeRPC(ctx, "local", "RPCListCVEs", params)
+	}
+	service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+		return service.rpcClient.InvokeRPC(ctx, "local", "RPCListCAPECs", params)
+	}
+	service.listASVS = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+		return service.rpcClient.InvokeRPC(ctx, "local", "RPCListASVS", params)
+	}
+	service.listCWEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+		return service.rpcClient.InvokeRPC(ctx, "local", "RPCListCWEs", params)
+	}
+	service.listATTACKs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+		return service.rpcClient.InvokeRPC(ctx, "local", "RPCListAttackTechniques", params)
 	}
 
 	// Try to load existing graph from storage
@@ -65,6 +110,7 @@ func NewAnalysisService(rpcClient *rpc.Client, logger *common.Logger, graphDBPat
 		logger.Warn("Failed to load graph from storage: %v", err)
 		// Not a fatal error - continue with empty graph
 	}
+	service.savedCounts = service.currentCounts()
 
 	// Start the FSM
 	if err := analyzeFSM.Start(); err != nil {
@@ -99,13 +145,17 @@ func (s *AnalysisService) loadGraphFromStorage() error {
 
 // Close closes the analysis service and saves the graph
 func (s *AnalysisService) Close() error {
+	s.stopAutoSave()
+
 	// Save graph before closing
+	s.persistMu.Lock()
 	if s.graph.NodeCount() > 0 {
 		s.logger.Info("Saving graph before shutdown...")
 		if err := s.graphStore.SaveGraph(s.graph); err != nil {
 			s.logger.Error("Failed to save graph: %v", err)
 		}
 	}
+	s.persistMu.Unlock()
 
 	// Stop FSM
 	if err := s.analyzeFSM.Stop(); err != nil {
@@ -140,18 +190,44 @@ func main() {
 		os.Exit(1)
 	}
 	defer service.Close()
+	if dir := os.Getenv("GRAPH_EXPORT_DIR"); dir != "" {
+		service.exportDir = dir
+	}
+	// Auto-saves only run inside the shared maintenance window; an unset or
+	// invalid V2E_MAINTENANCE_WINDOW leaves it always open
+	if service.window, err = maintenance.FromEnv(); err != nil {
+		logger.Warn("Invalid %s, background work is always allowed: %v", maintenance.EnvVar, err)
+		service.window, _ = maintenance.Parse("")
+	}
+	service.StartAutoSave(graphAutoSaveIntervalFromEnv(logger))
 
 	// Register RPC handlers
 	sp.RegisterHandler("RPCGetGraphStats", createGetGraphStatsHandler(service))
 	sp.RegisterHandler("RPCAddNode", createAddNodeHandler(service))
+	sp.RegisterHandler("RPCUpdateNode", createUpdateNodeHandler(service))
 	sp.RegisterHandler("RPCAddEdge", createAddEdgeHandler(service))
+	sp.RegisterHandler("RPCAddNodesBatch", createAddNodesBatchHandler(service))
+	sp.RegisterHandler("RPCAddEdgesBatch", createAddEdgesBatchHandler(service))
+	sp.RegisterHandler("RPCRemoveNode", createRemoveNodeHandler(service))
+	sp.RegisterHandler("RPCMergeNodes", createMergeNodesHandler(service))
 	sp.RegisterHandler("RPCGetNode", createGetNodeHandler(service))
 	sp.RegisterHandler("RPCGetNeighbors", createGetNeighborsHandler(service))
+	sp.RegisterHandler("RPCGetGraphSubgraph", createGetGraphSubgraphHandler(service))
 	sp.RegisterHandler("RPCFindPath", createFindPathHandler(service))
+	sp.RegisterHandler("RPCFindPathFiltered", createFindPathFilteredHandler(service))
 	sp.RegisterHandler("RPCGetNodesByType", createGetNodesByTypeHandler(service))
+	sp.RegisterHandler("RPCGetCentrality", createGetCentralityHandler(service))
+	sp.RegisterHandler("RPCGetGraphComponents", createGetGraphComponentsHandler(service))
 	sp.RegisterHandler("RPCGetUEEStatus", createGetUEEStatusHandler(service))
 	sp.RegisterHandler("RPCBuildCVEGraph", createBuildCVEGraphHandler(service))
+	sp.RegisterHandler("RPCReindexGraphFromLocal", createReindexGraphHandler(service))
+	sp.RegisterHandler("RPCGetGraphBuildStatus", createGetGraphBuildStatusHandler(service))
 	sp.RegisterHandler("RPCClearGraph", createClearGraphHandler(service))
+	sp.RegisterHandler("RPCListEdgeTypes", createListEdgeTypesHandler(service))
+	sp.RegisterHandler("RPCValidateURN", createValidateURNHandler(service))
+	sp.RegisterHandler("RPCCheckGraphIntegrity", createCheckGraphIntegrityHandler(service))
+	sp.RegisterHandler("RPCExportGraph", createExportGraphHandler(service))
+	sp.RegisterHandler("RPCGetATTACKForCWE", createGetATTACKForCWEHandler(service))
 
 	// Register new FSM control handlers
 	sp.RegisterHandler("RPCGetFSMState", createGetFSMStateHandler(service))
@@ -172,8 +248,10 @@ func main() {
 func createGetGraphStatsHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		stats := map[string]interface{}{
-			"node_count": service.graph.NodeCount(),
-			"edge_count": service.graph.EdgeCount(),
+			"node_count":    service.graph.NodeCount(),
+			"edge_count":    service.graph.EdgeCount(),
+			"nodes_by_type": service.graph.CountsByNodeType(),
+			"edges_by_type": service.graph.CountsByEdgeType(),
 		}
 		return subprocess.NewSuccessResponse(msg, stats)
 	}
@@ -207,10 +285,11 @@ func createAddNodeHandler(service *AnalysisService) subprocess.Handler {
 func createAddEdgeHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		var params struct {
-			From       string                 `json:"from"`
-			To         string                 `json:"to"`
-			Type       string                 `json:"type"`
-			Properties map[string]interface{} `json:"properties"`
+			From        string                 `json:"from"`
+			To          string                 `json:"to"`
+			Type        string                 `json:"type"`
+			Properties  map[string]interface{} `json:"properties"`
+			AllowCustom bool                   `json:"allow_custom"`
 		}
 
 		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
@@ -228,7 +307,11 @@ func createAddEdgeHandler(service *AnalysisService) subprocess.Handler {
 		}
 
 		edgeType := graph.EdgeType(params.Type)
-		if err := service.graph.AddEdge(from, to, edgeType, params.Properties); err != nil {
+		addEdge := service.graph.AddEdge
+		if params.AllowCustom {
+			addEdge = service.graph.AddCustomEdge
+		}
+		if err := addEdge(from, to, edgeType, params.Properties); err != nil {
 			return subprocess.NewErrorResponse(msg, "failed to add edge: "+err.Error()), nil
 		}
 
@@ -240,6 +323,243 @@ func createAddEdgeHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
+// maxGraphBatchSize is the most nodes or edges RPCAddNodesBatch and
+// RPCAddEdgesBatch accept in one call
+const maxGraphBatchSize = 50000
+
+// batchItemError is the error of one item of RPCAddNodesBatch or
+// RPCAddEdgesBatch, at its index in the request
+type batchItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// batchResponse builds the response of a batch handler from the result of
+// the graph, whose error indexes are into the items that were valid. Those
+// are mapped back to request indexes with positions, and merged with the
+// errors of the request items that did not parse.
+func batchResponse(msg *subprocess.Message, result graph.BatchResult, positions []int, parseErrors []batchItemError) (*subprocess.Message, error) {
+	errs := parseErrors
+	for _, e := range result.Errors {
+		errs = append(errs, batchItemError{Index: positions[e.Index], Error: e.Err.Error()})
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	if errs == nil {
+		errs = []batchItemError{}
+	}
+	return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+		"added":   result.Added,
+		"skipped": result.Skipped,
+		"failed":  len(errs),
+		"errors":  errs,
+	})
+}
+
+// createAddNodesBatchHandler adds many nodes in one call. Nodes already in
+// the graph are skipped rather than updated; an invalid URN fails its item
+// only.
+func createAddNodesBatchHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Nodes []struct {
+				URN        string                 `json:"urn"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"nodes"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		if len(params.Nodes) > maxGraphBatchSize {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("too many nodes: %d (max %d)", len(params.Nodes), maxGraphBatchSize)), nil
+		}
+
+		var parseErrors []batchItemError
+		nodes := make([]graph.NodeInput, 0, len(params.Nodes))
+		positions := make([]int, 0, len(params.Nodes))
+		for i, n := range params.Nodes {
+			u, err := urn.Parse(n.URN)
+			if err != nil {
+				parseErrors = append(parseErrors, batchItemError{Index: i, Error: "invalid URN: " + err.Error()})
+				continue
+			}
+			nodes = append(nodes, graph.NodeInput{URN: u, Properties: n.Properties})
+			positions = append(positions, i)
+		}
+
+		return batchResponse(msg, service.graph.AddNodes(nodes), positions, parseErrors)
+	}
+}
+
+// createAddEdgesBatchHandler adds many edges in one call. Edges already in
+// the graph are skipped; an invalid URN, unknown type or missing node fails
+// its item only.
+func createAddEdgesBatchHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Edges []struct {
+				From       string                 `json:"from"`
+				To         string                 `json:"to"`
+				Type       string                 `json:"type"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"edges"`
+			AllowCustom bool `json:"allow_custom"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		if len(params.Edges) > maxGraphBatchSize {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("too many edges: %d (max %d)", len(params.Edges), maxGraphBatchSize)), nil
+		}
+
+		var parseErrors []batchItemError
+		edges := make([]graph.EdgeInput, 0, len(params.Edges))
+		positions := make([]int, 0, len(params.Edges))
+		for i, e := range params.Edges {
+			from, err := urn.Parse(e.From)
+			if err != nil {
+				parseErrors = append(parseErrors, batchItemError{Index: i, Error: "invalid from URN: " + err.Error()})
+				continue
+			}
+			to, err := urn.Parse(e.To)
+			if err != nil {
+				parseErrors = append(parseErrors, batchItemError{Index: i, Error: "invalid to URN: " + err.Error()})
+				continue
+			}
+			edges = append(edges, graph.EdgeInput{From: from, To: to, Type: graph.EdgeType(e.Type), Properties: e.Properties})
+			positions = append(positions, i)
+		}
+
+		return batchResponse(msg, service.graph.AddEdges(edges, params.AllowCustom), positions, parseErrors)
+	}
+}
+
+// createListEdgeTypesHandler returns the edge type taxonomy accepted by RPCAddEdge
+func createListEdgeTypesHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"edge_types": graph.KnownEdgeTypes(),
+		})
+	}
+}
+
+// createValidateURNHandler checks a URN without touching the graph, so
+// clients can validate input before adding nodes. A URN that does not parse
+// is a successful response with valid=false and the reason.
+func createValidateURNHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			URN string `json:"urn"`
+		}
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		if params.URN == "" {
+			return subprocess.NewErrorResponse(msg, "urn is required"), nil
+		}
+
+		u, err := urn.Normalize(params.URN)
+		if err != nil {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"urn":   params.URN,
+				"valid": false,
+				"error": err.Error(),
+			})
+		}
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"urn":        params.URN,
+			"valid":      true,
+			"normalized": u.String(),
+			"canonical":  u.String() == params.URN,
+			"provider":   u.Provider,
+			"type":       u.Type,
+			"atomic_id":  u.AtomicID,
+		})
+	}
+}
+
+// createExportGraphHandler serializes the whole graph as GraphML or JSON
+// for external tooling. The export is streamed to a file in the export
+// directory and its path returned, so a large graph is neither held in
+// memory nor sent through the broker.
+func createExportGraphHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Format string `json:"format"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+				return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+			}
+		}
+		if params.Format == "" {
+			params.Format = graph.ExportFormatJSON
+		}
+
+		path, size, err := service.exportGraph(params.Format)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "failed to export graph: "+err.Error()), nil
+		}
+		service.logger.Info("Graph exported as %s to %s (%d bytes)", params.Format, path, size)
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"format": params.Format,
+			"path":   path,
+			"size":   size,
+		})
+	}
+}
+
+// exportGraph writes the graph in format to a new file of the export
+// directory and returns its path and size. The file is written under a
+// temporary name and renamed once complete, so a failed export leaves no
+// partial file behind.
+func (s *AnalysisService) exportGraph(format string) (string, int64, error) {
+	if err := os.MkdirAll(s.exportDir, 0o755); err != nil {
+		return "", 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.exportDir, ".graph-*.tmp")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.graph.Export(tmp, format); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	path := filepath.Join(s.exportDir, fmt.Sprintf("graph-%s.%s", time.Now().UTC().Format("20060102T150405.000000000Z"), format))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	return path, info.Size(), nil
+}
+
+// createCheckGraphIntegrityHandler reports edges whose type is outside the taxonomy
+func createCheckGraphIntegrityHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		unknown := service.graph.UnknownEdgeTypes()
+		total := 0
+		for _, count := range unknown {
+			total += count
+		}
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"ok":                 total == 0,
+			"unknown_edge_types": unknown,
+			"unknown_edge_count": total,
+		})
+	}
+}
+
 // createGetNodeHandler retrieves a node from the graph
 func createGetNodeHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -268,6 +588,111 @@ func createGetNodeHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
+// createUpdateNodeHandler updates the properties of an existing node, merging
+// them into the stored ones unless merge is false
+func createUpdateNodeHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			URN        string                 `json:"urn"`
+			Properties map[string]interface{} `json:"properties"`
+			Merge      bool                   `json:"merge"`
+		}
+		params.Merge = true
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+
+		u, err := urn.Parse(params.URN)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid URN: "+err.Error()), nil
+		}
+
+		node, exists := service.graph.UpdateNodeProperties(u, params.Properties, params.Merge)
+		if !exists {
+			return subprocess.NewErrorResponse(msg, "node not found"), nil
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"urn":        node.URN.String(),
+			"properties": node.Properties,
+		})
+	}
+}
+
+// createRemoveNodeHandler removes a node together with every edge to or
+// from it. Removing a node that is not in the graph is not an error, so
+// deletions elsewhere can be propagated without checking first.
+func createRemoveNodeHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			URN string `json:"urn"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+
+		u, err := urn.Parse(params.URN)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid URN: "+err.Error()), nil
+		}
+
+		removed := service.graph.RemoveNode(u)
+		if removed {
+			service.logger.Debug("Removed node %s", u)
+		}
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"urn":        u.String(),
+			"removed":    removed,
+			"node_count": service.graph.NodeCount(),
+			"edge_count": service.graph.EdgeCount(),
+		})
+	}
+}
+
+// createMergeNodesHandler folds a duplicate node into the node to keep,
+// moving its edges and properties over, e.g. CWE-079 into CWE-79 after a
+// build that formatted IDs inconsistently
+func createMergeNodesHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Keep   string `json:"keep"`
+			Remove string `json:"remove"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+
+		keep, err := urn.Parse(params.Keep)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid keep URN: "+err.Error()), nil
+		}
+		remove, err := urn.Parse(params.Remove)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid remove URN: "+err.Error()), nil
+		}
+
+		result, err := service.graph.MergeNodes(keep, remove)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "failed to merge nodes: "+err.Error()), nil
+		}
+		service.logger.Info("Merged node %s into %s: %d edges moved, %d self-edges and %d duplicate edges dropped",
+			remove, keep, result.EdgesMoved, result.SelfEdgesDropped, result.DuplicatesDropped)
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"keep":               keep.String(),
+			"removed":            remove.String(),
+			"edges_moved":        result.EdgesMoved,
+			"self_edges_dropped": result.SelfEdgesDropped,
+			"duplicates_dropped": result.DuplicatesDropped,
+			"node_count":         service.graph.NodeCount(),
+			"edge_count":         service.graph.EdgeCount(),
+		})
+	}
+}
+
 // createGetNeighborsHandler gets all neighbors of a node
 func createGetNeighborsHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -296,6 +721,78 @@ func createGetNeighborsHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
+// createGetGraphSubgraphHandler returns the nodes within depth hops of a
+// node, in either direction, and the typed edges among them
+func createGetGraphSubgraphHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			URN   string `json:"urn"`
+			Depth *int   `json:"depth"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		depth := 1
+		if params.Depth != nil {
+			depth = *params.Depth
+		}
+		if depth < 0 {
+			return subprocess.NewErrorResponse(msg, "depth must not be negative"), nil
+		}
+
+		u, err := urn.Parse(params.URN)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid URN: "+err.Error()), nil
+		}
+
+		sub, found := service.graph.Subgraph(u, depth)
+		if !found {
+			return subprocess.NewErrorResponse(msg, "node not found"), nil
+		}
+
+		nodes := sub.GetAllNodes()
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].URN.Key() < nodes[j].URN.Key() })
+		nodeData := make([]map[string]interface{}, len(nodes))
+		for i, node := range nodes {
+			nodeData[i] = map[string]interface{}{
+				"urn":        node.URN.String(),
+				"properties": node.Properties,
+			}
+		}
+
+		edges := sub.GetAllEdges()
+		sort.Slice(edges, func(i, j int) bool {
+			if a, b := edges[i].From.Key(), edges[j].From.Key(); a != b {
+				return a < b
+			}
+			if a, b := edges[i].To.Key(), edges[j].To.Key(); a != b {
+				return a < b
+			}
+			return edges[i].Type < edges[j].Type
+		})
+		edgeData := make([]map[string]interface{}, len(edges))
+		for i, edge := range edges {
+			edgeData[i] = map[string]interface{}{
+				"from":       edge.From.String(),
+				"to":         edge.To.String(),
+				"type":       edge.Type,
+				"properties": edge.Properties,
+			}
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"center":        u.String(),
+			"depth":         depth,
+			"node_count":    len(nodeData),
+			"edge_count":    len(edgeData),
+			"nodes":         nodeData,
+			"edges":         edgeData,
+			"edges_by_type": sub.CountsByEdgeType(),
+		})
+	}
+}
+
 // createFindPathHandler finds a path between two nodes
 func createFindPathHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -335,6 +832,56 @@ func createFindPathHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
+// createFindPathFilteredHandler finds a shortest path that only follows
+// edges of the given types
+func createFindPathFilteredHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			From      string   `json:"from"`
+			To        string   `json:"to"`
+			EdgeTypes []string `json:"edge_types"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		if len(params.EdgeTypes) == 0 {
+			return subprocess.NewErrorResponse(msg, "edge_types is required"), nil
+		}
+
+		from, err := urn.Parse(params.From)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid from URN: "+err.Error()), nil
+		}
+
+		to, err := urn.Parse(params.To)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid to URN: "+err.Error()), nil
+		}
+
+		allowed := make([]graph.EdgeType, len(params.EdgeTypes))
+		for i, t := range params.EdgeTypes {
+			allowed[i] = graph.EdgeType(t)
+		}
+
+		path, found := service.graph.FindPathFiltered(from, to, allowed)
+		if !found {
+			return subprocess.NewErrorResponse(msg, "no path found"), nil
+		}
+
+		pathStrings := make([]string, len(path))
+		for i, u := range path {
+			pathStrings[i] = u.String()
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"path":       pathStrings,
+			"length":     len(path),
+			"edge_types": params.EdgeTypes,
+		})
+	}
+}
+
 // createGetNodesByTypeHandler gets all nodes of a specific type
 func createGetNodesByTypeHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -364,6 +911,94 @@ func createGetNodesByTypeHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
+// createGetCentralityHandler ranks nodes by weighted degree centrality,
+// optionally only those of one type, e.g. the CWEs referenced by the most CVEs
+func createGetCentralityHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			NodeType  string `json:"node_type"`
+			Direction string `json:"direction"`
+			Limit     int    `json:"limit"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+				return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+			}
+		}
+		if params.Direction == "" {
+			params.Direction = string(graph.DirectionBoth)
+		}
+		if params.Limit <= 0 {
+			params.Limit = 10
+		}
+
+		scores, err := service.graph.TopCentrality(graph.Direction(params.Direction), urn.ResourceType(params.NodeType), params.Limit)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "failed to compute centrality: "+err.Error()), nil
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"scores":    scores,
+			"count":     len(scores),
+			"node_type": params.NodeType,
+			"direction": params.Direction,
+			"limit":     params.Limit,
+		})
+	}
+}
+
+// createGetGraphComponentsHandler returns the connected components of the
+// graph, edges taken as undirected, to show how fragmented it is
+func createGetGraphComponentsHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Limit      int `json:"limit"`
+			SampleSize int `json:"sample_size"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+				return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+			}
+		}
+		if params.Limit <= 0 {
+			params.Limit = 10
+		}
+		if params.SampleSize <= 0 {
+			params.SampleSize = 5
+		}
+
+		type component struct {
+			Size   int      `json:"size"`
+			Sample []string `json:"sample"`
+		}
+		all := service.graph.ConnectedComponents()
+		components := make([]component, 0, min(params.Limit, len(all)))
+		nodes, singletons, largest := 0, 0, 0
+		for i, members := range all {
+			nodes += len(members)
+			if len(members) == 1 {
+				singletons++
+			}
+			if i == 0 {
+				largest = len(members)
+			}
+			if i < params.Limit {
+				components = append(components, component{Size: len(members), Sample: members[:min(params.SampleSize, len(members))]})
+			}
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"components":             components,
+			"component_count":        len(all),
+			"node_count":             nodes,
+			"singleton_count":        singletons,
+			"largest_component_size": largest,
+			"limit":                  params.Limit,
+			"sample_size":            params.SampleSize,
+		})
+	}
+}
+
 // createGetUEEStatusHandler queries the meta service for UEE status
 func createGetUEEStatusHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -374,6 +1009,9 @@ func createGetUEEStatusHandler(service *AnalysisService) subprocess.Handler {
 		if err != nil {
 			return subprocess.NewErrorResponse(msg, "failed to query UEE status: "+err.Error()), nil
 		}
+		if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+			return subprocess.NewErrorResponse(msg, "failed to query UEE status: "+errMsg), nil
+		}
 
 		var sessions map[string]interface{}
 		if err := subprocess.UnmarshalFast(resp.Payload, &sessions); err != nil {
@@ -384,93 +1022,360 @@ func createGetUEEStatusHandler(service *AnalysisService) subprocess.Handler {
 	}
 }
 
-// createBuildCVEGraphHandler builds a graph from CVE data in the local service
+// createBuildCVEGraphHandler builds a graph from CVE data in the local service.
+// Concurrent triggers of the same build type share one build (see buildGroup).
 func createBuildCVEGraphHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		var params struct {
-			Limit int `json:"limit"`
+			Limit      int    `json:"limit"`
+			Rebuild    bool   `json:"rebuild"`
+			OnConflict string `json:"on_conflict"`
 		}
 
 		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
 			// Use default limit
 			params.Limit = 100
 		}
+		if params.Limit <= 0 {
+			params.Limit = 100
+		}
+		if params.OnConflict == "" {
+			params.OnConflict = OnConflictAttach
+		}
+		if params.OnConflict != OnConflictAttach && params.OnConflict != OnConflictReject {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("invalid on_conflict: %s (must be %s or %s)", params.OnConflict, OnConflictAttach, OnConflictReject)), nil
+		}
 
-		service.logger.Info("Building CVE graph with limit: %d", params.Limit)
-
-		// Query local service for CVE data
-		listParams := map[string]interface{}{
-			"offset": 0,
-			"limit":  params.Limit,
+		buildType := BuildTypeCVEGraph
+		if params.Rebuild {
+			buildType = BuildTypeFullRebuild
 		}
 
-		resp, err := service.rpcClient.InvokeRPC(ctx, "local", "RPCListCVEs", listParams)
-		if err != nil {
-			return subprocess.NewErrorResponse(msg, "failed to query CVE data: "+err.Error()), nil
+		build, started := service.builds.start(ctx, buildType, func(ctx context.Context, b *graphBuild) (map[string]interface{}, error) {
+			if params.Rebuild {
+				service.graph.Clear()
+				service.logger.Info("Graph cleared for full rebuild")
+			}
+			return service.buildCVEGraph(ctx, params.Limit, b)
+		})
+		return buildResponse(ctx, msg, service, build, started, params.OnConflict)
+	}
+}
+
+// buildResponse answers a build trigger: with the running build's handle when
+// the trigger attached with on_conflict reject, otherwise with the build's
+// result once it finishes
+func buildResponse(ctx context.Context, msg *subprocess.Message, service *AnalysisService, build *graphBuild, started bool, onConflict string) (*subprocess.Message, error) {
+	if !started {
+		service.logger.Info("Graph build %s already running, attaching (on_conflict: %s)", build.ID, onConflict)
+		if onConflict == OnConflictReject {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"status":   "in_progress",
+				"message":  MsgBuildInProgress,
+				"attached": true,
+				"build_id": build.ID,
+				"progress": build.Status(),
+			})
 		}
+	}
 
-		var cveData struct {
-			CVEs []map[string]interface{} `json:"cves"`
+	result, err := build.wait(ctx)
+	if err != nil {
+		return subprocess.NewErrorResponse(msg, err.Error()), nil
+	}
+
+	// The result is shared by every attached caller, so copy it
+	response := make(map[string]interface{}, len(result)+3)
+	for k, v := range result {
+		response[k] = v
+	}
+	response["build_id"] = build.ID
+	response["attached"] = !started
+	if !started {
+		response["message"] = MsgBuildInProgress
+	}
+	return subprocess.NewSuccessResponse(msg, response)
+}
+
+// buildCVEGraph adds CVE nodes and their CWE references to the graph,
+// reporting progress through b
+func (s *AnalysisService) buildCVEGraph(ctx context.Context, limit int, b *graphBuild) (map[string]interface{}, error) {
+	s.logger.Info("Building CVE graph with limit: %d (build %s)", limit, b.ID)
+
+	// Query local service for CVE data
+	listParams := map[string]interface{}{
+		"offset": 0,
+		"limit":  limit,
+	}
+
+	resp, err := s.listCVEs(ctx, listParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CVE data: %w", err)
+	}
+
+	var cveData struct {
+		CVEs []map[string]interface{} `json:"cves"`
+	}
+	if err := subprocess.UnmarshalFast(resp.Payload, &cveData); err != nil {
+		return nil, fmt.Errorf("failed to parse CVE response: %w", err)
+	}
+
+	// Build graph from CVE data
+	for _, cveMap := range cveData.CVEs {
+		cveID, ok := cveMap["id"].(string)
+		if !ok {
+			continue
 		}
-		if err := subprocess.UnmarshalFast(resp.Payload, &cveData); err != nil {
-			return subprocess.NewErrorResponse(msg, "failed to parse CVE response: "+err.Error()), nil
+
+		// Create CVE node
+		cveURN, err := urn.New(urn.ProviderNVD, urn.TypeCVE, cveID)
+		if err != nil {
+			s.logger.Warn("Invalid CVE ID: %s", cveID)
+			continue
 		}
 
-		// Build graph from CVE data
-		nodesAdded := 0
-		edgesAdded := 0
+		s.graph.AddNode(cveURN, cveMap)
+		b.nodesAdded.Add(1)
 
-		for _, cveMap := range cveData.CVEs {
-			cveID, ok := cveMap["id"].(string)
-			if !ok {
-				continue
+		// Extract CWE references if available
+		if cwes, ok := cveMap["cwe_ids"].([]interface{}); ok {
+			for _, cweID := range cwes {
+				cweIDStr, ok := cweID.(string)
+				if !ok {
+					continue
+				}
+
+				cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, cweIDStr)
+				if err != nil {
+					continue
+				}
+
+				// Add CWE node if not exists
+				if _, exists := s.graph.GetNode(cweURN); !exists {
+					s.graph.AddNode(cweURN, map[string]interface{}{"id": cweIDStr})
+					b.nodesAdded.Add(1)
+				}
+
+				// Add edge from CVE to CWE
+				if err := s.graph.AddEdge(cveURN, cweURN, graph.EdgeTypeReferences, nil); err == nil {
+					b.edgesAdded.Add(1)
+				}
 			}
+		}
+	}
+
+	// CAPECs and their ATT&CK techniques are a small catalog, so all of them
+	// are added regardless of limit. Without them the CVE graph is still
+	// usable, so a failure is only logged.
+	if err := s.buildCAPECGraph(ctx, b); err != nil {
+		s.logger.Warn("Skipping CAPEC relationships: %v", err)
+	}
+	// Likewise for the ASVS requirements and the CWEs they cover
+	if err := s.buildASVSGraph(ctx, b); err != nil {
+		s.logger.Warn("Skipping ASVS relationships: %v", err)
+	}
+
+	nodesAdded, edgesAdded := b.nodesAdded.Load(), b.edgesAdded.Load()
+	s.logger.Info("Graph build complete: %d nodes, %d edges added", nodesAdded, edgesAdded)
+
+	return map[string]interface{}{
+		"nodes_added": nodesAdded,
+		"edges_added": edgesAdded,
+		"total_nodes": s.graph.NodeCount(),
+		"total_edges": s.graph.EdgeCount(),
+	}, nil
+}
+
+// capecPageSize is the page size used to list CAPECs, the most the local
+// service returns at once
+const capecPageSize = 1000
+
+// buildCAPECGraph adds CAPEC nodes with references edges to the ATT&CK
+// techniques of their taxonomy mappings, reporting progress through b
+func (s *AnalysisService) buildCAPECGraph(ctx context.Context, b *graphBuild) error {
+	for offset := 0; ; offset += capecPageSize {
+		resp, err := s.listCAPECs(ctx, map[string]interface{}{
+			"offset": offset,
+			"limit":  capecPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query CAPEC data: %w", err)
+		}
+		if resp.Type == subprocess.MessageTypeError {
+			return fmt.Errorf("failed to query CAPEC data: %s", resp.Error)
+		}
+
+		var page struct {
+			CAPECs []struct {
+				ID               string `json:"id"`
+				Name             string `json:"name"`
+				Likelihood       string `json:"likelihood"`
+				TypicalSeverity  string `json:"typical_severity"`
+				AttackTechniques []struct {
+					TechniqueID string `json:"technique_id"`
+					Name        string `json:"name"`
+				} `json:"attack_techniques"`
+			} `json:"capecs"`
+			Total int `json:"total"`
+		}
+		if err := subprocess.UnmarshalFast(resp.Payload, &page); err != nil {
+			return fmt.Errorf("failed to parse CAPEC response: %w", err)
+		}
 
-			// Create CVE node
-			cveURN, err := urn.New(urn.ProviderNVD, urn.TypeCVE, cveID)
+		for _, c := range page.CAPECs {
+			capecURN, err := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, c.ID)
 			if err != nil {
-				service.logger.Warn("Invalid CVE ID: %s", cveID)
+				s.logger.Warn("Invalid CAPEC ID: %s", c.ID)
 				continue
 			}
+			if _, exists := s.graph.GetNode(capecURN); !exists {
+				b.nodesAdded.Add(1)
+			}
+			s.graph.AddNode(capecURN, map[string]interface{}{
+				"id":               c.ID,
+				"name":             c.Name,
+				"likelihood":       c.Likelihood,
+				"typical_severity": c.TypicalSeverity,
+			})
+
+			for _, t := range c.AttackTechniques {
+				techniqueURN, err := urn.New(urn.ProviderMITRE, urn.TypeATTACK, t.TechniqueID)
+				if err != nil {
+					continue
+				}
+				if _, exists := s.graph.GetNode(techniqueURN); !exists {
+					s.graph.AddNode(techniqueURN, map[string]interface{}{"id": t.TechniqueID, "name": t.Name})
+					b.nodesAdded.Add(1)
+				}
+				if hasEdge(s.graph, capecURN, techniqueURN, graph.EdgeTypeReferences) {
+					continue
+				}
+				if err := s.graph.AddEdge(capecURN, techniqueURN, graph.EdgeTypeReferences, nil); err == nil {
+					b.edgesAdded.Add(1)
+				}
+			}
+		}
 
-			service.graph.AddNode(cveURN, cveMap)
-			nodesAdded++
-
-			// Extract CWE references if available
-			if cwes, ok := cveMap["cwe_ids"].([]interface{}); ok {
-				for _, cweID := range cwes {
-					cweIDStr, ok := cweID.(string)
-					if !ok {
-						continue
-					}
+		if len(page.CAPECs) == 0 || offset+len(page.CAPECs) >= page.Total {
+			return nil
+		}
+	}
+}
 
-					cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, cweIDStr)
-					if err != nil {
-						continue
-					}
+// asvsPageSize is the page size used to list ASVS requirements, the most the
+// local service returns at once
+const asvsPageSize = 1000
+
+// buildASVSGraph adds ASVS requirement nodes with mitigates edges to the CWEs
+// they cover, reporting progress through b
+func (s *AnalysisService) buildASVSGraph(ctx context.Context, b *graphBuild) error {
+	for offset := 0; ; offset += asvsPageSize {
+		resp, err := s.listASVS(ctx, map[string]interface{}{
+			"offset": offset,
+			"limit":  asvsPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query ASVS data: %w", err)
+		}
+		if resp.Type == subprocess.MessageTypeError {
+			return fmt.Errorf("failed to query ASVS data: %s", resp.Error)
+		}
 
-					// Add CWE node if not exists
-					if _, exists := service.graph.GetNode(cweURN); !exists {
-						service.graph.AddNode(cweURN, map[string]interface{}{"id": cweIDStr})
-						nodesAdded++
-					}
+		var page struct {
+			Requirements []struct {
+				RequirementID string   `json:"RequirementID"`
+				Chapter       string   `json:"Chapter"`
+				Section       string   `json:"Section"`
+				CWEs          []string `json:"CWEs"`
+			} `json:"requirements"`
+			Total int `json:"total"`
+		}
+		if err := subprocess.UnmarshalFast(resp.Payload, &page); err != nil {
+			return fmt.Errorf("failed to parse ASVS response: %w", err)
+		}
 
-					// Add edge from CVE to CWE
-					if err := service.graph.AddEdge(cveURN, cweURN, graph.EdgeTypeReferences, nil); err == nil {
-						edgesAdded++
-					}
+		for _, r := range page.Requirements {
+			asvsURN, err := urn.New(urn.ProviderOWASP, urn.TypeASVS, r.RequirementID)
+			if err != nil {
+				s.logger.Warn("Invalid ASVS requirement ID: %s", r.RequirementID)
+				continue
+			}
+			if _, exists := s.graph.GetNode(asvsURN); !exists {
+				b.nodesAdded.Add(1)
+			}
+			s.graph.AddNode(asvsURN, map[string]interface{}{
+				"id":      r.RequirementID,
+				"chapter": r.Chapter,
+				"section": r.Section,
+			})
+
+			for _, cweID := range r.CWEs {
+				cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, cweID)
+				if err != nil {
+					continue
+				}
+				if _, exists := s.graph.GetNode(cweURN); !exists {
+					s.graph.AddNode(cweURN, map[string]interface{}{"id": cweID})
+					b.nodesAdded.Add(1)
+				}
+				if hasEdge(s.graph, asvsURN, cweURN, graph.EdgeTypeMitigates) {
+					continue
+				}
+				if err := s.graph.AddEdge(asvsURN, cweURN, graph.EdgeTypeMitigates, nil); err == nil {
+					b.edgesAdded.Add(1)
 				}
 			}
 		}
 
-		service.logger.Info("Graph build complete: %d nodes, %d edges added", nodesAdded, edgesAdded)
+		if len(page.Requirements) == 0 || offset+len(page.Requirements) >= page.Total {
+			return nil
+		}
+	}
+}
 
-		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
-			"nodes_added": nodesAdded,
-			"edges_added": edgesAdded,
-			"total_nodes": service.graph.NodeCount(),
-			"total_edges": service.graph.EdgeCount(),
-		})
+// hasEdge reports whether an edge of the given type links from to to, so
+// rebuilding the graph does not duplicate it
+func hasEdge(g *graph.Graph, from, to *urn.URN, edgeType graph.EdgeType) bool {
+	for _, edge := range g.GetOutgoingEdges(from) {
+		if edge.Type == edgeType && edge.To.Equal(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// createGetGraphBuildStatusHandler returns the progress of a graph build by
+// its handle, or of the latest build of a type
+func createGetGraphBuildStatusHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			BuildID   string `json:"build_id"`
+			BuildType string `json:"build_type"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+				return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+			}
+		}
+
+		switch {
+		case params.BuildID != "":
+			build, ok := service.builds.get(params.BuildID)
+			if !ok {
+				return subprocess.NewErrorResponse(msg, "build not found: "+params.BuildID), nil
+			}
+			return subprocess.NewSuccessResponse(msg, build.Status())
+		case params.BuildType != "":
+			build, ok := service.builds.latest(params.BuildType)
+			if !ok {
+				return subprocess.NewErrorResponse(msg, "no build of type: "+params.BuildType), nil
+			}
+			return subprocess.NewSuccessResponse(msg, build.Status())
+		default:
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"running": service.builds.list(),
+			})
+		}
 	}
 }
 
@@ -531,25 +1436,12 @@ func createResumeAnalysisHandler(service *AnalysisService) subprocess.Handler {
 // createSaveGraphHandler saves the graph to disk
 func createSaveGraphHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
-		graphFSM := service.analyzeFSM.GetGraphFSM()
-
-		// Start persistence
-		if err := graphFSM.StartPersist(); err != nil {
-			return subprocess.NewErrorResponse(msg, "failed to start persistence: "+err.Error()), nil
-		}
-
-		// Save graph
-		if err := service.graphStore.SaveGraph(service.graph); err != nil {
-			graphFSM.FailPersist(err)
-			return subprocess.NewErrorResponse(msg, "failed to save graph: "+err.Error()), nil
-		}
-
-		// Complete persistence
-		if err := graphFSM.CompletePersist(); err != nil {
-			return subprocess.NewErrorResponse(msg, "failed to complete persistence: "+err.Error()), nil
+		service.persistMu.Lock()
+		metadata, err := service.saveGraph()
+		service.persistMu.Unlock()
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, err.Error()), nil
 		}
-
-		metadata, _ := service.graphStore.GetMetadata()
 		service.logger.Info("Graph saved to disk")
 
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
@@ -564,14 +1456,18 @@ func createSaveGraphHandler(service *AnalysisService) subprocess.Handler {
 // createLoadGraphHandler loads the graph from disk
 func createLoadGraphHandler(service *AnalysisService) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		service.persistMu.Lock()
+		defer service.persistMu.Unlock()
+
 		// Load graph
 		loadedGraph, err := service.graphStore.LoadGraph()
 		if err != nil {
 			return subprocess.NewErrorResponse(msg, "failed to load graph: "+err.Error()), nil
 		}
 
-		// Replace current graph
+		// Replace current graph; it matches the disk until changed
 		service.graph = loadedGraph
+		service.savedCounts = service.currentCounts()
 		service.logger.Info("Graph loaded from disk")
 
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
@@ -581,3 +1477,99 @@ func createLoadGraphHandler(service *AnalysisService) subprocess.Handler {
 		})
 	}
 }
+
+// linkedNodesOfType returns the nodes of the given type connected to u by an
+// edge in either direction. Mapping edges are not consistently oriented
+// (CAPEC references CWE, CWE related to CAPEC), so both are followed.
+func linkedNodesOfType(g *graph.Graph, u *urn.URN, resourceType urn.ResourceType) []*urn.URN {
+	seen := make(map[string]bool)
+	var result []*urn.URN
+	add := func(other *urn.URN) {
+		if other.Type == resourceType && !seen[other.Key()] {
+			seen[other.Key()] = true
+			result = append(result, other)
+		}
+	}
+	for _, edge := range g.GetOutgoingEdges(u) {
+		add(edge.To)
+	}
+	for _, edge := range g.GetIncomingEdges(u) {
+		add(edge.From)
+	}
+	return result
+}
+
+// bridgedTechnique is an ATT&CK technique reached from a CWE through CAPECs
+type bridgedTechnique struct {
+	URN         string                 `json:"urn"`
+	TechniqueID string                 `json:"technique_id"`
+	CAPECs      []string               `json:"capecs"`
+	BridgeCount int                    `json:"bridge_count"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+// createGetATTACKForCWEHandler returns the ATT&CK techniques a CWE relates to
+// through CAPEC attack patterns, ranked by the number of bridging CAPECs
+func createGetATTACKForCWEHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			CWEID string `json:"cwe_id"`
+		}
+
+		if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+		}
+		if params.CWEID == "" {
+			return subprocess.NewErrorResponse(msg, "cwe_id is required"), nil
+		}
+
+		cweID := params.CWEID
+		if !strings.HasPrefix(strings.ToUpper(cweID), "CWE-") {
+			cweID = "CWE-" + cweID
+		}
+		cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, strings.ToUpper(cweID))
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, "invalid CWE ID: "+err.Error()), nil
+		}
+
+		capecs := linkedNodesOfType(service.graph, cweURN, urn.TypeCAPEC)
+		byTechnique := make(map[string]*bridgedTechnique)
+		for _, capecURN := range capecs {
+			for _, techniqueURN := range linkedNodesOfType(service.graph, capecURN, urn.TypeATTACK) {
+				t, ok := byTechnique[techniqueURN.Key()]
+				if !ok {
+					t = &bridgedTechnique{
+						URN:         techniqueURN.String(),
+						TechniqueID: techniqueURN.AtomicID,
+					}
+					if node, exists := service.graph.GetNode(techniqueURN); exists {
+						t.Properties = node.Properties
+					}
+					byTechnique[techniqueURN.Key()] = t
+				}
+				t.CAPECs = append(t.CAPECs, capecURN.AtomicID)
+				t.BridgeCount++
+			}
+		}
+
+		techniques := make([]*bridgedTechnique, 0, len(byTechnique))
+		for _, t := range byTechnique {
+			sort.Strings(t.CAPECs)
+			techniques = append(techniques, t)
+		}
+		sort.Slice(techniques, func(i, j int) bool {
+			if techniques[i].BridgeCount != techniques[j].BridgeCount {
+				return techniques[i].BridgeCount > techniques[j].BridgeCount
+			}
+			return techniques[i].TechniqueID < techniques[j].TechniqueID
+		})
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"cwe_id":            cweURN.AtomicID,
+			"techniques":        techniques,
+			"count":             len(techniques),
+			"capec_count":       len(capecs),
+			"no_capec_mappings": len(capecs) == 0,
+		})
+	}
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
 	"github.com/cyw0ng95/v2e/pkg/graph"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 	"github.com/cyw0ng95/v2e/pkg/urn"
 	"gorm.io/gorm"
@@ -84,6 +93,444 @@ func TestAnalysisServiceGraphOperations(t *testing.T) {
 	})
 }
 
+func TestRemoveNodeHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "RemoveNodeHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_remove_node.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		other, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-5678")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		service.graph.AddNode(cve, nil)
+		service.graph.AddNode(other, nil)
+		service.graph.AddNode(cwe, nil)
+		service.graph.AddEdge(cve, cwe, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(other, cwe, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(cwe, cve, graph.EdgeTypeRelatedTo, nil)
+
+		handler := createRemoveNodeHandler(service)
+		remove := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		// The node goes with its outgoing and incoming edges
+		_, result := remove(`{"urn": "v2e::nvd::cve::CVE-2024-1234"}`)
+		if result["removed"] != true || result["node_count"] != float64(2) || result["edge_count"] != float64(1) {
+			t.Errorf("Unexpected result %v", result)
+		}
+		if service.graph.EdgeCount() != 1 || len(service.graph.GetOutgoingEdges(cwe)) != 0 || len(service.graph.GetIncomingEdges(cwe)) != 1 {
+			t.Errorf("Expected only the edge between the remaining nodes to be left")
+		}
+
+		if _, result := remove(`{"urn": "v2e::nvd::cve::CVE-2024-1234"}`); result["removed"] != false || result["edge_count"] != float64(1) {
+			t.Errorf("Expected removing a missing node to be a no-op, got %v", result)
+		}
+		if resp, _ := remove(`{"urn": "CVE-2024-1234"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an invalid URN to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestMergeNodesHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "MergeNodesHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_merge_nodes.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		keep, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		dup, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-079")
+		service.graph.AddNode(cve, nil)
+		service.graph.AddNode(keep, nil)
+		service.graph.AddNode(dup, nil)
+		service.graph.AddEdge(cve, keep, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(cve, dup, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(dup, keep, graph.EdgeTypeRelatedTo, nil)
+
+		handler := createMergeNodesHandler(service)
+		merge := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		_, result := merge(`{"keep": "v2e::mitre::cwe::CWE-79", "remove": "v2e::mitre::cwe::CWE-079"}`)
+		if result["edges_moved"] != float64(1) || result["self_edges_dropped"] != float64(1) || result["duplicates_dropped"] != float64(1) ||
+			result["node_count"] != float64(2) || result["edge_count"] != float64(1) {
+			t.Errorf("Unexpected result %v", result)
+		}
+
+		if resp, _ := merge(`{"keep": "v2e::mitre::cwe::CWE-79", "remove": "v2e::mitre::cwe::CWE-079"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected merging a missing node to fail, got %+v", resp)
+		}
+		if resp, _ := merge(`{"keep": "CWE-79", "remove": "v2e::mitre::cwe::CWE-079"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an invalid URN to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestValidateURNHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ValidateURNHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		handler := createValidateURNHandler(nil)
+		validate := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		_, result := validate(`{"urn": "v2e::MITRE::cwe::cwe-079"}`)
+		if result["valid"] != true || result["normalized"] != "v2e::mitre::cwe::CWE-79" || result["canonical"] != false ||
+			result["provider"] != "mitre" || result["type"] != "cwe" || result["atomic_id"] != "CWE-79" {
+			t.Errorf("Unexpected result %v", result)
+		}
+
+		_, result = validate(`{"urn": "v2e::nvd::cve::CVE-2024-1234"}`)
+		if result["valid"] != true || result["canonical"] != true {
+			t.Errorf("Expected a canonical URN, got %v", result)
+		}
+
+		_, result = validate(`{"urn": "v2e::::cve::CVE-2024-1234"}`)
+		if errText, _ := result["error"].(string); result["valid"] != false || !strings.Contains(errText, "provider is missing") {
+			t.Errorf("Expected a missing provider to be reported, got %v", result)
+		}
+		_, result = validate(`{"urn": "v2e::nvd::vuln::CVE-2024-1234"}`)
+		if errText, _ := result["error"].(string); result["valid"] != false || !strings.Contains(errText, "not a valid resource type") {
+			t.Errorf("Expected a bad type to be reported, got %v", result)
+		}
+
+		if resp, _ := validate(`{}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected a missing urn to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestUpdateNodeHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "UpdateNodeHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_update_node.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		service.graph.AddNode(cve, map[string]interface{}{"severity": "HIGH", "cvss": map[string]interface{}{"score": 7.5}})
+
+		handler := createUpdateNodeHandler(service)
+		update := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		// Properties are merged by default
+		_, result := update(`{"urn": "v2e::nvd::cve::CVE-2024-1234", "properties": {"kev": true, "cvss": {"vector": "AV:N"}}}`)
+		props, _ := result["properties"].(map[string]interface{})
+		cvss, _ := props["cvss"].(map[string]interface{})
+		if props["severity"] != "HIGH" || props["kev"] != true || cvss["score"] != 7.5 || cvss["vector"] != "AV:N" {
+			t.Errorf("Expected the properties to be merged, got %v", result)
+		}
+
+		_, result = update(`{"urn": "v2e::nvd::cve::CVE-2024-1234", "properties": {"kev": false}, "merge": false}`)
+		if props, _ := result["properties"].(map[string]interface{}); len(props) != 1 || props["kev"] != false {
+			t.Errorf("Expected the properties to be replaced, got %v", result)
+		}
+
+		if resp, _ := update(`{"urn": "v2e::nvd::cve::CVE-2024-5678", "properties": {"kev": true}}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected a missing node to be rejected, got %+v", resp)
+		}
+		if resp, _ := update(`{"urn": "CVE-2024-1234"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an invalid URN to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestGetGraphSubgraphHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "GetGraphSubgraphHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_subgraph.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		capec, _ := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-86")
+		service.graph.AddNode(cve, nil)
+		service.graph.AddNode(cwe, nil)
+		service.graph.AddNode(capec, nil)
+		service.graph.AddEdge(cve, cwe, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(capec, cwe, graph.EdgeTypeExploits, nil)
+
+		handler := createGetGraphSubgraphHandler(service)
+		subgraph := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		// Depth 0 is only the center node
+		_, result := subgraph(`{"urn": "v2e::nvd::cve::CVE-2024-1234", "depth": 0}`)
+		if result["node_count"] != float64(1) || result["edge_count"] != float64(0) {
+			t.Errorf("Expected only the center at depth 0, got %v", result)
+		}
+
+		// Two hops from the CVE reach the CAPEC through the incoming edge of
+		// the CWE; edges carry their type
+		_, result = subgraph(`{"urn": "v2e::nvd::cve::CVE-2024-1234", "depth": 2}`)
+		if result["node_count"] != float64(3) || result["edge_count"] != float64(2) {
+			t.Fatalf("Expected 3 nodes and 2 edges at depth 2, got %v", result)
+		}
+		edges, _ := result["edges"].([]interface{})
+		types := make([]string, 0, len(edges))
+		for _, e := range edges {
+			types = append(types, e.(map[string]interface{})["type"].(string))
+		}
+		sort.Strings(types)
+		if strings.Join(types, ",") != "exploits,references" {
+			t.Errorf("Expected the exploits and references edges, got %v", types)
+		}
+
+		// The depth defaults to one hop
+		if _, result := subgraph(`{"urn": "v2e::nvd::cve::CVE-2024-1234"}`); result["depth"] != float64(1) || result["node_count"] != float64(2) {
+			t.Errorf("Expected one hop by default, got %v", result)
+		}
+
+		for _, payload := range []string{
+			`{"urn": "v2e::nvd::cve::CVE-2024-9999"}`,
+			`{"urn": "v2e::nvd::cve::CVE-2024-1234", "depth": -1}`,
+			`{"urn": "CVE-2024-1234"}`,
+		} {
+			if resp, _ := subgraph(payload); resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected %s to be rejected, got %+v", payload, resp)
+			}
+		}
+	})
+}
+
+func TestGetCentralityHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "GetCentralityHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_centrality.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0002")
+		xss, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		sqli, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-89")
+		for _, u := range []*urn.URN{cve1, cve2, xss, sqli} {
+			service.graph.AddNode(u, nil)
+		}
+		service.graph.AddEdge(cve1, xss, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(cve2, xss, graph.EdgeTypeReferences, nil)
+
+		// Weights are set through RPCAddEdge properties
+		addEdge := createAddEdgeHandler(service)
+		resp, _ := addEdge(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(
+			`{"from": "v2e::nvd::cve::CVE-2024-0001", "to": "v2e::mitre::cwe::CWE-89", "type": "references", "properties": {"weight": 5}}`)})
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("Expected the weighted edge to be added, got %s", resp.Error)
+		}
+		resp, _ = addEdge(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(
+			`{"from": "v2e::nvd::cve::CVE-2024-0002", "to": "v2e::mitre::cwe::CWE-89", "type": "references", "properties": {"weight": -1}}`)})
+		if resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected a negative weight to be rejected, got %+v", resp)
+		}
+
+		handler := createGetCentralityHandler(service)
+		getCentrality := func(payload string) (*subprocess.Message, map[string]interface{}) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+			}
+			return resp, result
+		}
+
+		_, result := getCentrality(`{"node_type": "cwe", "direction": "in", "limit": 1}`)
+		scores, _ := result["scores"].([]interface{})
+		if len(scores) != 1 || result["count"] != float64(1) {
+			t.Fatalf("Expected the top CWE only, got %v", result)
+		}
+		if top := scores[0].(map[string]interface{}); top["urn"] != sqli.Key() || top["score"] != float64(5) {
+			t.Errorf("Expected the weighted CWE first, got %v", top)
+		}
+
+		// Defaults rank every node type by in and out edges
+		_, result = getCentrality(``)
+		if result["direction"] != "both" || result["limit"] != float64(10) || result["count"] != float64(4) {
+			t.Errorf("Unexpected defaults %v", result)
+		}
+
+		if resp, _ := getCentrality(`{"direction": "sideways"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an unknown direction to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestGetGraphComponentsHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "GetGraphComponentsHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_components.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		// A CVE->CWE graph with two islands and two CVEs without CWEs
+		xss, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		sqli, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-89")
+		service.graph.AddNode(xss, nil)
+		service.graph.AddNode(sqli, nil)
+		for i, cwe := range []*urn.URN{xss, xss, xss, sqli, nil, nil} {
+			cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, fmt.Sprintf("CVE-2024-%04d", i+1))
+			service.graph.AddNode(cve, nil)
+			if cwe != nil {
+				service.graph.AddEdge(cve, cwe, graph.EdgeTypeReferences, nil)
+			}
+		}
+
+		handler := createGetGraphComponentsHandler(service)
+		resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(`{"limit": 2, "sample_size": 2}`)})
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("Expected a response, got %s", resp.Error)
+		}
+		var result struct {
+			Components []struct {
+				Size   int      `json:"size"`
+				Sample []string `json:"sample"`
+			} `json:"components"`
+			ComponentCount int `json:"component_count"`
+			NodeCount      int `json:"node_count"`
+			SingletonCount int `json:"singleton_count"`
+			Largest        int `json:"largest_component_size"`
+		}
+		if err := subprocess.UnmarshalFast(resp.Payload, &result); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if result.ComponentCount != 4 || result.NodeCount != 8 || result.SingletonCount != 2 || result.Largest != 4 {
+			t.Errorf("Unexpected summary %+v", result)
+		}
+		if len(result.Components) != 2 || result.Components[0].Size != 4 || result.Components[1].Size != 2 {
+			t.Fatalf("Expected the two largest components, got %+v", result.Components)
+		}
+		if sample := result.Components[0].Sample; len(sample) != 2 || sample[0] != xss.Key() {
+			t.Errorf("Expected a sample of 2 members starting with %s, got %v", xss.Key(), sample)
+		}
+
+		resp, _ = handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(`{"limit": "all"}`)})
+		if resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected invalid parameters to be rejected, got %+v", resp)
+		}
+	})
+}
+
+func TestAddNodesAndEdgesBatchHandlers(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "AddNodesAndEdgesBatchHandlers", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_batch.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		type batchResult struct {
+			Added   int              `json:"added"`
+			Skipped int              `json:"skipped"`
+			Failed  int              `json:"failed"`
+			Errors  []batchItemError `json:"errors"`
+		}
+		call := func(handler subprocess.Handler, payload string) batchResult {
+			t.Helper()
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			if resp.Type != subprocess.MessageTypeResponse {
+				t.Fatalf("Expected a response, got %s", resp.Error)
+			}
+			var result batchResult
+			if err := subprocess.UnmarshalFast(resp.Payload, &result); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			return result
+		}
+
+		// An invalid URN fails its item only, and repeats are skipped
+		nodes := call(createAddNodesBatchHandler(service), `{"nodes": [
+			{"urn": "v2e::nvd::cve::CVE-2024-0001", "properties": {"score": 9.8}},
+			{"urn": "not-a-urn"},
+			{"urn": "v2e::mitre::cwe::CWE-79"},
+			{"urn": "v2e::nvd::cve::CVE-2024-0001"}
+		]}`)
+		if nodes.Added != 2 || nodes.Skipped != 1 || nodes.Failed != 1 || nodes.Errors[0].Index != 1 {
+			t.Errorf("Unexpected nodes result %+v", nodes)
+		}
+
+		edges := call(createAddEdgesBatchHandler(service), `{"edges": [
+			{"from": "v2e::nvd::cve::CVE-2024-0001", "to": "v2e::mitre::cwe::CWE-79", "type": "references"},
+			{"from": "v2e::nvd::cve::CVE-2024-0001", "to": "v2e::mitre::cwe::CWE-89", "type": "references"},
+			{"from": "bad", "to": "v2e::mitre::cwe::CWE-79", "type": "references"},
+			{"from": "v2e::nvd::cve::CVE-2024-0001", "to": "v2e::mitre::cwe::CWE-79", "type": "references"}
+		]}`)
+		if edges.Added != 1 || edges.Skipped != 1 || edges.Failed != 2 {
+			t.Fatalf("Unexpected edges result %+v", edges)
+		}
+		if edges.Errors[0].Index != 1 || edges.Errors[1].Index != 2 || !strings.Contains(edges.Errors[1].Error, "invalid from URN") {
+			t.Errorf("Expected errors in request order, got %+v", edges.Errors)
+		}
+		if service.graph.NodeCount() != 2 || service.graph.EdgeCount() != 1 {
+			t.Errorf("Expected 2 nodes and 1 edge, got %d and %d", service.graph.NodeCount(), service.graph.EdgeCount())
+		}
+	})
+}
+
 func TestAnalysisServiceMultipleEdgeTypes(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "MultipleEdgeTypes", nil, func(t *testing.T, tx *gorm.DB) {
 		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
@@ -164,6 +611,29 @@ func TestAnalysisServicePathFinding(t *testing.T) {
 		if !path[3].Equal(attack) {
 			t.Error("Path should end with ATT&CK")
 		}
+
+		// The filtered handler only follows the given edge types
+		handler := createFindPathFilteredHandler(service)
+		find := func(payload string) *subprocess.Message {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			return resp
+		}
+		resp := find(`{"from": "v2e::nvd::cve::CVE-2024-1234", "to": "v2e::mitre::attack::T1566", "edge_types": ["references", "related_to", "exploits"]}`)
+		var result struct {
+			Path   []string `json:"path"`
+			Length int      `json:"length"`
+		}
+		if resp.Type != subprocess.MessageTypeResponse || subprocess.UnmarshalFast(resp.Payload, &result) != nil || result.Length != 4 {
+			t.Errorf("Expected a filtered path of 4 nodes, got %+v", resp)
+		}
+		for _, payload := range []string{
+			`{"from": "v2e::nvd::cve::CVE-2024-1234", "to": "v2e::mitre::attack::T1566", "edge_types": ["references", "related_to"]}`,
+			`{"from": "v2e::nvd::cve::CVE-2024-1234", "to": "v2e::mitre::attack::T1566", "edge_types": []}`,
+		} {
+			if resp := find(payload); resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected %s to be rejected, got %+v", payload, resp)
+			}
+		}
 	})
 }
 
@@ -201,3 +671,666 @@ func TestAnalysisServiceGetNodesByType(t *testing.T) {
 		}
 	})
 }
+
+func TestAnalysisServiceEdgeTypeValidation(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "EdgeTypeValidation", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_edge_type_validation.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		service.graph.AddNode(cve, nil)
+		service.graph.AddNode(cwe, nil)
+
+		addEdge := createAddEdgeHandler(service)
+		call := func(payload string) *subprocess.Message {
+			resp, err := addEdge(context.Background(), &subprocess.Message{
+				Type: subprocess.MessageTypeRequest, ID: "RPCAddEdge", Payload: []byte(payload),
+			})
+			if err != nil {
+				t.Fatalf("Handler returned error: %v", err)
+			}
+			return resp
+		}
+
+		resp := call(`{"from":"` + cve.String() + `","to":"` + cwe.String() + `","type":"referances"}`)
+		if resp.Type != subprocess.MessageTypeError || !strings.Contains(resp.Error, "unknown edge type") {
+			t.Errorf("Expected unknown edge type error, got %s %q", resp.Type, resp.Error)
+		}
+
+		resp = call(`{"from":"` + cve.String() + `","to":"` + cwe.String() + `","type":"referances","allow_custom":true}`)
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("Expected custom edge to be accepted, got %q", resp.Error)
+		}
+
+		resp, _ = createCheckGraphIntegrityHandler(service)(context.Background(), &subprocess.Message{
+			Type: subprocess.MessageTypeRequest, ID: "RPCCheckGraphIntegrity",
+		})
+		var result struct {
+			OK               bool           `json:"ok"`
+			UnknownEdgeTypes map[string]int `json:"unknown_edge_types"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("Failed to decode integrity result: %v", err)
+		}
+		if result.OK || result.UnknownEdgeTypes["referances"] != 1 {
+			t.Errorf("Integrity check = %+v, want one referances edge", result)
+		}
+	})
+}
+
+func TestAnalysisServiceATTACKForCWE(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ATTACKForCWE", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_attack_for_cwe.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cwe79 := urn.MustNew(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		cwe20 := urn.MustNew(urn.ProviderMITRE, urn.TypeCWE, "CWE-20")
+		capec63 := urn.MustNew(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-63")
+		capec588 := urn.MustNew(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-588")
+		t1059 := urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1059")
+		t1189 := urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1189")
+		for _, u := range []*urn.URN{cwe79, cwe20, capec63, capec588, t1059, t1189} {
+			service.graph.AddNode(u, nil)
+		}
+		// Mixed orientations: CAPEC references CWE, CWE related to CAPEC
+		service.graph.AddEdge(capec63, cwe79, graph.EdgeTypeReferences, nil)
+		service.graph.AddEdge(cwe79, capec588, graph.EdgeTypeRelatedTo, nil)
+		service.graph.AddEdge(capec63, t1059, graph.EdgeTypeMapsTo, nil)
+		service.graph.AddEdge(capec588, t1059, graph.EdgeTypeMapsTo, nil)
+		service.graph.AddEdge(t1189, capec588, graph.EdgeTypeMapsTo, nil)
+
+		handler := createGetATTACKForCWEHandler(service)
+		call := func(cweID string) map[string]interface{} {
+			resp, err := handler(context.Background(), &subprocess.Message{
+				Type: subprocess.MessageTypeRequest, ID: "RPCGetATTACKForCWE",
+				Payload: []byte(`{"cwe_id":"` + cweID + `"}`),
+			})
+			if err != nil || resp.Type != subprocess.MessageTypeResponse {
+				t.Fatalf("RPCGetATTACKForCWE(%s) failed: %v %+v", cweID, err, resp)
+			}
+			var result map[string]interface{}
+			if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			return result
+		}
+
+		result := call("79")
+		techniques := result["techniques"].([]interface{})
+		if len(techniques) != 2 || result["capec_count"].(float64) != 2 || result["no_capec_mappings"].(bool) {
+			t.Fatalf("Unexpected result: %v", result)
+		}
+		first := techniques[0].(map[string]interface{})
+		if first["technique_id"] != "T1059" || first["bridge_count"].(float64) != 2 {
+			t.Errorf("Expected T1059 bridged by 2 CAPECs first, got %v", first)
+		}
+		if second := techniques[1].(map[string]interface{}); second["technique_id"] != "T1189" {
+			t.Errorf("Expected T1189 second, got %v", second)
+		}
+
+		result = call("CWE-20")
+		if !result["no_capec_mappings"].(bool) || len(result["techniques"].([]interface{})) != 0 {
+			t.Errorf("Expected empty result with no_capec_mappings for CWE-20, got %v", result)
+		}
+	})
+}
+
+func TestBuildCVEGraphCoalescesConcurrentTriggers(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "BuildCVEGraphCoalescesConcurrentTriggers", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_build_coalesce.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		// The local service answers once released, so every trigger overlaps the first
+		var calls atomic.Int32
+		release := make(chan struct{})
+		service.listCVEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			calls.Add(1)
+			<-release
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{
+				"cves": []map[string]interface{}{
+					{"id": "CVE-2024-0001", "cwe_ids": []string{"CWE-79"}},
+					{"id": "CVE-2024-0002", "cwe_ids": []string{"CWE-79"}},
+				},
+			})
+		}
+		service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{"capecs": []interface{}{}, "total": 0})
+		}
+		service.listASVS = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{"requirements": []interface{}{}, "total": 0})
+		}
+		handler := createBuildCVEGraphHandler(service)
+		build := func(payload string) map[string]interface{} {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			if resp.Type == subprocess.MessageTypeError {
+				t.Errorf("Build failed: %s", resp.Error)
+				return nil
+			}
+			var result map[string]interface{}
+			subprocess.UnmarshalFast(resp.Payload, &result)
+			return result
+		}
+
+		const triggers = 5
+		results := make([]map[string]interface{}, triggers)
+		var wg sync.WaitGroup
+		for i := 0; i < triggers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = build(`{"limit": 10}`)
+			}(i)
+		}
+
+		// Wait until the build runs and the other triggers have attached
+		for {
+			b, ok := service.builds.latest(BuildTypeCVEGraph)
+			if ok && b.Status().Attached == triggers-1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		rejected := build(`{"limit": 10, "on_conflict": "reject"}`)
+		if rejected["status"] != "in_progress" || rejected["message"] != MsgBuildInProgress || rejected["build_id"] == nil {
+			t.Errorf("Expected the rejected trigger to get the running build's handle, got %v", rejected)
+		}
+
+		close(release)
+		wg.Wait()
+
+		if n := calls.Load(); n != 1 {
+			t.Errorf("Expected one build to query the local service, got %d", n)
+		}
+		if service.graph.NodeCount() != 3 || service.graph.EdgeCount() != 2 {
+			t.Errorf("Expected 3 nodes and 2 edges, got %d and %d", service.graph.NodeCount(), service.graph.EdgeCount())
+		}
+		leaders := 0
+		for _, result := range results {
+			if result["build_id"] != rejected["build_id"] || result["nodes_added"] != float64(3) {
+				t.Errorf("Expected every trigger to share the build result, got %v", result)
+			}
+			if result["attached"] == false {
+				leaders++
+			}
+		}
+		if leaders != 1 {
+			t.Errorf("Expected exactly one trigger to start the build, got %d", leaders)
+		}
+
+		status, _ := createGetGraphBuildStatusHandler(service)(context.Background(), &subprocess.Message{
+			Type:    subprocess.MessageTypeRequest,
+			Payload: []byte(`{"build_id": "` + rejected["build_id"].(string) + `"}`),
+		})
+		var progress GraphBuildStatus
+		subprocess.UnmarshalFast(status.Payload, &progress)
+		if progress.State != "completed" || progress.NodesAdded != 3 || progress.Attached != triggers {
+			t.Errorf("Unexpected build status %+v", progress)
+		}
+
+		// A later trigger starts a new build
+		if again := build(`{"limit": 10}`); again["build_id"] == rejected["build_id"] || again["attached"] != false {
+			t.Errorf("Expected a new build once the previous one finished, got %v", again)
+		}
+	})
+}
+
+func TestBuildCVEGraphLinksCAPECsToATTACK(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "BuildCVEGraphLinksCAPECsToATTACK", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_build_capec_attack.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		service.listCVEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{
+				"cves": []map[string]interface{}{{"id": "CVE-2024-0001", "cwe_ids": []string{"CWE-307"}}},
+			})
+		}
+		// Two pages: CAPEC-49 and CAPEC-16 share T1110, CAPEC-1 has no mappings
+		var offsets []int
+		service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			offset := params.(map[string]interface{})["offset"].(int)
+			offsets = append(offsets, offset)
+			page := []map[string]interface{}{
+				{"id": "CAPEC-49", "name": "Password Brute Forcing", "attack_techniques": []map[string]string{
+					{"technique_id": "T1110.001", "name": "Brute Force: Password Guessing"},
+					{"technique_id": "T1110", "name": "Brute Force"},
+				}},
+				{"id": "CAPEC-16", "name": "Dictionary-based Password Attack", "attack_techniques": []map[string]string{
+					{"technique_id": "T1110", "name": "Brute Force"},
+				}},
+			}
+			if offset > 0 {
+				page = []map[string]interface{}{{"id": "CAPEC-1", "name": "Accessing Functionality Not Properly Constrained by ACLs"}}
+			}
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{"capecs": page, "total": capecPageSize + 1})
+		}
+		service.listASVS = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{"requirements": []interface{}{}, "total": 0})
+		}
+
+		handler := createBuildCVEGraphHandler(service)
+		build := func() map[string]interface{} {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(`{"limit": 10}`)})
+			if resp.Type == subprocess.MessageTypeError {
+				t.Fatalf("Build failed: %s", resp.Error)
+			}
+			var result map[string]interface{}
+			subprocess.UnmarshalFast(resp.Payload, &result)
+			return result
+		}
+
+		// CVE, CWE, 3 CAPECs and 2 techniques; 1 CVE edge and 3 CAPEC edges
+		result := build()
+		if result["nodes_added"] != float64(7) || result["edges_added"] != float64(4) {
+			t.Errorf("Unexpected build result %v", result)
+		}
+		if len(offsets) != 2 || offsets[1] != capecPageSize {
+			t.Errorf("Expected two CAPEC pages, got offsets %v", offsets)
+		}
+
+		capec49 := urn.MustNew(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-49")
+		neighbors := service.graph.GetNeighbors(capec49)
+		ids := make([]string, 0, len(neighbors))
+		for _, n := range neighbors {
+			if n.Type != urn.TypeATTACK {
+				t.Errorf("Unexpected neighbor %s", n)
+			}
+			ids = append(ids, n.AtomicID)
+		}
+		sort.Strings(ids)
+		if len(ids) != 2 || ids[0] != "T1110" || ids[1] != "T1110.001" {
+			t.Errorf("Expected CAPEC-49 to reference its techniques, got %v", ids)
+		}
+		if node, ok := service.graph.GetNode(urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1110")); !ok || node.Properties["name"] != "Brute Force" {
+			t.Errorf("Expected the technique node with its name, got %+v", node)
+		}
+		if in := service.graph.GetIncomingEdges(urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1110")); len(in) != 2 {
+			t.Errorf("Expected both CAPECs to reference T1110, got %d edges", len(in))
+		}
+
+		// Rebuilding does not duplicate the CAPEC edges
+		offsets = nil
+		build()
+		if len(service.graph.GetOutgoingEdges(capec49)) != 2 || len(service.graph.GetIncomingEdges(urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1110"))) != 2 {
+			t.Errorf("Expected the CAPEC edges not to be duplicated")
+		}
+
+		// A local service without CAPECs does not fail the CVE build
+		service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewErrorResponse(&subprocess.Message{}, "failed to list CAPECs"), nil
+		}
+		build()
+	})
+}
+
+func TestBuildCVEGraphLinksASVSToCWE(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "BuildCVEGraphLinksASVSToCWE", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_build_asvs_cwe.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		service.listCVEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{
+				"cves": []map[string]interface{}{{"id": "CVE-2024-0001", "cwe_ids": []string{"CWE-79"}}},
+			})
+		}
+		service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{"capecs": []interface{}{}, "total": 0})
+		}
+		// V1.2.1 covers two CWEs, one of them already referenced by the CVE
+		service.listASVS = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, map[string]interface{}{
+				"requirements": []map[string]interface{}{
+					{"RequirementID": "V1.2.1", "Chapter": "V1", "CWEs": []string{"CWE-79", "CWE-116"}},
+					{"RequirementID": "V1.2.2", "Chapter": "V1", "CWEs": []string{"CWE-79"}},
+					{"RequirementID": "V2.1.1", "Chapter": "V2"},
+				},
+				"total": 3,
+			})
+		}
+
+		handler := createBuildCVEGraphHandler(service)
+		build := func() map[string]interface{} {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(`{"limit": 10}`)})
+			if resp.Type == subprocess.MessageTypeError {
+				t.Fatalf("Build failed: %s", resp.Error)
+			}
+			var result map[string]interface{}
+			subprocess.UnmarshalFast(resp.Payload, &result)
+			return result
+		}
+
+		// CVE, 2 CWEs and 3 requirements; 1 CVE edge and 3 ASVS edges
+		result := build()
+		if result["nodes_added"] != float64(6) || result["edges_added"] != float64(4) {
+			t.Errorf("Unexpected build result %v", result)
+		}
+
+		cwe79 := urn.MustNew(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		mitigating := 0
+		for _, edge := range service.graph.GetIncomingEdges(cwe79) {
+			if edge.Type == graph.EdgeTypeMitigates && edge.From.Type == urn.TypeASVS {
+				mitigating++
+			}
+		}
+		if mitigating != 2 {
+			t.Errorf("Expected two ASVS requirements to mitigate CWE-79, got %d", mitigating)
+		}
+		v121 := urn.MustNew(urn.ProviderOWASP, urn.TypeASVS, "V1.2.1")
+		if node, ok := service.graph.GetNode(v121); !ok || node.Properties["chapter"] != "V1" {
+			t.Errorf("Expected the requirement node with its chapter, got %+v", node)
+		}
+
+		// Rebuilding does not duplicate the ASVS edges
+		build()
+		if len(service.graph.GetOutgoingEdges(v121)) != 2 {
+			t.Errorf("Expected the ASVS edges not to be duplicated")
+		}
+	})
+}
+
+func TestExportGraphHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ExportGraphHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_export_graph.db"
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		service.graph.AddNode(cve, nil)
+		service.graph.AddNode(cwe, nil)
+		service.graph.AddEdge(cve, cwe, graph.EdgeTypeReferences, nil)
+
+		service.exportDir = t.TempDir()
+
+		handler := createExportGraphHandler(service)
+		export := func(payload string) (*subprocess.Message, map[string]interface{}, string) {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			var result map[string]interface{}
+			var data string
+			if resp.Type == subprocess.MessageTypeResponse {
+				subprocess.UnmarshalFast(resp.Payload, &result)
+				path, _ := result["path"].(string)
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read the export file: %v", err)
+				}
+				if size, _ := result["size"].(float64); int(size) != len(content) {
+					t.Errorf("Expected size %d, got %v", len(content), result["size"])
+				}
+				data = string(content)
+			}
+			return resp, result, data
+		}
+
+		if _, result, data := export(`{}`); result["format"] != "json" || !strings.HasSuffix(result["path"].(string), ".json") || !strings.HasPrefix(data, `{"node_count":2,"edge_count":1,`) {
+			t.Errorf("Expected a JSON export by default, got %v: %s", result, data)
+		}
+		if _, result, data := export(`{"format": "graphml"}`); !strings.Contains(data, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns"`) ||
+			!strings.Contains(data, `source="v2e::nvd::cve::CVE-2024-1234" target="v2e::mitre::cwe::CWE-79"`) {
+			t.Errorf("Expected a GraphML export, got %v: %s", result, data)
+		}
+		if resp, _, _ := export(`{"format": "dot"}`); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an unknown format to be rejected, got %+v", resp)
+		}
+		if entries, _ := os.ReadDir(service.exportDir); len(entries) != 2 {
+			t.Errorf("Expected only the two completed exports in the export directory, got %d entries", len(entries))
+		}
+	})
+}
+
+func TestGraphAutoSave(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "GraphAutoSave", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := t.TempDir() + "/autosave.db"
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+		savedNodes := func() int {
+			metadata, err := service.graphStore.GetMetadata()
+			if err != nil {
+				return 0
+			}
+			return metadata.NodeCount
+		}
+
+		// Nothing changed since startup
+		if service.autoSave() {
+			t.Error("Expected an unchanged graph not to be saved")
+		}
+
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		service.graph.AddNode(cve, nil)
+		if !service.autoSave() || savedNodes() != 1 {
+			t.Fatalf("Expected the changed graph to be saved, %d nodes on disk", savedNodes())
+		}
+		if service.autoSave() {
+			t.Error("Expected a saved graph not to be saved again")
+		}
+
+		// A build in progress defers the save
+		graphFSM := service.analyzeFSM.GetGraphFSM()
+		if err := graphFSM.StartBuild(); err != nil {
+			t.Fatalf("StartBuild failed: %v", err)
+		}
+		service.graph.AddNode(cwe, nil)
+		if service.autoSave() || savedNodes() != 1 {
+			t.Error("Expected no save while the graph is building")
+		}
+		graphFSM.CompleteBuild()
+		if !service.autoSave() || savedNodes() != 2 {
+			t.Errorf("Expected the built graph to be saved, %d nodes on disk", savedNodes())
+		}
+
+		// Outside the maintenance window the save waits for it to open; a
+		// window open only on a day three days from now is closed today
+		closed, err := maintenance.Parse(time.Now().AddDate(0, 0, 3).Weekday().String()[:3])
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		service.window = closed
+		service.graph.RemoveNode(cwe)
+		if service.autoSave() || savedNodes() != 2 {
+			t.Error("Expected no save outside the maintenance window")
+		}
+		service.window = nil
+
+		// The ticker saves changes until the service is closed
+		service.StartAutoSave(10 * time.Millisecond)
+		service.graph.RemoveNode(cwe)
+		deadline := time.Now().Add(2 * time.Second)
+		for savedNodes() != 1 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if savedNodes() != 1 {
+			t.Errorf("Expected the ticker to save the removal, %d nodes on disk", savedNodes())
+		}
+	})
+}
+
+func TestGraphAutoSaveIntervalFromEnv(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "GraphAutoSaveIntervalFromEnv", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		old := os.Getenv("GRAPH_AUTOSAVE_INTERVAL")
+		defer os.Setenv("GRAPH_AUTOSAVE_INTERVAL", old)
+		for value, want := range map[string]time.Duration{
+			"":     DefaultGraphAutoSaveInterval,
+			"30s":  30 * time.Second,
+			"0":    0,
+			"soon": DefaultGraphAutoSaveInterval,
+			"-1m":  DefaultGraphAutoSaveInterval,
+		} {
+			os.Setenv("GRAPH_AUTOSAVE_INTERVAL", value)
+			if got := graphAutoSaveIntervalFromEnv(logger); got != want {
+				t.Errorf("GRAPH_AUTOSAVE_INTERVAL=%q: got %v, want %v", value, got, want)
+			}
+		}
+	})
+}
+
+func TestReindexGraphFromLocal(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ReindexGraphFromLocal", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stdout, "test", common.InfoLevel)
+		dbPath := "/tmp/test_reindex_graph.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		service, err := NewAnalysisService(nil, logger, dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		defer service.Close()
+
+		respond := func(payload map[string]interface{}) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(&subprocess.Message{}, payload)
+		}
+		service.listCWEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return respond(map[string]interface{}{"cwes": []map[string]interface{}{
+				{"ID": "CWE-79", "Name": "Cross-site Scripting", "RelatedAttackPatterns": []string{"CAPEC-63"}},
+				{"ID": "CWE-307", "Name": "Improper Restriction of Excessive Authentication Attempts"},
+			}, "total": 2})
+		}
+		service.listCAPECs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return respond(map[string]interface{}{"capecs": []map[string]interface{}{
+				{"id": "CAPEC-63", "name": "Cross-Site Scripting (XSS)", "weaknesses": []string{"79"}},
+				{"id": "CAPEC-49", "name": "Password Brute Forcing", "weaknesses": []string{"307"}, "attack_techniques": []map[string]string{
+					{"technique_id": "T1110", "name": "Brute Force"},
+				}},
+			}, "total": 2})
+		}
+		service.listATTACKs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			return respond(map[string]interface{}{"techniques": []map[string]interface{}{
+				{"id": "T1110", "name": "Brute Force"},
+				{"id": "T1059", "name": "Command and Scripting Interpreter"},
+			}, "total": 2})
+		}
+		// Two CVE pages; the second fails while failCVEs is set
+		failCVEs := true
+		service.listCVEs = func(ctx context.Context, params interface{}) (*subprocess.Message, error) {
+			weakness := func(id string) []map[string]interface{} {
+				return []map[string]interface{}{{"description": []map[string]string{{"lang": "en", "value": id}}}}
+			}
+			if params.(map[string]interface{})["cursor"] == "" {
+				return respond(map[string]interface{}{"cves": []map[string]interface{}{
+					{"id": "CVE-2024-0001", "weaknesses": weakness("CWE-79")},
+					{"id": "CVE-2024-0002", "weaknesses": weakness("NVD-CWE-Other")},
+				}, "next_cursor": "page-2"})
+			}
+			if failCVEs {
+				return subprocess.NewErrorResponse(&subprocess.Message{}, "local service unavailable"), nil
+			}
+			return respond(map[string]interface{}{"cves": []map[string]interface{}{
+				{"id": "CVE-2024-0003", "weaknesses": weakness("CWE-307")},
+			}, "next_cursor": ""})
+		}
+		// A stale node is dropped by the reindex
+		service.graph.AddNode(urn.MustNew(urn.ProviderNVD, urn.TypeCVE, "CVE-1999-0001"), nil)
+
+		handler := createReindexGraphHandler(service)
+		reindex := func(payload string) *subprocess.Message {
+			resp, _ := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, Payload: []byte(payload)})
+			return resp
+		}
+
+		if resp := reindex(`{}`); resp.Type != subprocess.MessageTypeError || !strings.Contains(resp.Error, "local service unavailable") {
+			t.Fatalf("Expected the reindex to fail in the CVE phase, got %+v", resp)
+		}
+
+		// Resuming starts the CVE phase over on the graph of its checkpoint
+		failCVEs = false
+		resp := reindex(`{"resume": true}`)
+		if resp.Type == subprocess.MessageTypeError {
+			t.Fatalf("Resumed reindex failed: %s", resp.Error)
+		}
+		var result struct {
+			Nodes      map[string]int64 `json:"nodes"`
+			Edges      map[string]int64 `json:"edges"`
+			Resumed    bool             `json:"resumed"`
+			TotalNodes int              `json:"total_nodes"`
+			TotalEdges int              `json:"total_edges"`
+		}
+		if err := subprocess.UnmarshalFast(resp.Payload, &result); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		wantNodes := map[string]int64{"cwe": 2, "capec": 2, "attack": 2, "cve": 3}
+		wantEdges := map[string]int64{"cve_cwe": 2, "cwe_capec": 2, "capec_attack": 1}
+		for k, v := range wantNodes {
+			if result.Nodes[k] != v {
+				t.Errorf("Expected %d %s nodes, got %v", v, k, result.Nodes)
+			}
+		}
+		for k, v := range wantEdges {
+			if result.Edges[k] != v {
+				t.Errorf("Expected %d %s edges, got %v", v, k, result.Edges)
+			}
+		}
+		if !result.Resumed || result.TotalNodes != 9 || result.TotalEdges != 5 {
+			t.Errorf("Unexpected reindex result %+v", result)
+		}
+		if _, ok := service.graph.GetNode(urn.MustNew(urn.ProviderNVD, urn.TypeCVE, "CVE-1999-0001")); ok {
+			t.Error("Expected the stale node to be dropped")
+		}
+		if node, ok := service.graph.GetNode(urn.MustNew(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")); !ok || node.Properties["name"] != "Cross-site Scripting" {
+			t.Errorf("Expected CWE-79 with its name, got %+v", node)
+		}
+
+		// CVE -> CWE -> CAPEC -> ATT&CK is connected end to end
+		path, found := service.graph.FindPath(
+			urn.MustNew(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0003"),
+			urn.MustNew(urn.ProviderMITRE, urn.TypeATTACK, "T1110"))
+		if !found || len(path) != 4 {
+			t.Errorf("Expected a 4-node path from CVE-2024-0003 to T1110, got %v", path)
+		}
+
+		// A finished reindex is not resumed but starts over
+		resp = reindex(`{"resume": true}`)
+		if err := subprocess.UnmarshalFast(resp.Payload, &result); err != nil || result.Resumed || result.TotalNodes != 9 {
+			t.Errorf("Expected a fresh reindex, got %+v (%v)", result, err)
+		}
+	})
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/graph"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/urn"
+)
+
+// reindexPageSize is the page size used to list each data type, the most the
+// local service returns at once
+const reindexPageSize = 1000
+
+// reindexCheckpointPages is the number of pages between two checkpoints of a
+// phase. Each checkpoint saves the graph, so it must stay infrequent.
+const reindexCheckpointPages = 20
+
+// reindexCheckpointID is the graph store checkpoint of the reindex
+const reindexCheckpointID = "reindex"
+
+// Reindex phases, in the order they run. CWEs come first so the CVE, CAPEC
+// and ATT&CK phases link to CWE nodes carrying their names.
+const (
+	reindexPhaseCWE    = "cwe"
+	reindexPhaseCAPEC  = "capec"
+	reindexPhaseATTACK = "attack"
+	reindexPhaseCVE    = "cve"
+	reindexPhaseDone   = "done"
+)
+
+var reindexPhases = []string{reindexPhaseCWE, reindexPhaseCAPEC, reindexPhaseATTACK, reindexPhaseCVE}
+
+// Edge types reported by a reindex, named after the data types they link
+const (
+	reindexEdgeCVECWE      = "cve_cwe"
+	reindexEdgeCWECAPEC    = "cwe_capec"
+	reindexEdgeCAPECATTACK = "capec_attack"
+)
+
+// reindexCheckpoint is the progress of a reindex: the phase it is in, where
+// that phase resumes, and the counts so far. The CVE phase pages with a
+// cursor, the others with an offset.
+type reindexCheckpoint struct {
+	Phase  string           `json:"phase"`
+	Offset int              `json:"offset"`
+	Cursor string           `json:"cursor"`
+	Nodes  map[string]int64 `json:"nodes"`
+	Edges  map[string]int64 `json:"edges"`
+}
+
+// reindex is one run of RPCReindexGraphFromLocal
+type reindex struct {
+	s  *AnalysisService
+	b  *graphBuild
+	cp reindexCheckpoint
+}
+
+// createReindexGraphHandler rebuilds the graph from every data type of the
+// local service. It shares the coalescing of graph builds (see buildGroup).
+func createReindexGraphHandler(service *AnalysisService) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Resume     bool   `json:"resume"`
+			OnConflict string `json:"on_conflict"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalFast(msg.Payload, &params); err != nil {
+				return subprocess.NewErrorResponse(msg, "invalid parameters: "+err.Error()), nil
+			}
+		}
+		if params.OnConflict == "" {
+			params.OnConflict = OnConflictAttach
+		}
+		if params.OnConflict != OnConflictAttach && params.OnConflict != OnConflictReject {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("invalid on_conflict: %s (must be %s or %s)", params.OnConflict, OnConflictAttach, OnConflictReject)), nil
+		}
+
+		build, started := service.builds.start(ctx, BuildTypeReindex, func(ctx context.Context, b *graphBuild) (map[string]interface{}, error) {
+			return service.reindexGraph(ctx, params.Resume, b)
+		})
+		return buildResponse(ctx, msg, service, build, started, params.OnConflict)
+	}
+}
+
+// reindexGraph clears the graph and rebuilds it from the CWEs, CAPECs,
+// ATT&CK techniques and CVEs of the local service. With resume, an unfinished
+// reindex continues from its last checkpoint instead.
+func (s *AnalysisService) reindexGraph(ctx context.Context, resume bool, b *graphBuild) (map[string]interface{}, error) {
+	r := &reindex{s: s, b: b}
+	resumed := false
+	if cp, ok := s.loadReindexCheckpoint(); resume && ok && cp.Phase != reindexPhaseDone {
+		// The graph saved with the checkpoint holds exactly what it counts;
+		// the graph in memory may hold more of the interrupted phase
+		s.persistMu.Lock()
+		loadedGraph, err := s.graphStore.LoadGraph()
+		if err == nil {
+			s.graph = loadedGraph
+			s.savedCounts = s.currentCounts()
+		}
+		s.persistMu.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load graph at reindex checkpoint: %w", err)
+		}
+		r.cp, resumed = cp, true
+		s.logger.Info("Resuming graph reindex at phase %s (offset %d, cursor %q, build %s)", cp.Phase, cp.Offset, cp.Cursor, b.ID)
+	} else {
+		r.cp = reindexCheckpoint{Phase: reindexPhaseCWE, Nodes: map[string]int64{}, Edges: map[string]int64{}}
+		s.graph.Clear()
+		s.logger.Info("Graph cleared for reindex (build %s)", b.ID)
+	}
+	if r.cp.Nodes == nil {
+		r.cp.Nodes = map[string]int64{}
+	}
+	if r.cp.Edges == nil {
+		r.cp.Edges = map[string]int64{}
+	}
+
+	for i := phaseIndex(r.cp.Phase); i < len(reindexPhases); i++ {
+		phase := reindexPhases[i]
+		var err error
+		switch phase {
+		case reindexPhaseCWE:
+			err = r.indexCWEs(ctx)
+		case reindexPhaseCAPEC:
+			err = r.indexCAPECs(ctx)
+		case reindexPhaseATTACK:
+			err = r.indexATTACKs(ctx)
+		case reindexPhaseCVE:
+			err = r.indexCVEs(ctx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reindex %s phase: %w", phase, err)
+		}
+
+		// A finished phase is checkpointed as the start of the next one
+		next := reindexPhaseDone
+		if i+1 < len(reindexPhases) {
+			next = reindexPhases[i+1]
+		}
+		r.cp = reindexCheckpoint{Phase: next, Nodes: r.cp.Nodes, Edges: r.cp.Edges}
+		if err := r.checkpoint(); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Graph reindex phase %s complete: %d nodes, %d edges in graph", phase, s.graph.NodeCount(), s.graph.EdgeCount())
+	}
+	s.logger.Info("Graph reindex complete: %d nodes, %d edges", s.graph.NodeCount(), s.graph.EdgeCount())
+
+	return map[string]interface{}{
+		"nodes":       r.cp.Nodes,
+		"edges":       r.cp.Edges,
+		"resumed":     resumed,
+		"total_nodes": s.graph.NodeCount(),
+		"total_edges": s.graph.EdgeCount(),
+	}, nil
+}
+
+// phaseIndex returns the position of phase in reindexPhases
+func phaseIndex(phase string) int {
+	for i, p := range reindexPhases {
+		if p == phase {
+			return i
+		}
+	}
+	return 0
+}
+
+// loadReindexCheckpoint returns the checkpoint of the last reindex, if any
+func (s *AnalysisService) loadReindexCheckpoint() (reindexCheckpoint, bool) {
+	var cp reindexCheckpoint
+	stored, err := s.graphStore.GetCheckpoint(reindexCheckpointID)
+	if err != nil {
+		return cp, false
+	}
+	data, err := subprocess.MarshalFast(stored["data"])
+	if err != nil || subprocess.UnmarshalFast(data, &cp) != nil {
+		s.logger.Warn("Ignoring unreadable graph reindex checkpoint")
+		return cp, false
+	}
+	return cp, true
+}
+
+// checkpoint saves the graph, then the reindex progress, so a resumed reindex
+// starts from a graph holding everything the checkpoint counts
+func (r *reindex) checkpoint() error {
+	r.s.persistMu.Lock()
+	_, err := r.s.saveGraph()
+	r.s.persistMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to save graph at reindex checkpoint: %w", err)
+	}
+	err = r.s.graphStore.SaveCheckpoint(reindexCheckpointID, map[string]interface{}{
+		"phase":  r.cp.Phase,
+		"offset": r.cp.Offset,
+		"cursor": r.cp.Cursor,
+		"nodes":  r.cp.Nodes,
+		"edges":  r.cp.Edges,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save reindex checkpoint: %w", err)
+	}
+	return nil
+}
+
+// pageDone logs the progress of a phase after a page and checkpoints it
+// every reindexCheckpointPages pages; reindexGraph checkpoints its end
+func (r *reindex) pageDone(page, processed int, last bool) error {
+	r.s.logger.Info("Graph reindex %s: %d processed, %d nodes, %d edges in graph", r.cp.Phase, processed, r.s.graph.NodeCount(), r.s.graph.EdgeCount())
+	if !last && page%reindexCheckpointPages == 0 {
+		return r.checkpoint()
+	}
+	return nil
+}
+
+// addNode adds or updates a node, counting it under its data type when new
+func (r *reindex) addNode(u *urn.URN, properties map[string]interface{}) {
+	if _, exists := r.s.graph.GetNode(u); !exists {
+		r.cp.Nodes[string(u.Type)]++
+		r.b.nodesAdded.Add(1)
+	}
+	r.s.graph.AddNode(u, properties)
+}
+
+// ensureNode adds a node with only its ID unless it already exists, so a
+// reference does not overwrite the properties of the node it points to
+func (r *reindex) ensureNode(u *urn.URN) {
+	if _, exists := r.s.graph.GetNode(u); !exists {
+		r.addNode(u, map[string]interface{}{"id": u.AtomicID})
+	}
+}
+
+// link adds an edge unless it is already in the graph, counting it under
+// edgeName
+func (r *reindex) link(from, to *urn.URN, edgeType graph.EdgeType, edgeName string) {
+	r.ensureNode(to)
+	if hasEdge(r.s.graph, from, to, edgeType) {
+		return
+	}
+	if err := r.s.graph.AddEdge(from, to, edgeType, nil); err == nil {
+		r.cp.Edges[edgeName]++
+		r.b.edgesAdded.Add(1)
+	}
+}
+
+// listPage queries one page of a data type from the local service
+func listPage(ctx context.Context, list func(ctx context.Context, params interface{}) (*subprocess.Message, error), params map[string]interface{}, what string, page interface{}) error {
+	resp, err := list(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to query %s data: %w", what, err)
+	}
+	if resp.Type == subprocess.MessageTypeError {
+		return fmt.Errorf("failed to query %s data: %s", what, resp.Error)
+	}
+	if err := subprocess.UnmarshalFast(resp.Payload, page); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", what, err)
+	}
+	return nil
+}
+
+// normalizeID prefixes a bare number such as a CAPEC's related weakness "79"
+// with the catalog prefix, giving "CWE-79"
+func normalizeID(id, prefix string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if id == "" || strings.HasPrefix(id, prefix) {
+		return id
+	}
+	return prefix + id
+}
+
+// indexCWEs adds CWE nodes with related_to edges to their related CAPECs
+func (r *reindex) indexCWEs(ctx context.Context) error {
+	for page := 1; ; page++ {
+		var resp struct {
+			CWEs []struct {
+				ID                    string   `json:"ID"`
+				Name                  string   `json:"Name"`
+				Abstraction           string   `json:"Abstraction"`
+				Status                string   `json:"Status"`
+				RelatedAttackPatterns []string `json:"RelatedAttackPatterns"`
+			} `json:"cwes"`
+			Total int `json:"total"`
+		}
+		if err := listPage(ctx, r.s.listCWEs, map[string]interface{}{"offset": r.cp.Offset, "limit": reindexPageSize}, "CWE", &resp); err != nil {
+			return err
+		}
+		for _, c := range resp.CWEs {
+			cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, normalizeID(c.ID, "CWE-"))
+			if err != nil {
+				r.s.logger.Warn("Invalid CWE ID: %s", c.ID)
+				continue
+			}
+			r.addNode(cweURN, map[string]interface{}{
+				"id":          cweURN.AtomicID,
+				"name":        c.Name,
+				"abstraction": c.Abstraction,
+				"status":      c.Status,
+			})
+			for _, capecID := range c.RelatedAttackPatterns {
+				if capecURN, err := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, normalizeID(capecID, "CAPEC-")); err == nil {
+					r.link(cweURN, capecURN, graph.EdgeTypeRelatedTo, reindexEdgeCWECAPEC)
+				}
+			}
+		}
+		r.cp.Offset += len(resp.CWEs)
+		last := len(resp.CWEs) == 0 || r.cp.Offset >= resp.Total
+		if err := r.pageDone(page, r.cp.Offset, last); err != nil || last {
+			return err
+		}
+	}
+}
+
+// indexCAPECs adds CAPEC nodes with related_to edges from the CWEs they
+// relate to and references edges to the ATT&CK techniques they map to
+func (r *reindex) indexCAPECs(ctx context.Context) error {
+	for page := 1; ; page++ {
+		var resp struct {
+			CAPECs []struct {
+				ID               string   `json:"id"`
+				Name             string   `json:"name"`
+				Likelihood       string   `json:"likelihood"`
+				TypicalSeverity  string   `json:"typical_severity"`
+				Weaknesses       []string `json:"weaknesses"`
+				AttackTechniques []struct {
+					TechniqueID string `json:"technique_id"`
+				} `json:"attack_techniques"`
+			} `json:"capecs"`
+			Total int `json:"total"`
+		}
+		if err := listPage(ctx, r.s.listCAPECs, map[string]interface{}{"offset": r.cp.Offset, "limit": reindexPageSize}, "CAPEC", &resp); err != nil {
+			return err
+		}
+		for _, c := range resp.CAPECs {
+			capecURN, err := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, c.ID)
+			if err != nil {
+				r.s.logger.Warn("Invalid CAPEC ID: %s", c.ID)
+				continue
+			}
+			r.addNode(capecURN, map[string]interface{}{
+				"id":               c.ID,
+				"name":             c.Name,
+				"likelihood":       c.Likelihood,
+				"typical_severity": c.TypicalSeverity,
+			})
+			for _, cweID := range c.Weaknesses {
+				cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, normalizeID(cweID, "CWE-"))
+				if err != nil {
+					continue
+				}
+				r.ensureNode(cweURN)
+				r.link(cweURN, capecURN, graph.EdgeTypeRelatedTo, reindexEdgeCWECAPEC)
+			}
+			for _, t := range c.AttackTechniques {
+				if techniqueURN, err := urn.New(urn.ProviderMITRE, urn.TypeATTACK, t.TechniqueID); err == nil {
+					r.link(capecURN, techniqueURN, graph.EdgeTypeReferences, reindexEdgeCAPECATTACK)
+				}
+			}
+		}
+		r.cp.Offset += len(resp.CAPECs)
+		last := len(resp.CAPECs) == 0 || r.cp.Offset >= resp.Total
+		if err := r.pageDone(page, r.cp.Offset, last); err != nil || last {
+			return err
+		}
+	}
+}
+
+// indexATTACKs adds ATT&CK technique nodes, including the techniques no
+// CAPEC maps to
+func (r *reindex) indexATTACKs(ctx context.Context) error {
+	for page := 1; ; page++ {
+		var resp struct {
+			Techniques []struct {
+				ID         string `json:"id"`
+				Name       string `json:"name"`
+				Domain     string `json:"domain"`
+				Revoked    bool   `json:"revoked"`
+				Deprecated bool   `json:"deprecated"`
+			} `json:"techniques"`
+			Total int `json:"total"`
+		}
+		if err := listPage(ctx, r.s.listATTACKs, map[string]interface{}{"offset": r.cp.Offset, "limit": reindexPageSize}, "ATT&CK", &resp); err != nil {
+			return err
+		}
+		for _, t := range resp.Techniques {
+			techniqueURN, err := urn.New(urn.ProviderMITRE, urn.TypeATTACK, t.ID)
+			if err != nil {
+				r.s.logger.Warn("Invalid ATT&CK technique ID: %s", t.ID)
+				continue
+			}
+			r.addNode(techniqueURN, map[string]interface{}{
+				"id":         t.ID,
+				"name":       t.Name,
+				"domain":     t.Domain,
+				"revoked":    t.Revoked,
+				"deprecated": t.Deprecated,
+			})
+		}
+		r.cp.Offset += len(resp.Techniques)
+		last := len(resp.Techniques) == 0 || r.cp.Offset >= resp.Total
+		if err := r.pageDone(page, r.cp.Offset, last); err != nil || last {
+			return err
+		}
+	}
+}
+
+// indexCVEs adds CVE nodes with references edges to their CWEs. CVEs are
+// paged with a cursor, so CVEs stored during the reindex do not shift pages.
+func (r *reindex) indexCVEs(ctx context.Context) error {
+	processed := 0
+	for page := 1; ; page++ {
+		var resp struct {
+			CVEs []struct {
+				ID           string  `json:"id"`
+				Published    string  `json:"published"`
+				LastModified string  `json:"lastModified"`
+				Status       string  `json:"status"`
+				BaseScore    float64 `json:"baseScore"`
+				BaseSeverity string  `json:"baseSeverity"`
+				Weaknesses   []struct {
+					Description []struct {
+						Value string `json:"value"`
+					} `json:"description"`
+				} `json:"weaknesses"`
+			} `json:"cves"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := listPage(ctx, r.s.listCVEs, map[string]interface{}{"cursor": r.cp.Cursor, "limit": reindexPageSize}, "CVE", &resp); err != nil {
+			return err
+		}
+		for _, c := range resp.CVEs {
+			cveURN, err := urn.New(urn.ProviderNVD, urn.TypeCVE, c.ID)
+			if err != nil {
+				r.s.logger.Warn("Invalid CVE ID: %s", c.ID)
+				continue
+			}
+			// A summary only: the full CVE stays in the local service
+			r.addNode(cveURN, map[string]interface{}{
+				"id":           c.ID,
+				"published":    c.Published,
+				"lastModified": c.LastModified,
+				"status":       c.Status,
+				"baseScore":    c.BaseScore,
+				"baseSeverity": c.BaseSeverity,
+			})
+			for _, w := range c.Weaknesses {
+				for _, d := range w.Description {
+					// NVD-CWE-Other and NVD-CWE-noinfo name no weakness
+					if !strings.HasPrefix(d.Value, "CWE-") {
+						continue
+					}
+					if cweURN, err := urn.New(urn.ProviderMITRE, urn.TypeCWE, d.Value); err == nil {
+						r.link(cveURN, cweURN, graph.EdgeTypeReferences, reindexEdgeCVECWE)
+					}
+				}
+			}
+		}
+		processed += len(resp.CVEs)
+		r.cp.Cursor = resp.NextCursor
+		last := resp.NextCursor == ""
+		if err := r.pageDone(page, processed, last); err != nil || last {
+			return err
+		}
+	}
+}
@@ -15,6 +15,15 @@ var (
 	buildOptimizerBatch   = "1"    // Default batch size
 	buildOptimizerFlush   = "10"   // Flush interval in milliseconds
 	buildOptimizerPolicy  = "drop" // Offer policy: drop, wait, reject
+	buildOptimizerDedup   = "1024" // Recent requests remembered to drop duplicates; 0 disables
+
+	// Message statistics persistence
+	buildStatsDBPath = "broker_stats.db" // bbolt file holding the lifetime message stats
+	buildStatsFlush  = "30"              // Save interval in seconds; 0 saves on shutdown only
+
+	// Per-process CPU and memory history
+	buildProcMetricsInterval  = "30"  // Sampling interval in seconds
+	buildProcMetricsRetention = "120" // Samples kept per process
 )
 
 // buildOptimizerBufferValue returns the buffer capacity from build-time config
@@ -49,6 +58,38 @@ func buildOptimizerFlushValue() time.Duration {
 	return 10 * time.Millisecond // default
 }
 
+// buildOptimizerDedupValue returns the duplicate request window from build-time config
+func buildOptimizerDedupValue() int {
+	if val, err := strconv.Atoi(buildOptimizerDedup); err == nil && val >= 0 {
+		return val
+	}
+	return 1024 // default
+}
+
+// buildStatsFlushValue returns the message stats save interval from build-time config
+func buildStatsFlushValue() time.Duration {
+	if val, err := strconv.Atoi(buildStatsFlush); err == nil && val >= 0 {
+		return time.Duration(val) * time.Second
+	}
+	return 30 * time.Second // default
+}
+
+// buildProcMetricsIntervalValue returns the process metrics sampling interval from build-time config
+func buildProcMetricsIntervalValue() time.Duration {
+	if val, err := strconv.Atoi(buildProcMetricsInterval); err == nil && val > 0 {
+		return time.Duration(val) * time.Second
+	}
+	return 30 * time.Second // default
+}
+
+// buildProcMetricsRetentionValue returns the samples kept per process from build-time config
+func buildProcMetricsRetentionValue() int {
+	if val, err := strconv.Atoi(buildProcMetricsRetention); err == nil && val > 0 {
+		return val
+	}
+	return 120 // default
+}
+
 // buildOptimizerPolicyValue returns the offer policy from build-time config
 func buildOptimizerPolicyValue() string {
 	if buildOptimizerPolicy == "" {
@@ -8,6 +8,10 @@ const (
 	LogMsgErrorLoadingProcesses    = "Error loading processes from config: %v"
 	LogMsgAdaptiveOptEnabled       = "Adaptive optimization enabled (freq=%v)"
 	LogMsgOptimizerStarted         = "Optimizer started: buffer=%v workers=%v policy=%s batch=%d flush=%v"
+	LogMsgDedupWindow              = "Duplicate request window: %d requests"
+	LogMsgStatsPersistence         = "Persisting message stats to %s every %v"
+	LogMsgStatsPersistenceFailed   = "Message stats persistence disabled: %v"
+	LogMsgProcessMetrics           = "Keeping %d process metrics samples per process, every %v"
 	LogMsgBrokerStarted            = "Broker started, managing %d processes"
 	LogMsgErrorProcessingMessage   = "Error processing broker message - Message ID: %s, Source: %s, Target: %s, Error: %v"
 	LogMsgSuccessProcessingMessage = "Successfully processed broker message - Message ID: %s, Source: %s, Target: %s"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cyw0ng95/v2e/cmd/v2broker/metrics"
 	"github.com/cyw0ng95/v2e/cmd/v2broker/mq"
@@ -59,6 +60,19 @@ type Broker struct {
 	transportManager *transport.TransportManager
 	// permitManager manages the global worker permit pool (Phase 2 UEE)
 	permitManager *permits.PermitManager
+	// dedup drops requests delivered more than once (see SetDedupWindow)
+	dedup atomic.Pointer[dedupCache]
+	// healthErrors holds the last failed RPCHealthCheck ping per process
+	healthErrors map[string]healthError
+	healthMu     sync.Mutex
+	// statsStore persists the lifetime message stats, if enabled (see
+	// EnableStatsPersistence)
+	statsStore *statsStore
+	// inflight tracks the routed requests RPCCancelRPC can cancel
+	inflight *inflightRoutes
+	// processMetrics holds the recent CPU and memory samples per process, if
+	// enabled (see EnableProcessMetrics)
+	processMetrics atomic.Pointer[processMetricsHistory]
 }
 
 // NewBroker creates a new Broker instance.
@@ -76,9 +90,13 @@ func NewBroker() *Broker {
 		rpcEndpoints:     make(map[string][]string),
 		pendingRequests:  make(map[string]*PendingRequest),
 		correlationSeq:   0,
+		healthErrors:     make(map[string]healthError),
 		transportManager: transport.NewTransportManager(),
+		inflight:         newInflightRoutes(),
 	}
 
+	b.dedup.Store(newDedupCache(0))
+
 	// Set transport error handler to log warnings
 	b.transportManager.SetTransportErrorHandler(func(err error) {
 		if b.logger != nil {
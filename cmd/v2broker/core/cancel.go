@@ -0,0 +1,142 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	subprocess "github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+const (
+	// maxInflightRoutes caps the requests tracked for RPCCancelRPC
+	maxInflightRoutes = 10000
+	// inflightRouteTTL is how long a request without a response is tracked
+	// before it may be dropped to make room
+	inflightRouteTTL = 10 * time.Minute
+)
+
+// inflightRoute is a request routed to a process that has not answered yet
+type inflightRoute struct {
+	target string
+	at     time.Time
+}
+
+// inflightRoutes tracks the requests routed between processes until their
+// response passes back through the broker, so the sender can cancel them.
+// Requests are keyed by sender and correlation ID, like in the dedup cache.
+type inflightRoutes struct {
+	mu     sync.Mutex
+	routes map[dedupKey]inflightRoute
+}
+
+func newInflightRoutes() *inflightRoutes {
+	return &inflightRoutes{routes: make(map[dedupKey]inflightRoute)}
+}
+
+// start records a request routed to a process. When the table is full the
+// requests older than inflightRouteTTL are dropped; if none is, the request is
+// not tracked and simply cannot be cancelled.
+func (r *inflightRoutes) start(msg *proc.Message) {
+	if msg.Type != proc.MessageTypeRequest || msg.CorrelationID == "" {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.routes) >= maxInflightRoutes {
+		for key, route := range r.routes {
+			if now.Sub(route.at) > inflightRouteTTL {
+				delete(r.routes, key)
+			}
+		}
+		if len(r.routes) >= maxInflightRoutes {
+			return
+		}
+	}
+	r.routes[dedupKey{source: msg.Source, correlationID: msg.CorrelationID}] = inflightRoute{target: msg.Target, at: now}
+}
+
+// finish forgets the request answered by msg, a response or error sent back
+// by the process the request was routed to
+func (r *inflightRoutes) finish(msg *proc.Message) {
+	if (msg.Type != proc.MessageTypeResponse && msg.Type != proc.MessageTypeError) || msg.CorrelationID == "" {
+		return
+	}
+	key := dedupKey{source: msg.Target, correlationID: msg.CorrelationID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if route, ok := r.routes[key]; ok && route.target == msg.Source {
+		delete(r.routes, key)
+	}
+}
+
+// take removes and returns the in-flight request source sent with
+// correlationID
+func (r *inflightRoutes) take(source, correlationID string) (inflightRoute, bool) {
+	key := dedupKey{source: source, correlationID: correlationID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	route, ok := r.routes[key]
+	if ok {
+		delete(r.routes, key)
+	}
+	return route, ok
+}
+
+// len returns the number of requests tracked
+func (r *inflightRoutes) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.routes)
+}
+
+// InflightRequestCount returns the number of routed requests still awaiting
+// a response, i.e. the requests RPCCancelRPC can cancel.
+func (b *Broker) InflightRequestCount() int {
+	return b.inflight.len()
+}
+
+// HandleRPCCancelRPC handles the RPCCancelRPC RPC request. It cancels a
+// request the caller sent through the broker that has not been answered yet:
+// the built-in RPCCancelRPC is forwarded to the process handling it, which
+// cancels the handler's context. Only the sender of a request can cancel it.
+// The handler's reply to the cancelled request, usually a context canceled
+// error, is still routed back.
+func (b *Broker) HandleRPCCancelRPC(reqMsg *proc.Message) (*proc.Message, error) {
+	var params subprocess.CancelRPCRequest
+	if len(reqMsg.Payload) > 0 {
+		if err := json.Unmarshal(reqMsg.Payload, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse request parameters: %w", err)
+		}
+	}
+	if params.CorrelationID == "" {
+		return nil, fmt.Errorf("correlation_id is required")
+	}
+
+	route, ok := b.inflight.take(reqMsg.Source, params.CorrelationID)
+	if !ok {
+		return nil, fmt.Errorf("no in-flight request %s from %s", params.CorrelationID, reqMsg.Source)
+	}
+
+	cancelMsg, err := proc.NewRequestMessage(subprocess.RPCCancelRPC, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cancel message: %w", err)
+	}
+	cancelMsg.Source = "broker"
+	cancelMsg.Target = route.target
+	cancelMsg.CorrelationID = b.GenerateCorrelationID()
+	cancelMsg.TraceID = reqMsg.TraceID
+	if err := b.SendToProcess(route.target, cancelMsg); err != nil {
+		return nil, fmt.Errorf("failed to send cancel to %s: %w", route.target, err)
+	}
+
+	b.logger.Info("Cancelled request %s from %s to %s", params.CorrelationID, reqMsg.Source, route.target)
+	return b.newBrokerResponse(reqMsg, map[string]interface{}{
+		"correlation_id": params.CorrelationID,
+		"target":         route.target,
+		"cancelled":      true,
+	})
+}
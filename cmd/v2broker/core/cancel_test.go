@@ -0,0 +1,93 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/cmd/v2broker/transport"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	subprocess "github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// last returns the last message sent to the process, if any
+func (r *recordingTransport) last() *proc.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.msgs) == 0 {
+		return nil
+	}
+	return r.msgs[len(r.msgs)-1]
+}
+
+func TestHandleRPCCancelRPC(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandleRPCCancelRPC", nil, func(t *testing.T, tx *gorm.DB) {
+		b := NewBroker()
+		defer b.Shutdown()
+		tm := transport.NewTransportManager()
+		remote, access := &recordingTransport{}, &recordingTransport{}
+		tm.RegisterTransport("remote", remote)
+		tm.RegisterTransport("access", access)
+		b.transportManager = tm
+
+		route := func(source, target, id, correlationID string) {
+			t.Helper()
+			msg := &proc.Message{Type: proc.MessageTypeRequest, ID: id, Source: source, Target: target, CorrelationID: correlationID}
+			if err := b.RouteMessage(msg, source); err != nil {
+				t.Fatalf("RouteMessage failed: %v", err)
+			}
+		}
+		cancel := func(source, correlationID string) (*proc.Message, error) {
+			req, _ := proc.NewRequestMessage("RPCCancelRPC", map[string]string{"correlation_id": correlationID})
+			req.Source = source
+			return b.HandleRPCCancelRPC(req)
+		}
+
+		route("access", "remote", "RPCFetchCVEs", "corr-1")
+		route("access", "remote", "RPCFetchCVEs", "corr-2")
+		if n := b.InflightRequestCount(); n != 2 {
+			t.Fatalf("Expected 2 in-flight requests, got %d", n)
+		}
+
+		// Only the sender can cancel its request
+		if _, err := cancel("meta", "corr-1"); err == nil {
+			t.Error("Expected another process not to cancel the request")
+		}
+		resp, err := cancel("access", "corr-1")
+		if err != nil {
+			t.Fatalf("HandleRPCCancelRPC failed: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(resp.Payload, &result)
+		if result["cancelled"] != true || result["target"] != "remote" || resp.Target != "access" {
+			t.Errorf("Unexpected cancel response %+v %s", result, resp.Payload)
+		}
+		forwarded := remote.last()
+		var params subprocess.CancelRPCRequest
+		if forwarded == nil || forwarded.ID != subprocess.RPCCancelRPC || forwarded.Source != "broker" {
+			t.Fatalf("Expected RPCCancelRPC forwarded to remote, got %+v", forwarded)
+		}
+		if json.Unmarshal(forwarded.Payload, &params); params.CorrelationID != "corr-1" {
+			t.Errorf("Expected the cancel to name corr-1, got %s", forwarded.Payload)
+		}
+		if _, err := cancel("access", "corr-1"); err == nil {
+			t.Error("Expected a cancelled request not to be cancelled twice")
+		}
+
+		// A response from the target ends the request
+		respMsg := &proc.Message{Type: proc.MessageTypeResponse, ID: "RPCFetchCVEs", Source: "remote", Target: "access", CorrelationID: "corr-2"}
+		if err := b.RouteMessage(respMsg, "remote"); err != nil {
+			t.Fatalf("RouteMessage failed: %v", err)
+		}
+		if access.last() != respMsg || b.InflightRequestCount() != 0 {
+			t.Errorf("Expected the response delivered and the request forgotten, %d left", b.InflightRequestCount())
+		}
+		if _, err := cancel("access", "corr-2"); err == nil {
+			t.Error("Expected an answered request not to be cancellable")
+		}
+		if _, err := cancel("access", ""); err == nil {
+			t.Error("Expected an error without correlation_id")
+		}
+	})
+}
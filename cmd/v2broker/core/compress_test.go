@@ -0,0 +1,66 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/cmd/v2broker/transport"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestRouteMessage_CompressedPayload(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRouteMessage_CompressedPayload", nil, func(t *testing.T, tx *gorm.DB) {
+		b := NewBroker()
+		defer b.Shutdown()
+		tm := transport.NewTransportManager()
+		remote, meta := &recordingTransport{}, &recordingTransport{}
+		tm.RegisterTransport("remote", remote)
+		tm.RegisterTransport("meta", meta)
+		b.transportManager = tm
+
+		// A compressed response is forwarded as is
+		payload := []byte(`{"vulnerabilities":"` + strings.Repeat("CVE-2024-0001 ", proc.DefaultCompressThreshold) + `"}`)
+		resp, err := proc.CompressPayload(&proc.Message{Type: proc.MessageTypeResponse, ID: "RPCFetchCVEs", Source: "remote", Target: "meta", CorrelationID: "corr-1", Payload: payload}, proc.DefaultCompressThreshold)
+		if err != nil || !resp.Compressed {
+			t.Fatalf("CompressPayload = %+v, %v", resp, err)
+		}
+		wire := string(resp.Payload)
+		if err := b.RouteMessage(resp, "remote"); err != nil {
+			t.Fatalf("RouteMessage failed: %v", err)
+		}
+		if got := meta.last(); got == nil || !got.Compressed || string(got.Payload) != wire {
+			t.Error("Expected the compressed payload forwarded untouched")
+		}
+
+		// A compressed request to the broker itself is read decompressed
+		route := &proc.Message{Type: proc.MessageTypeRequest, ID: "RPCFetchCVEs", Source: "meta", Target: "remote", CorrelationID: "corr-2"}
+		if err := b.RouteMessage(route, "meta"); err != nil {
+			t.Fatalf("RouteMessage failed: %v", err)
+		}
+		cancel, _ := proc.NewRequestMessage("RPCCancelRPC", map[string]string{"correlation_id": "corr-2", "reason": strings.Repeat("timed out ", 100)})
+		cancel.Source, cancel.CorrelationID = "meta", "corr-3"
+		if cancel, err = proc.CompressPayload(cancel, 1); err != nil || !cancel.Compressed {
+			t.Fatalf("CompressPayload = %+v, %v", cancel, err)
+		}
+		if err := b.ProcessMessage(cancel); err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		reply := meta.last()
+		var result map[string]interface{}
+		if reply == nil || reply.Type != proc.MessageTypeResponse || json.Unmarshal(reply.Payload, &result) != nil || result["cancelled"] != true {
+			t.Errorf("Expected the compressed cancel request handled, got %+v", reply)
+		}
+
+		// A corrupt compressed payload is answered with an error
+		bad := &proc.Message{Type: proc.MessageTypeRequest, ID: "RPCCancelRPC", Source: "meta", CorrelationID: "corr-4", Payload: []byte(`"not gzip"`), Compressed: true}
+		if err := b.ProcessMessage(bad); err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if reply := meta.last(); reply == nil || reply.Type != proc.MessageTypeError || !strings.Contains(reply.Error, "invalid compressed payload") {
+			t.Errorf("Expected an error reply, got %+v", reply)
+		}
+	})
+}
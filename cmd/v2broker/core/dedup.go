@@ -0,0 +1,80 @@
+package core
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+)
+
+// dedupKey identifies a request by its sender and correlation ID
+type dedupKey struct {
+	source        string
+	correlationID string
+}
+
+// dedupCache remembers the most recent requests routed by the broker so a
+// request delivered twice, e.g. by a sender retrying after a lost ack, is
+// only routed once. It is an LRU of size window; a window of zero disables it.
+type dedupCache struct {
+	mu      sync.Mutex
+	window  int
+	order   *list.List // front is the most recently seen key
+	entries map[dedupKey]*list.Element
+	dropped atomic.Int64
+}
+
+func newDedupCache(window int) *dedupCache {
+	if window < 0 {
+		window = 0
+	}
+	return &dedupCache{
+		window:  window,
+		order:   list.New(),
+		entries: make(map[dedupKey]*list.Element),
+	}
+}
+
+// seen records msg and reports whether a request with the same source and
+// correlation ID is already in the window. Only requests with a correlation
+// ID are tracked: responses are matched to their pending request once
+// anyway, and events carry no identity to compare.
+func (c *dedupCache) seen(msg *proc.Message) bool {
+	if c.window == 0 || msg.Type != proc.MessageTypeRequest || msg.CorrelationID == "" {
+		return false
+	}
+	key := dedupKey{source: msg.Source, correlationID: msg.CorrelationID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.dropped.Add(1)
+		return true
+	}
+	c.entries[key] = c.order.PushFront(key)
+	if c.order.Len() > c.window {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(dedupKey))
+	}
+	return false
+}
+
+// stats returns the window and the number of duplicates dropped so far
+func (c *dedupCache) stats() proc.DedupStats {
+	return proc.DedupStats{Window: c.window, Dropped: c.dropped.Load()}
+}
+
+// SetDedupWindow sets how many recent requests the broker remembers to drop
+// duplicates of (see dedupCache); zero disables deduplication. Requests seen
+// under the previous window are forgotten, while the dropped count is kept.
+func (b *Broker) SetDedupWindow(window int) {
+	cache := newDedupCache(window)
+	if old := b.dedup.Load(); old != nil {
+		cache.dropped.Store(old.dropped.Load())
+	}
+	b.dedup.Store(cache)
+}
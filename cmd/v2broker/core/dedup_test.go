@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestRouteMessage_DropsDuplicateRequests(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRouteMessage_DropsDuplicateRequests", nil, func(t *testing.T, tx *gorm.DB) {
+		broker := NewBroker()
+		defer broker.Shutdown()
+
+		routed := func() int {
+			n := 0
+			for {
+				select {
+				case <-broker.MessageChannel():
+					n++
+				default:
+					return n
+				}
+			}
+		}
+		route := func(msgType proc.MessageType, source, correlationID string) {
+			msg := &proc.Message{Type: msgType, ID: "RPCImport", CorrelationID: correlationID}
+			if err := broker.RouteMessage(msg, source); err != nil {
+				t.Fatalf("RouteMessage failed: %v", err)
+			}
+		}
+
+		// Deduplication is off until a window is set
+		route(proc.MessageTypeRequest, "meta", "corr-1")
+		route(proc.MessageTypeRequest, "meta", "corr-1")
+		if n := routed(); n != 2 {
+			t.Fatalf("Expected both requests routed without a window, got %d", n)
+		}
+
+		broker.SetDedupWindow(2)
+		route(proc.MessageTypeRequest, "meta", "corr-1")
+		route(proc.MessageTypeRequest, "meta", "corr-1")
+		// Same correlation ID from another sender, a response and a request
+		// without correlation ID are not duplicates
+		route(proc.MessageTypeRequest, "access", "corr-1")
+		route(proc.MessageTypeResponse, "meta", "corr-1")
+		route(proc.MessageTypeRequest, "meta", "")
+		route(proc.MessageTypeRequest, "meta", "")
+		if n := routed(); n != 5 {
+			t.Errorf("Expected 5 of 6 messages routed, got %d", n)
+		}
+
+		// corr-1 from meta falls out of the window of two
+		route(proc.MessageTypeRequest, "meta", "corr-2")
+		route(proc.MessageTypeRequest, "meta", "corr-3")
+		route(proc.MessageTypeRequest, "meta", "corr-1")
+		if n := routed(); n != 3 {
+			t.Errorf("Expected requests outside the window routed again, got %d", n)
+		}
+
+		resp, err := broker.HandleRPCGetMessageStats(&proc.Message{Type: proc.MessageTypeRequest, ID: "RPCGetMessageStats", Source: "sysmon"})
+		if err != nil {
+			t.Fatalf("HandleRPCGetMessageStats failed: %v", err)
+		}
+		var stats proc.MessageStatsResponse
+		if err := json.Unmarshal(resp.Payload, &stats); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+		if stats.Dedup != (proc.DedupStats{Window: 2, Dropped: 1}) {
+			t.Errorf("Expected one duplicate dropped in a window of 2, got %+v", stats.Dedup)
+		}
+
+		// Changing the window keeps the count and disabling it lets
+		// duplicates through
+		broker.SetDedupWindow(0)
+		route(proc.MessageTypeRequest, "meta", "corr-3")
+		if n := routed(); n != 1 {
+			t.Errorf("Expected the request routed with deduplication disabled, got %d", n)
+		}
+		if got := broker.dedup.Load().stats(); got != (proc.DedupStats{Window: 0, Dropped: 1}) {
+			t.Errorf("Expected the dropped count kept, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/cmd/v2broker/transport"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// connectFragmentingProcess registers a UDS transport for id whose sends are
+// fragmented at maxSize, starts the broker's reader on it and dials it as a
+// subprocess fragmenting at the same size
+func connectFragmentingProcess(t *testing.T, b *Broker, id string, maxSize int) *subprocess.Subprocess {
+	t.Helper()
+	socketPath, err := b.transportManager.RegisterUDSTransport(id, true)
+	if err != nil {
+		t.Fatalf("RegisterUDSTransport(%s) failed: %v", id, err)
+	}
+	tr, err := b.transportManager.GetTransport(id)
+	if err != nil {
+		t.Fatalf("GetTransport(%s) failed: %v", id, err)
+	}
+	tr.(*transport.UDSTransport).SetMaxFragmentSize(maxSize)
+
+	b.mu.Lock()
+	b.processes[id] = NewTestProcess(id, ProcessStatusRunning)
+	b.mu.Unlock()
+	b.wg.Add(1)
+	go b.readUDSMessages(id, tr)
+
+	sp, err := subprocess.DialUDS(id, socketPath)
+	if err != nil {
+		t.Fatalf("DialUDS(%s) failed: %v", id, err)
+	}
+	sp.SetMaxFragmentSize(maxSize)
+	sp.SetCompressThreshold(0)
+	return sp
+}
+
+func TestBroker_RoutesLargeMessageBetweenSubprocesses(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestBroker_RoutesLargeMessageBetweenSubprocesses", nil, func(t *testing.T, tx *gorm.DB) {
+		dir, err := os.MkdirTemp("", "v2efrag")
+		if err != nil {
+			t.Fatalf("MkdirTemp failed: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		b := NewBroker()
+		tm := transport.NewTransportManager()
+		tm.SetUdsBasePath(filepath.Join(dir, "v2e"))
+		b.transportManager = tm
+
+		// Both legs carry many fragments: local to the broker, and the
+		// broker on to access
+		const maxSize = 4096
+		big := strings.Repeat("0123456789abcdef", 64*1024)
+		local := connectFragmentingProcess(t, b, "local", maxSize)
+		access := connectFragmentingProcess(t, b, "access", maxSize)
+		local.RegisterHandler("RPCGetBig", func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			return subprocess.NewSuccessResponse(msg, map[string]string{"data": big})
+		})
+		responses := make(chan *subprocess.Message, 1)
+		access.RegisterHandler(string(subprocess.MessageTypeResponse), func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+			responses <- msg
+			return nil, nil
+		})
+		go local.Run()
+		go access.Run()
+		defer func() {
+			_ = b.Kill("local")
+			_ = b.Kill("access")
+			tm.CloseAll()
+			_ = local.CloseTransport()
+			_ = access.CloseTransport()
+			b.Shutdown()
+		}()
+
+		if err := access.SendMessage(&subprocess.Message{
+			Type:          subprocess.MessageTypeRequest,
+			ID:            "RPCGetBig",
+			Target:        "local",
+			CorrelationID: "corr-big",
+		}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+
+		select {
+		case resp := <-responses:
+			var result map[string]string
+			if err := subprocess.UnmarshalFast(resp.Payload, &result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.CorrelationID != "corr-big" || result["data"] != big {
+				t.Fatalf("Expected the whole payload for corr-big, got %d bytes for %q", len(result["data"]), resp.CorrelationID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for the large response")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	subprocess "github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// DefaultHealthCheckTimeout bounds how long RPCHealthCheck waits for each
+// process to answer its ping
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// healthError is the last failed ping of a process
+type healthError struct {
+	message string
+	at      time.Time
+}
+
+// HandleRPCHealthCheck handles the RPCHealthCheck RPC request. It pings every
+// registered process concurrently with the built-in RPCPing and reports
+// whether it answered and how long it took. A process that is not running is
+// reported unhealthy without a ping, and one that does not answer within the
+// timeout (timeout_ms, DefaultHealthCheckTimeout by default) is reported
+// unhealthy rather than holding up the others. The last ping error of each
+// process is kept across checks.
+func (b *Broker) HandleRPCHealthCheck(reqMsg *proc.Message) (*proc.Message, error) {
+	var params struct {
+		TimeoutMs int `json:"timeout_ms"`
+	}
+	if len(reqMsg.Payload) > 0 {
+		if err := json.Unmarshal(reqMsg.Payload, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse request parameters: %w", err)
+		}
+	}
+	if params.TimeoutMs < 0 {
+		return nil, fmt.Errorf("timeout_ms must not be negative")
+	}
+	timeout := DefaultHealthCheckTimeout
+	if params.TimeoutMs > 0 {
+		timeout = time.Duration(params.TimeoutMs) * time.Millisecond
+	}
+
+	b.mu.RLock()
+	processes := make(map[string]ProcessStatus, len(b.processes))
+	for id, p := range b.processes {
+		p.mu.RLock()
+		processes[id] = p.info.Status
+		p.mu.RUnlock()
+	}
+	b.mu.RUnlock()
+
+	resp := proc.HealthCheckResponse{Healthy: true, Processes: make(map[string]proc.ProcessHealth, len(processes))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id, status := range processes {
+		wg.Add(1)
+		go func(id string, status ProcessStatus) {
+			defer wg.Done()
+			health := b.pingProcess(id, status, timeout)
+			mu.Lock()
+			resp.Processes[id] = health
+			if !health.Alive {
+				resp.Healthy = false
+			}
+			mu.Unlock()
+		}(id, status)
+	}
+	wg.Wait()
+
+	b.logger.Debug("Handled RPCHealthCheck: processes=%d healthy=%v", len(resp.Processes), resp.Healthy)
+	return b.newBrokerResponse(reqMsg, resp)
+}
+
+// pingProcess pings one process and records the outcome
+func (b *Broker) pingProcess(id string, status ProcessStatus, timeout time.Duration) proc.ProcessHealth {
+	var health proc.ProcessHealth
+	var pingErr error
+	if status != ProcessStatusRunning {
+		pingErr = fmt.Errorf("process is %s", status)
+	} else {
+		start := time.Now()
+		resp, err := b.InvokeRPC("broker", id, subprocess.RPCPing, nil, timeout)
+		health.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		switch {
+		case err != nil:
+			pingErr = err
+		case resp.Type == proc.MessageTypeError:
+			pingErr = fmt.Errorf("%s", resp.Error)
+		default:
+			health.Alive = true
+		}
+	}
+
+	b.healthMu.Lock()
+	defer b.healthMu.Unlock()
+	if pingErr != nil {
+		b.logger.Warn("Health check of process %s failed: %v", id, pingErr)
+		if b.healthErrors == nil {
+			b.healthErrors = make(map[string]healthError)
+		}
+		b.healthErrors[id] = healthError{message: pingErr.Error(), at: time.Now()}
+	}
+	if last, ok := b.healthErrors[id]; ok {
+		health.LastError = last.message
+		health.LastErrorAt = &last.at
+	}
+	return health
+}
+
+// processHealthCheck answers an RPCHealthCheck request off the routing path
+// (see ProcessMessage)
+func (b *Broker) processHealthCheck(msg *proc.Message) {
+	respMsg, err := b.HandleRPCHealthCheck(msg)
+	if err != nil {
+		respMsg = proc.NewErrorMessage(msg.ID, err)
+		respMsg.Source = "broker"
+		respMsg.Target = msg.Source
+		respMsg.TraceID = msg.TraceID
+		respMsg.Priority = msg.Priority
+		respMsg.CorrelationID = msg.CorrelationID
+	}
+	if err := b.RouteMessage(respMsg, "broker"); err != nil {
+		b.logger.Warn("Failed to route RPCHealthCheck response to %s: %v", msg.Source, err)
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestHandleRPCHealthCheck(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandleRPCHealthCheck", nil, func(t *testing.T, tx *gorm.DB) {
+		broker := NewBroker()
+		defer broker.Shutdown()
+
+		check := func(payload interface{}) proc.HealthCheckResponse {
+			t.Helper()
+			req, err := proc.NewRequestMessage("RPCHealthCheck", payload)
+			if err != nil {
+				t.Fatalf("NewRequestMessage failed: %v", err)
+			}
+			req.Source = "access"
+			resp, err := broker.HandleRPCHealthCheck(req)
+			if err != nil {
+				t.Fatalf("HandleRPCHealthCheck failed: %v", err)
+			}
+			var report proc.HealthCheckResponse
+			if err := json.Unmarshal(resp.Payload, &report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			return report
+		}
+
+		if report := check(nil); !report.Healthy || len(report.Processes) != 0 {
+			t.Errorf("Expected a healthy empty report without processes, got %+v", report)
+		}
+
+		// An exited process is not pinged; a running one without a transport
+		// fails to receive the ping
+		broker.InsertProcessForTest(NewTestProcess("exited", ProcessStatusExited))
+		broker.InsertProcessForTest(NewTestProcess("unreachable", ProcessStatusRunning))
+		report := check(map[string]int{"timeout_ms": 50})
+		if report.Healthy || len(report.Processes) != 2 {
+			t.Fatalf("Expected an unhealthy report of two processes, got %+v", report)
+		}
+		exited := report.Processes["exited"]
+		if exited.Alive || exited.LastError != "process is exited" || exited.LastErrorAt == nil || exited.LatencyMs != 0 {
+			t.Errorf("Unexpected health of exited process: %+v", exited)
+		}
+		if unreachable := report.Processes["unreachable"]; unreachable.Alive || unreachable.LastError == "" {
+			t.Errorf("Expected the unreachable process reported with its error, got %+v", unreachable)
+		}
+
+		req := &proc.Message{Type: proc.MessageTypeRequest, ID: "RPCHealthCheck", Payload: json.RawMessage(`{"timeout_ms":-1}`)}
+		if _, err := broker.HandleRPCHealthCheck(req); err == nil {
+			t.Error("Expected an error for a negative timeout")
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	subprocess "github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// DefaultSetLogLevelTimeout bounds how long RPCSetLogLevelAll waits for each
+// process to apply the new level
+const DefaultSetLogLevelTimeout = 2 * time.Second
+
+// HandleRPCSetLogLevelAll handles the RPCSetLogLevelAll RPC request. It sets
+// the log level of the broker and sends the built-in RPCSetLogLevel to every
+// running process concurrently, so verbosity can be raised during an
+// incident and lowered again without a restart. An unknown level is rejected
+// before any process is changed. A process that is not running, or does not
+// answer within the timeout, is reported failed rather than holding up the
+// others.
+func (b *Broker) HandleRPCSetLogLevelAll(reqMsg *proc.Message) (*proc.Message, error) {
+	var params struct {
+		Level string `json:"level"`
+	}
+	if len(reqMsg.Payload) > 0 {
+		if err := json.Unmarshal(reqMsg.Payload, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse request parameters: %w", err)
+		}
+	}
+	if params.Level == "" {
+		return nil, fmt.Errorf("level is required")
+	}
+	level, err := common.ParseLogLevel(params.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	logger := b.logger
+	processes := make(map[string]ProcessStatus, len(b.processes))
+	for id, p := range b.processes {
+		p.mu.RLock()
+		processes[id] = p.info.Status
+		p.mu.RUnlock()
+	}
+	b.mu.RUnlock()
+
+	resp := proc.SetLogLevelAllResponse{Level: level.String(), Processes: make(map[string]proc.ProcessLogLevel, len(processes)+1)}
+	resp.Processes["broker"] = proc.ProcessLogLevel{Previous: logger.GetLevel().String()}
+	logger.SetLevel(level)
+	common.SetLevel(level)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id, status := range processes {
+		wg.Add(1)
+		go func(id string, status ProcessStatus) {
+			defer wg.Done()
+			result := b.setProcessLogLevel(id, status, level)
+			mu.Lock()
+			resp.Processes[id] = result
+			if result.Error != "" {
+				resp.Failed++
+			}
+			mu.Unlock()
+		}(id, status)
+	}
+	wg.Wait()
+
+	logger.Info("Handled RPCSetLogLevelAll: level=%s processes=%d failed=%d", level, len(resp.Processes), resp.Failed)
+	return b.newBrokerResponse(reqMsg, resp)
+}
+
+// setProcessLogLevel sends RPCSetLogLevel to one process
+func (b *Broker) setProcessLogLevel(id string, status ProcessStatus, level common.LogLevel) proc.ProcessLogLevel {
+	if status != ProcessStatusRunning {
+		return proc.ProcessLogLevel{Error: fmt.Sprintf("process is %s", status)}
+	}
+	resp, err := b.InvokeRPC("broker", id, subprocess.RPCSetLogLevel, subprocess.SetLogLevelRequest{Level: level.String()}, DefaultSetLogLevelTimeout)
+	if err != nil {
+		b.logger.Warn("Failed to set log level of process %s: %v", id, err)
+		return proc.ProcessLogLevel{Error: err.Error()}
+	}
+	if resp.Type == proc.MessageTypeError {
+		return proc.ProcessLogLevel{Error: resp.Error}
+	}
+	var result subprocess.SetLogLevelResponse
+	if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+		return proc.ProcessLogLevel{Error: fmt.Sprintf("failed to parse response: %v", err)}
+	}
+	return proc.ProcessLogLevel{Previous: result.Previous}
+}
+
+// processSetLogLevelAll answers an RPCSetLogLevelAll request off the routing
+// path, as it waits on replies routed by the same readers (see
+// ProcessMessage)
+func (b *Broker) processSetLogLevelAll(msg *proc.Message) {
+	respMsg, err := b.HandleRPCSetLogLevelAll(msg)
+	if err != nil {
+		respMsg = brokerErrorReply(msg, err)
+	}
+	if err := b.RouteMessage(respMsg, "broker"); err != nil {
+		b.logger.Warn("Failed to route RPCSetLogLevelAll response to %s: %v", msg.Source, err)
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestHandleRPCSetLogLevelAll(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandleRPCSetLogLevelAll", nil, func(t *testing.T, tx *gorm.DB) {
+		defer common.SetLevel(common.GetLevel())
+
+		broker := NewBroker()
+		defer broker.Shutdown()
+		logger := common.NewLogger(io.Discard, "[BROKER] ", common.InfoLevel)
+		broker.SetLogger(logger)
+		broker.InsertProcessForTest(NewTestProcess("exited", ProcessStatusExited))
+		broker.InsertProcessForTest(NewTestProcess("unreachable", ProcessStatusRunning))
+
+		setLevel := func(payload string) (*proc.Message, error) {
+			req := &proc.Message{Type: proc.MessageTypeRequest, ID: "RPCSetLogLevelAll", Source: "access", Payload: json.RawMessage(payload)}
+			return broker.HandleRPCSetLogLevelAll(req)
+		}
+
+		resp, err := setLevel(`{"level":"debug"}`)
+		if err != nil {
+			t.Fatalf("HandleRPCSetLogLevelAll failed: %v", err)
+		}
+		var report proc.SetLogLevelAllResponse
+		if err := json.Unmarshal(resp.Payload, &report); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		if report.Level != "DEBUG" || len(report.Processes) != 3 || report.Failed != 2 {
+			t.Fatalf("Expected DEBUG over three processes with two failed, got %+v", report)
+		}
+		if b := report.Processes["broker"]; b.Previous != "INFO" || b.Error != "" {
+			t.Errorf("Unexpected broker result %+v", b)
+		}
+		if exited := report.Processes["exited"]; exited.Error != "process is exited" {
+			t.Errorf("Unexpected result of exited process %+v", exited)
+		}
+		if unreachable := report.Processes["unreachable"]; unreachable.Error == "" {
+			t.Errorf("Expected the unreachable process reported with its error, got %+v", unreachable)
+		}
+		if logger.GetLevel() != common.DebugLevel {
+			t.Errorf("Expected the broker logger at DEBUG, got %s", logger.GetLevel())
+		}
+
+		// Unknown levels are rejected before anything changes
+		for _, payload := range []string{`{"level":"verbose"}`, `{}`, `{"level":`} {
+			if _, err := setLevel(payload); err == nil {
+				t.Errorf("Expected an error for %s", payload)
+			}
+		}
+		if logger.GetLevel() != common.DebugLevel {
+			t.Errorf("Expected a rejected level to leave DEBUG, got %s", logger.GetLevel())
+		}
+	})
+}
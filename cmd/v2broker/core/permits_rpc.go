@@ -127,27 +127,11 @@ func (b *Broker) HandleRPCGetKernelMetrics(reqMsg *proc.Message) (*proc.Message,
 	// Get kernel metrics from optimizer
 	metrics := b.optimizer.GetKernelMetrics()
 
-	// Build response with all metrics
-	responseData := map[string]interface{}{
-		"p99_latency_ms":    metrics.P99LatencyMs,
-		"buffer_saturation": metrics.BufferSaturation,
-		"active_workers":    metrics.ActiveWorkers,
-		"total_permits":     metrics.TotalPermits,
-		"allocated_permits": metrics.AllocatedPermits,
-		"available_permits": metrics.AvailablePermits,
-		"message_rate":      metrics.MessageRate,
-		"error_rate":        metrics.ErrorRate,
-	}
-
-	respMsg, err := proc.NewResponseMessage(reqMsg.ID, responseData)
+	respMsg, err := b.newBrokerResponse(reqMsg, metrics)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create response message: %w", err)
+		return nil, err
 	}
 
-	respMsg.Source = "broker"
-	respMsg.Target = reqMsg.Source
-	respMsg.CorrelationID = reqMsg.CorrelationID
-
 	b.logger.Debug("Handled RPCGetKernelMetrics: P99=%.2fms buffer=%.1f%% permits=%d/%d",
 		metrics.P99LatencyMs, metrics.BufferSaturation,
 		metrics.AllocatedPermits, metrics.TotalPermits)
@@ -1,12 +1,14 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/cyw0ng95/v2e/cmd/v2broker/metrics"
 	"github.com/cyw0ng95/v2e/cmd/v2broker/transport"
 	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
 // MetricsEncodingUnknown is used for JSON messages in the metrics registry
@@ -29,7 +31,7 @@ func (b *Broker) SendToProcess(processID string, msg *proc.Message) error {
 			b.bus.Record(msg, true)
 			// Record in metrics registry with wire size and GOB encoding
 			b.metricsRegistry.RecordMessage(msg, true, wireSize, metrics.EncodingGOB)
-			b.logger.Debug("Sent message to process %s via transport: type=%s id=%s size=%d encoding=GOB", processID, msg.Type, msg.ID, wireSize)
+			b.logger.Debug("Sent message to process %s via transport: type=%s id=%s trace=%s size=%d encoding=GOB", processID, msg.Type, msg.ID, msg.TraceID, wireSize)
 			return nil
 		}
 		return fmt.Errorf("failed to send message to process %s via transport: %w", processID, err)
@@ -44,6 +46,17 @@ func (b *Broker) readUDSMessages(processID string, transport transport.Transport
 
 	b.logger.Debug("Starting UDS message reading for process %s", processID)
 
+	// Large responses may arrive as fragments; rebuild them before routing
+	reassembler := subprocess.NewReassembler(0)
+	sweepCtx, stopSweep := context.WithCancel(b.ctx)
+	defer stopSweep()
+	go reassembler.Sweep(sweepCtx, 5*time.Second, func(lost *proc.Message) {
+		b.logger.Warn("Dropping incomplete fragmented message from process %s: %s", processID, lost.Error)
+		if err := b.RouteMessage(lost, processID); err != nil {
+			b.logger.Warn("Failed to route fragment timeout error for process %s: %v", processID, err)
+		}
+	})
+
 	for {
 		// Check if process still exists and is running
 		b.mu.RLock()
@@ -101,6 +114,29 @@ func (b *Broker) readUDSMessages(processID string, transport transport.Transport
 			continue
 		}
 
+		// A message whose fragments did not all arrive is replaced by an
+		// error carrying its routing, so the waiting requester is told
+		if reassembler.Pending() > 0 {
+			for _, lost := range reassembler.Expire() {
+				b.logger.Warn("Dropping incomplete fragmented message from process %s: %s", processID, lost.Error)
+				if err := b.RouteMessage(lost, processID); err != nil {
+					b.logger.Warn("Failed to route fragment timeout error for process %s: %v", processID, err)
+				}
+			}
+		}
+
+		if msg.Type == proc.MessageTypeFragment {
+			complete, err := reassembler.Add(msg)
+			if err != nil {
+				b.logger.Warn("Invalid fragment from process %s: %v", processID, err)
+				continue
+			}
+			if complete == nil {
+				continue
+			}
+			msg = complete
+		}
+
 		// Record received message in metrics with GOB encoding
 		data, _ := proc.MarshalBinaryWithEncoding(msg, proc.EncodingGOB)
 		wireSize := len(data)
@@ -142,6 +178,8 @@ func (b *Broker) readUDSMessages(processID string, transport transport.Transport
 					Error:         err.Error(),
 					Target:        msg.Source,
 					CorrelationID: msg.CorrelationID,
+					TraceID:       msg.TraceID,
+					Priority:      msg.Priority,
 				}
 				_ = b.SendToProcess(msg.Source, errorMsg)
 			}
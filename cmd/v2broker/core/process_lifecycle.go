@@ -258,6 +258,12 @@ func (b *Broker) Shutdown() error {
 
 	b.wg.Wait()
 
+	if b.statsStore != nil {
+		b.flushStats()
+		b.statsStore.close()
+		b.statsStore = nil
+	}
+
 	b.bus.Close()
 
 	// Clean up transport manager - close all transports including those
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/procfs"
+	"github.com/cyw0ng95/v2e/pkg/proc"
+)
+
+// DefaultProcessMetricsRetention is the number of samples kept per process
+// when EnableProcessMetrics is given no retention: an hour at the default
+// interval
+const DefaultProcessMetricsRetention = 120
+
+// DefaultProcessMetricsInterval is the default time between two samples
+const DefaultProcessMetricsInterval = 30 * time.Second
+
+// ProcessMetricsSample is the CPU and memory use of a process at one point
+// in time
+type ProcessMetricsSample struct {
+	Time       time.Time `json:"time"`
+	PID        int       `json:"pid"`
+	CPUPercent float64   `json:"cpu_percent"` // Of one core, since the previous sample
+	RSSBytes   uint64    `json:"rss_bytes"`
+}
+
+// processMetricsRing keeps the latest samples of one process
+type processMetricsRing struct {
+	samples []ProcessMetricsSample
+	next    int // Slot the next sample goes to once samples is full
+	// CPU time and time of the previous reading, for CPUPercent
+	lastPID   int
+	lastTicks uint64
+	lastTime  time.Time
+}
+
+// add appends a sample, overwriting the oldest one once capacity samples
+// are kept
+func (r *processMetricsRing) add(s ProcessMetricsSample, capacity int) {
+	if len(r.samples) < capacity {
+		r.samples = append(r.samples, s)
+		return
+	}
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % capacity
+}
+
+// ordered returns a copy of the samples, oldest first
+func (r *processMetricsRing) ordered() []ProcessMetricsSample {
+	out := make([]ProcessMetricsSample, 0, len(r.samples))
+	out = append(out, r.samples[r.next:]...)
+	return append(out, r.samples[:r.next]...)
+}
+
+// processMetricsHistory holds the recent samples of every process
+type processMetricsHistory struct {
+	mu        sync.RWMutex
+	retention int
+	interval  time.Duration
+	rings     map[string]*processMetricsRing
+}
+
+// EnableProcessMetrics samples the CPU and memory use of every running
+// subprocess each interval and keeps the last retention samples per process
+// ID (DefaultProcessMetricsRetention if retention is not positive), served
+// by RPCGetProcessMetricsHistory. The history of a process survives its
+// restarts; the first sample after a restart has a CPUPercent of 0.
+func (b *Broker) EnableProcessMetrics(interval time.Duration, retention int) {
+	if retention <= 0 {
+		retention = DefaultProcessMetricsRetention
+	}
+	if interval <= 0 {
+		interval = DefaultProcessMetricsInterval
+	}
+	b.processMetrics.Store(&processMetricsHistory{
+		retention: retention,
+		interval:  interval,
+		rings:     make(map[string]*processMetricsRing),
+	})
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.sampleProcessMetrics(time.Now())
+			case <-b.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// sampleProcessMetrics records one sample of every running subprocess.
+// Processes that cannot be read, e.g. because they just exited, are skipped.
+func (b *Broker) sampleProcessMetrics(now time.Time) {
+	h := b.processMetrics.Load()
+	if h == nil {
+		return
+	}
+
+	b.mu.RLock()
+	pids := make(map[string]int, len(b.processes))
+	for id, p := range b.processes {
+		p.mu.RLock()
+		if p.info.Status == ProcessStatusRunning && p.info.PID > 0 {
+			pids[id] = p.info.PID
+		}
+		p.mu.RUnlock()
+	}
+	b.mu.RUnlock()
+
+	for id, pid := range pids {
+		ticks, rss, err := procfs.ReadProcessStat(pid)
+		if err != nil {
+			b.logger.Debug("Failed to sample metrics of process %s (pid %d): %v", id, pid, err)
+			continue
+		}
+		h.record(id, pid, ticks, rss, now)
+	}
+}
+
+// record adds a reading of a process to its history
+func (h *processMetricsHistory) record(id string, pid int, ticks, rss uint64, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.rings[id]
+	if !ok {
+		ring = &processMetricsRing{}
+		h.rings[id] = ring
+	}
+	sample := ProcessMetricsSample{Time: now.UTC(), PID: pid, RSSBytes: rss}
+	// A restarted process has a new PID and its CPU time starts over
+	if ring.lastPID == pid && ticks >= ring.lastTicks && now.After(ring.lastTime) {
+		cpuSeconds := float64(ticks-ring.lastTicks) / procfs.ClockTicksPerSecond
+		sample.CPUPercent = cpuSeconds / now.Sub(ring.lastTime).Seconds() * 100
+	}
+	ring.lastPID, ring.lastTicks, ring.lastTime = pid, ticks, now
+	ring.add(sample, h.retention)
+}
+
+// history returns the latest limit samples of a process, oldest first, or
+// all kept samples if limit is not positive
+func (h *processMetricsHistory) history(id string, limit int) []ProcessMetricsSample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ring, ok := h.rings[id]
+	if !ok {
+		return []ProcessMetricsSample{}
+	}
+	samples := ring.ordered()
+	if limit > 0 && limit < len(samples) {
+		samples = samples[len(samples)-limit:]
+	}
+	return samples
+}
+
+// HandleRPCGetProcessMetricsHistory handles the RPCGetProcessMetricsHistory
+// RPC request. It returns the kept CPU and memory samples of a process,
+// oldest first. A known process without samples yet yields none; an unknown
+// process ID is an error.
+func (b *Broker) HandleRPCGetProcessMetricsHistory(reqMsg *proc.Message) (*proc.Message, error) {
+	var params struct {
+		ProcessID string `json:"process_id"`
+		Limit     int    `json:"limit"`
+	}
+	if len(reqMsg.Payload) > 0 {
+		if err := json.Unmarshal(reqMsg.Payload, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse request parameters: %w", err)
+		}
+	}
+	if params.ProcessID == "" {
+		return nil, fmt.Errorf("process_id is required")
+	}
+	h := b.processMetrics.Load()
+	if h == nil {
+		return nil, fmt.Errorf("process metrics are not enabled")
+	}
+
+	b.mu.RLock()
+	_, known := b.processes[params.ProcessID]
+	b.mu.RUnlock()
+	samples := h.history(params.ProcessID, params.Limit)
+	if !known && len(samples) == 0 {
+		return nil, fmt.Errorf("process not found: %s", params.ProcessID)
+	}
+
+	b.logger.Debug("Handled RPCGetProcessMetricsHistory: process=%s samples=%d", params.ProcessID, len(samples))
+	return b.newBrokerResponse(reqMsg, map[string]interface{}{
+		"process_id":       params.ProcessID,
+		"samples":          samples,
+		"retention":        h.retention,
+		"interval_seconds": h.interval.Seconds(),
+	})
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestProcessMetricsHistory(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestProcessMetricsHistory", nil, func(t *testing.T, tx *gorm.DB) {
+		h := &processMetricsHistory{retention: 3, interval: time.Second, rings: make(map[string]*processMetricsRing)}
+		start := time.Now()
+
+		// 50 ticks in 1s is half a core; a new PID restarts the CPU baseline
+		h.record("meta", 100, 1000, 1<<20, start)
+		h.record("meta", 100, 1050, 2<<20, start.Add(time.Second))
+		h.record("meta", 100, 1050, 3<<20, start.Add(2*time.Second))
+		h.record("meta", 200, 10, 4<<20, start.Add(3*time.Second))
+		h.record("local", 300, 0, 5<<20, start)
+
+		samples := h.history("meta", 0)
+		if len(samples) != 3 {
+			t.Fatalf("Expected the 3 latest samples, got %+v", samples)
+		}
+		if samples[0].RSSBytes != 2<<20 || samples[2].RSSBytes != 4<<20 || !samples[0].Time.Before(samples[1].Time) {
+			t.Errorf("Expected the oldest sample rotated out and the rest oldest first, got %+v", samples)
+		}
+		if samples[0].CPUPercent != 50 || samples[1].CPUPercent != 0 || samples[2].CPUPercent != 0 || samples[2].PID != 200 {
+			t.Errorf("Unexpected CPU use %+v", samples)
+		}
+		if last := h.history("meta", 1); len(last) != 1 || last[0].PID != 200 {
+			t.Errorf("Expected the latest sample, got %+v", last)
+		}
+		if other := h.history("local", 0); len(other) != 1 || other[0].RSSBytes != 5<<20 {
+			t.Errorf("Expected the histories of processes kept apart, got %+v", other)
+		}
+		if none := h.history("missing", 0); none == nil || len(none) != 0 {
+			t.Errorf("Expected no samples of an unknown process, got %+v", none)
+		}
+	})
+}
+
+func TestHandleRPCGetProcessMetricsHistory(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandleRPCGetProcessMetricsHistory", nil, func(t *testing.T, tx *gorm.DB) {
+		broker := NewBroker()
+		defer broker.Shutdown()
+
+		get := func(payload interface{}) (map[string]interface{}, error) {
+			t.Helper()
+			req, err := proc.NewRequestMessage("RPCGetProcessMetricsHistory", payload)
+			if err != nil {
+				t.Fatalf("NewRequestMessage failed: %v", err)
+			}
+			resp, err := broker.HandleRPCGetProcessMetricsHistory(req)
+			if err != nil {
+				return nil, err
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal(resp.Payload, &result); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			return result, nil
+		}
+
+		// The test binary stands in for a subprocess; an exited process is
+		// not sampled
+		self := NewTestProcess("meta", ProcessStatusRunning)
+		self.info.PID = os.Getpid()
+		broker.InsertProcessForTest(self)
+		broker.InsertProcessForTest(NewTestProcess("exited", ProcessStatusExited))
+
+		if _, err := get(map[string]string{"process_id": "meta"}); err == nil {
+			t.Error("Expected an error while process metrics are disabled")
+		}
+
+		broker.EnableProcessMetrics(time.Hour, 2)
+		now := time.Now()
+		for i := 0; i < 3; i++ {
+			broker.sampleProcessMetrics(now.Add(time.Duration(i) * time.Second))
+		}
+
+		result, err := get(map[string]string{"process_id": "meta"})
+		if err != nil {
+			t.Fatalf("HandleRPCGetProcessMetricsHistory failed: %v", err)
+		}
+		samples, _ := result["samples"].([]interface{})
+		if len(samples) != 2 || result["retention"] != float64(2) || result["interval_seconds"] != float64(3600) {
+			t.Fatalf("Expected 2 retained samples, got %v", result)
+		}
+		if sample := samples[1].(map[string]interface{}); sample["pid"] != float64(os.Getpid()) || sample["rss_bytes"].(float64) <= 0 {
+			t.Errorf("Unexpected sample %v", sample)
+		}
+
+		if result, err := get(map[string]string{"process_id": "exited"}); err != nil || len(result["samples"].([]interface{})) != 0 {
+			t.Errorf("Expected no samples of an exited process, got %v (%v)", result, err)
+		}
+		if _, err := get(map[string]string{"process_id": "missing"}); err == nil {
+			t.Error("Expected an error for an unknown process")
+		}
+		if _, err := get(nil); err == nil {
+			t.Error("Expected an error without a process ID")
+		}
+	})
+}
@@ -36,8 +36,15 @@ func (b *Broker) RouteMessage(msg *proc.Message, sourceProcess string) error {
 		msg.Source = sourceProcess
 	}
 
+	if b.dedup.Load().seen(msg) {
+		b.logger.Warn("Dropping duplicate request: id=%s correlation_id=%s from=%s trace=%s", msg.ID, msg.CorrelationID, msg.Source, msg.TraceID)
+		return nil
+	}
+
+	b.inflight.finish(msg)
+
 	if msg.Type == proc.MessageTypeResponse && msg.CorrelationID != "" {
-		b.logger.Debug("Received response message: id=%s correlation_id=%s from=%s", msg.ID, msg.CorrelationID, msg.Source)
+		b.logger.Debug("Received response message: id=%s correlation_id=%s from=%s trace=%s", msg.ID, msg.CorrelationID, msg.Source, msg.TraceID)
 		// Use atomic load-and-delete operation to reduce lock contention
 		b.pendingMu.Lock()
 		pending, exists := b.pendingRequests[msg.CorrelationID]
@@ -66,12 +73,17 @@ func (b *Broker) RouteMessage(msg *proc.Message, sourceProcess string) error {
 
 	if msg.Target != "" {
 		if msg.Target == "broker" {
-			b.logger.Debug("Routing message to broker for local processing: type=%s id=%s from=%s", msg.Type, msg.ID, msg.Source)
+			b.logger.Debug("Routing message to broker for local processing: type=%s id=%s from=%s trace=%s", msg.Type, msg.ID, msg.Source, msg.TraceID)
 			return b.ProcessMessage(msg)
 		}
 
-		b.logger.Debug("Routing message from %s to %s: type=%s id=%s", msg.Source, msg.Target, msg.Type, msg.ID)
-		return b.SendToProcess(msg.Target, msg)
+		b.logger.Debug("Routing message from %s to %s: type=%s id=%s trace=%s", msg.Source, msg.Target, msg.Type, msg.ID, msg.TraceID)
+		b.inflight.start(msg)
+		if err := b.SendToProcess(msg.Target, msg); err != nil {
+			b.inflight.take(msg.Source, msg.CorrelationID)
+			return err
+		}
+		return nil
 	}
 
 	return b.SendMessage(msg)
@@ -84,12 +96,31 @@ func (b *Broker) Route(msg *proc.Message, sourceProcess string) error {
 	return b.RouteMessage(msg, sourceProcess)
 }
 
+// brokerErrorReply builds the error response of the broker to a request
+func brokerErrorReply(msg *proc.Message, err error) *proc.Message {
+	errMsg := proc.NewErrorMessage(msg.ID, err)
+	errMsg.Source = "broker"
+	errMsg.Target = msg.Source
+	errMsg.TraceID = msg.TraceID
+	errMsg.Priority = msg.Priority
+	if msg.CorrelationID != "" {
+		errMsg.CorrelationID = msg.CorrelationID
+	}
+	return errMsg
+}
+
 // ProcessMessage processes a message directed at the broker.
 func (b *Broker) ProcessMessage(msg *proc.Message) error {
 	if msg.Type != proc.MessageTypeRequest {
 		return nil
 	}
 
+	// Requests to the broker itself are read here, so a compressed payload
+	// is restored; routed messages are forwarded compressed
+	if err := proc.DecompressPayload(msg); err != nil {
+		return b.RouteMessage(brokerErrorReply(msg, err), "broker")
+	}
+
 	var respMsg *proc.Message
 	var err error
 
@@ -104,24 +135,24 @@ func (b *Broker) ProcessMessage(msg *proc.Message) error {
 		respMsg, err = b.HandleRPCReleasePermits(msg)
 	case "RPCGetKernelMetrics":
 		respMsg, err = b.HandleRPCGetKernelMetrics(msg)
+	case "RPCCancelRPC":
+		respMsg, err = b.HandleRPCCancelRPC(msg)
+	case "RPCGetProcessMetricsHistory":
+		respMsg, err = b.HandleRPCGetProcessMetricsHistory(msg)
+	case "RPCHealthCheck":
+		// The check waits on replies routed by the same readers that
+		// deliver this request, so it must not block the caller
+		go b.processHealthCheck(msg)
+		return nil
+	case "RPCSetLogLevelAll":
+		go b.processSetLogLevelAll(msg)
+		return nil
 	default:
-		errMsg := proc.NewErrorMessage(msg.ID, fmt.Errorf("unknown RPC method: %s", msg.ID))
-		errMsg.Source = "broker"
-		errMsg.Target = msg.Source
-		if msg.CorrelationID != "" {
-			errMsg.CorrelationID = msg.CorrelationID
-		}
-		return b.RouteMessage(errMsg, "broker")
+		return b.RouteMessage(brokerErrorReply(msg, fmt.Errorf("unknown RPC method: %s", msg.ID)), "broker")
 	}
 
 	if err != nil {
-		errMsg := proc.NewErrorMessage(msg.ID, err)
-		errMsg.Source = "broker"
-		errMsg.Target = msg.Source
-		if msg.CorrelationID != "" {
-			errMsg.CorrelationID = msg.CorrelationID
-		}
-		return b.RouteMessage(errMsg, "broker")
+		return b.RouteMessage(brokerErrorReply(msg, err), "broker")
 	}
 
 	return b.RouteMessage(respMsg, "broker")
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -74,15 +75,66 @@ func (b *Broker) InvokeRPC(sourceProcess, targetProcess, rpcMethod string, paylo
 }
 
 // HandleRPCGetMessageStats handles the RPCGetMessageStats RPC request.
-// Delegates to the metrics registry for enhanced telemetry.
+// It combines the session and lifetime bus counters with the wire-level
+// telemetry of the metrics registry and the duplicate request filter into a
+// typed payload so counts stay int64 end to end. With group_by "trace" it adds the counters of each
+// recent trace, or of trace_id alone if given.
 func (b *Broker) HandleRPCGetMessageStats(reqMsg *proc.Message) (*proc.Message, error) {
-	return b.metricsRegistry.HandleRPCGetMessageStats(reqMsg)
+	var params struct {
+		GroupBy string `json:"group_by"`
+		TraceID string `json:"trace_id"`
+	}
+	if len(reqMsg.Payload) > 0 {
+		if err := json.Unmarshal(reqMsg.Payload, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse request parameters: %w", err)
+		}
+	}
+
+	resp := proc.MessageStatsResponse{
+		Total:      b.GetMessageStats(),
+		PerProcess: b.GetPerProcessStats(),
+		Wire:       b.metricsRegistry.Snapshot(),
+		Dedup:      b.dedup.Load().stats(),
+	}
+	resp.Lifetime, resp.LifetimePerProcess = b.GetLifetimeStats()
+	switch params.GroupBy {
+	case "":
+		if params.TraceID != "" {
+			return nil, fmt.Errorf("trace_id requires group_by trace")
+		}
+	case "trace":
+		resp.PerTrace = b.GetPerTraceStats()
+		if params.TraceID != "" {
+			ts, ok := resp.PerTrace[params.TraceID]
+			resp.PerTrace = map[string]TraceStats{}
+			if ok {
+				resp.PerTrace[params.TraceID] = ts
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown group_by: %s", params.GroupBy)
+	}
+	return b.newBrokerResponse(reqMsg, resp)
 }
 
 // HandleRPCGetMessageCount handles the RPCGetMessageCount RPC request.
-// Delegates to the metrics registry.
 func (b *Broker) HandleRPCGetMessageCount(reqMsg *proc.Message) (*proc.Message, error) {
-	return b.metricsRegistry.HandleRPCGetMessageCount(reqMsg)
+	return b.newBrokerResponse(reqMsg, proc.MessageCountResponse{Count: b.GetMessageCount()})
+}
+
+// newBrokerResponse creates a response from the broker to reqMsg, addressed
+// back to its sender so the caller can match it to the pending request
+func (b *Broker) newBrokerResponse(reqMsg *proc.Message, payload interface{}) (*proc.Message, error) {
+	respMsg, err := proc.NewResponseMessage(reqMsg.ID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create response message: %w", err)
+	}
+	respMsg.Source = "broker"
+	respMsg.Target = reqMsg.Source
+	respMsg.CorrelationID = reqMsg.CorrelationID
+	respMsg.TraceID = reqMsg.TraceID
+	respMsg.Priority = reqMsg.Priority
+	return respMsg, nil
 }
 
 // RegisterEndpoint registers an RPC endpoint for a process.
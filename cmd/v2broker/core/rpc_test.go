@@ -96,26 +96,79 @@ func TestHandleRPCGetMessageStats(t *testing.T) {
 			t.Errorf("Expected target 'test-caller', got %s", respMsg.Target)
 		}
 
-		// Parse the response payload as a map
-		var payload map[string]interface{}
+		// Parse the response payload
+		var payload proc.MessageStatsResponse
 		if err := respMsg.UnmarshalPayload(&payload); err != nil {
 			t.Fatalf("Failed to unmarshal payload: %v", err)
 		}
+		if payload.Total.TotalSent < 1 {
+			t.Errorf("Expected TotalSent >= 1, got %d", payload.Total.TotalSent)
+		}
+		if payload.PerProcess["test-target"].TotalSent < 1 {
+			t.Errorf("Expected per-process stats for test-target, got %+v", payload.PerProcess)
+		}
+	})
+
+}
+
+func TestHandleRPCGetMessageStats_GroupByTrace(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandleRPCGetMessageStats_GroupByTrace", nil, func(t *testing.T, tx *gorm.DB) {
+		broker := NewBroker()
+		defer broker.Shutdown()
 
-		// Extract 'total' sub-map and check TotalSent
-		total, ok := payload["total"].(map[string]interface{})
-		if !ok {
-			t.Fatalf("Response payload missing 'total' field or wrong type")
+		for _, traceID := range []string{"t1", "t1", "t2"} {
+			msg, _ := proc.NewRequestMessage("test-req", nil)
+			msg.Target = "test-target"
+			msg.TraceID = traceID
+			broker.SendMessage(msg)
 		}
-		totalSent, ok := total["total_sent"].(float64)
-		if !ok {
-			t.Fatalf("Expected total_sent to be float64, got %T", total["total_sent"])
+
+		stats := func(params interface{}) (*proc.MessageStatsResponse, error) {
+			rpcReq, _ := proc.NewRequestMessage("RPCGetMessageStats", params)
+			rpcReq.Source = "test-caller"
+			rpcReq.TraceID = "caller-trace"
+			respMsg, err := broker.HandleRPCGetMessageStats(rpcReq)
+			if err != nil {
+				return nil, err
+			}
+			if respMsg.TraceID != "caller-trace" {
+				t.Errorf("Expected the response to carry the request trace, got %q", respMsg.TraceID)
+			}
+			var payload proc.MessageStatsResponse
+			if err := respMsg.UnmarshalPayload(&payload); err != nil {
+				t.Fatalf("Failed to unmarshal payload: %v", err)
+			}
+			return &payload, nil
 		}
-		if totalSent < 1 {
-			t.Errorf("Expected TotalSent >= 1, got %v", totalSent)
+
+		payload, err := stats(nil)
+		if err != nil || payload.PerTrace != nil {
+			t.Errorf("Expected no per-trace stats by default, got %+v (%v)", payload, err)
+		}
+
+		payload, err = stats(map[string]string{"group_by": "trace"})
+		if err != nil {
+			t.Fatalf("HandleRPCGetMessageStats failed: %v", err)
+		}
+		if payload.PerTrace["t1"].RequestCount != 2 || payload.PerTrace["t2"].RequestCount != 1 {
+			t.Errorf("Unexpected per-trace stats %+v", payload.PerTrace)
+		}
+		if p := payload.PerTrace["t1"].Processes; len(p) != 1 || p[0] != "test-target" {
+			t.Errorf("Expected t1 to have reached test-target, got %v", p)
 		}
-	})
 
+		payload, err = stats(map[string]string{"group_by": "trace", "trace_id": "t2"})
+		if err != nil || len(payload.PerTrace) != 1 || payload.PerTrace["t2"].RequestCount != 1 {
+			t.Errorf("Expected only t2, got %+v (%v)", payload, err)
+		}
+
+		if _, err := stats(map[string]string{"group_by": "process"}); err == nil {
+			t.Error("Expected an unknown group_by to be rejected")
+		}
+		if _, err := stats(map[string]string{"trace_id": "t2"}); err == nil {
+			t.Error("Expected trace_id without group_by to be rejected")
+		}
+	})
 }
 
 func TestHandleRPCGetMessageCount(t *testing.T) {
@@ -154,25 +207,12 @@ func TestHandleRPCGetMessageCount(t *testing.T) {
 		}
 
 		// Parse the response payload
-		var payload map[string]interface{}
+		var payload proc.MessageCountResponse
 		if err := respMsg.UnmarshalPayload(&payload); err != nil {
 			t.Fatalf("Failed to unmarshal payload: %v", err)
 		}
-
-		// Verify count exists
-		count, ok := payload["count"]
-		if !ok {
-			t.Fatal("Response payload missing 'count' field")
-		}
-
-		// Count should be at least 1
-		countFloat, ok := count.(float64)
-		if !ok {
-			t.Fatalf("Expected count to be float64, got %T", count)
-		}
-
-		if countFloat < 1 {
-			t.Errorf("Expected count >= 1, got %f", countFloat)
+		if payload.Count < 1 {
+			t.Errorf("Expected count >= 1, got %d", payload.Count)
 		}
 	})
 
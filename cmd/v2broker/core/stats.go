@@ -10,6 +10,17 @@ func (b *Broker) GetPerProcessStats() map[string]PerProcessStats {
 	return b.bus.GetPerProcessStats()
 }
 
+// GetLifetimeStats returns a copy of the broker-wide and per-process stats
+// summed over every session (see EnableStatsPersistence).
+func (b *Broker) GetLifetimeStats() (MessageStats, map[string]PerProcessStats) {
+	return b.bus.GetLifetimeStats()
+}
+
+// GetPerTraceStats returns a copy of the stats of the most recent traces.
+func (b *Broker) GetPerTraceStats() map[string]TraceStats {
+	return b.bus.GetPerTraceStats()
+}
+
 // GetMessageCount returns the total number of messages processed (sent + received).
 func (b *Broker) GetMessageCount() int64 {
 	return b.bus.GetMessageCount()
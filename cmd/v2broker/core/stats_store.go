@@ -0,0 +1,154 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+var (
+	bucketMessageStats = []byte("message_stats")
+	keyLifetimeStats   = []byte("lifetime")
+)
+
+// persistedStats is the lifetime message statistics as stored on disk
+type persistedStats struct {
+	Total      MessageStats               `json:"total"`
+	PerProcess map[string]PerProcessStats `json:"per_process"`
+	SavedAt    time.Time                  `json:"saved_at"`
+}
+
+// statsStore keeps the lifetime message statistics of the broker in a
+// small bbolt file so they survive restarts
+type statsStore struct {
+	db *bolt.DB
+}
+
+// openStatsStore opens the stats file at path, creating it if needed
+func openStatsStore(path string) (*statsStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketMessageStats)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket %s: %w", bucketMessageStats, err)
+	}
+	return &statsStore{db: db}, nil
+}
+
+// load returns the stored statistics; ok is false if none were saved yet
+func (s *statsStore) load() (stats persistedStats, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketMessageStats).Get(keyLifetimeStats)
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &stats)
+	})
+	if err != nil {
+		return persistedStats{}, false, fmt.Errorf("failed to decode stored stats: %w", err)
+	}
+	return stats, ok, nil
+}
+
+// save replaces the stored statistics
+func (s *statsStore) save(stats persistedStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to encode stats: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketMessageStats).Put(keyLifetimeStats, data)
+	})
+}
+
+func (s *statsStore) close() error {
+	return s.db.Close()
+}
+
+// isCorruptStatsFile reports whether err from openStatsStore means the file
+// is not a usable bbolt database, as opposed to one that is locked by
+// another process or cannot be accessed
+func isCorruptStatsFile(err error) bool {
+	for _, target := range []error{berrors.ErrInvalid, berrors.ErrVersionMismatch, berrors.ErrChecksum, berrors.ErrInvalidMapping, berrors.ErrIncompatibleValue} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	// bbolt reports a truncated file with an unwrapped error
+	return strings.Contains(err.Error(), "file size too small")
+}
+
+// EnableStatsPersistence makes the lifetime message statistics survive
+// restarts: the counters saved in the bbolt file at path are added to the
+// lifetime counters, which are then saved back every interval and on
+// Shutdown. An interval of zero only saves on Shutdown. A stats file that
+// is corrupt is moved aside to path+".corrupt" and counting starts afresh,
+// so a damaged file never keeps the broker from starting. Other errors, such
+// as the file being locked by another broker, are returned and leave the
+// file alone.
+func (b *Broker) EnableStatsPersistence(path string, interval time.Duration) error {
+	store, err := openStatsStore(path)
+	if err != nil {
+		if !isCorruptStatsFile(err) {
+			return err
+		}
+		b.logger.Warn("Stats file %s is corrupt, starting with fresh counters: %v", path, err)
+		if err := os.Rename(path, path+".corrupt"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move aside stats file: %w", err)
+		}
+		if store, err = openStatsStore(path); err != nil {
+			return err
+		}
+	}
+
+	stats, ok, err := store.load()
+	if err != nil {
+		b.logger.Warn("Stored stats in %s are unreadable, starting with fresh counters: %v", path, err)
+	} else if ok {
+		b.bus.RestoreLifetimeStats(stats.Total, stats.PerProcess)
+		b.logger.Info("Restored lifetime message stats saved at %s: %d messages", stats.SavedAt.Format(time.RFC3339), stats.Total.TotalSent+stats.Total.TotalReceived)
+	}
+	b.statsStore = store
+
+	if interval > 0 {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					b.flushStats()
+				case <-b.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// flushStats saves the lifetime message statistics if persistence is enabled
+func (b *Broker) flushStats() {
+	if b.statsStore == nil {
+		return
+	}
+	total, perProcess := b.bus.GetLifetimeStats()
+	if err := b.statsStore.save(persistedStats{Total: total, PerProcess: perProcess, SavedAt: time.Now()}); err != nil {
+		b.logger.Warn("Failed to save message stats: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	bolt "go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+func TestEnableStatsPersistence_SurvivesRestart(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestEnableStatsPersistence_SurvivesRestart", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "broker_stats.db")
+
+		first := NewBroker()
+		if err := first.EnableStatsPersistence(path, 0); err != nil {
+			t.Fatalf("EnableStatsPersistence failed: %v", err)
+		}
+		first.bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Source: "access", Target: "meta"}, true)
+		first.bus.Record(&proc.Message{Type: proc.MessageTypeResponse, Source: "meta", Target: "access"}, true)
+		// Shutdown saves the counters
+		first.Shutdown()
+
+		second := NewBroker()
+		defer second.Shutdown()
+		if err := second.EnableStatsPersistence(path, 10*time.Millisecond); err != nil {
+			t.Fatalf("EnableStatsPersistence failed: %v", err)
+		}
+		second.bus.Record(&proc.Message{Type: proc.MessageTypeEvent, Source: "meta", Target: "access"}, true)
+
+		if session := second.GetMessageStats(); session.TotalSent != 1 {
+			t.Errorf("Expected fresh session counters, got %+v", session)
+		}
+		total, perProcess := second.GetLifetimeStats()
+		if total.TotalSent != 3 || total.RequestCount != 1 || total.ResponseCount != 1 || total.EventCount != 1 {
+			t.Errorf("Expected the earlier session in the lifetime counters, got %+v", total)
+		}
+		if perProcess["meta"].TotalSent != 1 || perProcess["access"].TotalSent != 2 {
+			t.Errorf("Unexpected lifetime per-process counters %+v", perProcess)
+		}
+
+		// The ticker saves without waiting for Shutdown
+		time.Sleep(50 * time.Millisecond)
+		stats, ok, err := second.statsStore.load()
+		if err != nil || !ok || stats.Total.TotalSent != 3 {
+			t.Errorf("Expected the lifetime counters saved periodically, got %+v ok=%v err=%v", stats.Total, ok, err)
+		}
+	})
+}
+
+func TestEnableStatsPersistence_CorruptFile(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestEnableStatsPersistence_CorruptFile", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "broker_stats.db")
+		if err := os.WriteFile(path, []byte("not a bbolt file, just garbage that is long enough to be read"), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+
+		broker := NewBroker()
+		defer broker.Shutdown()
+		if err := broker.EnableStatsPersistence(path, 0); err != nil {
+			t.Fatalf("Expected a corrupt stats file to be replaced, got %v", err)
+		}
+		if total, _ := broker.GetLifetimeStats(); total != (MessageStats{}) {
+			t.Errorf("Expected fresh lifetime counters, got %+v", total)
+		}
+		if _, err := os.Stat(path + ".corrupt"); err != nil {
+			t.Errorf("Expected the corrupt file moved aside: %v", err)
+		}
+
+		// Unreadable stored stats in a valid file also start afresh
+		if err := broker.statsStore.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(bucketMessageStats).Put(keyLifetimeStats, []byte("{"))
+		}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		broker.statsStore.close()
+		broker.statsStore = nil
+
+		again := NewBroker()
+		defer again.Shutdown()
+		if err := again.EnableStatsPersistence(path, 0); err != nil {
+			t.Fatalf("EnableStatsPersistence failed: %v", err)
+		}
+		if total, _ := again.GetLifetimeStats(); total != (MessageStats{}) {
+			t.Errorf("Expected fresh lifetime counters, got %+v", total)
+		}
+	})
+}
+
+func TestEnableStatsPersistence_LockedFileKept(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestEnableStatsPersistence_LockedFileKept", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "broker_stats.db")
+		holder, err := openStatsStore(path)
+		if err != nil {
+			t.Fatalf("openStatsStore failed: %v", err)
+		}
+		defer holder.close()
+
+		// A file held by another broker is an error, not corruption
+		broker := NewBroker()
+		defer broker.Shutdown()
+		if err := broker.EnableStatsPersistence(path, 0); err == nil {
+			t.Fatal("Expected an error for a locked stats file")
+		}
+		if _, err := os.Stat(path + ".corrupt"); !os.IsNotExist(err) {
+			t.Errorf("Expected a locked stats file not to be moved aside, got %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected the stats file kept: %v", err)
+		}
+	})
+}
@@ -87,6 +87,9 @@ type MessageStats = mq.MessageStats
 // PerProcessStats aliases mq.PerProcessStats for compatibility.
 type PerProcessStats = mq.PerProcessStats
 
+// TraceStats aliases mq.TraceStats for compatibility.
+type TraceStats = mq.TraceStats
+
 // PendingRequest represents a pending request awaiting a response.
 type PendingRequest struct {
 	SourceProcess string
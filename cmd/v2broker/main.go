@@ -42,6 +42,17 @@ func main() {
 	// Use the subprocess logger as the broker logger
 	broker.SetLogger(logger)
 
+	// Keep lifetime message stats across restarts (build-time configurable)
+	if err := broker.EnableStatsPersistence(buildStatsDBPath, buildStatsFlushValue()); err != nil {
+		logger.Warn(LogMsgStatsPersistenceFailed, err)
+	} else {
+		logger.Info(LogMsgStatsPersistence, buildStatsDBPath, buildStatsFlushValue())
+	}
+
+	// Keep a window of CPU and memory samples per process (build-time configurable)
+	broker.EnableProcessMetrics(buildProcMetricsIntervalValue(), buildProcMetricsRetentionValue())
+	logger.Info(LogMsgProcessMetrics, buildProcMetricsRetentionValue(), buildProcMetricsIntervalValue())
+
 	// Load processes from configuration
 	if err := broker.LoadProcessesFromConfig(nil); err != nil {
 		logger.Error(LogMsgErrorLoadingProcesses, err)
@@ -62,6 +73,10 @@ func main() {
 		AdaptationFreq: 10 * time.Second,             // Default adaptation frequency
 	}
 
+	// Drop requests delivered twice, e.g. by retries (build-time configurable)
+	broker.SetDedupWindow(buildOptimizerDedupValue())
+	logger.Info(LogMsgDedupWindow, buildOptimizerDedupValue())
+
 	opt := perf.NewWithConfig(broker, optConfig)
 	broker.SetOptimizer(opt)
 
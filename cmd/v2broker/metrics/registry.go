@@ -50,52 +50,39 @@ func (r *Registry) RecordMessage(msg *proc.Message, sent bool, wireSize int, enc
 	}
 }
 
-// HandleRPCGetMessageStats handles the RPCGetMessageStats RPC call
-func (r *Registry) HandleRPCGetMessageStats(reqMsg *proc.Message) (*proc.Message, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	result := map[string]interface{}{
-		"total_messages":        r.messageCount,
-		"sent_messages":         r.sentCount,
-		"received_messages":     r.receivedCount,
-		"total_wire_bytes":      r.totalWireSize,
-		"encoding_distribution": r.encodingDistribution,
-	}
-
-	msg, err := proc.NewResponseMessage(reqMsg.ID, result)
-	if err != nil {
-		return nil, err
+// String returns the lower-case name of the encoding
+func (e EncodingType) String() string {
+	switch e {
+	case EncodingJSON:
+		return "json"
+	case EncodingGOB:
+		return "gob"
+	case EncodingPLAIN:
+		return "plain"
+	default:
+		return "unknown"
 	}
-	return msg, nil
 }
 
-// HandleRPCGetMessageCount handles the RPCGetMessageCount RPC call
-func (r *Registry) HandleRPCGetMessageCount(reqMsg *proc.Message) (*proc.Message, error) {
+// Snapshot returns the current wire-level counters
+func (r *Registry) Snapshot() proc.WireStats {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	result := map[string]interface{}{
-		"count": r.messageCount,
+	distribution := make(map[string]int64, len(r.encodingDistribution))
+	for encoding, count := range r.encodingDistribution {
+		distribution[encoding.String()] += count
 	}
-
-	msg, err := proc.NewResponseMessage(reqMsg.ID, result)
-	if err != nil {
-		return nil, err
+	return proc.WireStats{
+		TotalMessages:        r.messageCount,
+		SentMessages:         r.sentCount,
+		ReceivedMessages:     r.receivedCount,
+		TotalWireBytes:       r.totalWireSize,
+		EncodingDistribution: distribution,
 	}
-	return msg, nil
 }
 
 // MarshalJSON implements json.Marshaler for Registry (optional)
 func (r *Registry) MarshalJSON() ([]byte, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	return json.Marshal(map[string]interface{}{
-		"message_count":         r.messageCount,
-		"sent_count":            r.sentCount,
-		"received_count":        r.receivedCount,
-		"total_wire_size":       r.totalWireSize,
-		"encoding_distribution": r.encodingDistribution,
-	})
+	return json.Marshal(r.Snapshot())
 }
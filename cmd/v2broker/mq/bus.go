@@ -9,51 +9,68 @@ import (
 	"github.com/cyw0ng95/v2e/pkg/proc"
 )
 
-// MessageStats contains aggregated message statistics.
-type MessageStats struct {
-	TotalSent        int64
-	TotalReceived    int64
-	RequestCount     int64
-	ResponseCount    int64
-	EventCount       int64
-	ErrorCount       int64
-	FirstMessageTime time.Time
-	LastMessageTime  time.Time
-}
+// MessageStats contains aggregated message statistics. It is the wire type
+// of RPCGetMessageStats so counters are served without conversion.
+type MessageStats = proc.MessageStats
 
 // PerProcessStats contains per-process message statistics.
-type PerProcessStats struct {
-	TotalSent        int64
-	TotalReceived    int64
-	RequestCount     int64
-	ResponseCount    int64
-	EventCount       int64
-	ErrorCount       int64
-	FirstMessageTime time.Time
-	LastMessageTime  time.Time
-}
-
-// Bus implements a buffered message bus with statistics tracking.
+type PerProcessStats = proc.MessageStats
+
+// TraceStats contains the message statistics of one trace.
+type TraceStats = proc.TraceStats
+
+// MaxTraces bounds the traces whose stats are kept; the oldest trace is
+// dropped when a new one would exceed it.
+const MaxTraces = 1000
+
+// Bus implements a buffered message bus with statistics tracking. Besides
+// the counters of the current session it keeps lifetime counters, which
+// start from the counters of earlier sessions (see RestoreLifetimeStats).
+//
+// Messages are queued in two lanes: high-priority (control-plane) messages
+// have their own buffer and are received ahead of normal ones, so health
+// checks and cancellations are not stuck behind bulk traffic.
 type Bus struct {
-	ch              chan *proc.Message
-	stats           MessageStats
-	perProcessStats map[string]PerProcessStats
-	mu              sync.RWMutex
-	ctx             context.Context
+	ch                 chan *proc.Message
+	high               chan *proc.Message
+	stats              MessageStats
+	perProcessStats    map[string]PerProcessStats
+	lifetime           MessageStats
+	lifetimePerProcess map[string]PerProcessStats
+	perTraceStats      map[string]*TraceStats
+	traceOrder         []string
+	mu                 sync.RWMutex
+	ctx                context.Context
 }
 
 // NewBus creates a new bus with the provided buffer size and lifecycle context.
 func NewBus(ctx context.Context, buffer int) *Bus {
 	return &Bus{
-		ch:              make(chan *proc.Message, buffer),
-		perProcessStats: make(map[string]PerProcessStats),
-		ctx:             ctx,
+		ch:                 make(chan *proc.Message, buffer),
+		high:               make(chan *proc.Message, buffer),
+		perProcessStats:    make(map[string]PerProcessStats),
+		lifetimePerProcess: make(map[string]PerProcessStats),
+		perTraceStats:      make(map[string]*TraceStats),
+		ctx:                ctx,
 	}
 }
 
-// Channel exposes the underlying channel for consumers that select directly.
+// Channel exposes the normal lane for consumers that select directly.
+// High-priority messages are only delivered by Receive.
 func (b *Bus) Channel() chan *proc.Message { return b.ch }
 
+// lane returns the channel a message is queued in
+func (b *Bus) lane(msg *proc.Message) chan *proc.Message {
+	if msg.IsHighPriority() {
+		return b.high
+	}
+	return b.ch
+}
+
+// QueueDepths returns the number of messages waiting in the high and the
+// normal lane.
+func (b *Bus) QueueDepths() (high, normal int) { return len(b.high), len(b.ch) }
+
 // BufferCap returns the channel capacity.
 func (b *Bus) BufferCap() int { return cap(b.ch) }
 
@@ -65,7 +82,7 @@ func (b *Bus) Send(ctx context.Context, msg *proc.Message) (err error) {
 		}
 	}()
 	select {
-	case b.ch <- msg:
+	case b.lane(msg) <- msg:
 		b.updateStats(msg, isSent(msg))
 		return nil
 	case <-ctx.Done():
@@ -79,15 +96,31 @@ func (b *Bus) Send(ctx context.Context, msg *proc.Message) (err error) {
 func (b *Bus) SendInternal(msg *proc.Message) {
 	defer func() { recover() }()
 	select {
-	case b.ch <- msg:
+	case b.lane(msg) <- msg:
 		b.updateStats(msg, isSent(msg))
 	case <-b.ctx.Done():
 	}
 }
 
-// Receive dequeues a message, updating stats as a receive.
+// Receive dequeues a message, updating stats as a receive. A waiting
+// high-priority message is received before any normal one.
 func (b *Bus) Receive(ctx context.Context) (*proc.Message, error) {
 	select {
+	case msg, ok := <-b.high:
+		if !ok {
+			return nil, context.Canceled
+		}
+		b.updateStats(msg, false)
+		return msg, nil
+	default:
+	}
+	select {
+	case msg, ok := <-b.high:
+		if !ok {
+			return nil, context.Canceled
+		}
+		b.updateStats(msg, false)
+		return msg, nil
 	case msg, ok := <-b.ch:
 		if !ok {
 			return nil, context.Canceled
@@ -119,6 +152,45 @@ func (b *Bus) GetPerProcessStats() map[string]PerProcessStats {
 	return out
 }
 
+// GetLifetimeStats returns a snapshot of the broker-wide and per-process
+// counters summed over every session.
+func (b *Bus) GetLifetimeStats() (MessageStats, map[string]PerProcessStats) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[string]PerProcessStats, len(b.lifetimePerProcess))
+	for k, v := range b.lifetimePerProcess {
+		out[k] = v
+	}
+	return b.lifetime, out
+}
+
+// RestoreLifetimeStats adds the counters of earlier sessions, as returned by
+// GetLifetimeStats, to the lifetime counters. The session counters are left
+// alone.
+func (b *Bus) RestoreLifetimeStats(total MessageStats, perProcess map[string]PerProcessStats) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	addStats(&b.lifetime, total)
+	for k, v := range perProcess {
+		ps := b.lifetimePerProcess[k]
+		addStats(&ps, v)
+		b.lifetimePerProcess[k] = ps
+	}
+}
+
+// GetPerTraceStats returns a copy of the stats of the most recent traces.
+func (b *Bus) GetPerTraceStats() map[string]TraceStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[string]TraceStats, len(b.perTraceStats))
+	for k, v := range b.perTraceStats {
+		ts := *v
+		ts.Processes = append([]string(nil), v.Processes...)
+		out[k] = ts
+	}
+	return out
+}
+
 // GetMessageCount returns total sent + received.
 func (b *Bus) GetMessageCount() int64 {
 	b.mu.RLock()
@@ -131,8 +203,11 @@ func (b *Bus) Record(msg *proc.Message, isSent bool) {
 	b.updateStats(msg, isSent)
 }
 
-// Close closes the underlying channel.
-func (b *Bus) Close() { close(b.ch) }
+// Close closes both lanes.
+func (b *Bus) Close() {
+	close(b.high)
+	close(b.ch)
+}
 
 // isSent infers direction: true if broker is sending to a target, false if received.
 func isSent(msg *proc.Message) bool {
@@ -145,60 +220,101 @@ func (b *Bus) updateStats(msg *proc.Message, isSent bool) {
 	defer b.mu.Unlock()
 
 	now := time.Now()
+	countMessage(&b.stats, msg, isSent, now)
+	countMessage(&b.lifetime, msg, isSent, now)
 
-	if b.stats.FirstMessageTime.IsZero() {
-		b.stats.FirstMessageTime = now
+	var procID string
+	if isSent {
+		procID = msg.Target
+	} else {
+		procID = msg.Source
+	}
+
+	if procID != "" {
+		ps := b.perProcessStats[procID]
+		countMessage(&ps, msg, isSent, now)
+		b.perProcessStats[procID] = ps
+
+		lps := b.lifetimePerProcess[procID]
+		countMessage(&lps, msg, isSent, now)
+		b.lifetimePerProcess[procID] = lps
+	}
+
+	if msg.TraceID != "" {
+		countTrace(b.traceStats(msg.TraceID), msg, isSent, procID, now)
+	}
+}
+
+// traceStats returns the stats of a trace, starting them, and dropping the
+// oldest trace if there are too many, when the trace is new. Callers must
+// hold b.mu.
+func (b *Bus) traceStats(traceID string) *TraceStats {
+	ts, ok := b.perTraceStats[traceID]
+	if ok {
+		return ts
+	}
+	if len(b.traceOrder) >= MaxTraces {
+		delete(b.perTraceStats, b.traceOrder[0])
+		b.traceOrder = b.traceOrder[1:]
 	}
-	b.stats.LastMessageTime = now
+	ts = &TraceStats{}
+	b.perTraceStats[traceID] = ts
+	b.traceOrder = append(b.traceOrder, traceID)
+	return ts
+}
+
+// countTrace adds a message of a trace exchanged with procID to its stats.
+func countTrace(ts *TraceStats, msg *proc.Message, isSent bool, procID string, now time.Time) {
+	countMessage(&ts.MessageStats, msg, isSent, now)
+	if procID == "" {
+		return
+	}
+	for _, p := range ts.Processes {
+		if p == procID {
+			return
+		}
+	}
+	ts.Processes = append(ts.Processes, procID)
+}
+
+// countMessage adds a message to stats.
+func countMessage(stats *MessageStats, msg *proc.Message, isSent bool, now time.Time) {
+	if stats.FirstMessageTime.IsZero() {
+		stats.FirstMessageTime = now
+	}
+	stats.LastMessageTime = now
 
 	if isSent {
-		b.stats.TotalSent++
+		stats.TotalSent++
 	} else {
-		b.stats.TotalReceived++
+		stats.TotalReceived++
 	}
 
 	switch msg.Type {
 	case proc.MessageTypeRequest:
-		b.stats.RequestCount++
+		stats.RequestCount++
 	case proc.MessageTypeResponse:
-		b.stats.ResponseCount++
+		stats.ResponseCount++
 	case proc.MessageTypeEvent:
-		b.stats.EventCount++
+		stats.EventCount++
 	case proc.MessageTypeError:
-		b.stats.ErrorCount++
+		stats.ErrorCount++
 	}
+}
 
-	var procID string
-	if isSent {
-		procID = msg.Target
-	} else {
-		procID = msg.Source
+// addStats adds the counters of src to stats, keeping the earliest first and
+// the latest last message time.
+func addStats(stats *MessageStats, src MessageStats) {
+	stats.TotalSent += src.TotalSent
+	stats.TotalReceived += src.TotalReceived
+	stats.RequestCount += src.RequestCount
+	stats.ResponseCount += src.ResponseCount
+	stats.EventCount += src.EventCount
+	stats.ErrorCount += src.ErrorCount
+	if !src.FirstMessageTime.IsZero() && (stats.FirstMessageTime.IsZero() || src.FirstMessageTime.Before(stats.FirstMessageTime)) {
+		stats.FirstMessageTime = src.FirstMessageTime
 	}
-
-	if procID != "" {
-		ps := b.perProcessStats[procID]
-		if ps.FirstMessageTime.IsZero() {
-			ps.FirstMessageTime = now
-		}
-		ps.LastMessageTime = now
-
-		if isSent {
-			ps.TotalSent++
-		} else {
-			ps.TotalReceived++
-		}
-
-		switch msg.Type {
-		case proc.MessageTypeRequest:
-			ps.RequestCount++
-		case proc.MessageTypeResponse:
-			ps.ResponseCount++
-		case proc.MessageTypeEvent:
-			ps.EventCount++
-		case proc.MessageTypeError:
-			ps.ErrorCount++
-		}
-
-		b.perProcessStats[procID] = ps
+	if src.LastMessageTime.After(stats.LastMessageTime) {
+		stats.LastMessageTime = src.LastMessageTime
 	}
 }
@@ -3,8 +3,10 @@ package mq
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 	"gorm.io/gorm"
+	"strings"
 	"testing"
 	"time"
 
@@ -144,3 +146,108 @@ func TestFirstAndLastMessageTimesSet(t *testing.T) {
 	})
 
 }
+
+func TestBusPerTraceStats(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestBusPerTraceStats", nil, func(t *testing.T, tx *gorm.DB) {
+		bus := NewBus(context.Background(), 4)
+
+		bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Source: "access", Target: "meta", TraceID: "t1"}, true)
+		bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Source: "meta", Target: "local", TraceID: "t1"}, true)
+		bus.Record(&proc.Message{Type: proc.MessageTypeResponse, Source: "local", Target: "meta", TraceID: "t1"}, true)
+		bus.Record(&proc.Message{Type: proc.MessageTypeError, Source: "meta", Target: "access", TraceID: "t1"}, true)
+		bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Source: "access", Target: "meta"}, true)
+
+		per := bus.GetPerTraceStats()
+		if len(per) != 1 {
+			t.Fatalf("Expected only the traced messages to be grouped, got %+v", per)
+		}
+		ts := per["t1"]
+		if ts.TotalSent != 4 || ts.RequestCount != 2 || ts.ResponseCount != 1 || ts.ErrorCount != 1 {
+			t.Errorf("Unexpected trace counts %+v", ts)
+		}
+		if strings.Join(ts.Processes, ",") != "meta,local,access" {
+			t.Errorf("Expected the processes in first-contact order, got %v", ts.Processes)
+		}
+
+		// The copy is detached from the bus
+		ts.Processes[0] = "changed"
+		if bus.GetPerTraceStats()["t1"].Processes[0] != "meta" {
+			t.Error("Expected GetPerTraceStats to return a copy")
+		}
+
+		// The oldest traces are dropped
+		for i := 0; i < MaxTraces; i++ {
+			bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Target: "meta", TraceID: fmt.Sprintf("n%d", i)}, true)
+		}
+		per = bus.GetPerTraceStats()
+		if len(per) != MaxTraces {
+			t.Errorf("Expected %d traces, got %d", MaxTraces, len(per))
+		}
+		if _, ok := per["t1"]; ok {
+			t.Error("Expected the oldest trace to be dropped")
+		}
+	})
+}
+
+func TestBusLifetimeStats(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestBusLifetimeStats", nil, func(t *testing.T, tx *gorm.DB) {
+		bus := NewBus(context.Background(), 4)
+		bus.Record(&proc.Message{Type: proc.MessageTypeRequest, Source: "access", Target: "meta"}, true)
+
+		earlier := time.Now().Add(-time.Hour)
+		bus.RestoreLifetimeStats(
+			MessageStats{TotalSent: 10, RequestCount: 7, ErrorCount: 3, FirstMessageTime: earlier, LastMessageTime: earlier},
+			map[string]PerProcessStats{"meta": {TotalSent: 4, RequestCount: 4}, "local": {TotalReceived: 2, EventCount: 2}},
+		)
+		bus.Record(&proc.Message{Type: proc.MessageTypeResponse, Source: "meta", Target: "access"}, true)
+
+		session := bus.GetMessageStats()
+		if session.TotalSent != 2 || session.RequestCount != 1 {
+			t.Errorf("Expected the session counters untouched by the restore, got %+v", session)
+		}
+		total, per := bus.GetLifetimeStats()
+		if total.TotalSent != 12 || total.RequestCount != 8 || total.ResponseCount != 1 || total.ErrorCount != 3 {
+			t.Errorf("Unexpected lifetime counters %+v", total)
+		}
+		if !total.FirstMessageTime.Equal(earlier) || !total.LastMessageTime.After(earlier) {
+			t.Errorf("Expected the earliest first and latest last message time, got %+v", total)
+		}
+		if per["meta"].TotalSent != 5 || per["access"].TotalSent != 1 || per["local"].EventCount != 2 {
+			t.Errorf("Unexpected lifetime per-process counters %+v", per)
+		}
+	})
+}
+
+func TestBusHighPriorityLane(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestBusHighPriorityLane", nil, func(t *testing.T, tx *gorm.DB) {
+		ctx := context.Background()
+		bus := NewBus(ctx, 2)
+
+		// The normal lane is full, the high lane still takes messages
+		for _, id := range []string{"n1", "n2"} {
+			if err := bus.Send(ctx, &proc.Message{Type: proc.MessageTypeRequest, ID: id}); err != nil {
+				t.Fatalf("Send %s returned error: %v", id, err)
+			}
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if err := bus.Send(sendCtx, &proc.Message{Type: proc.MessageTypeRequest, ID: "RPCHealthCheck", Priority: proc.MessagePriorityHigh}); err != nil {
+			t.Fatalf("Send of a high-priority message returned error: %v", err)
+		}
+		if high, normal := bus.QueueDepths(); high != 1 || normal != 2 {
+			t.Fatalf("Expected 1 high and 2 normal queued, got %d and %d", high, normal)
+		}
+
+		var order []string
+		for i := 0; i < 3; i++ {
+			msg, err := bus.Receive(ctx)
+			if err != nil {
+				t.Fatalf("Receive returned error: %v", err)
+			}
+			order = append(order, msg.ID)
+		}
+		if strings.Join(order, ",") != "RPCHealthCheck,n1,n2" {
+			t.Fatalf("Expected the high-priority message first, got %v", order)
+		}
+	})
+}
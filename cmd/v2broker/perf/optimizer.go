@@ -117,6 +117,11 @@ type Optimizer struct {
 	cancel            context.CancelFunc
 
 	optimizedMessages chan *proc.Message
+	// highMessages is the lane of high-priority (control-plane) messages;
+	// workers take from it before optimizedMessages
+	highMessages chan *proc.Message
+	// highPriorityMessages counts messages accepted into the high lane
+	highPriorityMessages int64
 
 	numWorkers int
 	workerWG   sync.WaitGroup
@@ -319,6 +324,7 @@ func New(router routing.Router) *Optimizer {
 		router:            router,
 		statsSyncInterval: 100 * time.Millisecond,
 		optimizedMessages: make(chan *proc.Message, 1000),
+		highMessages:      make(chan *proc.Message, 1000),
 		bufferCap:         1000,
 		numWorkers:        n,
 		ctx:               ctx,
@@ -400,6 +406,7 @@ func NewWithConfig(router routing.Router, cfg Config) *Optimizer {
 		router:            router,
 		statsSyncInterval: cfg.StatsInterval,
 		optimizedMessages: make(chan *proc.Message, cfg.BufferCap),
+		highMessages:      make(chan *proc.Message, cfg.BufferCap),
 		bufferCap:         cfg.BufferCap,
 		numWorkers:        cfg.NumWorkers,
 		offerPolicy:       cfg.OfferPolicy,
@@ -457,33 +464,30 @@ func (o *Optimizer) worker(id int) {
 	setIOPriority()
 
 	for {
-		// collect at least one message (blocking)
+		// collect at least one message (blocking), high lane first
 		var batch []*proc.Message
-		select {
-		case msg := <-o.optimizedMessages:
-			batch = append(batch, msg)
-
-			// Record message arrival in monitor
-			if o.monitor != nil {
-				o.monitor.RecordMessage()
-			}
-		case <-o.ctx.Done():
+		msg, ok := o.next()
+		if !ok {
 			return
 		}
+		batch = append(batch, msg)
 
-		// collect up to batchSize-1 more messages, waiting up to flushInterval
-		if o.batchSize > 1 {
+		// collect up to batchSize-1 more messages, waiting up to flushInterval;
+		// a high-priority message is not held back to fill a batch
+		if o.batchSize > 1 && !msg.IsHighPriority() {
 			deadline := time.NewTimer(o.flushInterval)
 		collectLoop:
 			for len(batch) < o.batchSize {
 				select {
+				case msg := <-o.highMessages:
+					o.recordArrival()
+					batch = append(batch, msg)
+					break collectLoop
 				case msg := <-o.optimizedMessages:
 					batch = append(batch, msg)
 
 					// Record message arrival in monitor
-					if o.monitor != nil {
-						o.monitor.RecordMessage()
-					}
+					o.recordArrival()
 					if len(batch) >= o.batchSize {
 						break collectLoop
 					}
@@ -501,6 +505,11 @@ func (o *Optimizer) worker(id int) {
 				default:
 				}
 			}
+			// A high-priority message collected last is routed first
+			if last := batch[len(batch)-1]; last.IsHighPriority() {
+				copy(batch[1:], batch[:len(batch)-1])
+				batch[0] = last
+			}
 		}
 
 		// process batch
@@ -526,6 +535,35 @@ func (o *Optimizer) worker(id int) {
 	}
 }
 
+// next blocks until a message is available and returns it, taking a waiting
+// high-priority message before any normal one. It returns false when the
+// optimizer stops.
+func (o *Optimizer) next() (*proc.Message, bool) {
+	select {
+	case msg := <-o.highMessages:
+		o.recordArrival()
+		return msg, true
+	default:
+	}
+	select {
+	case msg := <-o.highMessages:
+		o.recordArrival()
+		return msg, true
+	case msg := <-o.optimizedMessages:
+		o.recordArrival()
+		return msg, true
+	case <-o.ctx.Done():
+		return nil, false
+	}
+}
+
+// recordArrival records a dequeued message in the monitor
+func (o *Optimizer) recordArrival() {
+	if o.monitor != nil {
+		o.monitor.RecordMessage()
+	}
+}
+
 // SetLogger attaches a logger to the optimizer for runtime logging.
 func (o *Optimizer) SetLogger(l *common.Logger) {
 	o.logger = l
@@ -551,6 +589,8 @@ func (o *Optimizer) updateAtomic(msg *proc.Message, sent bool) {
 
 // Offer allows non-blocking enqueue to optimized queue.
 // Offer attempts a non-blocking enqueue and returns whether the message was accepted.
+// High-priority messages are queued in their own lane, so the offer policy
+// never drops or delays them on account of normal traffic.
 func (o *Optimizer) Offer(msg *proc.Message) bool {
 	// Update queue depth in monitor
 	if o.monitor != nil {
@@ -558,17 +598,29 @@ func (o *Optimizer) Offer(msg *proc.Message) bool {
 		o.monitor.UpdateMessageQueueDepth(queueDepth)
 	}
 
+	if msg.IsHighPriority() {
+		if !o.offerTo(o.highMessages, msg) {
+			return false
+		}
+		atomic.AddInt64(&o.highPriorityMessages, 1)
+		return true
+	}
+	return o.offerTo(o.optimizedMessages, msg)
+}
+
+// offerTo enqueues msg in the lane ch according to the offer policy
+func (o *Optimizer) offerTo(ch chan *proc.Message, msg *proc.Message) bool {
 	switch o.offerPolicy {
 	case "block":
 		// blocking send
-		o.optimizedMessages <- msg
+		ch <- msg
 		return true
 	case "timeout":
 		// try to send within timeout
 		if o.offerTimeout <= 0 {
 			// treat zero as immediate drop
 			select {
-			case o.optimizedMessages <- msg:
+			case ch <- msg:
 				return true
 			default:
 				atomic.AddInt64(&o.droppedMessages, 1)
@@ -578,7 +630,7 @@ func (o *Optimizer) Offer(msg *proc.Message) bool {
 		timer := time.NewTimer(o.offerTimeout)
 		defer timer.Stop()
 		select {
-		case o.optimizedMessages <- msg:
+		case ch <- msg:
 			return true
 		case <-timer.C:
 			atomic.AddInt64(&o.droppedMessages, 1)
@@ -587,18 +639,18 @@ func (o *Optimizer) Offer(msg *proc.Message) bool {
 	case "drop_oldest":
 		// remove oldest message if possible, then enqueue
 		select {
-		case o.optimizedMessages <- msg:
+		case ch <- msg:
 			return true
 		default:
 			// try to remove one oldest
 			select {
-			case <-o.optimizedMessages:
+			case <-ch:
 				atomic.AddInt64(&o.droppedMessages, 1)
 			default:
 			}
 			// attempt to enqueue again
 			select {
-			case o.optimizedMessages <- msg:
+			case ch <- msg:
 				return true
 			default:
 				atomic.AddInt64(&o.droppedMessages, 1)
@@ -608,7 +660,7 @@ func (o *Optimizer) Offer(msg *proc.Message) bool {
 	default:
 		// default: drop (non-blocking)
 		select {
-		case o.optimizedMessages <- msg:
+		case ch <- msg:
 			return true
 		default:
 			atomic.AddInt64(&o.droppedMessages, 1)
@@ -636,6 +688,9 @@ func (o *Optimizer) Metrics() map[string]interface{} {
 		"total_messages_processed": total,
 		"messages_per_second":      mps,
 		"message_channel_buffer":   cap(o.optimizedMessages),
+		"high_priority_buffer":     cap(o.highMessages),
+		"high_priority_queued":     len(o.highMessages),
+		"high_priority_messages":   atomic.LoadInt64(&o.highPriorityMessages),
 		"active_workers":           o.numWorkers,
 		"dropped_messages":         atomic.LoadInt64(&o.droppedMessages),
 		"go_routines":              runtime.NumGoroutine(),
@@ -242,3 +242,71 @@ func BenchmarkOptimizer_LargePayloadMemory(b *testing.B) {
 	// Wait for messages to be processed
 	time.Sleep(200 * time.Millisecond)
 }
+
+// gatedRouter records routed messages and holds the first one until released
+type gatedRouter struct {
+	simpleRouter
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (r *gatedRouter) Route(msg *proc.Message, sourceProcess string) error {
+	r.once.Do(func() {
+		close(r.started)
+		<-r.release
+	})
+	return r.simpleRouter.Route(msg, sourceProcess)
+}
+
+func (r *gatedRouter) ProcessBrokerMessage(msg *proc.Message) error {
+	return r.Route(msg, "broker")
+}
+
+func TestOfferHighPriorityLane(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestOfferHighPriorityLane", nil, func(t *testing.T, tx *gorm.DB) {
+		router := &gatedRouter{started: make(chan struct{}), release: make(chan struct{})}
+		opt := NewWithConfig(router, Config{BufferCap: 2, NumWorkers: 1, OfferPolicy: "drop", BatchSize: 1})
+		defer opt.Stop()
+
+		// The only worker is busy with n0 while the lanes fill up
+		opt.Offer(&proc.Message{ID: "n0"})
+		<-router.started
+		for _, id := range []string{"n1", "n2"} {
+			if !opt.Offer(&proc.Message{ID: id}) {
+				t.Fatalf("expected %s accepted", id)
+			}
+		}
+		if opt.Offer(&proc.Message{ID: "n3"}) {
+			t.Fatal("expected n3 dropped with the normal lane full")
+		}
+		if !opt.Offer(&proc.Message{ID: "RPCCancelRPC", Priority: proc.MessagePriorityHigh}) {
+			t.Fatal("expected the high-priority message accepted with the normal lane full")
+		}
+		close(router.release)
+
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			router.mu.Lock()
+			count := len(router.msgs)
+			router.mu.Unlock()
+			if count >= 4 {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		router.mu.Lock()
+		defer router.mu.Unlock()
+		var order []string
+		for _, m := range router.msgs {
+			order = append(order, m.ID)
+		}
+		if fmt.Sprint(order) != "[n0 RPCCancelRPC n1 n2]" {
+			t.Fatalf("expected the high-priority message routed ahead of queued ones, got %v", order)
+		}
+		if m := opt.Metrics(); m["high_priority_messages"] != int64(1) || m["dropped_messages"] != int64(1) {
+			t.Fatalf("unexpected metrics %v", m)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestUDSTransport_SendFragmentsLargeMessages(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestUDSTransport_SendFragmentsLargeMessages", nil, func(t *testing.T, tx *gorm.DB) {
+		dir, err := os.MkdirTemp("", "v2efrag")
+		if err != nil {
+			t.Fatalf("MkdirTemp failed: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		socketPath := filepath.Join(dir, "access.sock")
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		defer listener.Close()
+
+		const maxSize = 4096
+		tr := NewUDSTransport(socketPath, false)
+		tr.SetMaxFragmentSize(maxSize)
+		if err := tr.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer tr.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept failed: %v", err)
+		}
+		defer conn.Close()
+
+		payload := []byte(`"` + strings.Repeat("x", 20*maxSize) + `"`)
+		msg := &proc.Message{Type: proc.MessageTypeResponse, ID: "RPCGetBig", Source: "local", Target: "access", CorrelationID: "corr-1", Payload: payload}
+		go func() { _ = tr.Send(msg) }()
+
+		// No line exceeds a few times the fragment size, so an unfragmented
+		// message would fail the scan
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, 0, maxSize), 4*maxSize)
+		reassembler := subprocess.NewReassembler(0)
+		for scanner.Scan() {
+			var frag proc.Message
+			if err := subprocess.UnmarshalFast(scanner.Bytes(), &frag); err != nil {
+				t.Fatalf("Failed to decode line: %v", err)
+			}
+			if frag.Type != proc.MessageTypeFragment || frag.Target != "access" || frag.CorrelationID != "corr-1" {
+				t.Fatalf("Expected a routed fragment, got %+v", frag)
+			}
+			complete, err := reassembler.Add(&frag)
+			if err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			if complete != nil {
+				if string(complete.Payload) != string(payload) || complete.ID != "RPCGetBig" {
+					t.Fatalf("Expected the original message reassembled, got %d bytes for %s", len(complete.Payload), complete.ID)
+				}
+				return
+			}
+		}
+		t.Fatalf("Connection ended before the message was reassembled: %v", scanner.Err())
+	})
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/bytedance/sonic"
 	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
 // UDSTransport implements Transport using Unix Domain Sockets for communication
@@ -30,6 +31,9 @@ type UDSTransport struct {
 	errorHandler         func(error)
 	done                 chan struct{}  // Signals acceptLoop to exit
 	acceptLoopWg         sync.WaitGroup // Tracks acceptLoop goroutine
+	// maxFragmentSize is the marshaled size above which sent messages are
+	// split into fragments; 0 disables fragmentation
+	maxFragmentSize int
 }
 
 // NewUDSTransport creates a new UDSTransport with the specified socket path
@@ -40,10 +44,19 @@ func NewUDSTransport(socketPath string, isServer bool) *UDSTransport {
 		maxReconnectAttempts: 5,
 		reconnectDelay:       1 * time.Second,
 		done:                 make(chan struct{}),
+		maxFragmentSize:      subprocess.DefaultProcMaxFragmentSize(),
 	}
 	return transport
 }
 
+// SetMaxFragmentSize sets the marshaled message size above which sent
+// messages are split into fragments; 0 disables fragmentation
+func (t *UDSTransport) SetMaxFragmentSize(size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxFragmentSize = size
+}
+
 // SetReconnectOptions sets the reconnection options for the transport
 func (t *UDSTransport) SetReconnectOptions(maxAttempts int, delay time.Duration) {
 	t.mu.Lock()
@@ -119,13 +132,16 @@ func (t *UDSTransport) Connect() error {
 	return nil
 }
 
-// Send sends a message through the UDS connection
+// Send sends a message through the UDS connection. A message larger than
+// the maximum fragment size is sent as ordered fragments, which the
+// receiving subprocess reassembles.
 func (t *UDSTransport) Send(msg *proc.Message) error {
 	t.mu.RLock()
 	if t.connection == nil {
 		t.mu.RUnlock()
 		return fmt.Errorf("transport not connected")
 	}
+	maxFragmentSize := t.maxFragmentSize
 	t.mu.RUnlock()
 
 	data, err := sonic.Marshal(msg)
@@ -133,6 +149,28 @@ func (t *UDSTransport) Send(msg *proc.Message) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	fragments, err := subprocess.FragmentMessage(msg, data, maxFragmentSize)
+	if err != nil {
+		return err
+	}
+	if fragments == nil {
+		return t.writeLine(data)
+	}
+	for _, frag := range fragments {
+		fragData, err := sonic.Marshal(frag)
+		if err != nil {
+			return fmt.Errorf("failed to marshal fragment: %w", err)
+		}
+		if err := t.writeLine(fragData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLine writes one marshaled message followed by a newline, reconnecting
+// once if the write fails
+func (t *UDSTransport) writeLine(data []byte) error {
 	t.mu.RLock()
 	conn := t.connection
 	t.mu.RUnlock()
@@ -215,3 +215,75 @@ func createGetASVSByIDHandler(store *asvs.LocalASVSStore, logger *common.Logger)
 		}, nil
 	}
 }
+
+// createGetASVSByCWEHandler creates a handler for RPCGetASVSByCWE
+func createGetASVSByCWEHandler(store *asvs.LocalASVSStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			CWEID string `json:"cwe_id"`
+		}
+
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			logger.Debug("Processing GetASVSByCWE request failed due to malformed payload: %s", string(msg.Payload))
+			return &subprocess.Message{
+				Type:          subprocess.MessageTypeError,
+				ID:            msg.ID,
+				Error:         "failed to parse request",
+				CorrelationID: msg.CorrelationID,
+				Target:        msg.Source,
+			}, nil
+		}
+
+		if req.CWEID == "" {
+			return &subprocess.Message{
+				Type:          subprocess.MessageTypeError,
+				ID:            msg.ID,
+				Error:         "cwe_id is required",
+				CorrelationID: msg.CorrelationID,
+				Target:        msg.Source,
+			}, nil
+		}
+
+		logger.Debug(LogMsgGetASVSByCWEReq, req.CWEID)
+
+		items, err := store.GetByCWE(ctx, req.CWEID)
+		if err != nil {
+			logger.Warn(LogMsgFailedGetASVSByCWE, err, req.CWEID)
+			return &subprocess.Message{
+				Type:          subprocess.MessageTypeError,
+				ID:            msg.ID,
+				Error:         err.Error(),
+				CorrelationID: msg.CorrelationID,
+				Target:        msg.Source,
+			}, nil
+		}
+
+		logger.Debug(LogMsgGetASVSByCWECompleted, len(items), req.CWEID)
+
+		resp := map[string]interface{}{
+			"requirements": items,
+			"total":        len(items),
+		}
+
+		jsonData, err := subprocess.MarshalFast(resp)
+		if err != nil {
+			logger.Warn(LogMsgFailedMarshalListASVS, err)
+			return &subprocess.Message{
+				Type:          subprocess.MessageTypeError,
+				ID:            msg.ID,
+				Error:         "failed to marshal ASVS requirements",
+				CorrelationID: msg.CorrelationID,
+				Target:        msg.Source,
+			}, nil
+		}
+
+		return &subprocess.Message{
+			Type:          subprocess.MessageTypeResponse,
+			ID:            msg.ID,
+			CorrelationID: msg.CorrelationID,
+			Target:        msg.Source,
+			Payload:       jsonData,
+		}, nil
+	}
+}
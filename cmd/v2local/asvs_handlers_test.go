@@ -38,7 +38,7 @@ func TestASVSHandlers(t *testing.T) {
 		// Create a mock HTTP server that serves a test CSV
 		csvContent := `Requirement ID,Chapter,Section,Description,L1,L2,L3,CWE
 1.1.1,V1,Architecture,Test requirement 1,x,x,x,CWE-1127
-1.1.2,V1,Architecture,Test requirement 2,,x,x,CWE-79
+1.1.2,V1,Architecture,Test requirement 2,,x,x,"CWE-79, CWE-1127"
 2.1.1,V2,Authentication,Test requirement 3,x,x,,CWE-521
 `
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -157,6 +157,45 @@ func TestASVSHandlers(t *testing.T) {
 		}
 	})
 
+	// Test GetASVSByCWE handler
+	t.Run("GetASVSByCWE", func(t *testing.T) {
+		cweH := createGetASVSByCWEHandler(store, logger)
+		cweReq := map[string]interface{}{"cwe_id": "1127"}
+		cweResp, err := cweH(ctx, &subprocess.Message{
+			Type:    subprocess.MessageTypeRequest,
+			ID:      "c1",
+			Payload: func() []byte { b, _ := subprocess.MarshalFast(cweReq); return b }(),
+		})
+		if err != nil || cweResp == nil || cweResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("get by CWE handler failed: err=%v resp=%v", err, cweResp)
+		}
+
+		var result struct {
+			Requirements []asvs.ASVSRequirement `json:"requirements"`
+			Total        int                    `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(cweResp, &result); err != nil {
+			t.Fatalf("unmarshal get by CWE result: %v", err)
+		}
+		if result.Total != 2 || len(result.Requirements) != 2 {
+			t.Fatalf("expected 2 requirements for CWE-1127 got %d", result.Total)
+		}
+		if got := result.Requirements[1].CWEs; len(got) != 2 || got[0] != "CWE-79" || got[1] != "CWE-1127" {
+			t.Fatalf("expected CWEs [CWE-79 CWE-1127] for 1.1.2 got %v", got)
+		}
+
+		for _, payload := range []map[string]interface{}{{}, {"cwe_id": "not-a-cwe"}} {
+			resp, err := cweH(ctx, &subprocess.Message{
+				Type:    subprocess.MessageTypeRequest,
+				ID:      "c2",
+				Payload: func() []byte { b, _ := subprocess.MarshalFast(payload); return b }(),
+			})
+			if err != nil || resp == nil || resp.Type != subprocess.MessageTypeError {
+				t.Fatalf("expected error for payload %v", payload)
+			}
+		}
+	})
+
 	// Test error cases
 	t.Run("GetASVSByID_NotFound", func(t *testing.T) {
 		getH := createGetASVSByIDHandler(store, logger)
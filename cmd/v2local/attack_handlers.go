@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/cyw0ng95/v2e/pkg/attack"
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"gorm.io/gorm"
 )
 
 // createImportATTACKsHandler handles importing ATT&CK data from XLSX file
@@ -14,8 +16,9 @@ func createImportATTACKsHandler(store *attack.LocalAttackStore, logger *common.L
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug(LogMsgImportATTACKInvoked)
 		var req struct {
-			Path  string `json:"path"`
-			Force bool   `json:"force,omitempty"`
+			Path   string `json:"path"`
+			Force  bool   `json:"force,omitempty"`
+			DryRun bool   `json:"dry_run,omitempty"`
 		}
 		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
 			logger.Warn(LogMsgFailedParseReq, errResp.Error)
@@ -25,14 +28,15 @@ func createImportATTACKsHandler(store *attack.LocalAttackStore, logger *common.L
 		if errResp := subprocess.RequireField(msg, req.Path, "path"); errResp != nil {
 			return errResp, nil
 		}
-		if err := store.ImportFromXLSX(req.Path, req.Force); err != nil {
+		report, err := store.ImportFromXLSXWithReport(req.Path, req.Force, req.DryRun)
+		if err != nil {
 			logger.Warn(LogMsgFailedImportATTACKXLSX, err, req.Path)
 			if _, statErr := os.Stat(req.Path); statErr != nil {
 				logger.Warn(LogMsgATTACKImportStatError, statErr, req.Path)
 			}
 			return subprocess.NewErrorResponse(msg, "failed to import ATT&CKs"), nil
 		}
-		return subprocess.NewSuccessResponse(msg, map[string]bool{"success": true})
+		return subprocess.NewSuccessResponse(msg, importResult(report))
 	}
 }
 
@@ -56,27 +60,108 @@ func createGetAttackTechniqueByIDHandler(store *attack.LocalAttackStore, logger
 			return subprocess.NewErrorResponse(msg, "ATT&CK technique not found"), nil
 		}
 
-		// Build a client-friendly payload
+		resp, err := subprocess.NewSuccessResponse(msg, attackTechniquePayload(item))
+		if err != nil {
+			logger.Warn(LogMsgFailedMarshalAttackTechnique, err, req.ID)
+			return subprocess.NewErrorResponse(msg, "failed to marshal ATT&CK technique"), nil
+		}
+		return resp, nil
+	}
+}
+
+// createGetAttackSubTechniquesHandler handles listing the sub-techniques of
+// an ATT&CK technique
+func createGetAttackSubTechniquesHandler(store *attack.LocalAttackStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse request: %v", errResp.Error)
+			return errResp, nil
+		}
+		if errResp := subprocess.RequireField(msg, req.ID, "id"); errResp != nil {
+			return errResp, nil
+		}
+		logger.Debug(LogMsgGetAttackSubTechniquesReq, req.ID)
+		items, err := store.GetSubTechniques(ctx, req.ID)
+		if err != nil {
+			logger.Warn(LogMsgFailedGetAttackSubTechniques, err, req.ID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return subprocess.NewErrorResponse(msg, "ATT&CK technique not found"), nil
+			}
+			return subprocess.NewErrorResponse(msg, "failed to get ATT&CK sub-techniques: "+err.Error()), nil
+		}
+
+		mapped := make([]map[string]interface{}, 0, len(items))
+		for i := range items {
+			mapped = append(mapped, attackTechniquePayload(&items[i]))
+		}
 		payload := map[string]interface{}{
-			"id":          item.ID,
-			"name":        item.Name,
-			"description": item.Description,
-			"domain":      item.Domain,
-			"platform":    item.Platform,
-			"created":     item.Created,
-			"modified":    item.Modified,
-			"revoked":     item.Revoked,
-			"deprecated":  item.Deprecated,
+			"id":             req.ID,
+			"sub_techniques": mapped,
+			"total":          len(mapped),
 		}
 		resp, err := subprocess.NewSuccessResponse(msg, payload)
 		if err != nil {
 			logger.Warn(LogMsgFailedMarshalAttackTechnique, err, req.ID)
-			return subprocess.NewErrorResponse(msg, "failed to marshal ATT&CK technique"), nil
+			return subprocess.NewErrorResponse(msg, "failed to marshal ATT&CK sub-techniques"), nil
 		}
 		return resp, nil
 	}
 }
 
+// createGetAttackTechniqueRelationshipsHandler handles getting the
+// mitigations, groups and software related to an ATT&CK technique
+func createGetAttackTechniqueRelationshipsHandler(store *attack.LocalAttackStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse request: %v", errResp.Error)
+			return errResp, nil
+		}
+		if errResp := subprocess.RequireField(msg, req.ID, "id"); errResp != nil {
+			return errResp, nil
+		}
+		logger.Debug(LogMsgGetAttackRelationshipsReq, req.ID)
+		rels, err := store.GetTechniqueRelationships(ctx, req.ID)
+		if err != nil {
+			logger.Warn(LogMsgFailedGetAttackRelationships, err, req.ID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return subprocess.NewErrorResponse(msg, "ATT&CK technique not found"), nil
+			}
+			return subprocess.NewErrorResponse(msg, "failed to get ATT&CK technique relationships: "+err.Error()), nil
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, rels)
+		if err != nil {
+			logger.Warn(LogMsgFailedMarshalAttackTechnique, err, req.ID)
+			return subprocess.NewErrorResponse(msg, "failed to marshal ATT&CK technique relationships"), nil
+		}
+		return resp, nil
+	}
+}
+
+// attackTechniquePayload builds the client-friendly form of a technique
+func attackTechniquePayload(item *attack.AttackTechnique) map[string]interface{} {
+	payload := map[string]interface{}{
+		"id":          item.ID,
+		"name":        item.Name,
+		"description": item.Description,
+		"domain":      item.Domain,
+		"platform":    item.Platform,
+		"created":     item.Created,
+		"modified":    item.Modified,
+		"revoked":     item.Revoked,
+		"deprecated":  item.Deprecated,
+	}
+	if item.ParentID != "" {
+		payload["parent_id"] = item.ParentID
+	}
+	return payload
+}
+
 // createGetAttackTacticByIDHandler handles getting an ATT&CK tactic by ID
 func createGetAttackTacticByIDHandler(store *attack.LocalAttackStore, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -262,17 +347,7 @@ func createListAttackTechniquesHandler(store *attack.LocalAttackStore, logger *c
 		mapped := make([]map[string]interface{}, 0, len(items))
 		for _, it := range items {
 			logger.Debug(LogMsgMappingAttackTechnique, msg.ID, it.ID)
-			mapped = append(mapped, map[string]interface{}{
-				"id":          it.ID,
-				"name":        it.Name,
-				"description": it.Description,
-				"domain":      it.Domain,
-				"platform":    it.Platform,
-				"created":     it.Created,
-				"modified":    it.Modified,
-				"revoked":     it.Revoked,
-				"deprecated":  it.Deprecated,
-			})
+			mapped = append(mapped, attackTechniquePayload(&it))
 		}
 
 		resp := map[string]interface{}{
@@ -546,6 +621,40 @@ func createGetAttackImportMetadataHandler(store *attack.LocalAttackStore, logger
 	}
 }
 
+// createGetAttackCoverageHandler handles counting ATT&CK techniques per
+// tactic for the matrix view. Every tactic is listed, including those without
+// techniques, and a technique of several tactics counts under each.
+func createGetAttackCoverageHandler(store *attack.LocalAttackStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgGetAttackCoverageInvoked, msg.ID)
+		var req struct {
+			IncludeSubTechniques bool `json:"include_subtechniques,omitempty"`
+		}
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn(LogMsgFailedParseReq, errResp.Error)
+				return errResp, nil
+			}
+		}
+
+		coverage, total, err := store.GetTacticCoverage(ctx, req.IncludeSubTechniques)
+		if err != nil {
+			logger.Warn(LogMsgFailedGetAttackCoverage, err)
+			return subprocess.NewErrorResponse(msg, "failed to get ATT&CK tactic coverage"), nil
+		}
+		counts := make(map[string]int, len(coverage))
+		for _, c := range coverage {
+			counts[c.ID] = c.Count
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"tactics": coverage,
+			"counts":  counts,
+			"total":   total,
+		})
+	}
+}
+
 // createGetAttackTechniqueHandler handles getting an ATT&CK technique by ID (alias for GetAttackTechniqueByID)
 func createGetAttackTechniqueHandler(store *attack.LocalAttackStore, logger *common.Logger) subprocess.Handler {
 	return createGetAttackTechniqueByIDHandler(store, logger)
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// capabilityProber reports which features of a data type are usable
+type capabilityProber interface {
+	DataCapabilities(ctx context.Context) (*capability.Capabilities, error)
+}
+
+// createGetDataCapabilitiesHandler creates a handler for
+// RPCGetDataCapabilities, which reports per data type whether data is stored
+// and whether the derived data optional features rely on is populated, in
+// the order the stores are given
+func createGetDataCapabilitiesHandler(logger *common.Logger, stores ...capabilityProber) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("RPCGetDataCapabilities handler invoked. msg.ID=%s, correlation_id=%s", msg.ID, msg.CorrelationID)
+
+		capabilities := make([]capability.Capabilities, 0, len(stores))
+		for _, store := range stores {
+			c, err := store.DataCapabilities(ctx)
+			if err != nil {
+				logger.Warn("Failed to probe data capabilities: %v", err)
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to probe data capabilities: %v", err)), nil
+			}
+			capabilities = append(capabilities, *c)
+		}
+
+		resp, err := subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"capabilities": capabilities,
+		})
+		if err != nil {
+			logger.Error("Failed to marshal data capabilities: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal data capabilities: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"os"
@@ -10,19 +11,22 @@ import (
 
 	"github.com/cyw0ng95/v2e/pkg/capec"
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
 // capecStore captures the subset of CAPEC store behaviors needed by handlers.
 type capecStore interface {
-	ImportFromXML(xmlPath string, force bool) error
+	ImportFromXMLWithSchema(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error)
 	GetCatalogMeta(ctx context.Context) (*capec.CAPECCatalogMeta, error)
-	ListCAPECsPaginated(ctx context.Context, offset, limit int) ([]capec.CAPECItemModel, int64, error)
+	ListCAPECsFiltered(ctx context.Context, offset, limit int, abstraction, sortBy, order string) ([]capec.CAPECItemModel, int64, error)
 	GetByID(ctx context.Context, capecID string) (*capec.CAPECItemModel, error)
 	GetRelatedWeaknesses(ctx context.Context, capecID int) ([]capec.CAPECRelatedWeaknessModel, error)
 	GetExamples(ctx context.Context, capecID int) ([]capec.CAPECExampleModel, error)
 	GetMitigations(ctx context.Context, capecID int) ([]capec.CAPECMitigationModel, error)
 	GetReferences(ctx context.Context, capecID int) ([]capec.CAPECReferenceModel, error)
+	GetAttackMappings(ctx context.Context, capecID int) ([]capec.CAPECAttackMappingModel, error)
+	GetCAPECIDsByCWE(ctx context.Context, cweID string) ([]int, error)
 }
 
 // createImportCAPECsHandler creates a handler for RPCImportCAPECs
@@ -31,9 +35,10 @@ func createImportCAPECsHandler(store capecStore, logger *common.Logger) subproce
 		logger.Info(LogMsgStartingImportCAPEC, msg.CorrelationID)
 		logger.Debug("RPCImportCAPECs handler invoked. msg.ID=%s, correlation_id=%s", msg.ID, msg.CorrelationID)
 		var req struct {
-			Path  string `json:"path"`
-			XSD   string `json:"xsd,omitempty"`
-			Force bool   `json:"force,omitempty"`
+			Path   string `json:"path"`
+			XSD    string `json:"xsd,omitempty"`
+			Force  bool   `json:"force,omitempty"`
+			DryRun bool   `json:"dry_run,omitempty"`
 		}
 		if msg.Payload != nil {
 			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
@@ -45,18 +50,39 @@ func createImportCAPECsHandler(store capecStore, logger *common.Logger) subproce
 		if errResp := subprocess.RequireField(msg, req.Path, "path"); errResp != nil {
 			return errResp, nil
 		}
-		logger.Info("Starting CAPEC import from path: %s. correlation_id=%s", req.Path, msg.CorrelationID)
-		if err := store.ImportFromXML(req.Path, req.Force); err != nil {
+		logger.Info("Starting CAPEC import from path: %s, dry_run=%t. correlation_id=%s", req.Path, req.DryRun, msg.CorrelationID)
+		report, err := store.ImportFromXMLWithSchema(req.Path, req.XSD, req.Force, req.DryRun)
+		if err != nil {
 			logger.Warn("Failed to import CAPEC from XML: %v (path: %s)", err, req.Path)
 			if _, statErr := os.Stat(req.Path); statErr != nil {
 				logger.Warn("CAPEC import file stat error: %v (path: %s)", statErr, req.Path)
 			}
-			return subprocess.NewErrorResponse(msg, "failed to import CAPECs"), nil
+			return importErrorResponse(msg, err), nil
 		}
 		logger.Info(LogMsgImportCAPECCompleted, req.Path)
 		logger.Debug("Processing ImportCAPECs request completed successfully for path %s. correlation_id=%s", req.Path, msg.CorrelationID)
-		return subprocess.NewSuccessResponse(msg, map[string]bool{"success": true})
+		return subprocess.NewSuccessResponse(msg, importResult(report))
+	}
+}
+
+// importResult is the response of an import RPC: success plus the import
+// report (dry_run, inserted, updated, warnings)
+func importResult(report *catalog.ImportReport) interface{} {
+	return struct {
+		Success bool `json:"success"`
+		*catalog.ImportReport
+	}{Success: true, ImportReport: report}
+}
+
+// importErrorResponse is the response of a failed import RPC. A file rejected
+// as malformed or not conforming to the XSD yields its validation errors with
+// their line and column; other failures a generic message.
+func importErrorResponse(msg *subprocess.Message, err error) *subprocess.Message {
+	var schemaErr *capec.SchemaError
+	if errors.As(err, &schemaErr) {
+		return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to import CAPECs: %v", schemaErr))
 	}
+	return subprocess.NewErrorResponse(msg, "failed to import CAPECs")
 }
 
 // xmlInnerToPlain strips all XML/HTML tags and returns plain text suitable for
@@ -98,13 +124,14 @@ func createForceImportCAPECsHandler(store capecStore, logger *common.Logger) sub
 			return errResp, nil
 		}
 		logger.Info("Starting force CAPEC import from path: %s. correlation_id=%s", req.Path, msg.CorrelationID)
-		if err := store.ImportFromXML(req.Path, true); err != nil {
+		report, err := store.ImportFromXMLWithSchema(req.Path, req.XSD, true, false)
+		if err != nil {
 			logger.Warn("Failed to import CAPEC from XML (force): %v (path: %s)", err, req.Path)
-			return subprocess.NewErrorResponse(msg, "failed to import CAPECs"), nil
+			return importErrorResponse(msg, err), nil
 		}
 		logger.Info(LogMsgForceImportCAPECCompleted, req.Path)
 		logger.Debug("Processing ForceImportCAPECs request completed successfully for path %s. correlation_id=%s", req.Path, msg.CorrelationID)
-		return subprocess.NewSuccessResponse(msg, map[string]bool{"success": true})
+		return subprocess.NewSuccessResponse(msg, importResult(report))
 	}
 }
 
@@ -160,20 +187,28 @@ func createGetCAPECByIDHandler(store capecStore, logger *common.Logger) subproce
 			}
 		}
 
+		var techniques []map[string]string
+		if ms, err := store.GetAttackMappings(ctx, item.CAPECID); err == nil {
+			for _, m := range ms {
+				techniques = append(techniques, map[string]string{"technique_id": m.TechniqueID, "name": m.TechniqueName})
+			}
+		}
+
 		// Build a client-friendly payload: use string ID "CAPEC-<n>" and simple keys
 		description := xmlInnerToPlain(item.Description)
 		payload := map[string]interface{}{
-			"id":               fmt.Sprintf("CAPEC-%d", item.CAPECID),
-			"name":             item.Name,
-			"summary":          xmlInnerToPlain(item.Summary),
-			"description":      description,
-			"status":           item.Status,
-			"likelihood":       item.Likelihood,
-			"typical_severity": item.TypicalSeverity,
-			"weaknesses":       weaknesses,
-			"examples":         examples,
-			"mitigations":      mitigations,
-			"references":       references,
+			"id":                fmt.Sprintf("CAPEC-%d", item.CAPECID),
+			"name":              item.Name,
+			"summary":           xmlInnerToPlain(item.Summary),
+			"description":       description,
+			"status":            item.Status,
+			"likelihood":        item.Likelihood,
+			"typical_severity":  item.TypicalSeverity,
+			"weaknesses":        weaknesses,
+			"examples":          examples,
+			"mitigations":       mitigations,
+			"references":        references,
+			"attack_techniques": techniques,
 		}
 		resp, err := subprocess.NewSuccessResponse(msg, payload)
 		if err != nil {
@@ -184,6 +219,44 @@ func createGetCAPECByIDHandler(store capecStore, logger *common.Logger) subproce
 	}
 }
 
+// createGetCAPECsByCWEHandler creates a handler for RPCGetCAPECsByCWE, which
+// lists the CAPECs whose related weaknesses include a CWE
+func createGetCAPECsByCWEHandler(store capecStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			CWEID string `json:"cwe_id"`
+		}
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn("Failed to parse request: %v", errResp.Error)
+				return errResp, nil
+			}
+		}
+		if errResp := subprocess.RequireField(msg, req.CWEID, "cwe_id"); errResp != nil {
+			return errResp, nil
+		}
+		n, err := capec.NormalizeCWEID(req.CWEID)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, err.Error()), nil
+		}
+		ids, err := store.GetCAPECIDsByCWE(ctx, n)
+		if err != nil {
+			logger.Warn("Failed to get CAPECs of CWE: %v (cwe_id=%s)", err, req.CWEID)
+			return subprocess.NewErrorResponse(msg, "failed to get CAPECs of CWE"), nil
+		}
+		capecIDs := make([]string, len(ids))
+		for i, id := range ids {
+			capecIDs[i] = fmt.Sprintf("CAPEC-%d", id)
+		}
+		logger.Debug("GetCAPECsByCWE: %d CAPECs for CWE-%s", len(capecIDs), n)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"cwe_id":    "CWE-" + n,
+			"capec_ids": capecIDs,
+			"total":     len(capecIDs),
+		})
+	}
+}
+
 // createGetCAPECCatalogMetaHandler creates a handler for RPCGetCAPECCatalogMeta
 func createGetCAPECCatalogMetaHandler(store capecStore, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -221,8 +294,11 @@ func createListCAPECsHandler(store capecStore, logger *common.Logger) subprocess
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug("Processing ListCAPECs request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
 		var req struct {
-			Offset int `json:"offset"`
-			Limit  int `json:"limit"`
+			Offset           int    `json:"offset"`
+			Limit            int    `json:"limit"`
+			SortBy           string `json:"sortBy"`
+			Order            string `json:"order"`
+			AbstractionLevel string `json:"abstractionLevel"`
 		}
 		if msg.Payload != nil {
 			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
@@ -237,8 +313,14 @@ func createListCAPECsHandler(store capecStore, logger *common.Logger) subprocess
 		if req.Offset < 0 {
 			req.Offset = 0
 		}
-		logger.Info("Processing ListCAPECs request - Message ID: %s, Correlation ID: %s, Offset: %d, Limit: %d", msg.ID, msg.CorrelationID, req.Offset, req.Limit)
-		items, total, err := store.ListCAPECsPaginated(ctx, req.Offset, req.Limit)
+		if req.SortBy == "" {
+			req.SortBy = capec.SortByID
+		}
+		if req.Order == "" {
+			req.Order = capec.OrderAsc
+		}
+		logger.Info("Processing ListCAPECs request - Message ID: %s, Correlation ID: %s, Offset: %d, Limit: %d, SortBy: %s, Order: %s, AbstractionLevel: %s", msg.ID, msg.CorrelationID, req.Offset, req.Limit, req.SortBy, req.Order, req.AbstractionLevel)
+		items, total, err := store.ListCAPECsFiltered(ctx, req.Offset, req.Limit, req.AbstractionLevel, req.SortBy, req.Order)
 		if err != nil {
 			logger.Warn("Failed to list CAPECs from store - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
 			logger.Debug("Processing ListCAPECs request failed - Message ID: %s, Error details: %v", msg.ID, err)
@@ -285,18 +367,29 @@ func createListCAPECsHandler(store capecStore, logger *common.Logger) subprocess
 				logger.Debug("No references found for CAPEC %d - Message ID: %s, Error: %v", it.CAPECID, msg.ID, err)
 			}
 
+			var techniques []map[string]string
+			if ms, err := store.GetAttackMappings(ctx, it.CAPECID); err == nil {
+				for _, m := range ms {
+					techniques = append(techniques, map[string]string{"technique_id": m.TechniqueID, "name": m.TechniqueName})
+				}
+			} else {
+				logger.Debug("No ATT&CK mappings found for CAPEC %d - Message ID: %s, Error: %v", it.CAPECID, msg.ID, err)
+			}
+
 			mapped = append(mapped, map[string]interface{}{
-				"id":               fmt.Sprintf("CAPEC-%d", it.CAPECID),
-				"name":             it.Name,
-				"summary":          xmlInnerToPlain(it.Summary),
-				"description":      xmlInnerToPlain(it.Description),
-				"status":           it.Status,
-				"likelihood":       it.Likelihood,
-				"typical_severity": it.TypicalSeverity,
-				"weaknesses":       weaknesses,
-				"examples":         examples,
-				"mitigations":      mitigations,
-				"references":       references,
+				"id":                fmt.Sprintf("CAPEC-%d", it.CAPECID),
+				"name":              it.Name,
+				"summary":           xmlInnerToPlain(it.Summary),
+				"description":       xmlInnerToPlain(it.Description),
+				"status":            it.Status,
+				"abstraction":       it.Abstraction,
+				"likelihood":        it.Likelihood,
+				"typical_severity":  it.TypicalSeverity,
+				"weaknesses":        weaknesses,
+				"examples":          examples,
+				"mitigations":       mitigations,
+				"references":        references,
+				"attack_techniques": techniques,
 			})
 		}
 
@@ -305,6 +398,11 @@ func createListCAPECsHandler(store capecStore, logger *common.Logger) subprocess
 			"offset": req.Offset,
 			"limit":  req.Limit,
 			"total":  total,
+			"sortBy": req.SortBy,
+			"order":  req.Order,
+		}
+		if req.AbstractionLevel != "" {
+			resp["abstractionLevel"] = req.AbstractionLevel
 		}
 		msgResp, err := subprocess.NewSuccessResponse(msg, resp)
 		if err != nil {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/cyw0ng95/v2e/pkg/capec"
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
@@ -21,6 +22,9 @@ type stubCAPECStore struct {
 	listItems     []capec.CAPECItemModel
 	listTotal     int64
 	listErr       error
+	lastList      struct {
+		abstraction, sortBy, order string
+	}
 	getItem       *capec.CAPECItemModel
 	getErr        error
 	related       []capec.CAPECRelatedWeaknessModel
@@ -31,20 +35,31 @@ type stubCAPECStore struct {
 	mitigationErr error
 	references    []capec.CAPECReferenceModel
 	refErr        error
+	techniques    []capec.CAPECAttackMappingModel
+	byCWE         map[string][]int
+	report        *catalog.ImportReport
 	lastImport    struct {
-		path  string
-		xsd   string
-		force bool
+		path   string
+		xsd    string
+		force  bool
+		dryRun bool
 	}
 }
 
-func (s *stubCAPECStore) ImportFromXML(xmlPath string, force bool) error {
+func (s *stubCAPECStore) ImportFromXMLWithSchema(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
 	s.lastImport = struct {
-		path  string
-		xsd   string
-		force bool
-	}{path: xmlPath, force: force}
-	return s.importErr
+		path   string
+		xsd    string
+		force  bool
+		dryRun bool
+	}{path: xmlPath, xsd: xsdPath, force: force, dryRun: dryRun}
+	if s.importErr != nil {
+		return nil, s.importErr
+	}
+	if s.report == nil {
+		return catalog.NewImportReport(dryRun), nil
+	}
+	return s.report, nil
 }
 
 func (s *stubCAPECStore) GetCatalogMeta(ctx context.Context) (*capec.CAPECCatalogMeta, error) {
@@ -54,7 +69,8 @@ func (s *stubCAPECStore) GetCatalogMeta(ctx context.Context) (*capec.CAPECCatalo
 	return s.meta, nil
 }
 
-func (s *stubCAPECStore) ListCAPECsPaginated(ctx context.Context, offset, limit int) ([]capec.CAPECItemModel, int64, error) {
+func (s *stubCAPECStore) ListCAPECsFiltered(ctx context.Context, offset, limit int, abstraction, sortBy, order string) ([]capec.CAPECItemModel, int64, error) {
+	s.lastList.abstraction, s.lastList.sortBy, s.lastList.order = abstraction, sortBy, order
 	if s.listErr != nil {
 		return nil, 0, s.listErr
 	}
@@ -99,6 +115,14 @@ func (s *stubCAPECStore) GetReferences(ctx context.Context, capecID int) ([]cape
 	return s.references, nil
 }
 
+func (s *stubCAPECStore) GetAttackMappings(ctx context.Context, capecID int) ([]capec.CAPECAttackMappingModel, error) {
+	return s.techniques, nil
+}
+
+func (s *stubCAPECStore) GetCAPECIDsByCWE(ctx context.Context, cweID string) ([]int, error) {
+	return s.byCWE[cweID], nil
+}
+
 func TestXmlInnerToPlain_StripsTagsAndUnescapes(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestXmlInnerToPlain_StripsTagsAndUnescapes", nil, func(t *testing.T, tx *gorm.DB) {
 		in := "<p>Hello &amp; <strong>World</strong></p>\n<em>!</em>"
@@ -236,6 +260,44 @@ func TestCreateImportCAPECsHandler_Succeeds(t *testing.T) {
 
 }
 
+func TestCreateImportCAPECsHandler_DryRun(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCreateImportCAPECsHandler_DryRun", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
+		report := catalog.NewImportReport(true)
+		report.Record(false)
+		report.Record(true)
+		report.Warnf("CAPEC-1 has no name")
+		store := &stubCAPECStore{report: report}
+		handler := createImportCAPECsHandler(store, logger)
+
+		payload, _ := subprocess.MarshalFast(map[string]any{"path": "file.xml", "dry_run": true})
+		msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCImportCAPECs", Payload: payload}
+		resp, err := handler(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected success response, got %+v", resp)
+		}
+		if !store.lastImport.dryRun {
+			t.Fatalf("dry_run not passed to the store: %+v", store.lastImport)
+		}
+		var result struct {
+			Success  bool     `json:"success"`
+			DryRun   bool     `json:"dry_run"`
+			Inserted int      `json:"inserted"`
+			Updated  int      `json:"updated"`
+			Warnings []string `json:"warnings"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if !result.Success || !result.DryRun || result.Inserted != 1 || result.Updated != 1 || len(result.Warnings) != 1 {
+			t.Fatalf("unexpected dry-run result: %+v", result)
+		}
+	})
+}
+
 func TestCreateImportCAPECsHandler_PropagatesStoreError(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestCreateImportCAPECsHandler_PropagatesStoreError", nil, func(t *testing.T, tx *gorm.DB) {
 		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
@@ -255,6 +317,31 @@ func TestCreateImportCAPECsHandler_PropagatesStoreError(t *testing.T) {
 
 }
 
+func TestCreateImportCAPECsHandler_ReturnsSchemaErrors(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCreateImportCAPECsHandler_ReturnsSchemaErrors", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
+		store := &stubCAPECStore{importErr: &capec.SchemaError{
+			Path:   "file.xml",
+			Errors: []capec.XMLValidationError{{Line: 12, Column: 5, Message: "Premature end of data"}},
+		}}
+
+		for _, handler := range []subprocess.Handler{createImportCAPECsHandler(store, logger), createForceImportCAPECsHandler(store, logger)} {
+			payload, _ := subprocess.MarshalFast(map[string]string{"path": "file.xml", "xsd": "capec.xsd"})
+			msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCImportCAPECs", Payload: payload}
+			resp, err := handler(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("handler returned error: %v", err)
+			}
+			if resp.Type != subprocess.MessageTypeError || !strings.Contains(resp.Error, "line 12, column 5: Premature end of data") {
+				t.Fatalf("expected the validation errors, got %+v", resp)
+			}
+			if store.lastImport.xsd != "capec.xsd" {
+				t.Fatalf("xsd not passed to the store: %+v", store.lastImport)
+			}
+		}
+	})
+}
+
 func TestCreateGetCAPECCatalogMetaHandler_Error(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestCreateGetCAPECCatalogMetaHandler_Error", nil, func(t *testing.T, tx *gorm.DB) {
 		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
@@ -328,6 +415,44 @@ func TestCreateListCAPECsHandler_NormalizesParams(t *testing.T) {
 
 }
 
+func TestCreateListCAPECsHandler_SortAndFilter(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateListCAPECsHandler_SortAndFilter", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
+		store := &stubCAPECStore{
+			listItems: []capec.CAPECItemModel{{CAPECID: 1, Name: "n", Abstraction: "Meta"}},
+			listTotal: 1,
+		}
+		handler := createListCAPECsHandler(store, logger)
+
+		// Defaults: ascending by ID, no filter
+		resp, err := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCListCAPECs"})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v (err %v)", resp, err)
+		}
+		if store.lastList.sortBy != capec.SortByID || store.lastList.order != capec.OrderAsc || store.lastList.abstraction != "" {
+			t.Fatalf("unexpected defaults passed to store: %+v", store.lastList)
+		}
+
+		payload, _ := subprocess.MarshalFast(map[string]interface{}{"sortBy": "name", "order": "desc", "abstractionLevel": "Meta"})
+		resp, err = handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCListCAPECs", Payload: payload})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v (err %v)", resp, err)
+		}
+		if store.lastList.sortBy != "name" || store.lastList.order != "desc" || store.lastList.abstraction != "Meta" {
+			t.Fatalf("unexpected options passed to store: %+v", store.lastList)
+		}
+		var decoded map[string]interface{}
+		if err := subprocess.UnmarshalFast(resp.Payload, &decoded); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		capecs := decoded["capecs"].([]interface{})
+		if decoded["abstractionLevel"] != "Meta" || capecs[0].(map[string]interface{})["abstraction"] != "Meta" {
+			t.Fatalf("unexpected payload: %+v", decoded)
+		}
+	})
+
+}
+
 func TestCreateListCAPECsHandler_StoreError(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestCreateListCAPECsHandler_StoreError", nil, func(t *testing.T, tx *gorm.DB) {
 		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
@@ -392,6 +517,7 @@ func TestCreateGetCAPECByIDHandler_Success(t *testing.T) {
 			examples:    []capec.CAPECExampleModel{{ExampleText: "<p>ex</p>"}},
 			mitigations: []capec.CAPECMitigationModel{{MitigationText: "<p>mt</p>"}},
 			references:  []capec.CAPECReferenceModel{{ExternalReference: "ref", URL: "http://example.com"}},
+			techniques:  []capec.CAPECAttackMappingModel{{TechniqueID: "T1110", TechniqueName: "Brute Force"}},
 		}
 		handler := createGetCAPECByIDHandler(store, logger)
 
@@ -419,10 +545,51 @@ func TestCreateGetCAPECByIDHandler_Success(t *testing.T) {
 		if len(refs) != 1 {
 			t.Fatalf("unexpected references: %+v", decoded["references"])
 		}
+		techniques, _ := decoded["attack_techniques"].([]any)
+		if len(techniques) != 1 || techniques[0].(map[string]any)["technique_id"] != "T1110" {
+			t.Fatalf("unexpected attack techniques: %+v", decoded["attack_techniques"])
+		}
 	})
 
 }
 
+func TestCreateGetCAPECsByCWEHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateGetCAPECsByCWEHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testWriter{t}, "test", common.ErrorLevel)
+		store := &stubCAPECStore{byCWE: map[string][]int{"79": {7, 63}}}
+		handler := createGetCAPECsByCWEHandler(store, logger)
+
+		call := func(cweID string) *subprocess.Message {
+			payload, _ := subprocess.MarshalFast(map[string]string{"cwe_id": cweID})
+			msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCAPECsByCWE", Payload: payload}
+			resp, err := handler(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("handler returned error: %v", err)
+			}
+			return resp
+		}
+
+		resp := call("cwe-79")
+		var decoded struct {
+			CWEID    string   `json:"cwe_id"`
+			CAPECIDs []string `json:"capec_ids"`
+			Total    int      `json:"total"`
+		}
+		if err := subprocess.UnmarshalFast(resp.Payload, &decoded); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if decoded.CWEID != "CWE-79" || decoded.Total != 2 || len(decoded.CAPECIDs) != 2 || decoded.CAPECIDs[0] != "CAPEC-7" {
+			t.Fatalf("unexpected payload: %+v", decoded)
+		}
+		if resp := call("XSS"); resp.Type != subprocess.MessageTypeError {
+			t.Fatalf("expected an invalid CWE ID to be rejected, got %+v", resp)
+		}
+		if resp := call(""); resp.Type != subprocess.MessageTypeError || resp.Error != "cwe_id is required" {
+			t.Fatalf("expected validation error, got %+v", resp)
+		}
+	})
+}
+
 // testWriter adapts testing.T to io.Writer for logger output suppression.
 type testWriter struct{ t *testing.T }
 
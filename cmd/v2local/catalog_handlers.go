@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// catalogVersioner reports the version of a loaded taxonomy catalog
+type catalogVersioner interface {
+	CatalogVersion(ctx context.Context) (*catalog.Version, error)
+}
+
+// createGetCatalogVersionsHandler creates a handler for RPCGetCatalogVersions,
+// which reports the version, source, import time and entry count of every
+// loaded taxonomy catalog, in the order the stores are given
+func createGetCatalogVersionsHandler(logger *common.Logger, stores ...catalogVersioner) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("RPCGetCatalogVersions handler invoked. msg.ID=%s, correlation_id=%s", msg.ID, msg.CorrelationID)
+
+		versions := make([]catalog.Version, 0, len(stores))
+		for _, store := range stores {
+			v, err := store.CatalogVersion(ctx)
+			if err != nil {
+				logger.Warn("Failed to get catalog version: %v", err)
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to get catalog version: %v", err)), nil
+			}
+			versions = append(versions, *v)
+		}
+
+		resp, err := subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"catalogs": versions,
+		})
+		if err != nil {
+			logger.Error("Failed to marshal catalog versions: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal catalog versions: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
@@ -151,6 +151,10 @@ const (
 	LogMsgGetAttackTechniqueByIDReq            = "GetAttackTechniqueByID request: id=%s"
 	LogMsgFailedGetAttackTechnique             = "Failed to get ATT&CK technique: %v (id=%s)"
 	LogMsgFailedMarshalAttackTechnique         = "Failed to marshal ATT&CK technique: %v (id=%s)"
+	LogMsgGetAttackSubTechniquesReq            = "GetAttackSubTechniques request: id=%s"
+	LogMsgFailedGetAttackSubTechniques         = "Failed to get ATT&CK sub-techniques: %v (id=%s)"
+	LogMsgGetAttackRelationshipsReq            = "GetAttackTechniqueRelationships request: id=%s"
+	LogMsgFailedGetAttackRelationships         = "Failed to get ATT&CK technique relationships: %v (id=%s)"
 	LogMsgGetAttackTacticByIDReq               = "GetAttackTacticByID request: id=%s"
 	LogMsgFailedGetAttackTactic                = "Failed to get ATT&CK tactic: %v (id=%s)"
 	LogMsgFailedMarshalAttackTactic            = "Failed to marshal ATT&CK tactic: %v (id=%s)"
@@ -191,6 +195,9 @@ const (
 	LogMsgGetAttackImportMetadataInvoked       = "RPCGetAttackImportMetadata handler invoked"
 	LogMsgFailedGetAttackImportMetadata        = "Failed to get ATT&CK import metadata: %v"
 	LogMsgFailedMarshalAttackImportMetadata    = "Failed to marshal ATT&CK import metadata: %v"
+	LogMsgGetAttackCoverageInvoked             = "RPCGetAttackCoverage handler invoked with message ID: %s"
+	LogMsgFailedGetAttackCoverage              = "Failed to get ATT&CK tactic coverage: %v"
+	LogMsgFailedImportKEV                      = "Failed to import KEV catalog: %v"
 
 	// ASVS Handlers Logs
 	LogMsgASVSDatabasePathConfigured = "ASVS database path configured: %s"
@@ -216,4 +223,7 @@ const (
 	LogMsgFailedListASVS             = "Failed to list ASVS requirements: %v"
 	LogMsgProcessingListASVSFailed   = "Processing ListASVS request failed: %v"
 	LogMsgFailedMarshalListASVS      = "Failed to marshal ASVS requirements list: %v"
+	LogMsgGetASVSByCWEReq            = "GetASVSByCWE request: cwe_id=%s"
+	LogMsgFailedGetASVSByCWE         = "Failed to get ASVS requirements by CWE: %v (cwe_id=%s)"
+	LogMsgGetASVSByCWECompleted      = "GetASVSByCWE operation completed, returned: %d records (cwe_id=%s)"
 )
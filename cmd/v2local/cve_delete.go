@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common/intentlog"
+	"github.com/cyw0ng95/v2e/pkg/cve/local"
+	"github.com/cyw0ng95/v2e/pkg/notes"
+	"gorm.io/gorm"
+)
+
+// intentOpDeleteCVE is the intent of deleting a CVE from the CVE database
+// together with its bookmarks in the bookmark database
+const intentOpDeleteCVE = "delete_cve"
+
+// cveDeleter deletes a CVE by ID
+type cveDeleter interface {
+	DeleteCVE(cveID string) error
+}
+
+// deleteCVEIntent is the payload of an intentOpDeleteCVE intent
+type deleteCVEIntent struct {
+	CVEID string `json:"cve_id"`
+}
+
+// cascadingCVEDeleter deletes a CVE and the bookmarks that point at it. The
+// two live in different databases, so the delete is covered by an intent:
+// if the process dies between them, recovery finishes the job on restart
+// instead of leaving bookmarks of a CVE that no longer exists.
+type cascadingCVEDeleter struct {
+	db        *local.DB
+	bookmarks *gorm.DB
+	intents   *intentlog.Log
+}
+
+// DeleteCVE deletes the CVE and its bookmarks. A missing CVE is reported as
+// gorm.ErrRecordNotFound without touching the bookmarks.
+func (c *cascadingCVEDeleter) DeleteCVE(cveID string) error {
+	intent, err := c.intents.Begin(intentOpDeleteCVE, deleteCVEIntent{CVEID: cveID})
+	if err != nil {
+		return err
+	}
+	if err := c.db.DeleteCVE(cveID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Nothing was written, so there is nothing to recover
+			if cerr := c.intents.Complete(intent.ID); cerr != nil {
+				return cerr
+			}
+		}
+		return err
+	}
+	if err := c.deleteBookmarks(cveID); err != nil {
+		return fmt.Errorf("CVE deleted but its bookmarks were not, they are removed on restart: %w", err)
+	}
+	return c.intents.Complete(intent.ID)
+}
+
+// resolveDeleteCVE completes an interrupted delete. Both steps are
+// idempotent, so it is safe whichever of them had already run.
+func (c *cascadingCVEDeleter) resolveDeleteCVE(intent intentlog.Intent) error {
+	var payload deleteCVEIntent
+	if err := intent.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if err := c.db.DeleteCVE(payload.CVEID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return c.deleteBookmarks(payload.CVEID)
+}
+
+// resolvers returns the intent resolvers of the operations c performs
+func (c *cascadingCVEDeleter) resolvers() map[string]intentlog.Resolver {
+	return map[string]intentlog.Resolver{
+		intentOpDeleteCVE: c.resolveDeleteCVE,
+	}
+}
+
+// deleteBookmarks deletes the bookmarks of a CVE
+func (c *cascadingCVEDeleter) deleteBookmarks(cveID string) error {
+	var ids []uint
+	if err := c.bookmarks.Model(&notes.BookmarkModel{}).
+		Where("LOWER(item_type) = ? AND item_id = ?", "cve", cveID).
+		Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to find bookmarks of %s: %w", cveID, err)
+	}
+	service := notes.NewBookmarkService(c.bookmarks)
+	for _, id := range ids {
+		if err := service.DeleteBookmark(context.Background(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
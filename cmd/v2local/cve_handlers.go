@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/cve/local"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 )
 
 // createSaveCVEByIDHandler creates a handler for RPCSaveCVEByID
@@ -47,6 +50,81 @@ func createSaveCVEByIDHandler(db *local.DB, logger *common.Logger) subprocess.Ha
 	}
 }
 
+// maxSaveCVEsBatch bounds the CVEs of one RPCSaveCVEsBatch request, which
+// are committed in a single transaction
+const maxSaveCVEsBatch = 2000
+
+// createSaveCVEsBatchHandler creates a handler for RPCSaveCVEsBatch
+func createSaveCVEsBatchHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing SaveCVEsBatch request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req rpc.SaveCVEsBatchParams
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse SaveCVEsBatch request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+			return errResp, nil
+		}
+		if len(req.CVEs) == 0 {
+			return subprocess.NewErrorResponse(msg, "cves is required"), nil
+		}
+		if len(req.CVEs) > maxSaveCVEsBatch {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("too many CVEs: %d (max %d)", len(req.CVEs), maxSaveCVEsBatch)), nil
+		}
+		for i := range req.CVEs {
+			if req.CVEs[i].ID == "" {
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("cves[%d].id is required", i)), nil
+			}
+		}
+		inserted, updated, err := db.SaveCVEsBatch(req.CVEs)
+		if err != nil {
+			logger.Warn("Failed to save CVE batch - Message ID: %s, Correlation ID: %s, Count: %d, Error: %v", msg.ID, msg.CorrelationID, len(req.CVEs), err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to save CVEs: %v", err)), nil
+		}
+		logger.Info("Successfully saved CVE batch - Message ID: %s, Inserted: %d, Updated: %d", msg.ID, inserted, updated)
+		resp, err := subprocess.NewSuccessResponse(msg, rpc.SaveCVEsBatchResult{Success: true, Inserted: inserted, Updated: updated})
+		if err != nil {
+			logger.Warn("Failed to marshal SaveCVEsBatch response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// createImportKEVHandler creates a handler for RPCImportKEV, which stores
+// CISA KEV catalog entries given inline or as a catalog file
+func createImportKEVHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing ImportKEV request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req rpc.ImportKEVParams
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse ImportKEV request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+			return errResp, nil
+		}
+		var result rpc.ImportKEVResult
+		var err error
+		switch {
+		case req.Path != "" && req.Vulnerabilities != nil:
+			return subprocess.NewErrorResponse(msg, "path and vulnerabilities are mutually exclusive"), nil
+		case req.Path != "":
+			result.Listed, result.Matched, err = db.ImportKEVFile(req.Path)
+		case req.Vulnerabilities != nil:
+			result.Listed, result.Matched, err = db.ImportKEV(req.Vulnerabilities, req.Replace)
+		default:
+			return subprocess.NewErrorResponse(msg, "path or vulnerabilities is required"), nil
+		}
+		if err != nil {
+			logger.Warn(LogMsgFailedImportKEV, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to import KEV catalog: %v", err)), nil
+		}
+		logger.Info("Successfully imported KEV catalog - Message ID: %s, Listed: %d, Matched: %d", msg.ID, result.Listed, result.Matched)
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal ImportKEV response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
 // createIsCVEStoredByIDHandler creates a handler for RPCIsCVEStoredByID
 func createIsCVEStoredByIDHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -101,6 +179,10 @@ func createGetCVEByIDHandler(db *local.DB, logger *common.Logger) subprocess.Han
 			logger.Debug(LogMsgProcessingGetCVEFailedErr, req.CVEID, msg.ID, err)
 			return subprocess.NewErrorResponse(msg, fmt.Sprintf(LogMsgCVEIDNotFound, err)), nil
 		}
+		// Health results are optional enrichment; a lookup failure should not fail the get
+		if err := db.AttachReferenceHealth(cveItem); err != nil {
+			logger.Warn("Failed to attach reference health for CVE %s: %v", req.CVEID, err)
+		}
 		logger.Info(LogMsgSuccessGetCVE, msg.ID, msg.CorrelationID, req.CVEID)
 		logger.Debug(LogMsgProcessingGetCVECompleted, msg.ID, req.CVEID)
 		resp, err := subprocess.NewSuccessResponse(msg, cveItem)
@@ -113,7 +195,7 @@ func createGetCVEByIDHandler(db *local.DB, logger *common.Logger) subprocess.Han
 }
 
 // createDeleteCVEByIDHandler creates a handler for RPCDeleteCVEByID
-func createDeleteCVEByIDHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+func createDeleteCVEByIDHandler(db cveDeleter, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		var req struct {
 			CVEID string `json:"cve_id"`
@@ -153,8 +235,12 @@ func createListCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handl
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug("Processing ListCVEs request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
 		var req struct {
-			Offset int `json:"offset"`
-			Limit  int `json:"limit"`
+			Offset          int  `json:"offset"`
+			Limit           int  `json:"limit"`
+			IncludeRejected bool `json:"include_rejected"`
+			KEV             bool `json:"kev"`
+			// Cursor selects cursor paging; "" starts from the first CVE
+			Cursor *string `json:"cursor"`
 		}
 		req.Offset = 0
 		req.Limit = 10
@@ -166,13 +252,35 @@ func createListCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handl
 			}
 		}
 		logger.Info("Processing ListCVEs request - Message ID: %s, Correlation ID: %s, Offset: %d, Limit: %d", msg.ID, msg.CorrelationID, req.Offset, req.Limit)
-		cves, err := db.ListCVEs(req.Offset, req.Limit)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		if req.Cursor != nil && (req.KEV || req.Limit <= 0) {
+			return subprocess.NewErrorResponse(msg, "cursor requires a positive limit and cannot be combined with kev"), nil
+		}
+		var cves []cve.CVEItem
+		var total int64
+		var nextCursor string
+		var err error
+		if req.Cursor != nil {
+			// Stable iteration in storage order, from the last CVE returned
+			cves, nextCursor, err = db.ListCVEsAfter(*req.Cursor, req.Limit, excluded)
+		} else if req.KEV {
+			// Only CVEs listed in the KEV catalog, earliest due date first
+			cves, total, err = db.ListKEVCVEs(req.Offset, req.Limit, excluded)
+		} else {
+			cves, err = db.ListCVEsFiltered(req.Offset, req.Limit, excluded)
+		}
 		if err != nil {
 			logger.Warn("Failed to list CVEs from database - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
 			logger.Debug("Processing ListCVEs request failed - Message ID: %s, Error details: %v", msg.ID, err)
 			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to list CVEs: %v", err)), nil
 		}
-		total, err := db.Count()
+		if !req.KEV {
+			total, err = db.CountFiltered(excluded)
+		}
 		if err != nil {
 			logger.Warn("Failed to get CVE count from database - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
 			logger.Debug("Processing ListCVEs request failed to get count - Message ID: %s, Error details: %v", msg.ID, err)
@@ -184,6 +292,9 @@ func createListCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handl
 			"cves":  cves,
 			"total": total,
 		}
+		if req.Cursor != nil {
+			result["next_cursor"] = nextCursor
+		}
 		resp, err := subprocess.NewSuccessResponse(msg, result)
 		if err != nil {
 			logger.Warn("Failed to marshal ListCVEs response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
@@ -193,6 +304,286 @@ func createListCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handl
 	}
 }
 
+// createSearchCVEsHandler creates a handler for RPCSearchCVEs, which lists
+// the CVEs matching a keyword and CVSS severity with RPCListCVEs' paging
+func createSearchCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing SearchCVEs request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req struct {
+			Keyword         string `json:"keyword"`
+			Severity        string `json:"severity"`
+			Offset          int    `json:"offset"`
+			Limit           int    `json:"limit"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		req.Limit = 10
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn("Failed to parse SearchCVEs request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+				return errResp, nil
+			}
+		}
+		logger.Info("Processing SearchCVEs request - Message ID: %s, Keyword: %q, Severity: %q, Offset: %d, Limit: %d", msg.ID, req.Keyword, req.Severity, req.Offset, req.Limit)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		cves, total, err := db.SearchCVEs(req.Keyword, req.Severity, req.Offset, req.Limit, excluded)
+		if err != nil {
+			logger.Warn("Failed to search CVEs - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to search CVEs: %v", err)), nil
+		}
+		logger.Info("Successfully searched CVEs - Message ID: %s, Returned: %d, Total: %d", msg.ID, len(cves), total)
+		result := map[string]interface{}{
+			"cves":  cves,
+			"total": total,
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal SearchCVEs response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// searchIndexes are the full-text indexes RPCReindexSearch rebuilds, by
+// entity
+var searchIndexes = []string{"cve"}
+
+// createReindexSearchHandler creates a handler for RPCReindexSearch, which
+// rebuilds the full-text index of one entity, or of all when entity is empty
+// or "all", from its base table
+func createReindexSearchHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			Entity string `json:"entity"`
+		}
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn("Failed to parse ReindexSearch request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+				return errResp, nil
+			}
+		}
+		entities := searchIndexes
+		if req.Entity != "" && req.Entity != "all" {
+			if !slices.Contains(searchIndexes, req.Entity) {
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("unsupported entity %q: must be cve or all", req.Entity)), nil
+			}
+			entities = []string{req.Entity}
+		}
+
+		results := make([]map[string]interface{}, 0, len(entities))
+		var totalRows int64
+		for _, entity := range entities {
+			logger.Info("Rebuilding %s search index", entity)
+			start := time.Now()
+			rows, err := db.ReindexCVESearch()
+			if err != nil {
+				logger.Warn("Failed to rebuild %s search index - Message ID: %s, Error: %v", entity, msg.ID, err)
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to reindex %s: %v", entity, err)), nil
+			}
+			elapsed := time.Since(start)
+			logger.Info("Rebuilt %s search index: %d rows in %v", entity, rows, elapsed)
+			results = append(results, map[string]interface{}{
+				"entity":      entity,
+				"rows":        rows,
+				"duration_ms": elapsed.Milliseconds(),
+			})
+			totalRows += rows
+		}
+
+		resp, err := subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"indexes":    results,
+			"total_rows": totalRows,
+		})
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// createGetCVEsByCWEHandler creates a handler for RPCGetCVEsByCWE, which
+// lists the CVEs whose weaknesses include a CWE with RPCListCVEs' paging
+func createGetCVEsByCWEHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing GetCVEsByCWE request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req struct {
+			CWEID           string `json:"cwe_id"`
+			Offset          int    `json:"offset"`
+			Limit           int    `json:"limit"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		req.Limit = 10
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse GetCVEsByCWE request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+			return errResp, nil
+		}
+		if errResp := subprocess.RequireField(msg, req.CWEID, "cwe_id"); errResp != nil {
+			logger.Warn("cwe_id is required for GetCVEsByCWE - Message ID: %s", msg.ID)
+			return errResp, nil
+		}
+		logger.Info("Processing GetCVEsByCWE request - Message ID: %s, CWE ID: %s, Offset: %d, Limit: %d", msg.ID, req.CWEID, req.Offset, req.Limit)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		cves, total, err := db.GetCVEsByCWE(req.CWEID, req.Offset, req.Limit, excluded)
+		if err != nil {
+			logger.Warn("Failed to get CVEs by CWE - Message ID: %s, CWE ID: %s, Error: %v", msg.ID, req.CWEID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to get CVEs by CWE: %v", err)), nil
+		}
+		logger.Info("Successfully got CVEs by CWE - Message ID: %s, CWE ID: %s, Returned: %d, Total: %d", msg.ID, req.CWEID, len(cves), total)
+		result := map[string]interface{}{
+			"cves":  cves,
+			"total": total,
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal GetCVEsByCWE response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// createGetCVEsWithExploitRefsHandler creates a handler for
+// RPCGetCVEsWithExploitRefs, which lists the CVEs with a reference tagged
+// "Exploit" (e.g. a public proof of concept) with RPCListCVEs' paging
+func createGetCVEsWithExploitRefsHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing GetCVEsWithExploitRefs request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req struct {
+			Offset          int  `json:"offset"`
+			Limit           int  `json:"limit"`
+			IncludeRejected bool `json:"include_rejected"`
+		}
+		req.Limit = 10
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn("Failed to parse GetCVEsWithExploitRefs request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+				return errResp, nil
+			}
+		}
+		logger.Info("Processing GetCVEsWithExploitRefs request - Message ID: %s, Offset: %d, Limit: %d", msg.ID, req.Offset, req.Limit)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		cves, total, err := db.GetCVEsWithExploitRefs(req.Offset, req.Limit, excluded)
+		if err != nil {
+			logger.Warn("Failed to get CVEs with exploit references - Message ID: %s, Error: %v", msg.ID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to get CVEs with exploit references: %v", err)), nil
+		}
+		logger.Info("Successfully got CVEs with exploit references - Message ID: %s, Returned: %d, Total: %d", msg.ID, len(cves), total)
+		result := map[string]interface{}{
+			"cves":  cves,
+			"total": total,
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal GetCVEsWithExploitRefs response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// createGetCVEByCPEHandler creates a handler for RPCGetCVEByCPE, which lists
+// the CVEs with a vulnerable configuration matching a CPE vendor and product
+// prefix with RPCListCVEs' paging
+func createGetCVEByCPEHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing GetCVEByCPE request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req struct {
+			CPE             string `json:"cpe"`
+			Offset          int    `json:"offset"`
+			Limit           int    `json:"limit"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		req.Limit = 10
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse GetCVEByCPE request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+			return errResp, nil
+		}
+		if errResp := subprocess.RequireField(msg, req.CPE, "cpe"); errResp != nil {
+			logger.Warn("cpe is required for GetCVEByCPE - Message ID: %s", msg.ID)
+			return errResp, nil
+		}
+		logger.Info("Processing GetCVEByCPE request - Message ID: %s, CPE: %s, Offset: %d, Limit: %d", msg.ID, req.CPE, req.Offset, req.Limit)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		cves, total, err := db.GetCVEsByCPE(req.CPE, req.Offset, req.Limit, excluded)
+		if err != nil {
+			logger.Warn("Failed to get CVEs by CPE - Message ID: %s, CPE: %s, Error: %v", msg.ID, req.CPE, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to get CVEs by CPE: %v", err)), nil
+		}
+		logger.Info("Successfully got CVEs by CPE - Message ID: %s, CPE: %s, Returned: %d, Total: %d", msg.ID, req.CPE, len(cves), total)
+		result := map[string]interface{}{
+			"cves":  cves,
+			"total": total,
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal GetCVEByCPE response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
+// createGetCVETimelineHandler creates a handler for RPCGetCVETimeline, which
+// counts the CVEs by published month, optionally of a CWE and a severity
+func createGetCVETimelineHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing GetCVETimeline request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
+		var req struct {
+			CWEID           string `json:"cwe_id"`
+			Severity        string `json:"severity"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				logger.Warn("Failed to parse GetCVETimeline request - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, errResp.Error)
+				return errResp, nil
+			}
+		}
+		logger.Info("Processing GetCVETimeline request - Message ID: %s, CWE ID: %q, Severity: %q", msg.ID, req.CWEID, req.Severity)
+		// Rejected and disputed CVEs are hidden unless explicitly requested
+		var excluded []string
+		if !req.IncludeRejected {
+			excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+		}
+		timeline, err := db.CVETimeline(req.CWEID, req.Severity, excluded)
+		if err != nil {
+			logger.Warn("Failed to get CVE timeline - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to get CVE timeline: %v", err)), nil
+		}
+		var total int64
+		for _, bucket := range timeline {
+			total += bucket.Count
+		}
+		logger.Info("Successfully got CVE timeline - Message ID: %s, Months: %d, Total: %d", msg.ID, len(timeline), total)
+		result := map[string]interface{}{
+			"timeline": timeline,
+			"total":    total,
+		}
+		resp, err := subprocess.NewSuccessResponse(msg, result)
+		if err != nil {
+			logger.Warn("Failed to marshal GetCVETimeline response - Message ID: %s, Correlation ID: %s, Error: %v", msg.ID, msg.CorrelationID, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
+
 // createCountCVEsHandler creates a handler for RPCCountCVEs
 func createCountCVEsHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -282,7 +673,7 @@ func createUpdateCVEHandler(db *local.DB, logger *common.Logger) subprocess.Hand
 // createDeleteCVEHandler creates a handler for RPCDeleteCVE
 // Accepts { cve_id: string } and deletes the CVE from the database
 // Note: This is an alias for RPCDeleteCVEByID with the same functionality
-func createDeleteCVEHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+func createDeleteCVEHandler(db cveDeleter, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug("Processing RPCDeleteCVE request - Message ID: %s, Correlation ID: %s", msg.ID, msg.CorrelationID)
 		var req struct {
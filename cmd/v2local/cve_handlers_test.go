@@ -5,13 +5,16 @@ import (
 "github.com/cyw0ng95/v2e/pkg/testutils"
 	"bytes"
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/cve/local"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 )
 
 func makeMsgWithPayload(t *testing.T, payload interface{}) *subprocess.Message {
@@ -52,6 +55,9 @@ func TestCVEHandlers(t *testing.T) {
 		deleteH := createDeleteCVEByIDHandler(db, logger)
 		listH := createListCVEsHandler(db, logger)
 		countH := createCountCVEsHandler(db, logger)
+		searchH := createSearchCVEsHandler(db, logger)
+		byCWEH := createGetCVEsByCWEHandler(db, logger)
+		byCPEH := createGetCVEByCPEHandler(db, logger)
 
 		ctx := context.Background()
 
@@ -59,6 +65,14 @@ func TestCVEHandlers(t *testing.T) {
 		item := cve.CVEItem{
 			ID:           "CVE-TEST-1",
 			Descriptions: []cve.Description{{Lang: "en", Value: "test"}},
+			Weaknesses:   []cve.Weakness{{Source: "nvd@nist.gov", Type: "Primary", Description: []cve.Description{{Lang: "en", Value: "CWE-79"}}}},
+			Configurations: []cve.Config{{Nodes: []cve.Node{{Operator: "OR", CPEMatch: []cve.CPEMatch{
+				{Vulnerable: true, Criteria: "cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*"},
+			}}}}},
+			References: []cve.Reference{
+				{URL: "https://github.example/poc", Tags: []string{"Exploit", "Third Party Advisory"}},
+				{URL: "https://lists.example/thread"},
+			},
 		}
 
 		// Save
@@ -99,6 +113,9 @@ func TestCVEHandlers(t *testing.T) {
 		if got.ID != item.ID {
 			t.Fatalf("expected id %s got %s", item.ID, got.ID)
 		}
+		if len(got.References) != 2 || got.References[0].Category != cve.ReferenceCategoryExploit || got.References[1].Category != cve.ReferenceCategoryOther {
+			t.Fatalf("expected classified references, got %+v", got.References)
+		}
 
 		// Count
 		countResp, err := countH(ctx, &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "c2"})
@@ -120,6 +137,128 @@ func TestCVEHandlers(t *testing.T) {
 			t.Fatalf("unmarshal list result: %v", err)
 		}
 
+		// Search
+		searchResp, err := searchH(ctx, makeMsgWithPayload(t, map[string]interface{}{"keyword": "test", "limit": 5}))
+		if err != nil || searchResp == nil || searchResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("search handler failed: err=%v resp=%v", err, searchResp)
+		}
+		var searchRes struct {
+			CVEs  []cve.CVEItem `json:"cves"`
+			Total int64         `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(searchResp, &searchRes); err != nil {
+			t.Fatalf("unmarshal search result: %v", err)
+		}
+		if searchRes.Total != 1 || len(searchRes.CVEs) != 1 || searchRes.CVEs[0].ID != item.ID {
+			t.Fatalf("expected the CVE to match the search, got: %+v", searchRes)
+		}
+		badResp, _ := searchH(ctx, makeMsgWithPayload(t, map[string]interface{}{"severity": "severe"}))
+		if badResp == nil || badResp.Type != subprocess.MessageTypeError {
+			t.Fatalf("expected an invalid severity to fail, got: %v", badResp)
+		}
+
+		// Reindex search
+		reindexH := createReindexSearchHandler(db, logger)
+		reindexResp, err := reindexH(ctx, makeMsgWithPayload(t, map[string]interface{}{"entity": "cve"}))
+		if err != nil || reindexResp == nil || reindexResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("reindex handler failed: err=%v resp=%v", err, reindexResp)
+		}
+		var reindexRes struct {
+			Indexes []struct {
+				Entity string `json:"entity"`
+				Rows   int64  `json:"rows"`
+			} `json:"indexes"`
+			TotalRows int64 `json:"total_rows"`
+		}
+		if err := subprocess.UnmarshalPayload(reindexResp, &reindexRes); err != nil {
+			t.Fatalf("unmarshal reindex result: %v", err)
+		}
+		if len(reindexRes.Indexes) != 1 || reindexRes.Indexes[0].Entity != "cve" || reindexRes.TotalRows != 1 {
+			t.Fatalf("expected the one CVE to be reindexed, got: %+v", reindexRes)
+		}
+		if badResp, _ := reindexH(ctx, makeMsgWithPayload(t, map[string]interface{}{"entity": "cwe"})); badResp == nil || badResp.Type != subprocess.MessageTypeError {
+			t.Fatalf("expected an unsupported entity to fail, got: %v", badResp)
+		}
+
+		// By CWE
+		byCWEResp, err := byCWEH(ctx, makeMsgWithPayload(t, map[string]interface{}{"cwe_id": "CWE-79", "limit": 5}))
+		if err != nil || byCWEResp == nil || byCWEResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("by-CWE handler failed: err=%v resp=%v", err, byCWEResp)
+		}
+		var byCWERes struct {
+			CVEs  []cve.CVEItem `json:"cves"`
+			Total int64         `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(byCWEResp, &byCWERes); err != nil {
+			t.Fatalf("unmarshal by-CWE result: %v", err)
+		}
+		if byCWERes.Total != 1 || len(byCWERes.CVEs) != 1 || byCWERes.CVEs[0].ID != item.ID {
+			t.Fatalf("expected the CVE to be listed under CWE-79, got: %+v", byCWERes)
+		}
+		for _, bad := range []map[string]interface{}{{}, {"cwe_id": "XSS"}} {
+			if badResp, _ := byCWEH(ctx, makeMsgWithPayload(t, bad)); badResp == nil || badResp.Type != subprocess.MessageTypeError {
+				t.Fatalf("expected %v to fail, got: %v", bad, badResp)
+			}
+		}
+
+		// By CPE
+		byCPEResp, err := byCPEH(ctx, makeMsgWithPayload(t, map[string]interface{}{"cpe": "cpe:2.3:a:apache:log", "limit": 5}))
+		if err != nil || byCPEResp == nil || byCPEResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("by-CPE handler failed: err=%v resp=%v", err, byCPEResp)
+		}
+		var byCPERes struct {
+			CVEs  []cve.CVEItem `json:"cves"`
+			Total int64         `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(byCPEResp, &byCPERes); err != nil {
+			t.Fatalf("unmarshal by-CPE result: %v", err)
+		}
+		if byCPERes.Total != 1 || len(byCPERes.CVEs) != 1 || byCPERes.CVEs[0].ID != item.ID {
+			t.Fatalf("expected the CVE to affect apache:log4j, got: %+v", byCPERes)
+		}
+		for _, bad := range []map[string]interface{}{{}, {"cpe": "cpe:2.3:a"}} {
+			if badResp, _ := byCPEH(ctx, makeMsgWithPayload(t, bad)); badResp == nil || badResp.Type != subprocess.MessageTypeError {
+				t.Fatalf("expected %v to fail, got: %v", bad, badResp)
+			}
+		}
+
+		// With exploit references
+		exploitH := createGetCVEsWithExploitRefsHandler(db, logger)
+		exploitResp, err := exploitH(ctx, &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "e1"})
+		if err != nil || exploitResp == nil || exploitResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("exploit refs handler failed: err=%v resp=%v", err, exploitResp)
+		}
+		var exploitRes struct {
+			CVEs  []cve.CVEItem `json:"cves"`
+			Total int64         `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(exploitResp, &exploitRes); err != nil {
+			t.Fatalf("unmarshal exploit refs result: %v", err)
+		}
+		if exploitRes.Total != 1 || len(exploitRes.CVEs) != 1 || exploitRes.CVEs[0].ID != item.ID {
+			t.Fatalf("expected the CVE to have an exploit reference, got: %+v", exploitRes)
+		}
+
+		// Timeline: the CVE has no published date
+		timelineH := createGetCVETimelineHandler(db, logger)
+		timelineResp, err := timelineH(ctx, makeMsgWithPayload(t, map[string]interface{}{"cwe_id": "CWE-79"}))
+		if err != nil || timelineResp == nil || timelineResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("timeline handler failed: err=%v resp=%v", err, timelineResp)
+		}
+		var timelineRes struct {
+			Timeline []local.TimelineBucket `json:"timeline"`
+			Total    int64                  `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(timelineResp, &timelineRes); err != nil {
+			t.Fatalf("unmarshal timeline result: %v", err)
+		}
+		if timelineRes.Total != 1 || len(timelineRes.Timeline) != 1 || timelineRes.Timeline[0].Month != local.TimelineUnknown {
+			t.Fatalf("expected the CVE in the unknown month, got: %+v", timelineRes)
+		}
+		if badResp, _ := timelineH(ctx, makeMsgWithPayload(t, map[string]interface{}{"severity": "SEVERE"})); badResp == nil || badResp.Type != subprocess.MessageTypeError {
+			t.Fatalf("expected an invalid severity to fail, got: %v", badResp)
+		}
+
 		// Delete
 		delReq := map[string]interface{}{"cve_id": item.ID}
 		delResp, err := deleteH(ctx, makeMsgWithPayload(t, delReq))
@@ -146,3 +285,177 @@ func TestCVEHandlers(t *testing.T) {
 	})
 
 }
+
+func TestSaveCVEsBatchHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVEsBatchHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := local.NewDB(filepath.Join(t.TempDir(), "cve-batch.db"))
+		if err != nil {
+			t.Fatalf("NewDB error: %v", err)
+		}
+		defer db.Close()
+		h := createSaveCVEsBatchHandler(db, common.NewLogger(&bytes.Buffer{}, "", common.ErrorLevel))
+		ctx := context.Background()
+
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-TEST-1"}); err != nil {
+			t.Fatalf("SaveCVE error: %v", err)
+		}
+		req := map[string]interface{}{"cves": []cve.CVEItem{{ID: "CVE-TEST-1"}, {ID: "CVE-TEST-2"}, {ID: "CVE-TEST-3"}}}
+		resp, err := h(ctx, makeMsgWithPayload(t, req))
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("batch handler failed: err=%v resp=%+v", err, resp)
+		}
+		var result rpc.SaveCVEsBatchResult
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("unmarshal batch result: %v", err)
+		}
+		if !result.Success || result.Inserted != 2 || result.Updated != 1 {
+			t.Errorf("Expected 2 inserted and 1 updated, got %+v", result)
+		}
+
+		for _, bad := range []map[string]interface{}{
+			{"cves": []cve.CVEItem{}},
+			{"cves": []cve.CVEItem{{ID: "CVE-TEST-4"}, {}}},
+			{"cves": make([]cve.CVEItem, maxSaveCVEsBatch+1)},
+		} {
+			resp, _ := h(ctx, makeMsgWithPayload(t, bad))
+			if resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected an error response, got %+v", resp)
+			}
+		}
+		if count, _ := db.Count(); count != 3 {
+			t.Errorf("Expected rejected batches to store nothing, got %d CVEs", count)
+		}
+	})
+}
+
+func TestImportKEVHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestImportKEVHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		db, err := local.NewDB(filepath.Join(dir, "cve-kev.db"))
+		if err != nil {
+			t.Fatalf("NewDB error: %v", err)
+		}
+		defer db.Close()
+		logger := common.NewLogger(&bytes.Buffer{}, "", common.ErrorLevel)
+		h := createImportKEVHandler(db, logger)
+		listH := createListCVEsHandler(db, logger)
+		ctx := context.Background()
+
+		for _, id := range []string{"CVE-TEST-1", "CVE-TEST-2"} {
+			if err := db.SaveCVE(&cve.CVEItem{ID: id}); err != nil {
+				t.Fatalf("SaveCVE error: %v", err)
+			}
+		}
+		req := map[string]interface{}{"vulnerabilities": []cve.KEVEntry{
+			{CVEID: "CVE-TEST-1", DueDate: "2024-03-22"},
+			{CVEID: "CVE-TEST-9", DueDate: "2024-03-22"},
+		}}
+		resp, err := h(ctx, makeMsgWithPayload(t, req))
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("import handler failed: err=%v resp=%+v", err, resp)
+		}
+		var result rpc.ImportKEVResult
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("unmarshal import result: %v", err)
+		}
+		if result.Listed != 2 || result.Matched != 1 {
+			t.Errorf("Expected 2 listed and 1 matched, got %+v", result)
+		}
+
+		resp, _ = listH(ctx, makeMsgWithPayload(t, map[string]interface{}{"kev": true}))
+		var list struct {
+			CVEs  []cve.CVEItem `json:"cves"`
+			Total int64         `json:"total"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &list); err != nil {
+			t.Fatalf("unmarshal list result: %v", err)
+		}
+		if list.Total != 1 || len(list.CVEs) != 1 || list.CVEs[0].KEV == nil || list.CVEs[0].KEV.DueDate != "2024-03-22" {
+			t.Errorf("Expected only CVE-TEST-1 with its due date, got %+v", list)
+		}
+
+		// A catalog file replaces the stored catalog
+		path := filepath.Join(dir, "known_exploited_vulnerabilities.json")
+		catalog := `{"catalogVersion":"2024.03.01","count":1,"vulnerabilities":[{"cveID":"CVE-TEST-2","dueDate":"2024-04-01"}]}`
+		if err := os.WriteFile(path, []byte(catalog), 0o644); err != nil {
+			t.Fatalf("write catalog: %v", err)
+		}
+		resp, _ = h(ctx, makeMsgWithPayload(t, map[string]interface{}{"path": path}))
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil || result.Listed != 1 || result.Matched != 1 {
+			t.Errorf("Expected the file to list CVE-TEST-2 alone, got %+v (%v)", result, err)
+		}
+		if item, _ := db.GetCVE("CVE-TEST-1"); item == nil || item.KEV != nil {
+			t.Errorf("Expected CVE-TEST-1 to be unlisted, got %+v", item)
+		}
+
+		for _, bad := range []map[string]interface{}{
+			{},
+			{"path": path, "vulnerabilities": []cve.KEVEntry{}},
+			{"path": filepath.Join(dir, "missing.json")},
+		} {
+			resp, _ := h(ctx, makeMsgWithPayload(t, bad))
+			if resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected an error response for %v, got %+v", bad, resp)
+			}
+		}
+	})
+}
+
+func TestListCVEsHandler_Cursor(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestListCVEsHandler_Cursor", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := local.NewDB(filepath.Join(t.TempDir(), "cve-cursor.db"))
+		if err != nil {
+			t.Fatalf("NewDB error: %v", err)
+		}
+		defer db.Close()
+		logger := common.NewLogger(&bytes.Buffer{}, "", common.ErrorLevel)
+		h := createListCVEsHandler(db, logger)
+		ctx := context.Background()
+
+		for _, id := range []string{"CVE-TEST-1", "CVE-TEST-2", "CVE-TEST-3"} {
+			if err := db.SaveCVE(&cve.CVEItem{ID: id}); err != nil {
+				t.Fatalf("SaveCVE error: %v", err)
+			}
+		}
+
+		var ids []string
+		cursor := ""
+		for i := 0; i < 3; i++ {
+			resp, err := h(ctx, makeMsgWithPayload(t, map[string]interface{}{"cursor": cursor, "limit": 2}))
+			if err != nil || resp.Type != subprocess.MessageTypeResponse {
+				t.Fatalf("list handler failed: err=%v resp=%+v", err, resp)
+			}
+			var page struct {
+				CVEs       []cve.CVEItem `json:"cves"`
+				Total      int64         `json:"total"`
+				NextCursor string        `json:"next_cursor"`
+			}
+			if err := subprocess.UnmarshalPayload(resp, &page); err != nil {
+				t.Fatalf("unmarshal list result: %v", err)
+			}
+			if page.Total != 3 {
+				t.Errorf("Expected total 3, got %d", page.Total)
+			}
+			for _, item := range page.CVEs {
+				ids = append(ids, item.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if got := strings.Join(ids, ","); got != "CVE-TEST-1,CVE-TEST-2,CVE-TEST-3" {
+			t.Errorf("Expected every CVE once in storage order, got %s", got)
+		}
+
+		for _, bad := range []map[string]interface{}{
+			{"cursor": "bogus"},
+			{"cursor": "", "kev": true},
+		} {
+			resp, _ := h(ctx, makeMsgWithPayload(t, bad))
+			if resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected an error for %v, got %+v", bad, resp)
+			}
+		}
+	})
+}
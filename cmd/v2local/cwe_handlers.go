@@ -120,3 +120,34 @@ func createImportCWEsHandler(store *cwe.LocalCWEStore, logger *common.Logger) su
 		return subprocess.NewSuccessResponse(msg, map[string]bool{"success": true})
 	}
 }
+
+// createGetCWETreeHandler creates a handler for RPCGetCWETree
+func createGetCWETreeHandler(store *cwe.LocalCWEStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			RootID string `json:"root_id"`
+			ViewID string `json:"view_id"`
+		}
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse request: %v", errResp.Error)
+			logger.Debug("Processing GetCWETree request failed due to malformed payload: %s", string(msg.Payload))
+			return errResp, nil
+		}
+		if errResp := subprocess.RequireField(msg, req.RootID, "root_id"); errResp != nil {
+			return errResp, nil
+		}
+		logger.Debug("GetCWETree request: root_id=%s, view_id=%s", req.RootID, req.ViewID)
+		tree, err := store.GetCWETree(ctx, req.RootID, req.ViewID)
+		if err != nil {
+			logger.Warn("Failed to get CWE tree: %v (root_id=%s)", err, req.RootID)
+			return subprocess.NewErrorResponse(msg, err.Error()), nil
+		}
+		logger.Debug("Processing GetCWETree request completed for root %s: %d nodes", req.RootID, tree.NodeCount)
+		resp, err := subprocess.NewSuccessResponse(msg, tree)
+		if err != nil {
+			logger.Warn("Failed to marshal CWE tree: %v (root_id=%s)", err, req.RootID)
+			return subprocess.NewErrorResponse(msg, "failed to marshal CWE tree"), nil
+		}
+		return resp, nil
+	}
+}
@@ -30,7 +30,10 @@ func TestCWEHandlers(t *testing.T) {
 		}
 
 		// create a small JSON file for import
-		sample := []map[string]interface{}{{"ID": "CWE-1", "Name": "Test CWE"}}
+		sample := []map[string]interface{}{
+			{"ID": "CWE-1", "Name": "Test CWE"},
+			{"ID": "CWE-2", "Name": "Child CWE", "RelatedWeaknesses": []map[string]string{{"Nature": "ChildOf", "CweID": "CWE-1", "ViewID": "1000"}}},
+		}
 		data, err := subprocess.MarshalFast(sample)
 		if err != nil {
 			t.Fatalf("marshal sample: %v", err)
@@ -77,6 +80,27 @@ func TestCWEHandlers(t *testing.T) {
 		if total, ok := listResult["total"].(float64); !ok || total < 1 {
 			t.Fatalf("expected total >=1 got %v", listResult["total"])
 		}
+
+		// Tree
+		treeH := createGetCWETreeHandler(store, logger)
+		treeReq := map[string]interface{}{"root_id": "1"}
+		treeResp, err := treeH(ctx, &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "t1", Payload: func() []byte { b, _ := subprocess.MarshalFast(treeReq); return b }()})
+		if err != nil || treeResp == nil || treeResp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("tree handler failed: err=%v resp=%v", err, treeResp)
+		}
+		var tree cwe.CWETree
+		if err := subprocess.UnmarshalPayload(treeResp, &tree); err != nil {
+			t.Fatalf("unmarshal tree: %v", err)
+		}
+		if tree.Root.ID != "CWE-1" || len(tree.Root.Children) != 1 || tree.Root.Children[0].ID != "CWE-2" {
+			t.Fatalf("expected CWE-1 with child CWE-2 got %+v", tree.Root)
+		}
+		for _, payload := range []map[string]interface{}{{}, {"root_id": "CWE-404"}} {
+			resp, err := treeH(ctx, &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "t2", Payload: func() []byte { b, _ := subprocess.MarshalFast(payload); return b }()})
+			if err != nil || resp == nil || resp.Type != subprocess.MessageTypeError {
+				t.Fatalf("expected error for payload %v", payload)
+			}
+		}
 	})
 
 }
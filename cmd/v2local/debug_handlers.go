@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/cve/local"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// EnvDebugRPC enables the debug RPCs. They reveal the database schema, so
+// they are only registered when it is set to a true value, which should be
+// confined to development and test deployments.
+const EnvDebugRPC = "V2E_DEBUG_RPC"
+
+// debugRPCsEnabled reports whether EnvDebugRPC is set to a true value
+func debugRPCsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnvDebugRPC))
+	return enabled
+}
+
+// explainQueryRequest is the payload of RPCExplainQuery. Filter takes the
+// parameters of the entity's list RPC; for CVEs, Keyword or Severity select
+// the query of RPCSearchCVEs.
+type explainQueryRequest struct {
+	Entity string `json:"entity"`
+	Filter struct {
+		Offset          int    `json:"offset"`
+		Limit           int    `json:"limit"`
+		IncludeRejected bool   `json:"include_rejected"`
+		KEV             bool   `json:"kev"`
+		Keyword         string `json:"keyword"`
+		Severity        string `json:"severity"`
+	} `json:"filter"`
+}
+
+// createExplainQueryHandler creates a handler for RPCExplainQuery, which
+// returns SQLite's query plan for the statements a list RPC would run
+func createExplainQueryHandler(db *local.DB, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req explainQueryRequest
+		req.Filter.Limit = 10
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse RPCExplainQuery request: %v", errResp.Error)
+			return errResp, nil
+		}
+
+		var plans []local.QueryPlan
+		var err error
+		switch req.Entity {
+		case "cve":
+			// Same filters as RPCListCVEs and RPCSearchCVEs
+			var excluded []string
+			if !req.Filter.IncludeRejected {
+				excluded = []string{cve.StatusRejected, cve.StatusDisputed}
+			}
+			f := req.Filter
+			switch {
+			case f.KEV && (f.Keyword != "" || f.Severity != ""):
+				return subprocess.NewErrorResponse(msg, "kev cannot be combined with keyword or severity"), nil
+			case f.KEV:
+				plans, err = db.ExplainListKEVCVEs(f.Offset, f.Limit, excluded)
+			case f.Keyword != "" || f.Severity != "":
+				plans, err = db.ExplainSearchCVEs(f.Keyword, f.Severity, f.Offset, f.Limit, excluded)
+			default:
+				plans, err = db.ExplainListCVEs(f.Offset, f.Limit, excluded)
+			}
+		default:
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("unsupported entity %q: must be cve", req.Entity)), nil
+		}
+		if err != nil {
+			logger.Warn("Failed to explain %s query: %v", req.Entity, err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to explain query: %v", err)), nil
+		}
+
+		logger.Debug("Explained %s list query: %d statements", req.Entity, len(plans))
+		resp, err := subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"entity":  req.Entity,
+			"queries": plans,
+		})
+		if err != nil {
+			logger.Error("Failed to marshal result: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
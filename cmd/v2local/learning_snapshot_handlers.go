@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/notes"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// Handler for RPCLearningExport
+func createLearningExportHandler(service *notes.BookmarkService, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		snapshot, err := service.ExportLearningSnapshot(ctx)
+		if err != nil {
+			logger.Warn("Failed to export learning snapshot: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to export learning snapshot: %v", err)), nil
+		}
+		logger.Info("Exported learning snapshot: %d items, %d links", len(snapshot.Items), len(snapshot.Links))
+		return subprocess.NewSuccessResponse(msg, snapshot)
+	}
+}
+
+// Handler for RPCLearningImport
+func createLearningImportHandler(service *notes.BookmarkService, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var params struct {
+			Snapshot *notes.LearningSnapshot `json:"snapshot"`
+		}
+		if errResp := subprocess.ParseRequest(msg, &params); errResp != nil {
+			logger.Warn("Failed to parse request: %v", errResp.Error)
+			return errResp, nil
+		}
+		if params.Snapshot == nil {
+			logger.Warn("snapshot is required")
+			return subprocess.NewErrorResponse(msg, "snapshot is required"), nil
+		}
+		result, err := service.ImportLearningSnapshot(ctx, params.Snapshot)
+		if err != nil {
+			logger.Warn("Failed to import learning snapshot: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to import learning snapshot: %v", err)), nil
+		}
+		logger.Info("Imported learning snapshot: %d created, %d updated, %d kept, %d links added",
+			result.Created, result.Updated, result.Kept, result.LinksAdded)
+		return subprocess.NewSuccessResponse(msg, result)
+	}
+}
@@ -7,6 +7,7 @@ Notes:
 ------
 - Uses SQLite databases for local CVE and CWE storage
 - Database paths configured via CVE_DB_PATH and CWE_DB_PATH environment variables (defaults: cve.db, cwe.db)
+- Reference URL health checking is opt-in via CVE_REFERENCE_HEALTH_INTERVAL
 - Supports GORM for ORM operations
 - Service runs as a subprocess managed by the broker
 - All requests are routed through the broker via RPC
@@ -14,14 +15,19 @@ Notes:
 package main
 
 import (
+	"context"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/asvs"
 	"github.com/cyw0ng95/v2e/pkg/attack"
 	"github.com/cyw0ng95/v2e/pkg/capec"
 	"github.com/cyw0ng95/v2e/pkg/cce"
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/intentlog"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
 	"github.com/cyw0ng95/v2e/pkg/cve/local"
 	"github.com/cyw0ng95/v2e/pkg/cwe"
 	"github.com/cyw0ng95/v2e/pkg/notes"
@@ -129,6 +135,43 @@ func main() {
 		db.Close()
 	}()
 
+	// Rows per transaction for bulk CVE saves; unset commits each batch at once
+	if commitSize := os.Getenv("CVE_DB_COMMIT_SIZE"); commitSize != "" {
+		if n, err := strconv.Atoi(commitSize); err != nil || n < 0 {
+			logger.Warn("Invalid CVE_DB_COMMIT_SIZE %q, committing each batch at once", commitSize)
+		} else {
+			db.SetCommitSize(n)
+			logger.Info("CVE bulk save commit size configured: %d", n)
+		}
+	}
+
+	// Heavy background work only runs inside the shared maintenance window;
+	// an unset or invalid V2E_MAINTENANCE_WINDOW leaves it always open
+	window, err := maintenance.FromEnv()
+	if err != nil {
+		logger.Warn("Invalid %s, background work is always allowed: %v", maintenance.EnvVar, err)
+		window, _ = maintenance.Parse("")
+	}
+
+	// Optionally probe CVE reference URLs in the background; disabled unless
+	// CVE_REFERENCE_HEALTH_INTERVAL is set to a Go duration such as "24h"
+	if interval := os.Getenv("CVE_REFERENCE_HEALTH_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil {
+			logger.Warn("Invalid CVE_REFERENCE_HEALTH_INTERVAL %q: %v", interval, err)
+		} else {
+			config := local.DefaultReferenceHealthConfig()
+			config.Interval = d
+			config.Window = window
+			checker := local.NewReferenceHealthChecker(db, config)
+			checkCtx, stopCheck := context.WithCancel(context.Background())
+			defer stopCheck()
+			go checker.Run(checkCtx, func(err error) {
+				logger.Warn("Reference health sweep failed: %v", err)
+			})
+			logger.Info("Reference health checker enabled with interval %v", d)
+		}
+	}
+
 	// Initialize CWE store (using a separate DB file or the same as CVE)
 	cweDBPath := os.Getenv("CWE_DB_PATH")
 	if cweDBPath == "" {
@@ -225,6 +268,22 @@ func main() {
 	}
 	logger.Info("Notes service initialized and migrations completed")
 
+	// Writes spanning the CVE and bookmark databases are covered by intents;
+	// finish any that a crash interrupted before serving requests
+	intents, err := intentlog.Open(intentlog.DirFromEnv())
+	if err != nil {
+		logger.Error("Failed to open intent log: %v", err)
+		os.Exit(1)
+	}
+	deleter := &cascadingCVEDeleter{db: db, bookmarks: bookmarkDB.GormDB(), intents: intents}
+	recovered, err := intents.Recover(deleter.resolvers())
+	if recovered > 0 {
+		logger.Info("Recovered %d interrupted cross-database writes from %s", recovered, intents.Dir())
+	}
+	if err != nil {
+		logger.Warn("Some interrupted writes could not be recovered, retrying on next start: %v", err)
+	}
+
 	// Initialize SSG store
 	ssgDBPath := os.Getenv("SSG_DB_PATH")
 	if ssgDBPath == "" {
@@ -246,22 +305,65 @@ func main() {
 	logger.Info("Registering RPC handlers...")
 	sp.RegisterHandler("RPCSaveCVEByID", createSaveCVEByIDHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCSaveCVEByID")
+	sp.RegisterHandler("RPCSaveCVEsBatch", createSaveCVEsBatchHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCSaveCVEsBatch")
+	sp.RegisterHandler("RPCImportKEV", createImportKEVHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCImportKEV")
 	sp.RegisterHandler("RPCIsCVEStoredByID", createIsCVEStoredByIDHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCIsCVEStoredByID")
 	sp.RegisterHandler("RPCGetCVEByID", createGetCVEByIDHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVEByID")
-	sp.RegisterHandler("RPCDeleteCVEByID", createDeleteCVEByIDHandler(db, logger))
+	sp.RegisterHandler("RPCDeleteCVEByID", createDeleteCVEByIDHandler(deleter, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCDeleteCVEByID")
 	sp.RegisterHandler("RPCListCVEs", createListCVEsHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListCVEs")
+	sp.RegisterHandler("RPCSearchCVEs", createSearchCVEsHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCSearchCVEs")
+	sp.RegisterHandler("RPCReindexSearch", createReindexSearchHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCReindexSearch")
+	sp.RegisterHandler("RPCGetCVEsByCWE", createGetCVEsByCWEHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVEsByCWE")
+	sp.RegisterHandler("RPCGetCVEsWithExploitRefs", createGetCVEsWithExploitRefsHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVEsWithExploitRefs")
+	sp.RegisterHandler("RPCGetCVEByCPE", createGetCVEByCPEHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVEByCPE")
+	sp.RegisterHandler("RPCGetCVETimeline", createGetCVETimelineHandler(db, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVETimeline")
 	sp.RegisterHandler("RPCCountCVEs", createCountCVEsHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCCountCVEs")
+	sp.RegisterHandler("RPCGetMaintenanceWindow", createGetMaintenanceWindowHandler(window, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetMaintenanceWindow")
+	storage := &storageSources{
+		cve:       db,
+		cwe:       cweStore,
+		capec:     capecStore,
+		attack:    attackStore,
+		asvs:      asvsStore,
+		bookmarks: bookmarkDB.GormDB(),
+		files: []storageFile{
+			{"cve", dbPath},
+			{"cwe", cweDBPath},
+			{"capec", capecDBPath},
+			{"attack", attackDBPath},
+			{"asvs", asvsDBPath},
+			{"cce", cceDBPath},
+			{"bookmark", bookmarkDBPath},
+			{"ssg", ssgDBPath},
+		},
+	}
+	sp.RegisterHandler("RPCGetStorageStats", createGetStorageStatsHandler(storage, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetStorageStats")
+	if debugRPCsEnabled() {
+		sp.RegisterHandler("RPCExplainQuery", createExplainQueryHandler(db, logger))
+		logger.Info(LogMsgRPCHandlerRegistered, "RPCExplainQuery")
+		logger.Warn("Debug RPCs enabled by %s; they reveal the database schema", EnvDebugRPC)
+	}
 	// Register additional CVE handlers for meta service compatibility
 	sp.RegisterHandler("RPCCreateCVE", createCreateCVEHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCCreateCVE")
 	sp.RegisterHandler("RPCUpdateCVE", createUpdateCVEHandler(db, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCUpdateCVE")
-	sp.RegisterHandler("RPCDeleteCVE", createDeleteCVEHandler(db, logger))
+	sp.RegisterHandler("RPCDeleteCVE", createDeleteCVEHandler(deleter, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCDeleteCVE")
 	sp.RegisterHandler("RPCGetCWEByID", createGetCWEByIDHandler(cweStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCWEByID")
@@ -269,6 +371,8 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListCWEs")
 	sp.RegisterHandler("RPCImportCWEs", createImportCWEsHandler(cweStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCImportCWEs")
+	sp.RegisterHandler("RPCGetCWETree", createGetCWETreeHandler(cweStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCWETree")
 	sp.RegisterHandler("RPCImportCAPECs", createImportCAPECsHandler(capecStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCImportCAPECs")
 	sp.RegisterHandler("RPCForceImportCAPECs", createForceImportCAPECsHandler(capecStore, logger))
@@ -277,8 +381,14 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListCAPECs")
 	sp.RegisterHandler("RPCGetCAPECByID", createGetCAPECByIDHandler(capecStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCAPECByID")
+	sp.RegisterHandler("RPCGetCAPECsByCWE", createGetCAPECsByCWEHandler(capecStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCAPECsByCWE")
 	sp.RegisterHandler("RPCGetCAPECCatalogMeta", createGetCAPECCatalogMetaHandler(capecStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCAPECCatalogMeta")
+	sp.RegisterHandler("RPCGetCatalogVersions", createGetCatalogVersionsHandler(logger, cweStore, capecStore, attackStore))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCatalogVersions")
+	sp.RegisterHandler("RPCGetDataCapabilities", createGetDataCapabilitiesHandler(logger, db, cweStore, capecStore, attackStore))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetDataCapabilities")
 
 	// Register CWE View handlers
 	RegisterCWEViewHandlers(sp, cweStore, logger)
@@ -299,6 +409,10 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackGroup")
 	sp.RegisterHandler("RPCGetAttackTechniqueByID", createGetAttackTechniqueByIDHandler(attackStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackTechniqueByID")
+	sp.RegisterHandler("RPCGetAttackSubTechniques", createGetAttackSubTechniquesHandler(attackStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackSubTechniques")
+	sp.RegisterHandler("RPCGetAttackTechniqueRelationships", createGetAttackTechniqueRelationshipsHandler(attackStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackTechniqueRelationships")
 	sp.RegisterHandler("RPCGetAttackTacticByID", createGetAttackTacticByIDHandler(attackStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackTacticByID")
 	sp.RegisterHandler("RPCGetAttackMitigationByID", createGetAttackMitigationByIDHandler(attackStore, logger))
@@ -319,6 +433,8 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListAttackGroups")
 	sp.RegisterHandler("RPCGetAttackImportMetadata", createGetAttackImportMetadataHandler(attackStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackImportMetadata")
+	sp.RegisterHandler("RPCGetAttackCoverage", createGetAttackCoverageHandler(attackStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetAttackCoverage")
 
 	// Register ASVS handlers
 	sp.RegisterHandler("RPCImportASVS", createImportASVSHandler(asvsStore, logger))
@@ -327,6 +443,8 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListASVS")
 	sp.RegisterHandler("RPCGetASVSByID", createGetASVSByIDHandler(asvsStore, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetASVSByID")
+	sp.RegisterHandler("RPCGetASVSByCWE", createGetASVSByCWEHandler(asvsStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetASVSByCWE")
 
 	// Register Notes service handlers
 	notes.NewRPCHandlers(notesServiceContainer, sp, logger)
@@ -340,6 +458,11 @@ func main() {
 	sp.RegisterHandler("RPCListMemoryCards", listMemoryCardsHandler(notesServiceContainer.MemoryCardService.(*notes.MemoryCardService), logger))
 	logger.Info("Memory Card handlers registered")
 
+	// Register learning progress export/import handlers
+	sp.RegisterHandler("RPCLearningExport", createLearningExportHandler(notesServiceContainer.BookmarkService.(*notes.BookmarkService), logger))
+	sp.RegisterHandler("RPCLearningImport", createLearningImportHandler(notesServiceContainer.BookmarkService.(*notes.BookmarkService), logger))
+	logger.Info("Learning snapshot handlers registered")
+
 	// Register SSG handlers
 	RegisterSSGHandlers(sp, ssgStore, logger)
 	logger.Info("SSG handlers registered")
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// createGetMaintenanceWindowHandler creates a handler for RPCGetMaintenanceWindow
+func createGetMaintenanceWindowHandler(window *maintenance.Window, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		state := window.State()
+		logger.Debug("Maintenance window state: open=%v spec=%q", state.Open, state.Spec)
+		resp, err := subprocess.NewSuccessResponse(msg, state)
+		if err != nil {
+			logger.Error("Failed to marshal result: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
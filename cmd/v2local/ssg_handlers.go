@@ -231,6 +231,41 @@ func createSSGListRulesHandler(store *local.Store, logger *common.Logger) subpro
 	}
 }
 
+// createSSGSearchHandler creates a handler for RPCSearchSSG
+func createSSGSearchHandler(store *local.Store, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug("Processing RPCSearchSSG request")
+		var req struct {
+			Keyword  string   `json:"keyword"`
+			GuideID  string   `json:"guide_id"`
+			Product  string   `json:"product"`
+			Severity string   `json:"severity"`
+			Tags     []string `json:"tags"`
+			Offset   int      `json:"offset"`
+			Limit    int      `json:"limit"`
+		}
+		// Set defaults
+		req.Offset = 0
+		req.Limit = 20
+		if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+			logger.Warn("Failed to parse RPCSearchSSG request: %v", errResp.Error)
+			return errResp, nil
+		}
+		results, total, err := store.SearchRules(req.Keyword, req.GuideID, req.Product, req.Severity, req.Tags, req.Offset, req.Limit)
+		if err != nil {
+			logger.Warn("Failed to search rules: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to search rules: %v", err)), nil
+		}
+		logger.Info("Found %d rules for %q (total: %d)", len(results), req.Keyword, total)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"results": results,
+			"total":   total,
+			"offset":  req.Offset,
+			"limit":   req.Limit,
+		})
+	}
+}
+
 // createSSGGetChildRulesHandler creates a handler for RPCSSGGetChildRules
 func createSSGGetChildRulesHandler(store *local.Store, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -505,6 +540,9 @@ func RegisterSSGHandlers(sp *subprocess.Subprocess, store *local.Store, logger *
 	sp.RegisterHandler("RPCSSGListRules", createSSGListRulesHandler(store, logger))
 	logger.Info("RPC handler registered: RPCSSGListRules")
 
+	sp.RegisterHandler("RPCSearchSSG", createSSGSearchHandler(store, logger))
+	logger.Info("RPC handler registered: RPCSearchSSG")
+
 	sp.RegisterHandler("RPCSSGGetChildRules", createSSGGetChildRulesHandler(store, logger))
 	logger.Info("RPC handler registered: RPCSSGGetChildRules")
 
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cyw0ng95/v2e/pkg/asvs"
+	"github.com/cyw0ng95/v2e/pkg/attack"
+	"github.com/cyw0ng95/v2e/pkg/capec"
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve/local"
+	"github.com/cyw0ng95/v2e/pkg/cwe"
+	"github.com/cyw0ng95/v2e/pkg/notes"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"gorm.io/gorm"
+)
+
+// storageSources are the stores and database files RPCGetStorageStats
+// reports on
+type storageSources struct {
+	cve       *local.DB
+	cwe       *cwe.LocalCWEStore
+	capec     *capec.LocalCAPECStore
+	attack    *attack.LocalAttackStore
+	asvs      *asvs.LocalASVSStore
+	bookmarks *gorm.DB
+	// files are the SQLite files of the databases
+	files []storageFile
+}
+
+// storageFile names a SQLite database file
+type storageFile struct {
+	name string
+	path string
+}
+
+// storageCount is the number of rows of one dataset
+type storageCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// storageFileStats is the on-disk size of one SQLite database. A file that
+// cannot be stat'd is reported with size 0, SizeUnavailable and the error.
+type storageFileStats struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	// WALSizeBytes is the size of the write-ahead log next to the file, 0
+	// if there is none
+	WALSizeBytes    int64  `json:"wal_size_bytes"`
+	SizeUnavailable bool   `json:"size_unavailable,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// collectStorageCounts counts the rows of each dataset
+func collectStorageCounts(ctx context.Context, src *storageSources) ([]storageCount, error) {
+	var counts []storageCount
+	add := func(name string, count func() (int64, error)) error {
+		n, err := count()
+		if err != nil {
+			return fmt.Errorf("failed to count %s: %w", name, err)
+		}
+		counts = append(counts, storageCount{Name: name, Count: n})
+		return nil
+	}
+	modelCount := func(model interface{}) func() (int64, error) {
+		return func() (int64, error) {
+			var n int64
+			err := src.bookmarks.WithContext(ctx).Model(model).Count(&n).Error
+			return n, err
+		}
+	}
+
+	if err := add("cve", src.cve.Count); err != nil {
+		return nil, err
+	}
+	if err := add("cwe", func() (int64, error) { return src.cwe.Count(ctx) }); err != nil {
+		return nil, err
+	}
+	if err := add("capec", func() (int64, error) { return src.capec.Count(ctx) }); err != nil {
+		return nil, err
+	}
+	attackCounts, err := src.attack.Counts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attack: %w", err)
+	}
+	counts = append(counts,
+		storageCount{Name: "attack_techniques", Count: attackCounts.Techniques},
+		storageCount{Name: "attack_tactics", Count: attackCounts.Tactics},
+		storageCount{Name: "attack_mitigations", Count: attackCounts.Mitigations},
+		storageCount{Name: "attack_software", Count: attackCounts.Software},
+		storageCount{Name: "attack_groups", Count: attackCounts.Groups},
+	)
+	if err := add("asvs", func() (int64, error) { return src.asvs.Count(ctx) }); err != nil {
+		return nil, err
+	}
+	if err := add("bookmarks", modelCount(&notes.BookmarkModel{})); err != nil {
+		return nil, err
+	}
+	if err := add("notes", modelCount(&notes.NoteModel{})); err != nil {
+		return nil, err
+	}
+	if err := add("memory_cards", modelCount(&notes.MemoryCardModel{})); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// statStorageFile returns the on-disk size of a SQLite database file
+func statStorageFile(f storageFile) storageFileStats {
+	stats := storageFileStats{Name: f.name, Path: f.path}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		stats.SizeUnavailable, stats.Error = true, err.Error()
+		return stats
+	}
+	stats.SizeBytes = info.Size()
+	if wal, err := os.Stat(f.path + "-wal"); err == nil {
+		stats.WALSizeBytes = wal.Size()
+	}
+	return stats
+}
+
+// createGetStorageStatsHandler creates a handler for RPCGetStorageStats
+func createGetStorageStatsHandler(src *storageSources, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		counts, err := collectStorageCounts(ctx, src)
+		if err != nil {
+			logger.Error("Failed to collect storage counts: %v", err)
+			return subprocess.NewErrorResponse(msg, err.Error()), nil
+		}
+
+		var totalSize int64
+		files := make([]storageFileStats, 0, len(src.files))
+		for _, f := range src.files {
+			stats := statStorageFile(f)
+			if stats.SizeUnavailable {
+				logger.Warn("Failed to stat database file %s: %s", f.path, stats.Error)
+			}
+			totalSize += stats.SizeBytes + stats.WALSizeBytes
+			files = append(files, stats)
+		}
+
+		resp, err := subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"counts":           counts,
+			"databases":        files,
+			"total_size_bytes": totalSize,
+		})
+		if err != nil {
+			logger.Error("Failed to marshal result: %v", err)
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		return resp, nil
+	}
+}
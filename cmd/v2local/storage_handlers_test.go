@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/asvs"
+	"github.com/cyw0ng95/v2e/pkg/attack"
+	"github.com/cyw0ng95/v2e/pkg/capec"
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/cve/local"
+	"github.com/cyw0ng95/v2e/pkg/cwe"
+	"github.com/cyw0ng95/v2e/pkg/notes"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestGetStorageStatsHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestGetStorageStatsHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		var buf bytes.Buffer
+		logger := common.NewLogger(&buf, "", common.DebugLevel)
+
+		paths := map[string]string{}
+		for _, name := range []string{"cve", "cwe", "capec", "attack", "asvs", "bookmark"} {
+			paths[name] = filepath.Join(dir, name+".db")
+		}
+		db, err := local.NewDB(paths["cve"])
+		if err != nil {
+			t.Fatalf("NewDB error: %v", err)
+		}
+		defer db.Close()
+		cweStore, err := cwe.NewLocalCWEStore(paths["cwe"])
+		if err != nil {
+			t.Fatalf("NewLocalCWEStore error: %v", err)
+		}
+		capecStore, err := capec.NewLocalCAPECStore(paths["capec"])
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore error: %v", err)
+		}
+		attackStore, err := attack.NewLocalAttackStore(paths["attack"])
+		if err != nil {
+			t.Fatalf("NewLocalAttackStore error: %v", err)
+		}
+		asvsStore, err := asvs.NewLocalASVSStore(paths["asvs"])
+		if err != nil {
+			t.Fatalf("NewLocalASVSStore error: %v", err)
+		}
+		bookmarkDB, err := local.NewDB(paths["bookmark"])
+		if err != nil {
+			t.Fatalf("NewDB error: %v", err)
+		}
+		defer bookmarkDB.Close()
+		if err := notes.MigrateNotesTables(bookmarkDB.GormDB()); err != nil {
+			t.Fatalf("MigrateNotesTables error: %v", err)
+		}
+
+		for _, id := range []string{"CVE-2024-0001", "CVE-2024-0002"} {
+			if err := db.SaveCVE(&cve.CVEItem{ID: id}); err != nil {
+				t.Fatalf("SaveCVE error: %v", err)
+			}
+		}
+
+		src := &storageSources{
+			cve:       db,
+			cwe:       cweStore,
+			capec:     capecStore,
+			attack:    attackStore,
+			asvs:      asvsStore,
+			bookmarks: bookmarkDB.GormDB(),
+			files: []storageFile{
+				{"cve", paths["cve"]},
+				{"bookmark", paths["bookmark"]},
+				{"missing", filepath.Join(dir, "missing.db")},
+			},
+		}
+		h := createGetStorageStatsHandler(src, logger)
+		resp, err := h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "stats"})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("handler failed: err=%v resp=%+v", err, resp)
+		}
+
+		var result struct {
+			Counts         []storageCount     `json:"counts"`
+			Databases      []storageFileStats `json:"databases"`
+			TotalSizeBytes int64              `json:"total_size_bytes"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		counts := map[string]int64{}
+		for _, c := range result.Counts {
+			counts[c.Name] = c.Count
+		}
+		for _, name := range []string{"cwe", "capec", "attack_techniques", "asvs", "bookmarks", "notes", "memory_cards"} {
+			if n, ok := counts[name]; !ok || n != 0 {
+				t.Errorf("Expected an empty %s count, got %d (reported %v)", name, n, ok)
+			}
+		}
+		if counts["cve"] != 2 {
+			t.Errorf("Expected 2 CVEs, got %d", counts["cve"])
+		}
+
+		// A file that cannot be stat'd is flagged rather than failing the call
+		if len(result.Databases) != 3 {
+			t.Fatalf("Expected 3 databases, got %+v", result.Databases)
+		}
+		var total int64
+		for _, f := range result.Databases[:2] {
+			if f.SizeUnavailable || f.SizeBytes == 0 {
+				t.Errorf("Expected a size for %s, got %+v", f.Name, f)
+			}
+			total += f.SizeBytes + f.WALSizeBytes
+		}
+		if missing := result.Databases[2]; !missing.SizeUnavailable || missing.SizeBytes != 0 || missing.Error == "" {
+			t.Errorf("Expected the missing file flagged with size 0, got %+v", missing)
+		}
+		if result.TotalSizeBytes != total {
+			t.Errorf("Expected total size %d, got %d", total, result.TotalSizeBytes)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+)
+
+// CVE cache policy modes
+const (
+	CachePolicyAlways = "always" // Cache every fetched CVE (default)
+	CachePolicyNever  = "never"  // Never cache; fetched CVEs are returned transient
+	CachePolicyFilter = "filter" // Cache only CVEs matching the criteria
+)
+
+// Environment variables configuring the CVE cache policy
+const (
+	EnvCVECachePolicy      = "CVE_CACHE_POLICY"
+	EnvCVECacheMinCVSS     = "CVE_CACHE_MIN_CVSS"
+	EnvCVECacheYears       = "CVE_CACHE_YEARS"
+	EnvCVECacheVendors     = "CVE_CACHE_VENDORS"
+	EnvCVECacheDenyVendors = "CVE_CACHE_DENY_VENDORS"
+)
+
+// CVECachePolicy decides which CVEs fetched from remote by RPCGetCVE are
+// saved to local storage. In filter mode a CVE is cached when it meets every
+// configured criterion and no denied vendor is affected.
+type CVECachePolicy struct {
+	Mode        string   `json:"mode"`
+	MinCVSS     float64  `json:"min_cvss,omitempty"`     // Highest base score of any CVSS version
+	Years       []int    `json:"years,omitempty"`        // Year of the CVE ID
+	Vendors     []string `json:"vendors,omitempty"`      // At least one affected CPE vendor must be listed
+	DenyVendors []string `json:"deny_vendors,omitempty"` // No affected CPE vendor may be listed
+}
+
+// CVEFreshness tells where an RPCGetCVE response came from and whether it
+// was cached under the applied policy
+type CVEFreshness struct {
+	Source      string `json:"source"` // "local" or "remote"
+	Cached      bool   `json:"cached"`
+	CachePolicy string `json:"cache_policy"`
+	// CacheReason explains a remote fetch's caching decision
+	CacheReason string `json:"cache_reason,omitempty"`
+}
+
+// cveWithFreshness is the RPCGetCVE response: the CVE fields with the
+// freshness block alongside
+type cveWithFreshness struct {
+	*cve.CVEItem
+	Freshness CVEFreshness `json:"freshness"`
+}
+
+// cachePolicyFromEnv reads and validates the cache policy. With nothing
+// configured every CVE is cached, as before the policy existed.
+func cachePolicyFromEnv() (*CVECachePolicy, error) {
+	policy := &CVECachePolicy{Mode: strings.ToLower(strings.TrimSpace(os.Getenv(EnvCVECachePolicy)))}
+	if policy.Mode == "" {
+		policy.Mode = CachePolicyAlways
+	}
+
+	if v := strings.TrimSpace(os.Getenv(EnvCVECacheMinCVSS)); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", EnvCVECacheMinCVSS, v, err)
+		}
+		policy.MinCVSS = score
+	}
+	for _, v := range splitList(os.Getenv(EnvCVECacheYears)) {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid year %q in %s", v, EnvCVECacheYears)
+		}
+		policy.Years = append(policy.Years, year)
+	}
+	policy.Vendors = splitList(strings.ToLower(os.Getenv(EnvCVECacheVendors)))
+	policy.DenyVendors = splitList(strings.ToLower(os.Getenv(EnvCVECacheDenyVendors)))
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks that the policy is consistent
+func (p *CVECachePolicy) Validate() error {
+	hasCriteria := p.MinCVSS != 0 || len(p.Years) > 0 || len(p.Vendors) > 0 || len(p.DenyVendors) > 0
+	switch p.Mode {
+	case CachePolicyAlways, CachePolicyNever:
+		if hasCriteria {
+			return fmt.Errorf("cache policy %q takes no criteria; use %q", p.Mode, CachePolicyFilter)
+		}
+	case CachePolicyFilter:
+		if !hasCriteria {
+			return fmt.Errorf("cache policy %q needs at least one criterion", CachePolicyFilter)
+		}
+	default:
+		return fmt.Errorf("invalid cache policy %q: must be %s, %s or %s", p.Mode, CachePolicyAlways, CachePolicyNever, CachePolicyFilter)
+	}
+	if p.MinCVSS < 0 || p.MinCVSS > 10 {
+		return fmt.Errorf("minimum CVSS score %v is outside 0-10", p.MinCVSS)
+	}
+	for _, year := range p.Years {
+		if year < 1999 || year > 9999 {
+			return fmt.Errorf("invalid CVE year %d", year)
+		}
+	}
+	return nil
+}
+
+// ShouldCache decides whether a fetched CVE is cached and why
+func (p *CVECachePolicy) ShouldCache(item *cve.CVEItem) (bool, string) {
+	switch p.Mode {
+	case CachePolicyAlways:
+		return true, "policy caches every CVE"
+	case CachePolicyNever:
+		return false, "policy caches no CVE"
+	}
+
+	if p.MinCVSS > 0 {
+		score, ok := maxBaseScore(item)
+		if !ok {
+			return false, "no CVSS score"
+		}
+		if score < p.MinCVSS {
+			return false, fmt.Sprintf("CVSS %.1f below minimum %.1f", score, p.MinCVSS)
+		}
+	}
+	if len(p.Years) > 0 {
+		year := cveYear(item.ID)
+		if !containsInt(p.Years, year) {
+			return false, fmt.Sprintf("year %d not cached", year)
+		}
+	}
+	vendors := affectedVendors(item)
+	for _, v := range p.DenyVendors {
+		if vendors[v] {
+			return false, fmt.Sprintf("vendor %s denied", v)
+		}
+	}
+	if len(p.Vendors) > 0 {
+		matched := false
+		for _, v := range p.Vendors {
+			if vendors[v] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, "no allowed vendor affected"
+		}
+	}
+	return true, "matches cache criteria"
+}
+
+// maxBaseScore returns the highest base score across all CVSS versions
+func maxBaseScore(item *cve.CVEItem) (float64, bool) {
+	if item.Metrics == nil {
+		return 0, false
+	}
+	var scores []float64
+	for _, m := range item.Metrics.CvssMetricV40 {
+		scores = append(scores, m.CvssData.BaseScore)
+	}
+	for _, m := range item.Metrics.CvssMetricV31 {
+		scores = append(scores, m.CvssData.BaseScore)
+	}
+	for _, m := range item.Metrics.CvssMetricV30 {
+		scores = append(scores, m.CvssData.BaseScore)
+	}
+	for _, m := range item.Metrics.CvssMetricV2 {
+		scores = append(scores, m.CvssData.BaseScore)
+	}
+	if len(scores) == 0 {
+		return 0, false
+	}
+	best := scores[0]
+	for _, s := range scores[1:] {
+		if s > best {
+			best = s
+		}
+	}
+	return best, true
+}
+
+// cveYear returns the year of a CVE ID ("CVE-2024-1234" is 2024), or 0
+func cveYear(id string) int {
+	parts := strings.SplitN(id, "-", 3)
+	if len(parts) < 3 {
+		return 0
+	}
+	year, _ := strconv.Atoi(parts[1])
+	return year
+}
+
+// affectedVendors returns the lower-cased vendors of the vulnerable CPEs
+// ("cpe:2.3:a:<vendor>:<product>:...")
+func affectedVendors(item *cve.CVEItem) map[string]bool {
+	vendors := make(map[string]bool)
+	for _, config := range item.Configurations {
+		for _, node := range config.Nodes {
+			for _, match := range node.CPEMatch {
+				if !match.Vulnerable {
+					continue
+				}
+				if parts := strings.Split(match.Criteria, ":"); len(parts) > 3 && parts[3] != "*" {
+					vendors[strings.ToLower(parts[3])] = true
+				}
+			}
+		}
+	}
+	return vendors
+}
+
+// splitList splits a comma-separated list, dropping blanks
+func splitList(s string) []string {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// policyTestCVE returns a CVE with a CVSS v3.1 score affecting one vendor
+func policyTestCVE(id string, score float64, vendor string) *cve.CVEItem {
+	item := &cve.CVEItem{ID: id}
+	if score > 0 {
+		item.Metrics = &cve.Metrics{CvssMetricV31: []cve.CVSSMetricV3{{CvssData: cve.CVSSDataV3{BaseScore: score}}}}
+	}
+	if vendor != "" {
+		item.Configurations = []cve.Config{{Nodes: []cve.Node{{CPEMatch: []cve.CPEMatch{
+			{Vulnerable: true, Criteria: "cpe:2.3:a:" + vendor + ":product:1.0:*:*:*:*:*:*:*"},
+			{Vulnerable: false, Criteria: "cpe:2.3:o:platform:os:-:*:*:*:*:*:*:*"},
+		}}}}}
+	}
+	return item
+}
+
+func TestCVECachePolicy_ShouldCache(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVECachePolicy_ShouldCache", nil, func(t *testing.T, tx *gorm.DB) {
+		filter := &CVECachePolicy{Mode: CachePolicyFilter, MinCVSS: 7, Years: []int{2023, 2024}, Vendors: []string{"apache", "microsoft"}, DenyVendors: []string{"microsoft"}}
+		if err := filter.Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+
+		cases := []struct {
+			name   string
+			policy *CVECachePolicy
+			item   *cve.CVEItem
+			cache  bool
+			reason string
+		}{
+			{"always", &CVECachePolicy{Mode: CachePolicyAlways}, policyTestCVE("CVE-2010-0001", 0, ""), true, "every"},
+			{"never", &CVECachePolicy{Mode: CachePolicyNever}, policyTestCVE("CVE-2024-0001", 9.8, "apache"), false, "no CVE"},
+			{"match", filter, policyTestCVE("CVE-2024-0001", 9.8, "apache"), true, "matches"},
+			{"low score", filter, policyTestCVE("CVE-2024-0002", 5.3, "apache"), false, "below minimum"},
+			{"no score", filter, policyTestCVE("CVE-2024-0003", 0, "apache"), false, "no CVSS"},
+			{"old year", filter, policyTestCVE("CVE-2019-0004", 9.8, "apache"), false, "year 2019"},
+			{"denied vendor", filter, policyTestCVE("CVE-2024-0005", 9.8, "Microsoft"), false, "microsoft denied"},
+			{"other vendor", filter, policyTestCVE("CVE-2024-0006", 9.8, "oracle"), false, "no allowed vendor"},
+			{"platform only", filter, policyTestCVE("CVE-2024-0007", 9.8, ""), false, "no allowed vendor"},
+		}
+		for _, c := range cases {
+			cache, reason := c.policy.ShouldCache(c.item)
+			if cache != c.cache || !strings.Contains(reason, c.reason) {
+				t.Errorf("%s: ShouldCache = %v %q, want %v containing %q", c.name, cache, reason, c.cache, c.reason)
+			}
+		}
+	})
+}
+
+func TestCVECachePolicy_Validate(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVECachePolicy_Validate", nil, func(t *testing.T, tx *gorm.DB) {
+		invalid := []*CVECachePolicy{
+			{Mode: "sometimes"},
+			{Mode: CachePolicyFilter},
+			{Mode: CachePolicyAlways, MinCVSS: 7},
+			{Mode: CachePolicyFilter, MinCVSS: 11},
+			{Mode: CachePolicyFilter, Years: []int{24}},
+		}
+		for _, p := range invalid {
+			if err := p.Validate(); err == nil {
+				t.Errorf("Expected %+v to be rejected", p)
+			}
+		}
+		for _, p := range []*CVECachePolicy{{Mode: CachePolicyAlways}, {Mode: CachePolicyNever}, {Mode: CachePolicyFilter, DenyVendors: []string{"acme"}}} {
+			if err := p.Validate(); err != nil {
+				t.Errorf("Expected %+v to be valid, got %v", p, err)
+			}
+		}
+	})
+}
+
+func TestCVEWithFreshness_KeepsCVEFields(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVEWithFreshness_KeepsCVEFields", nil, func(t *testing.T, tx *gorm.DB) {
+		data, err := json.Marshal(cveWithFreshness{
+			CVEItem:   &cve.CVEItem{ID: "CVE-2024-0001", VulnStatus: "Analyzed"},
+			Freshness: CVEFreshness{Source: "remote", CachePolicy: CachePolicyNever, CacheReason: "policy caches no CVE"},
+		})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded map[string]interface{}
+		json.Unmarshal(data, &decoded)
+		if decoded["id"] != "CVE-2024-0001" || decoded["vulnStatus"] != "Analyzed" {
+			t.Errorf("Expected the CVE fields at the top level, got %s", data)
+		}
+		freshness, _ := decoded["freshness"].(map[string]interface{})
+		if freshness["source"] != "remote" || freshness["cached"] != false || freshness["cache_policy"] != CachePolicyNever {
+			t.Errorf("Unexpected freshness block %v", freshness)
+		}
+	})
+}
@@ -24,9 +24,16 @@ const (
 	LogMsgCreatingRunStore       = "[meta] Creating run store..."
 	LogMsgFailedToCreateRunStore = "[meta] Failed to create run store: %v"
 	LogMsgRunStoreCreated        = "[meta] Run store created successfully"
+	LogMsgInvalidCachePolicy     = "[meta] Invalid CVE cache policy: %v"
+	LogMsgCachePolicyConfigured  = "[meta] CVE cache policy: %s (min CVSS: %v, years: %v, vendors: %v, denied vendors: %v)"
 	LogMsgRunStoreOpening        = "[meta] Opening run store at path: %s"
 	LogMsgRunStoreOpened         = "[meta] Run store opened successfully"
 	LogMsgRunStoreClosing        = "[meta] Closing run store"
+	LogMsgTimelineRecordFailed   = "[meta] Failed to record %s timeline event: %v"
+	LogMsgInvalidCVELoaderConfig = "[meta] Invalid CVE loader configuration: %v"
+	LogMsgCVELoaderConfigured    = "[meta] CVE loader: concurrency %d, offline %v"
+	LogMsgPrefetchStarted        = "[meta] Prefetch %s started: %d CVEs"
+	LogMsgPrefetchCompleted      = "[meta] Prefetch %s completed: %d CVEs (stored %d, fetched %d, not cached %d, failed %d)"
 
 	// Component Initialization Log Messages
 	LogMsgSubprocessCreated        = "[meta] Subprocess created with ID: %s"
@@ -122,6 +129,10 @@ const (
 	LogMsgStartingTypedSession    = "[meta] Starting typed session: id=%s, type=%s, index=%d, batch=%d"
 	LogMsgTypedSessionStarted     = "[meta] Typed session started successfully: %s"
 	LogMsgTypedSessionStartFailed = "[meta] Typed session start failed: %v"
+	LogMsgSessionSubscribed       = "[meta] Session events subscription %s: run %s, subscriber %s"
+	LogMsgSessionSubscribeFailed  = "[meta] Session events subscription %s failed: %v"
+	LogMsgSessionUnsubscribed     = "[meta] Session events subscription %s cancelled"
+	LogMsgSessionEventFailed      = "[meta] Failed to send %s event of subscription %s: %v"
 
 	// CWE View Job Log Messages
 	LogMsgCWEViewJobStartRequested = "[meta] CWE view job start requested"
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+)
+
+// Presence of a CVE in a diff
+const (
+	CVEPresenceBoth       = "both"
+	CVEPresenceLocalOnly  = "local_only"
+	CVEPresenceRemoteOnly = "remote_only"
+)
+
+// Fields compared by RPCDiffCVE
+const (
+	DiffFieldDescriptions = "descriptions"
+	DiffFieldMetrics      = "metrics"
+	DiffFieldReferences   = "references"
+	DiffFieldLastModified = "lastModified"
+)
+
+// CVEFieldDiff is one field that differs between the local and remote copy
+// of a CVE
+type CVEFieldDiff struct {
+	Field string `json:"field"`
+	// Lang is the language of a differing description
+	Lang   string      `json:"lang,omitempty"`
+	Local  interface{} `json:"local,omitempty"`
+	Remote interface{} `json:"remote,omitempty"`
+	// Added and Removed are the reference URLs only in the remote and only in
+	// the local copy
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// CVEDiff compares the locally stored copy of a CVE with the remote one
+type CVEDiff struct {
+	CVEID    string `json:"cve_id"`
+	Presence string `json:"presence"`
+	// Identical is set when the CVE exists on both sides and no compared
+	// field differs
+	Identical bool `json:"identical"`
+	// Outdated is set when the remote copy has a different lastModified, so
+	// the local copy is stale
+	Outdated           bool           `json:"outdated"`
+	LocalLastModified  *time.Time     `json:"local_last_modified,omitempty"`
+	RemoteLastModified *time.Time     `json:"remote_last_modified,omitempty"`
+	Changes            []CVEFieldDiff `json:"changes"`
+}
+
+// diffCVE compares the local and remote copies of a CVE field by field; either
+// may be nil when the CVE exists on one side only
+func diffCVE(cveID string, local, remote *cve.CVEItem) CVEDiff {
+	d := CVEDiff{CVEID: cveID, Changes: []CVEFieldDiff{}}
+	if local != nil {
+		d.LocalLastModified = lastModifiedOf(local)
+	}
+	if remote != nil {
+		d.RemoteLastModified = lastModifiedOf(remote)
+	}
+	switch {
+	case local == nil:
+		d.Presence = CVEPresenceRemoteOnly
+		return d
+	case remote == nil:
+		d.Presence = CVEPresenceLocalOnly
+		return d
+	}
+	d.Presence = CVEPresenceBoth
+
+	if !local.LastModified.Equal(remote.LastModified.Time) {
+		d.Outdated = true
+		d.Changes = append(d.Changes, CVEFieldDiff{
+			Field:  DiffFieldLastModified,
+			Local:  d.LocalLastModified,
+			Remote: d.RemoteLastModified,
+		})
+	}
+	d.Changes = append(d.Changes, diffDescriptions(local.Descriptions, remote.Descriptions)...)
+	if !reflect.DeepEqual(metricsOrEmpty(local.Metrics), metricsOrEmpty(remote.Metrics)) {
+		d.Changes = append(d.Changes, CVEFieldDiff{Field: DiffFieldMetrics, Local: local.Metrics, Remote: remote.Metrics})
+	}
+	if change, ok := diffReferences(local.References, remote.References); ok {
+		d.Changes = append(d.Changes, change)
+	}
+	d.Identical = len(d.Changes) == 0
+	return d
+}
+
+// lastModifiedOf returns the lastModified time of a CVE, nil if unset
+func lastModifiedOf(item *cve.CVEItem) *time.Time {
+	if item.LastModified.IsZero() {
+		return nil
+	}
+	t := item.LastModified.UTC()
+	return &t
+}
+
+// metricsOrEmpty treats a CVE without metrics like one with no scores
+func metricsOrEmpty(m *cve.Metrics) cve.Metrics {
+	if m == nil {
+		return cve.Metrics{}
+	}
+	return *m
+}
+
+// diffDescriptions returns one change per language whose description was
+// added, removed or reworded, sorted by language
+func diffDescriptions(local, remote []cve.Description) []CVEFieldDiff {
+	byLang := func(descs []cve.Description) map[string]string {
+		m := make(map[string]string, len(descs))
+		for _, d := range descs {
+			m[d.Lang] = d.Value
+		}
+		return m
+	}
+	localByLang, remoteByLang := byLang(local), byLang(remote)
+
+	langs := make([]string, 0, len(localByLang)+len(remoteByLang))
+	for lang := range localByLang {
+		langs = append(langs, lang)
+	}
+	for lang := range remoteByLang {
+		if _, ok := localByLang[lang]; !ok {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+
+	var changes []CVEFieldDiff
+	for _, lang := range langs {
+		l, inLocal := localByLang[lang]
+		r, inRemote := remoteByLang[lang]
+		if inLocal && inRemote && l == r {
+			continue
+		}
+		change := CVEFieldDiff{Field: DiffFieldDescriptions, Lang: lang}
+		if inLocal {
+			change.Local = l
+		}
+		if inRemote {
+			change.Remote = r
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// diffReferences compares references by URL and source and tags; the health
+// attached locally is not part of the NVD data and is ignored. It reports
+// whether they differ.
+func diffReferences(local, remote []cve.Reference) (CVEFieldDiff, bool) {
+	key := func(ref cve.Reference) string {
+		tags := append([]string(nil), ref.Tags...)
+		sort.Strings(tags)
+		return fmt.Sprintf("%s\x00%s\x00%v", ref.URL, ref.Source, tags)
+	}
+	localKeys := make(map[string]bool, len(local))
+	localURLs := make(map[string]bool, len(local))
+	for _, ref := range local {
+		localKeys[key(ref)] = true
+		localURLs[ref.URL] = true
+	}
+	remoteKeys := make(map[string]bool, len(remote))
+	remoteURLs := make(map[string]bool, len(remote))
+	for _, ref := range remote {
+		remoteKeys[key(ref)] = true
+		remoteURLs[ref.URL] = true
+	}
+	if reflect.DeepEqual(localKeys, remoteKeys) {
+		return CVEFieldDiff{}, false
+	}
+
+	change := CVEFieldDiff{Field: DiffFieldReferences}
+	for url := range remoteURLs {
+		if !localURLs[url] {
+			change.Added = append(change.Added, url)
+		}
+	}
+	for url := range localURLs {
+		if !remoteURLs[url] {
+			change.Removed = append(change.Removed, url)
+		}
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	// References with the same URLs but other sources or tags are listed in
+	// full
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		change.Local, change.Remote = stripHealth(local), stripHealth(remote)
+	}
+	return change, true
+}
+
+// stripHealth returns references without their locally attached health
+func stripHealth(refs []cve.Reference) []cve.Reference {
+	out := make([]cve.Reference, len(refs))
+	for i, ref := range refs {
+		ref.Health = nil
+		out[i] = ref
+	}
+	return out
+}
+
+// loadLocalCVE returns the locally stored copy of a CVE, or nil if it is not
+// stored
+func loadLocalCVE(ctx context.Context, client rpcInvoker, cveID string) (*cve.CVEItem, error) {
+	resp, err := client.InvokeRPC(ctx, "local", "RPCIsCVEStoredByID", &rpc.CVEIDParams{CVEID: cveID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check local storage: %w", err)
+	}
+	if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+		return nil, fmt.Errorf("failed to check local storage: %s", errMsg)
+	}
+	var check struct {
+		Stored bool `json:"stored"`
+	}
+	if err := subprocess.UnmarshalPayload(resp, &check); err != nil {
+		return nil, fmt.Errorf("failed to parse check response: %w", err)
+	}
+	if !check.Stored {
+		return nil, nil
+	}
+
+	resp, err = client.InvokeRPC(ctx, "local", "RPCGetCVEByID", &rpc.CVEIDParams{CVEID: cveID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CVE from local storage: %w", err)
+	}
+	if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+		return nil, fmt.Errorf("failed to get CVE from local storage: %s", errMsg)
+	}
+	var item cve.CVEItem
+	if err := subprocess.UnmarshalPayload(resp, &item); err != nil {
+		return nil, fmt.Errorf("failed to parse local CVE data: %w", err)
+	}
+	return &item, nil
+}
+
+// createDiffCVEHandler creates a handler for RPCDiffCVE, which compares the
+// locally stored copy of a CVE with the current remote one to tell whether
+// the local copy is stale. Nothing is saved.
+func createDiffCVEHandler(client rpcInvoker, loader *CVELoader, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCDiffCVE")
+
+		var req struct {
+			CVEID string `json:"cve_id"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		if req.CVEID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "cve_id is required"), nil
+		}
+
+		local, err := loadLocalCVE(ctx, client, req.CVEID)
+		if err != nil {
+			logger.Warn("RPCDiffCVE: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+		// Bypass the CVE cache of remote, which may hold a copy older than
+		// the change being checked for; the loader still coalesces this
+		// fetch with other fresh fetches of the same CVE
+		remote, err := loader.FetchRemoteFresh(ctx, req.CVEID)
+		if errors.Is(err, errCVENotFound) {
+			remote, err = nil, nil
+		}
+		if err != nil {
+			logger.Warn("RPCDiffCVE: failed to fetch CVE %s from remote: %v", req.CVEID, err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to fetch CVE from remote: %v", err)), nil
+		}
+		if local == nil && remote == nil {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("CVE %s not found", req.CVEID)), nil
+		}
+
+		diff := diffCVE(req.CVEID, local, remote)
+		logger.Info("RPCDiffCVE: CVE %s is %s, outdated=%t, %d changed fields", req.CVEID, diff.Presence, diff.Outdated, len(diff.Changes))
+		return subprocess.NewSuccessResponse(msg, diff)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// fakeDiffRPC serves the local and remote copies of CVEs
+type fakeDiffRPC struct {
+	local  map[string]cve.CVEItem
+	remote map[string]cve.CVEItem
+}
+
+func (f *fakeDiffRPC) InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	id := params.(*rpc.CVEIDParams).CVEID
+	switch {
+	case target == "local" && method == "RPCIsCVEStoredByID":
+		_, ok := f.local[id]
+		return subprocess.NewSuccessResponse(req, map[string]interface{}{"stored": ok, "cve_id": id})
+	case target == "local" && method == "RPCGetCVEByID":
+		return subprocess.NewSuccessResponse(req, f.local[id])
+	case target == "remote" && method == "RPCGetCVEByID":
+		var resp cve.CVEResponse
+		if item, ok := f.remote[id]; ok {
+			resp.Vulnerabilities = append(resp.Vulnerabilities, struct {
+				CVE cve.CVEItem `json:"cve"`
+			}{CVE: item})
+		}
+		return subprocess.NewSuccessResponse(req, resp)
+	}
+	return nil, errors.New("unexpected method " + target + "." + method)
+}
+
+func diffTestCVE(lastModified time.Time) cve.CVEItem {
+	return cve.CVEItem{
+		ID:           "CVE-2024-0001",
+		LastModified: cve.NewNVDTime(lastModified),
+		Descriptions: []cve.Description{{Lang: "en", Value: "A flaw"}},
+		Metrics: &cve.Metrics{CvssMetricV31: []cve.CVSSMetricV3{{
+			Source: "nvd@nist.gov", CvssData: cve.CVSSDataV3{BaseScore: 7.5},
+		}}},
+		References: []cve.Reference{{URL: "https://example.com/a", Tags: []string{"Patch"}}},
+	}
+}
+
+func TestDiffCVE(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDiffCVE", nil, func(t *testing.T, tx *gorm.DB) {
+		modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		local := diffTestCVE(modified)
+		remote := diffTestCVE(modified)
+		// Health is attached locally and not compared
+		local.References[0].Health = &cve.ReferenceHealth{Status: cve.ReferenceHealthOK}
+
+		d := diffCVE(local.ID, &local, &remote)
+		if d.Presence != CVEPresenceBoth || !d.Identical || d.Outdated || len(d.Changes) != 0 {
+			t.Fatalf("Expected identical copies, got %+v", d)
+		}
+
+		remote.LastModified = cve.NewNVDTime(modified.Add(time.Hour))
+		remote.Descriptions = append(remote.Descriptions, cve.Description{Lang: "es", Value: "Un fallo"})
+		remote.Metrics.CvssMetricV31[0].CvssData.BaseScore = 9.8
+		remote.References = append(remote.References, cve.Reference{URL: "https://example.com/b"})
+
+		d = diffCVE(local.ID, &local, &remote)
+		if !d.Outdated || d.Identical {
+			t.Fatalf("Expected an outdated local copy, got %+v", d)
+		}
+		fields := make([]string, len(d.Changes))
+		for i, c := range d.Changes {
+			fields[i] = c.Field
+		}
+		want := []string{DiffFieldLastModified, DiffFieldDescriptions, DiffFieldMetrics, DiffFieldReferences}
+		if len(fields) != len(want) {
+			t.Fatalf("Expected changes %v, got %v", want, fields)
+		}
+		for i := range want {
+			if fields[i] != want[i] {
+				t.Fatalf("Expected changes %v, got %v", want, fields)
+			}
+		}
+		if c := d.Changes[1]; c.Lang != "es" || c.Local != nil || c.Remote != "Un fallo" {
+			t.Errorf("Unexpected description change %+v", c)
+		}
+		if c := d.Changes[3]; len(c.Added) != 1 || c.Added[0] != "https://example.com/b" || len(c.Removed) != 0 {
+			t.Errorf("Unexpected reference change %+v", c)
+		}
+	})
+}
+
+func TestDiffCVEHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDiffCVEHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		f := &fakeDiffRPC{
+			local:  map[string]cve.CVEItem{"CVE-2024-0001": diffTestCVE(modified), "CVE-2024-0002": {ID: "CVE-2024-0002"}},
+			remote: map[string]cve.CVEItem{"CVE-2024-0001": diffTestCVE(modified.Add(time.Hour)), "CVE-2024-0003": {ID: "CVE-2024-0003"}},
+		}
+		l := newTestLoader(f, CVELoaderConfig{})
+		handler := createDiffCVEHandler(f, l, l.logger)
+
+		call := func(cveID string) (*subprocess.Message, CVEDiff) {
+			payload, _ := json.Marshal(map[string]string{"cve_id": cveID})
+			msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCDiffCVE", Payload: json.RawMessage(payload)}
+			resp, err := handler(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("Handler failed: %v", err)
+			}
+			var d CVEDiff
+			if isErr, _ := subprocess.IsErrorResponse(resp); !isErr {
+				if err := subprocess.UnmarshalPayload(resp, &d); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+			}
+			return resp, d
+		}
+
+		if _, d := call("CVE-2024-0001"); d.Presence != CVEPresenceBoth || !d.Outdated {
+			t.Errorf("Expected an outdated local copy, got %+v", d)
+		}
+		if _, d := call("CVE-2024-0002"); d.Presence != CVEPresenceLocalOnly || d.Outdated {
+			t.Errorf("Expected a local-only CVE, got %+v", d)
+		}
+		if _, d := call("CVE-2024-0003"); d.Presence != CVEPresenceRemoteOnly {
+			t.Errorf("Expected a remote-only CVE, got %+v", d)
+		}
+		if resp, _ := call("CVE-2024-0004"); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an error for a CVE on neither side, got %+v", resp)
+		}
+		if resp, _ := call(""); resp.Type != subprocess.MessageTypeError {
+			t.Errorf("Expected an error without cve_id, got %+v", resp)
+		}
+	})
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+)
+
+// Environment variables configuring the CVE loader
+const (
+	EnvCVELoaderConcurrency = "CVE_LOADER_CONCURRENCY"
+	EnvOffline              = "V2E_OFFLINE"
+)
+
+// DefaultCVELoaderConcurrency is the number of CVEs loaded at once when
+// CVE_LOADER_CONCURRENCY is unset
+const DefaultCVELoaderConcurrency = 4
+
+// MaxPrefetchIDs caps the CVE IDs of one RPCPrefetchCVEs call
+const MaxPrefetchIDs = 10000
+
+// maxFinishedPrefetches is how many finished prefetches keep their status
+const maxFinishedPrefetches = 32
+
+// Prefetch states
+const (
+	PrefetchStateRunning   = "running"
+	PrefetchStateCompleted = "completed"
+)
+
+var (
+	// ErrOffline is returned instead of fetching from remote in offline mode
+	ErrOffline = errors.New("offline mode: remote fetches are disabled")
+	// errCVENotFound is returned when remote has no such CVE
+	errCVENotFound = errors.New("CVE not found")
+)
+
+// rpcInvoker is the part of rpc.Client the loader uses
+type rpcInvoker interface {
+	InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error)
+}
+
+// CVELoaderConfig configures the CVE loader. Remote fetches are not rate
+// limited here: remote spaces every NVD request with its own limiter (see
+// NVD_RATE_LIMIT), which the loader's fetches go through.
+type CVELoaderConfig struct {
+	Concurrency int  `json:"concurrency"` // CVEs loaded at once
+	Offline     bool `json:"offline"`     // Never fetch from remote
+}
+
+// cveLoaderConfigFromEnv reads and validates the loader configuration
+func cveLoaderConfigFromEnv() (CVELoaderConfig, error) {
+	cfg := CVELoaderConfig{Concurrency: DefaultCVELoaderConcurrency}
+	if v := strings.TrimSpace(os.Getenv(EnvCVELoaderConcurrency)); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid %s %q: must be a positive integer", EnvCVELoaderConcurrency, v)
+		}
+		cfg.Concurrency = n
+	}
+	if v := strings.TrimSpace(os.Getenv(EnvOffline)); v != "" {
+		offline, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s %q: %v", EnvOffline, v, err)
+		}
+		cfg.Offline = offline
+	}
+	return cfg, nil
+}
+
+// flight is one in-progress load that later callers for the same key join
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// PrefetchStatus reports the progress of one prefetch
+type PrefetchStatus struct {
+	ID    string `json:"prefetch_id"`
+	State string `json:"state"`
+	Total int    `json:"total"`
+	Done  int    `json:"done"`
+	// Stored counts CVEs that were already in local storage
+	Stored int `json:"stored"`
+	// Fetched counts CVEs fetched from remote and cached
+	Fetched int `json:"fetched"`
+	// NotCached counts CVEs fetched but not cached under the cache policy
+	NotCached  int               `json:"not_cached"`
+	Failed     int               `json:"failed"`
+	Errors     map[string]string `json:"errors,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// prefetchOutcome is how one CVE of a prefetch was loaded
+type prefetchOutcome int
+
+const (
+	outcomeStored prefetchOutcome = iota
+	outcomeFetched
+	outcomeNotCached
+)
+
+// CVELoader is the one loader for CVEs fetched from remote, shared by
+// RPCGetCVE and RPCPrefetchCVEs. Loads of the same CVE in flight at the same
+// time are coalesced into one, at most Concurrency CVEs are loaded at once
+// and offline mode never reaches remote.
+type CVELoader struct {
+	rpc         rpcInvoker
+	logger      *common.Logger
+	cachePolicy *CVECachePolicy
+	timeline    timelineRecorder
+	config      CVELoaderConfig
+
+	sem chan struct{}
+
+	mu         sync.Mutex
+	flights    map[string]*flight
+	prefetches map[string]*PrefetchStatus
+	finished   []string // finished prefetch IDs, oldest first
+	seq        uint64
+}
+
+// NewCVELoader creates a CVE loader
+func NewCVELoader(client rpcInvoker, logger *common.Logger, cachePolicy *CVECachePolicy, timeline timelineRecorder, config CVELoaderConfig) *CVELoader {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultCVELoaderConcurrency
+	}
+	l := &CVELoader{
+		rpc:         client,
+		logger:      logger,
+		cachePolicy: cachePolicy,
+		timeline:    timeline,
+		config:      config,
+		sem:         make(chan struct{}, config.Concurrency),
+		flights:     make(map[string]*flight),
+		prefetches:  make(map[string]*PrefetchStatus),
+	}
+	return l
+}
+
+// do runs fn once for all callers asking for key at the same time. The
+// load is detached from the first caller's cancellation, since other callers
+// may be waiting on it; each caller stops waiting when its own ctx is done.
+func (l *CVELoader) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	l.mu.Lock()
+	f, ok := l.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		l.flights[key] = f
+		go func() {
+			f.value, f.err = fn(context.WithoutCancel(ctx))
+			l.mu.Lock()
+			delete(l.flights, key)
+			l.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FetchRemote fetches a CVE from remote, joining a fetch of the same CVE
+// already in flight. Remote may answer from its CVE cache.
+func (l *CVELoader) FetchRemote(ctx context.Context, cveID string) (*cve.CVEItem, error) {
+	return l.fetchRemoteShared(ctx, cveID, false)
+}
+
+// FetchRemoteFresh is FetchRemote bypassing the CVE cache of remote, for
+// callers that compare against the current NVD data. It only joins fetches
+// that are fresh too.
+func (l *CVELoader) FetchRemoteFresh(ctx context.Context, cveID string) (*cve.CVEItem, error) {
+	return l.fetchRemoteShared(ctx, cveID, true)
+}
+
+// fetchRemoteShared runs fetchRemote, joining a fetch of the same kind
+// already in flight
+func (l *CVELoader) fetchRemoteShared(ctx context.Context, cveID string, fresh bool) (*cve.CVEItem, error) {
+	if l.config.Offline {
+		return nil, ErrOffline
+	}
+	key := "fetch:" + cveID
+	if fresh {
+		key = "fetch-fresh:" + cveID
+	}
+	v, err := l.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return l.fetchRemote(ctx, cveID, fresh)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*cve.CVEItem), nil
+}
+
+// fetchRemote makes one remote fetch within the concurrency limit
+func (l *CVELoader) fetchRemote(ctx context.Context, cveID string, fresh bool) (*cve.CVEItem, error) {
+	select {
+	case l.sem <- struct{}{}:
+		defer func() { <-l.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	resp, err := l.rpc.InvokeRPC(ctx, "remote", "RPCGetCVEByID", &rpc.CVEIDParams{CVEID: cveID, Fresh: fresh})
+	if err != nil {
+		return nil, err
+	}
+	if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+		return nil, fmt.Errorf("%s", errMsg)
+	}
+	var result cve.CVEResponse
+	if err := subprocess.UnmarshalPayload(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse remote CVE response: %w", err)
+	}
+	if len(result.Vulnerabilities) == 0 {
+		return nil, errCVENotFound
+	}
+	return &result.Vulnerabilities[0].CVE, nil
+}
+
+// ensureLocal makes sure a CVE is in local storage, fetching it from remote
+// and caching it under the cache policy when it is not. Overlapping
+// prefetches of the same CVE share one load.
+func (l *CVELoader) ensureLocal(ctx context.Context, cveID string) (prefetchOutcome, error) {
+	v, err := l.do(ctx, "ensure:"+cveID, func(ctx context.Context) (interface{}, error) {
+		resp, err := l.rpc.InvokeRPC(ctx, "local", "RPCIsCVEStoredByID", &rpc.CVEIDParams{CVEID: cveID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check local storage: %w", err)
+		}
+		if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+			return nil, fmt.Errorf("failed to check local storage: %s", errMsg)
+		}
+		var check struct {
+			Stored bool `json:"stored"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &check); err != nil {
+			return nil, fmt.Errorf("failed to parse check response: %w", err)
+		}
+		if check.Stored {
+			return outcomeStored, nil
+		}
+
+		item, err := l.FetchRemote(ctx, cveID)
+		if err != nil {
+			return nil, err
+		}
+		if cache, reason := l.cachePolicy.ShouldCache(item); !cache {
+			l.logger.Debug("Prefetch: not caching CVE %s: %s", cveID, reason)
+			return outcomeNotCached, nil
+		}
+		saveResp, err := l.rpc.InvokeRPC(ctx, "local", "RPCSaveCVEByID", &rpc.SaveCVEByIDParams{CVE: *item})
+		if err == nil {
+			if isErr, errMsg := subprocess.IsErrorResponse(saveResp); isErr {
+				err = fmt.Errorf("%s", errMsg)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to save CVE: %w", err)
+		}
+		recordCVEChange(l.timeline, l.logger, cveID, "cached")
+		return outcomeFetched, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(prefetchOutcome), nil
+}
+
+// normalizeCVEIDs upper-cases, trims and deduplicates CVE IDs, keeping the
+// first occurrence's position
+func normalizeCVEIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// Prefetch starts loading the given CVEs into local storage in the
+// background and returns the new prefetch's status. The loads carry the
+// trace of ctx but are not cancelled with it.
+func (l *CVELoader) Prefetch(ctx context.Context, ids []string) PrefetchStatus {
+	l.mu.Lock()
+	l.seq++
+	status := &PrefetchStatus{
+		ID:        fmt.Sprintf("prefetch-%d-%d", time.Now().UnixNano(), l.seq),
+		State:     PrefetchStateRunning,
+		Total:     len(ids),
+		StartedAt: time.Now().UTC(),
+	}
+	l.prefetches[status.ID] = status
+	snapshot := status.snapshot()
+	l.mu.Unlock()
+
+	go l.runPrefetch(context.WithoutCancel(ctx), status, ids)
+	return snapshot
+}
+
+// runPrefetch loads the IDs with at most Concurrency workers
+func (l *CVELoader) runPrefetch(ctx context.Context, status *PrefetchStatus, ids []string) {
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < l.config.Concurrency && i < len(ids); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				outcome, err := l.ensureLocal(ctx, id)
+				l.recordOutcome(status, id, outcome, err)
+			}
+		}()
+	}
+	for _, id := range ids {
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	finished := time.Now().UTC()
+	status.State = PrefetchStateCompleted
+	status.FinishedAt = &finished
+	l.finished = append(l.finished, status.ID)
+	for len(l.finished) > maxFinishedPrefetches {
+		delete(l.prefetches, l.finished[0])
+		l.finished = l.finished[1:]
+	}
+	l.logger.Info(LogMsgPrefetchCompleted, status.ID, status.Total, status.Stored, status.Fetched, status.NotCached, status.Failed)
+}
+
+// recordOutcome counts one loaded CVE
+func (l *CVELoader) recordOutcome(status *PrefetchStatus, cveID string, outcome prefetchOutcome, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status.Done++
+	if err != nil {
+		status.Failed++
+		if status.Errors == nil {
+			status.Errors = make(map[string]string)
+		}
+		status.Errors[cveID] = err.Error()
+		return
+	}
+	switch outcome {
+	case outcomeStored:
+		status.Stored++
+	case outcomeFetched:
+		status.Fetched++
+	case outcomeNotCached:
+		status.NotCached++
+	}
+}
+
+// PrefetchStatus returns the status of a running or recently finished prefetch
+func (l *CVELoader) PrefetchStatus(id string) (PrefetchStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status, ok := l.prefetches[id]
+	if !ok {
+		return PrefetchStatus{}, false
+	}
+	return status.snapshot(), true
+}
+
+// ListPrefetches returns the statuses of running and recently finished
+// prefetches, newest first
+func (l *CVELoader) ListPrefetches() []PrefetchStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]PrefetchStatus, 0, len(l.prefetches))
+	for _, status := range l.prefetches {
+		out = append(out, status.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out
+}
+
+// snapshot copies the status; the caller holds the loader's lock
+func (s *PrefetchStatus) snapshot() PrefetchStatus {
+	c := *s
+	if s.Errors != nil {
+		c.Errors = make(map[string]string, len(s.Errors))
+		for k, v := range s.Errors {
+			c.Errors[k] = v
+		}
+	}
+	return c
+}
+
+// createPrefetchCVEsHandler creates a handler for RPCPrefetchCVEs, which
+// loads the given CVEs into local storage in the background
+func createPrefetchCVEsHandler(loader *CVELoader, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCPrefetchCVEs")
+
+		var req struct {
+			CVEIDs []string `json:"cve_ids"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		ids := normalizeCVEIDs(req.CVEIDs)
+		if len(ids) == 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "cve_ids is required"), nil
+		}
+		if len(ids) > MaxPrefetchIDs {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("too many cve_ids: %d, at most %d", len(ids), MaxPrefetchIDs)), nil
+		}
+
+		status := loader.Prefetch(ctx, ids)
+		logger.Info(LogMsgPrefetchStarted, status.ID, status.Total)
+		return subprocess.NewSuccessResponse(msg, status)
+	}
+}
+
+// createGetPrefetchStatusHandler creates a handler for RPCGetPrefetchStatus.
+// With a prefetch_id it returns that prefetch's progress, otherwise the
+// progress of all running and recently finished prefetches.
+func createGetPrefetchStatusHandler(loader *CVELoader, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCGetPrefetchStatus")
+
+		var req struct {
+			PrefetchID string `json:"prefetch_id"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.PrefetchID == "" {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"prefetches": loader.ListPrefetches(),
+			})
+		}
+		status, ok := loader.PrefetchStatus(req.PrefetchID)
+		if !ok {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("prefetch %s not found", req.PrefetchID)), nil
+		}
+		return subprocess.NewSuccessResponse(msg, status)
+	}
+}
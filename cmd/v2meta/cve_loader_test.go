@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// fakeLoaderRPC serves the local and remote RPCs the loader calls. Remote
+// fetches block until release is closed.
+type fakeLoaderRPC struct {
+	release chan struct{}
+
+	mu           sync.Mutex
+	stored       map[string]bool
+	remoteCalls  map[string]int
+	inFlight     int
+	maxInFlight  int
+	savedCVEIDs  []string
+	remoteMissed map[string]bool
+	freshCalls   int
+}
+
+func newFakeLoaderRPC(stored ...string) *fakeLoaderRPC {
+	f := &fakeLoaderRPC{
+		release:      make(chan struct{}),
+		stored:       make(map[string]bool),
+		remoteCalls:  make(map[string]int),
+		remoteMissed: make(map[string]bool),
+	}
+	for _, id := range stored {
+		f.stored[id] = true
+	}
+	return f
+}
+
+func (f *fakeLoaderRPC) InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	switch method {
+	case "RPCIsCVEStoredByID":
+		id := params.(*rpc.CVEIDParams).CVEID
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return subprocess.NewSuccessResponse(req, map[string]interface{}{"stored": f.stored[id], "cve_id": id})
+	case "RPCSaveCVEByID":
+		item := params.(*rpc.SaveCVEByIDParams).CVE
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.stored[item.ID] = true
+		f.savedCVEIDs = append(f.savedCVEIDs, item.ID)
+		return subprocess.NewSuccessResponse(req, map[string]interface{}{"success": true})
+	case "RPCGetCVEByID":
+		p := params.(*rpc.CVEIDParams)
+		id := p.CVEID
+		f.mu.Lock()
+		f.remoteCalls[id]++
+		if p.Fresh {
+			f.freshCalls++
+		}
+		f.inFlight++
+		if f.inFlight > f.maxInFlight {
+			f.maxInFlight = f.inFlight
+		}
+		missed := f.remoteMissed[id]
+		f.mu.Unlock()
+		defer func() {
+			f.mu.Lock()
+			f.inFlight--
+			f.mu.Unlock()
+		}()
+
+		<-f.release
+		var resp cve.CVEResponse
+		if !missed {
+			resp.Vulnerabilities = append(resp.Vulnerabilities, struct {
+				CVE cve.CVEItem `json:"cve"`
+			}{CVE: cve.CVEItem{ID: id}})
+		}
+		return subprocess.NewSuccessResponse(req, resp)
+	}
+	return nil, errors.New("unexpected method " + method)
+}
+
+func (f *fakeLoaderRPC) calls(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.remoteCalls[id]
+}
+
+type nopTimeline struct{}
+
+func (nopTimeline) RecordEvent(string, interface{}) error { return nil }
+
+func newTestLoader(client rpcInvoker, config CVELoaderConfig) *CVELoader {
+	logger := common.NewLogger(io.Discard, "", common.ErrorLevel)
+	return NewCVELoader(client, logger, &CVECachePolicy{Mode: CachePolicyAlways}, nopTimeline{}, config)
+}
+
+// waitPrefetch polls until the prefetch completes
+func waitPrefetch(t *testing.T, l *CVELoader, id string) PrefetchStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, ok := l.PrefetchStatus(id)
+		if !ok {
+			t.Fatalf("Prefetch %s not found", id)
+		}
+		if status.State == PrefetchStateCompleted {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Prefetch %s did not complete", id)
+	return PrefetchStatus{}
+}
+
+func TestCVELoader_CoalescesRemoteFetches(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVELoader_CoalescesRemoteFetches", nil, func(t *testing.T, tx *gorm.DB) {
+		f := newFakeLoaderRPC()
+		l := newTestLoader(f, CVELoaderConfig{Concurrency: 2})
+
+		var wg sync.WaitGroup
+		results := make([]*cve.CVEItem, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				item, err := l.FetchRemote(context.Background(), "CVE-2024-0001")
+				if err != nil {
+					t.Errorf("FetchRemote failed: %v", err)
+				}
+				results[i] = item
+			}(i)
+		}
+		// Let every caller join before the fetch returns
+		for f.calls("CVE-2024-0001") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(f.release)
+		wg.Wait()
+
+		if n := f.calls("CVE-2024-0001"); n != 1 {
+			t.Errorf("Expected one remote fetch, got %d", n)
+		}
+		for i, item := range results {
+			if item == nil || item.ID != "CVE-2024-0001" {
+				t.Errorf("Caller %d got %+v", i, item)
+			}
+		}
+
+		// A finished fetch is not reused: the next one reaches remote again
+		if _, err := l.FetchRemote(context.Background(), "CVE-2024-0001"); err != nil {
+			t.Fatalf("FetchRemote failed: %v", err)
+		}
+		if n := f.calls("CVE-2024-0001"); n != 2 {
+			t.Errorf("Expected a second remote fetch, got %d", n)
+		}
+
+		f.remoteMissed["CVE-2024-9999"] = true
+		if _, err := l.FetchRemote(context.Background(), "CVE-2024-9999"); !errors.Is(err, errCVENotFound) {
+			t.Errorf("Expected errCVENotFound, got %v", err)
+		}
+
+		// Only fresh fetches ask remote to bypass its cache
+		if _, err := l.FetchRemoteFresh(context.Background(), "CVE-2024-0001"); err != nil {
+			t.Fatalf("FetchRemoteFresh failed: %v", err)
+		}
+		f.mu.Lock()
+		fresh := f.freshCalls
+		f.mu.Unlock()
+		if fresh != 1 {
+			t.Errorf("Expected one fresh remote fetch, got %d", fresh)
+		}
+	})
+}
+
+func TestCVELoader_OverlappingPrefetches(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVELoader_OverlappingPrefetches", nil, func(t *testing.T, tx *gorm.DB) {
+		f := newFakeLoaderRPC("CVE-2024-0001")
+		f.remoteMissed["CVE-2024-0004"] = true
+		l := newTestLoader(f, CVELoaderConfig{Concurrency: 2})
+
+		ids := normalizeCVEIDs([]string{"CVE-2024-0001", " cve-2024-0002", "CVE-2024-0002", "CVE-2024-0003", "CVE-2024-0004", ""})
+		if len(ids) != 4 || ids[1] != "CVE-2024-0002" {
+			t.Fatalf("Expected four normalized IDs, got %v", ids)
+		}
+
+		first := l.Prefetch(context.Background(), ids)
+		second := l.Prefetch(context.Background(), ids[1:3])
+		if first.ID == second.ID || first.State != PrefetchStateRunning || first.Total != 4 {
+			t.Fatalf("Unexpected prefetch statuses %+v and %+v", first, second)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(f.release)
+
+		done := waitPrefetch(t, l, first.ID)
+		if done.Done != 4 || done.Stored != 1 || done.Fetched != 2 || done.Failed != 1 || done.FinishedAt == nil {
+			t.Errorf("Unexpected first prefetch status %+v", done)
+		}
+		if _, ok := done.Errors["CVE-2024-0004"]; !ok {
+			t.Errorf("Expected an error for CVE-2024-0004, got %v", done.Errors)
+		}
+		other := waitPrefetch(t, l, second.ID)
+		if other.Done != 2 || other.Failed != 0 || other.Stored+other.Fetched != 2 {
+			t.Errorf("Unexpected second prefetch status %+v", other)
+		}
+
+		for _, id := range []string{"CVE-2024-0002", "CVE-2024-0003"} {
+			if n := f.calls(id); n != 1 {
+				t.Errorf("Expected one remote fetch of %s, got %d", id, n)
+			}
+		}
+		if n := f.calls("CVE-2024-0001"); n != 0 {
+			t.Errorf("Expected no remote fetch of a stored CVE, got %d", n)
+		}
+		if len(f.savedCVEIDs) != 2 {
+			t.Errorf("Expected two CVEs saved, got %v", f.savedCVEIDs)
+		}
+		if f.maxInFlight > 2 {
+			t.Errorf("Expected at most 2 remote fetches at once, got %d", f.maxInFlight)
+		}
+		if list := l.ListPrefetches(); len(list) != 2 {
+			t.Errorf("Expected two listed prefetches, got %d", len(list))
+		}
+	})
+}
+
+func TestCVELoader_Offline(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVELoader_Offline", nil, func(t *testing.T, tx *gorm.DB) {
+		f := newFakeLoaderRPC("CVE-2024-0001")
+		close(f.release)
+
+		offline := newTestLoader(f, CVELoaderConfig{Offline: true})
+		if _, err := offline.FetchRemote(context.Background(), "CVE-2024-0009"); !errors.Is(err, ErrOffline) {
+			t.Errorf("Expected ErrOffline, got %v", err)
+		}
+		status := waitPrefetch(t, offline, offline.Prefetch(context.Background(), []string{"CVE-2024-0001", "CVE-2024-0009"}).ID)
+		if status.Stored != 1 || status.Failed != 1 {
+			t.Errorf("Expected stored CVEs to still count offline, got %+v", status)
+		}
+		if n := f.calls("CVE-2024-0009"); n != 0 {
+			t.Errorf("Expected no remote fetch offline, got %d", n)
+		}
+	})
+}
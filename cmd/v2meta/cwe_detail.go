@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cwe"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// Relations RPCGetCWEDetail can include alongside a CWE
+const (
+	CWEDetailIncludeCAPEC  = "capec"
+	CWEDetailIncludeAttack = "attack"
+)
+
+const (
+	// MaxCWEDetailCAPECs caps the CAPECs loaded for one CWE detail
+	MaxCWEDetailCAPECs = 25
+	// MaxCWEDetailTechniques caps the ATT&CK techniques loaded for one CWE
+	// detail
+	MaxCWEDetailTechniques = 50
+	// cweDetailFanout is the number of lookups in flight at once
+	cweDetailFanout = 8
+)
+
+// CWEDetailMissing is a related record that could not be loaded
+type CWEDetailMissing struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// CWEDetailTechnique is an ATT&CK technique reached through the CAPECs of a
+// CWE. Technique is unset when the technique could not be loaded.
+type CWEDetailTechnique struct {
+	TechniqueID string                 `json:"technique_id"`
+	Name        string                 `json:"name,omitempty"`
+	CAPECIDs    []string               `json:"capec_ids"`
+	Technique   map[string]interface{} `json:"technique,omitempty"`
+}
+
+// CWEDetail is a CWE with the relations requested by include
+type CWEDetail struct {
+	CWE              *cwe.CWEItem             `json:"cwe"`
+	CAPECs           []map[string]interface{} `json:"capecs,omitempty"`
+	AttackTechniques []CWEDetailTechnique     `json:"attack_techniques,omitempty"`
+	// Truncated holds the number of related records left out by the caps,
+	// keyed by "capecs" or "attack_techniques"
+	Truncated map[string]int     `json:"truncated,omitempty"`
+	Missing   []CWEDetailMissing `json:"missing,omitempty"`
+}
+
+// invokeLocal calls a local RPC and decodes its result into v
+func invokeLocal(ctx context.Context, client rpcInvoker, method string, params, v interface{}) error {
+	resp, err := client.InvokeRPC(ctx, "local", method, params)
+	if err != nil {
+		return err
+	}
+	if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+		return fmt.Errorf("%s", errMsg)
+	}
+	if err := subprocess.UnmarshalPayload(resp, v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	return nil
+}
+
+// fanOut calls fn for 0..n-1 with at most cweDetailFanout calls at once
+func fanOut(n int, fn func(i int)) {
+	sem := make(chan struct{}, cweDetailFanout)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// capecNumber returns the number of a CAPEC ID given as "CAPEC-66" or "66"
+func capecNumber(id string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(id)), "CAPEC-"))
+	return n, err == nil && n > 0
+}
+
+// loadCWEDetail loads a CWE and fans out to its related CAPECs and,
+// through them, ATT&CK techniques. Only a missing CWE is an error; a
+// relation that fails to load is recorded in Missing.
+func loadCWEDetail(ctx context.Context, client rpcInvoker, cweID string, withCAPEC, withAttack bool) (*CWEDetail, error) {
+	var item cwe.CWEItem
+	if err := invokeLocal(ctx, client, "RPCGetCWEByID", map[string]string{"cwe_id": cweID}, &item); err != nil {
+		return nil, fmt.Errorf("failed to get CWE-%s: %w", cweID, err)
+	}
+	detail := &CWEDetail{CWE: &item, Truncated: map[string]int{}}
+	if !withCAPEC && !withAttack {
+		return detail, nil
+	}
+
+	// Mappings recorded by CAPEC and by the CWE itself
+	seen := make(map[int]bool)
+	var mapping struct {
+		CAPECIDs []string `json:"capec_ids"`
+	}
+	if err := invokeLocal(ctx, client, "RPCGetCAPECsByCWE", map[string]string{"cwe_id": cweID}, &mapping); err != nil {
+		detail.Missing = append(detail.Missing, CWEDetailMissing{Kind: "capec_mapping", ID: "CWE-" + cweID, Error: err.Error()})
+	}
+	for _, id := range append(mapping.CAPECIDs, item.RelatedAttackPatterns...) {
+		if n, ok := capecNumber(id); ok {
+			seen[n] = true
+		}
+	}
+	nums := make([]int, 0, len(seen))
+	for n := range seen {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	if len(nums) > MaxCWEDetailCAPECs {
+		detail.Truncated["capecs"] = len(nums) - MaxCWEDetailCAPECs
+		nums = nums[:MaxCWEDetailCAPECs]
+	}
+
+	capecs := make([]map[string]interface{}, len(nums))
+	errs := make([]error, len(nums))
+	fanOut(len(nums), func(i int) {
+		errs[i] = invokeLocal(ctx, client, "RPCGetCAPECByID", map[string]string{"capec_id": strconv.Itoa(nums[i])}, &capecs[i])
+	})
+	loaded := make([]map[string]interface{}, 0, len(nums))
+	for i, n := range nums {
+		if errs[i] != nil {
+			detail.Missing = append(detail.Missing, CWEDetailMissing{Kind: "capec", ID: fmt.Sprintf("CAPEC-%d", n), Error: errs[i].Error()})
+			continue
+		}
+		loaded = append(loaded, capecs[i])
+	}
+	if withCAPEC {
+		detail.CAPECs = loaded
+	}
+	if withAttack {
+		detail.AttackTechniques = loadCWEDetailTechniques(ctx, client, loaded, detail)
+	}
+	if len(detail.Truncated) == 0 {
+		detail.Truncated = nil
+	}
+	return detail, nil
+}
+
+// loadCWEDetailTechniques loads the ATT&CK techniques the given CAPECs map
+// to, in technique ID order
+func loadCWEDetailTechniques(ctx context.Context, client rpcInvoker, capecs []map[string]interface{}, detail *CWEDetail) []CWEDetailTechnique {
+	byID := make(map[string]*CWEDetailTechnique)
+	for _, c := range capecs {
+		capecID, _ := c["id"].(string)
+		refs, _ := c["attack_techniques"].([]interface{})
+		for _, ref := range refs {
+			m, _ := ref.(map[string]interface{})
+			id, _ := m["technique_id"].(string)
+			if id == "" {
+				continue
+			}
+			t, ok := byID[id]
+			if !ok {
+				name, _ := m["name"].(string)
+				t = &CWEDetailTechnique{TechniqueID: id, Name: name}
+				byID[id] = t
+			}
+			t.CAPECIDs = append(t.CAPECIDs, capecID)
+		}
+	}
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) > MaxCWEDetailTechniques {
+		detail.Truncated["attack_techniques"] = len(ids) - MaxCWEDetailTechniques
+		ids = ids[:MaxCWEDetailTechniques]
+	}
+
+	techniques := make([]CWEDetailTechnique, len(ids))
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		techniques[i] = *byID[id]
+	}
+	fanOut(len(ids), func(i int) {
+		errs[i] = invokeLocal(ctx, client, "RPCGetAttackTechniqueByID", map[string]string{"id": ids[i]}, &techniques[i].Technique)
+	})
+	for i, id := range ids {
+		if errs[i] != nil {
+			techniques[i].Technique = nil
+			detail.Missing = append(detail.Missing, CWEDetailMissing{Kind: "attack_technique", ID: id, Error: errs[i].Error()})
+		}
+	}
+	return techniques
+}
+
+// createGetCWEDetailHandler creates a handler for RPCGetCWEDetail, which
+// returns a CWE with its related CAPECs and ATT&CK techniques as selected by
+// include
+func createGetCWEDetailHandler(client rpcInvoker, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCGetCWEDetail")
+
+		var req struct {
+			CWEID   string   `json:"cwe_id"`
+			Include []string `json:"include"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		if req.CWEID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "cwe_id is required"), nil
+		}
+		// Local stores CWEs by number
+		cweID := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(req.CWEID)), "CWE-")
+		if _, err := strconv.ParseUint(cweID, 10, 32); err != nil {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("invalid cwe_id %q", req.CWEID)), nil
+		}
+		var withCAPEC, withAttack bool
+		for _, inc := range req.Include {
+			switch inc {
+			case CWEDetailIncludeCAPEC:
+				withCAPEC = true
+			case CWEDetailIncludeAttack:
+				withAttack = true
+			default:
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("unknown include %q: must be %s or %s", inc, CWEDetailIncludeCAPEC, CWEDetailIncludeAttack)), nil
+			}
+		}
+
+		detail, err := loadCWEDetail(ctx, client, cweID, withCAPEC, withAttack)
+		if err != nil {
+			logger.Warn("RPCGetCWEDetail: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+		logger.Info("RPCGetCWEDetail: CWE-%s with %d CAPECs, %d techniques, %d missing", cweID, len(detail.CAPECs), len(detail.AttackTechniques), len(detail.Missing))
+		return subprocess.NewSuccessResponse(msg, detail)
+	}
+}
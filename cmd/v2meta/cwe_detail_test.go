@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cwe"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// fakeCWEDetailRPC serves CWEs, CAPECs and ATT&CK techniques from maps; a
+// record not in a map is not found
+type fakeCWEDetailRPC struct {
+	cwes       map[string]cwe.CWEItem
+	capecsOf   map[string][]string
+	capecs     map[string]map[string]interface{}
+	techniques map[string]map[string]interface{}
+}
+
+func (f *fakeCWEDetailRPC) InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	p := params.(map[string]string)
+	var (
+		v  interface{}
+		ok bool
+	)
+	switch method {
+	case "RPCGetCWEByID":
+		v, ok = f.cwes[p["cwe_id"]]
+	case "RPCGetCAPECsByCWE":
+		v, ok = map[string]interface{}{"capec_ids": f.capecsOf[p["cwe_id"]]}, f.capecsOf != nil
+	case "RPCGetCAPECByID":
+		v, ok = f.capecs[p["capec_id"]]
+	case "RPCGetAttackTechniqueByID":
+		v, ok = f.techniques[p["id"]]
+	default:
+		return nil, errors.New("unexpected method " + target + "." + method)
+	}
+	if !ok {
+		return subprocess.NewErrorResponse(req, "not found"), nil
+	}
+	return subprocess.NewSuccessResponse(req, v)
+}
+
+func newFakeCWEDetailRPC() *fakeCWEDetailRPC {
+	capec := func(n int, techniques ...string) map[string]interface{} {
+		refs := make([]interface{}, len(techniques))
+		for i, t := range techniques {
+			refs[i] = map[string]interface{}{"technique_id": t, "name": "Technique " + t}
+		}
+		return map[string]interface{}{"id": fmt.Sprintf("CAPEC-%d", n), "attack_techniques": refs}
+	}
+	return &fakeCWEDetailRPC{
+		cwes: map[string]cwe.CWEItem{
+			"79": {ID: "79", Name: "XSS", RelatedAttackPatterns: []string{"63", "209"}},
+		},
+		capecsOf: map[string][]string{"79": {"CAPEC-63", "CAPEC-588"}},
+		capecs: map[string]map[string]interface{}{
+			"63":  capec(63, "T1059", "T1185"),
+			"588": capec(588, "T1059"),
+		},
+		techniques: map[string]map[string]interface{}{
+			"T1059": {"id": "T1059", "name": "Command and Scripting Interpreter"},
+		},
+	}
+}
+
+func callCWEDetail(t *testing.T, client rpcInvoker, req map[string]interface{}) (*subprocess.Message, CWEDetail) {
+	t.Helper()
+	payload, _ := json.Marshal(req)
+	msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCWEDetail", Payload: json.RawMessage(payload)}
+	resp, err := createGetCWEDetailHandler(client, common.NewLogger(io.Discard, "", common.ErrorLevel))(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	var d CWEDetail
+	if isErr, _ := subprocess.IsErrorResponse(resp); !isErr {
+		if err := subprocess.UnmarshalPayload(resp, &d); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+	}
+	return resp, d
+}
+
+func TestGetCWEDetailHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetCWEDetailHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		f := newFakeCWEDetailRPC()
+
+		_, d := callCWEDetail(t, f, map[string]interface{}{"cwe_id": "CWE-79", "include": []string{"capec", "attack"}})
+		if d.CWE == nil || d.CWE.Name != "XSS" {
+			t.Fatalf("Expected CWE-79, got %+v", d.CWE)
+		}
+		if len(d.CAPECs) != 2 || d.CAPECs[0]["id"] != "CAPEC-63" || d.CAPECs[1]["id"] != "CAPEC-588" {
+			t.Errorf("Expected CAPEC-63 and CAPEC-588, got %v", d.CAPECs)
+		}
+		if len(d.AttackTechniques) != 2 {
+			t.Fatalf("Expected 2 techniques, got %+v", d.AttackTechniques)
+		}
+		if tech := d.AttackTechniques[0]; tech.TechniqueID != "T1059" || len(tech.CAPECIDs) != 2 || tech.Technique == nil {
+			t.Errorf("Unexpected technique %+v", tech)
+		}
+		if tech := d.AttackTechniques[1]; tech.TechniqueID != "T1185" || tech.Name != "Technique T1185" || tech.Technique != nil {
+			t.Errorf("Expected T1185 from its CAPEC reference only, got %+v", tech)
+		}
+		// CAPEC-209 and T1185 are not stored
+		if len(d.Missing) != 2 || d.Missing[0].ID != "CAPEC-209" || d.Missing[1].ID != "T1185" {
+			t.Errorf("Unexpected missing %+v", d.Missing)
+		}
+
+		_, d = callCWEDetail(t, f, map[string]interface{}{"cwe_id": "79"})
+		if d.CWE == nil || d.CAPECs != nil || d.AttackTechniques != nil || d.Missing != nil {
+			t.Errorf("Expected the CWE alone without include, got %+v", d)
+		}
+
+		_, d = callCWEDetail(t, f, map[string]interface{}{"cwe_id": "79", "include": []string{"attack"}})
+		if d.CAPECs != nil || len(d.AttackTechniques) != 2 {
+			t.Errorf("Expected techniques without CAPECs, got %+v", d)
+		}
+
+		for _, req := range []map[string]interface{}{
+			{},
+			{"cwe_id": "XSS"},
+			{"cwe_id": "79", "include": []string{"cve"}},
+			{"cwe_id": "80"},
+		} {
+			if resp, _ := callCWEDetail(t, f, req); resp.Type != subprocess.MessageTypeError {
+				t.Errorf("Expected an error for %v, got %+v", req, resp)
+			}
+		}
+	})
+}
+
+func TestGetCWEDetailHandler_Caps(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetCWEDetailHandler_Caps", nil, func(t *testing.T, tx *gorm.DB) {
+		f := newFakeCWEDetailRPC()
+		// The mapping lookup fails; the CWE's own patterns still count
+		f.capecsOf = nil
+		item := f.cwes["79"]
+		item.RelatedAttackPatterns = nil
+		f.capecs = map[string]map[string]interface{}{}
+		for n := 1; n <= MaxCWEDetailCAPECs+5; n++ {
+			item.RelatedAttackPatterns = append(item.RelatedAttackPatterns, fmt.Sprintf("CAPEC-%d", n))
+			refs := make([]interface{}, 0, 3)
+			for j := 0; j < 3; j++ {
+				refs = append(refs, map[string]interface{}{"technique_id": fmt.Sprintf("T%d%d", n, j)})
+			}
+			f.capecs[fmt.Sprint(n)] = map[string]interface{}{"id": fmt.Sprintf("CAPEC-%d", n), "attack_techniques": refs}
+		}
+		f.cwes["79"] = item
+
+		_, d := callCWEDetail(t, f, map[string]interface{}{"cwe_id": "79", "include": []string{"capec", "attack"}})
+		if len(d.CAPECs) != MaxCWEDetailCAPECs || d.Truncated["capecs"] != 5 {
+			t.Errorf("Expected %d CAPECs and 5 truncated, got %d and %v", MaxCWEDetailCAPECs, len(d.CAPECs), d.Truncated)
+		}
+		if len(d.AttackTechniques) != MaxCWEDetailTechniques || d.Truncated["attack_techniques"] != 3*MaxCWEDetailCAPECs-MaxCWEDetailTechniques {
+			t.Errorf("Expected %d techniques, got %d and %v", MaxCWEDetailTechniques, len(d.AttackTechniques), d.Truncated)
+		}
+		if len(d.Missing) == 0 || d.Missing[0].Kind != "capec_mapping" {
+			t.Errorf("Expected the failed mapping lookup reported, got %+v", d.Missing)
+		}
+	})
+}
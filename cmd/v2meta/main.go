@@ -17,18 +17,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/cyw0ng95/v2e/cmd/v2meta/providers"
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
 	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
 	cwejob "github.com/cyw0ng95/v2e/pkg/cwe/job"
+	"github.com/cyw0ng95/v2e/pkg/meta/fsm"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 	"github.com/cyw0ng95/v2e/pkg/rpc"
 	ssgjob "github.com/cyw0ng95/v2e/pkg/ssg/job"
+	"github.com/cyw0ng95/v2e/pkg/urn"
 )
 
 // Default constants are now in pkg/common/defaults.go
@@ -42,41 +46,58 @@ const (
 	DataTypeCAPEC  = taskflow.DataTypeCAPEC
 	DataTypeATTACK = taskflow.DataTypeATTACK
 	DataTypeCCE    = taskflow.DataTypeCCE
+	DataTypeKEV    = taskflow.DataTypeKEV
 )
 
 // Alias the DataType from the taskflow package so local code can use it directly
 type DataType = taskflow.DataType
 
+// importFunc starts the import of a data type
+type importFunc func(ctx context.Context, sessionID string, params map[string]interface{}) (string, error)
+
 // DataPopulationController manages data population for different data types
 type DataPopulationController struct {
-	rpcClient *rpc.Client
-	logger    *common.Logger
+	rpcClient   *rpc.Client
+	jobExecutor *taskflow.JobExecutor
+	logger      *common.Logger
+	importers   map[DataType]importFunc
 }
 
-// NewDataPopulationController creates a new controller for data population
-func NewDataPopulationController(rpcClient *rpc.Client, logger *common.Logger) *DataPopulationController {
-	return &DataPopulationController{
-		rpcClient: rpcClient,
-		logger:    logger,
+// NewDataPopulationController creates a new controller for data population.
+// Imports take a slot of their data type from jobExecutor, so they count
+// against its concurrency limits (see RPCUpdateConcurrency); a nil
+// jobExecutor leaves them unlimited.
+func NewDataPopulationController(rpcClient *rpc.Client, jobExecutor *taskflow.JobExecutor, logger *common.Logger) *DataPopulationController {
+	c := &DataPopulationController{
+		rpcClient:   rpcClient,
+		jobExecutor: jobExecutor,
+		logger:      logger,
+	}
+	c.importers = map[DataType]importFunc{
+		DataTypeCWE:    c.startCWEImport,
+		DataTypeCAPEC:  c.startCAPECImport,
+		DataTypeATTACK: c.startATTACKImport,
+		DataTypeCCE:    c.startCCEImport,
 	}
+	return c
 }
 
 // StartDataPopulation starts a data population job for a specific data type
 func (c *DataPopulationController) StartDataPopulation(ctx context.Context, dataType DataType, params map[string]interface{}) (string, error) {
 	sessionID := fmt.Sprintf("%s-%d", dataType, time.Now().Unix())
 
-	switch dataType {
-	case DataTypeCWE:
-		return c.startCWEImport(ctx, sessionID, params)
-	case DataTypeCAPEC:
-		return c.startCAPECImport(ctx, sessionID, params)
-	case DataTypeATTACK:
-		return c.startATTACKImport(ctx, sessionID, params)
-	case DataTypeCCE:
-		return c.startCCEImport(ctx, sessionID, params)
-	default:
+	start, ok := c.importers[dataType]
+	if !ok {
 		return "", fmt.Errorf("unsupported data type: %s", dataType)
 	}
+	if c.jobExecutor != nil {
+		release, err := c.jobExecutor.AcquireSlot(ctx, dataType)
+		if err != nil {
+			return "", fmt.Errorf("failed to wait for a %s import slot: %w", dataType, err)
+		}
+		defer release()
+	}
+	return start(ctx, sessionID, params)
 }
 
 // startCWEImport starts a CWE import job
@@ -115,14 +136,19 @@ func (c *DataPopulationController) startCAPECImport(ctx context.Context, session
 		path = "assets/capec_contents_latest.xml" // default path
 	}
 
+	xsd, ok := params["xsd"].(string)
+	if !ok {
+		xsd = "assets/capec_schema_latest.xsd" // default schema
+	}
+
 	force, _ := params["force"].(bool)
 
-	c.logger.Info("Starting CAPEC import: session_id=%s, path=%s, force=%t", sessionID, path, force)
+	c.logger.Info("Starting CAPEC import: session_id=%s, path=%s, xsd=%s, force=%t", sessionID, path, xsd, force)
 
 	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
 
-	paramsObj := &rpc.ImportParams{Path: path, Force: force}
+	paramsObj := &rpc.ImportParams{Path: path, XSD: xsd, Force: force}
 	c.logger.Debug("About to invoke RPCImportCAPECs on local service")
 	resp, err := c.rpcClient.InvokeRPC(ctx, "local", "RPCImportCAPECs", paramsObj)
 	if err != nil {
@@ -213,14 +239,19 @@ func (a *RPCClientAdapter) InvokeRPC(ctx context.Context, target, method string,
 // This ensures consistency when the service restarts.
 //
 // Recovery logic:
-// - "running" runs: Auto-resume (service crashed or was restarted while job was running)
+// - "running" runs: Auto-resume from the last checkpoint (service crashed or was restarted while job was running)
 // - "paused" runs: Keep paused (user explicitly paused, don't auto-resume)
 // - Terminal states: No action needed
-func recoverRuns(jobExecutor *taskflow.JobExecutor, logger *common.Logger) {
+//
+// CWE view jobs follow the same logic from their checkpoints.
+func recoverRuns(jobExecutor *taskflow.JobExecutor, cweJobController *cwejob.Controller, logger *common.Logger) {
 	if err := jobExecutor.RecoverRuns(context.Background()); err != nil {
 		logger.Warn("Failed to recover runs: %v", err)
 		logger.Debug("Run recovery failed: %v", err)
 	}
+	if _, err := cweJobController.Recover(context.Background()); err != nil {
+		logger.Warn("Failed to recover CWE view job: %v", err)
+	}
 }
 
 func main() {
@@ -232,6 +263,20 @@ func main() {
 	}
 	sp, logger := subprocess.StandardStartup(configStruct)
 
+	// Validate the CVE cache policy before serving requests
+	cachePolicy, err := cachePolicyFromEnv()
+	if err != nil {
+		logger.Error(LogMsgInvalidCachePolicy, err)
+		os.Exit(1)
+	}
+	logger.Info(LogMsgCachePolicyConfigured, cachePolicy.Mode, cachePolicy.MinCVSS, cachePolicy.Years, cachePolicy.Vendors, cachePolicy.DenyVendors)
+	loaderConfig, err := cveLoaderConfigFromEnv()
+	if err != nil {
+		logger.Error(LogMsgInvalidCVELoaderConfig, err)
+		os.Exit(1)
+	}
+	logger.Info(LogMsgCVELoaderConfigured, loaderConfig.Concurrency, loaderConfig.Offline)
+
 	// Get run database path from environment or use default
 	runDBPath := os.Getenv("SESSION_DB_PATH")
 	if runDBPath == "" {
@@ -267,15 +312,28 @@ func main() {
 	logger.Info(LogMsgRPCClientCreated)
 	rpcClient := rpc.NewClient(sp, logger, common.DefaultRPCTimeout)
 	rpcAdapter := &RPCClientAdapter{client: rpcClient}
+	cveLoader := NewCVELoader(rpcClient, logger, cachePolicy, runStore, loaderConfig)
 	logger.Info(LogMsgRPCAdapterCreated)
 
-	// Create job executor with Taskflow (100 concurrent goroutines)
+	// Create job executor with Taskflow (100 concurrent goroutines; CVE runs
+	// save at most 80 CVEs at once when they fall back to one by one)
 	logger.Info(LogMsgJobExecutorCreated, 100)
-	jobExecutor := taskflow.NewJobExecutor(rpcAdapter, runStore, logger, 100)
+	jobExecutor := taskflow.NewJobExecutor(rpcAdapter, runStore, logger, 100, map[taskflow.DataType]int{
+		taskflow.DataTypeCVE: 80,
+	})
+	// Batches only run inside the shared maintenance window; an unset or
+	// invalid V2E_MAINTENANCE_WINDOW leaves it always open
+	window, err := maintenance.FromEnv()
+	if err != nil {
+		logger.Warn("Invalid %s, background work is always allowed: %v", maintenance.EnvVar, err)
+		window, _ = maintenance.Parse("")
+	}
+	jobExecutor.SetMaintenanceWindow(window)
 
 	// Create CWE job controller (separate controller for view jobs)
 	logger.Info(LogMsgCWEJobControllerCreated)
 	cweJobController := cwejob.NewController(rpcAdapter, logger)
+	cweJobController.SetCheckpointStore(runStore)
 
 	// Create SSG import job orchestrator
 	logger.Info("SSG import job orchestrator created")
@@ -283,34 +341,62 @@ func main() {
 
 	// Create data population controller for all data types
 	logger.Info(LogMsgDataPopControllerCreated)
-	dataPopController := NewDataPopulationController(rpcClient, logger)
+	dataPopController := NewDataPopulationController(rpcClient, jobExecutor, logger)
 
 	// Recover runs if needed after restart
 	// This ensures job consistency when the service restarts
 	logger.Info(LogMsgRunRecoveryStarted)
-	recoverRuns(jobExecutor, logger)
+	recoverRuns(jobExecutor, cweJobController, logger)
 	logger.Info(LogMsgRunRecoveryCompleted)
 
+	// Checkpoint running jobs on SIGTERM so the restart resumes them from
+	// their last stored batch instead of their first, and let completion
+	// callbacks in flight finish so their outcome is recorded
+	sp.OnShutdown(func() {
+		if err := jobExecutor.CheckpointAll(); err != nil {
+			logger.Warn("Failed to checkpoint runs: %v", err)
+		}
+		jobExecutor.WaitCallbacks()
+	})
+
 	// Register RPC handlers for CRUD operations
 	logger.Info("Registering RPC handlers...")
-	sp.RegisterHandler("RPCGetCVE", createGetCVEHandler(rpcClient, logger))
+	sp.RegisterHandler("RPCGetCVE", createGetCVEHandler(rpcClient, cveLoader, logger, cachePolicy, runStore))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVE")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetCVE")
-	sp.RegisterHandler("RPCCreateCVE", createCreateCVEHandler(rpcClient, logger))
+	sp.RegisterHandler("RPCCreateCVE", createCreateCVEHandler(rpcClient, logger, runStore))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCCreateCVE")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCCreateCVE")
-	sp.RegisterHandler("RPCUpdateCVE", createUpdateCVEHandler(rpcClient, logger))
+	sp.RegisterHandler("RPCUpdateCVE", createUpdateCVEHandler(rpcClient, logger, runStore))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCUpdateCVE")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCUpdateCVE")
-	sp.RegisterHandler("RPCDeleteCVE", createDeleteCVEHandler(rpcClient, logger))
+	sp.RegisterHandler("RPCDeleteCVE", createDeleteCVEHandler(rpcClient, logger, runStore))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCDeleteCVE")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCDeleteCVE")
 	sp.RegisterHandler("RPCListCVEs", createListCVEsHandler(rpcClient, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCListCVEs")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCListCVEs")
+	sp.RegisterHandler("RPCSearchCVEs", createSearchCVEsHandler(rpcClient, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCSearchCVEs")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCSearchCVEs")
+	sp.RegisterHandler("RPCGetCVEsByCWE", createGetCVEsByCWEHandler(rpcClient, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVEsByCWE")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetCVEsByCWE")
 	sp.RegisterHandler("RPCCountCVEs", createCountCVEsHandler(rpcClient, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCCountCVEs")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCCountCVEs")
+	sp.RegisterHandler("RPCGetStorageStats", createProxyHandler(rpcClient, logger, "local", "RPCGetStorageStats"))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetStorageStats")
+	sp.RegisterHandler("RPCGetTimeline", createGetTimelineHandler(runStore, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetTimeline")
+	sp.RegisterHandler("RPCPrefetchCVEs", createPrefetchCVEsHandler(cveLoader, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCPrefetchCVEs")
+	sp.RegisterHandler("RPCGetPrefetchStatus", createGetPrefetchStatusHandler(cveLoader, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetPrefetchStatus")
+	sp.RegisterHandler("RPCDiffCVE", createDiffCVEHandler(rpcClient, cveLoader, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCDiffCVE")
+	sp.RegisterHandler("RPCGetCWEDetail", createGetCWEDetailHandler(rpcClient, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCWEDetail")
 
 	// Register job control RPC handlers
 	sp.RegisterHandler("RPCStartSession", createStartSessionHandler(jobExecutor, logger))
@@ -325,12 +411,59 @@ func main() {
 	sp.RegisterHandler("RPCGetSessionStatus", createGetSessionStatusHandler(jobExecutor, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetSessionStatus")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetSessionStatus")
+	sessionEvents := newSessionEvents(sp, jobExecutor, runStore, logger)
+	sp.RegisterHandler("RPCSubscribeSessionEvents", createSubscribeSessionEventsHandler(sessionEvents, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCSubscribeSessionEvents")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCSubscribeSessionEvents")
+	sp.RegisterHandler("RPCUnsubscribeSessionEvents", createUnsubscribeSessionEventsHandler(sessionEvents, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCUnsubscribeSessionEvents")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCUnsubscribeSessionEvents")
+	sp.RegisterHandler("RPCListRuns", createListRunsHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCListRuns")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCListRuns")
+
+	sp.RegisterHandler("RPCListSessions", createListSessionsHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCListSessions")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCListSessions")
+	sp.RegisterHandler("RPCGetProviderMetrics", createGetProviderMetricsHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetProviderMetrics")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetProviderMetrics")
+	sp.RegisterHandler("RPCGetExecutorStats", createGetExecutorStatsHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetExecutorStats")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetExecutorStats")
+	sp.RegisterHandler("RPCGetFSMTransitions", createGetFSMTransitionsHandler(fsm.DefaultTransitionLog, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetFSMTransitions")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetFSMTransitions")
 	sp.RegisterHandler("RPCPauseJob", createPauseJobHandler(jobExecutor, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCPauseJob")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCPauseJob")
 	sp.RegisterHandler("RPCResumeJob", createResumeJobHandler(jobExecutor, logger))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCResumeJob")
 	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCResumeJob")
+	sp.RegisterHandler("RPCPauseAllProviders", createPauseAllProvidersHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCPauseAllProviders")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCPauseAllProviders")
+	sp.RegisterHandler("RPCResumeAllProviders", createResumeAllProvidersHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCResumeAllProviders")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCResumeAllProviders")
+
+	sp.RegisterHandler("RPCSetRunPriority", createSetRunPriorityHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCSetRunPriority")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCSetRunPriority")
+
+	sp.RegisterHandler("RPCUpdateConcurrency", createUpdateConcurrencyHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCUpdateConcurrency")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCUpdateConcurrency")
+
+	sp.RegisterHandler("RPCListQuarantined", createListQuarantinedHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCListQuarantined")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCListQuarantined")
+	sp.RegisterHandler("RPCRetryQuarantined", createRetryQuarantinedHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCRetryQuarantined")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCRetryQuarantined")
+	sp.RegisterHandler("RPCGetRunLogs", createGetRunLogsHandler(jobExecutor, logger))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetRunLogs")
+	logger.Debug(LogMsgRPCClientHandlerRegistered, "RPCGetRunLogs")
 
 	// Register CWE view job RPC handlers
 	sp.RegisterHandler("RPCStartCWEViewJob", createStartCWEViewJobHandler(cweJobController, logger))
@@ -367,15 +500,10 @@ func main() {
 	go func() {
 		logger.Info("Starting CWE import control routine...")
 		time.Sleep(2 * time.Second)
-		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-		defer cancel()
-		params := &rpc.ImportParams{Path: "assets/cwe-raw.json"}
-		logger.Info(LogMsgCWEImportTriggered, params.Path)
-		resp, err := rpcClient.InvokeRPC(ctx, "local", "RPCImportCWEs", params)
-		if err != nil {
+		path := "assets/cwe-raw.json"
+		logger.Info(LogMsgCWEImportTriggered, path)
+		if _, err := dataPopController.StartDataPopulation(context.Background(), DataTypeCWE, map[string]interface{}{"path": path}); err != nil {
 			logger.Warn("Failed to import CWE on local: %v", err)
-		} else if resp.Type == subprocess.MessageTypeError {
-			logger.Warn("CWE import error: %s", resp.Error)
 		} else {
 			logger.Info("CWE import triggered on local")
 		}
@@ -398,13 +526,11 @@ func main() {
 			return
 		}
 		// If meta not present or query failed, attempt import
-		params := &rpc.ImportParams{Path: "assets/capec_contents_latest.xml"}
-		logger.Info(LogMsgCAPECImportTriggered, params.Path)
-		resp, err := rpcClient.InvokeRPC(ctx, "local", "RPCImportCAPECs", params)
-		if err != nil {
+		path := "assets/capec_contents_latest.xml"
+		logger.Info(LogMsgCAPECImportTriggered, path)
+		params := map[string]interface{}{"path": path, "xsd": "assets/capec_schema_latest.xsd"}
+		if _, err := dataPopController.StartDataPopulation(context.Background(), DataTypeCAPEC, params); err != nil {
 			logger.Warn("Failed to import CAPEC on local: %v", err)
-		} else if resp.Type == subprocess.MessageTypeError {
-			logger.Warn("CAPEC import error: %s", resp.Error)
 		} else {
 			logger.Info("CAPEC import triggered on local")
 		}
@@ -582,8 +708,9 @@ func createStartCCEImportHandler(controller *DataPopulationController, logger *c
 }
 
 // createGetCVEHandler creates a handler that retrieves CVE data
-// Flow: Check local storage first, if not found fetch from remote and save locally
-func createGetCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+// Flow: Check local storage first, if not found fetch from remote and save
+// locally when the cache policy allows it
+func createGetCVEHandler(rpcClient *rpc.Client, loader *CVELoader, logger *common.Logger, cachePolicy *CVECachePolicy, timeline timelineRecorder) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug(LogMsgRPCHandlerCalled, "RPCGetCVE")
 		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
@@ -591,6 +718,8 @@ func createGetCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subproces
 		// Parse the request payload
 		var req struct {
 			CVEID string `json:"cve_id"`
+			// EnrichEPSS attaches the current EPSS score from the remote service
+			EnrichEPSS bool `json:"enrich_epss"`
 		}
 		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
 			logger.Warn("Failed to parse request: %v", err)
@@ -633,6 +762,7 @@ func createGetCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subproces
 		}
 
 		var cveData *cve.CVEItem
+		freshness := CVEFreshness{Source: "local", Cached: true, CachePolicy: cachePolicy.Mode}
 
 		if checkResult.Stored {
 			// Step 2a: CVE is stored locally, retrieve it
@@ -656,106 +786,435 @@ func createGetCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subproces
 				logger.Debug("GetCVE failed to parse local CVE data for CVE ID %s: %v", req.CVEID, err)
 				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse local CVE data: %v", err)), nil
 			}
+
+			// Store a changed EPSS score so later reads need no enrichment
+			if req.EnrichEPSS && enrichEPSS(ctx, rpcClient, logger, cveData) {
+				saveResp, err := rpcClient.InvokeRPC(ctx, "local", "RPCSaveCVEByID", &rpc.SaveCVEByIDParams{CVE: *cveData})
+				if err == nil {
+					if isErr, errMsg := subprocess.IsErrorResponse(saveResp); isErr {
+						err = fmt.Errorf("%s", errMsg)
+					}
+				}
+				if err != nil {
+					logger.Warn("Failed to save EPSS score of CVE %s (continuing anyway): %v", req.CVEID, err)
+				}
+			}
 		} else {
 			// Step 2b: CVE not found locally, fetch from remote
 			logger.Info("RPCGetCVE: CVE %s not found locally, fetching from remote NVD API", req.CVEID)
-			remoteResp, err := rpcClient.InvokeRPC(ctx, "remote", "RPCGetCVEByID", &rpc.CVEIDParams{CVEID: req.CVEID})
+			// The loader coalesces this fetch with prefetches of the same CVE
+			remoteCVE, err := loader.FetchRemote(ctx, req.CVEID)
+			if errors.Is(err, errCVENotFound) {
+				logger.Warn("CVE %s not found in NVD", req.CVEID)
+				logger.Debug("GetCVE remote fetch found no vulnerabilities for CVE ID %s", req.CVEID)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("CVE %s not found", req.CVEID)), nil
+			}
 			if err != nil {
 				logger.Warn("Failed to fetch CVE from remote: %v", err)
 				logger.Debug("GetCVE failed to fetch CVE from remote for CVE ID %s: %v", req.CVEID, err)
 				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to fetch CVE from remote: %v", err)), nil
 			}
 
-			// Check if the response is an error
-			if isErr, errMsg := subprocess.IsErrorResponse(remoteResp); isErr {
-				logger.Warn("Error fetching CVE from remote: %s", errMsg)
-				logger.Debug("GetCVE remote fetch returned error for CVE ID %s: %s", req.CVEID, errMsg)
-				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to fetch CVE from remote: %s", errMsg)), nil
+			cveData = remoteCVE
+			freshness.Source = "remote"
+			if req.EnrichEPSS {
+				enrichEPSS(ctx, rpcClient, logger, cveData)
 			}
 
-			// Parse remote response (NVD API format)
-			var remoteResult cve.CVEResponse
-			if err := subprocess.UnmarshalPayload(remoteResp, &remoteResult); err != nil {
-				logger.Warn("Failed to parse remote CVE response: %v", err)
-				logger.Debug("GetCVE failed to parse remote CVE response for CVE ID %s: %v", req.CVEID, err)
-				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse remote CVE response: %v", err)), nil
+			// Step 3: Save fetched CVE to local storage if the policy allows it;
+			// otherwise it is returned transient
+			cache, reason := cachePolicy.ShouldCache(cveData)
+			freshness.Cached, freshness.CacheReason = false, reason
+			if !cache {
+				logger.Info("RPCGetCVE: Not caching CVE %s: %s", req.CVEID, reason)
+			} else {
+				logger.Info("RPCGetCVE: Saving CVE %s to local storage", req.CVEID)
+				saveResp, err := rpcClient.InvokeRPC(ctx, "local", "RPCSaveCVEByID", &rpc.SaveCVEByIDParams{CVE: *cveData})
+				if err == nil {
+					if isErr, errMsg := subprocess.IsErrorResponse(saveResp); isErr {
+						err = fmt.Errorf("%s", errMsg)
+					}
+				}
+				if err != nil {
+					logger.Warn("Failed to save CVE to local storage (continuing anyway): %v", err)
+					logger.Debug("GetCVE save to local storage failed for CVE ID %s: %v", req.CVEID, err)
+					// Continue even if save fails - we still have the data
+					freshness.CacheReason = fmt.Sprintf("save failed: %v", err)
+				} else {
+					freshness.Cached = true
+					recordCVEChange(timeline, logger, req.CVEID, "cached")
+				}
 			}
+		}
 
-			// Extract CVE data from response
-			if len(remoteResult.Vulnerabilities) == 0 {
-				logger.Warn("CVE %s not found in NVD", req.CVEID)
-				logger.Debug("GetCVE remote fetch found no vulnerabilities for CVE ID %s", req.CVEID)
-				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("CVE %s not found", req.CVEID)), nil
-			}
+		logger.Info("RPCGetCVE: Successfully retrieved CVE %s", req.CVEID)
+		logger.Debug("GetCVE request completed successfully for CVE ID %s", req.CVEID)
+		return subprocess.NewSuccessResponse(msg, cveWithFreshness{CVEItem: cveData, Freshness: freshness})
+	}
+}
 
-			cveData = &remoteResult.Vulnerabilities[0].CVE
+// enrichEPSS sets the EPSS score of a CVE from the remote service and reports
+// whether it changed. A CVE without a score keeps the stored one; a failed
+// lookup is logged and leaves the CVE as it is.
+func enrichEPSS(ctx context.Context, rpcClient *rpc.Client, logger *common.Logger, item *cve.CVEItem) bool {
+	resp, err := rpcClient.InvokeRPC(ctx, "remote", "RPCGetEPSS", &rpc.CVEIDParams{CVEID: item.ID})
+	if err == nil {
+		if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+			err = fmt.Errorf("%s", errMsg)
+		}
+	}
+	var result struct {
+		Found bool      `json:"found"`
+		EPSS  *cve.EPSS `json:"epss"`
+	}
+	if err == nil {
+		err = subprocess.UnmarshalPayload(resp, &result)
+	}
+	if err != nil {
+		logger.Warn("Failed to get EPSS score of CVE %s (continuing anyway): %v", item.ID, err)
+		return false
+	}
+	if !result.Found || result.EPSS == nil {
+		logger.Debug("No EPSS score for CVE %s", item.ID)
+		return false
+	}
+	if item.EPSS != nil && *item.EPSS == *result.EPSS {
+		return false
+	}
+	item.EPSS = result.EPSS
+	return true
+}
 
-			// Step 3: Save fetched CVE to local storage
-			logger.Info("RPCGetCVE: Saving CVE %s to local storage", req.CVEID)
-			_, err = rpcClient.InvokeRPC(ctx, "local", "RPCSaveCVEByID", &rpc.SaveCVEByIDParams{CVE: *cveData})
-			if err != nil {
-				logger.Warn("Failed to save CVE to local storage (continuing anyway): %v", err)
-				logger.Debug("GetCVE save to local storage failed for CVE ID %s: %v", req.CVEID, err)
-				// Continue even if save fails - we still have the data
-			}
+// parseSessionID returns the optional session_id of a session control request
+func parseSessionID(msg *subprocess.Message) (string, error) {
+	var req struct {
+		SessionID string `json:"session_id"`
+	}
+	if msg.Payload != nil {
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			return "", err
 		}
+	}
+	return req.SessionID, nil
+}
 
-		logger.Info("RPCGetCVE: Successfully retrieved CVE %s", req.CVEID)
-		logger.Debug("GetCVE request completed successfully for CVE ID %s", req.CVEID)
-		return subprocess.NewSuccessResponse(msg, cveData)
+// sessionRun returns the run a session control request applies to: the run
+// named by session_id, or the current CVE run when it is omitted, as before
+// sessions of several data types could run at once. It returns nil if there
+// is no such run.
+func sessionRun(jobExecutor *taskflow.JobExecutor, sessionID string) (*taskflow.JobRun, error) {
+	if sessionID != "" {
+		run, err := jobExecutor.GetStatus(sessionID)
+		if err != nil {
+			return nil, nil
+		}
+		return run, nil
 	}
+	return jobExecutor.GetCurrentRun(taskflow.DataTypeCVE)
 }
 
-// createGetSessionStatusHandler creates a handler that returns the current run status
+// createGetSessionStatusHandler creates a handler that returns the status of
+// a run: the one named by session_id, or else the active CVE run, or else the
+// oldest active run
 func createGetSessionStatusHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug("RPCGetSessionStatus: Getting run status")
 
-		// Get active run
-		run, err := jobExecutor.GetActiveRun()
+		sessionID, err := parseSessionID(msg)
 		if err != nil {
-			logger.Warn("Failed to get active run: %v", err)
-			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active run: %v", err)), nil
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		// Get active runs
+		activeRuns, err := jobExecutor.GetActiveRuns()
+		if err != nil {
+			logger.Warn("Failed to get active runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active runs: %v", err)), nil
+		}
+		activeSessions := runIDs(activeRuns)
+
+		var run *taskflow.JobRun
+		if sessionID != "" {
+			run, _ = sessionRun(jobExecutor, sessionID)
+		} else {
+			for _, activeRun := range activeRuns {
+				if activeRun.DataType == taskflow.DataTypeCVE {
+					run = activeRun
+					break
+				}
+			}
+			if run == nil && len(activeRuns) > 0 {
+				run = activeRuns[0]
+			}
 		}
 
 		if run == nil {
-			// No active run - return empty status
+			// No such run - return empty status
 			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
-				"has_session": false,
+				"has_session":     false,
+				"active_sessions": activeSessions,
 			})
 		}
 
-		// Prepare the enhanced result with new data structures
-		result := map[string]interface{}{
-			"has_session":       true,
-			"session_id":        run.ID,
-			"state":             run.State,
-			"data_type":         run.DataType, // New field
-			"start_index":       run.StartIndex,
-			"results_per_batch": run.ResultsPerBatch,
-			"created_at":        run.CreatedAt,
-			"updated_at":        run.UpdatedAt,
-			"fetched_count":     run.FetchedCount,
-			"stored_count":      run.StoredCount,
-			"error_count":       run.ErrorCount,
-			"error_message":     run.ErrorMessage,
-			// New fields for enhanced progress tracking
-			"progress": run.Progress,
-			"params":   run.Params,
-		}
+		result := runStatus(run, activeSessions)
 
 		logger.Debug("RPCGetSessionStatus: Successfully retrieved run status")
 		return subprocess.NewSuccessResponse(msg, result)
 	}
 }
 
+// runIDs returns the IDs of runs
+func runIDs(runs []*taskflow.JobRun) []string {
+	ids := make([]string, len(runs))
+	for i, run := range runs {
+		ids[i] = run.ID
+	}
+	return ids
+}
+
+// runStatus is the status of a run as RPCGetSessionStatus returns it, and as
+// session events push it
+func runStatus(run *taskflow.JobRun, activeSessions []string) map[string]interface{} {
+	status := map[string]interface{}{
+		"has_session":       true,
+		"session_id":        run.ID,
+		"state":             run.State,
+		"data_type":         run.DataType, // New field
+		"start_index":       run.StartIndex,
+		"results_per_batch": run.ResultsPerBatch,
+		"priority":          run.Priority,
+		"created_at":        run.CreatedAt,
+		"updated_at":        run.UpdatedAt,
+		"fetched_count":     run.FetchedCount,
+		"stored_count":      run.StoredCount,
+		"error_count":       run.ErrorCount,
+		"error_message":     run.ErrorMessage,
+		// New fields for enhanced progress tracking
+		"progress": run.Progress,
+		"params":   run.Params,
+		// Every active run, one per data type
+		"active_sessions": activeSessions,
+	}
+	if run.Callback != nil {
+		status["callback"] = run.Callback
+	}
+	return status
+}
+
+// createListRunsHandler creates a handler that returns the run history,
+// newest first, with the active run flagged
+func createListRunsHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			Offset int `json:"offset"`
+			Limit  int `json:"limit"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Offset < 0 || req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "offset and limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = 100
+		}
+
+		runs, total, err := jobExecutor.ListRuns(req.Limit, req.Offset)
+		if err != nil {
+			logger.Warn("Failed to list runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to list runs: %v", err)), nil
+		}
+		if runs == nil {
+			runs = []taskflow.RunRecord{}
+		}
+
+		logger.Debug("RPCListRuns: %d of %d runs", len(runs), total)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"runs":   runs,
+			"total":  total,
+			"offset": req.Offset,
+			"limit":  req.Limit,
+		})
+	}
+}
+
+// sessionSummary is a run as listed by RPCListSessions
+type sessionSummary struct {
+	ID           string            `json:"id"`
+	State        taskflow.JobState `json:"state"`
+	DataType     taskflow.DataType `json:"data_type"`
+	Active       bool              `json:"active"`
+	FetchedCount int64             `json:"fetched_count"`
+	StoredCount  int64             `json:"stored_count"`
+	ErrorCount   int64             `json:"error_count"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// newSessionSummary summarizes a run
+func newSessionSummary(run *taskflow.JobRun, active bool) sessionSummary {
+	return sessionSummary{
+		ID:           run.ID,
+		State:        run.State,
+		DataType:     run.DataType,
+		Active:       active,
+		FetchedCount: run.FetchedCount,
+		StoredCount:  run.StoredCount,
+		ErrorCount:   run.ErrorCount,
+		CreatedAt:    run.CreatedAt,
+		UpdatedAt:    run.UpdatedAt,
+	}
+}
+
+// createListSessionsHandler creates a handler that summarizes the UEE
+// sessions for the analysis service: every active run, then the most recent
+// other runs of the history
+func createListSessionsHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			Limit int `json:"limit"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = 20
+		}
+
+		activeRuns, err := jobExecutor.GetActiveRuns()
+		if err != nil {
+			logger.Warn("Failed to get active runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active runs: %v", err)), nil
+		}
+		recent, total, err := jobExecutor.ListRuns(req.Limit, 0)
+		if err != nil {
+			logger.Warn("Failed to list runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to list runs: %v", err)), nil
+		}
+
+		sessions := make([]sessionSummary, 0, len(activeRuns)+len(recent))
+		listed := make(map[string]bool, len(activeRuns))
+		for _, run := range activeRuns {
+			sessions = append(sessions, newSessionSummary(run, true))
+			listed[run.ID] = true
+		}
+		for _, record := range recent {
+			if !listed[record.ID] {
+				sessions = append(sessions, newSessionSummary(record.JobRun, record.Active))
+			}
+		}
+
+		logger.Debug("RPCListSessions: %d active, %d listed of %d runs", len(activeRuns), len(sessions), total)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"sessions":     sessions,
+			"active_count": len(activeRuns),
+			"total":        total,
+		})
+	}
+}
+
+// createGetProviderMetricsHandler creates a handler that returns the
+// throughput of the active runs over time, for charts
+func createGetProviderMetricsHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			DataType taskflow.DataType `json:"data_type"`
+			Limit    int               `json:"limit"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = 60
+		}
+
+		providers := []taskflow.ProviderMetrics{}
+		for _, m := range jobExecutor.GetProviderMetrics(req.Limit) {
+			if req.DataType == "" || m.DataType == req.DataType {
+				providers = append(providers, m)
+			}
+		}
+
+		logger.Debug("RPCGetProviderMetrics: %d active runs (data_type=%q)", len(providers), req.DataType)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"providers": providers,
+			"limit":     req.Limit,
+		})
+	}
+}
+
+// createGetExecutorStatsHandler creates a handler that returns how saturated
+// the worker pool of the job executor is
+func createGetExecutorStatsHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		stats := jobExecutor.GetExecutorStats()
+		logger.Debug("RPCGetExecutorStats: %d in flight, %d queued, %d rejected", stats.InFlight, stats.QueueDepth, stats.Rejected)
+		return subprocess.NewSuccessResponse(msg, stats)
+	}
+}
+
+// createGetFSMTransitionsHandler creates a handler that returns the most
+// recent state changes of the ETL FSMs, newest first
+func createGetFSMTransitionsHandler(transitions *fsm.TransitionLog, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			Limit int `json:"limit"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = fsm.DefaultTransitionsLimit
+		}
+		if req.Limit > transitions.Capacity() {
+			req.Limit = transitions.Capacity()
+		}
+
+		records := transitions.Recent(req.Limit)
+		logger.Debug("RPCGetFSMTransitions: returning %d transitions", len(records))
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"transitions": records,
+			"limit":       req.Limit,
+			"capacity":    transitions.Capacity(),
+		})
+	}
+}
+
 // createPauseJobHandler creates a handler that pauses the running job
 func createPauseJobHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Info("RPCPauseJob: Pausing job")
 
-		// Get active run first
-		run, err := jobExecutor.GetActiveRun()
+		sessionID, err := parseSessionID(msg)
+		if err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		// Get the run first
+		run, err := sessionRun(jobExecutor, sessionID)
 		if err != nil {
 			logger.Warn("Failed to get active run: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active run: %v", err)), nil
@@ -772,10 +1231,11 @@ func createPauseJobHandler(jobExecutor *taskflow.JobExecutor, logger *common.Log
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to pause job: %v", err)), nil
 		}
 
-		logger.Info("RPCPauseJob: Successfully paused job")
+		logger.Info("RPCPauseJob: Successfully paused job %s", run.ID)
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
-			"success": true,
-			"state":   "paused",
+			"success":    true,
+			"session_id": run.ID,
+			"state":      "paused",
 		})
 	}
 }
@@ -785,8 +1245,14 @@ func createResumeJobHandler(jobExecutor *taskflow.JobExecutor, logger *common.Lo
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Info("RPCResumeJob: Resuming job")
 
-		// Get active run first
-		run, err := jobExecutor.GetActiveRun()
+		sessionID, err := parseSessionID(msg)
+		if err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		// Get the run first
+		run, err := sessionRun(jobExecutor, sessionID)
 		if err != nil {
 			logger.Warn("Failed to get active run: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active run: %v", err)), nil
@@ -803,14 +1269,66 @@ func createResumeJobHandler(jobExecutor *taskflow.JobExecutor, logger *common.Lo
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to resume job: %v", err)), nil
 		}
 
-		logger.Info("RPCResumeJob: Successfully resumed job")
+		logger.Info("RPCResumeJob: Successfully resumed job %s", run.ID)
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
-			"success": true,
-			"state":   "running",
+			"success":    true,
+			"session_id": run.ID,
+			"state":      "running",
 		})
 	}
 }
 
+// createPauseAllProvidersHandler creates a handler that pauses every running
+// run, e.g. when NVD rate-limits the whole pipeline
+func createPauseAllProvidersHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Info("RPCPauseAllProviders: Pausing all runs")
+		results, err := jobExecutor.PauseAll()
+		if err != nil {
+			logger.Warn("Failed to pause runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to pause runs: %v", err)), nil
+		}
+		return bulkControlResponse(msg, "RPCPauseAllProviders", results, logger)
+	}
+}
+
+// createResumeAllProvidersHandler creates a handler that resumes the paused
+// runs, the latest of each data type
+func createResumeAllProvidersHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Info("RPCResumeAllProviders: Resuming all paused runs")
+		results, err := jobExecutor.ResumeAll(ctx)
+		if err != nil {
+			logger.Warn("Failed to resume runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to resume runs: %v", err)), nil
+		}
+		return bulkControlResponse(msg, "RPCResumeAllProviders", results, logger)
+	}
+}
+
+// bulkControlResponse returns the per-run outcomes of a bulk pause or resume
+// with the number of runs per outcome
+func bulkControlResponse(msg *subprocess.Message, method string, results []taskflow.BulkControlResult, logger *common.Logger) (*subprocess.Message, error) {
+	counts := map[string]int{
+		taskflow.BulkOutcomePaused:  0,
+		taskflow.BulkOutcomeResumed: 0,
+		taskflow.BulkOutcomeSkipped: 0,
+		taskflow.BulkOutcomeFailed:  0,
+	}
+	for _, r := range results {
+		counts[r.Outcome]++
+		if r.Outcome == taskflow.BulkOutcomeFailed {
+			logger.Warn("%s: run %s failed: %s", method, r.RunID, r.Error)
+		}
+	}
+	logger.Info("%s: %d paused, %d resumed, %d skipped, %d failed", method,
+		counts[taskflow.BulkOutcomePaused], counts[taskflow.BulkOutcomeResumed], counts[taskflow.BulkOutcomeSkipped], counts[taskflow.BulkOutcomeFailed])
+	return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+		"runs":   results,
+		"counts": counts,
+	})
+}
+
 // createStartSessionHandler creates a handler that starts a new job session
 func createStartSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -819,6 +1337,7 @@ func createStartSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common
 			DataType        taskflow.DataType `json:"data_type"`
 			StartIndex      int               `json:"start_index"`
 			ResultsPerBatch int               `json:"results_per_batch"`
+			Priority        string            `json:"priority"`
 			Params          map[string]interface{}
 		}
 		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
@@ -843,9 +1362,15 @@ func createStartSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "results_per_batch must be positive"), nil
 		}
 
+		priority, err := taskflow.ParsePriority(req.Priority)
+		if err != nil {
+			logger.Warn("Invalid priority: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+
 		sessionID := fmt.Sprintf("%s-%d", req.DataType, time.Now().Unix())
 
-		err := jobExecutor.StartTyped(ctx, sessionID, req.StartIndex, req.ResultsPerBatch, req.DataType)
+		err = jobExecutor.StartTypedWithParams(ctx, sessionID, req.StartIndex, req.ResultsPerBatch, req.DataType, priority, req.Params)
 		if err != nil {
 			logger.Warn("Failed to start job session: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to start job session: %v", err)), nil
@@ -864,6 +1389,7 @@ func createStartSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common
 			"session_id":  run.ID,
 			"data_type":   string(run.DataType),
 			"state":       run.State,
+			"priority":    run.Priority,
 			"created_at":  run.CreatedAt,
 			"start_index": run.StartIndex,
 			"batch_size":  run.ResultsPerBatch,
@@ -876,8 +1402,14 @@ func createStopSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common.
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Info("RPCStopSession: Stopping job session")
 
-		// Get active run first
-		run, err := jobExecutor.GetActiveRun()
+		sessionID, err := parseSessionID(msg)
+		if err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		// Get the run first
+		run, err := sessionRun(jobExecutor, sessionID)
 		if err != nil {
 			logger.Warn("Failed to get active run: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active run: %v", err)), nil
@@ -894,9 +1426,10 @@ func createStopSessionHandler(jobExecutor *taskflow.JobExecutor, logger *common.
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to stop job session: %v", err)), nil
 		}
 
-		logger.Info("RPCStopSession: Successfully stopped job session")
+		logger.Info("RPCStopSession: Successfully stopped job session %s", run.ID)
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
-			"success": true,
+			"success":    true,
+			"session_id": run.ID,
 		})
 	}
 }
@@ -909,11 +1442,19 @@ func createStartCWEViewJobHandler(controller *cwejob.Controller, logger *common.
 		// Parse the request payload
 		var req struct {
 			Params map[string]interface{}
+			// SessionID resumes a stopped or interrupted job from its checkpoint
+			SessionID string `json:"session_id"`
 		}
 		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
 			logger.Warn("Failed to parse request: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
 		}
+		if req.SessionID != "" {
+			if req.Params == nil {
+				req.Params = make(map[string]interface{})
+			}
+			req.Params["session_id"] = req.SessionID
+		}
 
 		// Start the CWE view job
 		sessionID, err := controller.Start(ctx, req.Params)
@@ -926,6 +1467,7 @@ func createStartCWEViewJobHandler(controller *cwejob.Controller, logger *common.
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
 			"success":    true,
 			"session_id": sessionID,
+			"resumed":    req.SessionID != "",
 		})
 	}
 }
@@ -964,7 +1506,7 @@ func createStopCWEViewJobHandler(controller *cwejob.Controller, logger *common.L
 }
 
 // createCreateCVEHandler creates a handler that creates a new CVE
-func createCreateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+func createCreateCVEHandler(rpcClient *rpc.Client, logger *common.Logger, timeline timelineRecorder) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug(LogMsgRPCHandlerCalled, "RPCCreateCVE")
 		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
@@ -995,6 +1537,7 @@ func createCreateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subpro
 		}
 
 		logger.Info("RPCCreateCVE: Successfully created CVE")
+		recordCVEChange(timeline, logger, req.ID, "created")
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
 			"success": true,
 		})
@@ -1002,7 +1545,7 @@ func createCreateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subpro
 }
 
 // createUpdateCVEHandler creates a handler that updates an existing CVE
-func createUpdateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+func createUpdateCVEHandler(rpcClient *rpc.Client, logger *common.Logger, timeline timelineRecorder) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug(LogMsgRPCHandlerCalled, "RPCUpdateCVE")
 		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
@@ -1033,6 +1576,7 @@ func createUpdateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subpro
 		}
 
 		logger.Info("RPCUpdateCVE: Successfully updated CVE")
+		recordCVEChange(timeline, logger, req.ID, "updated")
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
 			"success": true,
 		})
@@ -1040,7 +1584,7 @@ func createUpdateCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subpro
 }
 
 // createDeleteCVEHandler creates a handler that deletes an existing CVE
-func createDeleteCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+func createDeleteCVEHandler(rpcClient *rpc.Client, logger *common.Logger, timeline timelineRecorder) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Debug(LogMsgRPCHandlerCalled, "RPCDeleteCVE")
 		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
@@ -1073,12 +1617,33 @@ func createDeleteCVEHandler(rpcClient *rpc.Client, logger *common.Logger) subpro
 		}
 
 		logger.Info("RPCDeleteCVE: Successfully deleted CVE")
+		recordCVEChange(timeline, logger, req.CVEID, "deleted")
+		removeCVEFromGraph(ctx, rpcClient, logger, req.CVEID)
 		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
 			"success": true,
 		})
 	}
 }
 
+// removeCVEFromGraph removes a deleted CVE's node and its edges from the
+// analysis graph. The CVE itself is already deleted, so a failure is only
+// logged; the node is dropped at the latest when the graph is next rebuilt.
+func removeCVEFromGraph(ctx context.Context, rpcClient *rpc.Client, logger *common.Logger, cveID string) {
+	u, err := urn.New(urn.ProviderNVD, urn.TypeCVE, cveID)
+	if err != nil {
+		logger.Warn("Cannot remove CVE %s from the analysis graph: %v", cveID, err)
+		return
+	}
+	resp, err := rpcClient.InvokeRPC(ctx, "analysis", "RPCRemoveNode", &rpc.URNParams{URN: u.String()})
+	if err != nil {
+		logger.Warn("Failed to remove CVE %s from the analysis graph: %v", cveID, err)
+		return
+	}
+	if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+		logger.Warn("Failed to remove CVE %s from the analysis graph: %s", cveID, errMsg)
+	}
+}
+
 // createListCVEsHandler creates a handler that lists CVEs
 func createListCVEsHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -1124,6 +1689,108 @@ func createListCVEsHandler(rpcClient *rpc.Client, logger *common.Logger) subproc
 	}
 }
 
+// createSearchCVEsHandler creates a handler that searches CVEs by keyword
+// and severity in local storage
+func createSearchCVEsHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCSearchCVEs")
+		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
+
+		// Parse the request payload
+		var req struct {
+			Keyword         string `json:"keyword"`
+			Severity        string `json:"severity"`
+			Offset          int    `json:"offset"`
+			Limit           int    `json:"limit"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		if req.Offset < 0 {
+			logger.Error("offset must be non-negative")
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "offset must be non-negative"), nil
+		}
+
+		if req.Limit <= 0 {
+			logger.Error("limit must be positive")
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must be positive"), nil
+		}
+
+		// Search CVEs
+		resp, err := rpcClient.InvokeRPC(ctx, "local", "RPCSearchCVEs", &req)
+		if err != nil {
+			logger.Warn("Failed to search CVEs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to search CVEs: %v", err)), nil
+		}
+
+		// Check if the response is an error
+		if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+			logger.Warn("Error searching CVEs: %s", errMsg)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to search CVEs: %s", errMsg)), nil
+		}
+
+		logger.Info("RPCSearchCVEs: Successfully searched CVEs")
+		// Forward the response directly (payload is already marshaled)
+		return resp, nil
+	}
+}
+
+// createGetCVEsByCWEHandler creates a handler that lists the CVEs mapped to
+// a CWE in local storage
+func createGetCVEsByCWEHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCGetCVEsByCWE")
+		logger.Debug(LogMsgRPCRequestReceived, msg.Type, msg.ID, msg.Source, msg.CorrelationID)
+
+		// Parse the request payload
+		var req struct {
+			CWEID           string `json:"cwe_id"`
+			Offset          int    `json:"offset"`
+			Limit           int    `json:"limit"`
+			IncludeRejected bool   `json:"include_rejected"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+
+		if req.CWEID == "" {
+			logger.Error("cwe_id is required")
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "cwe_id is required"), nil
+		}
+
+		if req.Offset < 0 {
+			logger.Error("offset must be non-negative")
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "offset must be non-negative"), nil
+		}
+
+		if req.Limit <= 0 {
+			logger.Error("limit must be positive")
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must be positive"), nil
+		}
+
+		// Look up the CVEs
+		resp, err := rpcClient.InvokeRPC(ctx, "local", "RPCGetCVEsByCWE", &req)
+		if err != nil {
+			logger.Warn("Failed to get CVEs by CWE: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get CVEs by CWE: %v", err)), nil
+		}
+
+		// Check if the response is an error
+		if isErr, errMsg := subprocess.IsErrorResponse(resp); isErr {
+			logger.Warn("Error getting CVEs by CWE: %s", errMsg)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get CVEs by CWE: %s", errMsg)), nil
+		}
+
+		logger.Info("RPCGetCVEsByCWE: Successfully listed CVEs of %s", req.CWEID)
+		// Forward the response directly (payload is already marshaled)
+		return resp, nil
+	}
+}
+
 // createCountCVEsHandler creates a handler that counts CVEs
 func createCountCVEsHandler(rpcClient *rpc.Client, logger *common.Logger) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -1158,13 +1825,22 @@ func createStartTypedSessionHandler(jobExecutor *taskflow.JobExecutor, logger *c
 			StartIndex      int                    `json:"start_index"`
 			ResultsPerBatch int                    `json:"results_per_batch"`
 			DataType        taskflow.DataType      `json:"data_type"`
+			Priority        string                 `json:"priority"`
 			Params          map[string]interface{} `json:"params,omitempty"`
+			CallbackURL     string                 `json:"callback_url,omitempty"`
 		}
 
 		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
 			logger.Error("Failed to parse request: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
 		}
+		// The callback URL is stored with the other run parameters
+		if req.CallbackURL != "" {
+			if req.Params == nil {
+				req.Params = make(map[string]interface{})
+			}
+			req.Params[taskflow.ParamCallbackURL] = req.CallbackURL
+		}
 
 		// Set defaults only if not provided (after unmarshaling)
 		if req.StartIndex == 0 {
@@ -1182,11 +1858,17 @@ func createStartTypedSessionHandler(jobExecutor *taskflow.JobExecutor, logger *c
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "session_id is required"), nil
 		}
 
-		logger.Info("RPCStartTypedSession: Starting job run %s (data_type=%s, start_index=%d, batch_size=%d)",
-			req.SessionID, req.DataType, req.StartIndex, req.ResultsPerBatch)
+		priority, err := taskflow.ParsePriority(req.Priority)
+		if err != nil {
+			logger.Error("Invalid priority: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+
+		logger.Info("RPCStartTypedSession: Starting job run %s (data_type=%s, start_index=%d, batch_size=%d, priority=%s)",
+			req.SessionID, req.DataType, req.StartIndex, req.ResultsPerBatch, priority)
 
 		// Start the job with the specified data type
-		err := jobExecutor.StartTyped(ctx, req.SessionID, req.StartIndex, req.ResultsPerBatch, req.DataType)
+		err = jobExecutor.StartTypedWithParams(ctx, req.SessionID, req.StartIndex, req.ResultsPerBatch, req.DataType, priority, req.Params)
 		if err != nil {
 			logger.Error("Failed to start job: %v", err)
 			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to start job: %v", err)), nil
@@ -1205,6 +1887,7 @@ func createStartTypedSessionHandler(jobExecutor *taskflow.JobExecutor, logger *c
 			"session_id":  run.ID,
 			"state":       run.State,
 			"data_type":   run.DataType,
+			"priority":    run.Priority,
 			"created_at":  run.CreatedAt,
 			"start_index": run.StartIndex,
 			"batch_size":  run.ResultsPerBatch,
@@ -1213,6 +1896,194 @@ func createStartTypedSessionHandler(jobExecutor *taskflow.JobExecutor, logger *c
 	}
 }
 
+// createSetRunPriorityHandler creates a handler that changes the scheduling priority of a run
+func createSetRunPriorityHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			SessionID string `json:"session_id"`
+			Priority  string `json:"priority"`
+		}
+
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		if req.SessionID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "session_id is required"), nil
+		}
+		if req.Priority == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "priority is required"), nil
+		}
+
+		priority, err := taskflow.ParsePriority(req.Priority)
+		if err != nil {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+
+		if err := jobExecutor.SetRunPriority(req.SessionID, priority); err != nil {
+			logger.Warn("Failed to set run priority: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to set run priority: %v", err)), nil
+		}
+
+		logger.Info("RPCSetRunPriority: Run %s priority set to %s", req.SessionID, priority)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"success":    true,
+			"session_id": req.SessionID,
+			"priority":   priority,
+		})
+	}
+}
+
+// createUpdateConcurrencyHandler creates a handler that changes the worker
+// limit of the job runs of a data type at runtime
+func createUpdateConcurrencyHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			DataType string `json:"data_type"`
+			Limit    *int   `json:"limit"`
+		}
+
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		if req.DataType == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "data_type is required"), nil
+		}
+		if req.Limit == nil {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit is required"), nil
+		}
+
+		dataType, err := taskflow.ParseDataType(req.DataType)
+		if err != nil {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+
+		if err := jobExecutor.SetConcurrencyLimit(dataType, *req.Limit); err != nil {
+			logger.Warn("Failed to update concurrency: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to update concurrency: %v", err)), nil
+		}
+
+		global, limits := jobExecutor.ConcurrencyLimits()
+		logger.Info("RPCUpdateConcurrency: %s limit set to %d", dataType, *req.Limit)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"success":            true,
+			"data_type":          dataType,
+			"limit":              *req.Limit,
+			"global_concurrency": global,
+			"limits":             limits,
+		})
+	}
+}
+
+// createListQuarantinedHandler creates a handler that lists items parked after
+// failing to store
+func createListQuarantinedHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			DataType taskflow.DataType `json:"data_type"`
+			Offset   int               `json:"offset"`
+			Limit    int               `json:"limit"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Offset < 0 || req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "offset and limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = 100
+		}
+
+		items, total, capacity, err := jobExecutor.ListQuarantined(req.DataType, req.Offset, req.Limit)
+		if err != nil {
+			logger.Warn("Failed to list quarantined items: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to list quarantined items: %v", err)), nil
+		}
+		if items == nil {
+			items = []taskflow.QuarantinedItem{}
+		}
+
+		logger.Debug("RPCListQuarantined: %d of %d items (data_type=%q)", len(items), total, req.DataType)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"data_type": req.DataType,
+			"items":     items,
+			"total":     total,
+			"offset":    req.Offset,
+			"limit":     req.Limit,
+			"cap":       capacity,
+		})
+	}
+}
+
+// createRetryQuarantinedHandler creates a handler that replays the store call
+// of quarantined items, e.g. after a schema fix
+func createRetryQuarantinedHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			DataType taskflow.DataType `json:"data_type"`
+			IDs      []string          `json:"ids"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+
+		result, err := jobExecutor.RetryQuarantined(ctx, req.DataType, req.IDs)
+		if err != nil {
+			logger.Warn("Failed to retry quarantined items: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to retry quarantined items: %v", err)), nil
+		}
+
+		logger.Info("RPCRetryQuarantined: %d retried, %d stored, %d still failing",
+			result.Retried, len(result.Succeeded), len(result.Failed))
+		return subprocess.NewSuccessResponse(msg, result)
+	}
+}
+
+// createGetRunLogsHandler creates a handler that tails the structured event
+// log of a run, for a focused view of what a failed run went through
+func createGetRunLogsHandler(jobExecutor *taskflow.JobExecutor, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			RunID string `json:"run_id"`
+			Limit int    `json:"limit"`
+		}
+		if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+			logger.Warn("Failed to parse request: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+		}
+		if req.RunID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "run_id is required"), nil
+		}
+		if req.Limit < 0 {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "limit must not be negative"), nil
+		}
+		if req.Limit == 0 {
+			req.Limit = 100
+		}
+
+		events, total, err := jobExecutor.GetRunLogs(req.RunID, req.Limit)
+		if err != nil {
+			logger.Warn("Failed to get logs of run %s: %v", req.RunID, err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get run logs: %v", err)), nil
+		}
+
+		logger.Debug("RPCGetRunLogs: %d of %d events of run %s", len(events), total, req.RunID)
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"run_id": req.RunID,
+			"events": events,
+			"total":  total,
+			"limit":  req.Limit,
+		})
+	}
+}
+
 // createProxyHandler returns a handler that proxies the RPC call to the given target and method.
 func createProxyHandler(rpcClient *rpc.Client, logger *common.Logger, target, method string) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,9 +27,22 @@ type CVEProvider struct {
 	errorCount         int64
 	totalProcessed     int64
 	failureThreshold   float64
+	prefetchDepth      int // Batches fetched ahead of storage (0 = serial)
 	workerPool         *workerPool
 }
 
+// cveBatch is a fetched batch waiting to be stored, together with the
+// incremental cursor that becomes current once the batch has been stored.
+type cveBatch struct {
+	cves   []map[string]interface{}
+	cursor string
+	err    error
+}
+
+// rateLimitBackoff is how long the provider waits in WAITING_BACKOFF after
+// NVD rate-limits a fetch
+const rateLimitBackoff = 30 * time.Second
+
 type workerPool struct {
 	tasks   chan func()
 	workers int
@@ -73,6 +87,7 @@ type CVEProviderConfig struct {
 	CheckpointInterval int
 	LastModStartDate   string
 	FailureThreshold   float64
+	PrefetchDepth      int // Lookahead for the fetch/store pipeline; 0 disables it
 }
 
 // NewCVEProvider creates a new CVE provider
@@ -86,6 +101,9 @@ func NewCVEProvider(config CVEProviderConfig) (*CVEProvider, error) {
 	if config.FailureThreshold <= 0 {
 		config.FailureThreshold = 0.1 // 10% default
 	}
+	if config.PrefetchDepth < 0 {
+		config.PrefetchDepth = 0
+	}
 
 	// Create executor function that will be called by BaseProviderFSM
 	var provider *CVEProvider
@@ -114,6 +132,7 @@ func NewCVEProvider(config CVEProviderConfig) (*CVEProvider, error) {
 		checkpointInterval: config.CheckpointInterval,
 		lastModStartDate:   config.LastModStartDate,
 		failureThreshold:   config.FailureThreshold,
+		prefetchDepth:      config.PrefetchDepth,
 		workerPool:         newWorkerPool(4),
 	}
 
@@ -125,40 +144,154 @@ func NewCVEProvider(config CVEProviderConfig) (*CVEProvider, error) {
 	return provider, nil
 }
 
-// executeBatch performs one batch of CVE fetching
+// executeBatch performs one batch of CVE fetching. When prefetching is
+// enabled it instead drives the fetch/store pipeline until the source is
+// exhausted or the provider leaves RUNNING.
 func (p *CVEProvider) executeBatch() error {
+	// Check error rate and auto-pause if threshold exceeded
+	if err := p.checkErrorThreshold(); err != nil {
+		return err
+	}
+
+	if p.prefetchDepth > 0 {
+		return p.executePipeline()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	// Check error rate and auto-pause if threshold exceeded
-	if err := p.checkErrorThreshold(); err != nil {
+	cves, err := p.fetchBatch(ctx, p.lastModStartDate)
+	if err != nil {
+		p.backoffOnRateLimit(err)
 		return err
 	}
 
+	if len(cves) == 0 {
+		p.logger.Info("No more CVEs to fetch, provider completed")
+		return nil // No more data, provider will transition to TERMINATED
+	}
+
+	p.storeBatch(ctx, cves)
+	p.lastModStartDate = batchCursor(cves, p.lastModStartDate)
+	return nil
+}
+
+// executePipeline overlaps fetching and storing: a prefetch goroutine keeps
+// up to prefetchDepth batches ready while the current batch is stored.
+// The incremental cursor only advances after a batch has been fully stored,
+// so batches still buffered when the provider is paused or stopped are
+// discarded and refetched on resume rather than lost or stored twice.
+func (p *CVEProvider) executePipeline() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan cveBatch, p.prefetchDepth)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.prefetch(ctx, p.lastModStartDate, batches)
+	}()
+
+	// Drain: abort any in-flight fetch and wait for the prefetcher to exit
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for {
+		if state := p.GetState(); state != fsm.ProviderRunning {
+			p.logger.Info("CVE pipeline draining: state=%s, discarding %d prefetched batches", state, len(batches))
+			return nil
+		}
+
+		batch, ok := <-batches
+		if !ok {
+			return nil
+		}
+		if batch.err != nil {
+			p.backoffOnRateLimit(batch.err)
+			return batch.err
+		}
+		if len(batch.cves) == 0 {
+			p.logger.Info("No more CVEs to fetch, provider completed")
+			return nil
+		}
+
+		// Storing uses its own context so a drain never interrupts a
+		// partially stored batch
+		storeCtx, storeCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		p.storeBatch(storeCtx, batch.cves)
+		storeCancel()
+		p.lastModStartDate = batch.cursor
+
+		if err := p.checkErrorThreshold(); err != nil {
+			return err
+		}
+	}
+}
+
+// prefetch fetches batches ahead of storage, starting at cursor, until the
+// source is exhausted, a fetch fails, or ctx is cancelled. It closes out
+// when it returns.
+func (p *CVEProvider) prefetch(ctx context.Context, cursor string, out chan<- cveBatch) {
+	defer close(out)
+
+	for {
+		cves, err := p.fetchBatch(ctx, cursor)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A batch that does not move a set cursor only repeats the CVEs at
+		// the cursor, which are already stored; end the pipeline there
+		next := batchCursor(cves, cursor)
+		if err == nil && cursor != "" && next == cursor {
+			cves = nil
+		}
+		select {
+		case out <- cveBatch{cves: cves, cursor: next, err: err}:
+		case <-ctx.Done():
+			return
+		}
+
+		// Stop on error or end of data; an unchanged cursor would refetch
+		// the same batch forever, so hand control back to the FSM instead
+		if err != nil || len(cves) == 0 || next == cursor {
+			return
+		}
+		cursor = next
+	}
+}
+
+// fetchBatch fetches one batch of CVEs modified since lastModStartDate
+func (p *CVEProvider) fetchBatch(ctx context.Context, lastModStartDate string) ([]map[string]interface{}, error) {
 	// Fetch CVEs with incremental update support
 	params := map[string]interface{}{
 		"limit": p.batchSize,
 	}
 
 	// Use incremental fetching if lastModStartDate is set
-	if p.lastModStartDate != "" {
-		params["lastModStartDate"] = p.lastModStartDate
+	if lastModStartDate != "" {
+		params["lastModStartDate"] = lastModStartDate
 	}
 
-	p.logger.Info("Fetching CVE batch: size=%d, lastModStartDate=%s", p.batchSize, p.lastModStartDate)
+	p.logger.Info("Fetching CVE batch: size=%d, lastModStartDate=%s", p.batchSize, lastModStartDate)
 
 	resp, err := p.rpcClient.InvokeRPC(ctx, "remote", "RPCFetchCVEBatch", params)
 	if err != nil {
-		p.errorCount++
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		atomic.AddInt64(&p.errorCount, 1)
 		p.logger.Error("Failed to fetch CVE batch: %v", err)
-		return fmt.Errorf("failed to fetch CVE batch: %w", err)
+		return nil, fmt.Errorf("failed to fetch CVE batch: %w", err)
 	}
 
 	// Check for error response
 	if isErr, errMsg := subprocess.IsErrorResponse(resp.(*subprocess.Message)); isErr {
-		p.errorCount++
+		atomic.AddInt64(&p.errorCount, 1)
 		p.logger.Error("CVE fetch returned error: %s", errMsg)
-		return fmt.Errorf("CVE fetch failed: %s", errMsg)
+		return nil, fmt.Errorf("CVE fetch failed: %s", errMsg)
 	}
 
 	// Extract CVEs from response
@@ -166,25 +299,23 @@ func (p *CVEProvider) executeBatch() error {
 		CVEs []map[string]interface{} `json:"cves"`
 	}
 	if err := subprocess.UnmarshalPayload(resp.(*subprocess.Message), &batchResp); err != nil {
-		p.errorCount++
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+		atomic.AddInt64(&p.errorCount, 1)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	cves := batchResp.CVEs
-	if len(cves) == 0 {
-		p.logger.Info("No more CVEs to fetch, provider completed")
-		return nil // No more data, provider will transition to TERMINATED
-	}
+	return batchResp.CVEs, nil
+}
 
-	// Process CVEs in parallel using worker pool
+// storeBatch saves a batch of CVEs in parallel using the worker pool and
+// returns once every item has been handled
+func (p *CVEProvider) storeBatch(ctx context.Context, cves []map[string]interface{}) {
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 
 	for _, cveMap := range cves {
 		wg.Add(1)
 		cveID, ok := cveMap["cve_id"].(string)
 		if !ok {
-			p.errorCount++
+			atomic.AddInt64(&p.errorCount, 1)
 			p.logger.Warn("Missing CVE ID")
 			wg.Done()
 			continue
@@ -196,16 +327,15 @@ func (p *CVEProvider) executeBatch() error {
 
 			// Save to local storage
 			if err := p.saveCVE(ctx, cveMap); err != nil {
+				atomic.AddInt64(&p.errorCount, 1)
 				p.logger.Error("Failed to save CVE %s: %v", cveID, err)
 				return
 			}
 
-			mu.Lock()
-			atomic.AddInt64(&p.totalProcessed, 1)
-			mu.Unlock()
+			processed := atomic.AddInt64(&p.totalProcessed, 1)
 
 			// Save checkpoint every N items
-			if p.totalProcessed%int64(p.checkpointInterval) == 0 {
+			if processed%int64(p.checkpointInterval) == 0 {
 				itemURN, err := urn.Parse(fmt.Sprintf("v2e::nvd::cve::%s", cveID))
 				if err != nil {
 					p.logger.Error("Failed to parse URN for CVE %s: %v", cveID, err)
@@ -213,22 +343,42 @@ func (p *CVEProvider) executeBatch() error {
 					if err := p.SaveCheckpoint(itemURN, true, ""); err != nil {
 						p.logger.Error("Failed to save checkpoint: %v", err)
 					} else {
-						p.logger.Info("Checkpoint saved at %s (processed: %d)", itemURN.Key(), p.totalProcessed)
+						p.logger.Info("Checkpoint saved at %s (processed: %d)", itemURN.Key(), processed)
 					}
 				}
 			}
-
-			// Update lastModStartDate for incremental updates
-			if lastMod, ok := cveMap["last_modified"].(string); ok {
-				p.lastModStartDate = lastMod
-			}
 		})
 	}
 
 	wg.Wait()
 
-	p.logger.Info("Processed CVE batch: %d items, total: %d, errors: %d", len(cves), p.totalProcessed, p.errorCount)
-	return nil
+	p.logger.Info("Processed CVE batch: %d items, total: %d, errors: %d",
+		len(cves), atomic.LoadInt64(&p.totalProcessed), atomic.LoadInt64(&p.errorCount))
+}
+
+// batchCursor returns the latest last_modified timestamp in a batch, which is
+// where the next incremental fetch starts. NVD timestamps share one format,
+// so lexical order matches chronological order.
+func batchCursor(cves []map[string]interface{}, current string) string {
+	cursor := current
+	for _, cveMap := range cves {
+		if lastMod, ok := cveMap["last_modified"].(string); ok && lastMod > cursor {
+			cursor = lastMod
+		}
+	}
+	return cursor
+}
+
+// backoffOnRateLimit moves a running provider into WAITING_BACKOFF when a
+// fetch failed because NVD rate-limited it, so no further fetches are issued
+// until the backoff has elapsed
+func (p *CVEProvider) backoffOnRateLimit(err error) {
+	if err == nil || !strings.Contains(err.Error(), "RATE_LIMITED") {
+		return
+	}
+	if rlErr := p.OnRateLimited(rateLimitBackoff); rlErr != nil {
+		p.logger.Warn("Failed to enter rate-limit backoff: %v", rlErr)
+	}
 }
 
 // saveCVE saves a CVE to local storage via RPC
@@ -317,15 +467,15 @@ func deepEqual(a, b interface{}) bool {
 		bStr, bOk := b.(string)
 		return aOk && bOk && aStr == bStr
 	case int, int32, int64:
-		aInt, aOk := a.(int64)
-		bInt, bOk := b.(int64)
+		aInt, aOk := toInt64(a)
+		bInt, bOk := toInt64(b)
 		if !aOk || !bOk {
 			return false
 		}
 		return aInt == bInt
 	case float32, float64:
-		aFloat, aOk := a.(float64)
-		bFloat, bOk := b.(float64)
+		aFloat, aOk := toFloat64(a)
+		bFloat, bOk := toFloat64(b)
 		if !aOk || !bOk {
 			return false
 		}
@@ -363,6 +513,30 @@ func deepEqual(a, b interface{}) bool {
 	}
 }
 
+// toInt64 widens any integer type deepEqual compares to int64
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// toFloat64 widens either float type deepEqual compares to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
 // loadLastCheckpoint loads the last checkpoint from storage
 func (p *CVEProvider) loadLastCheckpoint() error {
 	stats := p.GetStats()
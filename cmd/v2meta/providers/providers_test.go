@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
@@ -22,6 +23,7 @@ func testLogger() *common.Logger {
 
 // MockRPCClient implements a mock RPC client for testing
 type MockRPCClient struct {
+	mu        sync.Mutex
 	responses map[string]*subprocess.Message
 	errors    map[string]error
 	callCount map[string]int
@@ -35,8 +37,10 @@ func NewMockRPCClient() *MockRPCClient {
 	}
 }
 
-func (m *MockRPCClient) InvokeRPC(ctx context.Context, target string, method string, params interface{}) (*subprocess.Message, error) {
+func (m *MockRPCClient) InvokeRPC(ctx context.Context, target string, method string, params interface{}) (interface{}, error) {
 	key := target + "::" + method
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.callCount[key]++
 
 	if err, exists := m.errors[key]; exists {
@@ -57,7 +61,9 @@ func (m *MockRPCClient) InvokeRPC(ctx context.Context, target string, method str
 
 func (m *MockRPCClient) SetResponse(target, method string, response map[string]interface{}) {
 	key := target + "::" + method
-	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	payloadBytes, _ := json.Marshal(response)
 	msg := &subprocess.Message{
 		Type:    subprocess.MessageTypeResponse,
@@ -68,7 +74,9 @@ func (m *MockRPCClient) SetResponse(target, method string, response map[string]i
 
 func (m *MockRPCClient) SetErrorResponse(target, method string, errMsg string) {
 	key := target + "::" + method
-	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	response := map[string]interface{}{
 		"error": errMsg,
 	}
@@ -82,26 +90,27 @@ func (m *MockRPCClient) SetErrorResponse(target, method string, errMsg string) {
 
 func (m *MockRPCClient) SetError(target, method string, err error) {
 	key := target + "::" + method
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.errors[key] = err
 }
 
 func (m *MockRPCClient) GetCallCount(target, method string) int {
 	key := target + "::" + method
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.callCount[key]
 }
 
-
-// Test 1: Provider Factory Creation
+// Test 1: CVE Provider Creation
 func TestProviderFactory_CreateProvider_CVE(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CreateCVEProvider", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewCVEProvider(CVEProviderConfig{
+			ID:        "test-cve-1",
 			Storage:   nil, // Nil storage is ok for these tests
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
-		})
-
-		provider, err := factory.CreateProvider(ProviderTypeCVE, "test-cve-1", map[string]interface{}{
-			"batch_size": 50,
+			BatchSize: 50,
 		})
 
 		if err != nil {
@@ -122,17 +131,15 @@ func TestProviderFactory_CreateProvider_CVE(t *testing.T) {
 	})
 }
 
-// Test 2: Provider Factory Creation - CWE
+// Test 2: CWE Provider Creation
 func TestProviderFactory_CreateProvider_CWE(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CreateCWEProvider", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewCWEProvider(CWEProviderConfig{
+			ID:        "test-cwe-1",
 			Storage:   nil,
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
-		})
-
-		provider, err := factory.CreateProvider(ProviderTypeCWE, "test-cwe-1", map[string]interface{}{
-			"file_path": "/tmp/cwe.xml",
+			FilePath:  "/tmp/cwe.xml",
 		})
 
 		if err != nil {
@@ -145,17 +152,16 @@ func TestProviderFactory_CreateProvider_CWE(t *testing.T) {
 	})
 }
 
-// Test 3: Provider Factory Creation - CAPEC
+// Test 3: CAPEC Provider Creation
 func TestProviderFactory_CreateProvider_CAPEC(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CreateCAPECProvider", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewCAPECProvider(CAPECProviderConfig{
+			ID:        "test-capec-1",
 			Storage:   nil,
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
 		})
 
-		provider, err := factory.CreateProvider(ProviderTypeCAPEC, "test-capec-1", map[string]interface{}{})
-
 		if err != nil {
 			t.Fatalf("Failed to create CAPEC provider: %v", err)
 		}
@@ -166,17 +172,16 @@ func TestProviderFactory_CreateProvider_CAPEC(t *testing.T) {
 	})
 }
 
-// Test 4: Provider Factory Creation - ATT&CK
+// Test 4: ATT&CK Provider Creation
 func TestProviderFactory_CreateProvider_ATTACK(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CreateATTACKProvider", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewATTACKProvider(ATTACKProviderConfig{
+			ID:        "test-attack-1",
 			Storage:   nil,
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
 		})
 
-		provider, err := factory.CreateProvider(ProviderTypeATTACK, "test-attack-1", map[string]interface{}{})
-
 		if err != nil {
 			t.Fatalf("Failed to create ATT&CK provider: %v", err)
 		}
@@ -187,54 +192,11 @@ func TestProviderFactory_CreateProvider_ATTACK(t *testing.T) {
 	})
 }
 
-// Test 5: Provider Factory - Unsupported Type
-func TestProviderFactory_CreateProvider_UnsupportedType(t *testing.T) {
-	testutils.Run(t, testutils.Level1, "UnsupportedType", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
-			Storage:   nil,
-			RPCClient: NewMockRPCClient(),
-			Logger:    testLogger(),
-		})
-
-		_, err := factory.CreateProvider("unknown", "test-1", map[string]interface{}{})
-
-		if err == nil {
-			t.Error("Expected error for unsupported type, got nil")
-		}
-	})
-}
-
-// Test 6: Provider Factory - Get Supported Providers
-func TestProviderFactory_GetSupportedProviders(t *testing.T) {
-	testutils.Run(t, testutils.Level1, "GetSupportedProviders", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{})
-
-		supported := factory.GetSupportedProviders()
-
-		if len(supported) != 4 {
-			t.Errorf("Supported providers count = %d, want 4", len(supported))
-		}
-
-		expectedTypes := map[ProviderType]bool{
-			ProviderTypeCVE:    true,
-			ProviderTypeCWE:    true,
-			ProviderTypeCAPEC:  true,
-			ProviderTypeATTACK: true,
-		}
-
-		for _, providerType := range supported {
-			if !expectedTypes[providerType] {
-				t.Errorf("Unexpected provider type: %s", providerType)
-			}
-		}
-	})
-}
-
 // Test 7: CVE Provider - Basic Execution
 func TestCVEProvider_ExecuteBatch_Success(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "ExecuteBatchSuccess", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		// Mock successful CVE fetch
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
 			"cves": []map[string]interface{}{
@@ -430,7 +392,7 @@ func TestCVEProvider_SaveCheckpoint_Success(t *testing.T) {
 func TestCVEProvider_IncrementalFetching(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "IncrementalFetching", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		lastModDate := "2024-01-01T00:00:00Z"
 
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
@@ -503,7 +465,7 @@ func TestCVEProvider_GetProgress(t *testing.T) {
 func TestCVEProvider_EmptyBatch_Completion(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "EmptyBatchCompletion", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		// Return empty CVE list
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
 			"cves": []map[string]interface{}{},
@@ -549,7 +511,7 @@ func TestCVEProvider_FetchError(t *testing.T) {
 func TestCVEProvider_SaveError(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "SaveError", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
 			"cves": []map[string]interface{}{
 				{"cve_id": "CVE-2024-00001"},
@@ -582,7 +544,7 @@ func TestCVEProvider_SaveError(t *testing.T) {
 func TestCVEProvider_MissingCVEID(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "MissingCVEID", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
 			"cves": []map[string]interface{}{
 				{"description": "No CVE ID"}, // Missing cve_id
@@ -610,13 +572,13 @@ func TestCVEProvider_MissingCVEID(t *testing.T) {
 func TestCVEProvider_UpdateExistingCVE(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "UpdateExistingCVE", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		// Mock existing CVE
 		existingCVE, _ := json.Marshal(map[string]interface{}{
 			"cve_id":      "CVE-2024-00001",
 			"description": "Old description",
 		})
-		
+
 		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
 			"cves": []map[string]interface{}{
 				{
@@ -708,17 +670,15 @@ func TestCVEProvider_CustomConfiguration(t *testing.T) {
 // Test 22: CWE Provider - Creation with File Path
 func TestCWEProvider_CreationWithFilePath(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CWECreationWithFilePath", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
-			Storage:   nil,
-			RPCClient: NewMockRPCClient(),
-			Logger:    testLogger(),
-		})
-
-		provider, err := factory.CreateProvider(ProviderTypeCWE, "test-cwe", map[string]interface{}{
-			"file_path":           "/tmp/cwe.xml",
-			"batch_size":          200,
-			"failure_threshold":   0.15,
-			"checkpoint_interval": 50,
+		provider, err := NewCWEProvider(CWEProviderConfig{
+			ID:                 "test-cwe",
+			Storage:            nil,
+			RPCClient:          NewMockRPCClient(),
+			Logger:             testLogger(),
+			FilePath:           "/tmp/cwe.xml",
+			BatchSize:          200,
+			FailureThreshold:   0.15,
+			CheckpointInterval: 50,
 		})
 
 		if err != nil {
@@ -728,20 +688,22 @@ func TestCWEProvider_CreationWithFilePath(t *testing.T) {
 		if provider == nil {
 			t.Fatal("Provider is nil")
 		}
+		if provider.filePath != "/tmp/cwe.xml" || provider.batchSize != 200 || provider.checkpointInterval != 50 {
+			t.Errorf("Provider config not applied: file=%s batch=%d checkpoint=%d", provider.filePath, provider.batchSize, provider.checkpointInterval)
+		}
 	})
 }
 
 // Test 23: CAPEC Provider - Default Options
 func TestCAPECProvider_DefaultOptions(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CAPECDefaultOptions", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewCAPECProvider(CAPECProviderConfig{
+			ID:        "test-capec",
 			Storage:   nil,
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
 		})
 
-		provider, err := factory.CreateProvider(ProviderTypeCAPEC, "test-capec", map[string]interface{}{})
-
 		if err != nil {
 			t.Fatalf("Failed to create CAPEC provider: %v", err)
 		}
@@ -755,14 +717,12 @@ func TestCAPECProvider_DefaultOptions(t *testing.T) {
 // Test 24: ATT&CK Provider - Custom Batch Size
 func TestATTACKProvider_CustomBatchSize(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "ATTACKCustomBatchSize", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewATTACKProvider(ATTACKProviderConfig{
+			ID:        "test-attack",
 			Storage:   nil,
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
-		})
-
-		provider, err := factory.CreateProvider(ProviderTypeATTACK, "test-attack", map[string]interface{}{
-			"batch_size": 500,
+			BatchSize: 500,
 		})
 
 		if err != nil {
@@ -772,29 +732,8 @@ func TestATTACKProvider_CustomBatchSize(t *testing.T) {
 		if provider == nil {
 			t.Fatal("Provider is nil")
 		}
-	})
-}
-
-// Test 25: Provider Factory - Options Type Assertions
-func TestProviderFactory_OptionsTypeAssertions(t *testing.T) {
-	testutils.Run(t, testutils.Level1, "OptionsTypeAssertions", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
-			Storage:   nil,
-			RPCClient: NewMockRPCClient(),
-			Logger:    testLogger(),
-		})
-
-		// Test with wrong type for batch_size (should ignore invalid type)
-		provider, err := factory.CreateProvider(ProviderTypeCVE, "test-cve", map[string]interface{}{
-			"batch_size": "invalid", // String instead of int
-		})
-
-		if err != nil {
-			t.Fatalf("Provider creation should succeed despite invalid option: %v", err)
-		}
-
-		if provider == nil {
-			t.Fatal("Provider is nil")
+		if provider.batchSize != 500 {
+			t.Errorf("Batch size = %d, want 500", provider.batchSize)
 		}
 	})
 }
@@ -830,7 +769,7 @@ func TestDeepEqual_SimpleValues(t *testing.T) {
 func TestCVEProvider_CheckpointInterval(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "CheckpointInterval", nil, func(t *testing.T, tx *gorm.DB) {
 		mockRPC := NewMockRPCClient()
-		
+
 		// Create batch of CVEs that triggers checkpoint
 		cves := make([]map[string]interface{}, 10)
 		for i := 0; i < 10; i++ {
@@ -911,17 +850,16 @@ func TestCVEProvider_HighErrorRate(t *testing.T) {
 	})
 }
 
-// Test 30: Provider Factory - Nil Storage
+// Test 30: CVE Provider - Nil Storage
 func TestProviderFactory_NilStorage(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "NilStorage", nil, func(t *testing.T, tx *gorm.DB) {
-		factory := NewProviderFactory(FactoryConfig{
+		provider, err := NewCVEProvider(CVEProviderConfig{
+			ID:        "test-cve",
 			Storage:   nil, // Nil storage
 			RPCClient: NewMockRPCClient(),
 			Logger:    testLogger(),
 		})
 
-		provider, err := factory.CreateProvider(ProviderTypeCVE, "test-cve", map[string]interface{}{})
-
 		// Should still create provider (storage is optional for some operations)
 		if err != nil {
 			t.Fatalf("Provider creation should succeed with nil storage: %v", err)
@@ -932,3 +870,186 @@ func TestProviderFactory_NilStorage(t *testing.T) {
 		}
 	})
 }
+
+// Test 31: CVE Provider - Prefetch Pipeline Stores Each Batch Once
+func TestCVEProvider_Pipeline_StoresBatchOnce(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "PipelineStoresBatchOnce", nil, func(t *testing.T, tx *gorm.DB) {
+		mockRPC := NewMockRPCClient()
+		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
+			"cves": []map[string]interface{}{
+				{
+					"cve_id":        "CVE-2024-00001",
+					"last_modified": "2024-01-01T00:00:00Z",
+				},
+			},
+		})
+		mockRPC.SetError("local", "RPCGetCVE", fmt.Errorf("not found"))
+		mockRPC.SetResponse("local", "RPCSaveCVE", map[string]interface{}{
+			"success": true,
+		})
+
+		provider, err := NewCVEProvider(CVEProviderConfig{
+			ID:            "test-cve-pipeline",
+			RPCClient:     mockRPC,
+			Logger:        testLogger(),
+			BatchSize:     1,
+			PrefetchDepth: 2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create provider: %v", err)
+		}
+		provider.Transition(fsm.ProviderAcquiring)
+		provider.Transition(fsm.ProviderRunning)
+
+		if err := provider.executeBatch(); err != nil {
+			t.Fatalf("ExecuteBatch failed: %v", err)
+		}
+
+		// The mock always returns the same batch, so the cursor stops
+		// advancing after the first fetch and the pipeline must end there
+		if got := mockRPC.GetCallCount("local", "RPCSaveCVE"); got != 1 {
+			t.Errorf("SaveCVE call count = %d, want 1", got)
+		}
+		if provider.lastModStartDate != "2024-01-01T00:00:00Z" {
+			t.Errorf("lastModStartDate = %q, want 2024-01-01T00:00:00Z", provider.lastModStartDate)
+		}
+	})
+}
+
+// Test 32: CVE Provider - Pipeline Drains Without Storing When Not Running
+func TestCVEProvider_Pipeline_DrainsWhenNotRunning(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "PipelineDrainsWhenNotRunning", nil, func(t *testing.T, tx *gorm.DB) {
+		mockRPC := NewMockRPCClient()
+		mockRPC.SetResponse("remote", "RPCFetchCVEBatch", map[string]interface{}{
+			"cves": []map[string]interface{}{
+				{
+					"cve_id":        "CVE-2024-00002",
+					"last_modified": "2024-02-01T00:00:00Z",
+				},
+			},
+		})
+
+		provider, err := NewCVEProvider(CVEProviderConfig{
+			ID:               "test-cve-drain",
+			RPCClient:        mockRPC,
+			Logger:           testLogger(),
+			LastModStartDate: "2024-01-01T00:00:00Z",
+			PrefetchDepth:    2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create provider: %v", err)
+		}
+
+		// Provider is IDLE, so the pipeline must drain immediately
+		if err := provider.executeBatch(); err != nil {
+			t.Fatalf("ExecuteBatch failed: %v", err)
+		}
+
+		if got := mockRPC.GetCallCount("local", "RPCSaveCVE"); got != 0 {
+			t.Errorf("SaveCVE call count = %d, want 0", got)
+		}
+		if provider.lastModStartDate != "2024-01-01T00:00:00Z" {
+			t.Errorf("lastModStartDate advanced to %q without storing", provider.lastModStartDate)
+		}
+	})
+}
+
+// Test 33: CVE Provider - Rate-Limited Fetch Enters Backoff
+func TestCVEProvider_RateLimitedFetchBacksOff(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "RateLimitedFetchBacksOff", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, depth := range []int{0, 2} {
+			mockRPC := NewMockRPCClient()
+			mockRPC.SetError("remote", "RPCFetchCVEBatch", fmt.Errorf("RATE_LIMITED: retry later"))
+
+			provider, err := NewCVEProvider(CVEProviderConfig{
+				ID:            fmt.Sprintf("test-cve-backoff-%d", depth),
+				RPCClient:     mockRPC,
+				Logger:        testLogger(),
+				PrefetchDepth: depth,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			provider.Transition(fsm.ProviderAcquiring)
+			provider.Transition(fsm.ProviderRunning)
+
+			if err := provider.executeBatch(); err == nil {
+				t.Errorf("Depth %d: expected the rate-limit error returned", depth)
+			}
+			if state := provider.GetState(); state != fsm.ProviderWaitingBackoff {
+				t.Errorf("Depth %d: state = %s, want %s", depth, state, fsm.ProviderWaitingBackoff)
+			}
+
+			// Other fetch errors do not back off
+			mockRPC.SetError("remote", "RPCFetchCVEBatch", fmt.Errorf("connection reset"))
+			provider.Transition(fsm.ProviderAcquiring)
+			provider.Transition(fsm.ProviderRunning)
+			provider.executeBatch()
+			if state := provider.GetState(); state != fsm.ProviderRunning {
+				t.Errorf("Depth %d: state = %s after a plain error, want %s", depth, state, fsm.ProviderRunning)
+			}
+		}
+	})
+}
+
+// pagedCVEClient serves one CVE per fetch, each modified a day after the
+// cursor it is asked from, until pages CVEs have been served
+type pagedCVEClient struct {
+	*MockRPCClient
+	mu      sync.Mutex
+	pages   int
+	fetched int
+	saved   []string
+}
+
+func (c *pagedCVEClient) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch method {
+	case "RPCFetchCVEBatch":
+		var cves []map[string]interface{}
+		if c.fetched < c.pages {
+			c.fetched++
+			cves = append(cves, map[string]interface{}{
+				"cve_id":        fmt.Sprintf("CVE-2024-%05d", c.fetched),
+				"last_modified": fmt.Sprintf("2024-01-%02dT00:00:00Z", c.fetched),
+			})
+		}
+		payload, _ := json.Marshal(map[string]interface{}{"cves": cves})
+		return &subprocess.Message{Type: subprocess.MessageTypeResponse, Payload: payload}, nil
+	case "RPCSaveCVE":
+		c.saved = append(c.saved, params.(map[string]interface{})["cve_id"].(string))
+	}
+	return c.MockRPCClient.InvokeRPC(ctx, target, method, params)
+}
+
+// Test 34: CVE Provider - Pipeline Stores Every Prefetched Batch In Order
+func TestCVEProvider_Pipeline_StoresAllBatchesInOrder(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "PipelineStoresAllBatchesInOrder", nil, func(t *testing.T, tx *gorm.DB) {
+		client := &pagedCVEClient{MockRPCClient: NewMockRPCClient(), pages: 5}
+		client.SetError("local", "RPCGetCVE", fmt.Errorf("not found"))
+
+		provider, err := NewCVEProvider(CVEProviderConfig{
+			ID:            "test-cve-pages",
+			RPCClient:     client,
+			Logger:        testLogger(),
+			BatchSize:     1,
+			PrefetchDepth: 2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create provider: %v", err)
+		}
+		provider.Transition(fsm.ProviderAcquiring)
+		provider.Transition(fsm.ProviderRunning)
+
+		if err := provider.executeBatch(); err != nil {
+			t.Fatalf("ExecuteBatch failed: %v", err)
+		}
+		if fmt.Sprint(client.saved) != "[CVE-2024-00001 CVE-2024-00002 CVE-2024-00003 CVE-2024-00004 CVE-2024-00005]" {
+			t.Errorf("Expected each CVE stored once in order, got %v", client.saved)
+		}
+		if provider.lastModStartDate != "2024-01-05T00:00:00Z" {
+			t.Errorf("lastModStartDate = %q, want the last stored batch", provider.lastModStartDate)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+const (
+	// EventSessionStatus is pushed with the status of a subscribed run
+	// whenever its state or counts change
+	EventSessionStatus = "session_status"
+	// EventSessionEnd is pushed with the final status of a subscribed run when
+	// it terminates or is deleted; it is the last event of a subscription
+	EventSessionEnd = "session_end"
+	// MaxSessionSubscriptions caps the concurrent session event subscriptions.
+	// A subscriber that goes away without unsubscribing holds its slot until
+	// the run terminates.
+	MaxSessionSubscriptions = 16
+)
+
+// eventSender sends unsolicited messages through the broker
+type eventSender interface {
+	SendMessage(msg *subprocess.Message) error
+}
+
+// runChangeNotifier signals changes to the runs
+type runChangeNotifier interface {
+	Changes() <-chan struct{}
+}
+
+// sessionEvent is the payload of session events. Seq increases with every
+// event of a subscription, so subscribers can drop events delivered out of
+// order.
+type sessionEvent struct {
+	Seq    int                    `json:"seq"`
+	Status map[string]interface{} `json:"status"`
+}
+
+// sessionEvents pushes the status of runs to their subscribers, one watcher
+// goroutine per subscription
+type sessionEvents struct {
+	sender      eventSender
+	jobExecutor *taskflow.JobExecutor
+	changes     runChangeNotifier
+	logger      *common.Logger
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+}
+
+// newSessionEvents creates the session event subscriptions
+func newSessionEvents(sender eventSender, jobExecutor *taskflow.JobExecutor, changes runChangeNotifier, logger *common.Logger) *sessionEvents {
+	return &sessionEvents{
+		sender:      sender,
+		jobExecutor: jobExecutor,
+		changes:     changes,
+		logger:      logger,
+		subs:        make(map[string]context.CancelFunc),
+	}
+}
+
+// subscribe starts pushing the events of run to target, correlated with the
+// subscription ID. run is the status the subscriber already has.
+func (e *sessionEvents) subscribe(id, target string, run *taskflow.JobRun) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.subs[id]; exists {
+		return fmt.Errorf("subscription %s already exists", id)
+	}
+	if len(e.subs) >= MaxSessionSubscriptions {
+		return fmt.Errorf("too many session event subscriptions (max %d)", MaxSessionSubscriptions)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.subs[id] = cancel
+	go e.watch(ctx, id, target, run)
+	return nil
+}
+
+// unsubscribe stops a subscription, reporting whether it existed
+func (e *sessionEvents) unsubscribe(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cancel, exists := e.subs[id]
+	if exists {
+		cancel()
+		delete(e.subs, id)
+	}
+	return exists
+}
+
+// count returns the number of subscriptions
+func (e *sessionEvents) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.subs)
+}
+
+// watch pushes an event whenever the run changes until it terminates or the
+// subscription is cancelled
+func (e *sessionEvents) watch(ctx context.Context, id, target string, last *taskflow.JobRun) {
+	defer e.unsubscribe(id)
+
+	seq := 0
+	for {
+		// Take the change channel before reading the run, so that a change
+		// made in between is not missed
+		changed := e.changes.Changes()
+
+		run, err := e.jobExecutor.GetStatus(last.ID)
+		if err != nil {
+			// The run is gone; end with the last status seen
+			seq++
+			e.send(ctx, id, target, EventSessionEnd, seq, last)
+			return
+		}
+		if run.State.IsTerminal() {
+			seq++
+			e.send(ctx, id, target, EventSessionEnd, seq, run)
+			return
+		}
+		if runProgressed(last, run) {
+			seq++
+			e.send(ctx, id, target, EventSessionStatus, seq, run)
+			last = run
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+// runProgressed reports whether the state or counts of a run changed
+func runProgressed(before, after *taskflow.JobRun) bool {
+	return before.State != after.State ||
+		before.FetchedCount != after.FetchedCount ||
+		before.StoredCount != after.StoredCount ||
+		before.ErrorCount != after.ErrorCount
+}
+
+// send pushes one event of a subscription, unless it was cancelled: once
+// unsubscribe returns, no more events are sent
+func (e *sessionEvents) send(ctx context.Context, id, target, eventID string, seq int, run *taskflow.JobRun) {
+	activeRuns, err := e.jobExecutor.GetActiveRuns()
+	if err != nil {
+		e.logger.Warn("Failed to get active runs: %v", err)
+	}
+	payload, err := jsonutil.Marshal(sessionEvent{Seq: seq, Status: runStatus(run, runIDs(activeRuns))})
+	if err != nil {
+		e.logger.Warn(LogMsgSessionEventFailed, eventID, id, err)
+		return
+	}
+
+	msg := &subprocess.Message{
+		Type:          subprocess.MessageTypeEvent,
+		ID:            eventID,
+		Target:        target,
+		CorrelationID: id,
+		Payload:       payload,
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	if err := e.sender.SendMessage(msg); err != nil {
+		e.logger.Warn(LogMsgSessionEventFailed, eventID, id, err)
+	}
+}
+
+// createSubscribeSessionEventsHandler creates a handler that subscribes the
+// caller to the status events of a run: the one named by session_id, or else
+// the active CVE run. Events are sent to the caller as event messages whose
+// correlation ID is the subscription ID.
+func createSubscribeSessionEventsHandler(events *sessionEvents, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			SubscriptionID string `json:"subscription_id"`
+			SessionID      string `json:"session_id"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.SubscriptionID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "subscription_id is required"), nil
+		}
+
+		run, err := sessionRun(events.jobExecutor, req.SessionID)
+		if err != nil {
+			logger.Warn("Failed to get run: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get run: %v", err)), nil
+		}
+		activeRuns, err := events.jobExecutor.GetActiveRuns()
+		if err != nil {
+			logger.Warn("Failed to get active runs: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to get active runs: %v", err)), nil
+		}
+
+		if run == nil {
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"subscribed": false,
+				"status": map[string]interface{}{
+					"has_session":     false,
+					"active_sessions": runIDs(activeRuns),
+				},
+			})
+		}
+		status := runStatus(run, runIDs(activeRuns))
+		if run.State.IsTerminal() {
+			// Nothing will change any more
+			return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+				"subscribed": false,
+				"status":     status,
+			})
+		}
+
+		if err := events.subscribe(req.SubscriptionID, msg.Source, run); err != nil {
+			logger.Warn(LogMsgSessionSubscribeFailed, req.SubscriptionID, err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", err.Error()), nil
+		}
+		logger.Info(LogMsgSessionSubscribed, req.SubscriptionID, run.ID, msg.Source)
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"subscribed":      true,
+			"subscription_id": req.SubscriptionID,
+			"status":          status,
+		})
+	}
+}
+
+// createUnsubscribeSessionEventsHandler creates a handler that cancels a
+// session event subscription
+func createUnsubscribeSessionEventsHandler(events *sessionEvents, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			SubscriptionID string `json:"subscription_id"`
+		}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.SubscriptionID == "" {
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", "subscription_id is required"), nil
+		}
+
+		existed := events.unsubscribe(req.SubscriptionID)
+		if existed {
+			logger.Info(LogMsgSessionUnsubscribed, req.SubscriptionID)
+		}
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"unsubscribed": existed,
+		})
+	}
+}
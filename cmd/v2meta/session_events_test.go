@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// chanSender collects the sent messages
+type chanSender chan *subprocess.Message
+
+func (c chanSender) SendMessage(msg *subprocess.Message) error {
+	c <- msg
+	return nil
+}
+
+func sessionEventsRequest(t *testing.T, method string, params map[string]interface{}) *subprocess.Message {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal params: %v", err)
+	}
+	return &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method, Source: "access", Payload: json.RawMessage(payload)}
+}
+
+func receiveSessionEvent(t *testing.T, sent chanSender) (*subprocess.Message, sessionEvent) {
+	t.Helper()
+	select {
+	case msg := <-sent:
+		var event sessionEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		return msg, event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a session event")
+	}
+	return nil, sessionEvent{}
+}
+
+func TestSessionEvents(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "SessionEvents", nil, func(t *testing.T, tx *gorm.DB) {
+		runStore := taskflow.NewTempRunStore(t)
+		jobExecutor := taskflow.NewJobExecutor(nil, runStore, taskflow.NewTestLogger(t), 1, nil)
+		sent := make(chanSender, 16)
+		events := newSessionEvents(sent, jobExecutor, runStore, taskflow.NewTestLogger(t))
+		subscribe := createSubscribeSessionEventsHandler(events, taskflow.NewTestLogger(t))
+		unsubscribe := createUnsubscribeSessionEventsHandler(events, taskflow.NewTestLogger(t))
+
+		if _, err := runStore.CreateRun("run-1", 0, 100, taskflow.DataTypeCVE); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		if err := runStore.UpdateState("run-1", taskflow.StateRunning); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+
+		resp, _ := subscribe(context.Background(), sessionEventsRequest(t, "RPCSubscribeSessionEvents", map[string]interface{}{}))
+		if resp.Type != subprocess.MessageTypeError {
+			t.Fatalf("Expected an error without subscription_id, got %+v", resp)
+		}
+
+		resp, _ = subscribe(context.Background(), sessionEventsRequest(t, "RPCSubscribeSessionEvents", map[string]interface{}{
+			"subscription_id": "sub-1",
+			"session_id":      "run-1",
+		}))
+		var result struct {
+			Subscribed bool                   `json:"subscribed"`
+			Status     map[string]interface{} `json:"status"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil || !result.Subscribed || result.Status["session_id"] != "run-1" {
+			t.Fatalf("Expected a subscription to run-1, got %+v (%v)", resp, err)
+		}
+
+		// Progress is pushed to the subscriber
+		if err := runStore.UpdateProgress("run-1", 10, 8, 1); err != nil {
+			t.Fatalf("UpdateProgress failed: %v", err)
+		}
+		msg, event := receiveSessionEvent(t, sent)
+		if msg.ID != EventSessionStatus || msg.Target != "access" || msg.CorrelationID != "sub-1" {
+			t.Errorf("Unexpected event message %+v", msg)
+		}
+		if event.Seq != 1 || event.Status["fetched_count"] != float64(10) || event.Status["stored_count"] != float64(8) {
+			t.Errorf("Unexpected event %+v", event)
+		}
+
+		// Termination ends the subscription
+		if err := runStore.UpdateState("run-1", taskflow.StateCompleted); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+		msg, event = receiveSessionEvent(t, sent)
+		if msg.ID != EventSessionEnd || event.Seq != 2 || event.Status["state"] != string(taskflow.StateCompleted) {
+			t.Errorf("Expected the end event, got %+v %+v", msg, event)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for events.count() != 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if events.count() != 0 {
+			t.Errorf("Expected the subscription to be removed, %d left", events.count())
+		}
+
+		// A terminated run has nothing to subscribe to
+		resp, _ = subscribe(context.Background(), sessionEventsRequest(t, "RPCSubscribeSessionEvents", map[string]interface{}{
+			"subscription_id": "sub-2",
+			"session_id":      "run-1",
+		}))
+		if err := subprocess.UnmarshalPayload(resp, &result); err != nil || result.Subscribed {
+			t.Errorf("Expected no subscription to a completed run, got %+v (%v)", resp, err)
+		}
+
+		// Unsubscribing stops the events
+		if _, err := runStore.CreateRun("run-2", 0, 100, taskflow.DataTypeCVE); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		subscribe(context.Background(), sessionEventsRequest(t, "RPCSubscribeSessionEvents", map[string]interface{}{
+			"subscription_id": "sub-3",
+			"session_id":      "run-2",
+		}))
+		resp, _ = unsubscribe(context.Background(), sessionEventsRequest(t, "RPCUnsubscribeSessionEvents", map[string]interface{}{"subscription_id": "sub-3"}))
+		var unsubscribed struct {
+			Unsubscribed bool `json:"unsubscribed"`
+		}
+		if err := subprocess.UnmarshalPayload(resp, &unsubscribed); err != nil || !unsubscribed.Unsubscribed || events.count() != 0 {
+			t.Errorf("Expected sub-3 to be cancelled, got %+v (%v)", resp, err)
+		}
+		runStore.UpdateProgress("run-2", 5, 5, 0)
+		select {
+		case msg := <-sent:
+			t.Errorf("Expected no events after unsubscribing, got %+v", msg)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// DefaultTimelineLimit is the number of timeline events returned by default
+const DefaultTimelineLimit = 50
+
+// MaxTimelineLimit caps the number of timeline events of one call
+const MaxTimelineLimit = 500
+
+// timelineRecorder appends events to the activity timeline
+type timelineRecorder interface {
+	RecordEvent(eventType string, payload interface{}) error
+}
+
+// recordCVEChange records a CVE change on the timeline. The change itself
+// already succeeded, so a failure to record it is only logged.
+func recordCVEChange(timeline timelineRecorder, logger *common.Logger, cveID, action string) {
+	event := taskflow.CVEChangeEvent{CVEID: cveID, Action: action}
+	if err := timeline.RecordEvent(taskflow.EventTypeCVEChange, event); err != nil {
+		logger.Warn(LogMsgTimelineRecordFailed, taskflow.EventTypeCVEChange, err)
+	}
+}
+
+// createGetTimelineHandler creates a handler for RPCGetTimeline, the
+// activity feed merging run and CVE change events in recording order
+func createGetTimelineHandler(runStore *taskflow.RunStore, logger *common.Logger) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		logger.Debug(LogMsgRPCHandlerCalled, "RPCGetTimeline")
+
+		var req struct {
+			Since string `json:"since"`
+			Limit int    `json:"limit"`
+		}
+		if len(msg.Payload) > 0 {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				logger.Warn("Failed to parse request: %v", err)
+				return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.Limit <= 0 {
+			req.Limit = DefaultTimelineLimit
+		}
+		if req.Limit > MaxTimelineLimit {
+			req.Limit = MaxTimelineLimit
+		}
+
+		events, next, err := runStore.ListTimeline(req.Since, req.Limit)
+		if err != nil {
+			logger.Warn("Failed to list timeline: %v", err)
+			return subprocess.NewErrorResponseWithPrefix(msg, "meta", fmt.Sprintf("failed to list timeline: %v", err)), nil
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"events":      events,
+			"next_cursor": next,
+		})
+	}
+}
@@ -18,6 +18,10 @@ const (
 	ErrMsgFailedFetchCount      = "failed to fetch CVE count: %v"
 	ErrMsgFailedMarshalResult   = "failed to marshal result: %v"
 	ErrMsgFailedFetchCVEs       = "failed to fetch CVEs: %v"
+	ErrMsgLastModStartRequired  = "last_mod_start_date is required"
+	ErrMsgEPSSRateLimited       = "EPSS_RATE_LIMITED: FIRST EPSS API rate limit exceeded (HTTP 429)"
+	ErrMsgFailedFetchEPSS       = "failed to fetch EPSS: %v"
+	ErrMsgFailedFetchKEV        = "failed to fetch KEV catalog: %v"
 
 	// Service lifecycle messages
 	LogMsgServiceReady            = "[remote] Remote service ready and accepting requests"
@@ -34,6 +38,20 @@ const (
 	LogMsgAPIKeyDetected = "[remote] NVD API key detected in environment"
 	LogMsgAPIKeyNotSet   = "[remote] NVD API key not set in environment"
 	LogMsgFetcherCreated = "[remote] CVE fetcher created with API key: %t"
+	LogMsgFetcherMode    = "[remote] CVE fetcher mode: %s (fixtures: %s)"
+	LogMsgFetcherModeBad = "[remote] %v; using live mode"
+	LogMsgNVDBaseURL     = "[remote] CVE fetcher using NVD base URL: %s"
+	LogMsgNVDBaseURLBad  = "[remote] %v; using the public NVD API"
+
+	// NVD rate limit messages
+	LogMsgNVDRateLimit    = "[remote] NVD rate limit: %d requests per %s, shared by all runs"
+	LogMsgNVDRateLimitBad = "[remote] %v; using %d requests per %s"
+	LogMsgNVDRateLimitOff = "[remote] NVD rate limit disabled"
+
+	// CVE cache messages
+	LogMsgCVECacheTTL      = "[remote] CVE cache TTL: %s"
+	LogMsgCVECacheTTLBad   = "[remote] %v; using the default TTL of %s"
+	LogMsgCVECacheDisabled = "[remote] CVE cache disabled"
 
 	// RPC handler messages
 	LogMsgRPCHandlerRegistered = "[remote] RPC handler registered: %s"
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/cyw0ng95/v2e/pkg/cve"
@@ -20,7 +21,7 @@ import (
 // newTestFetcher returns a fetcher configured to talk to serverURL by rewriting
 // the internal resty client and baseURL via reflection (test-only shim).
 func newTestFetcher(serverURL string) *remote.Fetcher {
-	f := remote.NewFetcher("")
+	f := remote.NewFetcher("", remote.WithRateLimitBackoff(time.Millisecond, time.Millisecond))
 	if serverURL == "" {
 		return f
 	}
@@ -208,3 +209,171 @@ func TestCreateFetchCVEsHandler_Error(t *testing.T) {
 	})
 
 }
+
+func TestCreateFetchCVEsModifiedHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateFetchCVEsModifiedHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("lastModStartDate") != "2024-01-01T00:00:00.000+00:00" || q.Get("lastModEndDate") != "2024-02-01T00:00:00.000+00:00" {
+				t.Errorf("unexpected window: %s", r.URL.RawQuery)
+			}
+			resp := cve.CVEResponse{TotalResults: 1, ResultsPerPage: 100, StartIndex: 0}
+			json.NewEncoder(w).Encode(resp)
+		}))
+		defer server.Close()
+		h := createFetchCVEsModifiedHandler(newTestFetcher(server.URL))
+
+		payload, _ := json.Marshal(map[string]string{
+			"last_mod_start_date": "2024-01-01T00:00:00Z",
+			"last_mod_end_date":   "2024-02-01T00:00:00Z",
+		})
+		msg := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "fetch-modified", Payload: payload}
+		resp, err := h(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v", resp)
+		}
+
+		// The start of the window is required
+		resp, _ = h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "fetch-modified"})
+		if resp.Type != subprocess.MessageTypeError || resp.Error != ErrMsgLastModStartRequired {
+			t.Fatalf("expected missing start error, got %+v", resp)
+		}
+	})
+}
+
+func TestCreateGetEPSSHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateGetEPSSHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("cve") == "CVE-2024-0001" {
+				w.Write([]byte(`{"status":"OK","data":[{"cve":"CVE-2024-0001","epss":"0.912","percentile":"0.998","date":"2024-03-01"}]}`))
+				return
+			}
+			w.Write([]byte(`{"status":"OK","data":[]}`))
+		}))
+		defer server.Close()
+		h := createGetEPSSHandler(remote.NewFetcher("", remote.WithEPSSBaseURL(server.URL)))
+
+		call := func(cveID string) map[string]interface{} {
+			t.Helper()
+			payload, _ := json.Marshal(map[string]string{"cve_id": cveID})
+			resp, err := h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetEPSS", Payload: payload})
+			if err != nil || resp.Type != subprocess.MessageTypeResponse {
+				t.Fatalf("expected response, got %+v %v", resp, err)
+			}
+			var result map[string]interface{}
+			json.Unmarshal(resp.Payload, &result)
+			return result
+		}
+
+		result := call("CVE-2024-0001")
+		epss, _ := result["epss"].(map[string]interface{})
+		if result["found"] != true || epss["score"] != 0.912 || epss["percentile"] != 0.998 {
+			t.Errorf("unexpected EPSS result %+v", result)
+		}
+		if result := call("CVE-2024-9999"); result["found"] != false || result["epss"] != nil {
+			t.Errorf("expected a CVE without a score to be not found, got %+v", result)
+		}
+
+		resp, _ := h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetEPSS", Payload: []byte(`{}`)})
+		if resp.Type != subprocess.MessageTypeError || resp.Error != ErrMsgCVEIDRequired {
+			t.Fatalf("expected validation error, got %+v", resp)
+		}
+	})
+}
+
+func TestCreateFetchKEVHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateFetchKEVHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/kev.json" {
+				w.Write([]byte(`{"catalogVersion":"2024.03.01","count":1,"vulnerabilities":[{"cveID":"CVE-2024-0001","dateAdded":"2024-03-01","dueDate":"2024-03-22"}]}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		h := createFetchKEVHandler(remote.NewFetcher("", remote.WithKEVFeedURL(server.URL+"/kev.json")))
+		resp, err := h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCFetchKEV"})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v %v", resp, err)
+		}
+		var catalog cve.KEVCatalog
+		if err := json.Unmarshal(resp.Payload, &catalog); err != nil {
+			t.Fatalf("failed to decode catalog: %v", err)
+		}
+		if catalog.CatalogVersion != "2024.03.01" || len(catalog.Vulnerabilities) != 1 || catalog.Vulnerabilities[0].DueDate != "2024-03-22" {
+			t.Errorf("unexpected catalog %+v", catalog)
+		}
+
+		h = createFetchKEVHandler(remote.NewFetcher("", remote.WithKEVFeedURL(server.URL+"/missing.json")))
+		resp, _ = h(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCFetchKEV"})
+		if resp.Type != subprocess.MessageTypeError {
+			t.Fatalf("expected an error for a failed fetch, got %+v", resp)
+		}
+	})
+}
+
+func TestCreateGetCacheStatsHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateGetCacheStatsHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(testutils.MakeCVEResponseJSON("CVE-2024-0001", 1))
+		}))
+		defer server.Close()
+
+		fetcher := remote.NewFetcher("", remote.WithBaseURL(server.URL), remote.WithCVECacheTTL(time.Minute))
+		get := createGetCVEByIDHandler(fetcher)
+		for i := 0; i < 2; i++ {
+			resp, _ := get(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCVEByID", Payload: []byte(`{"cve_id":"CVE-2024-0001"}`)})
+			if resp.Type != subprocess.MessageTypeResponse {
+				t.Fatalf("expected response, got %+v", resp)
+			}
+		}
+
+		resp, err := createGetCacheStatsHandler(fetcher)(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCacheStats"})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v %v", resp, err)
+		}
+		var stats remote.CacheStats
+		if err := json.Unmarshal(resp.Payload, &stats); err != nil {
+			t.Fatalf("failed to decode stats: %v", err)
+		}
+		if !stats.Enabled || stats.TTLSeconds != 60 || stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+}
+
+func TestCreateGetRateLimitStatsHandler(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCreateGetRateLimitStatsHandler", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(testutils.MakeCVEResponseJSON("CVE-2024-0001", 1))
+		}))
+		defer server.Close()
+
+		fetcher := remote.NewFetcher("", remote.WithBaseURL(server.URL), remote.WithCVECacheTTL(0), remote.WithRateLimit(remote.UpstreamNVD, 3, time.Hour))
+		resp, _ := createGetCVEByIDHandler(fetcher)(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetCVEByID", Payload: []byte(`{"cve_id":"CVE-2024-0001"}`)})
+		if resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v", resp)
+		}
+
+		resp, err := createGetRateLimitStatsHandler(fetcher)(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetRateLimitStats"})
+		if err != nil || resp.Type != subprocess.MessageTypeResponse {
+			t.Fatalf("expected response, got %+v %v", resp, err)
+		}
+		var result struct {
+			Limiters []remote.RateLimiterStats `json:"limiters"`
+		}
+		if err := json.Unmarshal(resp.Payload, &result); err != nil {
+			t.Fatalf("failed to decode stats: %v", err)
+		}
+		if len(result.Limiters) != 1 {
+			t.Fatalf("expected the NVD limiter only, got %+v", result.Limiters)
+		}
+		if l := result.Limiters[0]; l.Upstream != remote.UpstreamNVD || l.Limit != 3 || l.WindowSeconds != 3600 || l.Available != 2 {
+			t.Errorf("unexpected stats %+v", l)
+		}
+	})
+}
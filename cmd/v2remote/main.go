@@ -11,16 +11,20 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/cve/remote"
 	"github.com/cyw0ng95/v2e/pkg/cwe"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 	ssgremote "github.com/cyw0ng95/v2e/pkg/ssg/remote"
 )
 
@@ -40,9 +44,58 @@ func main() {
 		logger.Info(LogMsgAPIKeyNotSet)
 	}
 
+	// Point the fetcher at an NVD mirror if configured (optional)
+	var fetcherOpts []remote.FetcherOption
+	if raw := os.Getenv("NVD_BASE_URL"); raw != "" {
+		if baseURL, err := remote.ParseBaseURL(raw); err != nil {
+			logger.Warn(LogMsgNVDBaseURLBad, err)
+		} else {
+			fetcherOpts = append(fetcherOpts, remote.WithBaseURL(baseURL))
+		}
+	}
+
+	// Cache fetched CVEs for the configured TTL (optional)
+	if raw := os.Getenv(remote.EnvCVECacheTTL); raw != "" {
+		if ttl, err := remote.ParseCVECacheTTL(raw); err != nil {
+			logger.Warn(LogMsgCVECacheTTLBad, err, remote.DefaultCVECacheTTL)
+		} else {
+			fetcherOpts = append(fetcherOpts, remote.WithCVECacheTTL(ttl))
+		}
+	}
+
+	// Share NVD's quota across all runs; the default depends on the API key
+	nvdLimit, nvdWindow := remote.DefaultNVDRateLimit(apiKey != ""), remote.NVDRateWindow
+	if raw := os.Getenv(remote.EnvNVDRateLimit); raw != "" {
+		if limit, window, err := remote.ParseRateLimit(raw); err != nil {
+			logger.Warn(LogMsgNVDRateLimitBad, err, nvdLimit, nvdWindow)
+		} else {
+			nvdLimit, nvdWindow = limit, window
+		}
+	}
+	fetcherOpts = append(fetcherOpts, remote.WithRateLimit(remote.UpstreamNVD, nvdLimit, nvdWindow))
+
 	// Create CVE fetcher
-	fetcher := remote.NewFetcher(apiKey)
+	fetcher := remote.NewFetcher(apiKey, fetcherOpts...)
 	logger.Info(LogMsgFetcherCreated, apiKey != "")
+	if fetcher.BaseURL() != cve.NVDAPIURL {
+		logger.Info(LogMsgNVDBaseURL, fetcher.BaseURL())
+	}
+	if nvdLimit > 0 {
+		logger.Info(LogMsgNVDRateLimit, nvdLimit, nvdWindow)
+	} else {
+		logger.Info(LogMsgNVDRateLimitOff)
+	}
+	if stats := fetcher.CacheStats(); stats.Enabled {
+		logger.Info(LogMsgCVECacheTTL, time.Duration(stats.TTLSeconds)*time.Second)
+	} else {
+		logger.Info(LogMsgCVECacheDisabled)
+	}
+	if _, err := remote.ParseFetcherMode(os.Getenv(remote.EnvFetcherMode)); err != nil {
+		logger.Warn(LogMsgFetcherModeBad, err)
+	}
+	if fetcher.Mode() != remote.ModeLive {
+		logger.Info(LogMsgFetcherMode, fetcher.Mode(), fetcher.FixturesDir())
+	}
 
 	// Register RPC handlers
 	logger.Info("Registering RPC handlers...")
@@ -52,8 +105,18 @@ func main() {
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCVECnt")
 	sp.RegisterHandler("RPCFetchCVEs", createFetchCVEsHandler(fetcher))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCFetchCVEs")
+	sp.RegisterHandler("RPCFetchCVEsModified", createFetchCVEsModifiedHandler(fetcher))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCFetchCVEsModified")
 	sp.RegisterHandler("RPCFetchViews", createFetchViewsHandler())
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCFetchViews")
+	sp.RegisterHandler("RPCGetEPSS", createGetEPSSHandler(fetcher))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetEPSS")
+	sp.RegisterHandler("RPCFetchKEV", createFetchKEVHandler(fetcher))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCFetchKEV")
+	sp.RegisterHandler("RPCGetCacheStats", createGetCacheStatsHandler(fetcher))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetCacheStats")
+	sp.RegisterHandler("RPCGetRateLimitStats", createGetRateLimitStatsHandler(fetcher))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetRateLimitStats")
 
 	// Create SSG Git client and register handlers
 	ssgGitClient := ssgremote.NewGitClient(ssgremote.DefaultRepoURL(), ssgremote.DefaultRepoPath())
@@ -184,6 +247,7 @@ func createGetCVEByIDHandler(fetcher *remote.Fetcher) subprocess.Handler {
 		// Parse the request payload
 		var req struct {
 			CVEID string `json:"cve_id"`
+			Fresh bool   `json:"fresh"`
 		}
 		if errMsg := subprocess.ParseRequest(msg, &req); errMsg != nil {
 			return errMsg, nil
@@ -194,11 +258,15 @@ func createGetCVEByIDHandler(fetcher *remote.Fetcher) subprocess.Handler {
 			return errMsg, nil
 		}
 
-		// Fetch CVE from NVD
-		response, err := fetcher.FetchCVEByID(req.CVEID)
+		// Fetch CVE from NVD, or from the cache unless fresh data is asked for
+		fetch := fetcher.FetchCVEByIDContext
+		if req.Fresh {
+			fetch = fetcher.FetchCVEByIDFresh
+		}
+		response, err := fetch(ctx, req.CVEID)
 		if err != nil {
 			// Check if this is a rate limit error
-			if err == remote.ErrRateLimited {
+			if errors.Is(err, remote.ErrRateLimited) {
 				return subprocess.NewErrorResponse(msg, ErrMsgNVDRateLimited), nil
 			}
 			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchCVE, err)), nil
@@ -208,6 +276,66 @@ func createGetCVEByIDHandler(fetcher *remote.Fetcher) subprocess.Handler {
 	}
 }
 
+// createGetEPSSHandler creates a handler for RPCGetEPSS. A CVE without an
+// EPSS score is answered with found=false rather than an error.
+func createGetEPSSHandler(fetcher *remote.Fetcher) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			CVEID string `json:"cve_id"`
+		}
+		if errMsg := subprocess.ParseRequest(msg, &req); errMsg != nil {
+			return errMsg, nil
+		}
+		if errMsg := subprocess.RequireField(msg, req.CVEID, "cve_id"); errMsg != nil {
+			return errMsg, nil
+		}
+
+		epss, err := fetcher.FetchEPSSContext(ctx, req.CVEID)
+		if err != nil {
+			if errors.Is(err, remote.ErrRateLimited) {
+				return subprocess.NewErrorResponse(msg, ErrMsgEPSSRateLimited), nil
+			}
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchEPSS, err)), nil
+		}
+
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"cve_id": req.CVEID,
+			"found":  epss != nil,
+			"epss":   epss,
+		})
+	}
+}
+
+// createFetchKEVHandler creates a handler for RPCFetchKEV, which fetches the
+// whole CISA Known Exploited Vulnerabilities catalog
+func createFetchKEVHandler(fetcher *remote.Fetcher) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		catalog, err := fetcher.FetchKEVContext(ctx)
+		if err != nil {
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchKEV, err)), nil
+		}
+		return subprocess.NewSuccessResponse(msg, catalog)
+	}
+}
+
+// createGetCacheStatsHandler creates a handler for RPCGetCacheStats, which
+// reports the hits and misses of the fetcher's CVE cache
+func createGetCacheStatsHandler(fetcher *remote.Fetcher) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		return subprocess.NewSuccessResponse(msg, fetcher.CacheStats())
+	}
+}
+
+// createGetRateLimitStatsHandler creates a handler for RPCGetRateLimitStats,
+// which reports the state of the rate limiter of each upstream for debugging
+func createGetRateLimitStatsHandler(fetcher *remote.Fetcher) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"limiters": fetcher.RateLimiterStats(),
+		})
+	}
+}
+
 // createGetCVECntHandler creates a handler for RPCGetCVECnt
 func createGetCVECntHandler(fetcher *remote.Fetcher) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
@@ -229,10 +357,10 @@ func createGetCVECntHandler(fetcher *remote.Fetcher) subprocess.Handler {
 		}
 
 		// Fetch CVEs to get the total count
-		response, err := fetcher.FetchCVEs(req.StartIndex, req.ResultsPerPage)
+		response, err := fetcher.FetchCVEsContext(ctx, req.StartIndex, req.ResultsPerPage)
 		if err != nil {
 			// Check if this is a rate limit error
-			if err == remote.ErrRateLimited {
+			if errors.Is(err, remote.ErrRateLimited) {
 				return subprocess.NewErrorResponse(msg, ErrMsgNVDRateLimited), nil
 			}
 			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchCount, err)), nil
@@ -268,10 +396,36 @@ func createFetchCVEsHandler(fetcher *remote.Fetcher) subprocess.Handler {
 		}
 
 		// Fetch CVEs from NVD
-		response, err := fetcher.FetchCVEs(req.StartIndex, req.ResultsPerPage)
+		response, err := fetcher.FetchCVEsContext(ctx, req.StartIndex, req.ResultsPerPage)
 		if err != nil {
 			// Check if this is a rate limit error
-			if err == remote.ErrRateLimited {
+			if errors.Is(err, remote.ErrRateLimited) {
+				return subprocess.NewErrorResponse(msg, ErrMsgNVDRateLimited), nil
+			}
+			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchCVEs, err)), nil
+		}
+
+		return subprocess.NewSuccessResponse(msg, response)
+	}
+}
+
+// createFetchCVEsModifiedHandler creates a handler that fetches a page of the
+// CVEs last modified within a window, for incremental syncs
+func createFetchCVEsModifiedHandler(fetcher *remote.Fetcher) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		req := rpc.FetchCVEsModifiedParams{ResultsPerPage: 100}
+		if msg.Payload != nil {
+			if err := subprocess.UnmarshalPayload(msg, &req); err != nil {
+				return subprocess.NewErrorResponse(msg, fmt.Sprintf("failed to parse request: %v", err)), nil
+			}
+		}
+		if req.LastModStartDate.IsZero() {
+			return subprocess.NewErrorResponse(msg, ErrMsgLastModStartRequired), nil
+		}
+
+		response, err := fetcher.FetchCVEsModifiedSinceContext(ctx, req.StartIndex, req.ResultsPerPage, req.LastModStartDate, req.LastModEndDate)
+		if err != nil {
+			if errors.Is(err, remote.ErrRateLimited) {
 				return subprocess.NewErrorResponse(msg, ErrMsgNVDRateLimited), nil
 			}
 			return subprocess.NewErrorResponse(msg, fmt.Sprintf(ErrMsgFailedFetchCVEs, err)), nil
@@ -41,6 +41,8 @@ const (
 	LogMsgLoadAvgCollected         = "[sysmon] Load average collected: %v"
 	LogMsgUptimeCollected          = "[sysmon] Uptime collected: %.0fs"
 	LogMsgDiskUsageCollected       = "[sysmon] Disk usage collected: used=%d, total=%d"
+	LogMsgDiskUsageFailed          = "[sysmon] Disk usage of %s omitted: %v"
+	LogMsgDiskPathsConfigured      = "[sysmon] Disk usage reported for paths: %v"
 	LogMsgSwapUsageCollected       = "[sysmon] Swap usage collected: %d"
 	LogMsgNetworkUsageCollected    = "[sysmon] Network usage collected: rx=%d, tx=%d"
 	LogMsgResourceThresholdCrossed = "[sysmon] Resource threshold crossed: %s, value=%.2f"
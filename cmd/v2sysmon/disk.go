@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/procfs"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// diskPathsFromEnv returns the paths whose disk usage is reported: "/" and
+// the comma-separated paths of SYSMON_DISK_PATHS, e.g. the directory holding
+// cve.db when it is on another partition
+func diskPathsFromEnv() []string {
+	return parseDiskPaths(os.Getenv("SYSMON_DISK_PATHS"))
+}
+
+// parseDiskPaths returns "/" followed by the comma-separated paths of value,
+// without blanks and duplicates
+func parseDiskPaths(value string) []string {
+	paths := []string{"/"}
+	seen := map[string]bool{"/": true}
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// collectDiskUsage returns the used and total bytes of the filesystem of
+// each path, keyed by path. A path that cannot be read, e.g. one that does
+// not exist, is left out with a warning.
+func collectDiskUsage(logger *common.Logger, paths []string) map[string]map[string]uint64 {
+	disk := make(map[string]map[string]uint64, len(paths))
+	for _, p := range paths {
+		used, total, err := procfs.ReadDiskUsage(p)
+		if err != nil {
+			logger.Warn(LogMsgDiskUsageFailed, p, err)
+			continue
+		}
+		disk[p] = map[string]uint64{"used": used, "total": total}
+	}
+	return disk
+}
+
+// createGetDiskUsageByPathHandler creates a handler for RPCGetDiskUsageByPath,
+// which reports the disk usage of the given paths, or of the configured ones
+func createGetDiskUsageByPathHandler(logger *common.Logger, diskPaths []string) subprocess.Handler {
+	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+		var req struct {
+			Paths []string `json:"paths"`
+		}
+		if msg.Payload != nil {
+			if errResp := subprocess.ParseRequest(msg, &req); errResp != nil {
+				return errResp, nil
+			}
+		}
+		paths := diskPaths
+		if len(req.Paths) > 0 {
+			paths = req.Paths
+		}
+		return subprocess.NewSuccessResponse(msg, map[string]interface{}{
+			"disk": collectDiskUsage(logger, paths),
+		})
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/common/procfs"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 	"github.com/cyw0ng95/v2e/pkg/rpc"
 )
@@ -21,10 +23,15 @@ func main() {
 	logger.Info(LogMsgRPCClientCreated)
 	rpcClient := rpc.NewClient(sp, logger, rpc.DefaultRPCTimeout)
 
+	diskPaths := diskPathsFromEnv()
+	logger.Info(LogMsgDiskPathsConfigured, diskPaths)
+
 	// Register RPC handler for system metrics (pass rpcClient so we can query broker)
-	sp.RegisterHandler("RPCGetSysMetrics", createGetSysMetricsHandler(logger, rpcClient))
+	sp.RegisterHandler("RPCGetSysMetrics", createGetSysMetricsHandler(logger, rpcClient, diskPaths))
 	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetSysMetrics")
 	logger.Info(LogMsgRegisteredSysMetrics)
+	sp.RegisterHandler("RPCGetDiskUsageByPath", createGetDiskUsageByPathHandler(logger, diskPaths))
+	logger.Info(LogMsgRPCHandlerRegistered, "RPCGetDiskUsageByPath")
 
 	logger.Info(LogMsgServiceStarted)
 	logger.Info(LogMsgServiceReady)
@@ -34,13 +41,14 @@ func main() {
 	logger.Info(LogMsgServiceShutdownComplete)
 }
 
-// createGetSysMetricsHandler creates a handler for RPCGetSysMetrics
-func createGetSysMetricsHandler(logger *common.Logger, rpcClient *rpc.Client) subprocess.Handler {
+// createGetSysMetricsHandler creates a handler for RPCGetSysMetrics, which
+// reports the disk usage of diskPaths
+func createGetSysMetricsHandler(logger *common.Logger, rpcClient *rpc.Client, diskPaths []string) subprocess.Handler {
 	return func(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 		logger.Info(LogMsgStartingGetSysMetrics, msg.CorrelationID)
 		logger.Info(LogMsgSysMetricsInvoked, msg.ID, msg.CorrelationID)
 		logger.Info(LogMsgMetricCollectionStarted)
-		metrics, err := collectMetrics()
+		metrics, err := collectMetrics(logger, diskPaths)
 		logger.Info(LogMsgMetricCollectionCompleted)
 		if err != nil {
 			logger.Warn(LogMsgFailedCollectMetrics, err)
@@ -56,7 +64,7 @@ func createGetSysMetricsHandler(logger *common.Logger, rpcClient *rpc.Client) su
 			if rpcErr != nil {
 				logger.Info(LogMsgFailedFetchBrokerStats, rpcErr)
 			} else if resp != nil && len(resp.Payload) > 0 {
-				var msgStats map[string]interface{}
+				var msgStats proc.MessageStatsResponse
 				if err := subprocess.UnmarshalFast(resp.Payload, &msgStats); err != nil {
 					logger.Info(LogMsgFailedUnmarshalStats, err)
 				} else {
@@ -76,7 +84,7 @@ func createGetSysMetricsHandler(logger *common.Logger, rpcClient *rpc.Client) su
 	}
 }
 
-func collectMetrics() (map[string]interface{}, error) {
+func collectMetrics(logger *common.Logger, diskPaths []string) (map[string]interface{}, error) {
 	cpuUsage, err := procfs.ReadCPUUsage()
 	if err != nil {
 		return nil, err
@@ -96,11 +104,13 @@ func collectMetrics() (map[string]interface{}, error) {
 	if up, err := procfs.ReadUptime(); err == nil {
 		m["uptime"] = up
 	}
-	if used, total, err := procfs.ReadDiskUsage("/"); err == nil {
-		// provide object-style disk info keyed by mount path, and keep totals for compatibility
-		m["disk"] = map[string]map[string]uint64{"/": {"used": used, "total": total}}
-		m["disk_usage"] = used
-		m["disk_total"] = total
+	// provide object-style disk info keyed by mount path, and keep the totals
+	// of "/" for compatibility
+	disk := collectDiskUsage(logger, diskPaths)
+	m["disk"] = disk
+	if root, ok := disk["/"]; ok {
+		m["disk_usage"] = root["used"]
+		m["disk_total"] = root["total"]
 	}
 	if swap, err := procfs.ReadSwapUsage(); err == nil {
 		m["swap_usage"] = swap
@@ -122,6 +132,8 @@ func collectMetrics() (map[string]interface{}, error) {
 		m["network"] = netMap
 		m["net_rx"] = totalRx
 		m["net_tx"] = totalTx
+		// per-interface rates since the previous collection, in bytes/s
+		m["net_rx_rate"], m["net_tx_rate"] = netRates.update(time.Now(), netMap)
 	}
 	return m, nil
 }
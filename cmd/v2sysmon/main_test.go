@@ -3,11 +3,18 @@ package main
 import (
 "gorm.io/gorm"
 "github.com/cyw0ng95/v2e/pkg/testutils"
+	"bytes"
 	"context"
 	"encoding/json"
+	"math"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -103,3 +110,138 @@ func TestRPCGetSysMetrics(t *testing.T) {
 	})
 
 }
+
+// brokerStub answers every request written by the sysmon subprocess with the
+// given message stats, as the broker would
+type brokerStub struct {
+	client *rpc.Client
+	stats  proc.MessageStatsResponse
+	buf    bytes.Buffer
+}
+
+func (b *brokerStub) Write(p []byte) (int, error) {
+	b.buf.Write(p)
+	for {
+		line, err := b.buf.ReadBytes('\n')
+		if err != nil {
+			b.buf.Write(line)
+			return len(p), nil
+		}
+		var req subprocess.Message
+		if err := json.Unmarshal(line, &req); err != nil {
+			return len(p), err
+		}
+		resp, err := subprocess.NewSuccessResponse(&req, b.stats)
+		if err != nil {
+			return len(p), err
+		}
+		b.client.HandleResponse(context.Background(), resp)
+	}
+}
+
+func TestRPCGetSysMetrics_LargeBrokerCounts(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestRPCGetSysMetrics_LargeBrokerCounts", nil, func(t *testing.T, tx *gorm.DB) {
+		// 2^53 + 1 is the smallest integer float64 cannot represent
+		const large = int64(1)<<53 + 1
+
+		sp := subprocess.New("sysmon")
+		logger := common.NewLogger(os.Stderr, "[SYSMON] ", common.ErrorLevel)
+		client := rpc.NewClient(sp, logger, time.Second)
+		sp.SetOutput(&brokerStub{client: client, stats: proc.MessageStatsResponse{
+			Total:      proc.MessageStats{TotalSent: large, TotalReceived: large + 2},
+			PerProcess: map[string]proc.MessageStats{"remote": {RequestCount: large}},
+			Wire:       proc.WireStats{TotalWireBytes: large},
+		}})
+
+		handler := createGetSysMetricsHandler(logger, client, []string{"/"})
+		response, err := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetSysMetrics"})
+		assert.NoError(t, err)
+		assert.Equal(t, subprocess.MessageTypeResponse, response.Type)
+
+		var metrics struct {
+			MessageStats proc.MessageStatsResponse `json:"message_stats"`
+		}
+		assert.NoError(t, subprocess.UnmarshalPayload(response, &metrics))
+		assert.Equal(t, large, metrics.MessageStats.Total.TotalSent)
+		assert.Equal(t, large+2, metrics.MessageStats.Total.TotalReceived)
+		assert.Equal(t, large, metrics.MessageStats.PerProcess["remote"].RequestCount)
+		assert.Equal(t, large, metrics.MessageStats.Wire.TotalWireBytes)
+	})
+}
+
+func TestNetRateTracker(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestNetRateTracker", nil, func(t *testing.T, tx *gorm.DB) {
+		tracker := &netRateTracker{}
+		start := time.Unix(1700000000, 0)
+
+		// First sample: no previous counters, so zero rates; lo is skipped
+		rx, txRates := tracker.update(start, map[string]map[string]uint64{
+			"eth0": {"rx": 1000, "tx": 500},
+			"lo":   {"rx": 9999, "tx": 9999},
+		})
+		assert.Equal(t, map[string]float64{"eth0": 0}, rx)
+		assert.Equal(t, map[string]float64{"eth0": 0}, txRates)
+
+		// Two seconds later; wlan0 appears and starts at zero
+		rx, txRates = tracker.update(start.Add(2*time.Second), map[string]map[string]uint64{
+			"eth0":  {"rx": 5000, "tx": 1500},
+			"wlan0": {"rx": 100, "tx": 100},
+			"lo":    {"rx": 99999, "tx": 99999},
+		})
+		assert.Equal(t, map[string]float64{"eth0": 2000, "wlan0": 0}, rx)
+		assert.Equal(t, map[string]float64{"eth0": 500, "wlan0": 0}, txRates)
+
+		// eth0 rx wraps around 32 bits; wlan0 is reset
+		tracker.prev.counters["eth0"]["rx"] = math.MaxUint32 - 99
+		tracker.prev.counters["wlan0"]["tx"] = math.MaxUint32 + 1000
+		rx, txRates = tracker.update(start.Add(3*time.Second), map[string]map[string]uint64{
+			"eth0":  {"rx": 100, "tx": 1500},
+			"wlan0": {"rx": 300, "tx": 10},
+		})
+		assert.Equal(t, 200.0, rx["eth0"])
+		assert.Equal(t, 0.0, txRates["eth0"])
+		assert.Equal(t, 200.0, rx["wlan0"])
+		assert.Equal(t, 0.0, txRates["wlan0"])
+	})
+}
+
+func TestParseDiskPaths(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseDiskPaths", nil, func(t *testing.T, tx *gorm.DB) {
+		assert.Equal(t, []string{"/"}, parseDiskPaths(""))
+		assert.Equal(t, []string{"/", "/var/lib/v2e", "/data"}, parseDiskPaths(" /var/lib/v2e, ,/data,/,/data"))
+	})
+}
+
+func TestRPCGetDiskUsageByPath(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRPCGetDiskUsageByPath", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(os.Stderr, "[SYSMON] ", common.ErrorLevel)
+		dir := t.TempDir()
+		missing := dir + "/missing"
+		handler := createGetDiskUsageByPathHandler(logger, []string{"/", dir, missing})
+
+		var result struct {
+			Disk map[string]map[string]uint64 `json:"disk"`
+		}
+		response, err := handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetDiskUsageByPath"})
+		assert.NoError(t, err)
+		assert.NoError(t, subprocess.UnmarshalPayload(response, &result))
+		assert.Len(t, result.Disk, 2, "the missing path is omitted")
+		assert.NotZero(t, result.Disk[dir]["total"])
+		assert.Contains(t, result.Disk, "/")
+
+		// Requested paths replace the configured ones
+		payload, _ := json.Marshal(map[string][]string{"paths": {dir, missing}})
+		response, _ = handler(context.Background(), &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: "RPCGetDiskUsageByPath", Payload: payload})
+		result.Disk = nil
+		assert.NoError(t, subprocess.UnmarshalPayload(response, &result))
+		assert.Len(t, result.Disk, 1)
+		assert.Contains(t, result.Disk, dir)
+
+		// RPCGetSysMetrics reports the same paths under disk
+		metrics, err := collectMetrics(logger, []string{"/", dir, missing})
+		assert.NoError(t, err)
+		disk := metrics["disk"].(map[string]map[string]uint64)
+		assert.Len(t, disk, 2)
+		assert.Equal(t, disk["/"]["used"], metrics["disk_usage"])
+	})
+}
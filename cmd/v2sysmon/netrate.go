@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// netSample is the byte counters of the interfaces at one point in time
+type netSample struct {
+	at       time.Time
+	counters map[string]map[string]uint64
+}
+
+// netRateTracker turns the cumulative rx/tx byte counters of
+// /proc/net/dev into per-second rates by keeping the previous sample
+type netRateTracker struct {
+	mu   sync.Mutex
+	prev *netSample
+}
+
+// netRates is the tracker collectMetrics samples into
+var netRates = &netRateTracker{}
+
+// update records the counters sampled at now and returns the rx and tx rate
+// of each interface, in bytes per second, since the previous sample. The
+// first sample of an interface, and a counter that went backwards other than
+// by wrapping around 32 bits (e.g. the interface was re-created), report zero.
+// The loopback interface is skipped, like in the totals.
+func (t *netRateTracker) update(now time.Time, counters map[string]map[string]uint64) (rx, tx map[string]float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rx = make(map[string]float64, len(counters))
+	tx = make(map[string]float64, len(counters))
+	var elapsed float64
+	if t.prev != nil {
+		elapsed = now.Sub(t.prev.at).Seconds()
+	}
+	for ifName, c := range counters {
+		if ifName == "lo" {
+			continue
+		}
+		rx[ifName], tx[ifName] = 0, 0
+		if elapsed <= 0 {
+			continue
+		}
+		prev, ok := t.prev.counters[ifName]
+		if !ok {
+			continue
+		}
+		rx[ifName] = float64(counterDelta(prev["rx"], c["rx"])) / elapsed
+		tx[ifName] = float64(counterDelta(prev["tx"], c["tx"])) / elapsed
+	}
+	// Only move forward in time, so a late sample does not skew the next rate
+	if t.prev == nil || now.After(t.prev.at) {
+		t.prev = &netSample{at: now, counters: counters}
+	}
+	return rx, tx
+}
+
+// counterDelta returns how far a byte counter advanced from prev to cur. A
+// counter below its previous value has wrapped around if the previous value
+// fits in 32 bits, as on kernels with 32-bit counters; otherwise it was reset
+// and the delta is unknown, so 0 is returned.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if prev <= math.MaxUint32 {
+		return cur + (math.MaxUint32 - prev) + 1
+	}
+	return 0
+}
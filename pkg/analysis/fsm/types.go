@@ -171,6 +171,7 @@ type AnalyzeStateTransition struct {
 // Valid graph state transitions
 var validGraphTransitions = map[GraphStateTransition]bool{
 	{GraphIdle, GraphBuilding}:    true,
+	{GraphIdle, GraphPersisting}:  true, // Save a loaded or hand-built graph
 	{GraphBuilding, GraphReady}:   true,
 	{GraphBuilding, GraphError}:   true,
 	{GraphReady, GraphAnalyzing}:  true,
@@ -183,6 +184,7 @@ var validGraphTransitions = map[GraphStateTransition]bool{
 	{GraphPersisting, GraphError}: true,
 	{GraphError, GraphIdle}:       true, // Reset after error
 	{GraphError, GraphBuilding}:   true, // Retry after error
+	{GraphError, GraphPersisting}: true, // Retry a failed save
 }
 
 // Valid analysis state transitions
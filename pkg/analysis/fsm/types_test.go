@@ -173,6 +173,7 @@ func TestGraphStateTransitions(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "GraphStateTransitions", nil, func(t *testing.T, _ *gorm.DB) {
 		validTransitions := []GraphStateTransition{
 			{GraphIdle, GraphBuilding},
+			{GraphIdle, GraphPersisting},
 			{GraphBuilding, GraphReady},
 			{GraphBuilding, GraphError},
 			{GraphReady, GraphAnalyzing},
@@ -185,6 +186,7 @@ func TestGraphStateTransitions(t *testing.T) {
 			{GraphPersisting, GraphError},
 			{GraphError, GraphIdle},
 			{GraphError, GraphBuilding},
+			{GraphError, GraphPersisting},
 		}
 
 		for _, trans := range validTransitions {
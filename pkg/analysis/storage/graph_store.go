@@ -215,8 +215,9 @@ func (s *GraphStore) LoadGraph() (*graph.Graph, error) {
 				return fmt.Errorf("failed to parse to URN %s: %w", edgeData.To, err)
 			}
 
+			// Keep edges outside the taxonomy so the integrity check can report them
 			edgeType := graph.EdgeType(edgeData.Type)
-			if err := g.AddEdge(from, to, edgeType, edgeData.Properties); err != nil {
+			if err := g.AddCustomEdge(from, to, edgeType, edgeData.Properties); err != nil {
 				// Log error but continue loading
 				if s.logger != nil {
 					s.logger.Warn("Failed to add edge: %v", err)
@@ -0,0 +1,167 @@
+package asvs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// linkBatchSize bounds the requirement IDs per statement, well under SQLite's
+// limit on bound variables
+const linkBatchSize = 500
+
+// ParseCWEIDs returns the distinct CWE IDs ("CWE-79") listed in the CWE column
+// of an ASVS requirement, in column order. The column may name several CWEs
+// separated by commas, semicolons, slashes or spaces, with or without the
+// "CWE-" prefix, e.g. "79, CWE-116". Tokens that name no CWE are skipped.
+func ParseCWEIDs(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '/' || r == '|' || r == ' ' || r == '\t' || r == '\n'
+	})
+	seen := make(map[string]bool)
+	var ids []string
+	for _, f := range fields {
+		id := NormalizeCWEID(f)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// NormalizeCWEID returns the CWE ID named by s ("CWE-79", "cwe-79" or "79"),
+// or "" if s names no CWE
+func NormalizeCWEID(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	digits := strings.TrimPrefix(s, "CWE-")
+	if digits == "" {
+		return ""
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return "CWE-" + digits
+}
+
+// cweLinksOf returns the join rows of the given requirements
+func cweLinksOf(records []ASVSRequirementModel) []ASVSCWEModel {
+	var links []ASVSCWEModel
+	for _, r := range records {
+		for _, id := range ParseCWEIDs(r.CWE) {
+			links = append(links, ASVSCWEModel{RequirementID: r.RequirementID, CWEID: id})
+		}
+	}
+	return links
+}
+
+// saveRequirements upserts records and replaces their CWE links. It must run
+// inside a transaction.
+func saveRequirements(tx *gorm.DB, records []ASVSRequirementModel) error {
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(records, 100).Error; err != nil {
+		return err
+	}
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = r.RequirementID
+	}
+	for start := 0; start < len(ids); start += linkBatchSize {
+		end := min(start+linkBatchSize, len(ids))
+		if err := tx.Where("requirement_id IN ?", ids[start:end]).Delete(&ASVSCWEModel{}).Error; err != nil {
+			return err
+		}
+	}
+	links := cweLinksOf(records)
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(links, linkBatchSize).Error
+}
+
+// cweIDsByRequirement returns the linked CWE IDs of each of the given
+// requirements, in column order
+func (s *LocalASVSStore) cweIDsByRequirement(ctx context.Context, requirementIDs []string) (map[string][]string, error) {
+	byReq := make(map[string][]string, len(requirementIDs))
+	for start := 0; start < len(requirementIDs); start += linkBatchSize {
+		end := min(start+linkBatchSize, len(requirementIDs))
+		var links []ASVSCWEModel
+		if err := s.db.WithContext(ctx).Where("requirement_id IN ?", requirementIDs[start:end]).Order("id").Find(&links).Error; err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			byReq[l.RequirementID] = append(byReq[l.RequirementID], l.CWEID)
+		}
+	}
+	return byReq, nil
+}
+
+// toRequirements converts models to requirements with their linked CWE IDs
+func (s *LocalASVSStore) toRequirements(ctx context.Context, models []ASVSRequirementModel) ([]ASVSRequirement, error) {
+	ids := make([]string, len(models))
+	for i, m := range models {
+		ids[i] = m.RequirementID
+	}
+	cwes, err := s.cweIDsByRequirement(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := make([]ASVSRequirement, len(models))
+	for i, m := range models {
+		requirements[i] = ASVSRequirement{
+			RequirementID: m.RequirementID,
+			Chapter:       m.Chapter,
+			Section:       m.Section,
+			Description:   m.Description,
+			Level1:        m.Level1,
+			Level2:        m.Level2,
+			Level3:        m.Level3,
+			CWE:           m.CWE,
+			CWEs:          cwes[m.RequirementID],
+		}
+	}
+	return requirements, nil
+}
+
+// GetByCWE returns the ASVS requirements linked to a CWE, ordered by
+// requirement ID. The CWE may be given as "CWE-79" or "79".
+func (s *LocalASVSStore) GetByCWE(ctx context.Context, cweID string) ([]ASVSRequirement, error) {
+	id := NormalizeCWEID(cweID)
+	if id == "" {
+		return nil, fmt.Errorf("invalid cwe_id %q: must be a CWE ID such as CWE-79", cweID)
+	}
+
+	var models []ASVSRequirementModel
+	err := s.db.WithContext(ctx).
+		Where("requirement_id IN (SELECT requirement_id FROM asvs_cwes WHERE cwe_id = ?)", id).
+		Order("requirement_id ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.toRequirements(ctx, models)
+}
+
+// backfillCWELinks derives the join rows of requirements imported before the
+// asvs_cwes table existed. It runs once, while the table is still empty.
+func backfillCWELinks(db *gorm.DB) error {
+	var links int64
+	if err := db.Model(&ASVSCWEModel{}).Count(&links).Error; err != nil || links > 0 {
+		return err
+	}
+
+	var records []ASVSRequirementModel
+	return db.Where("cwe <> ''").FindInBatches(&records, linkBatchSize, func(tx *gorm.DB, batch int) error {
+		batchLinks := cweLinksOf(records)
+		if len(batchLinks) == 0 {
+			return nil
+		}
+		return db.CreateInBatches(batchLinks, linkBatchSize).Error
+	}).Error
+}
@@ -11,9 +11,9 @@ import (
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 var (
@@ -30,7 +30,7 @@ type LocalASVSStore struct {
 
 // NewLocalASVSStore creates or opens a local ASVS database at dbPath
 func NewLocalASVSStore(dbPath string) (*LocalASVSStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{
 		PrepareStmt: false,
 	})
 	if err != nil {
@@ -49,9 +49,12 @@ func NewLocalASVSStore(dbPath string) (*LocalASVSStore, error) {
 	}
 
 	// Auto-migrate the schema
-	if err := db.AutoMigrate(&ASVSRequirementModel{}); err != nil {
+	if err := db.AutoMigrate(&ASVSRequirementModel{}, &ASVSCWEModel{}); err != nil {
 		return nil, err
 	}
+	if err := backfillCWELinks(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill ASVS CWE links: %w", err)
+	}
 
 	return &LocalASVSStore{db: db}, nil
 }
@@ -185,7 +188,12 @@ func (s *LocalASVSStore) ImportFromCSV(ctx context.Context, url string) error {
 
 	// Batch insert using GORM
 	common.Info("Importing %d ASVS requirements", len(records))
-	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(records, 100).Error; err != nil {
+	err = dbretry.Do(func() error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return saveRequirements(tx, records)
+		})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to insert records: %w", err)
 	}
 
@@ -241,21 +249,10 @@ func (s *LocalASVSStore) ListASVSPaginated(ctx context.Context, offset, limit in
 		return nil, 0, err
 	}
 
-	// Convert models to ASVSRequirement
-	requirements := make([]ASVSRequirement, len(models))
-	for i, m := range models {
-		requirements[i] = ASVSRequirement{
-			RequirementID: m.RequirementID,
-			Chapter:       m.Chapter,
-			Section:       m.Section,
-			Description:   m.Description,
-			Level1:        m.Level1,
-			Level2:        m.Level2,
-			Level3:        m.Level3,
-			CWE:           m.CWE,
-		}
+	requirements, err := s.toRequirements(ctx, models)
+	if err != nil {
+		return nil, 0, err
 	}
-
 	return requirements, total, nil
 }
 
@@ -266,40 +263,11 @@ func (s *LocalASVSStore) GetByID(ctx context.Context, requirementID string) (*AS
 		return nil, err
 	}
 
-	return &ASVSRequirement{
-		RequirementID: model.RequirementID,
-		Chapter:       model.Chapter,
-		Section:       model.Section,
-		Description:   model.Description,
-		Level1:        model.Level1,
-		Level2:        model.Level2,
-		Level3:        model.Level3,
-		CWE:           model.CWE,
-	}, nil
-}
-
-// GetByCWE retrieves ASVS requirements by CWE
-func (s *LocalASVSStore) GetByCWE(ctx context.Context, cwe string) ([]ASVSRequirement, error) {
-	var models []ASVSRequirementModel
-	if err := s.db.WithContext(ctx).Where("cwe LIKE ?", "%"+cwe+"%").Find(&models).Error; err != nil {
+	requirements, err := s.toRequirements(ctx, []ASVSRequirementModel{model})
+	if err != nil {
 		return nil, err
 	}
-
-	requirements := make([]ASVSRequirement, len(models))
-	for i, m := range models {
-		requirements[i] = ASVSRequirement{
-			RequirementID: m.RequirementID,
-			Chapter:       m.Chapter,
-			Section:       m.Section,
-			Description:   m.Description,
-			Level1:        m.Level1,
-			Level2:        m.Level2,
-			Level3:        m.Level3,
-			CWE:           m.CWE,
-		}
-	}
-
-	return requirements, nil
+	return &requirements[0], nil
 }
 
 // Count returns the total number of ASVS requirements
@@ -68,6 +68,27 @@ func TestParseBoolColumn(t *testing.T) {
 	}
 }
 
+func TestParseCWEIDs(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseCWEIDs", nil, func(t *testing.T, tx *gorm.DB) {
+		tests := []struct {
+			raw  string
+			want []string
+		}{
+			{raw: "", want: nil},
+			{raw: "CWE-79", want: []string{"CWE-79"}},
+			{raw: "79, cwe-116; 200/79", want: []string{"CWE-79", "CWE-116", "CWE-200"}},
+			{raw: "CWE-79 CWE-80", want: []string{"CWE-79", "CWE-80"}},
+			{raw: "N/A, CWE-, CWE-x1", want: nil},
+		}
+		for _, tt := range tests {
+			got := ParseCWEIDs(tt.raw)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseCWEIDs(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		}
+	})
+}
+
 func TestListASVSPaginated(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "ListASVSPaginated_AllRecords", nil, func(t *testing.T, tx *gorm.DB) {
 		tmpDB := "/tmp/test_asvs_list.db"
@@ -313,10 +334,10 @@ func TestGetByCWE(t *testing.T) {
 			},
 		}
 
-		for _, rec := range testRecords {
-			if err := store.db.Create(&rec).Error; err != nil {
-				t.Fatalf("Failed to insert test record: %v", err)
-			}
+		if err := store.db.Transaction(func(tx *gorm.DB) error {
+			return saveRequirements(tx, testRecords)
+		}); err != nil {
+			t.Fatalf("Failed to insert test records: %v", err)
 		}
 
 		reqs, err := store.GetByCWE(ctx, "CWE-79")
@@ -327,6 +348,51 @@ func TestGetByCWE(t *testing.T) {
 		if len(reqs) != 2 {
 			t.Errorf("Expected 2 requirements for CWE-79, got %d", len(reqs))
 		}
+
+		// The bare number matches too, and CWE-7 is not a prefix match of CWE-79
+		reqs, err = store.GetByCWE(ctx, "89")
+		if err != nil {
+			t.Fatalf("Failed to get requirements by CWE: %v", err)
+		}
+		if len(reqs) != 2 || reqs[0].RequirementID != "1.1.2" || reqs[1].RequirementID != "2.1.1" {
+			t.Errorf("Expected 1.1.2 and 2.1.1 for CWE-89, got %+v", reqs)
+		}
+		if got := reqs[0].CWEs; len(got) != 2 || got[0] != "CWE-79" || got[1] != "CWE-89" {
+			t.Errorf("Expected CWEs [CWE-79 CWE-89] for 1.1.2, got %v", got)
+		}
+		if reqs, err := store.GetByCWE(ctx, "CWE-7"); err != nil || len(reqs) != 0 {
+			t.Errorf("Expected no requirements for CWE-7, got %d (err %v)", len(reqs), err)
+		}
+
+		if _, err := store.GetByCWE(ctx, "XSS"); err == nil {
+			t.Error("Expected error for an invalid CWE ID, got nil")
+		}
+	})
+
+	testutils.Run(t, testutils.Level2, "GetByCWE_BackfillsExistingRows", nil, func(t *testing.T, tx *gorm.DB) {
+		tmpDB := "/tmp/test_asvs_getbycwe_backfill.db"
+		defer os.Remove(tmpDB)
+
+		store, err := NewLocalASVSStore(tmpDB)
+		if err != nil {
+			t.Fatalf("Failed to create ASVS store: %v", err)
+		}
+		// A row written without links, as by an import before asvs_cwes existed
+		if err := store.db.Create(&ASVSRequirementModel{RequirementID: "1.1.1", CWE: "CWE-79; 116"}).Error; err != nil {
+			t.Fatalf("Failed to insert test record: %v", err)
+		}
+
+		store, err = NewLocalASVSStore(tmpDB)
+		if err != nil {
+			t.Fatalf("Failed to reopen ASVS store: %v", err)
+		}
+		reqs, err := store.GetByCWE(context.Background(), "CWE-116")
+		if err != nil {
+			t.Fatalf("Failed to get requirements by CWE: %v", err)
+		}
+		if len(reqs) != 1 || reqs[0].RequirementID != "1.1.1" {
+			t.Errorf("Expected the existing row backfilled, got %+v", reqs)
+		}
 	})
 }
 
@@ -344,7 +410,7 @@ func TestImportFromCSV(t *testing.T) {
 
 		csvContent := `Requirement ID,Chapter,Section,Description,L1,L2,L3,CWE
 1.1.1,V1,Architecture,Test requirement 1,x,x,x,CWE-79
-1.1.2,V1,Architecture,Test requirement 2,x,x,,CWE-89
+1.1.2,V1,Architecture,Test requirement 2,x,x,,"CWE-89, 79"
 2.1.1,V2,Authentication,Test requirement 3,x,x,,
 `
 
@@ -381,6 +447,27 @@ func TestImportFromCSV(t *testing.T) {
 		if !req.Level1 {
 			t.Error("Expected Level1 to be true")
 		}
+
+		reqs, err := store.GetByCWE(ctx, "CWE-79")
+		if err != nil {
+			t.Fatalf("Failed to get requirements by CWE: %v", err)
+		}
+		if len(reqs) != 2 {
+			t.Errorf("Expected 2 requirements for CWE-79, got %d", len(reqs))
+		}
+
+		// Re-importing replaces the links of a requirement whose CWEs changed
+		csvContent = strings.Replace(csvContent, `"CWE-89, 79"`, "CWE-89", 1)
+		if err := store.ImportFromCSV(ctx, server.URL); err != nil {
+			t.Fatalf("Failed to re-import from CSV: %v", err)
+		}
+		reqs, err = store.GetByCWE(ctx, "CWE-79")
+		if err != nil {
+			t.Fatalf("Failed to get requirements by CWE: %v", err)
+		}
+		if len(reqs) != 1 || reqs[0].RequirementID != "1.1.1" {
+			t.Errorf("Expected only 1.1.1 for CWE-79 after re-import, got %+v", reqs)
+		}
 	})
 
 	testutils.Run(t, testutils.Level2, "ImportFromCSV_ErrorStatus", nil, func(t *testing.T, tx *gorm.DB) {
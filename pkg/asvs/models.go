@@ -16,3 +16,17 @@ type ASVSRequirementModel struct {
 func (ASVSRequirementModel) TableName() string {
 	return "asvs_requirements"
 }
+
+// ASVSCWEModel links an ASVS requirement to one of the CWEs listed in its CWE
+// column. The rows are derived from that column and written in the same
+// transaction as the requirement row, so the two never disagree.
+type ASVSCWEModel struct {
+	ID            uint   `gorm:"primarykey"`
+	RequirementID string `gorm:"column:requirement_id;uniqueIndex:idx_asvs_cwe;not null"`
+	CWEID         string `gorm:"column:cwe_id;uniqueIndex:idx_asvs_cwe;index;not null"`
+}
+
+// TableName specifies the table name for ASVSCWEModel
+func (ASVSCWEModel) TableName() string {
+	return "asvs_cwes"
+}
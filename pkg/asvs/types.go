@@ -10,4 +10,6 @@ type ASVSRequirement struct {
 	Level2        bool   `json:"Level2"`
 	Level3        bool   `json:"Level3"`
 	CWE           string `json:"CWE,omitempty"`
+	// CWEs are the distinct CWE IDs ("CWE-79") parsed from CWE
+	CWEs []string `json:"CWEs,omitempty"`
 }
@@ -2,11 +2,15 @@ package attack
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -20,7 +24,7 @@ type LocalAttackStore struct {
 
 // NewLocalAttackStore creates or opens a local ATT&CK database at dbPath
 func NewLocalAttackStore(dbPath string) (*LocalAttackStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +44,7 @@ func NewLocalAttackStore(dbPath string) (*LocalAttackStore, error) {
 	err = db.AutoMigrate(
 		&AttackTechnique{},
 		&AttackTactic{},
+		&AttackTechniqueTactic{},
 		&AttackMitigation{},
 		&AttackSoftware{},
 		&AttackGroup{},
@@ -50,53 +55,156 @@ func NewLocalAttackStore(dbPath string) (*LocalAttackStore, error) {
 		return nil, err
 	}
 
+	// Link the sub-techniques of databases imported before techniques had a
+	// parent
+	if err := db.Model(&AttackTechnique{}).
+		Where("instr(id, '.') > 0 AND (parent_id IS NULL OR parent_id = '')").
+		Update("parent_id", gorm.Expr("substr(id, 1, instr(id, '.') - 1)")).Error; err != nil {
+		return nil, err
+	}
+
+	// Link the techniques of databases imported before tactics were stored
+	if err := backfillTechniqueTactics(db); err != nil {
+		return nil, err
+	}
+
 	return &LocalAttackStore{db: db}, nil
 }
 
+// backfillTechniqueTactics stores the tactics of techniques imported before
+// the tactic links existed, reading them again from the Techniques sheet of
+// the last imported file. It does nothing once any link exists, without
+// stored techniques, or when that file is gone or unreadable, in which case
+// a re-import links them.
+func backfillTechniqueTactics(db *gorm.DB) error {
+	var links, techniques int64
+	if err := db.Model(&AttackTechniqueTactic{}).Count(&links).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&AttackTechnique{}).Count(&techniques).Error; err != nil {
+		return err
+	}
+	if links > 0 || techniques == 0 {
+		return nil
+	}
+	var meta AttackMetadata
+	if err := db.Order("id desc").Limit(1).Find(&meta).Error; err != nil {
+		return err
+	}
+	if meta.SourceFile == "" {
+		return nil
+	}
+	file, err := excelize.OpenFile(meta.SourceFile)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, sheetName := range file.GetSheetMap() {
+			switch strings.ToLower(sheetName) {
+			case "techniques", "technique", "attack_techniques", "attacks":
+			default:
+				continue
+			}
+			rows, err := file.GetRows(sheetName)
+			if err != nil || len(rows) == 0 {
+				continue
+			}
+			headers := rows[0]
+			for _, row := range rows[1:] {
+				id := getStringValue(row, 0, headers, "ID")
+				if id == "" {
+					continue
+				}
+				var stored int64
+				if err := tx.Model(&AttackTechnique{}).Where("id = ?", id).Count(&stored).Error; err != nil {
+					return err
+				}
+				if stored == 0 {
+					continue
+				}
+				if err := saveTechniqueTactics(tx, id, row, headers); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
 // ImportFromXLSX reads ATT&CK data from an Excel file and imports it into the database
 func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
+	_, err := s.ImportFromXLSXWithReport(xlsxPath, force, false)
+	return err
+}
+
+// ImportFromXLSXWithReport imports ATT&CK data like ImportFromXLSX and reports
+// how many records were inserted and updated, with validation warnings. With
+// dryRun the file is parsed, validated and written in a transaction that is
+// rolled back, so nothing is stored.
+func (s *LocalAttackStore) ImportFromXLSXWithReport(xlsxPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	// The import writes in a transaction that a lock conflict rolls back
+	// whole, so it is safe to start over from the file
+	var report *catalog.ImportReport
+	err := dbretry.Do(func() error {
+		var err error
+		report, err = s.importFromXLSX(xlsxPath, force, dryRun)
+		return err
+	})
+	return report, err
+}
+
+// importFromXLSX makes one attempt at ImportFromXLSX
+func (s *LocalAttackStore) importFromXLSX(xlsxPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	report := catalog.NewImportReport(dryRun)
+	seen := make(map[string]bool)
 	// Check if file exists
 	if _, err := os.Stat(xlsxPath); os.IsNotExist(err) {
-		return fmt.Errorf("XLSX file does not exist: %s", xlsxPath)
+		return nil, fmt.Errorf("XLSX file does not exist: %s", xlsxPath)
 	}
 
 	file, err := excelize.OpenFile(xlsxPath)
 	if err != nil {
-		return fmt.Errorf("failed to open XLSX file: %v", err)
+		return nil, fmt.Errorf("failed to open XLSX file: %v", err)
 	}
 	defer file.Close()
 
 	// Start a transaction for atomic import
 	tx := s.db.Begin()
 	if tx.Error != nil {
-		return tx.Error
+		return nil, tx.Error
 	}
 
 	// Clean up existing data if force flag is true
 	if force {
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackTechnique{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear techniques: %v", err)
+			return nil, fmt.Errorf("failed to clear techniques: %v", err)
 		}
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackTactic{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear tactics: %v", err)
+			return nil, fmt.Errorf("failed to clear tactics: %v", err)
+		}
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackTechniqueTactic{}).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to clear technique tactics: %v", err)
 		}
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackMitigation{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear mitigations: %v", err)
+			return nil, fmt.Errorf("failed to clear mitigations: %v", err)
 		}
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackSoftware{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear software: %v", err)
+			return nil, fmt.Errorf("failed to clear software: %v", err)
 		}
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackGroup{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear groups: %v", err)
+			return nil, fmt.Errorf("failed to clear groups: %v", err)
 		}
 		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AttackRelationship{}).Error; err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to clear relationships: %v", err)
+			return nil, fmt.Errorf("failed to clear relationships: %v", err)
 		}
 	}
 
@@ -109,7 +217,7 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 		rows, err := file.GetRows(sheetName)
 		if err != nil {
 			tx.Rollback()
-			return fmt.Errorf("failed to read sheet '%s': %v", sheetName, err)
+			return nil, fmt.Errorf("failed to read sheet '%s': %v", sheetName, err)
 		}
 
 		if len(rows) == 0 {
@@ -118,6 +226,16 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 
 		// Assuming first row contains headers
 		headers := rows[0]
+		// The ATT&CK workbook names its relationship columns ("source ID",
+		// "target ID", ...) in an order unlike the positional fallback, so
+		// in that layout columns are looked up by header only
+		attackLayout := getStringIndex(headers, []string{"source ID"}) >= 0
+		col := func(i int) int {
+			if attackLayout {
+				return -1
+			}
+			return i
+		}
 		for i := 1; i < len(rows); i++ {
 			row := rows[i]
 
@@ -137,15 +255,26 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 						Revoked:     getBoolValue(row, getStringIndex(headers, []string{"Revoked", "Is Revoked", "Revoked?"})),
 						Deprecated:  getBoolValue(row, getStringIndex(headers, []string{"Deprecated", "Is Deprecated", "Deprecated?"})),
 					}
+					technique.ParentID = parentTechniqueID(technique.ID)
 
-					// Validate required fields
-					if technique.ID != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackTechnique{}, technique.ID)
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(technique).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert technique: %v", err)
+							return nil, fmt.Errorf("failed to insert technique: %v", err)
+						}
+						if err := saveTechniqueTactics(tx, technique.ID, row, headers); err != nil {
+							tx.Rollback()
+							return nil, err
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 6; skipped", sheetName, i+1, len(row))
 				}
 			case "tactics", "tactic", "attack_tactics":
 				if len(row) >= 5 { // Ensure row has enough columns
@@ -158,14 +287,20 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 						Modified:    getStringValue(row, 5, headers, "Modified", "Last Modified"),
 					}
 
-					// Validate required fields
-					if tactic.ID != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackTactic{}, tactic.ID)
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(tactic).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert tactic: %v", err)
+							return nil, fmt.Errorf("failed to insert tactic: %v", err)
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 5; skipped", sheetName, i+1, len(row))
 				}
 			case "mitigations", "mitigation", "attack_mitigations":
 				if len(row) >= 5 { // Ensure row has enough columns
@@ -178,14 +313,20 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 						Modified:    getStringValue(row, 5, headers, "Modified", "Last Modified"),
 					}
 
-					// Validate required fields
-					if mitigation.ID != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackMitigation{}, mitigation.ID)
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(mitigation).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert mitigation: %v", err)
+							return nil, fmt.Errorf("failed to insert mitigation: %v", err)
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 5; skipped", sheetName, i+1, len(row))
 				}
 			case "software", "attack_software":
 				if len(row) >= 6 { // Ensure row has enough columns
@@ -199,14 +340,20 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 						Modified:    getStringValue(row, 6, headers, "Modified", "Last Modified"),
 					}
 
-					// Validate required fields
-					if software.ID != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackSoftware{}, software.ID)
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(software).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert software: %v", err)
+							return nil, fmt.Errorf("failed to insert software: %v", err)
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 6; skipped", sheetName, i+1, len(row))
 				}
 			case "groups", "attack_groups", "adversary_groups":
 				if len(row) >= 5 { // Ensure row has enough columns
@@ -219,65 +366,149 @@ func (s *LocalAttackStore) ImportFromXLSX(xlsxPath string, force bool) error {
 						Modified:    getStringValue(row, 5, headers, "Modified", "Last Modified"),
 					}
 
-					// Validate required fields
-					if group.ID != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackGroup{}, group.ID)
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(group).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert group: %v", err)
+							return nil, fmt.Errorf("failed to insert group: %v", err)
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 5; skipped", sheetName, i+1, len(row))
 				}
 			case "relationships", "attack_relationships", "relations":
 				if len(row) >= 7 { // Ensure row has enough columns
 					relationship := &AttackRelationship{
-						ID:               fmt.Sprintf("%d_%s_%s", sheetIndex, getStringValue(row, 0, headers, "SourceRef"), getStringValue(row, 1, headers, "TargetRef")),
-						SourceRef:        getStringValue(row, 0, headers, "SourceRef"),
-						TargetRef:        getStringValue(row, 1, headers, "TargetRef"),
-						RelationshipType: getStringValue(row, 2, headers, "RelationshipType"),
-						SourceObjectType: getStringValue(row, 3, headers, "SourceObjectType"),
-						TargetObjectType: getStringValue(row, 4, headers, "TargetObjectType"),
-						Description:      getStringValue(row, 5, headers, "Description"),
-						Domain:           getStringValue(row, 6, headers, "Domain"),
-						Created:          getStringValue(row, 7, headers, "Created"),
-						Modified:         getStringValue(row, 8, headers, "Modified", "Last Modified"),
+						SourceRef:        getStringValue(row, col(0), headers, "SourceRef", "source ID"),
+						TargetRef:        getStringValue(row, col(1), headers, "TargetRef", "target ID"),
+						RelationshipType: getStringValue(row, col(2), headers, "RelationshipType", "mapping type"),
+						SourceObjectType: getStringValue(row, col(3), headers, "SourceObjectType", "source type"),
+						TargetObjectType: getStringValue(row, col(4), headers, "TargetObjectType", "target type"),
+						Description:      getStringValue(row, col(5), headers, "Description", "mapping description"),
+						Domain:           getStringValue(row, col(6), headers, "Domain"),
+						Created:          getStringValue(row, col(7), headers, "Created"),
+						Modified:         getStringValue(row, col(8), headers, "Modified", "Last Modified"),
+					}
+					relationship.ID = getStringValue(row, -1, headers, "STIX ID")
+					if relationship.ID == "" {
+						relationship.ID = fmt.Sprintf("%d_%s_%s", sheetIndex, relationship.SourceRef, relationship.TargetRef)
 					}
 
-					// Validate required fields
-					if relationship.SourceRef != "" && relationship.TargetRef != "" {
+					write, err := recordAttackRow(tx, report, seen, sheetName, i+1, &AttackRelationship{}, relationshipID(relationship))
+					if err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if write {
 						if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(relationship).Error; err != nil {
 							tx.Rollback()
-							return fmt.Errorf("failed to insert relationship: %v", err)
+							return nil, fmt.Errorf("failed to insert relationship: %v", err)
 						}
 						totalRecords++
 					}
+				} else if len(row) > 0 {
+					report.Warnf("sheet %s row %d has %d columns, fewer than 7; skipped", sheetName, i+1, len(row))
 				}
 			}
 		}
 	}
 
-	// Record import metadata
+	// Record import metadata, taking the version from the workbook's
+	// document properties
+	declared := ""
+	if props, err := file.GetDocProps(); err == nil {
+		declared = strings.TrimSpace(props.Version)
+	}
+	version, versionSource := catalog.ResolveVersion(declared, xlsxPath)
 	meta := &AttackMetadata{
 		ImportedAt:    time.Now().Unix(),
 		SourceFile:    xlsxPath,
 		TotalRecords:  totalRecords,
-		ImportVersion: "1.0", // Could be derived from XLSX metadata
+		ImportVersion: version,
+		VersionSource: versionSource,
 	}
 
 	// Insert or update import metadata
 	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(meta).Error; err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to record import metadata: %v", err)
+		return nil, fmt.Errorf("failed to record import metadata: %v", err)
+	}
+
+	if dryRun {
+		tx.Rollback()
+		return report, nil
 	}
 
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	return report, nil
+}
+
+// saveTechniqueTactics replaces the tactics of a technique with those listed
+// in its "Tactics" column, comma-separated. A sheet without the column leaves
+// the technique without tactics.
+func saveTechniqueTactics(tx *gorm.DB, techniqueID string, row, headers []string) error {
+	if err := tx.Where("technique_id = ?", techniqueID).Delete(&AttackTechniqueTactic{}).Error; err != nil {
+		return fmt.Errorf("failed to clear tactics of technique %s: %v", techniqueID, err)
+	}
+	idx := getStringIndex(headers, []string{"Tactics", "Tactic", "Kill Chain Phases"})
+	if idx < 0 || idx >= len(row) {
+		return nil
+	}
+	for _, tactic := range strings.Split(row[idx], ",") {
+		tactic = strings.TrimSpace(tactic)
+		if tactic == "" {
+			continue
+		}
+		link := &AttackTechniqueTactic{TechniqueID: techniqueID, Tactic: tactic}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error; err != nil {
+			return fmt.Errorf("failed to insert tactic of technique %s: %v", techniqueID, err)
+		}
+	}
 	return nil
 }
 
+// recordAttackRow validates the ID of a row about to be imported into the
+// table of model, counts it in report as inserted or updated and reports
+// whether the row is to be written. It must be called before the row is
+// written to tx. A row without an ID is skipped; a row whose ID was seen
+// earlier in the file is written over the earlier one.
+func recordAttackRow(tx *gorm.DB, report *catalog.ImportReport, seen map[string]bool, sheetName string, rowNum int, model interface{}, id string) (bool, error) {
+	if id == "" {
+		report.Warnf("sheet %s row %d has no ID; skipped", sheetName, rowNum)
+		return false, nil
+	}
+	key := fmt.Sprintf("%T/%s", model, id)
+	if seen[key] {
+		report.Warnf("sheet %s row %d repeats %s; it replaces the earlier row", sheetName, rowNum, id)
+		return true, nil
+	}
+	seen[key] = true
+	var n int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&n).Error; err != nil {
+		return false, err
+	}
+	report.Record(n > 0)
+	return true, nil
+}
+
+// relationshipID returns the ID of a relationship row, or "" if the row lacks
+// its source or target
+func relationshipID(r *AttackRelationship) string {
+	if r.SourceRef == "" || r.TargetRef == "" {
+		return ""
+	}
+	return r.ID
+}
+
 // GetTechniqueByID returns an ATT&CK technique by its ID (e.g. "T1001")
 func (s *LocalAttackStore) GetTechniqueByID(ctx context.Context, id string) (*AttackTechnique, error) {
 	var technique AttackTechnique
@@ -287,6 +518,125 @@ func (s *LocalAttackStore) GetTechniqueByID(ctx context.Context, id string) (*At
 	return &technique, nil
 }
 
+// GetSubTechniques returns the sub-techniques of a technique, ordered by ID.
+// A technique without sub-techniques, including a sub-technique itself,
+// yields an empty list; an unknown technique is an error.
+func (s *LocalAttackStore) GetSubTechniques(ctx context.Context, parentID string) ([]AttackTechnique, error) {
+	if _, err := s.GetTechniqueByID(ctx, parentID); err != nil {
+		return nil, err
+	}
+	techniques := []AttackTechnique{}
+	if err := s.db.WithContext(ctx).Where("parent_id = ?", parentID).Order("id asc").Find(&techniques).Error; err != nil {
+		return nil, err
+	}
+	return techniques, nil
+}
+
+// TechniqueRelationships holds the mitigations, groups and software related
+// to a technique. Each list is empty, never nil, when there is no relation.
+type TechniqueRelationships struct {
+	TechniqueID string             `json:"technique_id"`
+	Mitigations []AttackMitigation `json:"mitigations"`
+	Groups      []AttackGroup      `json:"groups"`
+	Software    []AttackSoftware   `json:"software"`
+}
+
+// GetTechniqueRelationships returns the mitigations, groups and software
+// linked to a technique by the imported relationships, in either direction
+// (e.g. "M1038 mitigates T1059", "G0007 uses T1059"), each ordered by ID.
+// The other end of a relationship is classified by its object type, or by
+// its ID prefix when the type is blank; related objects that were not
+// imported are left out. An unknown technique is an error.
+func (s *LocalAttackStore) GetTechniqueRelationships(ctx context.Context, techniqueID string) (*TechniqueRelationships, error) {
+	if _, err := s.GetTechniqueByID(ctx, techniqueID); err != nil {
+		return nil, err
+	}
+	var relationships []AttackRelationship
+	if err := s.db.WithContext(ctx).Where("source_ref = ? OR target_ref = ?", techniqueID, techniqueID).Find(&relationships).Error; err != nil {
+		return nil, err
+	}
+
+	var mitigationIDs, groupIDs, softwareIDs []string
+	for _, rel := range relationships {
+		otherID, otherType := rel.TargetRef, rel.TargetObjectType
+		if rel.TargetRef == techniqueID {
+			otherID, otherType = rel.SourceRef, rel.SourceObjectType
+		}
+		switch relatedObjectKind(otherID, otherType) {
+		case "mitigation":
+			mitigationIDs = append(mitigationIDs, otherID)
+		case "group":
+			groupIDs = append(groupIDs, otherID)
+		case "software":
+			softwareIDs = append(softwareIDs, otherID)
+		}
+	}
+
+	result := &TechniqueRelationships{
+		TechniqueID: techniqueID,
+		Mitigations: []AttackMitigation{},
+		Groups:      []AttackGroup{},
+		Software:    []AttackSoftware{},
+	}
+	db := s.db.WithContext(ctx)
+	if len(mitigationIDs) > 0 {
+		if err := db.Where("id IN ?", mitigationIDs).Order("id asc").Find(&result.Mitigations).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(groupIDs) > 0 {
+		if err := db.Where("id IN ?", groupIDs).Order("id asc").Find(&result.Groups).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(softwareIDs) > 0 {
+		if err := db.Where("id IN ?", softwareIDs).Order("id asc").Find(&result.Software).Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// relatedObjectKind classifies the object at one end of a relationship as
+// "mitigation", "group" or "software" from its STIX or ATT&CK workbook object
+// type, or from its ATT&CK ID prefix (M, G, S) when the type is blank;
+// anything else is ""
+func relatedObjectKind(id, objectType string) string {
+	switch strings.ToLower(strings.TrimSpace(objectType)) {
+	case "course-of-action", "mitigation":
+		return "mitigation"
+	case "intrusion-set", "group":
+		return "group"
+	case "malware", "tool", "software":
+		return "software"
+	case "":
+	default:
+		return ""
+	}
+	if len(id) < 2 || id[1] < '0' || id[1] > '9' {
+		return ""
+	}
+	switch id[0] {
+	case 'M':
+		return "mitigation"
+	case 'G':
+		return "group"
+	case 'S':
+		return "software"
+	}
+	return ""
+}
+
+// parentTechniqueID returns the ID of the technique a sub-technique belongs
+// to ("T1059" for "T1059.001"), or "" for a top-level technique
+func parentTechniqueID(id string) string {
+	parent, _, found := strings.Cut(id, ".")
+	if !found {
+		return ""
+	}
+	return parent
+}
+
 // GetTacticByID returns an ATT&CK tactic by its ID (e.g. "TA0001")
 func (s *LocalAttackStore) GetTacticByID(ctx context.Context, id string) (*AttackTactic, error) {
 	var tactic AttackTactic
@@ -355,6 +705,45 @@ func (s *LocalAttackStore) ListTacticsPaginated(ctx context.Context, offset, lim
 	return tactics, total, nil
 }
 
+// tacticCoverageJoin joins the techniques (q) of tactic links (l) to tactics
+// (t). Links name the tactic either as in the Tactics sheet ("Defense Evasion") or
+// as a kill chain phase ("defense-evasion"), so names are compared ignoring
+// case and hyphens. A technique counts under a tactic of its own domain only,
+// unless either domain is unset.
+const tacticCoverageJoin = `attack_techniques q ON q.id = l.technique_id
+	AND q.revoked = ? AND q.deprecated = ?
+	AND (q.domain = t.domain OR q.domain = '' OR t.domain = '')
+	AND (? OR q.parent_id IS NULL OR q.parent_id = '')`
+
+// GetTacticCoverage returns every tactic, ordered by ID, with the number of
+// techniques that belong to it, and the number of distinct techniques that
+// belong to at least one tactic. A technique of several tactics counts under
+// each; tactics without techniques have a zero count. Revoked and deprecated
+// techniques are not counted, nor sub-techniques unless includeSubTechniques.
+func (s *LocalAttackStore) GetTacticCoverage(ctx context.Context, includeSubTechniques bool) ([]TacticCoverage, int64, error) {
+	linkOn := "lower(replace(l.tactic, '-', ' ')) = lower(replace(t.name, '-', ' '))"
+
+	coverage := []TacticCoverage{}
+	if err := s.db.WithContext(ctx).Raw(`SELECT t.id, t.name, t.domain, COUNT(DISTINCT q.id) AS count
+		FROM attack_tactics t
+		LEFT JOIN attack_technique_tactics l ON `+linkOn+`
+		LEFT JOIN `+tacticCoverageJoin+`
+		GROUP BY t.id, t.name, t.domain
+		ORDER BY t.id`, false, false, includeSubTechniques).Scan(&coverage).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw(`SELECT COUNT(DISTINCT q.id)
+		FROM attack_tactics t
+		JOIN attack_technique_tactics l ON `+linkOn+`
+		JOIN `+tacticCoverageJoin, false, false, includeSubTechniques).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return coverage, total, nil
+}
+
 // ListMitigationsPaginated returns ATT&CK mitigations with pagination
 func (s *LocalAttackStore) ListMitigationsPaginated(ctx context.Context, offset, limit int) ([]AttackMitigation, int64, error) {
 	var mitigations []AttackMitigation
@@ -403,6 +792,35 @@ func (s *LocalAttackStore) ListGroupsPaginated(ctx context.Context, offset, limi
 	return groups, total, nil
 }
 
+// AttackCounts is the number of stored ATT&CK objects of each kind
+type AttackCounts struct {
+	Techniques  int64 `json:"techniques"`
+	Tactics     int64 `json:"tactics"`
+	Mitigations int64 `json:"mitigations"`
+	Software    int64 `json:"software"`
+	Groups      int64 `json:"groups"`
+}
+
+// Counts returns the number of stored ATT&CK objects of each kind
+func (s *LocalAttackStore) Counts(ctx context.Context) (*AttackCounts, error) {
+	counts := &AttackCounts{}
+	for _, c := range []struct {
+		model interface{}
+		count *int64
+	}{
+		{&AttackTechnique{}, &counts.Techniques},
+		{&AttackTactic{}, &counts.Tactics},
+		{&AttackMitigation{}, &counts.Mitigations},
+		{&AttackSoftware{}, &counts.Software},
+		{&AttackGroup{}, &counts.Groups},
+	} {
+		if err := s.db.WithContext(ctx).Model(c.model).Count(c.count).Error; err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
 // GetImportMetadata returns the stored ATT&CK import metadata
 func (s *LocalAttackStore) GetImportMetadata(ctx context.Context) (*AttackMetadata, error) {
 	var meta AttackMetadata
@@ -412,6 +830,49 @@ func (s *LocalAttackStore) GetImportMetadata(ctx context.Context) (*AttackMetada
 	return &meta, nil
 }
 
+// CatalogVersion returns the version, source, import time and entry count
+// of the loaded ATT&CK catalog. The entry count covers techniques, tactics,
+// mitigations, software and groups.
+func (s *LocalAttackStore) CatalogVersion(ctx context.Context) (*catalog.Version, error) {
+	v := &catalog.Version{Taxonomy: catalog.TaxonomyATTACK}
+	meta, err := s.GetImportMetadata(ctx)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		v.Version = meta.ImportVersion
+		v.VersionSource = meta.VersionSource
+		v.Source = meta.SourceFile
+		v.ImportedAt = meta.ImportedAt
+	}
+	for _, model := range []interface{}{&AttackTechnique{}, &AttackTactic{}, &AttackMitigation{}, &AttackSoftware{}, &AttackGroup{}} {
+		var n int64
+		if err := s.db.WithContext(ctx).Model(model).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		v.EntryCount += n
+	}
+	return v, nil
+}
+
+// DataCapabilities reports which ATT&CK features are usable: whether
+// techniques are stored, whether a full-text index exists and whether
+// relationships link the objects to each other
+func (s *LocalAttackStore) DataCapabilities(ctx context.Context) (*capability.Capabilities, error) {
+	c := &capability.Capabilities{DataType: "attack"}
+	var err error
+	if c.HasData, err = capability.HasRows(ctx, s.db, &AttackTechnique{}); err != nil {
+		return nil, err
+	}
+	if c.FTSAvailable, err = capability.HasFTSTable(ctx, s.db, "attack_fts"); err != nil {
+		return nil, err
+	}
+	if c.MappingsBuilt, err = capability.HasRows(ctx, s.db, &AttackRelationship{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // Helper functions for parsing Excel data
 func getStringValue(row []string, colIndex int, headers []string, possibleHeaders ...string) string {
 	// First, try to find the column by header name
@@ -427,8 +888,9 @@ func getStringValue(row []string, colIndex int, headers []string, possibleHeader
 		}
 	}
 
-	// Fallback to index if headers don't match or row doesn't have enough columns
-	if colIndex < len(row) {
+	// Fallback to index if headers don't match or row doesn't have enough
+	// columns; a negative index disables the fallback
+	if colIndex >= 0 && colIndex < len(row) {
 		return strings.TrimSpace(row[colIndex])
 	}
 
@@ -2,11 +2,15 @@ package attack
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 	"gorm.io/gorm"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -190,3 +194,505 @@ func TestImportFromXLSX_MultipleTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestCatalogVersion_FromWorkbookProperties(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCatalogVersion_FromWorkbookProperties", nil, func(t *testing.T, tx *gorm.DB) {
+		store, err := NewLocalAttackStore(":memory:")
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		ctx := context.Background()
+
+		if v, err := store.CatalogVersion(ctx); err != nil || v.Version != "" || v.EntryCount != 0 {
+			t.Fatalf("expected an empty catalog, got %+v, %v", v, err)
+		}
+
+		xlsxPath := filepath.Join(t.TempDir(), "enterprise-attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Techniques")
+		row := []interface{}{"ID", "Name", "Description", "Domain", "Platform", "Created"}
+		if err := f.SetSheetRow("Techniques", "A1", &row); err != nil {
+			t.Fatalf("failed to set header row: %v", err)
+		}
+		for i, id := range []string{"T0001", "T0002"} {
+			dataRow := []interface{}{id, "Technique " + id, "Desc", "enterprise", "linux", "2024-01-01"}
+			if err := f.SetSheetRow("Techniques", fmt.Sprintf("A%d", i+2), &dataRow); err != nil {
+				t.Fatalf("failed to set data row: %v", err)
+			}
+		}
+		if err := f.SetDocProps(&excelize.DocProperties{Version: "15.1"}); err != nil {
+			t.Fatalf("failed to set doc props: %v", err)
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+
+		if err := store.ImportFromXLSX(xlsxPath, true); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+		v, err := store.CatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("CatalogVersion error: %v", err)
+		}
+		if v.Taxonomy != catalog.TaxonomyATTACK || v.Version != "15.1" || v.VersionSource != catalog.VersionSourceCatalog {
+			t.Fatalf("expected the workbook version, got %+v", v)
+		}
+		if v.Source != xlsxPath || v.EntryCount != 2 || v.ImportedAt == 0 {
+			t.Fatalf("unexpected source, count or import time: %+v", v)
+		}
+	})
+}
+
+func TestGetSubTechniques(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetSubTechniques", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "attack.db")
+		store, err := NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		xlsxPath := filepath.Join(t.TempDir(), "attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Techniques")
+		rows := [][]interface{}{
+			{"ID", "Name", "Description", "Domain", "Platform", "Created", "Modified"},
+			{"T1059", "Command and Scripting Interpreter", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+			{"T1059.003", "Windows Command Shell", "Desc", "enterprise", "windows", "2020-01-01", "2021-01-01"},
+			{"T1059.001", "PowerShell", "Desc", "enterprise", "windows", "2020-01-01", "2021-01-01"},
+			{"T1001", "Data Obfuscation", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+		}
+		for i, row := range rows {
+			if err := f.SetSheetRow("Techniques", fmt.Sprintf("A%d", i+1), &row); err != nil {
+				t.Fatalf("failed to set row: %v", err)
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+		if err := store.ImportFromXLSX(xlsxPath, true); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+
+		ctx := context.Background()
+		subs, err := store.GetSubTechniques(ctx, "T1059")
+		if err != nil {
+			t.Fatalf("GetSubTechniques error: %v", err)
+		}
+		if len(subs) != 2 || subs[0].ID != "T1059.001" || subs[1].ID != "T1059.003" {
+			t.Fatalf("expected T1059.001 and T1059.003, got %+v", subs)
+		}
+
+		sub, err := store.GetTechniqueByID(ctx, "T1059.001")
+		if err != nil {
+			t.Fatalf("GetTechniqueByID error: %v", err)
+		}
+		if sub.ParentID != "T1059" {
+			t.Fatalf("expected parent T1059, got %q", sub.ParentID)
+		}
+
+		// Techniques without children, sub-techniques included, have none
+		for _, id := range []string{"T1001", "T1059.001"} {
+			subs, err := store.GetSubTechniques(ctx, id)
+			if err != nil {
+				t.Fatalf("GetSubTechniques(%s) error: %v", id, err)
+			}
+			if subs == nil || len(subs) != 0 {
+				t.Fatalf("expected an empty list for %s, got %+v", id, subs)
+			}
+		}
+
+		if _, err := store.GetSubTechniques(ctx, "T9999"); err == nil {
+			t.Fatalf("expected an error for an unknown technique")
+		}
+
+		// Sub-techniques stored without a parent are linked on open
+		if err := store.db.Model(&AttackTechnique{}).Where("id = ?", "T1059.001").Update("parent_id", "").Error; err != nil {
+			t.Fatalf("failed to clear parent: %v", err)
+		}
+		reopened, err := NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to reopen store: %v", err)
+		}
+		if sub, err := reopened.GetTechniqueByID(ctx, "T1059.001"); err != nil || sub.ParentID != "T1059" {
+			t.Fatalf("expected parent T1059 after reopen, got %+v (err=%v)", sub, err)
+		}
+	})
+}
+
+func TestGetTacticCoverage(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetTacticCoverage", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "attack.db")
+		store, err := NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		xlsxPath := filepath.Join(t.TempDir(), "attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Tactics")
+		f.NewSheet("Techniques")
+		sheets := map[string][][]interface{}{
+			"Tactics": {
+				{"ID", "Name", "Description", "Domain", "Created", "Modified"},
+				{"TA0003", "Persistence", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+				{"TA0004", "Privilege Escalation", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+				{"TA0005", "Defense Evasion", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+				{"TA0010", "Exfiltration", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+			},
+			"Techniques": {
+				{"ID", "Name", "Description", "Domain", "Platform", "Created", "Modified", "Tactics", "Revoked"},
+				{"T1078", "Valid Accounts", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "Persistence, Privilege Escalation, Defense Evasion", "false"},
+				{"T1078.001", "Default Accounts", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "Persistence, Defense Evasion", "false"},
+				{"T1027", "Obfuscated Files or Information", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "defense-evasion", "false"},
+				{"T1000", "Revoked Technique", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "Persistence", "true"},
+				{"T1001", "Data Obfuscation", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "", "false"},
+			},
+		}
+		for sheet, rows := range sheets {
+			for i, row := range rows {
+				if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+					t.Fatalf("failed to set row: %v", err)
+				}
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+		if err := store.ImportFromXLSX(xlsxPath, true); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+
+		ctx := context.Background()
+		counts := func(coverage []TacticCoverage) map[string]int {
+			m := make(map[string]int, len(coverage))
+			for _, c := range coverage {
+				m[c.ID] = c.Count
+			}
+			return m
+		}
+
+		coverage, total, err := store.GetTacticCoverage(ctx, false)
+		if err != nil {
+			t.Fatalf("GetTacticCoverage error: %v", err)
+		}
+		if len(coverage) != 4 || coverage[0].ID != "TA0003" || coverage[0].Name != "Persistence" {
+			t.Fatalf("expected all 4 tactics ordered by ID, got %+v", coverage)
+		}
+		want := map[string]int{"TA0003": 1, "TA0004": 1, "TA0005": 2, "TA0010": 0}
+		if got := counts(coverage); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("expected counts %v, got %v", want, got)
+		}
+		if total != 2 {
+			t.Fatalf("expected 2 techniques with a tactic, got %d", total)
+		}
+
+		coverage, total, err = store.GetTacticCoverage(ctx, true)
+		if err != nil {
+			t.Fatalf("GetTacticCoverage error: %v", err)
+		}
+		want = map[string]int{"TA0003": 2, "TA0004": 1, "TA0005": 3, "TA0010": 0}
+		if got := counts(coverage); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("expected counts with sub-techniques %v, got %v", want, got)
+		}
+		if total != 3 {
+			t.Fatalf("expected 3 techniques with a tactic, got %d", total)
+		}
+
+		// Techniques stored without tactic links are linked on open from the
+		// imported workbook
+		if err := store.db.Where("1 = 1").Delete(&AttackTechniqueTactic{}).Error; err != nil {
+			t.Fatalf("failed to clear tactic links: %v", err)
+		}
+		store, err = NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to reopen store: %v", err)
+		}
+		coverage, total, err = store.GetTacticCoverage(ctx, true)
+		if err != nil {
+			t.Fatalf("GetTacticCoverage error: %v", err)
+		}
+		if got := counts(coverage); fmt.Sprint(got) != fmt.Sprint(want) || total != 3 {
+			t.Fatalf("expected counts %v over 3 techniques after reopen, got %v over %d", want, got, total)
+		}
+
+		// A re-import replaces the tactics of a technique
+		rows := [][]interface{}{
+			{"ID", "Name", "Description", "Domain", "Platform", "Created", "Modified", "Tactics"},
+			{"T1078", "Valid Accounts", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01", "Exfiltration"},
+		}
+		f = excelize.NewFile()
+		f.SetSheetName("Sheet1", "Techniques")
+		for i, row := range rows {
+			if err := f.SetSheetRow("Techniques", fmt.Sprintf("A%d", i+1), &row); err != nil {
+				t.Fatalf("failed to set row: %v", err)
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+		if err := store.ImportFromXLSX(xlsxPath, false); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+		coverage, _, err = store.GetTacticCoverage(ctx, false)
+		if err != nil {
+			t.Fatalf("GetTacticCoverage error: %v", err)
+		}
+		want = map[string]int{"TA0003": 0, "TA0004": 0, "TA0005": 1, "TA0010": 1}
+		if got := counts(coverage); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("expected counts after re-import %v, got %v", want, got)
+		}
+	})
+}
+
+func TestParentTechniqueID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParentTechniqueID", nil, func(t *testing.T, tx *gorm.DB) {
+		cases := map[string]string{"T1059.001": "T1059", "T1059": "", "": ""}
+		for id, want := range cases {
+			if got := parentTechniqueID(id); got != want {
+				t.Errorf("parentTechniqueID(%q) = %q, want %q", id, got, want)
+			}
+		}
+	})
+}
+
+func TestImportFromXLSXWithReport_DryRun(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestImportFromXLSXWithReport_DryRun", nil, func(t *testing.T, tx *gorm.DB) {
+		store, err := NewLocalAttackStore(filepath.Join(t.TempDir(), "attack.db"))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		ctx := context.Background()
+		if err := store.db.Create(&AttackTechnique{ID: "T1001", Name: "Old"}).Error; err != nil {
+			t.Fatalf("failed to seed technique: %v", err)
+		}
+
+		xlsxPath := filepath.Join(t.TempDir(), "attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Techniques")
+		rows := [][]interface{}{
+			{"ID", "Name", "Description", "Domain", "Platform", "Created", "Modified"},
+			{"T1001", "Data Obfuscation", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+			{"T1059", "Command and Scripting Interpreter", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+			{"", "No ID", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+			{"T1002", "Too short"},
+			{"T1059", "Command and Scripting Interpreter", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+		}
+		for i, row := range rows {
+			if err := f.SetSheetRow("Techniques", fmt.Sprintf("A%d", i+1), &row); err != nil {
+				t.Fatalf("failed to set row: %v", err)
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+
+		report, err := store.ImportFromXLSXWithReport(xlsxPath, false, true)
+		if err != nil {
+			t.Fatalf("ImportFromXLSXWithReport returned error: %v", err)
+		}
+		if !report.DryRun || report.Inserted != 1 || report.Updated != 1 {
+			t.Fatalf("expected 1 inserted and 1 updated, got %+v", report)
+		}
+		if len(report.Warnings) != 3 {
+			t.Fatalf("expected warnings for the missing ID, short row and repeated ID, got %v", report.Warnings)
+		}
+
+		// Nothing was stored
+		if teq, err := store.GetTechniqueByID(ctx, "T1001"); err != nil || teq.Name != "Old" {
+			t.Fatalf("expected T1001 unchanged, got %+v (err=%v)", teq, err)
+		}
+		if _, err := store.GetTechniqueByID(ctx, "T1059"); err == nil {
+			t.Fatalf("expected T1059 not to be stored")
+		}
+		if _, err := store.GetImportMetadata(ctx); err == nil {
+			t.Fatalf("expected no import metadata after a dry run")
+		}
+
+		// A real import stores the same rows
+		report, err = store.ImportFromXLSXWithReport(xlsxPath, false, false)
+		if err != nil || report.DryRun || report.Inserted != 1 || report.Updated != 1 {
+			t.Fatalf("unexpected import report %+v (err=%v)", report, err)
+		}
+		if _, err := store.GetTechniqueByID(ctx, "T1059"); err != nil {
+			t.Fatalf("expected T1059 to be stored: %v", err)
+		}
+
+		// Rows reported as skipped are not stored; a repeated ID is reported
+		// as replacing the earlier row
+		var stored int64
+		if err := store.db.Model(&AttackTechnique{}).Count(&stored).Error; err != nil || stored != 2 {
+			t.Fatalf("expected only T1001 and T1059 stored, got %d (err=%v)", stored, err)
+		}
+		if w := strings.Join(report.Warnings, "\n"); !strings.Contains(w, "has no ID; skipped") || !strings.Contains(w, "repeats T1059; it replaces the earlier row") {
+			t.Errorf("unexpected warnings %v", report.Warnings)
+		}
+	})
+}
+
+func TestGetTechniqueRelationships(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetTechniqueRelationships", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "attack.db")
+		store, err := NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		xlsxPath := filepath.Join(t.TempDir(), "attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "Techniques")
+		sheets := map[string][][]interface{}{
+			"Techniques": {
+				{"ID", "Name", "Description", "Domain", "Platform", "Created", "Modified"},
+				{"T1059", "Command and Scripting Interpreter", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+				{"T1001", "Data Obfuscation", "Desc", "enterprise", "linux", "2020-01-01", "2021-01-01"},
+			},
+			"Mitigations": {
+				{"ID", "Name", "Description", "Domain", "Created", "Modified"},
+				{"M1042", "Disable or Remove Feature or Program", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+				{"M1038", "Execution Prevention", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+			},
+			"Groups": {
+				{"ID", "Name", "Description", "Domain", "Created", "Modified"},
+				{"G0007", "APT28", "Desc", "enterprise", "2020-01-01", "2021-01-01"},
+			},
+			"Software": {
+				{"ID", "Name", "Description", "Type", "Domain", "Created", "Modified"},
+				{"S0154", "Cobalt Strike", "Desc", "malware", "enterprise", "2020-01-01", "2021-01-01"},
+			},
+			"Relationships": {
+				{"SourceRef", "TargetRef", "RelationshipType", "SourceObjectType", "TargetObjectType", "Description", "Domain"},
+				{"M1042", "T1059", "mitigates", "course-of-action", "attack-pattern", "Desc", "enterprise"},
+				{"M1038", "T1059", "mitigates", "", "", "Desc", "enterprise"},
+				{"G0007", "T1059", "uses", "intrusion-set", "attack-pattern", "Desc", "enterprise"},
+				{"T1059", "S0154", "uses", "attack-pattern", "malware", "Desc", "enterprise"},
+				// Not imported, and not a mitigation, group or software
+				{"S9999", "T1059", "uses", "tool", "attack-pattern", "Desc", "enterprise"},
+				{"T1059", "TA0002", "belongs-to", "attack-pattern", "x-mitre-tactic", "Desc", "enterprise"},
+			},
+		}
+		for name, rows := range sheets {
+			if name != "Techniques" {
+				f.NewSheet(name)
+			}
+			for i, row := range rows {
+				if err := f.SetSheetRow(name, fmt.Sprintf("A%d", i+1), &row); err != nil {
+					t.Fatalf("failed to set row: %v", err)
+				}
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+		if err := store.ImportFromXLSX(xlsxPath, true); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+
+		ctx := context.Background()
+		rels, err := store.GetTechniqueRelationships(ctx, "T1059")
+		if err != nil {
+			t.Fatalf("GetTechniqueRelationships error: %v", err)
+		}
+		if len(rels.Mitigations) != 2 || rels.Mitigations[0].ID != "M1038" || rels.Mitigations[1].ID != "M1042" {
+			t.Errorf("expected M1038 and M1042, got %+v", rels.Mitigations)
+		}
+		if len(rels.Groups) != 1 || rels.Groups[0].Name != "APT28" {
+			t.Errorf("expected APT28, got %+v", rels.Groups)
+		}
+		if len(rels.Software) != 1 || rels.Software[0].ID != "S0154" {
+			t.Errorf("expected S0154, got %+v", rels.Software)
+		}
+
+		rels, err = store.GetTechniqueRelationships(ctx, "T1001")
+		if err != nil {
+			t.Fatalf("GetTechniqueRelationships(T1001) error: %v", err)
+		}
+		if rels.Mitigations == nil || rels.Groups == nil || rels.Software == nil ||
+			len(rels.Mitigations)+len(rels.Groups)+len(rels.Software) != 0 {
+			t.Errorf("expected empty lists for T1001, got %+v", rels)
+		}
+
+		if _, err := store.GetTechniqueRelationships(ctx, "T9999"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected not found for an unknown technique, got %v", err)
+		}
+	})
+}
+
+func TestGetTechniqueRelationships_AttackWorkbookLayout(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestGetTechniqueRelationships_AttackWorkbookLayout", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "attack.db")
+		store, err := NewLocalAttackStore(dbPath)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		// Sheet and column names as in the enterprise-attack workbook
+		xlsxPath := filepath.Join(t.TempDir(), "enterprise-attack.xlsx")
+		f := excelize.NewFile()
+		f.SetSheetName("Sheet1", "techniques")
+		common := []interface{}{"ID", "STIX ID", "name", "description", "url", "created", "last modified", "domain", "version"}
+		sheets := map[string][][]interface{}{
+			"techniques": {
+				append(append([]interface{}{}, common...), "tactics", "detection", "platforms", "is sub-technique", "sub-technique of"),
+				{"T1059", "attack-pattern--1", "Command and Scripting Interpreter", "Desc", "", "2017-05-31", "2025-04-15", "enterprise-attack", "2.6", "Execution", "", "Linux", "False", ""},
+			},
+			"mitigations": {
+				common,
+				{"M1038", "course-of-action--1", "Execution Prevention", "Desc", "", "2019-06-11", "2025-04-15", "enterprise-attack", "1.1"},
+			},
+			"groups": {
+				common,
+				{"G0007", "intrusion-set--1", "APT28", "Desc", "", "2017-05-31", "2025-04-15", "enterprise-attack", "5.1"},
+			},
+			"software": {
+				append(append([]interface{}{}, common...), "contributors", "platforms", "aliases", "type"),
+				{"S0154", "malware--1", "Cobalt Strike", "Desc", "", "2018-10-17", "2025-04-15", "enterprise-attack", "1.14", "", "Windows", "", "malware"},
+			},
+			"relationships": {
+				{"source ID", "source name", "source ref", "source type", "mapping type", "target ID", "target name", "target ref", "target type", "mapping description", "STIX ID", "created", "last modified"},
+				{"M1038", "Execution Prevention", "course-of-action--1", "mitigation", "mitigates", "T1059", "Command and Scripting Interpreter", "attack-pattern--1", "technique", "Desc", "relationship--1", "11 June 2019", "15 April 2025"},
+				{"G0007", "APT28", "intrusion-set--1", "group", "uses", "T1059", "Command and Scripting Interpreter", "attack-pattern--1", "technique", "Desc", "relationship--2", "31 May 2017", "15 April 2025"},
+				{"S0154", "Cobalt Strike", "malware--1", "software", "uses", "T1059", "Command and Scripting Interpreter", "attack-pattern--1", "technique", "Desc", "relationship--3", "17 October 2018", "15 April 2025"},
+				{"C0028", "2015 Ukraine Electric Power Attack", "campaign--1", "campaign", "uses", "T1059", "Command and Scripting Interpreter", "attack-pattern--1", "technique", "Desc", "relationship--4", "27 September 2023", "16 April 2025"},
+			},
+		}
+		for name, rows := range sheets {
+			if name != "techniques" {
+				f.NewSheet(name)
+			}
+			for i, row := range rows {
+				if err := f.SetSheetRow(name, fmt.Sprintf("A%d", i+1), &row); err != nil {
+					t.Fatalf("failed to set row: %v", err)
+				}
+			}
+		}
+		if err := f.SaveAs(xlsxPath); err != nil {
+			t.Fatalf("failed to save xlsx: %v", err)
+		}
+		if err := store.ImportFromXLSX(xlsxPath, true); err != nil {
+			t.Fatalf("ImportFromXLSX returned error: %v", err)
+		}
+
+		var rel AttackRelationship
+		if err := store.db.First(&rel, "id = ?", "relationship--1").Error; err != nil {
+			t.Fatalf("relationship--1 not imported: %v", err)
+		}
+		if rel.SourceRef != "M1038" || rel.TargetRef != "T1059" || rel.RelationshipType != "mitigates" ||
+			rel.SourceObjectType != "mitigation" || rel.TargetObjectType != "technique" {
+			t.Errorf("unexpected relationship columns: %+v", rel)
+		}
+
+		rels, err := store.GetTechniqueRelationships(context.Background(), "T1059")
+		if err != nil {
+			t.Fatalf("GetTechniqueRelationships error: %v", err)
+		}
+		if len(rels.Mitigations) != 1 || rels.Mitigations[0].ID != "M1038" {
+			t.Errorf("expected M1038, got %+v", rels.Mitigations)
+		}
+		if len(rels.Groups) != 1 || rels.Groups[0].ID != "G0007" {
+			t.Errorf("expected G0007, got %+v", rels.Groups)
+		}
+		if len(rels.Software) != 1 || rels.Software[0].ID != "S0154" {
+			t.Errorf("expected S0154, got %+v", rels.Software)
+		}
+	})
+}
@@ -11,6 +11,9 @@ type AttackTechnique struct {
 	Modified    string `json:"modified"`   // Last modified date
 	Revoked     bool   `json:"revoked"`    // Whether the technique is revoked
 	Deprecated  bool   `json:"deprecated"` // Whether the technique is deprecated
+	// ParentID is the technique a sub-technique belongs to, e.g. "T1059"
+	// for "T1059.001"; empty for a top-level technique
+	ParentID string `json:"parent_id,omitempty" gorm:"index"`
 }
 
 // ATT&CK Tactic represents a tactic in the ATT&CK framework
@@ -23,6 +26,22 @@ type AttackTactic struct {
 	Modified    string `json:"modified"` // Last modified date
 }
 
+// AttackTechniqueTactic links a technique to one of the tactics it belongs to,
+// as listed in the technique's "Tactics" column. A technique may belong to
+// several tactics.
+type AttackTechniqueTactic struct {
+	TechniqueID string `json:"technique_id" gorm:"primaryKey"` // e.g. "T1078"
+	Tactic      string `json:"tactic" gorm:"primaryKey"`       // tactic name, e.g. "Defense Evasion"
+}
+
+// TacticCoverage is the number of techniques that belong to a tactic
+type TacticCoverage struct {
+	ID     string `json:"id"` // e.g. "TA0001"
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
+	Count  int    `json:"count"`
+}
+
 // ATT&CK Mitigation represents a mitigation in the ATT&CK framework
 type AttackMitigation struct {
 	ID          string `json:"id"` // e.g. "M1001"
@@ -75,4 +94,5 @@ type AttackMetadata struct {
 	SourceFile    string `json:"source_file"`    // Path to the imported XLSX file
 	TotalRecords  int    `json:"total_records"`  // Total number of records imported
 	ImportVersion string `json:"import_version"` // Version of the ATT&CK dataset
+	VersionSource string `json:"version_source"` // catalog or file_mtime, see catalog.ResolveVersion
 }
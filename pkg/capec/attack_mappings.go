@@ -0,0 +1,61 @@
+package capec
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TaxonomyNameATTACK is the Taxonomy_Name of ATT&CK taxonomy mappings
+const TaxonomyNameATTACK = "ATTACK"
+
+// attackEntryIDRe matches ATT&CK entry IDs as CAPEC writes them: a technique
+// number with an optional sub-technique, with or without the T prefix
+var attackEntryIDRe = regexp.MustCompile(`^T?(\d{4}(\.\d{3})?)$`)
+
+// AttackTechniqueID returns the ATT&CK technique ID (e.g. "T1574.010") for
+// the Entry_ID of an ATT&CK taxonomy mapping (e.g. "1574.010"), or "" if the
+// entry is not a technique ID
+func AttackTechniqueID(entryID string) string {
+	m := attackEntryIDRe.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(entryID)))
+	if m == nil {
+		return ""
+	}
+	return "T" + m[1]
+}
+
+// saveAttackMappings replaces the ATT&CK mappings of a CAPEC with the ATT&CK
+// entries of its taxonomy mappings. Other taxonomies (WASC, OWASP) are
+// ignored, as are duplicates and malformed entry IDs.
+func saveAttackMappings(tx *gorm.DB, capecID int, mappings []TaxonomyMapping) error {
+	if err := tx.Where("capec_id = ?", capecID).Delete(&CAPECAttackMappingModel{}).Error; err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, m := range mappings {
+		if !strings.EqualFold(strings.TrimSpace(m.TaxonomyName), TaxonomyNameATTACK) {
+			continue
+		}
+		techniqueID := AttackTechniqueID(m.EntryID)
+		if techniqueID == "" || seen[techniqueID] {
+			continue
+		}
+		seen[techniqueID] = true
+		row := CAPECAttackMappingModel{CAPECID: capecID, TechniqueID: techniqueID, TechniqueName: strings.TrimSpace(m.EntryName)}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getAttackMappings returns the ATT&CK mappings of a CAPEC in technique order
+func getAttackMappings(ctx context.Context, db *gorm.DB, capecID int) ([]CAPECAttackMappingModel, error) {
+	var rows []CAPECAttackMappingModel
+	if err := db.WithContext(ctx).Where("capec_id = ?", capecID).Order("technique_id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
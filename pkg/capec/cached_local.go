@@ -15,6 +15,9 @@ import (
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"github.com/lestrrat-go/libxml2/parser"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -37,7 +40,7 @@ type CachedLocalCAPECStore struct {
 
 // NewCachedLocalCAPECStore creates or opens a local CAPEC database at dbPath with caching.
 func NewCachedLocalCAPECStore(dbPath string) (*CachedLocalCAPECStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{
 		// Enable prepared statement caching for better performance
 		PrepareStmt: true,
 	})
@@ -55,7 +58,7 @@ func NewCachedLocalCAPECStore(dbPath string) (*CachedLocalCAPECStore, error) {
 		db.Exec("PRAGMA cache_size=-40000")
 	}
 
-	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECCatalogMeta{}); err != nil {
+	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECAttackMappingModel{}, &CAPECCatalogMeta{}); err != nil {
 		return nil, err
 	}
 
@@ -137,21 +140,57 @@ func (s *CachedLocalCAPECStore) invalidateCachedCAPEC(capecID int) {
 	delete(s.cache, capecID)
 }
 
-// ImportFromXML imports CAPEC items from XML into DB without XSD validation.
+// ImportFromXML imports CAPEC items from XML into DB without XSD validation;
+// the file must still be well-formed.
 // This method invalidates the cache after import since data has changed.
 func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
+	_, err := s.ImportFromXMLWithReport(xmlPath, force, false)
+	return err
+}
+
+// ImportFromXMLWithReport imports CAPEC items like ImportFromXML and reports
+// how many were inserted and updated, with validation warnings. With dryRun
+// the file is parsed, validated and written in a transaction that is rolled
+// back, so nothing is stored.
+func (s *CachedLocalCAPECStore) ImportFromXMLWithReport(xmlPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	return s.ImportFromXMLWithSchema(xmlPath, "", force, dryRun)
+}
+
+// ImportFromXMLWithSchema imports CAPEC items like ImportFromXMLWithReport
+// after checking that the file is well-formed and, unless force is set,
+// that it conforms to the XSD at xsdPath (skipped if empty). A file that
+// fails either check, e.g. a truncated download, is rejected with a
+// *SchemaError before anything is written.
+func (s *CachedLocalCAPECStore) ImportFromXMLWithSchema(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	// The import writes in a transaction that a lock conflict rolls back
+	// whole, so it is safe to start over from the file
+	var report *catalog.ImportReport
+	err := dbretry.Do(func() error {
+		var err error
+		report, err = s.importFromXML(xmlPath, xsdPath, force, dryRun)
+		return err
+	})
+	return report, err
+}
+
+// importFromXML makes one attempt at ImportFromXMLWithSchema
+func (s *CachedLocalCAPECStore) importFromXML(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	report := catalog.NewImportReport(dryRun)
+	if err := validateImport(xmlPath, xsdPath, force, report); err != nil {
+		return nil, err
+	}
 	common.Info("Importing CAPEC data from XML file: %s", xmlPath)
 
 	// Parse XML file into a libxml2 document using the parser package
 	xf, err := os.Open(xmlPath)
 	if err != nil {
-		return fmt.Errorf("failed to open xml: %w", err)
+		return nil, fmt.Errorf("failed to open xml: %w", err)
 	}
 	defer xf.Close()
 	p := parser.New()
 	doc, err := p.ParseReader(xf)
 	if err != nil {
-		return fmt.Errorf("failed to parse xml: %w", err)
+		return nil, fmt.Errorf("failed to parse xml: %w", err)
 	}
 	defer func() {
 		if doc != nil {
@@ -179,22 +218,21 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 		if err := s.db.First(&meta).Error; err == nil {
 			if meta.Version == catalogVersion {
 				common.Info("CAPEC catalog version %s already imported; skipping import", catalogVersion)
-				return nil
+				report.Warnf("catalog version %s is already imported; the import is skipped unless forced", catalogVersion)
+				return report, nil
 			}
 		}
 	}
 
-	// Skip XSD validation entirely - this ensures imports work without XSD schema
-	common.Info("Skipping XSD validation as per security requirement; continuing with permissive import")
-
 	// Parse XML into attack pattern structs (streaming)
 	f, err := os.Open(xmlPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
 	decoder := xml.NewDecoder(f)
+	seen := make(map[int]bool)
 
 	tx := s.db.Begin()
 	defer func() {
@@ -210,7 +248,7 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 		}
 		if err != nil {
 			tx.Rollback()
-			return err
+			return nil, err
 		}
 		switch se := t.(type) {
 		case xml.StartElement:
@@ -218,7 +256,11 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 				var ap CAPECAttackPattern
 				if err := decoder.DecodeElement(&ap, &se); err != nil {
 					tx.Rollback()
-					return err
+					return nil, err
+				}
+				if err := checkAttackPattern(tx, report, seen, &ap); err != nil {
+					tx.Rollback()
+					return nil, err
 				}
 				// Upsert CAPEC item; ensure we populate Abstraction/Status and compute a
 				// summary fallback when Summary is empty.
@@ -238,7 +280,7 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 				}
 				if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&item).Error; err != nil {
 					tx.Rollback()
-					return err
+					return nil, err
 				}
 				// Related weaknesses
 				tx.Where("capec_id = ?", ap.ID).Delete(&CAPECRelatedWeaknessModel{})
@@ -250,7 +292,7 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 						r := CAPECRelatedWeaknessModel{CAPECID: ap.ID, CWEID: cwe.CWEID}
 						if err := tx.Create(&r).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 				}
@@ -261,7 +303,7 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 					e := strings.TrimSpace(ex.XML)
 					if err := tx.Create(&CAPECExampleModel{CAPECID: ap.ID, ExampleText: e}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 				}
 
@@ -271,7 +313,7 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 					mm := strings.TrimSpace(m.XML)
 					if err := tx.Create(&CAPECMitigationModel{CAPECID: ap.ID, MitigationText: mm}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 				}
 
@@ -285,31 +327,36 @@ func (s *CachedLocalCAPECStore) ImportFromXML(xmlPath string, force bool) error
 						seenRefs[ref] = true
 						if err := tx.Create(&CAPECReferenceModel{CAPECID: ap.ID, ExternalReference: ref, URL: ""}).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 				}
+
+				// ATT&CK techniques from the taxonomy mappings
+				if err := saveAttackMappings(tx, ap.ID, ap.TaxonomyMappings); err != nil {
+					tx.Rollback()
+					return nil, err
+				}
 			}
 		}
 	}
 
+	if dryRun {
+		tx.Rollback()
+		return report, nil
+	}
 	if err := tx.Commit().Error; err != nil {
-		return err
+		return nil, err
 	}
 	// persist catalog metadata
-	if catalogVersion != "" {
-		// Use a fixed primary key to ensure a single-row metadata table.
-		meta := CAPECCatalogMeta{ID: 1, Version: catalogVersion, Source: xmlPath, ImportedAtUTC: time.Now().UTC().Unix()}
-		// upsert single-row meta by primary key
-		if err := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&meta).Error; err != nil {
-			return err
-		}
+	if err := saveCatalogMeta(s.db, catalogVersion, xmlPath); err != nil {
+		return nil, err
 	}
 
 	// Invalidate entire cache after import since data has changed
 	s.invalidateAllCache()
 
-	return nil
+	return report, nil
 }
 
 // invalidateAllCache removes all entries from the cache
@@ -329,3 +376,14 @@ func (s *CachedLocalCAPECStore) Close() error {
 	}
 	return sqlDB.Close()
 }
+
+// CatalogVersion returns the version, source, import time and entry count
+// of the loaded CAPEC catalog
+func (s *CachedLocalCAPECStore) CatalogVersion(ctx context.Context) (*catalog.Version, error) {
+	return loadCatalogVersion(ctx, s.db)
+}
+
+// DataCapabilities reports which CAPEC features are usable
+func (s *CachedLocalCAPECStore) DataCapabilities(ctx context.Context) (*capability.Capabilities, error) {
+	return loadDataCapabilities(ctx, s.db)
+}
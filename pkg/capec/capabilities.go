@@ -0,0 +1,30 @@
+package capec
+
+import (
+	"context"
+
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"gorm.io/gorm"
+)
+
+// loadDataCapabilities reports whether CAPEC patterns are stored, whether a
+// full-text index exists and whether related weaknesses link them to CWEs
+func loadDataCapabilities(ctx context.Context, db *gorm.DB) (*capability.Capabilities, error) {
+	c := &capability.Capabilities{DataType: "capec"}
+	var err error
+	if c.HasData, err = capability.HasRows(ctx, db, &CAPECItemModel{}); err != nil {
+		return nil, err
+	}
+	if c.FTSAvailable, err = capability.HasFTSTable(ctx, db, "capec_fts"); err != nil {
+		return nil, err
+	}
+	if c.MappingsBuilt, err = capability.HasRows(ctx, db, &CAPECRelatedWeaknessModel{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DataCapabilities reports which CAPEC features are usable
+func (s *LocalCAPECStore) DataCapabilities(ctx context.Context) (*capability.Capabilities, error) {
+	return loadDataCapabilities(ctx, s.db)
+}
@@ -0,0 +1,48 @@
+package capec
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// saveCatalogMeta records the catalog imported from xmlPath in the
+// single-row meta table. A catalog without a Version attribute is recorded
+// with its file's modification time instead.
+func saveCatalogMeta(db *gorm.DB, declaredVersion, xmlPath string) error {
+	version, source := catalog.ResolveVersion(declaredVersion, xmlPath)
+	// Use a fixed primary key to ensure a single-row metadata table.
+	meta := CAPECCatalogMeta{ID: 1, Version: version, VersionSource: source, Source: xmlPath, ImportedAtUTC: time.Now().UTC().Unix()}
+	// upsert single-row meta by primary key
+	return db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&meta).Error
+}
+
+// loadCatalogVersion reports the loaded catalog's metadata and entry count
+func loadCatalogVersion(ctx context.Context, db *gorm.DB) (*catalog.Version, error) {
+	v := &catalog.Version{Taxonomy: catalog.TaxonomyCAPEC}
+	var meta CAPECCatalogMeta
+	err := db.WithContext(ctx).First(&meta).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		v.Version = meta.Version
+		v.VersionSource = meta.VersionSource
+		v.Source = meta.Source
+		v.ImportedAt = meta.ImportedAtUTC
+	}
+	if err := db.WithContext(ctx).Model(&CAPECItemModel{}).Count(&v.EntryCount).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CatalogVersion returns the version, source, import time and entry count
+// of the loaded CAPEC catalog
+func (s *LocalCAPECStore) CatalogVersion(ctx context.Context) (*catalog.Version, error) {
+	return loadCatalogVersion(ctx, s.db)
+}
@@ -0,0 +1,36 @@
+package capec
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NormalizeCWEID returns the number of a CWE ID given as "CWE-79" or "79"
+func NormalizeCWEID(cweID string) (string, error) {
+	n := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(cweID)), "CWE-")
+	if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+		return "", fmt.Errorf("invalid CWE ID %q: must be CWE-<number> or <number>", cweID)
+	}
+	return n, nil
+}
+
+// getCAPECIDsByCWE returns the numeric IDs of the CAPECs whose related
+// weaknesses include a CWE, in ID order. The catalog writes the number
+// ("79"); the "CWE-79" form some files use matches too.
+func getCAPECIDsByCWE(ctx context.Context, db *gorm.DB, cweID string) ([]int, error) {
+	n, err := NormalizeCWEID(cweID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	err = db.WithContext(ctx).Model(&CAPECRelatedWeaknessModel{}).
+		Where("cwe_id IN ?", []string{n, "CWE-" + n}).Distinct().Order("capec_id asc").Pluck("capec_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
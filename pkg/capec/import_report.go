@@ -0,0 +1,44 @@
+package capec
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"gorm.io/gorm"
+)
+
+// checkAttackPattern validates an attack pattern about to be imported and
+// counts it in report as inserted or updated. It must be called before the
+// pattern is written to tx. A pattern seen earlier in the file only adds a
+// warning, as the later one overwrites it.
+func checkAttackPattern(tx *gorm.DB, report *catalog.ImportReport, seen map[int]bool, ap *CAPECAttackPattern) error {
+	if ap.ID <= 0 {
+		report.Warnf("attack pattern %q has no valid ID", ap.Name)
+	}
+	if strings.TrimSpace(ap.Name) == "" {
+		report.Warnf("CAPEC-%d has no name", ap.ID)
+	}
+	for _, w := range ap.RelatedWeaknesses {
+		if _, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(w.CWEID), "CWE-")); err != nil {
+			report.Warnf("CAPEC-%d has an invalid related weakness %q", ap.ID, w.CWEID)
+		}
+	}
+	for _, m := range ap.TaxonomyMappings {
+		if strings.EqualFold(strings.TrimSpace(m.TaxonomyName), TaxonomyNameATTACK) && AttackTechniqueID(m.EntryID) == "" {
+			report.Warnf("CAPEC-%d has an ATT&CK mapping to %q, which is not a technique ID", ap.ID, m.EntryID)
+		}
+	}
+
+	if seen[ap.ID] {
+		report.Warnf("CAPEC-%d appears more than once; the last one is kept", ap.ID)
+		return nil
+	}
+	seen[ap.ID] = true
+	var n int64
+	if err := tx.Model(&CAPECItemModel{}).Where("capec_id = ?", ap.ID).Count(&n).Error; err != nil {
+		return err
+	}
+	report.Record(n > 0)
+	return nil
+}
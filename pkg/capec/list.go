@@ -0,0 +1,95 @@
+package capec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sort keys accepted by ListCAPECsFiltered
+const (
+	SortByID   = "id"
+	SortByName = "name"
+)
+
+// Sort orders accepted by ListCAPECsFiltered
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// abstractionLevels maps the lowercase abstraction levels of CAPEC attack
+// patterns to the form used in the catalog
+var abstractionLevels = map[string]string{
+	"meta":     "Meta",
+	"standard": "Standard",
+	"detailed": "Detailed",
+}
+
+// NormalizeAbstraction returns the catalog form of an abstraction level
+// ("Meta", "Standard" or "Detailed"), matched case-insensitively, or an error
+// for any other value
+func NormalizeAbstraction(level string) (string, error) {
+	if canonical, ok := abstractionLevels[strings.ToLower(strings.TrimSpace(level))]; ok {
+		return canonical, nil
+	}
+	return "", fmt.Errorf("invalid abstraction level %q: must be Meta, Standard or Detailed", level)
+}
+
+// listCAPECs returns a page of CAPEC items, optionally restricted to one
+// abstraction level, sorted by sortBy (SortByID when empty) in the given order
+// (OrderAsc when empty). total counts the items matching the filter.
+func listCAPECs(ctx context.Context, db *gorm.DB, offset, limit int, abstraction, sortBy, order string) ([]CAPECItemModel, int64, error) {
+	var column string
+	switch strings.ToLower(sortBy) {
+	case "", SortByID:
+		column = "capec_id"
+	case SortByName:
+		column = "name"
+	default:
+		return nil, 0, fmt.Errorf("invalid sortBy %q: must be %s or %s", sortBy, SortByID, SortByName)
+	}
+	switch strings.ToLower(order) {
+	case "", OrderAsc:
+		order = OrderAsc
+	case OrderDesc:
+		order = OrderDesc
+	default:
+		return nil, 0, fmt.Errorf("invalid order %q: must be %s or %s", order, OrderAsc, OrderDesc)
+	}
+
+	query := db.WithContext(ctx).Model(&CAPECItemModel{})
+	if abstraction != "" {
+		level, err := NormalizeAbstraction(abstraction)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("abstraction = ?", level)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	// Items with the same name keep a stable order across pages
+	orderBy := column + " " + order
+	if column != "capec_id" {
+		orderBy += ", capec_id " + order
+	}
+	var items []CAPECItemModel
+	if err := query.Order(orderBy).Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Count returns the total number of CAPEC items
+func (s *LocalCAPECStore) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&CAPECItemModel{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -11,9 +11,10 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -28,7 +29,7 @@ type LocalCAPECStore struct {
 
 // NewLocalCAPECStore creates or opens a local CAPEC database at dbPath.
 func NewLocalCAPECStore(dbPath string) (*LocalCAPECStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -43,26 +44,62 @@ func NewLocalCAPECStore(dbPath string) (*LocalCAPECStore, error) {
 		db.Exec("PRAGMA cache_size=-40000")
 	}
 
-	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECCatalogMeta{}); err != nil {
+	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECAttackMappingModel{}, &CAPECCatalogMeta{}); err != nil {
 		return nil, err
 	}
 	return &LocalCAPECStore{db: db}, nil
 }
 
-// ImportFromXML imports CAPEC items from XML into DB without XSD validation.
+// ImportFromXML imports CAPEC items from XML into DB without XSD validation;
+// the file must still be well-formed.
 func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
+	_, err := s.ImportFromXMLWithReport(xmlPath, force, false)
+	return err
+}
+
+// ImportFromXMLWithReport imports CAPEC items like ImportFromXML and reports
+// how many were inserted and updated, with validation warnings. With dryRun
+// the file is parsed, validated and written in a transaction that is rolled
+// back, so nothing is stored.
+func (s *LocalCAPECStore) ImportFromXMLWithReport(xmlPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	return s.ImportFromXMLWithSchema(xmlPath, "", force, dryRun)
+}
+
+// ImportFromXMLWithSchema imports CAPEC items like ImportFromXMLWithReport
+// after checking that the file is well-formed and, unless force is set,
+// that it conforms to the XSD at xsdPath (skipped if empty). A file that
+// fails either check, e.g. a truncated download, is rejected with a
+// *SchemaError before anything is written.
+func (s *LocalCAPECStore) ImportFromXMLWithSchema(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	// The import writes in a transaction that a lock conflict rolls back
+	// whole, so it is safe to start over from the file
+	var report *catalog.ImportReport
+	err := dbretry.Do(func() error {
+		var err error
+		report, err = s.importFromXML(xmlPath, xsdPath, force, dryRun)
+		return err
+	})
+	return report, err
+}
+
+// importFromXML makes one attempt at ImportFromXMLWithSchema
+func (s *LocalCAPECStore) importFromXML(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	report := catalog.NewImportReport(dryRun)
+	if err := validateImport(xmlPath, xsdPath, force, report); err != nil {
+		return nil, err
+	}
 	common.Info("Importing CAPEC data from XML file: %s", xmlPath)
 
 	// Parse XML file into a libxml2 document using the parser package
 	xf, err := os.Open(xmlPath)
 	if err != nil {
-		return fmt.Errorf("failed to open xml: %w", err)
+		return nil, fmt.Errorf("failed to open xml: %w", err)
 	}
 	defer xf.Close()
 	p := parser.New()
 	doc, err := p.ParseReader(xf)
 	if err != nil {
-		return fmt.Errorf("failed to parse xml: %w", err)
+		return nil, fmt.Errorf("failed to parse xml: %w", err)
 	}
 	defer func() {
 		if doc != nil {
@@ -90,22 +127,21 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 		if err := s.db.First(&meta).Error; err == nil {
 			if meta.Version == catalogVersion {
 				common.Info(LogMsgImportSkipped, catalogVersion)
-				return nil
+				report.Warnf("catalog version %s is already imported; the import is skipped unless forced", catalogVersion)
+				return report, nil
 			}
 		}
 	}
 
-	// Skip XSD validation entirely - this ensures imports work without XSD schema
-	common.Info("Skipping XSD validation as per security requirement; continuing with permissive import")
-
 	// Parse XML into attack pattern structs (streaming)
 	f, err := os.Open(xmlPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
 	decoder := xml.NewDecoder(f)
+	seen := make(map[int]bool)
 
 	tx := s.db.Begin()
 	defer func() {
@@ -121,7 +157,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 		}
 		if err != nil {
 			tx.Rollback()
-			return err
+			return nil, err
 		}
 		switch se := t.(type) {
 		case xml.StartElement:
@@ -129,7 +165,11 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 				var ap CAPECAttackPattern
 				if err := decoder.DecodeElement(&ap, &se); err != nil {
 					tx.Rollback()
-					return err
+					return nil, err
+				}
+				if err := checkAttackPattern(tx, report, seen, &ap); err != nil {
+					tx.Rollback()
+					return nil, err
 				}
 				// Upsert CAPEC item; ensure we populate Abstraction/Status and compute a
 				// summary fallback when Summary is empty. Description is stored as
@@ -150,7 +190,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 				}
 				if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&item).Error; err != nil {
 					tx.Rollback()
-					return err
+					return nil, err
 				}
 				// Related weaknesses
 				tx.Where("capec_id = ?", ap.ID).Delete(&CAPECRelatedWeaknessModel{})
@@ -162,7 +202,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 						r := CAPECRelatedWeaknessModel{CAPECID: ap.ID, CWEID: cwe.CWEID}
 						if err := tx.Create(&r).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 				}
@@ -173,7 +213,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 					e := strings.TrimSpace(ex.XML)
 					if err := tx.Create(&CAPECExampleModel{CAPECID: ap.ID, ExampleText: e}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 				}
 
@@ -183,7 +223,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 					mm := strings.TrimSpace(m.XML)
 					if err := tx.Create(&CAPECMitigationModel{CAPECID: ap.ID, MitigationText: mm}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 				}
 
@@ -197,27 +237,32 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 						seenRefs[ref] = true
 						if err := tx.Create(&CAPECReferenceModel{CAPECID: ap.ID, ExternalReference: ref, URL: ""}).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 				}
+
+				// ATT&CK techniques from the taxonomy mappings
+				if err := saveAttackMappings(tx, ap.ID, ap.TaxonomyMappings); err != nil {
+					tx.Rollback()
+					return nil, err
+				}
 			}
 		}
 	}
 
+	if dryRun {
+		tx.Rollback()
+		return report, nil
+	}
 	if err := tx.Commit().Error; err != nil {
-		return err
+		return nil, err
 	}
 	// persist catalog metadata
-	if catalogVersion != "" {
-		// Use a fixed primary key to ensure a single-row metadata table.
-		meta := CAPECCatalogMeta{ID: 1, Version: catalogVersion, Source: xmlPath, ImportedAtUTC: time.Now().UTC().Unix()}
-		// upsert single-row meta by primary key
-		if err := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&meta).Error; err != nil {
-			return err
-		}
+	if err := saveCatalogMeta(s.db, catalogVersion, xmlPath); err != nil {
+		return nil, err
 	}
-	return nil
+	return report, nil
 }
 
 // GetByID returns a CAPEC item by its textual ID (e.g. "CAPEC-123" or "123").
@@ -240,15 +285,15 @@ func (s *LocalCAPECStore) GetByID(ctx context.Context, id string) (*CAPECItemMod
 
 // ListCAPECsPaginated returns CAPEC items with pagination.
 func (s *LocalCAPECStore) ListCAPECsPaginated(ctx context.Context, offset, limit int) ([]CAPECItemModel, int64, error) {
-	var items []CAPECItemModel
-	var total int64
-	if err := s.db.WithContext(ctx).Model(&CAPECItemModel{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-	if err := s.db.WithContext(ctx).Order("capec_id asc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
-		return nil, 0, err
-	}
-	return items, total, nil
+	return listCAPECs(ctx, s.db, offset, limit, "", SortByID, OrderAsc)
+}
+
+// ListCAPECsFiltered returns CAPEC items with pagination, optionally
+// restricted to one abstraction level ("Meta", "Standard" or "Detailed") and
+// sorted by SortByID or SortByName in OrderAsc or OrderDesc order. Empty
+// values mean no filter and ascending ID order.
+func (s *LocalCAPECStore) ListCAPECsFiltered(ctx context.Context, offset, limit int, abstraction, sortBy, order string) ([]CAPECItemModel, int64, error) {
+	return listCAPECs(ctx, s.db, offset, limit, abstraction, sortBy, order)
 }
 
 // GetRelatedWeaknesses returns related CWE IDs for a given CAPEC numeric ID.
@@ -287,6 +332,17 @@ func (s *LocalCAPECStore) GetReferences(ctx context.Context, capecID int) ([]CAP
 	return rows, nil
 }
 
+// GetAttackMappings returns the ATT&CK techniques a CAPEC numeric ID maps to.
+func (s *LocalCAPECStore) GetAttackMappings(ctx context.Context, capecID int) ([]CAPECAttackMappingModel, error) {
+	return getAttackMappings(ctx, s.db, capecID)
+}
+
+// GetCAPECIDsByCWE returns the numeric IDs of the CAPECs related to a CWE
+// ID ("CWE-79" or "79"), in ID order.
+func (s *LocalCAPECStore) GetCAPECIDsByCWE(ctx context.Context, cweID string) ([]int, error) {
+	return getCAPECIDsByCWE(ctx, s.db, cweID)
+}
+
 // GetCatalogMeta returns the stored CAPEC catalog metadata (single row expected)
 func (s *LocalCAPECStore) GetCatalogMeta(ctx context.Context) (*CAPECCatalogMeta, error) {
 	var meta CAPECCatalogMeta
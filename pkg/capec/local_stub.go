@@ -10,8 +10,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -25,23 +26,60 @@ type LocalCAPECStore struct {
 // NewLocalCAPECStore creates or opens a local CAPEC database at dbPath.
 // This stub implementation mirrors the DB setup but does not perform XML validation.
 func NewLocalCAPECStore(dbPath string) (*LocalCAPECStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 	// AutoMigrate minimal tables to allow app to run; detailed imports require libxml2 build tag.
-	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECCatalogMeta{}); err != nil {
+	if err := db.AutoMigrate(&CAPECItemModel{}, &CAPECRelatedWeaknessModel{}, &CAPECExampleModel{}, &CAPECMitigationModel{}, &CAPECReferenceModel{}, &CAPECAttackMappingModel{}, &CAPECCatalogMeta{}); err != nil {
 		return nil, err
 	}
 	return &LocalCAPECStore{db: db}, nil
 }
 
-// ImportFromXML imports CAPEC items from XML into DB without XSD validation.
+// ImportFromXML imports CAPEC items from XML into DB without XSD validation;
+// the file must still be well-formed.
 func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
-	// Permissive importer: parse the CAPEC XML without XSD validation
+	_, err := s.ImportFromXMLWithReport(xmlPath, force, false)
+	return err
+}
+
+// ImportFromXMLWithReport imports CAPEC items like ImportFromXML and reports
+// how many were inserted and updated, with validation warnings. With dryRun
+// the file is parsed and validated, and each pattern is written in a
+// transaction that is rolled back, so nothing is stored.
+func (s *LocalCAPECStore) ImportFromXMLWithReport(xmlPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	return s.ImportFromXMLWithSchema(xmlPath, "", force, dryRun)
+}
+
+// ImportFromXMLWithSchema imports CAPEC items like ImportFromXMLWithReport
+// after checking that the file is well-formed and, unless force is set,
+// that it conforms to the XSD at xsdPath (skipped if empty). A file that
+// fails either check, e.g. a truncated download, is rejected with a
+// *SchemaError before anything is written.
+func (s *LocalCAPECStore) ImportFromXMLWithSchema(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	// Each pattern is upserted and its nested rows replaced, so after a lock
+	// conflict it is safe to start over from the file
+	var report *catalog.ImportReport
+	err := dbretry.Do(func() error {
+		var err error
+		report, err = s.importFromXML(xmlPath, xsdPath, force, dryRun)
+		return err
+	})
+	return report, err
+}
+
+// importFromXML makes one attempt at ImportFromXMLWithSchema
+func (s *LocalCAPECStore) importFromXML(xmlPath, xsdPath string, force, dryRun bool) (*catalog.ImportReport, error) {
+	report := catalog.NewImportReport(dryRun)
+	if err := validateImport(xmlPath, xsdPath, force, report); err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	// Permissive importer: the file was checked by validateImport
 	f, err := os.Open(xmlPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
@@ -57,7 +95,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return nil, err
 		}
 		se, ok := t.(xml.StartElement)
 		if !ok {
@@ -77,7 +115,8 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 				var meta CAPECCatalogMeta
 				if err := s.db.First(&meta).Error; err == nil {
 					if meta.Version == catalogVersion {
-						return nil
+						report.Warnf("catalog version %s is already imported; the import is skipped unless forced", catalogVersion)
+						return report, nil
 					}
 				}
 			}
@@ -87,15 +126,19 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 		}
 		// parse attributes
 		var capecID int
-		var nameAttr string
+		var nameAttr, statusAttr, abstractionAttr string
 		for _, a := range se.Attr {
-			if a.Name.Local == "ID" {
+			switch a.Name.Local {
+			case "ID":
 				if n, err := strconv.Atoi(a.Value); err == nil {
 					capecID = n
 				}
-			}
-			if a.Name.Local == "Name" {
+			case "Name":
 				nameAttr = a.Value
+			case "Status":
+				statusAttr = a.Value
+			case "Abstraction":
+				abstractionAttr = a.Value
 			}
 		}
 
@@ -108,15 +151,17 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 		var examples []string
 		var mitigations []string
 		var references []string
+		var taxonomyMappings []TaxonomyMapping
 
 		// read inner tokens until end of Attack_Pattern
+	pattern:
 		for {
 			it, err := dec.Token()
 			if err != nil {
 				if err == io.EOF {
 					break
 				}
-				return err
+				return nil, err
 			}
 			switch tt := it.(type) {
 			case xml.StartElement:
@@ -152,7 +197,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 					for {
 						inner, err := dec.Token()
 						if err != nil {
-							return err
+							return nil, err
 						}
 						if end, ok := inner.(xml.EndElement); ok && end.Name.Local == "Related_Weaknesses" {
 							break
@@ -169,7 +214,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 					for {
 						inner, err := dec.Token()
 						if err != nil {
-							return err
+							return nil, err
 						}
 						if end, ok := inner.(xml.EndElement); ok && end.Name.Local == "Example_Instances" {
 							break
@@ -186,7 +231,7 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 					for {
 						inner, err := dec.Token()
 						if err != nil {
-							return err
+							return nil, err
 						}
 						if end, ok := inner.(xml.EndElement); ok && end.Name.Local == "Mitigations" {
 							break
@@ -198,11 +243,27 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 							}
 						}
 					}
+				case "Taxonomy_Mappings":
+					for {
+						inner, err := dec.Token()
+						if err != nil {
+							return nil, err
+						}
+						if end, ok := inner.(xml.EndElement); ok && end.Name.Local == "Taxonomy_Mappings" {
+							break
+						}
+						if tm, ok := inner.(xml.StartElement); ok && tm.Name.Local == "Taxonomy_Mapping" {
+							var m TaxonomyMapping
+							if err := dec.DecodeElement(&m, &tm); err == nil {
+								taxonomyMappings = append(taxonomyMappings, m)
+							}
+						}
+					}
 				case "References":
 					for {
 						inner, err := dec.Token()
 						if err != nil {
-							return err
+							return nil, err
 						}
 						if end, ok := inner.(xml.EndElement); ok && end.Name.Local == "References" {
 							break
@@ -229,20 +290,29 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 							return truncateString(description, 200)
 						}(),
 						Description:     description,
-						Status:          "",
+						Status:          statusAttr,
+						Abstraction:     abstractionAttr,
 						Likelihood:      likelihood,
 						TypicalSeverity: typicalSeverity,
 					}
 					// use transaction
 					tx := s.db.Begin()
+					ap := CAPECAttackPattern{ID: capecID, Name: item.Name, TaxonomyMappings: taxonomyMappings}
+					for _, w := range weaknesses {
+						ap.RelatedWeaknesses = append(ap.RelatedWeaknesses, RelatedWeakness{CWEID: w})
+					}
+					if err := checkAttackPattern(tx, report, seen, &ap); err != nil {
+						tx.Rollback()
+						return nil, err
+					}
 					if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&item).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 					// replace related tables
 					if err := tx.Where("capec_id = ?", capecID).Delete(&CAPECRelatedWeaknessModel{}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 					// Deduplicate related weaknesses to avoid unique constraint violations
 					seenCWEs := make(map[string]bool)
@@ -252,35 +322,35 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 							rw := CAPECRelatedWeaknessModel{CAPECID: capecID, CWEID: w}
 							if err := tx.Create(&rw).Error; err != nil {
 								tx.Rollback()
-								return err
+								return nil, err
 							}
 						}
 					}
 					if err := tx.Where("capec_id = ?", capecID).Delete(&CAPECExampleModel{}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 					for _, e := range examples {
 						exm := CAPECExampleModel{CAPECID: capecID, ExampleText: e}
 						if err := tx.Create(&exm).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 					if err := tx.Where("capec_id = ?", capecID).Delete(&CAPECMitigationModel{}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 					for _, m := range mitigations {
 						mm := CAPECMitigationModel{CAPECID: capecID, MitigationText: m}
 						if err := tx.Create(&mm).Error; err != nil {
 							tx.Rollback()
-							return err
+							return nil, err
 						}
 					}
 					if err := tx.Where("capec_id = ?", capecID).Delete(&CAPECReferenceModel{}).Error; err != nil {
 						tx.Rollback()
-						return err
+						return nil, err
 					}
 					// Deduplicate references to avoid unique constraint violations
 					seenRefs := make(map[string]bool)
@@ -290,27 +360,32 @@ func (s *LocalCAPECStore) ImportFromXML(xmlPath string, force bool) error {
 							rr := CAPECReferenceModel{CAPECID: capecID, ExternalReference: r, URL: ""}
 							if err := tx.Create(&rr).Error; err != nil {
 								tx.Rollback()
-								return err
+								return nil, err
 							}
 						}
 					}
-					if err := tx.Commit().Error; err != nil {
-						return err
+					if err := saveAttackMappings(tx, capecID, taxonomyMappings); err != nil {
+						tx.Rollback()
+						return nil, err
+					}
+					if dryRun {
+						tx.Rollback()
+					} else if err := tx.Commit().Error; err != nil {
+						return nil, err
 					}
+					break pattern
 				}
 			}
 		}
 	}
+	if dryRun {
+		return report, nil
+	}
 	// persist catalog metadata
-	if catalogVersion != "" {
-		// Use a fixed primary key to ensure a single-row metadata table.
-		meta := CAPECCatalogMeta{ID: 1, Version: catalogVersion, Source: xmlPath, ImportedAtUTC: time.Now().Unix()}
-		// upsert single-row meta by primary key
-		if err := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&meta).Error; err != nil {
-			return err
-		}
+	if err := saveCatalogMeta(s.db, catalogVersion, xmlPath); err != nil {
+		return nil, err
 	}
-	return nil
+	return report, nil
 }
 
 // GetByID returns a CAPEC item by its textual ID (e.g. "CAPEC-123" or "123").
@@ -334,15 +409,15 @@ func (s *LocalCAPECStore) GetByID(ctx context.Context, id string) (*CAPECItemMod
 
 // ListCAPECsPaginated returns CAPEC items with pagination.
 func (s *LocalCAPECStore) ListCAPECsPaginated(ctx context.Context, offset, limit int) ([]CAPECItemModel, int64, error) {
-	var items []CAPECItemModel
-	var total int64
-	if err := s.db.WithContext(ctx).Model(&CAPECItemModel{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-	if err := s.db.WithContext(ctx).Order("capec_id asc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
-		return nil, 0, err
-	}
-	return items, total, nil
+	return listCAPECs(ctx, s.db, offset, limit, "", SortByID, OrderAsc)
+}
+
+// ListCAPECsFiltered returns CAPEC items with pagination, optionally
+// restricted to one abstraction level ("Meta", "Standard" or "Detailed") and
+// sorted by SortByID or SortByName in OrderAsc or OrderDesc order. Empty
+// values mean no filter and ascending ID order.
+func (s *LocalCAPECStore) ListCAPECsFiltered(ctx context.Context, offset, limit int, abstraction, sortBy, order string) ([]CAPECItemModel, int64, error) {
+	return listCAPECs(ctx, s.db, offset, limit, abstraction, sortBy, order)
 }
 
 // GetRelatedWeaknesses returns related CWE IDs for a given CAPEC numeric ID.
@@ -381,6 +456,17 @@ func (s *LocalCAPECStore) GetReferences(ctx context.Context, capecID int) ([]CAP
 	return rows, nil
 }
 
+// GetAttackMappings returns the ATT&CK techniques a CAPEC numeric ID maps to.
+func (s *LocalCAPECStore) GetAttackMappings(ctx context.Context, capecID int) ([]CAPECAttackMappingModel, error) {
+	return getAttackMappings(ctx, s.db, capecID)
+}
+
+// GetCAPECIDsByCWE returns the numeric IDs of the CAPECs related to a CWE
+// ID ("CWE-79" or "79"), in ID order.
+func (s *LocalCAPECStore) GetCAPECIDsByCWE(ctx context.Context, cweID string) ([]int, error) {
+	return getCAPECIDsByCWE(ctx, s.db, cweID)
+}
+
 // GetCatalogMeta returns the stored CAPEC catalog metadata (single row expected)
 func (s *LocalCAPECStore) GetCatalogMeta(ctx context.Context) (*CAPECCatalogMeta, error) {
 	var meta CAPECCatalogMeta
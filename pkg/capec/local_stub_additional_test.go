@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
 )
 
 func writeTempFile(t *testing.T, dir, name, content string) string {
@@ -62,6 +65,79 @@ func TestImportFromXML_PersistsData(t *testing.T) {
 
 }
 
+func TestImportFromXML_AttackMappings(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestImportFromXML_AttackMappings", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+
+		mappings := `<Taxonomy_Mappings>` +
+			`<Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>1574.010</Entry_ID><Entry_Name>Hijack Execution Flow: ServicesFile Permissions Weakness</Entry_Name></Taxonomy_Mapping>` +
+			`<Taxonomy_Mapping Taxonomy_Name="WASC"><Entry_ID>11</Entry_ID><Entry_Name>Brute Force</Entry_Name></Taxonomy_Mapping>` +
+			`<Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>1110</Entry_ID><Entry_Name>Brute Force</Entry_Name></Taxonomy_Mapping>` +
+			`<Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>T1110</Entry_ID><Entry_Name>Brute Force</Entry_Name></Taxonomy_Mapping>` +
+			`<Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>n/a</Entry_ID></Taxonomy_Mapping>` +
+			`</Taxonomy_Mappings>`
+		xmlPath := writeTempFile(t, dir, "capec.xml", `<?xml version="1.0"?><Attack_Patterns><Attack_Pattern ID="1" Name="Test"><Description>Desc</Description>`+mappings+`</Attack_Pattern><Attack_Pattern ID="2" Name="Unmapped"><Description>Desc</Description></Attack_Pattern></Attack_Patterns>`)
+		if err := store.ImportFromXML(xmlPath, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+
+		rows, err := store.GetAttackMappings(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("GetAttackMappings: %v", err)
+		}
+		if len(rows) != 2 || rows[0].TechniqueID != "T1110" || rows[1].TechniqueID != "T1574.010" || rows[1].TechniqueName != "Hijack Execution Flow: ServicesFile Permissions Weakness" {
+			t.Fatalf("expected the ATT&CK mappings only, once each, got %+v", rows)
+		}
+		if rows, _ := store.GetAttackMappings(context.Background(), 2); len(rows) != 0 {
+			t.Fatalf("expected no mappings, got %+v", rows)
+		}
+
+		// A re-import replaces the mappings
+		xmlPath = writeTempFile(t, dir, "capec2.xml", `<?xml version="1.0"?><Attack_Patterns><Attack_Pattern ID="1" Name="Test"><Description>Desc</Description><Taxonomy_Mappings><Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>1059</Entry_ID></Taxonomy_Mapping></Taxonomy_Mappings></Attack_Pattern></Attack_Patterns>`)
+		if err := store.ImportFromXML(xmlPath, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+		if rows, _ := store.GetAttackMappings(context.Background(), 1); len(rows) != 1 || rows[0].TechniqueID != "T1059" {
+			t.Fatalf("expected the mappings to be replaced, got %+v", rows)
+		}
+	})
+}
+
+func TestGetCAPECIDsByCWE(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestGetCAPECIDsByCWE", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+		xmlPath := writeTempFile(t, dir, "capec.xml", `<?xml version="1.0"?><Attack_Patterns>`+
+			`<Attack_Pattern ID="63" Name="XSS"><Description>Desc</Description><Related_Weaknesses><Related_Weakness CWE_ID="79" /><Related_Weakness CWE_ID="20" /></Related_Weaknesses></Attack_Pattern>`+
+			`<Attack_Pattern ID="7" Name="Blind"><Description>Desc</Description><Related_Weaknesses><Related_Weakness CWE_ID="CWE-79" /></Related_Weaknesses></Attack_Pattern>`+
+			`<Attack_Pattern ID="8" Name="Other"><Description>Desc</Description><Related_Weaknesses><Related_Weakness CWE_ID="89" /></Related_Weaknesses></Attack_Pattern>`+
+			`</Attack_Patterns>`)
+		if err := store.ImportFromXML(xmlPath, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+
+		for _, cweID := range []string{"CWE-79", "79", " cwe-79"} {
+			ids, err := store.GetCAPECIDsByCWE(context.Background(), cweID)
+			if err != nil || len(ids) != 2 || ids[0] != 7 || ids[1] != 63 {
+				t.Fatalf("GetCAPECIDsByCWE(%q) = %v, %v; want [7 63]", cweID, ids, err)
+			}
+		}
+		if ids, err := store.GetCAPECIDsByCWE(context.Background(), "CWE-1000"); err != nil || len(ids) != 0 {
+			t.Fatalf("expected no CAPECs, got %v, %v", ids, err)
+		}
+		if _, err := store.GetCAPECIDsByCWE(context.Background(), "XSS"); err == nil {
+			t.Fatal("expected an invalid CWE ID to be rejected")
+		}
+	})
+}
+
 func TestListCAPECsPaginated_ReturnsTotalAndItems(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestListCAPECsPaginated_ReturnsTotalAndItems", nil, func(t *testing.T, tx *gorm.DB) {
 		dir := t.TempDir()
@@ -83,3 +159,152 @@ func TestListCAPECsPaginated_ReturnsTotalAndItems(t *testing.T) {
 	})
 
 }
+
+func TestListCAPECsFiltered_SortsAndFiltersByAbstraction(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestListCAPECsFiltered_SortsAndFiltersByAbstraction", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("store: %v", err)
+		}
+		xmlContent := `<?xml version="1.0"?><Attack_Patterns>` +
+			`<Attack_Pattern ID="10" Name="Buffer Overflow" Abstraction="Standard" Status="Draft"><Description>d</Description></Attack_Pattern>` +
+			`<Attack_Pattern ID="2" Name="Account Lockout" Abstraction="Detailed" Status="Stable"><Description>d</Description></Attack_Pattern>` +
+			`<Attack_Pattern ID="100" Name="Overflow Buffers" Abstraction="Standard" Status="Stable"><Description>d</Description></Attack_Pattern>` +
+			`<Attack_Pattern ID="3" Name="Abuse Functionality" Abstraction="Meta" Status="Stable"><Description>d</Description></Attack_Pattern>` +
+			`</Attack_Patterns>`
+		if err := store.ImportFromXML(writeTempFile(t, dir, "capec.xml", xmlContent), true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+		ctx := context.Background()
+
+		// The abstraction level and status are persisted during import
+		item, err := store.GetByID(ctx, "CAPEC-2")
+		if err != nil || item.Abstraction != "Detailed" || item.Status != "Stable" {
+			t.Fatalf("expected abstraction and status to be persisted, got %+v (err %v)", item, err)
+		}
+
+		ids := func(items []CAPECItemModel) []int {
+			out := make([]int, len(items))
+			for i, it := range items {
+				out[i] = it.CAPECID
+			}
+			return out
+		}
+
+		items, total, err := store.ListCAPECsFiltered(ctx, 0, 10, "standard", SortByID, OrderDesc)
+		if err != nil {
+			t.Fatalf("ListCAPECsFiltered: %v", err)
+		}
+		if got := ids(items); total != 2 || len(got) != 2 || got[0] != 100 || got[1] != 10 {
+			t.Fatalf("expected Standard patterns 100, 10, got total=%d ids=%v", total, got)
+		}
+
+		items, total, err = store.ListCAPECsFiltered(ctx, 1, 2, "", SortByName, OrderAsc)
+		if err != nil {
+			t.Fatalf("ListCAPECsFiltered: %v", err)
+		}
+		if got := ids(items); total != 4 || len(got) != 2 || got[0] != 2 || got[1] != 10 {
+			t.Fatalf("expected second page by name 2, 10, got total=%d ids=%v", total, got)
+		}
+
+		for _, bad := range [][3]string{{"Basic", "", ""}, {"", "severity", ""}, {"", "", "sideways"}} {
+			if _, _, err := store.ListCAPECsFiltered(ctx, 0, 10, bad[0], bad[1], bad[2]); err == nil {
+				t.Errorf("expected an error for %v", bad)
+			}
+		}
+	})
+
+}
+
+func TestCatalogVersion_DeclaredAndMtimeFallback(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCatalogVersion_DeclaredAndMtimeFallback", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+		ctx := context.Background()
+
+		// Nothing imported yet
+		v, err := store.CatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("CatalogVersion: %v", err)
+		}
+		if v.Taxonomy != catalog.TaxonomyCAPEC || v.Version != "" || v.ImportedAt != 0 || v.EntryCount != 0 {
+			t.Fatalf("expected an empty catalog, got %+v", v)
+		}
+
+		versioned := writeTempFile(t, dir, "v.xml", `<Attack_Patterns Version="3.9"><Attack_Pattern ID="1" Name="A"><Description>Desc</Description></Attack_Pattern></Attack_Patterns>`)
+		if err := store.ImportFromXML(versioned, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+		v, err = store.CatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("CatalogVersion: %v", err)
+		}
+		if v.Version != "3.9" || v.VersionSource != catalog.VersionSourceCatalog || v.Source != versioned || v.EntryCount != 1 || v.ImportedAt == 0 {
+			t.Fatalf("unexpected catalog version: %+v", v)
+		}
+
+		// Without a Version attribute the file mtime is recorded
+		unversioned := writeTempFile(t, dir, "u.xml", `<Attack_Patterns><Attack_Pattern ID="3" Name="C"><Description>Desc</Description></Attack_Pattern></Attack_Patterns>`)
+		mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(unversioned, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ImportFromXML(unversioned, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+		v, err = store.CatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("CatalogVersion: %v", err)
+		}
+		if v.Version != "2024-05-01T12:00:00Z" || v.VersionSource != catalog.VersionSourceFileMtime || v.Source != unversioned {
+			t.Fatalf("expected the mtime fallback, got %+v", v)
+		}
+	})
+}
+
+func TestImportFromXMLWithReport_DryRun(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestImportFromXMLWithReport_DryRun", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+
+		existing := writeTempFile(t, dir, "existing.xml", `<?xml version="1.0"?><Attack_Patterns><Attack_Pattern ID="1" Name="Old"><Description>Desc</Description></Attack_Pattern></Attack_Patterns>`)
+		if err := store.ImportFromXML(existing, true); err != nil {
+			t.Fatalf("ImportFromXML: %v", err)
+		}
+
+		drop := writeTempFile(t, dir, "drop.xml", `<?xml version="1.0"?><Attack_Patterns Version="4.0">`+
+			`<Attack_Pattern ID="1" Name="New"><Description>Desc</Description></Attack_Pattern>`+
+			`<Attack_Pattern ID="2" Name=""><Description>Desc</Description><Related_Weaknesses><Related_Weakness CWE_ID="abc" /></Related_Weaknesses>`+
+			`<Taxonomy_Mappings><Taxonomy_Mapping Taxonomy_Name="ATTACK"><Entry_ID>bogus</Entry_ID></Taxonomy_Mapping></Taxonomy_Mappings></Attack_Pattern>`+
+			`</Attack_Patterns>`)
+		report, err := store.ImportFromXMLWithReport(drop, false, true)
+		if err != nil {
+			t.Fatalf("ImportFromXMLWithReport: %v", err)
+		}
+		if !report.DryRun || report.Inserted != 1 || report.Updated != 1 {
+			t.Fatalf("expected 1 inserted and 1 updated, got %+v", report)
+		}
+		if len(report.Warnings) != 3 {
+			t.Fatalf("expected warnings for the name, weakness and mapping of CAPEC-2, got %v", report.Warnings)
+		}
+
+		// Nothing was stored
+		item, err := store.GetByID(context.Background(), "CAPEC-1")
+		if err != nil || item.Name != "Old" {
+			t.Fatalf("expected CAPEC-1 unchanged, got %+v (err=%v)", item, err)
+		}
+		if _, err := store.GetByID(context.Background(), "CAPEC-2"); err == nil {
+			t.Fatalf("expected CAPEC-2 not to be stored")
+		}
+		if meta, err := store.GetCatalogMeta(context.Background()); err == nil && meta.Version == "4.0" {
+			t.Fatalf("expected the catalog version not to be recorded")
+		}
+	})
+}
@@ -39,10 +39,20 @@ type CAPECReferenceModel struct {
 	URL               string
 }
 
+// CAPECAttackMappingModel links a CAPEC to an ATT&CK technique from its
+// taxonomy mappings
+type CAPECAttackMappingModel struct {
+	ID            uint   `gorm:"primaryKey"`
+	CAPECID       int    `gorm:"index;uniqueIndex:ux_capec_attack,priority:1"`
+	TechniqueID   string `gorm:"index;uniqueIndex:ux_capec_attack,priority:2"` // e.g. T1574.010
+	TechniqueName string
+}
+
 // CAPECCatalogMeta stores metadata about the imported CAPEC catalog
 type CAPECCatalogMeta struct {
 	ID            uint   `gorm:"primaryKey"`
 	Version       string `gorm:"index"`
+	VersionSource string // catalog or file_mtime, see catalog.ResolveVersion
 	Source        string
 	ImportedAtUTC int64
 }
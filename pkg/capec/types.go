@@ -16,6 +16,7 @@ type CAPECAttackPattern struct {
 	Examples          []InnerXML        `xml:"Example_Instances>Example" json:"examples"`
 	Mitigations       []InnerXML        `xml:"Mitigations>Mitigation" json:"mitigations"`
 	References        []RelatedRef      `xml:"References>Reference" json:"references"`
+	TaxonomyMappings  []TaxonomyMapping `xml:"Taxonomy_Mappings>Taxonomy_Mapping" json:"taxonomy_mappings"`
 }
 
 type RelatedWeakness struct {
@@ -29,3 +30,11 @@ type InnerXML struct {
 type RelatedRef struct {
 	ExternalRef string `xml:"External_Reference_ID,attr" json:"external_reference_id"`
 }
+
+// TaxonomyMapping maps an attack pattern to an entry of another taxonomy,
+// e.g. an ATT&CK technique
+type TaxonomyMapping struct {
+	TaxonomyName string `xml:"Taxonomy_Name,attr" json:"taxonomy_name"`
+	EntryID      string `xml:"Entry_ID" json:"entry_id"`
+	EntryName    string `xml:"Entry_Name" json:"entry_name"`
+}
@@ -57,3 +57,20 @@ func TestCAPECAttackPattern_Unmarshal(t *testing.T) {
 	})
 
 }
+
+func TestAttackTechniqueID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestAttackTechniqueID", nil, func(t *testing.T, tx *gorm.DB) {
+		for entry, want := range map[string]string{
+			"1574.010": "T1574.010",
+			"1110":     "T1110",
+			" t1059 ":  "T1059",
+			"T1059.1":  "",
+			"11":       "",
+			"":         "",
+		} {
+			if got := AttackTechniqueID(entry); got != want {
+				t.Errorf("AttackTechniqueID(%q) = %q, want %q", entry, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package capec
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MaxSchemaErrors is the number of errors a SchemaError lists; the rest are
+// only counted
+const MaxSchemaErrors = 20
+
+// XMLValidationError is one place where a CAPEC XML file is malformed or does
+// not conform to the schema
+type XMLValidationError struct {
+	Line    int    `json:"line"`   // 0 if unknown
+	Column  int    `json:"column"` // 0 if unknown
+	Message string `json:"message"`
+}
+
+func (e XMLValidationError) String() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// SchemaError is returned by ImportFromXMLWithSchema when the XML file is not
+// well-formed, e.g. a truncated download, or does not conform to the XSD.
+// Nothing has been stored when it is returned.
+type SchemaError struct {
+	Path    string
+	Errors  []XMLValidationError // At most MaxSchemaErrors
+	Omitted int                  // Errors beyond MaxSchemaErrors
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, ve := range e.Errors {
+		msgs[i] = ve.String()
+	}
+	msg := fmt.Sprintf("%s is not a valid CAPEC catalog: %s", e.Path, strings.Join(msgs, "; "))
+	if e.Omitted > 0 {
+		msg += fmt.Sprintf(" (and %d more errors)", e.Omitted)
+	}
+	return msg
+}
+
+// checkWellFormed reads the whole XML file and fails with the line and column
+// of the first syntax error, so that a truncated or corrupted file is
+// rejected before anything is written
+func checkWellFormed(xmlPath string) error {
+	f, err := os.Open(xmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to open xml: %w", err)
+	}
+	defer f.Close()
+
+	dec := xml.NewDecoder(f)
+	invalid := func(msg string) error {
+		line, column := dec.InputPos()
+		return &SchemaError{Path: xmlPath, Errors: []XMLValidationError{{Line: line, Column: column, Message: msg}}}
+	}
+	sawRoot := false
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return invalid(syntaxErr.Msg)
+			}
+			return invalid(err.Error())
+		}
+		if _, ok := t.(xml.StartElement); ok {
+			sawRoot = true
+		}
+	}
+	if !sawRoot {
+		return invalid("no root element")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+//go:build CONFIG_USE_LIBXML2
+
+package capec
+
+/*
+#cgo pkg-config: libxml-2.0
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <libxml/parser.h>
+#include <libxml/xmlschemas.h>
+
+#define CAPEC_MAX_ERRORS 20
+
+typedef struct {
+	int count;
+	int lines[CAPEC_MAX_ERRORS];
+	int columns[CAPEC_MAX_ERRORS];
+	char *messages[CAPEC_MAX_ERRORS];
+} capec_errors;
+
+// The catalog schema imports the XHTML schema from the network, which is not
+// loaded offline; the strict wildcards that demand it are not errors of the
+// catalog itself.
+static int capec_is_xhtml_wildcard(const char *msg) {
+	return strstr(msg, "{http://www.w3.org/1999/xhtml}") != NULL &&
+		strstr(msg, "strict wildcard") != NULL;
+}
+
+// The error argument is xmlErrorPtr before libxml2 2.12 and const xmlError *
+// since, so it is taken as void * and cast.
+static void capec_collect_error(void *ctx, void *e) {
+	capec_errors *errs = (capec_errors *)ctx;
+	const xmlError *err = (const xmlError *)e;
+	const char *msg = err->message ? err->message : "unknown error";
+	if (capec_is_xhtml_wildcard(msg)) {
+		return;
+	}
+	if (errs->count < CAPEC_MAX_ERRORS) {
+		errs->lines[errs->count] = err->line;
+		errs->columns[errs->count] = err->int2;
+		errs->messages[errs->count] = strdup(msg);
+	}
+	errs->count++;
+}
+
+// The schema and document come from the libxml2 Go objects as addresses.
+static int capec_validate(uintptr_t schema, uintptr_t doc, capec_errors *errs) {
+	xmlSchemaValidCtxtPtr ctxt = xmlSchemaNewValidCtxt((xmlSchemaPtr)schema);
+	if (ctxt == NULL) {
+		return -1;
+	}
+	xmlSchemaSetValidStructuredErrors(ctxt, (xmlStructuredErrorFunc)capec_collect_error, errs);
+	int rc = xmlSchemaValidateDoc(ctxt, (xmlDocPtr)doc);
+	xmlSchemaFreeValidCtxt(ctxt);
+	return rc;
+}
+
+static void capec_free_errors(capec_errors *errs) {
+	int n = errs->count < CAPEC_MAX_ERRORS ? errs->count : CAPEC_MAX_ERRORS;
+	for (int i = 0; i < n; i++) {
+		free(errs->messages[i]);
+	}
+	free(errs);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/lestrrat-go/libxml2/parser"
+	"github.com/lestrrat-go/libxml2/xsd"
+)
+
+// validateImport rejects an XML file that is not well-formed and, unless
+// force is set, one that does not conform to the schema at xsdPath (if
+// given), before anything is written
+func validateImport(xmlPath, xsdPath string, force bool, report *catalog.ImportReport) error {
+	if err := checkWellFormed(xmlPath); err != nil {
+		return err
+	}
+	if xsdPath == "" {
+		return nil
+	}
+	if force {
+		report.Warnf("forced import: the catalog was not validated against %s", xsdPath)
+		return nil
+	}
+	return validateSchema(xmlPath, xsdPath)
+}
+
+// validateSchema validates the XML file against the XSD with libxml2,
+// collecting the line and column of each error
+func validateSchema(xmlPath, xsdPath string) error {
+	common.Info("Validating %s against schema %s", xmlPath, xsdPath)
+	schema, err := xsd.ParseFromFile(xsdPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse xsd %s: %w", xsdPath, err)
+	}
+	defer schema.Free()
+
+	xf, err := os.Open(xmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to open xml: %w", err)
+	}
+	defer xf.Close()
+	// Line numbers beyond 65535 are only kept with XMLParseBigLines
+	doc, err := parser.New(parser.XMLParseBigLines).ParseReader(xf)
+	if err != nil {
+		return fmt.Errorf("failed to parse xml: %w", err)
+	}
+	defer doc.Free()
+
+	errs := (*C.capec_errors)(C.calloc(1, C.sizeof_capec_errors))
+	if errs == nil {
+		return fmt.Errorf("failed to allocate validation errors")
+	}
+	defer C.capec_free_errors(errs)
+	rc := C.capec_validate(C.uintptr_t(schema.Pointer()), C.uintptr_t(doc.Pointer()), errs)
+	if rc < 0 {
+		return fmt.Errorf("failed to validate %s against %s", xmlPath, xsdPath)
+	}
+
+	count := int(errs.count)
+	if count == 0 {
+		return nil
+	}
+	schemaErr := &SchemaError{Path: xmlPath}
+	for i := 0; i < count && i < MaxSchemaErrors; i++ {
+		schemaErr.Errors = append(schemaErr.Errors, XMLValidationError{
+			Line:    int(errs.lines[i]),
+			Column:  int(errs.columns[i]),
+			Message: strings.TrimSpace(C.GoString(errs.messages[i])),
+		})
+	}
+	schemaErr.Omitted = count - len(schemaErr.Errors)
+	return schemaErr
+}
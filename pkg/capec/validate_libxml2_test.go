@@ -0,0 +1,103 @@
+//go:build CONFIG_USE_LIBXML2
+
+package capec
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+const validateSampleXSD = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="Attack_Pattern_Catalog">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="Attack_Patterns">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="Attack_Pattern" maxOccurs="unbounded">
+                <xs:complexType>
+                  <xs:sequence>
+                    <xs:element name="Description" type="xs:string"/>
+                  </xs:sequence>
+                  <xs:attribute name="ID" type="xs:integer" use="required"/>
+                  <xs:attribute name="Name" type="xs:string"/>
+                  <xs:attribute name="Abstraction" type="xs:string"/>
+                  <xs:attribute name="Status" type="xs:string"/>
+                </xs:complexType>
+              </xs:element>
+            </xs:sequence>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+      <xs:attribute name="Name" type="xs:string"/>
+      <xs:attribute name="Version" type="xs:string"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>
+`
+
+func TestImportFromXMLWithSchema_ValidatesAgainstXSD(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestImportFromXMLWithSchema_ValidatesAgainstXSD", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+		xsdPath := writeTempFile(t, dir, "capec.xsd", validateSampleXSD)
+
+		// Line 5 has a misspelt element, line 7 a non-numeric ID
+		invalid := strings.Replace(validateSampleXML, "<Description>First pattern</Description>", "<Summary>First pattern</Summary>", 1)
+		invalid = strings.Replace(invalid, `ID="2"`, `ID="two"`, 1)
+		xmlPath := writeTempFile(t, dir, "invalid.xml", invalid)
+
+		_, err = store.ImportFromXMLWithSchema(xmlPath, xsdPath, false, false)
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("Expected a SchemaError, got %v", err)
+		}
+		if len(schemaErr.Errors) != 2 || schemaErr.Errors[0].Line != 5 || schemaErr.Errors[1].Line != 7 {
+			t.Fatalf("Expected errors on lines 5 and 7, got %+v", schemaErr.Errors)
+		}
+		if !strings.Contains(schemaErr.Errors[0].Message, "Summary") {
+			t.Errorf("Expected the unexpected element named, got %q", schemaErr.Errors[0].Message)
+		}
+		var count int64
+		store.db.Model(&CAPECItemModel{}).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected nothing stored, got %d patterns", count)
+		}
+
+		// force imports what the importer can read anyway
+		misspelt := strings.Replace(validateSampleXML, "<Description>First pattern</Description>", "<Summary>First pattern</Summary>", 1)
+		report, err := store.ImportFromXMLWithSchema(writeTempFile(t, dir, "misspelt.xml", misspelt), xsdPath, true, false)
+		if err != nil {
+			t.Fatalf("Forced import failed: %v", err)
+		}
+		if len(report.Warnings) == 0 {
+			t.Error("Expected a warning that the schema was not applied")
+		}
+
+		if _, err := store.ImportFromXMLWithSchema(writeTempFile(t, dir, "valid.xml", validateSampleXML), xsdPath, false, false); err != nil {
+			t.Errorf("Expected a conforming file to import, got %v", err)
+		}
+		if _, err := store.ImportFromXMLWithSchema(xmlPath, filepath.Join(dir, "missing.xsd"), false, false); err == nil {
+			t.Error("Expected an error for a missing schema")
+		}
+	})
+}
+
+func TestValidateSchema_Catalog(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestValidateSchema_Catalog", nil, func(t *testing.T, tx *gorm.DB) {
+		// The shipped catalog conforms although the XHTML schema it refers
+		// to cannot be fetched offline
+		if err := validateSchema("../../assets/capec_contents_latest.xml", "../../assets/capec_schema_latest.xsd"); err != nil {
+			t.Errorf("Expected the shipped catalog to conform, got %v", err)
+		}
+	})
+}
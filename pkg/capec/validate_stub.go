@@ -0,0 +1,18 @@
+//go:build !CONFIG_USE_LIBXML2
+
+package capec
+
+import "github.com/cyw0ng95/v2e/pkg/common/catalog"
+
+// validateImport rejects an XML file that is not well-formed before anything
+// is written. Without libxml2 the schema cannot be applied, which the report
+// warns about.
+func validateImport(xmlPath, xsdPath string, force bool, report *catalog.ImportReport) error {
+	if err := checkWellFormed(xmlPath); err != nil {
+		return err
+	}
+	if xsdPath != "" && !force {
+		report.Warnf("the catalog was not validated against %s: XSD validation needs the CONFIG_USE_LIBXML2 build", xsdPath)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package capec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+const validateSampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Attack_Pattern_Catalog Name="CAPEC" Version="3.9">
+  <Attack_Patterns>
+    <Attack_Pattern ID="1" Name="First" Abstraction="Standard" Status="Stable">
+      <Description>First pattern</Description>
+    </Attack_Pattern>
+    <Attack_Pattern ID="2" Name="Second" Abstraction="Standard" Status="Stable">
+      <Description>Second pattern</Description>
+    </Attack_Pattern>
+  </Attack_Patterns>
+</Attack_Pattern_Catalog>
+`
+
+func TestImportFromXMLWithSchema_RejectsTruncatedFile(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestImportFromXMLWithSchema_RejectsTruncatedFile", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+		store, err := NewLocalCAPECStore(filepath.Join(dir, "capec.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCAPECStore: %v", err)
+		}
+
+		// Cut off within the second pattern, after the first is complete
+		cut := strings.Index(validateSampleXML, "<Description>Second")
+		xmlPath := writeTempFile(t, dir, "truncated.xml", validateSampleXML[:cut])
+
+		// force skips the schema, not the well-formedness check
+		for _, force := range []bool{false, true} {
+			_, err := store.ImportFromXMLWithSchema(xmlPath, "", force, false)
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Expected a SchemaError (force=%v), got %v", force, err)
+			}
+			if len(schemaErr.Errors) != 1 || schemaErr.Errors[0].Line != 8 || schemaErr.Errors[0].Column == 0 {
+				t.Errorf("Expected the position of the end of the file, got %+v", schemaErr.Errors)
+			}
+			if !strings.Contains(err.Error(), "line 8, column") {
+				t.Errorf("Expected the position in the message, got %q", err.Error())
+			}
+		}
+
+		if _, err := store.GetByID(context.Background(), "1"); err == nil {
+			t.Error("Expected nothing stored from a truncated file")
+		}
+		if _, err := store.ImportFromXMLWithReport(writeTempFile(t, dir, "empty.xml", ""), false, false); err == nil {
+			t.Error("Expected an empty file to be rejected")
+		}
+
+		report, err := store.ImportFromXMLWithSchema(writeTempFile(t, dir, "full.xml", validateSampleXML), "", false, false)
+		if err != nil {
+			t.Fatalf("ImportFromXMLWithSchema: %v", err)
+		}
+		if report.Inserted != 2 {
+			t.Errorf("Expected 2 patterns inserted, got %+v", report)
+		}
+	})
+}
+
+func TestSchemaErrorMessage(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSchemaErrorMessage", nil, func(t *testing.T, tx *gorm.DB) {
+		err := &SchemaError{
+			Path: "capec.xml",
+			Errors: []XMLValidationError{
+				{Line: 3, Column: 7, Message: "bad element"},
+				{Line: 9, Message: "missing attribute"},
+				{Message: "no position"},
+			},
+			Omitted: 4,
+		}
+		want := "capec.xml is not a valid CAPEC catalog: line 3, column 7: bad element; line 9: missing attribute; no position (and 4 more errors)"
+		if err.Error() != want {
+			t.Errorf("Expected %q, got %q", want, err.Error())
+		}
+	})
+}
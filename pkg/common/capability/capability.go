@@ -0,0 +1,98 @@
+// Package capability reports which features of a data type are usable, so
+// clients can hide filters and views whose derived data (indexes, imports,
+// mappings) is not populated yet. Capabilities are probed from the schema and
+// row counts rather than configuration, so they reflect what a query would
+// actually find.
+package capability
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Capabilities reports the features usable for one data type. Features that
+// do not apply to a data type are false.
+type Capabilities struct {
+	DataType string `json:"data_type"`
+	// HasData is true when at least one item is stored
+	HasData bool `json:"has_data"`
+	// FTSAvailable is true when a full-text index exists
+	FTSAvailable bool `json:"fts_available"`
+	// CVSSIndexed is true when CVSS scores are stored in an indexed column
+	CVSSIndexed bool `json:"cvss_indexed"`
+	// EPSSLoaded is true when EPSS scores are stored for at least one item
+	EPSSLoaded bool `json:"epss_loaded"`
+	// KEVLoaded is true when the CISA KEV catalog is imported
+	KEVLoaded bool `json:"kev_loaded"`
+	// CPEParsed is true when CPE criteria are parsed into a lookup table
+	CPEParsed bool `json:"cpe_parsed"`
+	// MappingsBuilt is true when links to other data types are stored
+	MappingsBuilt bool `json:"mappings_built"`
+}
+
+// quote quotes an SQLite identifier
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// tableName returns the name of a table given by name or by model
+func tableName(db *gorm.DB, table interface{}) (string, error) {
+	if name, ok := table.(string); ok {
+		return name, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(table); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// HasRows reports whether a table, given by name or by model, exists and has
+// at least one row. Soft-deleted rows count.
+func HasRows(ctx context.Context, db *gorm.DB, table interface{}) (bool, error) {
+	db = db.WithContext(ctx)
+	name, err := tableName(db, table)
+	if err != nil {
+		return false, err
+	}
+	if !db.Migrator().HasTable(name) {
+		return false, nil
+	}
+	var exists bool
+	err = db.Raw("SELECT EXISTS(SELECT 1 FROM " + quote(name) + ")").Scan(&exists).Error
+	return exists, err
+}
+
+// HasValues reports whether column exists in table and is set in at least
+// one row
+func HasValues(ctx context.Context, db *gorm.DB, table, column string) (bool, error) {
+	db = db.WithContext(ctx)
+	if !db.Migrator().HasTable(table) || !db.Migrator().HasColumn(table, column) {
+		return false, nil
+	}
+	var exists bool
+	err := db.Raw("SELECT EXISTS(SELECT 1 FROM " + quote(table) + " WHERE " + quote(column) + " IS NOT NULL)").Scan(&exists).Error
+	return exists, err
+}
+
+// HasIndexOn reports whether an index of table covers column
+func HasIndexOn(ctx context.Context, db *gorm.DB, table, column string) (bool, error) {
+	var exists bool
+	err := db.WithContext(ctx).Raw(
+		"SELECT EXISTS(SELECT 1 FROM pragma_index_list(?) AS il JOIN pragma_index_info(il.name) AS ii WHERE ii.name = ?)",
+		table, column,
+	).Scan(&exists).Error
+	return exists, err
+}
+
+// HasFTSTable reports whether table is a full-text search virtual table
+func HasFTSTable(ctx context.Context, db *gorm.DB, table string) (bool, error) {
+	var exists bool
+	err := db.WithContext(ctx).Raw(
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ? AND lower(sql) LIKE 'create virtual table%using fts%')",
+		table,
+	).Scan(&exists).Error
+	return exists, err
+}
@@ -0,0 +1,90 @@
+// Package catalog describes which version of a taxonomy catalog (CWE, CAPEC,
+// ATT&CK) is loaded, where it came from and when it was imported, so results
+// can be reproduced and stale catalogs spotted.
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Taxonomies with an imported catalog
+const (
+	TaxonomyCWE    = "cwe"
+	TaxonomyCAPEC  = "capec"
+	TaxonomyATTACK = "attack"
+)
+
+// Where a catalog's version was taken from
+const (
+	// VersionSourceCatalog means the catalog declares its own version
+	VersionSourceCatalog = "catalog"
+	// VersionSourceFileMtime means the catalog has no version, so the
+	// modification time of its source file stands in for it
+	VersionSourceFileMtime = "file_mtime"
+)
+
+// Version describes the catalog loaded for one taxonomy. A taxonomy that was
+// never imported has an empty Version and ImportedAt 0.
+type Version struct {
+	Taxonomy      string `json:"taxonomy"`
+	Version       string `json:"version"`
+	VersionSource string `json:"version_source,omitempty"`
+	Source        string `json:"source"`
+	ImportedAt    int64  `json:"imported_at"` // Unix seconds
+	EntryCount    int64  `json:"entry_count"`
+}
+
+// ResolveVersion returns the version to record for a catalog imported from
+// path, and where it came from. A version declared by the catalog wins;
+// otherwise the file's modification time is used, in RFC 3339 UTC. Both are
+// empty when neither is available.
+func ResolveVersion(declared, path string) (version, source string) {
+	if declared != "" {
+		return declared, VersionSourceCatalog
+	}
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime().UTC().Format(time.RFC3339), VersionSourceFileMtime
+	}
+	return "", ""
+}
+
+// MaxImportWarnings is the number of warnings an ImportReport keeps; further
+// ones are only counted
+const MaxImportWarnings = 100
+
+// ImportReport summarizes what importing a catalog file did or, in a dry run,
+// would do
+type ImportReport struct {
+	DryRun   bool     `json:"dry_run"`
+	Inserted int      `json:"inserted"`
+	Updated  int      `json:"updated"`
+	Warnings []string `json:"warnings"`
+	// OmittedWarnings counts the warnings beyond MaxImportWarnings
+	OmittedWarnings int `json:"omitted_warnings,omitempty"`
+}
+
+// NewImportReport returns an empty report
+func NewImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{DryRun: dryRun, Warnings: []string{}}
+}
+
+// Record counts an imported entry as updated if it already existed, else as
+// inserted
+func (r *ImportReport) Record(existed bool) {
+	if existed {
+		r.Updated++
+	} else {
+		r.Inserted++
+	}
+}
+
+// Warnf adds a validation warning
+func (r *ImportReport) Warnf(format string, args ...interface{}) {
+	if len(r.Warnings) >= MaxImportWarnings {
+		r.OmittedWarnings++
+		return
+	}
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,34 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestResolveVersion(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestResolveVersion", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "catalog.xml")
+		if err := os.WriteFile(path, []byte("<catalog/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, src := ResolveVersion("3.9", path); v != "3.9" || src != VersionSourceCatalog {
+			t.Errorf("Expected the declared version, got %q from %q", v, src)
+		}
+		if v, src := ResolveVersion("", path); v != "2024-05-01T12:00:00Z" || src != VersionSourceFileMtime {
+			t.Errorf("Expected the file mtime, got %q from %q", v, src)
+		}
+		if v, src := ResolveVersion("", filepath.Join(t.TempDir(), "missing.xml")); v != "" || src != "" {
+			t.Errorf("Expected no version for a missing file, got %q from %q", v, src)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+// Package dbretry absorbs transient SQLite lock contention in the store layer.
+// Stores open their databases with a busy timeout so SQLite itself waits for
+// a competing writer, and wrap write operations in Do so a lock that outlasts
+// the timeout is retried with backoff instead of surfacing as a raw
+// "database is locked" error from an RPC handler.
+package dbretry
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RetriesEnvVar overrides the number of attempts made by Do
+	RetriesEnvVar = "V2E_DB_BUSY_RETRIES"
+	// BusyTimeoutEnvVar overrides the busy timeout set at connection open,
+	// as a Go duration (e.g. "10s") or a number of milliseconds
+	BusyTimeoutEnvVar = "V2E_DB_BUSY_TIMEOUT"
+
+	// DefaultMaxAttempts is the number of attempts made when unconfigured
+	DefaultMaxAttempts = 5
+	// DefaultBusyTimeout is how long SQLite waits on a lock when
+	// unconfigured, the 30s the CVE store has always used
+	DefaultBusyTimeout = 30 * time.Second
+)
+
+// busyMessages are the driver messages of SQLITE_BUSY and SQLITE_LOCKED
+var busyMessages = []string{
+	"database is locked",
+	"database table is locked",
+	"sqlite_busy",
+	"sqlite_locked",
+}
+
+// IsBusy reports whether err is a transient lock conflict that is worth
+// retrying, as opposed to a genuine error such as a constraint violation
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range busyMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy controls how Do retries
+type Policy struct {
+	MaxAttempts int           // Total attempts, including the first
+	BaseDelay   time.Duration // Delay before the first retry, doubled each time
+	MaxDelay    time.Duration // Upper bound on a single delay
+}
+
+// DefaultPolicy returns the policy used by Do, honouring RetriesEnvVar
+func DefaultPolicy() Policy {
+	p := Policy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+	if n, err := strconv.Atoi(os.Getenv(RetriesEnvVar)); err == nil && n > 0 {
+		p.MaxAttempts = n
+	}
+	return p
+}
+
+// Do runs fn with the default policy
+func Do(fn func() error) error {
+	return DefaultPolicy().Do(fn)
+}
+
+// Do runs fn until it succeeds, fails with an error that is not a lock
+// conflict, or runs out of attempts. Delays grow exponentially with jitter so
+// writers that collided do not retry in lockstep. fn must be safe to repeat,
+// which holds for a single statement or a whole transaction.
+func (p Policy) Do(fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsBusy(err) || attempt >= attempts {
+			return err
+		}
+
+		if delay > 0 {
+			time.Sleep(delay + time.Duration(rand.Int63n(int64(delay)/2+1)))
+		}
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// BusyTimeout returns the busy timeout to set at connection open, honouring
+// BusyTimeoutEnvVar
+func BusyTimeout() time.Duration {
+	return parseBusyTimeout(os.Getenv(BusyTimeoutEnvVar))
+}
+
+// parseBusyTimeout parses a duration or a number of milliseconds, falling
+// back to DefaultBusyTimeout when v is empty or invalid
+func parseBusyTimeout(v string) time.Duration {
+	if v == "" {
+		return DefaultBusyTimeout
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return DefaultBusyTimeout
+}
+
+// DSN appends the busy timeout to a SQLite path or DSN. It is set through the
+// DSN rather than a PRAGMA statement because the driver applies DSN options to
+// every connection in the pool, while a PRAGMA only reaches one of them.
+func DSN(dbPath string) string {
+	return WithBusyTimeout(dbPath, BusyTimeout())
+}
+
+// WithBusyTimeout appends the given busy timeout to a SQLite path or DSN
+func WithBusyTimeout(dbPath string, timeout time.Duration) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + "_busy_timeout=" + strconv.FormatInt(timeout.Milliseconds(), 10)
+}
@@ -0,0 +1,169 @@
+package dbretry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestIsBusy(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestIsBusy", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, c := range []struct {
+			err  error
+			want bool
+		}{
+			{nil, false},
+			{errors.New("database is locked"), true},
+			{fmt.Errorf("failed to commit: %w", errors.New("database table is locked: cve_records")), true},
+			{errors.New("SQLITE_BUSY"), true},
+			{errors.New("UNIQUE constraint failed: cve_records.cve_id"), false},
+			{gorm.ErrRecordNotFound, false},
+		} {
+			if got := IsBusy(c.err); got != c.want {
+				t.Errorf("IsBusy(%v) = %v, want %v", c.err, got, c.want)
+			}
+		}
+	})
+}
+
+func TestPolicy_RetriesOnlyBusy(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestPolicy_RetriesOnlyBusy", nil, func(t *testing.T, tx *gorm.DB) {
+		p := Policy{MaxAttempts: 3}
+
+		calls := 0
+		err := p.Do(func() error {
+			calls++
+			return errors.New("database is locked")
+		})
+		if !IsBusy(err) || calls != 3 {
+			t.Errorf("Busy: err = %v after %d calls, want busy after 3", err, calls)
+		}
+
+		calls = 0
+		genuine := errors.New("no such table: cve_records")
+		if err := p.Do(func() error { calls++; return genuine }); err != genuine || calls != 1 {
+			t.Errorf("Genuine: err = %v after %d calls, want it returned after 1", err, calls)
+		}
+
+		calls = 0
+		err = p.Do(func() error {
+			calls++
+			if calls < 2 {
+				return errors.New("database is locked")
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("Transient: err = %v after %d calls, want success after 2", err, calls)
+		}
+	})
+}
+
+func TestDSN(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDSN", nil, func(t *testing.T, tx *gorm.DB) {
+		if got := WithBusyTimeout("cve.db", 2*time.Second); got != "cve.db?_busy_timeout=2000" {
+			t.Errorf("WithBusyTimeout = %q", got)
+		}
+		if got := WithBusyTimeout("ssg.db?_pragma=foreign_keys=on", 0); got != "ssg.db?_pragma=foreign_keys=on&_busy_timeout=0" {
+			t.Errorf("WithBusyTimeout = %q", got)
+		}
+
+		for v, want := range map[string]time.Duration{
+			"":      DefaultBusyTimeout,
+			"250":   250 * time.Millisecond,
+			"3s":    3 * time.Second,
+			"0":     0,
+			"bogus": DefaultBusyTimeout,
+		} {
+			if got := parseBusyTimeout(v); got != want {
+				t.Errorf("parseBusyTimeout(%q) = %v, want %v", v, got, want)
+			}
+		}
+	})
+}
+
+type retryRow struct {
+	ID     uint `gorm:"primarykey"`
+	Writer int
+}
+
+func TestPolicy_ConcurrentWritersSucceed(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestPolicy_ConcurrentWritersSucceed", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_dbretry_concurrent.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+		defer os.Remove(dbPath + "-wal")
+		defer os.Remove(dbPath + "-shm")
+
+		// No busy timeout, so every overlapping write fails straight away and
+		// only the retries let the writers through
+		open := func() *gorm.DB {
+			db, err := gorm.Open(sqlite.Open(WithBusyTimeout(dbPath, 0)), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Silent),
+			})
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			return db
+		}
+		setup := open()
+		if err := setup.Exec("PRAGMA journal_mode=WAL").Error; err != nil {
+			t.Fatalf("Failed to enable WAL: %v", err)
+		}
+		if err := setup.AutoMigrate(&retryRow{}); err != nil {
+			t.Fatalf("AutoMigrate failed: %v", err)
+		}
+
+		const writers, rows, statements = 8, 20, 10
+		policy := Policy{MaxAttempts: 200, BaseDelay: time.Millisecond, MaxDelay: 20 * time.Millisecond}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				db := open()
+				for i := 0; i < rows; i++ {
+					err := policy.Do(func() error {
+						return db.Transaction(func(tx *gorm.DB) error {
+							// Several statements per transaction keep the write
+							// lock held long enough for writers to collide
+							for j := 0; j < statements; j++ {
+								if err := tx.Create(&retryRow{Writer: w}).Error; err != nil {
+									return err
+								}
+							}
+							return nil
+						})
+					})
+					if err != nil {
+						errs <- fmt.Errorf("writer %d row %d: %w", w, i, err)
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		var count int64
+		if err := setup.Model(&retryRow{}).Count(&count).Error; err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		if count != writers*rows*statements {
+			t.Errorf("Stored %d rows, want %d", count, writers*rows*statements)
+		}
+	})
+}
@@ -8,6 +8,10 @@ const (
 	// Used by: sysmon, access, meta (should use this instead of 60s)
 	DefaultRPCTimeout = 30 * time.Second
 
+	// MaxRPCTimeout bounds the per-call timeout of an RPC request, long enough
+	// for a full NVD fetch but not unbounded
+	MaxRPCTimeout = 10 * time.Minute
+
 	// DefaultLongOperationTimeout is for operations that take longer (imports, bulk operations)
 	// Used by: meta for CWE/CAPEC/ATT&CK imports
 	DefaultLongOperationTimeout = 120 * time.Second
@@ -0,0 +1,213 @@
+// Package intentlog is a small write-ahead log of intents for writes that
+// span several databases. A single database wraps related writes in one
+// transaction; across databases there is no shared transaction, so a crash
+// between the writes would leave them inconsistent. Instead the intent is
+// made durable before the first write and removed after the last one. An
+// intent still present at startup belongs to a write that was interrupted,
+// and Recover hands it to a resolver that completes or rolls it back.
+//
+// Resolvers, like the writes themselves, must be idempotent: an intent may be
+// resolved after none, some or all of its writes were applied.
+package intentlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvVar overrides the directory intents are kept in
+	EnvVar = "V2E_INTENT_LOG_DIR"
+	// DefaultDir is the intent directory when unconfigured
+	DefaultDir = "intents"
+
+	fileSuffix = ".intent"
+)
+
+// ErrNoResolver is reported by Recover for an intent whose operation has no
+// resolver; the intent is kept for a later run
+var ErrNoResolver = errors.New("no resolver for intent operation")
+
+// Intent is one multi-store write in flight
+type Intent struct {
+	ID        string          `json:"id"`
+	Op        string          `json:"op"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Resolver completes or rolls back an interrupted intent
+type Resolver func(intent Intent) error
+
+// Log is a directory holding one file per pending intent
+type Log struct {
+	dir string
+	mu  sync.Mutex
+	seq uint64
+}
+
+// DirFromEnv returns the configured intent directory
+func DirFromEnv() string {
+	if dir := os.Getenv(EnvVar); dir != "" {
+		return dir
+	}
+	return DefaultDir
+}
+
+// Open opens the intent log in dir, creating the directory if needed
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create intent log directory: %w", err)
+	}
+	return &Log{dir: dir}, nil
+}
+
+// Dir returns the directory intents are kept in
+func (l *Log) Dir() string {
+	return l.dir
+}
+
+// Begin durably records the intent to perform op with payload. It returns
+// once the intent is on disk, so the writes it covers may start.
+func (l *Log) Begin(op string, payload interface{}) (*Intent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode intent payload: %w", err)
+	}
+
+	l.mu.Lock()
+	l.seq++
+	seq := l.seq
+	l.mu.Unlock()
+
+	now := time.Now().UTC()
+	intent := &Intent{
+		// The timestamp prefix keeps intents in start order when listed
+		ID:        fmt.Sprintf("%020d-%d-%d", now.UnixNano(), os.Getpid(), seq),
+		Op:        op,
+		Payload:   data,
+		CreatedAt: now,
+	}
+	if err := l.write(intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// Complete removes an intent once all of its writes are applied or rolled
+// back. Completing an intent twice is not an error.
+func (l *Log) Complete(id string) error {
+	err := os.Remove(l.path(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to complete intent %s: %w", id, err)
+	}
+	return nil
+}
+
+// Pending returns the intents not yet completed, oldest first
+func (l *Log) Pending() ([]Intent, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list intents: %w", err)
+	}
+	var intents []Intent
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read intent %s: %w", e.Name(), err)
+		}
+		var intent Intent
+		if err := json.Unmarshal(data, &intent); err != nil {
+			return nil, fmt.Errorf("failed to decode intent %s: %w", e.Name(), err)
+		}
+		intents = append(intents, intent)
+	}
+	sort.Slice(intents, func(i, j int) bool { return intents[i].ID < intents[j].ID })
+	return intents, nil
+}
+
+// Recover resolves every pending intent with the resolver of its operation,
+// oldest first, and completes the intents that resolved. It returns the
+// number resolved; intents that failed or have no resolver are kept and
+// their errors joined.
+func (l *Log) Recover(resolvers map[string]Resolver) (int, error) {
+	intents, err := l.Pending()
+	if err != nil {
+		return 0, err
+	}
+	resolved := 0
+	var errs []error
+	for _, intent := range intents {
+		resolve, ok := resolvers[intent.Op]
+		if !ok {
+			errs = append(errs, fmt.Errorf("intent %s: %w %q", intent.ID, ErrNoResolver, intent.Op))
+			continue
+		}
+		if err := resolve(intent); err != nil {
+			errs = append(errs, fmt.Errorf("intent %s (%s): %w", intent.ID, intent.Op, err))
+			continue
+		}
+		if err := l.Complete(intent.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resolved++
+	}
+	return resolved, errors.Join(errs...)
+}
+
+func (l *Log) path(id string) string {
+	return filepath.Join(l.dir, id+fileSuffix)
+}
+
+// write stores an intent atomically and syncs it, so that after a crash an
+// intent is either absent or complete
+func (l *Log) write(intent *Intent) error {
+	data, err := json.Marshal(intent)
+	if err != nil {
+		return fmt.Errorf("failed to encode intent: %w", err)
+	}
+	tmp, err := os.CreateTemp(l.dir, ".intent-*")
+	if err != nil {
+		return fmt.Errorf("failed to record intent: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record intent: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record intent: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record intent: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path(intent.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record intent: %w", err)
+	}
+	// Sync the directory so the rename itself survives a crash
+	if d, err := os.Open(l.dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Decode unmarshals the intent's payload into v
+func (i Intent) Decode(v interface{}) error {
+	return json.Unmarshal(i.Payload, v)
+}
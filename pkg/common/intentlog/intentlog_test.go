@@ -0,0 +1,125 @@
+package intentlog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+type movePayload struct {
+	Key string `json:"key"`
+}
+
+func TestLog_CompletedIntentIsNotPending(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestLog_CompletedIntentIsNotPending", nil, func(t *testing.T, tx *gorm.DB) {
+		l, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		intent, err := l.Begin("move", movePayload{Key: "a"})
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if pending, _ := l.Pending(); len(pending) != 1 || pending[0].ID != intent.ID {
+			t.Fatalf("Expected the intent to be pending, got %v", pending)
+		}
+		if err := l.Complete(intent.ID); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+		if err := l.Complete(intent.ID); err != nil {
+			t.Errorf("Completing twice should not fail: %v", err)
+		}
+		if pending, _ := l.Pending(); len(pending) != 0 {
+			t.Errorf("Expected no pending intents, got %v", pending)
+		}
+	})
+}
+
+func TestLog_RecoverAfterCrash(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestLog_RecoverAfterCrash", nil, func(t *testing.T, tx *gorm.DB) {
+		dir := t.TempDir()
+
+		// Two stores: the write reached the first one before the crash
+		first := map[string]bool{}
+		second := map[string]bool{}
+		l, err := Open(dir)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if _, err := l.Begin("move", movePayload{Key: "a"}); err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if _, err := l.Begin("unknown", movePayload{Key: "b"}); err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		first["a"] = true
+
+		// A temp file left by a crash mid-Begin is not an intent
+		if err := os.WriteFile(filepath.Join(dir, ".intent-123"), []byte("{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Restart: the move is rolled forward, the unknown op is kept
+		l, err = Open(dir)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		n, err := l.Recover(map[string]Resolver{
+			"move": func(intent Intent) error {
+				var p movePayload
+				if err := intent.Decode(&p); err != nil {
+					return err
+				}
+				first[p.Key] = true
+				second[p.Key] = true
+				return nil
+			},
+		})
+		if n != 1 {
+			t.Errorf("Expected 1 resolved intent, got %d", n)
+		}
+		if !errors.Is(err, ErrNoResolver) {
+			t.Errorf("Expected ErrNoResolver, got %v", err)
+		}
+		if !first["a"] || !second["a"] {
+			t.Errorf("Expected the write to be completed in both stores, got %v %v", first, second)
+		}
+		pending, _ := l.Pending()
+		if len(pending) != 1 || pending[0].Op != "unknown" {
+			t.Errorf("Expected only the unresolved intent to remain, got %v", pending)
+		}
+	})
+}
+
+func TestLog_FailedResolverKeepsIntent(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestLog_FailedResolverKeepsIntent", nil, func(t *testing.T, tx *gorm.DB) {
+		l, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if _, err := l.Begin("move", movePayload{Key: "a"}); err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+
+		fail := true
+		resolvers := map[string]Resolver{
+			"move": func(Intent) error {
+				if fail {
+					return errors.New("store unavailable")
+				}
+				return nil
+			},
+		}
+		if n, err := l.Recover(resolvers); n != 0 || err == nil {
+			t.Fatalf("Expected the resolver failure to be reported, got %d, %v", n, err)
+		}
+		fail = false
+		if n, err := l.Recover(resolvers); n != 1 || err != nil {
+			t.Fatalf("Expected the intent to resolve on retry, got %d, %v", n, err)
+		}
+	})
+}
@@ -68,6 +68,21 @@ func (l LogLevel) String() string {
 	}
 }
 
+// ParseLogLevel parses a level name: debug, info, warn or error, in any case
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "WARN":
+		return WarnLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q: must be debug, info, warn or error", s)
+}
+
 // CustomFormatter is a custom writer that formats logs as [Timestamp][Level][Entity] Message
 type CustomFormatter struct {
 	Out    io.Writer
@@ -34,6 +34,21 @@ func TestLogLevel_String(t *testing.T) {
 
 }
 
+func TestParseLogLevel(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseLogLevel", nil, func(t *testing.T, tx *gorm.DB) {
+		for s, want := range map[string]LogLevel{"debug": DebugLevel, "INFO": InfoLevel, " Warn ": WarnLevel, "error": ErrorLevel} {
+			if got, err := ParseLogLevel(s); err != nil || got != want {
+				t.Errorf("ParseLogLevel(%q) = %v, %v; want %v", s, got, err, want)
+			}
+		}
+		for _, s := range []string{"", "trace", "warning"} {
+			if _, err := ParseLogLevel(s); err == nil || !strings.Contains(err.Error(), "unknown log level") {
+				t.Errorf("ParseLogLevel(%q) error = %v, want unknown log level", s, err)
+			}
+		}
+	})
+}
+
 func TestNewLogger(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "TestNewLogger", nil, func(t *testing.T, tx *gorm.DB) {
 		var buf bytes.Buffer
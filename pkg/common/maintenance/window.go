@@ -0,0 +1,272 @@
+// Package maintenance provides the shared maintenance window that gates heavy
+// background work (reference health sweeps, rebuilds, compaction) so it only
+// runs during configured periods and leaves foreground traffic alone otherwise.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvVar is the environment variable every service reads the window spec from
+const EnvVar = "V2E_MAINTENANCE_WINDOW"
+
+// minutesPerDay is the number of minutes in a day
+const minutesPerDay = 24 * 60
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// period is one active period of a window. A period whose end is not after
+// its start crosses midnight and continues into the following day.
+type period struct {
+	days  [7]bool
+	start int // minutes since midnight, inclusive
+	end   int // minutes since midnight, exclusive
+}
+
+// contains reports whether the period covers the given weekday and minute
+func (p period) contains(day time.Weekday, minute int) bool {
+	if p.start < p.end {
+		return p.days[day] && minute >= p.start && minute < p.end
+	}
+	prev := (day + 6) % 7
+	return (p.days[day] && minute >= p.start) || (p.days[prev] && minute < p.end)
+}
+
+// Window is a set of active periods during which heavy background work is
+// allowed. A nil or empty Window allows work at any time.
+type Window struct {
+	spec    string
+	periods []period
+	now     func() time.Time
+}
+
+// State describes a window at a point in time, as reported over RPC
+type State struct {
+	Spec       string     `json:"spec"`
+	Always     bool       `json:"always"`
+	Open       bool       `json:"open"`
+	NextChange *time.Time `json:"next_change,omitempty"`
+}
+
+// Parse parses a window spec: periods separated by ";", each an optional day
+// list followed by an optional HH:MM-HH:MM time range, in local time.
+// Days are "*", names ("Sat"), lists ("Sat,Sun") or ranges ("Mon-Fri").
+//
+//	"Mon-Fri 01:00-05:00; Sat,Sun"   weekday nights and all weekend
+//	"22:00-02:00"                    every night, crossing midnight
+//
+// An empty spec means always allowed.
+func Parse(spec string) (*Window, error) {
+	w := &Window{spec: strings.TrimSpace(spec), now: time.Now}
+	if w.spec == "" {
+		return w, nil
+	}
+
+	for _, part := range strings.Split(w.spec, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid maintenance period %q", strings.TrimSpace(part))
+		}
+
+		p := period{start: 0, end: minutesPerDay}
+		daysSet := false
+		for _, field := range fields {
+			if strings.Contains(field, ":") {
+				start, end, err := parseTimeRange(field)
+				if err != nil {
+					return nil, err
+				}
+				p.start, p.end = start, end
+				continue
+			}
+			if daysSet {
+				return nil, fmt.Errorf("invalid maintenance period %q", strings.TrimSpace(part))
+			}
+			days, err := parseDays(field)
+			if err != nil {
+				return nil, err
+			}
+			p.days = days
+			daysSet = true
+		}
+		if !daysSet {
+			for d := range p.days {
+				p.days[d] = true
+			}
+		}
+		w.periods = append(w.periods, p)
+	}
+	return w, nil
+}
+
+// FromEnv parses the window spec in V2E_MAINTENANCE_WINDOW
+func FromEnv() (*Window, error) {
+	return Parse(os.Getenv(EnvVar))
+}
+
+// parseDays parses "*", a day name, or a comma-separated list of names and ranges
+func parseDays(field string) ([7]bool, error) {
+	var days [7]bool
+	if field == "*" {
+		for d := range days {
+			days[d] = true
+		}
+		return days, nil
+	}
+
+	for _, item := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, ok := dayNames[strings.ToLower(from)]
+		if !ok {
+			return days, fmt.Errorf("invalid day %q in maintenance window", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = dayNames[strings.ToLower(to)]; !ok {
+				return days, fmt.Errorf("invalid day %q in maintenance window", to)
+			}
+		}
+		// Ranges may wrap around the week, e.g. "Fri-Mon"
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeRange parses "HH:MM-HH:MM" into minutes since midnight. The end
+// may be "24:00"; an end equal to the start covers the whole day.
+func parseTimeRange(field string) (int, int, error) {
+	from, to, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time range %q in maintenance window", field)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == minutesPerDay {
+		return 0, 0, fmt.Errorf("invalid start time %q in maintenance window", from)
+	}
+	return start, end, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q in maintenance window", s)
+	}
+	return h*60 + m, nil
+}
+
+// Spec returns the spec the window was parsed from
+func (w *Window) Spec() string {
+	if w == nil {
+		return ""
+	}
+	return w.spec
+}
+
+// Always reports whether the window places no restriction on background work
+func (w *Window) Always() bool {
+	return w == nil || len(w.periods) == 0
+}
+
+// Allowed reports whether background work may run now
+func (w *Window) Allowed() bool {
+	if w.Always() {
+		return true
+	}
+	return w.AllowedAt(w.now())
+}
+
+// AllowedAt reports whether background work may run at t
+func (w *Window) AllowedAt(t time.Time) bool {
+	if w.Always() {
+		return true
+	}
+	t = t.Local()
+	minute := t.Hour()*60 + t.Minute()
+	for _, p := range w.periods {
+		if p.contains(t.Weekday(), minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the next time after t at which the window opens or
+// closes, or the zero time if it never changes
+func (w *Window) NextChange(t time.Time) time.Time {
+	if w.Always() {
+		return time.Time{}
+	}
+	open := w.AllowedAt(t)
+	next := t.Truncate(time.Minute)
+	// Periods have minute resolution, so a week of minutes covers every case
+	for i := 0; i < 7*minutesPerDay; i++ {
+		next = next.Add(time.Minute)
+		if w.AllowedAt(next) != open {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// Wait blocks until the window is open or ctx is done
+func (w *Window) Wait(ctx context.Context) error {
+	for !w.Allowed() {
+		delay := time.Minute
+		if next := w.NextChange(w.now()); !next.IsZero() {
+			delay = time.Until(next)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return ctx.Err()
+}
+
+// State returns the current state of the window
+func (w *Window) State() State {
+	state := State{Spec: w.Spec(), Always: w.Always(), Open: true}
+	if state.Always {
+		return state
+	}
+	now := w.now()
+	state.Open = w.AllowedAt(now)
+	if next := w.NextChange(now); !next.IsZero() {
+		state.NextChange = &next
+	}
+	return state
+}
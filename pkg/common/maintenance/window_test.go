@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// at returns the local time on the first week of 2024 (Mon Jan 1 .. Sun Jan 7)
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.January, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestWindow_EmptySpecAlwaysAllowed(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWindow_EmptySpecAlwaysAllowed", nil, func(t *testing.T, tx *gorm.DB) {
+		w, err := Parse("")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if !w.Always() || !w.Allowed() || !w.AllowedAt(at(3, 12, 0)) {
+			t.Error("Empty window should always allow work")
+		}
+		if !w.NextChange(at(3, 12, 0)).IsZero() {
+			t.Error("Empty window should never change")
+		}
+
+		var nilWindow *Window
+		if !nilWindow.Allowed() || nilWindow.Wait(context.Background()) != nil {
+			t.Error("Nil window should always allow work")
+		}
+	})
+}
+
+func TestWindow_Periods(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWindow_Periods", nil, func(t *testing.T, tx *gorm.DB) {
+		w, err := Parse("Mon-Fri 01:00-05:00; Sat,Sun; Wed 22:00-02:00")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		cases := []struct {
+			name string
+			t    time.Time
+			want bool
+		}{
+			{"weekday inside", at(2, 3, 0), true},
+			{"weekday start inclusive", at(2, 1, 0), true},
+			{"weekday end exclusive", at(2, 5, 0), false},
+			{"weekday daytime", at(2, 12, 0), false},
+			{"saturday all day", at(6, 12, 0), true},
+			{"sunday late", at(7, 23, 59), true},
+			{"wednesday night", at(3, 23, 0), true},
+			{"crosses into thursday", at(4, 1, 30), true},
+			{"thursday after overnight", at(4, 6, 0), false},
+			{"tuesday night not covered", at(2, 23, 0), false},
+		}
+		for _, c := range cases {
+			if got := w.AllowedAt(c.t); got != c.want {
+				t.Errorf("%s: AllowedAt(%v) = %v, want %v", c.name, c.t, got, c.want)
+			}
+		}
+	})
+}
+
+func TestWindow_ParseErrors(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWindow_ParseErrors", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, spec := range []string{
+			"Funday",
+			"Mon 25:00-26:00",
+			"Mon 01:00",
+			"Mon 01:60-02:00",
+			"Mon Tue",
+			"Mon 01:00-02:00 extra",
+			"24:00-01:00",
+		} {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", spec)
+			}
+		}
+	})
+}
+
+func TestWindow_StateAndNextChange(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWindow_StateAndNextChange", nil, func(t *testing.T, tx *gorm.DB) {
+		w, err := Parse("Mon-Fri 01:00-05:00")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		w.now = func() time.Time { return at(2, 12, 30) }
+
+		state := w.State()
+		if state.Always || state.Open {
+			t.Errorf("State = %+v, want closed", state)
+		}
+		if state.NextChange == nil || !state.NextChange.Equal(at(3, 1, 0)) {
+			t.Errorf("NextChange = %v, want %v", state.NextChange, at(3, 1, 0))
+		}
+
+		if next := w.NextChange(at(3, 2, 0)); !next.Equal(at(3, 5, 0)) {
+			t.Errorf("NextChange while open = %v, want %v", next, at(3, 5, 0))
+		}
+	})
+}
+
+func TestWindow_WaitHonoursContext(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWindow_WaitHonoursContext", nil, func(t *testing.T, tx *gorm.DB) {
+		w, err := Parse("Mon 01:00-02:00")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		w.now = func() time.Time { return at(2, 12, 0) }
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := w.Wait(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Wait = %v, want deadline exceeded", err)
+		}
+	})
+}
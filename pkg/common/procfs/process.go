@@ -0,0 +1,49 @@
+package procfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ClockTicksPerSecond is the unit of the CPU times of /proc/<pid>/stat
+// (USER_HZ, 100 on every Linux architecture Go supports)
+const ClockTicksPerSecond = 100
+
+// ReadProcessStat returns the CPU time a process has used so far, in clock
+// ticks (user plus system, see ClockTicksPerSecond), and its resident set
+// size in bytes, from /proc/<pid>/stat
+func ReadProcessStat(pid int) (cpuTicks uint64, rssBytes uint64, err error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The command name may contain spaces and parentheses, so the fields
+	// are counted from its closing parenthesis (field 2)
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return 0, 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	// fields[0] is field 3 (state); utime, stime and rss are fields 14, 15 and 24
+	if len(fields) < 22 {
+		return 0, 0, fmt.Errorf("malformed /proc/%d/stat: %d fields", pid, len(fields)+2)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	rssPages, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if rssPages < 0 {
+		rssPages = 0
+	}
+	return utime + stime, uint64(rssPages) * uint64(os.Getpagesize()), nil
+}
@@ -3,6 +3,7 @@ package procfs
 import (
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 	"gorm.io/gorm"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -85,3 +86,15 @@ func TestReadNetDevDetailedAndTotals(t *testing.T) {
 	})
 
 }
+
+func TestReadProcessStat(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestReadProcessStat", nil, func(t *testing.T, tx *gorm.DB) {
+		_, rss, err := ReadProcessStat(os.Getpid())
+		require.NoError(t, err)
+		require.Greater(t, rss, uint64(0), "a running process should have resident memory")
+
+		_, _, err = ReadProcessStat(-1)
+		require.Error(t, err, "a missing process should be an error")
+	})
+
+}
@@ -38,6 +38,7 @@ const (
 	LogMsgTFStoredCVEsSuccess = "Stored %d/%d CVEs successfully"
 	LogMsgTFFetchFailed       = "Fetch failed: %v"
 	LogMsgTFJobCompleted      = "Job completed: run_id=%s"
+	LogMsgTFJobCheckpointed   = "Job checkpointed: run_id=%s, start_index=%d, fetched=%d"
 
 	// Session Management Log Messages
 	LogMsgSessionCreated         = "Session created: id=%s"
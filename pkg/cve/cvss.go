@@ -0,0 +1,191 @@
+package cve
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attack vectors of CVSSSummary, in the CVSS v3 vocabulary. The v2 access
+// vector and v4 attack vector are normalized to these.
+const (
+	AttackVectorNetwork  = "NETWORK"
+	AttackVectorAdjacent = "ADJACENT_NETWORK"
+	AttackVectorLocal    = "LOCAL"
+	AttackVectorPhysical = "PHYSICAL"
+)
+
+// CVSSSummary is the parsed form of the preferred CVSS metric of a CVE,
+// derived locally (see DeriveCVSS); it is not part of the NVD payload. A CVE
+// without metrics has the zero summary.
+type CVSSSummary struct {
+	CVSSVersion         string  `json:"cvssVersion,omitempty"`
+	BaseScore           float64 `json:"baseScore,omitempty"`
+	BaseSeverity        string  `json:"baseSeverity,omitempty"`
+	AttackVector        string  `json:"attackVector,omitempty"`
+	ExploitabilityScore float64 `json:"exploitabilityScore,omitempty"`
+}
+
+// attackVectorCodes maps the AV values of a vector string
+var attackVectorCodes = map[string]string{
+	"N": AttackVectorNetwork,
+	"A": AttackVectorAdjacent,
+	"L": AttackVectorLocal,
+	"P": AttackVectorPhysical,
+}
+
+// ParseCVSSVector splits a CVSS vector string into its version and metric
+// values, e.g. "CVSS:3.1/AV:N/AC:L" into "3.1" and {AV: N, AC: L}. v2 vectors
+// carry no version prefix, so their version is "2.0".
+func ParseCVSSVector(vector string) (string, map[string]string, error) {
+	vector = strings.TrimSpace(vector)
+	if vector == "" {
+		return "", nil, fmt.Errorf("empty CVSS vector")
+	}
+	// NVD wraps some v2 vectors in parentheses
+	vector = strings.TrimSuffix(strings.TrimPrefix(vector, "("), ")")
+
+	version := "2.0"
+	parts := strings.Split(vector, "/")
+	if v, ok := strings.CutPrefix(parts[0], "CVSS:"); ok {
+		if v == "" {
+			return "", nil, fmt.Errorf("invalid CVSS vector %q: missing version", vector)
+		}
+		version = v
+		parts = parts[1:]
+	}
+
+	metrics := make(map[string]string, len(parts))
+	for _, part := range parts {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok || key == "" || value == "" {
+			return "", nil, fmt.Errorf("invalid CVSS vector %q: malformed metric %q", vector, part)
+		}
+		metrics[key] = value
+	}
+	return version, metrics, nil
+}
+
+// DeriveCVSS summarizes the preferred CVSS metric of a CVE: v3.1, then v3.0,
+// v4.0 and v2, and within a version the NVD (Primary) metric over the others.
+// Fields missing from the metric are taken from its vector string or, for the
+// severity, from the score.
+func DeriveCVSS(item *CVEItem) CVSSSummary {
+	if item == nil || item.Metrics == nil {
+		return CVSSSummary{}
+	}
+	m := item.Metrics
+	if metric := primaryV3(m.CvssMetricV31); metric != nil {
+		return summarizeV3(metric, "3.1")
+	}
+	if metric := primaryV3(m.CvssMetricV30); metric != nil {
+		return summarizeV3(metric, "3.0")
+	}
+	if len(m.CvssMetricV40) > 0 {
+		metric := &m.CvssMetricV40[0]
+		for i := range m.CvssMetricV40 {
+			if strings.EqualFold(m.CvssMetricV40[i].Type, "Primary") {
+				metric = &m.CvssMetricV40[i]
+				break
+			}
+		}
+		data := metric.CvssData
+		return CVSSSummary{
+			CVSSVersion:  versionOr(data.Version, "4.0"),
+			BaseScore:    data.BaseScore,
+			BaseSeverity: severityOf(data.BaseSeverity, data.BaseScore, false),
+			AttackVector: attackVectorOf(data.AttackVector, data.VectorString),
+		}
+	}
+	if len(m.CvssMetricV2) > 0 {
+		metric := &m.CvssMetricV2[0]
+		for i := range m.CvssMetricV2 {
+			if strings.EqualFold(m.CvssMetricV2[i].Type, "Primary") {
+				metric = &m.CvssMetricV2[i]
+				break
+			}
+		}
+		data := metric.CvssData
+		return CVSSSummary{
+			CVSSVersion:         versionOr(data.Version, "2.0"),
+			BaseScore:           data.BaseScore,
+			BaseSeverity:        severityOf(metric.BaseSeverity, data.BaseScore, true),
+			AttackVector:        attackVectorOf(data.AccessVector, data.VectorString),
+			ExploitabilityScore: metric.ExploitabilityScore,
+		}
+	}
+	return CVSSSummary{}
+}
+
+// primaryV3 returns the Primary metric of a v3.x list, else its first one
+func primaryV3(metrics []CVSSMetricV3) *CVSSMetricV3 {
+	for i := range metrics {
+		if strings.EqualFold(metrics[i].Type, "Primary") {
+			return &metrics[i]
+		}
+	}
+	if len(metrics) > 0 {
+		return &metrics[0]
+	}
+	return nil
+}
+
+// summarizeV3 summarizes a v3.x metric
+func summarizeV3(metric *CVSSMetricV3, version string) CVSSSummary {
+	data := metric.CvssData
+	return CVSSSummary{
+		CVSSVersion:         versionOr(data.Version, version),
+		BaseScore:           data.BaseScore,
+		BaseSeverity:        severityOf(data.BaseSeverity, data.BaseScore, false),
+		AttackVector:        attackVectorOf(data.AttackVector, data.VectorString),
+		ExploitabilityScore: metric.ExploitabilityScore,
+	}
+}
+
+func versionOr(version, fallback string) string {
+	if version != "" {
+		return version
+	}
+	return fallback
+}
+
+// severityOf returns the given severity upper-cased or, when it is empty, the
+// severity band of the score: v2 has no NONE or CRITICAL band
+func severityOf(severity string, score float64, v2 bool) string {
+	if severity != "" {
+		return strings.ToUpper(severity)
+	}
+	switch {
+	case v2 && score >= 7.0:
+		return "HIGH"
+	case v2 && score >= 4.0:
+		return "MEDIUM"
+	case v2:
+		return "LOW"
+	case score >= 9.0:
+		return "CRITICAL"
+	case score >= 7.0:
+		return "HIGH"
+	case score >= 4.0:
+		return "MEDIUM"
+	case score > 0:
+		return "LOW"
+	default:
+		return "NONE"
+	}
+}
+
+// attackVectorOf normalizes the attack vector of a metric or, when it is
+// empty, takes it from the AV value of the vector string
+func attackVectorOf(attackVector, vector string) string {
+	switch av := strings.ToUpper(attackVector); av {
+	case "":
+	case "ADJACENT":
+		return AttackVectorAdjacent
+	default:
+		return av
+	}
+	if _, metrics, err := ParseCVSSVector(vector); err == nil {
+		return attackVectorCodes[metrics["AV"]]
+	}
+	return ""
+}
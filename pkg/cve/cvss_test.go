@@ -0,0 +1,106 @@
+package cve
+
+import (
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestParseCVSSVector(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseCVSSVector", nil, func(t *testing.T, tx *gorm.DB) {
+		version, metrics, err := ParseCVSSVector("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H")
+		if err != nil {
+			t.Fatalf("ParseCVSSVector failed: %v", err)
+		}
+		if version != "3.1" || metrics["AV"] != "N" || metrics["C"] != "H" || len(metrics) != 8 {
+			t.Errorf("unexpected v3.1 parse: %s %v", version, metrics)
+		}
+
+		version, metrics, err = ParseCVSSVector("(AV:A/AC:M/Au:N/C:P/I:N/A:N)")
+		if err != nil {
+			t.Fatalf("ParseCVSSVector failed: %v", err)
+		}
+		if version != "2.0" || metrics["AV"] != "A" || metrics["Au"] != "N" {
+			t.Errorf("unexpected v2 parse: %s %v", version, metrics)
+		}
+
+		for _, vector := range []string{"", "CVSS:/AV:N", "CVSS:3.1/AV", "CVSS:3.1/AV:N//AC:L"} {
+			if _, _, err := ParseCVSSVector(vector); err == nil {
+				t.Errorf("expected error for %q", vector)
+			}
+		}
+	})
+}
+
+func TestDeriveCVSS(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDeriveCVSS", nil, func(t *testing.T, tx *gorm.DB) {
+		v31 := CVSSMetricV3{
+			Source: "nvd@nist.gov",
+			Type:   "Primary",
+			CvssData: CVSSDataV3{
+				Version:      "3.1",
+				VectorString: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+				BaseScore:    9.8,
+				BaseSeverity: "CRITICAL",
+				AttackVector: "NETWORK",
+			},
+			ExploitabilityScore: 3.9,
+		}
+		v2 := CVSSMetricV2{
+			Type:                "Primary",
+			CvssData:            CVSSDataV2{Version: "2.0", VectorString: "AV:L/AC:L/Au:N/C:P/I:P/A:P", BaseScore: 4.6},
+			ExploitabilityScore: 3.9,
+		}
+
+		tests := []struct {
+			name string
+			item *CVEItem
+			want CVSSSummary
+		}{
+			{name: "nil item", item: nil, want: CVSSSummary{}},
+			{name: "no metrics", item: &CVEItem{ID: "CVE-2024-0001"}, want: CVSSSummary{}},
+			{name: "empty metrics", item: &CVEItem{Metrics: &Metrics{}}, want: CVSSSummary{}},
+			{
+				name: "v3.1 preferred over v2",
+				item: &CVEItem{Metrics: &Metrics{CvssMetricV31: []CVSSMetricV3{v31}, CvssMetricV2: []CVSSMetricV2{v2}}},
+				want: CVSSSummary{CVSSVersion: "3.1", BaseScore: 9.8, BaseSeverity: "CRITICAL", AttackVector: AttackVectorNetwork, ExploitabilityScore: 3.9},
+			},
+			{
+				name: "v2 only, severity from score and vector",
+				item: &CVEItem{Metrics: &Metrics{CvssMetricV2: []CVSSMetricV2{v2}}},
+				want: CVSSSummary{CVSSVersion: "2.0", BaseScore: 4.6, BaseSeverity: "MEDIUM", AttackVector: AttackVectorLocal, ExploitabilityScore: 3.9},
+			},
+			{
+				name: "primary metric over secondary",
+				item: &CVEItem{Metrics: &Metrics{CvssMetricV31: []CVSSMetricV3{
+					{Type: "Secondary", CvssData: CVSSDataV3{BaseScore: 5.3, VectorString: "CVSS:3.1/AV:P/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N"}},
+					v31,
+				}}},
+				want: CVSSSummary{CVSSVersion: "3.1", BaseScore: 9.8, BaseSeverity: "CRITICAL", AttackVector: AttackVectorNetwork, ExploitabilityScore: 3.9},
+			},
+			{
+				name: "v3.0 fields from vector",
+				item: &CVEItem{Metrics: &Metrics{CvssMetricV30: []CVSSMetricV3{
+					{CvssData: CVSSDataV3{BaseScore: 5.3, VectorString: "CVSS:3.0/AV:P/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N"}},
+				}}},
+				want: CVSSSummary{CVSSVersion: "3.0", BaseScore: 5.3, BaseSeverity: "MEDIUM", AttackVector: AttackVectorPhysical},
+			},
+			{
+				name: "v4.0 adjacent normalized",
+				item: &CVEItem{Metrics: &Metrics{CvssMetricV40: []CVSSMetricV40{
+					{CvssData: CVSSDataV40{Version: "4.0", BaseScore: 0, AttackVector: "ADJACENT"}},
+				}}},
+				want: CVSSSummary{CVSSVersion: "4.0", BaseSeverity: "NONE", AttackVector: AttackVectorAdjacent},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := DeriveCVSS(tt.item); got != tt.want {
+					t.Errorf("DeriveCVSS() = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -339,3 +339,41 @@ func BenchmarkBatchInsertThroughput_ComplexCVEs(b *testing.B) {
 		db.Close()
 	}
 }
+
+// BenchmarkSaveCVEsCommitSize measures how the commit size of a 1000-row
+// batch trades commit overhead against lock duration
+func BenchmarkSaveCVEsCommitSize(b *testing.B) {
+	cves := make([]cve.CVEItem, 1000)
+	for i := range cves {
+		cves[i] = cve.CVEItem{
+			ID:           fmt.Sprintf("CVE-2021-%05d", i),
+			SourceID:     "nvd@nist.gov",
+			Published:    cve.NewNVDTime(time.Now()),
+			LastModified: cve.NewNVDTime(time.Now()),
+			VulnStatus:   "Analyzed",
+			Descriptions: []cve.Description{{Lang: "en", Value: "Test CVE description"}},
+		}
+	}
+
+	for _, commitSize := range []int{0, 50, 200, 500} {
+		b.Run(fmt.Sprintf("CommitSize_%d", commitSize), func(b *testing.B) {
+			dbPath := fmt.Sprintf("/tmp/bench_commit_%d.db", commitSize)
+			defer os.Remove(dbPath)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				os.Remove(dbPath)
+				db, err := NewDB(dbPath)
+				if err != nil {
+					b.Fatal(err)
+				}
+				db.SetCommitSize(commitSize)
+				if err := db.SaveCVEs(cves); err != nil {
+					b.Fatal(err)
+				}
+				db.Close()
+			}
+		})
+	}
+}
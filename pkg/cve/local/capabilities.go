@@ -0,0 +1,53 @@
+package local
+
+import (
+	"context"
+
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+)
+
+// Schema objects probed for the CVE capabilities. Each is created by the
+// feature that populates it; until then the capability reports false.
+const (
+	cveFTSTable    = "cve_fts"    // full-text index of IDs and descriptions
+	cveCVSSColumn  = "base_score" // CVSS base score column of cve_records
+	cveEPSSColumn  = "epss_score" // EPSS score column of cve_records
+	cveKEVTable    = "cve_kev"    // KEV catalog entries
+	cveCPETable    = "cve_cpes"   // parsed CPE criteria of each CVE
+	cveRecordTable = "cve_records"
+)
+
+// DataCapabilities reports which CVE features are usable: whether CVEs are
+// stored, and whether the full-text index, CVSS column index, EPSS scores,
+// KEV catalog, parsed CPEs and CWE links they rely on are populated
+func (d *DB) DataCapabilities(ctx context.Context) (*capability.Capabilities, error) {
+	c := &capability.Capabilities{DataType: "cve"}
+	db := d.db.WithContext(ctx)
+
+	var ids []uint
+	if err := db.Model(&CVERecord{}).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	c.HasData = len(ids) > 0
+
+	var err error
+	if c.FTSAvailable, err = capability.HasFTSTable(ctx, d.db, cveFTSTable); err != nil {
+		return nil, err
+	}
+	if c.CVSSIndexed, err = capability.HasIndexOn(ctx, d.db, cveRecordTable, cveCVSSColumn); err != nil {
+		return nil, err
+	}
+	if c.EPSSLoaded, err = capability.HasValues(ctx, d.db, cveRecordTable, cveEPSSColumn); err != nil {
+		return nil, err
+	}
+	if c.KEVLoaded, err = capability.HasRows(ctx, d.db, cveKEVTable); err != nil {
+		return nil, err
+	}
+	if c.CPEParsed, err = capability.HasRows(ctx, d.db, cveCPETable); err != nil {
+		return nil, err
+	}
+	if c.MappingsBuilt, err = capability.HasRows(ctx, d.db, &CVECWERecord{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
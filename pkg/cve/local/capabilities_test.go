@@ -0,0 +1,71 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestDataCapabilities(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestDataCapabilities", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "capabilities.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+		ctx := context.Background()
+
+		// An empty database has only the full-text and CVSS indexes
+		c, err := db.DataCapabilities(ctx)
+		if err != nil {
+			t.Fatalf("DataCapabilities failed: %v", err)
+		}
+		if *c != (capability.Capabilities{DataType: "cve", FTSAvailable: true, CVSSIndexed: true}) {
+			t.Errorf("Expected no capabilities, got %+v", c)
+		}
+
+		// A CVE with a weakness brings data and CWE links
+		if err := db.SaveCVE(cveWithCWEs("CVE-2024-0001", "CWE-79")); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		// The KEV table exists but is not loaded yet
+		gdb := db.GormDB()
+		for _, stmt := range []string{
+			"INSERT INTO cve_cpes (cve_id, criteria, part, vendor, product) VALUES ('CVE-2024-0001', 'cpe:2.3:a:apache:log4j:*', 'a', 'apache', 'log4j')",
+		} {
+			if err := gdb.Exec(stmt).Error; err != nil {
+				t.Fatalf("%s: %v", stmt, err)
+			}
+		}
+		c, err = db.DataCapabilities(ctx)
+		if err != nil {
+			t.Fatalf("DataCapabilities failed: %v", err)
+		}
+		want := capability.Capabilities{DataType: "cve", HasData: true, FTSAvailable: true, CVSSIndexed: true, CPEParsed: true, MappingsBuilt: true}
+		if *c != want {
+			t.Errorf("Expected %+v, got %+v", want, c)
+		}
+
+		// Setting an EPSS score and loading KEV enable the rest
+		for _, stmt := range []string{
+			"UPDATE cve_records SET epss_score = 0.97",
+			"INSERT INTO cve_kev (cve_id) VALUES ('CVE-2024-0001')",
+		} {
+			if err := gdb.Exec(stmt).Error; err != nil {
+				t.Fatalf("%s: %v", stmt, err)
+			}
+		}
+		c, err = db.DataCapabilities(ctx)
+		if err != nil {
+			t.Fatalf("DataCapabilities failed: %v", err)
+		}
+		want = capability.Capabilities{DataType: "cve", HasData: true, FTSAvailable: true, CVSSIndexed: true, EPSSLoaded: true, KEVLoaded: true, CPEParsed: true, MappingsBuilt: true}
+		if *c != want {
+			t.Errorf("Expected %+v, got %+v", want, c)
+		}
+	})
+}
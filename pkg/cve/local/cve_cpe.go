@@ -0,0 +1,219 @@
+package local
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm"
+)
+
+// cpe23Prefix starts every CPE 2.3 formatted string
+const cpe23Prefix = "cpe:2.3:"
+
+// CVECPERecord links a CVE to the CPE criteria of its configurations that
+// are marked vulnerable, with the part, vendor and product of the criteria
+// split out for lookups. Like the cve_cwe rows, they are derived from the
+// CVE's data and written in the same transaction as the CVE row.
+type CVECPERecord struct {
+	ID       uint   `gorm:"primarykey"`
+	CVEID    string `gorm:"uniqueIndex:idx_cve_cpe;not null"`
+	Criteria string `gorm:"uniqueIndex:idx_cve_cpe;not null"`
+	Part     string `gorm:"not null"`
+	Vendor   string `gorm:"index:idx_cve_cpe_vendor_product;not null"`
+	Product  string `gorm:"index:idx_cve_cpe_vendor_product;not null"`
+}
+
+// TableName overrides the default table name
+func (CVECPERecord) TableName() string {
+	return cveCPETable
+}
+
+// splitCPE splits a CPE 2.3 formatted string into its components after
+// "cpe:2.3:" (part, vendor, product, version, ...), keeping escaped colons
+// within a component. It returns nil if s is not a CPE 2.3 string.
+func splitCPE(s string) []string {
+	if !strings.HasPrefix(strings.ToLower(s), cpe23Prefix) {
+		return nil
+	}
+	s = s[len(cpe23Prefix):]
+	var fields []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteByte(s[i])
+			i++
+			b.WriteByte(s[i])
+		case s[i] == ':':
+			fields = append(fields, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(fields, b.String())
+}
+
+// cpeLinksOf returns the join rows of a CVE: one per distinct vulnerable CPE
+// criteria naming a vendor and product, sorted by criteria
+func cpeLinksOf(cveItem *cve.CVEItem) []CVECPERecord {
+	seen := make(map[string]bool)
+	var links []CVECPERecord
+	for _, config := range cveItem.Configurations {
+		for _, node := range config.Nodes {
+			for _, match := range node.CPEMatch {
+				if !match.Vulnerable || seen[match.Criteria] {
+					continue
+				}
+				fields := splitCPE(match.Criteria)
+				if len(fields) < 3 || fields[1] == "" || fields[2] == "" {
+					continue
+				}
+				seen[match.Criteria] = true
+				links = append(links, CVECPERecord{
+					CVEID:    cveItem.ID,
+					Criteria: match.Criteria,
+					Part:     strings.ToLower(fields[0]),
+					Vendor:   strings.ToLower(fields[1]),
+					Product:  strings.ToLower(fields[2]),
+				})
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Criteria < links[j].Criteria })
+	return links
+}
+
+// replaceCPELinks replaces the join rows of the given CVEs with links. It must
+// run inside the transaction that writes the CVE rows.
+func replaceCPELinks(tx *gorm.DB, cveIDs []string, links []CVECPERecord) error {
+	for start := 0; start < len(cveIDs); start += insertStatementSize {
+		end := min(start+insertStatementSize, len(cveIDs))
+		if err := tx.Where("cve_id IN ?", cveIDs[start:end]).Delete(&CVECPERecord{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(links, insertStatementSize).Error
+}
+
+// cpeQuery is a parsed GetCVEsByCPE query
+type cpeQuery struct {
+	part    string // "" for any
+	vendor  string
+	product string // Prefix; "" for any product of the vendor
+}
+
+// parseCPEQuery parses a CPE query: a CPE 2.3 string such as
+// "cpe:2.3:a:apache:log4j" or "vendor:product" ("apache:log4j"). The product
+// is matched as a prefix; without a product, the vendor is. Components after
+// the product, such as the version, are ignored. "*" means any value and a
+// trailing "*" is dropped.
+func parseCPEQuery(s string) (cpeQuery, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var q cpeQuery
+	var fields []string
+	if parts := splitCPE(s); parts != nil {
+		q.part = parts[0]
+		fields = parts[1:]
+	} else {
+		fields = strings.SplitN(s, ":", 3)
+	}
+	field := func(i int) string {
+		if i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSuffix(fields[i], "*")
+	}
+	q.part = strings.TrimSuffix(q.part, "*")
+	q.vendor, q.product = field(0), field(1)
+	if q.vendor == "" {
+		return cpeQuery{}, fmt.Errorf("invalid cpe %q: must name a vendor, e.g. cpe:2.3:a:apache:log4j or apache:log4j", s)
+	}
+	return q, nil
+}
+
+// prefixPattern returns a LIKE pattern matching values starting with s, with
+// LIKE wildcards in s escaped by a backslash
+func prefixPattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+// GetCVEsByCPE returns a page of the CVEs with a vulnerable configuration
+// matching a CPE query (see parseCPEQuery), newest first, and the number of
+// such CVEs. CVEs whose status is in excludeStatuses are left out.
+func (d *DB) GetCVEsByCPE(cpe string, offset, limit int, excludeStatuses []string) ([]cve.CVEItem, int64, error) {
+	q, err := parseCPEQuery(cpe)
+	if err != nil {
+		return nil, 0, err
+	}
+	scope := func() *gorm.DB {
+		links := d.db.Model(&CVECPERecord{}).Select("cve_id")
+		if q.part != "" {
+			links = links.Where("part = ?", q.part)
+		}
+		if q.product != "" {
+			links = links.Where(`vendor = ? AND product LIKE ? ESCAPE '\'`, q.vendor, prefixPattern(q.product))
+		} else {
+			links = links.Where(`vendor LIKE ? ESCAPE '\'`, prefixPattern(q.vendor))
+		}
+		return d.statusScope(excludeStatuses).Where("cve_id IN (?)", links)
+	}
+
+	var records []CVERecord
+	var total int64
+	err = dbretry.Do(func() error {
+		if err := scope().Offset(offset).Limit(limit).Order("published desc").Find(&records).Error; err != nil {
+			return err
+		}
+		return scope().Count(&total).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, 0, err
+		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, 0, err
+	}
+	return cves, total, nil
+}
+
+// backfillCPELinks derives the join rows of CVEs stored before the cve_cpes
+// table existed by parsing their stored configurations. It runs while the
+// table is still empty.
+func backfillCPELinks(db *gorm.DB) error {
+	var links int64
+	if err := db.Model(&CVECPERecord{}).Count(&links).Error; err != nil || links > 0 {
+		return err
+	}
+
+	var records []CVERecord
+	return db.Select("id", "cve_id", "data").FindInBatches(&records, 500, func(tx *gorm.DB, batch int) error {
+		var batchLinks []CVECPERecord
+		for _, r := range records {
+			var item cve.CVEItem
+			if err := jsonutil.Unmarshal([]byte(r.Data), &item); err != nil {
+				continue // An undecodable record has no usable configurations
+			}
+			item.ID = r.CVEID
+			batchLinks = append(batchLinks, cpeLinksOf(&item)...)
+		}
+		if len(batchLinks) == 0 {
+			return nil
+		}
+		return db.CreateInBatches(batchLinks, insertStatementSize).Error
+	}).Error
+}
@@ -0,0 +1,149 @@
+package local
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func cveWithCPEs(id string, criteria ...string) *cve.CVEItem {
+	item := &cve.CVEItem{ID: id, VulnStatus: "Analyzed"}
+	node := cve.Node{Operator: "OR"}
+	for _, c := range criteria {
+		node.CPEMatch = append(node.CPEMatch, cve.CPEMatch{Vulnerable: true, Criteria: c})
+	}
+	item.Configurations = []cve.Config{{Nodes: []cve.Node{node}}}
+	return item
+}
+
+func TestCPELinksOf(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCPELinksOf", nil, func(t *testing.T, tx *gorm.DB) {
+		item := cveWithCPEs("CVE-2024-0001",
+			"cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*",
+			"cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*",
+			`cpe:2.3:a:foo\:bar:Widget:*:*:*:*:*:*:*:*`,
+			"cpe:2.3:a:*:",
+			"not a cpe")
+		// A platform the vulnerable product runs on is not affected
+		item.Configurations[0].Nodes = append(item.Configurations[0].Nodes, cve.Node{
+			CPEMatch: []cve.CPEMatch{{Vulnerable: false, Criteria: "cpe:2.3:o:linux:linux_kernel:-:*:*:*:*:*:*:*"}},
+		})
+
+		got := cpeLinksOf(item)
+		want := []CVECPERecord{
+			{CVEID: "CVE-2024-0001", Criteria: "cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*", Part: "a", Vendor: "apache", Product: "log4j"},
+			{CVEID: "CVE-2024-0001", Criteria: `cpe:2.3:a:foo\:bar:Widget:*:*:*:*:*:*:*:*`, Part: "a", Vendor: `foo\:bar`, Product: "widget"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("cpeLinksOf = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestParseCPEQuery(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseCPEQuery", nil, func(t *testing.T, tx *gorm.DB) {
+		cases := map[string]cpeQuery{
+			"cpe:2.3:a:apache:log4j":                    {part: "a", vendor: "apache", product: "log4j"},
+			"CPE:2.3:A:Apache:Log4j:2.14.1:*:*:*:*:*:*": {part: "a", vendor: "apache", product: "log4j"},
+			"cpe:2.3:*:apache:*":                        {vendor: "apache"},
+			"apache:log4j":                              {vendor: "apache", product: "log4j"},
+			" apache:log* ":                             {vendor: "apache", product: "log"},
+			"apa":                                       {vendor: "apa"},
+		}
+		for in, want := range cases {
+			got, err := parseCPEQuery(in)
+			if err != nil || got != want {
+				t.Errorf("parseCPEQuery(%q) = %+v, %v, want %+v", in, got, err, want)
+			}
+		}
+		for _, in := range []string{"", "cpe:2.3:a", ":log4j", "*"} {
+			if _, err := parseCPEQuery(in); err == nil {
+				t.Errorf("Expected parseCPEQuery(%q) to fail", in)
+			}
+		}
+	})
+}
+
+func TestGetCVEsByCPE(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestGetCVEsByCPE", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "cves-by-cpe.db")
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+
+		older := cveWithCPEs("CVE-2021-44228", "cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*")
+		older.Published = cve.NewNVDTime(time.Date(2021, 12, 10, 0, 0, 0, 0, time.UTC))
+		newer := cveWithCPEs("CVE-2021-45046", "cpe:2.3:a:apache:log4j:2.15.0:*:*:*:*:*:*:*", "cpe:2.3:a:apache:log4j_extras:1.0:*:*:*:*:*:*:*")
+		newer.Published = cve.NewNVDTime(time.Date(2021, 12, 14, 0, 0, 0, 0, time.UTC))
+		rejected := cveWithCPEs("CVE-2021-0003", "cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*")
+		rejected.VulnStatus = "Rejected"
+		other := cveWithCPEs("CVE-2021-0004", "cpe:2.3:a:apache:tomcat:9.0:*:*:*:*:*:*:*")
+		if err := db.SaveCVEs([]cve.CVEItem{*older, *newer, *rejected, *other}); err != nil {
+			t.Fatalf("SaveCVEs failed: %v", err)
+		}
+
+		ids := func(items []cve.CVEItem) []string {
+			out := []string{}
+			for _, item := range items {
+				out = append(out, item.ID)
+			}
+			return out
+		}
+		excluded := []string{cve.StatusRejected, cve.StatusDisputed}
+
+		items, total, err := db.GetCVEsByCPE("cpe:2.3:a:apache:log4j", 0, 10, excluded)
+		if err != nil {
+			t.Fatalf("GetCVEsByCPE failed: %v", err)
+		}
+		if total != 2 || !reflect.DeepEqual(ids(items), []string{"CVE-2021-45046", "CVE-2021-44228"}) {
+			t.Errorf("Expected the two active log4j CVEs newest first, got %v (total %d)", ids(items), total)
+		}
+		if items, total, _ := db.GetCVEsByCPE("apache:log4j_ex", 0, 10, excluded); total != 1 || items[0].ID != "CVE-2021-45046" {
+			t.Errorf("Expected a product prefix to match, got %v (total %d)", ids(items), total)
+		}
+		if items, total, _ := db.GetCVEsByCPE("apache", 1, 1, nil); total != 4 || len(items) != 1 {
+			t.Errorf("Expected a page of one of the four apache CVEs, got %v (total %d)", ids(items), total)
+		}
+		if _, total, _ := db.GetCVEsByCPE("cpe:2.3:o:apache:log4j", 0, 10, nil); total != 0 {
+			t.Errorf("Expected no operating system named log4j, got %d", total)
+		}
+		if _, total, _ := db.GetCVEsByCPE("apache:log%", 0, 10, nil); total != 0 {
+			t.Errorf("Expected LIKE wildcards to be literal, got %d", total)
+		}
+		if _, _, err := db.GetCVEsByCPE("", 0, 10, nil); err == nil {
+			t.Error("Expected a query without a vendor to be rejected")
+		}
+
+		// An updated CVE replaces its links, a deleted one drops them
+		newer.Configurations = cveWithCPEs(newer.ID, "cpe:2.3:a:apache:tomcat:10.0:*:*:*:*:*:*:*").Configurations
+		if err := db.SaveCVE(newer); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if err := db.DeleteCVE("CVE-2021-0004"); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if items, total, _ := db.GetCVEsByCPE("apache:tomcat", 0, 10, nil); total != 1 || items[0].ID != "CVE-2021-45046" {
+			t.Errorf("Expected only the updated CVE to affect tomcat, got %v (total %d)", ids(items), total)
+		}
+
+		// CVEs stored before the cve_cpes table existed are linked on open
+		if err := db.GormDB().Exec("DELETE FROM cve_cpes").Error; err != nil {
+			t.Fatalf("Failed to clear cve_cpes: %v", err)
+		}
+		db.Close()
+		db, err = NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		if items, total, _ := db.GetCVEsByCPE("cpe:2.3:a:apache:log4j", 0, 10, nil); total != 2 || items[0].ID != "CVE-2021-44228" {
+			t.Errorf("Expected the backfilled links of the log4j CVEs, got %v (total %d)", ids(items), total)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package local
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm"
+)
+
+// CVECWERecord links a CVE to one of the CWEs listed in its weaknesses. The
+// rows are derived from the CVE's data and always written in the same
+// transaction as the CVE row, so the two never disagree.
+type CVECWERecord struct {
+	ID    uint   `gorm:"primarykey"`
+	CVEID string `gorm:"uniqueIndex:idx_cve_cwe;not null"`
+	CWEID string `gorm:"uniqueIndex:idx_cve_cwe;index;not null"`
+}
+
+// TableName overrides the default table name
+func (CVECWERecord) TableName() string {
+	return "cve_cwe"
+}
+
+// cweIDsOf returns the distinct CWE IDs ("CWE-79") of a CVE's weaknesses.
+// NVD placeholders such as "NVD-CWE-noinfo" are not CWEs and are skipped.
+func cweIDsOf(cveItem *cve.CVEItem) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, w := range cveItem.Weaknesses {
+		for _, d := range w.Description {
+			id := cweIDOf(d.Value)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// cweIDOf normalizes a weakness description ("CWE-79" or "CWE-79: Improper
+// Neutralization ...") to its CWE ID, or returns "" if it names no CWE
+func cweIDOf(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if !strings.HasPrefix(value, "CWE-") {
+		return ""
+	}
+	digits := strings.TrimPrefix(value, "CWE-")
+	n := 0
+	for n < len(digits) && digits[n] >= '0' && digits[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return ""
+	}
+	return "CWE-" + digits[:n]
+}
+
+// cweLinksOf returns the join rows of a CVE
+func cweLinksOf(cveItem *cve.CVEItem) []CVECWERecord {
+	ids := cweIDsOf(cveItem)
+	links := make([]CVECWERecord, len(ids))
+	for i, id := range ids {
+		links[i] = CVECWERecord{CVEID: cveItem.ID, CWEID: id}
+	}
+	return links
+}
+
+// replaceCWELinks replaces the join rows of the given CVEs with links. It must
+// run inside the transaction that writes the CVE rows.
+func replaceCWELinks(tx *gorm.DB, cveIDs []string, links []CVECWERecord) error {
+	for start := 0; start < len(cveIDs); start += insertStatementSize {
+		end := min(start+insertStatementSize, len(cveIDs))
+		if err := tx.Where("cve_id IN ?", cveIDs[start:end]).Delete(&CVECWERecord{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(links, insertStatementSize).Error
+}
+
+// GetCWEIDsByCVE returns the CWE IDs linked to a CVE, sorted
+func (d *DB) GetCWEIDsByCVE(cveID string) ([]string, error) {
+	ids := []string{}
+	err := d.db.Model(&CVECWERecord{}).Where("cve_id = ?", cveID).Order("cwe_id").Pluck("cwe_id", &ids).Error
+	return ids, err
+}
+
+// normalizeCWEID returns the CWE ID named by s ("CWE-79", "cwe-79" or "79"),
+// or "" if s names no CWE
+func normalizeCWEID(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(s, "CWE-") {
+		s = "CWE-" + s
+	}
+	if id := cweIDOf(s); id == s {
+		return id
+	}
+	return ""
+}
+
+// GetCVEsByCWE returns a page of the CVEs linked to a CWE, newest first, and
+// the number of such CVEs. The CWE may be given as "CWE-79" or "79". CVEs
+// whose status is in excludeStatuses are left out.
+func (d *DB) GetCVEsByCWE(cweID string, offset, limit int, excludeStatuses []string) ([]cve.CVEItem, int64, error) {
+	id := normalizeCWEID(cweID)
+	if id == "" {
+		return nil, 0, fmt.Errorf("invalid cwe_id %q: must be a CWE ID such as CWE-79", cweID)
+	}
+	scope := func() *gorm.DB {
+		return d.statusScope(excludeStatuses).Where("cve_id IN (SELECT cve_id FROM cve_cwe WHERE cwe_id = ?)", id)
+	}
+
+	var records []CVERecord
+	var total int64
+	err := dbretry.Do(func() error {
+		if err := scope().Offset(offset).Limit(limit).Order("published desc").Find(&records).Error; err != nil {
+			return err
+		}
+		return scope().Count(&total).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, 0, err
+		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, 0, err
+	}
+	return cves, total, nil
+}
+
+// backfillCWELinks derives the join rows of CVEs stored before the cve_cwe
+// table existed. It runs once, while the table is still empty.
+func backfillCWELinks(db *gorm.DB) error {
+	var links int64
+	if err := db.Model(&CVECWERecord{}).Count(&links).Error; err != nil || links > 0 {
+		return err
+	}
+
+	var records []CVERecord
+	return db.Select("id", "cve_id", "data").FindInBatches(&records, 500, func(tx *gorm.DB, batch int) error {
+		var batchLinks []CVECWERecord
+		for _, r := range records {
+			var item cve.CVEItem
+			if err := jsonutil.Unmarshal([]byte(r.Data), &item); err != nil {
+				continue // An undecodable record has no usable weaknesses
+			}
+			item.ID = r.CVEID
+			batchLinks = append(batchLinks, cweLinksOf(&item)...)
+		}
+		if len(batchLinks) == 0 {
+			return nil
+		}
+		return db.CreateInBatches(batchLinks, insertStatementSize).Error
+	}).Error
+}
@@ -0,0 +1,240 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func cveWithCWEs(id string, cweIDs ...string) *cve.CVEItem {
+	item := &cve.CVEItem{ID: id, VulnStatus: "Analyzed"}
+	for _, c := range cweIDs {
+		item.Weaknesses = append(item.Weaknesses, cve.Weakness{
+			Source:      "nvd@nist.gov",
+			Type:        "Primary",
+			Description: []cve.Description{{Lang: "en", Value: c}},
+		})
+	}
+	return item
+}
+
+// failCWELinks makes every write to the cve_cwe table fail, simulating a
+// crash between the CVE row and its join rows. It returns a func undoing it.
+func failCWELinks(t *testing.T, db *DB) func() {
+	t.Helper()
+	crash := func(tx *gorm.DB) {
+		if tx.Statement.Table == "cve_cwe" {
+			tx.AddError(errors.New("simulated crash writing cve_cwe"))
+		}
+	}
+	callbacks := db.GormDB().Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("test:crash_cve_cwe", crash); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("test:crash_cve_cwe", crash); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	return func() {
+		callbacks.Create().Remove("test:crash_cve_cwe")
+		callbacks.Delete().Remove("test:crash_cve_cwe")
+	}
+}
+
+func TestCWEIDsOf(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCWEIDsOf", nil, func(t *testing.T, tx *gorm.DB) {
+		item := cveWithCWEs("CVE-2024-0001", "CWE-89", "NVD-CWE-noinfo", "cwe-79: Improper Neutralization", "CWE-89", "CWE-")
+		got := cweIDsOf(item)
+		want := []string{"CWE-79", "CWE-89"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("cweIDsOf = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSaveCVE_CrashBetweenCVEAndJoinRows(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVE_CrashBetweenCVEAndJoinRows", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_save_cve_crash.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		// A new CVE whose join rows fail to write is not saved at all
+		restore := failCWELinks(t, db)
+		if err := db.SaveCVE(cveWithCWEs("CVE-2024-1001", "CWE-79")); err == nil {
+			t.Fatal("Expected SaveCVE to fail")
+		}
+		restore()
+		if _, err := db.GetCVE("CVE-2024-1001"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected the CVE row to be rolled back, got %v", err)
+		}
+
+		// The retried save writes both
+		if err := db.SaveCVE(cveWithCWEs("CVE-2024-1001", "CWE-79")); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if ids, _ := db.GetCWEIDsByCVE("CVE-2024-1001"); !reflect.DeepEqual(ids, []string{"CWE-79"}) {
+			t.Errorf("Expected [CWE-79], got %v", ids)
+		}
+
+		// An update that crashes keeps the previous CVE data and join rows
+		update := cveWithCWEs("CVE-2024-1001", "CWE-89")
+		update.VulnStatus = "Modified"
+		restore = failCWELinks(t, db)
+		if err := db.SaveCVE(update); err == nil {
+			t.Fatal("Expected SaveCVE to fail")
+		}
+		restore()
+		stored, err := db.GetCVE("CVE-2024-1001")
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		if stored.VulnStatus != "Analyzed" {
+			t.Errorf("Expected the previous CVE data, got status %q", stored.VulnStatus)
+		}
+		if ids, _ := db.GetCWEIDsByCVE("CVE-2024-1001"); !reflect.DeepEqual(ids, []string{"CWE-79"}) {
+			t.Errorf("Expected the previous join rows [CWE-79], got %v", ids)
+		}
+
+		// Once it succeeds the join rows are replaced
+		if err := db.SaveCVE(update); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if ids, _ := db.GetCWEIDsByCVE("CVE-2024-1001"); !reflect.DeepEqual(ids, []string{"CWE-89"}) {
+			t.Errorf("Expected [CWE-89], got %v", ids)
+		}
+	})
+}
+
+func TestSaveCVEs_CrashRollsBackCommit(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVEs_CrashRollsBackCommit", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_save_cves_crash.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		items := []cve.CVEItem{*cveWithCWEs("CVE-2024-2001", "CWE-79"), *cveWithCWEs("CVE-2024-2002", "CWE-20", "CWE-787")}
+		restore := failCWELinks(t, db)
+		if err := db.SaveCVEs(items); err == nil {
+			t.Fatal("Expected SaveCVEs to fail")
+		}
+		restore()
+		if count, _ := db.Count(); count != 0 {
+			t.Errorf("Expected no CVE rows after the crash, got %d", count)
+		}
+
+		if err := db.SaveCVEs(items); err != nil {
+			t.Fatalf("SaveCVEs failed: %v", err)
+		}
+		if ids, _ := db.GetCWEIDsByCVE("CVE-2024-2002"); !reflect.DeepEqual(ids, []string{"CWE-20", "CWE-787"}) {
+			t.Errorf("Expected [CWE-20 CWE-787], got %v", ids)
+		}
+
+		if err := db.DeleteCVE("CVE-2024-2002"); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if ids, _ := db.GetCWEIDsByCVE("CVE-2024-2002"); len(ids) != 0 {
+			t.Errorf("Expected the join rows to be deleted with the CVE, got %v", ids)
+		}
+	})
+}
+
+func TestNormalizeCWEID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestNormalizeCWEID", nil, func(t *testing.T, tx *gorm.DB) {
+		for in, want := range map[string]string{
+			"CWE-79":         "CWE-79",
+			" cwe-79 ":       "CWE-79",
+			"79":             "CWE-79",
+			"CWE-79: Improp": "",
+			"NVD-CWE-noinfo": "",
+			"CWE-":           "",
+			"":               "",
+		} {
+			if got := normalizeCWEID(in); got != want {
+				t.Errorf("normalizeCWEID(%q) = %q, want %q", in, got, want)
+			}
+		}
+	})
+}
+
+func TestGetCVEsByCWE(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestGetCVEsByCWE", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "cves-by-cwe.db")
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+
+		older := cveWithCWEs("CVE-2024-3001", "CWE-79")
+		older.Published = cve.NewNVDTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		newer := cveWithCWEs("CVE-2024-3002", "CWE-79", "CWE-89")
+		newer.Published = cve.NewNVDTime(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+		rejected := cveWithCWEs("CVE-2024-3003", "CWE-79")
+		rejected.VulnStatus = "Rejected"
+		other := cveWithCWEs("CVE-2024-3004", "CWE-89")
+		if err := db.SaveCVEs([]cve.CVEItem{*older, *newer, *rejected, *other}); err != nil {
+			t.Fatalf("SaveCVEs failed: %v", err)
+		}
+
+		ids := func(items []cve.CVEItem) []string {
+			out := []string{}
+			for _, item := range items {
+				out = append(out, item.ID)
+			}
+			return out
+		}
+		excluded := []string{cve.StatusRejected, cve.StatusDisputed}
+
+		items, total, err := db.GetCVEsByCWE("cwe-79", 0, 10, excluded)
+		if err != nil {
+			t.Fatalf("GetCVEsByCWE failed: %v", err)
+		}
+		if total != 2 || !reflect.DeepEqual(ids(items), []string{"CVE-2024-3002", "CVE-2024-3001"}) {
+			t.Errorf("Expected the two active CWE-79 CVEs newest first, got %v (total %d)", ids(items), total)
+		}
+		if items, total, _ := db.GetCVEsByCWE("79", 1, 1, nil); total != 3 || len(items) != 1 {
+			t.Errorf("Expected a page of one of three CVEs, got %v (total %d)", ids(items), total)
+		}
+		if _, _, err := db.GetCVEsByCWE("XSS", 0, 10, nil); err == nil {
+			t.Error("Expected an invalid CWE ID to be rejected")
+		}
+
+		// Deleted CVEs are no longer listed
+		if err := db.DeleteCVE("CVE-2024-3002"); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if items, total, _ := db.GetCVEsByCWE("CWE-79", 0, 10, excluded); total != 1 || len(items) != 1 {
+			t.Errorf("Expected only CVE-2024-3001 after the delete, got %v (total %d)", ids(items), total)
+		}
+
+		// CVEs stored before the cve_cwe table existed are linked on open
+		if err := db.GormDB().Exec("DELETE FROM cve_cwe").Error; err != nil {
+			t.Fatalf("Failed to clear cve_cwe: %v", err)
+		}
+		db.Close()
+		db, err = NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		if items, total, _ := db.GetCVEsByCWE("CWE-89", 0, 10, nil); total != 1 || items[0].ID != "CVE-2024-3004" {
+			t.Errorf("Expected the backfilled link of CVE-2024-3004, got %v (total %d)", ids(items), total)
+		}
+	})
+}
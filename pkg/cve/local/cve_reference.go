@@ -0,0 +1,169 @@
+package local
+
+import (
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm"
+)
+
+// CVEReferenceRecord is a reference of a CVE with its tags and the category
+// derived from them (see cve.ClassifyReference). Like the cve_cwe rows, the
+// rows are derived from the CVE's data and always written in the same
+// transaction as the CVE row.
+type CVEReferenceRecord struct {
+	ID       uint   `gorm:"primarykey"`
+	CVEID    string `gorm:"index;not null"`
+	Position int    // Index of the reference in the CVE's references
+	URL      string `gorm:"not null"`
+	Source   string
+	Tags     string // Comma-separated NVD tags, "" if the reference has none
+	Category string `gorm:"index"`
+}
+
+// TableName overrides the default table name
+func (CVEReferenceRecord) TableName() string {
+	return "cve_references"
+}
+
+// referenceLinksOf returns the reference rows of a CVE
+func referenceLinksOf(cveItem *cve.CVEItem) []CVEReferenceRecord {
+	links := make([]CVEReferenceRecord, 0, len(cveItem.References))
+	for i, ref := range cveItem.References {
+		if ref.URL == "" {
+			continue
+		}
+		links = append(links, CVEReferenceRecord{
+			CVEID:    cveItem.ID,
+			Position: i,
+			URL:      ref.URL,
+			Source:   ref.Source,
+			Tags:     strings.Join(ref.Tags, ","),
+			Category: cve.ClassifyReference(ref.Tags),
+		})
+	}
+	return links
+}
+
+// replaceReferenceLinks replaces the reference rows of the given CVEs with
+// links. It must run inside the transaction that writes the CVE rows.
+func replaceReferenceLinks(tx *gorm.DB, cveIDs []string, links []CVEReferenceRecord) error {
+	for start := 0; start < len(cveIDs); start += insertStatementSize {
+		end := min(start+insertStatementSize, len(cveIDs))
+		if err := tx.Where("cve_id IN ?", cveIDs[start:end]).Delete(&CVEReferenceRecord{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(links, insertStatementSize).Error
+}
+
+// attachReferenceCategories sets the category of the references of CVEs read
+// from the database. A reference without a stored row, e.g. one added to the
+// data by hand, is classified from its tags.
+func (d *DB) attachReferenceCategories(cves []cve.CVEItem) error {
+	if len(cves) == 0 {
+		return nil
+	}
+	ids := make([]string, len(cves))
+	for i := range cves {
+		ids[i] = cves[i].ID
+	}
+	var records []CVEReferenceRecord
+	if err := d.db.Select("cve_id", "position", "url", "category").Where("cve_id IN ?", ids).Find(&records).Error; err != nil {
+		return err
+	}
+	type key struct {
+		cveID    string
+		position int
+	}
+	stored := make(map[key]*CVEReferenceRecord, len(records))
+	for i := range records {
+		stored[key{records[i].CVEID, records[i].Position}] = &records[i]
+	}
+	for i := range cves {
+		for j := range cves[i].References {
+			ref := &cves[i].References[j]
+			if r := stored[key{cves[i].ID, j}]; r != nil && r.URL == ref.URL {
+				ref.Category = r.Category
+			} else {
+				ref.Category = cve.ClassifyReference(ref.Tags)
+			}
+		}
+	}
+	return nil
+}
+
+// stripReferenceCategories clears attached categories so they, like the
+// reference health, are never persisted into the CVE JSON data
+func stripReferenceCategories(cveItem *cve.CVEItem) {
+	for i := range cveItem.References {
+		cveItem.References[i].Category = ""
+	}
+}
+
+// GetCVEsWithExploitRefs returns a page of the CVEs with at least one
+// reference tagged "Exploit", newest first, and the number of such CVEs.
+// CVEs whose status is in excludeStatuses are left out.
+func (d *DB) GetCVEsWithExploitRefs(offset, limit int, excludeStatuses []string) ([]cve.CVEItem, int64, error) {
+	scope := func() *gorm.DB {
+		return d.statusScope(excludeStatuses).Where("cve_id IN (SELECT cve_id FROM cve_references WHERE category = ?)", cve.ReferenceCategoryExploit)
+	}
+
+	var records []CVERecord
+	var total int64
+	err := dbretry.Do(func() error {
+		if err := scope().Offset(offset).Limit(limit).Order("published desc").Find(&records).Error; err != nil {
+			return err
+		}
+		return scope().Count(&total).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, 0, err
+		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, 0, err
+	}
+	if err := d.attachReferenceCategories(cves); err != nil {
+		return nil, 0, err
+	}
+	return cves, total, nil
+}
+
+// backfillReferenceLinks derives the reference rows of CVEs stored before the
+// cve_references table existed. It runs once, while the table is still empty.
+func backfillReferenceLinks(db *gorm.DB) error {
+	var links int64
+	if err := db.Model(&CVEReferenceRecord{}).Count(&links).Error; err != nil || links > 0 {
+		return err
+	}
+
+	var records []CVERecord
+	return db.Select("id", "cve_id", "data").FindInBatches(&records, 500, func(tx *gorm.DB, batch int) error {
+		var batchLinks []CVEReferenceRecord
+		for _, r := range records {
+			var item cve.CVEItem
+			if err := jsonutil.Unmarshal([]byte(r.Data), &item); err != nil {
+				continue // An undecodable record has no usable references
+			}
+			item.ID = r.CVEID
+			batchLinks = append(batchLinks, referenceLinksOf(&item)...)
+		}
+		if len(batchLinks) == 0 {
+			return nil
+		}
+		return db.CreateInBatches(batchLinks, insertStatementSize).Error
+	}).Error
+}
@@ -0,0 +1,141 @@
+package local
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func cveWithReferences(id string, published time.Time, refs ...cve.Reference) *cve.CVEItem {
+	return &cve.CVEItem{
+		ID:         id,
+		VulnStatus: "Analyzed",
+		Published:  cve.NewNVDTime(published),
+		References: refs,
+	}
+}
+
+func TestCVEReferences(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCVEReferences", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "refs.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		withPoC := cveWithReferences("CVE-2024-2001", base,
+			cve.Reference{URL: "https://vendor.example/advisory", Tags: []string{"Vendor Advisory", "Patch"}},
+			cve.Reference{URL: "https://github.example/poc", Tags: []string{"Exploit", "Third Party Advisory"}},
+			cve.Reference{URL: "https://lists.example/thread"},
+		)
+		newerPoC := cveWithReferences("CVE-2024-2002", base.Add(24*time.Hour),
+			cve.Reference{URL: "https://exploit-db.example/1", Tags: []string{"Exploit"}},
+		)
+		noPoC := cveWithReferences("CVE-2024-2003", base.Add(48*time.Hour),
+			cve.Reference{URL: "https://vendor.example/fix", Tags: []string{"Patch"}},
+		)
+		if err := db.SaveCVE(withPoC); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if err := db.SaveCVEs([]cve.CVEItem{*newerPoC, *noPoC}); err != nil {
+			t.Fatalf("SaveCVEs failed: %v", err)
+		}
+
+		got, err := db.GetCVE(withPoC.ID)
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		want := []string{cve.ReferenceCategoryPatch, cve.ReferenceCategoryExploit, cve.ReferenceCategoryOther}
+		if len(got.References) != len(want) {
+			t.Fatalf("Expected %d references, got %+v", len(want), got.References)
+		}
+		for i, ref := range got.References {
+			if ref.Category != want[i] {
+				t.Errorf("Reference %s: expected category %q, got %q", ref.URL, want[i], ref.Category)
+			}
+		}
+
+		// Categories are derived, not stored in the CVE data
+		raw, err := db.GetCVERaw(withPoC.ID)
+		if err != nil {
+			t.Fatalf("GetCVERaw failed: %v", err)
+		}
+		if strings.Contains(raw.Data, "category") {
+			t.Errorf("Expected no categories in the stored data, got %s", raw.Data)
+		}
+
+		var tagged CVEReferenceRecord
+		if err := db.GormDB().Where("cve_id = ? AND position = 1", withPoC.ID).First(&tagged).Error; err != nil {
+			t.Fatalf("Failed to read reference row: %v", err)
+		}
+		if tagged.Tags != "Exploit,Third Party Advisory" || tagged.Category != cve.ReferenceCategoryExploit {
+			t.Errorf("Unexpected reference row %+v", tagged)
+		}
+
+		cves, total, err := db.GetCVEsWithExploitRefs(0, 10, nil)
+		if err != nil {
+			t.Fatalf("GetCVEsWithExploitRefs failed: %v", err)
+		}
+		if total != 2 || len(cves) != 2 || cves[0].ID != newerPoC.ID || cves[1].ID != withPoC.ID {
+			t.Fatalf("Expected the two CVEs with exploits, newest first, got %d: %+v", total, cves)
+		}
+		if cves[0].References[0].Category != cve.ReferenceCategoryExploit {
+			t.Errorf("Expected listed CVEs to carry categories, got %+v", cves[0].References)
+		}
+
+		// Re-saving without the exploit reference drops the CVE from the list
+		withPoC.References = withPoC.References[:1]
+		if err := db.SaveCVE(withPoC); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if err := db.DeleteCVE(newerPoC.ID); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if _, total, err := db.GetCVEsWithExploitRefs(0, 10, nil); err != nil || total != 0 {
+			t.Errorf("Expected no CVEs with exploits, got %d, %v", total, err)
+		}
+		var rows int64
+		db.GormDB().Model(&CVEReferenceRecord{}).Count(&rows)
+		if rows != 2 {
+			t.Errorf("Expected 2 reference rows left, got %d", rows)
+		}
+	})
+}
+
+func TestBackfillReferenceLinks(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestBackfillReferenceLinks", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "refs.db")
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+
+		// A CVE stored before the cve_references table existed
+		item := cveWithReferences("CVE-2024-2101", time.Now(), cve.Reference{URL: "https://poc.example", Tags: []string{"Exploit"}})
+		data, err := jsonutil.Marshal(item)
+		if err != nil {
+			t.Fatalf("Failed to marshal CVE: %v", err)
+		}
+		if err := db.GormDB().Create(&CVERecord{CVEID: item.ID, Status: cve.StatusActive, Data: string(data)}).Error; err != nil {
+			t.Fatalf("Failed to insert CVE row: %v", err)
+		}
+		db.Close()
+
+		db, err = NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		cves, total, err := db.GetCVEsWithExploitRefs(0, 10, nil)
+		if err != nil || total != 1 || cves[0].ID != item.ID {
+			t.Errorf("Expected the backfilled CVE listed, got %d, %v", total, err)
+		}
+	})
+}
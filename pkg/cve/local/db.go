@@ -1,19 +1,44 @@
 package local
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/jsonutil"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
+// insertStatementSize is the number of rows per INSERT statement in SaveCVEs
+const insertStatementSize = 100
+
 // DB represents the database connection
 type DB struct {
 	db *gorm.DB
+	// commitSize is the number of rows SaveCVEs commits per transaction;
+	// 0 commits each call in a single transaction
+	commitSize int
+	// fts is true when the cve_fts full-text index is available
+	fts bool
+}
+
+// SetCommitSize sets how many rows SaveCVEs commits per transaction.
+// Larger commits amortize fsync and index overhead and import faster, but
+// hold the SQLite write lock longer, stalling concurrent readers and writers.
+// Smaller commits release the lock sooner at the cost of more commits.
+// 0 (the default) commits each SaveCVEs call as one transaction.
+func (d *DB) SetCommitSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	d.commitSize = n
 }
 
 // GormDB returns the underlying GORM database instance
@@ -37,14 +62,69 @@ type CVERecord struct {
 	Published    time.Time `gorm:"index"`
 	LastModified time.Time `gorm:"index"`
 	VulnStatus   string    `gorm:"index"`
+	Status       string    `gorm:"index"`     // Derived status: active, rejected or disputed
 	Data         string    `gorm:"type:text"` // JSON representation of full CVEItem
+
+	// Derived from the preferred CVSS metric (see cve.DeriveCVSS). A NULL
+	// cvss_version marks a row stored before these columns existed.
+	CVSSVersion         string
+	BaseScore           float64 `gorm:"index"`
+	BaseSeverity        string  `gorm:"index"`
+	AttackVector        string  `gorm:"index"`
+	ExploitabilityScore float64
+
+	// EPSS score from FIRST (see cve.EPSS), NULL if none was stored. A save
+	// without a score keeps the stored one, so NVD refreshes do not clear it.
+	EPSSScore      *float64 `gorm:"index"`
+	EPSSPercentile *float64
+	EPSSDate       string
+}
+
+// applyDerived sets the locally derived fields of a CVE read from the record
+func (r *CVERecord) applyDerived(item *cve.CVEItem) {
+	item.Status = r.Status
+	item.CVSSSummary = cve.CVSSSummary{
+		CVSSVersion:         r.CVSSVersion,
+		BaseScore:           r.BaseScore,
+		BaseSeverity:        r.BaseSeverity,
+		AttackVector:        r.AttackVector,
+		ExploitabilityScore: r.ExploitabilityScore,
+	}
+	item.EPSS = nil
+	if r.EPSSScore != nil {
+		item.EPSS = &cve.EPSS{Score: *r.EPSSScore, Date: r.EPSSDate}
+		if r.EPSSPercentile != nil {
+			item.EPSS.Percentile = *r.EPSSPercentile
+		}
+	}
+}
+
+// setEPSS sets the EPSS columns of the record from item, if it has a score
+func (r *CVERecord) setEPSS(item *cve.CVEItem) {
+	if item.EPSS == nil {
+		return
+	}
+	score, percentile := item.EPSS.Score, item.EPSS.Percentile
+	r.EPSSScore = &score
+	r.EPSSPercentile = &percentile
+	r.EPSSDate = item.EPSS.Date
+}
+
+// marshalCVEData returns the JSON stored in the data column of a CVE. The
+// EPSS score lives in its own columns and the KEV listing in cve_kev, so they
+// are left out.
+func marshalCVEData(item *cve.CVEItem) ([]byte, error) {
+	epss, kev := item.EPSS, item.KEV
+	item.EPSS, item.KEV = nil, nil
+	defer func() { item.EPSS, item.KEV = epss, kev }()
+	return jsonutil.Marshal(item)
 }
 
 // NewOptimizedDB creates an optimized database connection
 func NewOptimizedDB(dbPath string) (*DB, error) {
 	// Disable GORM logging to prevent interference with RPC message parsing
 	// When running as a subprocess, stdout is used for RPC messages only
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 		// Enable prepared statement caching for better performance
 		PrepareStmt: true,
@@ -54,7 +134,26 @@ func NewOptimizedDB(dbPath string) (*DB, error) {
 	}
 
 	// Auto-migrate the schema
-	if err := db.AutoMigrate(&CVERecord{}); err != nil {
+	if err := db.AutoMigrate(&CVERecord{}, &CVECWERecord{}, &CVECPERecord{}, &CVEKEVRecord{}, &ReferenceHealthRecord{}, &CVEReferenceRecord{}); err != nil {
+		return nil, err
+	}
+	if err := backfillCVSS(db); err != nil {
+		return nil, err
+	}
+	if err := backfillStatus(db); err != nil {
+		return nil, err
+	}
+	if err := backfillCWELinks(db); err != nil {
+		return nil, err
+	}
+	if err := backfillCPELinks(db); err != nil {
+		return nil, err
+	}
+	if err := backfillReferenceLinks(db); err != nil {
+		return nil, err
+	}
+	fts, err := ensureCVEFTS(db)
+	if err != nil {
 		return nil, err
 	}
 
@@ -83,7 +182,7 @@ func NewOptimizedDB(dbPath string) (*DB, error) {
 		}
 	}
 
-	return &DB{db: db}, nil
+	return &DB{db: db, fts: fts}, nil
 }
 
 // NewDB creates a new database connection
@@ -91,7 +190,7 @@ func NewOptimizedDB(dbPath string) (*DB, error) {
 func NewDB(dbPath string) (*DB, error) {
 	// Disable GORM logging to prevent interference with RPC message parsing
 	// When running as a subprocess, stdout is used for RPC messages only
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 		// Enable prepared statement caching for better performance
 		PrepareStmt: true,
@@ -101,7 +200,26 @@ func NewDB(dbPath string) (*DB, error) {
 	}
 
 	// Auto-migrate the schema
-	if err := db.AutoMigrate(&CVERecord{}); err != nil {
+	if err := db.AutoMigrate(&CVERecord{}, &CVECWERecord{}, &CVECPERecord{}, &CVEKEVRecord{}, &ReferenceHealthRecord{}, &CVEReferenceRecord{}); err != nil {
+		return nil, err
+	}
+	if err := backfillCVSS(db); err != nil {
+		return nil, err
+	}
+	if err := backfillStatus(db); err != nil {
+		return nil, err
+	}
+	if err := backfillCWELinks(db); err != nil {
+		return nil, err
+	}
+	if err := backfillCPELinks(db); err != nil {
+		return nil, err
+	}
+	if err := backfillReferenceLinks(db); err != nil {
+		return nil, err
+	}
+	fts, err := ensureCVEFTS(db)
+	if err != nil {
 		return nil, err
 	}
 
@@ -139,7 +257,6 @@ func NewDB(dbPath string) (*DB, error) {
 		"PRAGMA mmap_size=268435456", // 256MB memory mapping
 		"PRAGMA temp_store=memory",   // Store temp tables in memory
 		"PRAGMA foreign_keys=OFF",    // Disable FK constraints for speed
-		"PRAGMA busy_timeout=30000",  // Wait up to 30 seconds for locks
 	}
 	for _, pragma := range pragmas {
 		if _, err := sqlDB.Exec(pragma); err != nil {
@@ -147,13 +264,62 @@ func NewDB(dbPath string) (*DB, error) {
 		}
 	}
 
-	return &DB{db: db}, nil
+	return &DB{db: db, fts: fts}, nil
+}
+
+// backfillStatus sets the derived status on records stored before the status
+// column existed. Only rejection is visible without decoding the JSON data;
+// disputed CVEs are picked up the next time they are re-fetched and saved.
+func backfillStatus(db *gorm.DB) error {
+	if err := db.Model(&CVERecord{}).Unscoped().
+		Where("(status = '' OR status IS NULL) AND vuln_status = ?", "Rejected").
+		Update("status", cve.StatusRejected).Error; err != nil {
+		return err
+	}
+	return db.Model(&CVERecord{}).Unscoped().
+		Where("status = '' OR status IS NULL").
+		Update("status", cve.StatusActive).Error
+}
+
+// backfillCVSS derives the CVSS columns of records stored before they
+// existed. Undecodable records get an empty summary so they are not revisited.
+func backfillCVSS(db *gorm.DB) error {
+	var records []CVERecord
+	return db.Unscoped().Select("id", "data").Where("cvss_version IS NULL").FindInBatches(&records, 500, func(_ *gorm.DB, batch int) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, r := range records {
+				var item cve.CVEItem
+				var summary cve.CVSSSummary
+				if err := jsonutil.Unmarshal([]byte(r.Data), &item); err == nil {
+					summary = cve.DeriveCVSS(&item)
+				}
+				err := tx.Model(&CVERecord{}).Unscoped().Where("id = ?", r.ID).UpdateColumns(map[string]interface{}{
+					"cvss_version":         summary.CVSSVersion,
+					"base_score":           summary.BaseScore,
+					"base_severity":        summary.BaseSeverity,
+					"attack_vector":        summary.AttackVector,
+					"exploitability_score": summary.ExploitabilityScore,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}).Error
 }
 
 // SaveCVE saves a CVE item to the database
 func (d *DB) SaveCVE(cveItem *cve.CVEItem) error {
+	// Re-derive on every save so a CVE that NVD later rejects or disputes
+	// replaces its stale status instead of keeping it
+	cveItem.Status = cve.DeriveStatus(cveItem)
+	cveItem.CVSSSummary = cve.DeriveCVSS(cveItem)
+	stripReferenceHealth(cveItem)
+	stripReferenceCategories(cveItem)
+
 	// Marshal the full CVE data to JSON
-	data, err := jsonutil.Marshal(cveItem)
+	data, err := marshalCVEData(cveItem)
 	if err != nil {
 		return err
 	}
@@ -164,62 +330,131 @@ func (d *DB) SaveCVE(cveItem *cve.CVEItem) error {
 		Published:    cveItem.Published.Time,
 		LastModified: cveItem.LastModified.Time,
 		VulnStatus:   cveItem.VulnStatus,
+		Status:       cveItem.Status,
 		Data:         string(data),
-	}
-
-	// Check if record exists
-	var existing CVERecord
-	result := d.db.Unscoped().Where("cve_id = ?", cveItem.ID).First(&existing)
 
-	switch {
-	case result.Error == nil:
-		// Record exists, update it
-		record.ID = existing.ID
-		record.CreatedAt = existing.CreatedAt
-		record.DeletedAt = gorm.DeletedAt{} // Clear soft delete flag
-		return d.db.Unscoped().Save(&record).Error
-	case result.Error == gorm.ErrRecordNotFound:
-		// Record doesn't exist, create it
-		return d.db.Create(&record).Error
-	default:
-		return result.Error
+		CVSSVersion:         cveItem.CVSSVersion,
+		BaseScore:           cveItem.BaseScore,
+		BaseSeverity:        cveItem.BaseSeverity,
+		AttackVector:        cveItem.AttackVector,
+		ExploitabilityScore: cveItem.ExploitabilityScore,
 	}
+	record.setEPSS(cveItem)
+
+	links := linksOf(cveItem)
+
+	// The CVE row and its cve_cwe, cve_cpes and cve_references rows are
+	// committed together, so a failure between them leaves none behind
+	return dbretry.Do(func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			// Check if record exists
+			var existing CVERecord
+			result := tx.Unscoped().Where("cve_id = ?", cveItem.ID).First(&existing)
+
+			var err error
+			switch {
+			case result.Error == nil:
+				// Record exists, update it
+				record.ID = existing.ID
+				record.CreatedAt = existing.CreatedAt
+				record.DeletedAt = gorm.DeletedAt{} // Clear soft delete flag
+				if record.EPSSScore == nil {
+					record.EPSSScore, record.EPSSPercentile, record.EPSSDate = existing.EPSSScore, existing.EPSSPercentile, existing.EPSSDate
+				}
+				err = tx.Unscoped().Save(&record).Error
+			case result.Error == gorm.ErrRecordNotFound:
+				// Record doesn't exist, create it
+				record.ID = 0
+				err = tx.Create(&record).Error
+			default:
+				err = result.Error
+			}
+			if err != nil {
+				return err
+			}
+			return replaceLinks(tx, []string{cveItem.ID}, links)
+		})
+	})
 }
 
 // BulkInsertRecords efficiently inserts multiple records in a single transaction
 func (d *DB) BulkInsert(records []CVERecord, batchSize int) error {
-	tx := d.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	return dbretry.Do(func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			for i := 0; i < len(records); i += batchSize {
+				end := min(i+batchSize, len(records))
+				if err := tx.Create(records[i:end]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
 
-	for i := 0; i < len(records); i += batchSize {
-		end := min(i+batchSize, len(records))
-		if err := tx.Create(records[i:end]).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-	return tx.Commit().Error
+// cveUpsert updates existing CVEs in place and restores soft-deleted ones.
+// The EPSS columns are only replaced by a new score.
+var cveUpsert = clause.OnConflict{
+	Columns: []clause.Column{{Name: "cve_id"}},
+	DoUpdates: append(clause.AssignmentColumns([]string{
+		"updated_at", "deleted_at", "source_id", "published",
+		"last_modified", "vuln_status", "status", "data", "cvss_version",
+		"base_score", "base_severity", "attack_vector", "exploitability_score",
+	}), clause.Assignments(map[string]interface{}{
+		"epss_score":      gorm.Expr("CASE WHEN excluded.epss_score IS NULL THEN cve_records.epss_score ELSE excluded.epss_score END"),
+		"epss_percentile": gorm.Expr("CASE WHEN excluded.epss_score IS NULL THEN cve_records.epss_percentile ELSE excluded.epss_percentile END"),
+		"epss_date":       gorm.Expr("CASE WHEN excluded.epss_score IS NULL THEN cve_records.epss_date ELSE excluded.epss_date END"),
+	})...),
 }
 
-// SaveCVEs saves multiple CVE items to the database using batch insert for better performance
-func (d *DB) SaveCVEs(cves []cve.CVEItem) error {
-	if len(cves) == 0 {
-		return nil
+// cveLinks are the join rows derived from the data of CVEs
+type cveLinks struct {
+	cwes []CVECWERecord
+	cpes []CVECPERecord
+	refs []CVEReferenceRecord
+}
+
+// linksOf returns the join rows of a CVE
+func linksOf(cveItem *cve.CVEItem) cveLinks {
+	return cveLinks{cwes: cweLinksOf(cveItem), cpes: cpeLinksOf(cveItem), refs: referenceLinksOf(cveItem)}
+}
+
+// add appends the rows of other
+func (l *cveLinks) add(other cveLinks) {
+	l.cwes = append(l.cwes, other.cwes...)
+	l.cpes = append(l.cpes, other.cpes...)
+	l.refs = append(l.refs, other.refs...)
+}
+
+// replaceLinks replaces the join rows of the given CVEs with links. It must
+// run inside the transaction that writes the CVE rows.
+func replaceLinks(tx *gorm.DB, cveIDs []string, links cveLinks) error {
+	if err := replaceCWELinks(tx, cveIDs, links.cwes); err != nil {
+		return err
 	}
+	if err := replaceCPELinks(tx, cveIDs, links.cpes); err != nil {
+		return err
+	}
+	return replaceReferenceLinks(tx, cveIDs, links.refs)
+}
 
+// cveRecordsOf derives the status of each CVE item and returns the records
+// and join rows to store for them
+func cveRecordsOf(cves []cve.CVEItem) ([]CVERecord, []cveLinks, error) {
 	// Pre-allocate records slice with exact capacity
 	records := make([]CVERecord, len(cves))
+	links := make([]cveLinks, len(cves))
 
 	for i := range cves {
+		cves[i].Status = cve.DeriveStatus(&cves[i])
+		cves[i].CVSSSummary = cve.DeriveCVSS(&cves[i])
+		stripReferenceHealth(&cves[i])
+		stripReferenceCategories(&cves[i])
+
 		// Marshal the full CVE data to JSON
-		// Use value type instead of pointer to avoid unnecessary allocation
-		data, err := jsonutil.Marshal(cves[i])
+		data, err := marshalCVEData(&cves[i])
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		// Direct assignment instead of append since we pre-allocated
@@ -229,13 +464,120 @@ func (d *DB) SaveCVEs(cves []cve.CVEItem) error {
 			Published:    cves[i].Published.Time,
 			LastModified: cves[i].LastModified.Time,
 			VulnStatus:   cves[i].VulnStatus,
+			Status:       cves[i].Status,
 			Data:         string(data),
+
+			CVSSVersion:         cves[i].CVSSVersion,
+			BaseScore:           cves[i].BaseScore,
+			BaseSeverity:        cves[i].BaseSeverity,
+			AttackVector:        cves[i].AttackVector,
+			ExploitabilityScore: cves[i].ExploitabilityScore,
 		}
+		records[i].setEPSS(&cves[i])
+		links[i] = linksOf(&cves[i])
+	}
+	return records, links, nil
+}
+
+// SaveCVEs upserts multiple CVE items using batched inserts, committing
+// in sub-transactions of the configured commit size (see SetCommitSize).
+// If a sub-transaction fails, the ones before it stay committed.
+func (d *DB) SaveCVEs(cves []cve.CVEItem) error {
+	if len(cves) == 0 {
+		return nil
 	}
 
-	// Use CreateInBatches for better performance
-	// Process 100 records at a time to balance memory and performance
-	return d.db.CreateInBatches(records, 100).Error
+	records, links, err := cveRecordsOf(cves)
+	if err != nil {
+		return err
+	}
+
+	commitSize := d.commitSize
+	if commitSize <= 0 || commitSize > len(records) {
+		commitSize = len(records)
+	}
+
+	for start := 0; start < len(records); start += commitSize {
+		end := min(start+commitSize, len(records))
+		ids := make([]string, 0, end-start)
+		var batchLinks cveLinks
+		for i := start; i < end; i++ {
+			ids = append(ids, records[i].CVEID)
+			batchLinks.add(links[i])
+		}
+		err := dbretry.Do(func() error {
+			return d.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Clauses(cveUpsert).CreateInBatches(records[start:end], insertStatementSize).Error; err != nil {
+					return err
+				}
+				return replaceLinks(tx, ids, batchLinks)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to commit CVEs %d-%d of %d: %w", start+1, end, len(records), err)
+		}
+	}
+	return nil
+}
+
+// SaveCVEsBatch upserts CVE items in a single transaction, so either all of
+// them are stored or none, and reports how many were new and how many
+// replaced a stored (or soft-deleted) CVE. A CVE given more than once is
+// stored once, from its last occurrence. Unlike SaveCVEs it ignores the
+// commit size.
+func (d *DB) SaveCVEsBatch(cves []cve.CVEItem) (inserted, updated int, err error) {
+	// Keep the last occurrence of each CVE, in first-seen order
+	unique := make([]cve.CVEItem, 0, len(cves))
+	index := make(map[string]int, len(cves))
+	for _, item := range cves {
+		if i, ok := index[item.ID]; ok {
+			unique[i] = item
+			continue
+		}
+		index[item.ID] = len(unique)
+		unique = append(unique, item)
+	}
+	if len(unique) == 0 {
+		return 0, 0, nil
+	}
+
+	records, links, err := cveRecordsOf(unique)
+	if err != nil {
+		return 0, 0, err
+	}
+	ids := make([]string, len(records))
+	var allLinks cveLinks
+	for i := range records {
+		ids[i] = records[i].CVEID
+		allLinks.add(links[i])
+	}
+
+	err = dbretry.Do(func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			var existing int64
+			for start := 0; start < len(ids); start += insertStatementSize {
+				var n int64
+				end := min(start+insertStatementSize, len(ids))
+				if err := tx.Unscoped().Model(&CVERecord{}).Where("cve_id IN ?", ids[start:end]).Count(&n).Error; err != nil {
+					return err
+				}
+				existing += n
+			}
+			if err := tx.Clauses(cveUpsert).CreateInBatches(records, insertStatementSize).Error; err != nil {
+				return err
+			}
+			if err := replaceLinks(tx, ids, allLinks); err != nil {
+				return err
+			}
+			updated = int(existing)
+			inserted = len(records) - updated
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return inserted, updated, nil
 }
 
 // GetCVE retrieves a CVE by ID from the database
@@ -245,31 +587,35 @@ func (d *DB) GetCVE(cveID string) (*cve.CVEItem, error) {
 		return nil, err
 	}
 
-	var cveItem cve.CVEItem
-	if err := jsonutil.Unmarshal([]byte(record.Data), &cveItem); err != nil {
+	cves := make([]cve.CVEItem, 1)
+	if err := jsonutil.Unmarshal([]byte(record.Data), &cves[0]); err != nil {
+		return nil, err
+	}
+	record.applyDerived(&cves[0])
+	if err := d.attachKEV(cves); err != nil {
+		return nil, err
+	}
+	if err := d.attachReferenceCategories(cves); err != nil {
 		return nil, err
 	}
 
-	return &cveItem, nil
+	return &cves[0], nil
 }
 
 // ListCVEs retrieves CVEs with pagination
 func (d *DB) ListCVEs(offset, limit int) ([]cve.CVEItem, error) {
+	return d.ListCVEsFiltered(offset, limit, nil)
+}
+
+// ListCVEsFiltered retrieves CVEs with pagination, skipping any whose derived
+// status is listed in excludeStatuses
+func (d *DB) ListCVEsFiltered(offset, limit int, excludeStatuses []string) ([]cve.CVEItem, error) {
 	var records []CVERecord
 
-	// Retry logic for database locking issues
-	var err error
-	for attempt := 0; attempt < 3; attempt++ {
-		err = d.db.Offset(offset).Limit(limit).Order("published desc").Find(&records).Error
-		if err == nil {
-			break
-		}
-		// If it's a database lock error, wait and retry
-		if strings.Contains(err.Error(), "database is locked") && attempt < 2 {
-			time.Sleep(time.Millisecond * time.Duration(10*(attempt+1))) // Exponential backoff
-			continue
-		}
-		// For other errors or final attempt, return immediately
+	err := dbretry.Do(func() error {
+		return d.listScope(offset, limit, excludeStatuses).Find(&records).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -279,31 +625,104 @@ func (d *DB) ListCVEs(offset, limit int) ([]cve.CVEItem, error) {
 		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
 			return nil, err
 		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, err
 	}
 
 	return cves, nil
 }
 
-// Count returns the total number of CVEs in the database
-func (d *DB) Count() (int64, error) {
-	var count int64
+// ListCVEsAfter retrieves up to limit CVEs stored after the cursor, in
+// storage order, skipping any whose derived status is listed in
+// excludeStatuses. It returns the cursor to continue from, or "" once there
+// are no more CVEs. An empty cursor starts from the first CVE. Unlike offset
+// paging, CVEs stored while iterating are neither repeated nor skipped: a
+// new CVE comes after every stored one, and an updated CVE keeps its place.
+func (d *DB) ListCVEsAfter(cursor string, limit int, excludeStatuses []string) ([]cve.CVEItem, string, error) {
+	afterID, err := decodeCVECursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var records []CVERecord
+	err = dbretry.Do(func() error {
+		return d.statusScope(excludeStatuses).Where("id > ?", afterID).Order("id").Limit(limit).Find(&records).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
 
-	// Retry logic for database locking issues
-	var err error
-	for attempt := 0; attempt < 3; attempt++ {
-		err = d.db.Model(&CVERecord{}).Count(&count).Error
-		if err == nil {
-			return count, nil
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, "", err
 		}
-		// If it's a database lock error, wait and retry
-		if strings.Contains(err.Error(), "database is locked") && attempt < 2 {
-			time.Sleep(time.Millisecond * time.Duration(10*(attempt+1))) // Exponential backoff
-			continue
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(records) > 0 && len(records) == limit {
+		next = encodeCVECursor(records[len(records)-1].ID)
+	}
+	return cves, next, nil
+}
+
+// cveCursorPrefix marks the cursors of ListCVEsAfter
+const cveCursorPrefix = "cve:"
+
+// encodeCVECursor returns the opaque cursor of the CVE record with id
+func encodeCVECursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cveCursorPrefix + strconv.FormatUint(uint64(id), 10)))
+}
+
+// decodeCVECursor returns the CVE record ID of a cursor; 0 for ""
+func decodeCVECursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), cveCursorPrefix) {
+		if id, perr := strconv.ParseUint(strings.TrimPrefix(string(raw), cveCursorPrefix), 10, 64); perr == nil {
+			return uint(id), nil
 		}
-		// For other errors or final attempt, return immediately
-		return 0, err
 	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
+
+// listScope returns the page query of ListCVEsFiltered
+func (d *DB) listScope(offset, limit int, excludeStatuses []string) *gorm.DB {
+	return d.statusScope(excludeStatuses).Offset(offset).Limit(limit).Order("published desc")
+}
+
+// statusScope returns a query on CVE records excluding the given statuses
+func (d *DB) statusScope(excludeStatuses []string) *gorm.DB {
+	query := d.db.Model(&CVERecord{})
+	if len(excludeStatuses) > 0 {
+		query = query.Where("status NOT IN ?", excludeStatuses)
+	}
+	return query
+}
+
+// Count returns the total number of CVEs in the database
+func (d *DB) Count() (int64, error) {
+	return d.CountFiltered(nil)
+}
 
+// CountFiltered returns the number of CVEs whose derived status is not listed
+// in excludeStatuses
+func (d *DB) CountFiltered(excludeStatuses []string) (int64, error) {
+	var count int64
+	err := dbretry.Do(func() error {
+		return d.statusScope(excludeStatuses).Count(&count).Error
+	})
+	if err != nil {
+		return 0, err
+	}
 	return count, nil
 }
 
@@ -348,9 +767,24 @@ func (d *DB) GetCVERaw(cveID string) (*CVERecord, error) {
 
 // DeleteCVE deletes a CVE from the database by ID
 func (d *DB) DeleteCVE(cveID string) error {
-	result := d.db.Where("cve_id = ?", cveID).Delete(&CVERecord{})
-	if result.Error != nil {
-		return result.Error
+	var result *gorm.DB
+	err := dbretry.Do(func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			result = tx.Where("cve_id = ?", cveID).Delete(&CVERecord{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if err := tx.Where("cve_id = ?", cveID).Delete(&CVECWERecord{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("cve_id = ?", cveID).Delete(&CVECPERecord{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("cve_id = ?", cveID).Delete(&CVEReferenceRecord{}).Error
+		})
+	})
+	if err != nil {
+		return err
 	}
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
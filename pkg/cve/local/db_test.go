@@ -3,7 +3,10 @@ package local
 import (
 "gorm.io/gorm"
 "github.com/cyw0ng95/v2e/pkg/testutils"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -578,3 +581,362 @@ func TestDeleteCVE_NotFound(t *testing.T) {
 	})
 
 }
+
+func TestListCVEsFiltered_ExcludesRejected(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestListCVEsFiltered_ExcludesRejected", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_status_filter_cve.db"
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		items := []*cve.CVEItem{
+			{ID: "CVE-2024-0001", VulnStatus: "Analyzed", Published: cve.NewNVDTime(time.Now())},
+			{ID: "CVE-2024-0002", VulnStatus: "Rejected", Published: cve.NewNVDTime(time.Now())},
+			{ID: "CVE-2024-0003", VulnStatus: "Modified", Published: cve.NewNVDTime(time.Now()),
+				CVETags: []cve.CVETag{{Tags: []string{"disputed"}}}},
+		}
+		for _, item := range items {
+			if err := db.SaveCVE(item); err != nil {
+				t.Fatalf("Failed to save %s: %v", item.ID, err)
+			}
+		}
+
+		excluded := []string{cve.StatusRejected, cve.StatusDisputed}
+		cves, err := db.ListCVEsFiltered(0, 10, excluded)
+		if err != nil {
+			t.Fatalf("ListCVEsFiltered failed: %v", err)
+		}
+		if len(cves) != 1 || cves[0].ID != "CVE-2024-0001" {
+			t.Errorf("Expected only CVE-2024-0001, got %+v", cves)
+		}
+
+		count, err := db.CountFiltered(excluded)
+		if err != nil {
+			t.Fatalf("CountFiltered failed: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected filtered count 1, got %d", count)
+		}
+
+		all, err := db.ListCVEs(0, 10)
+		if err != nil {
+			t.Fatalf("ListCVEs failed: %v", err)
+		}
+		if len(all) != 3 {
+			t.Errorf("Expected 3 CVEs without filter, got %d", len(all))
+		}
+	})
+}
+
+func TestListCVEsAfter(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestListCVEsAfter", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "cursor.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		for _, id := range []string{"CVE-2024-0005", "CVE-2024-0001", "CVE-2024-0003", "CVE-2024-0002"} {
+			if err := db.SaveCVE(&cve.CVEItem{ID: id, VulnStatus: "Analyzed"}); err != nil {
+				t.Fatalf("Failed to save %s: %v", id, err)
+			}
+		}
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0004", VulnStatus: "Rejected"}); err != nil {
+			t.Fatalf("Failed to save CVE-2024-0004: %v", err)
+		}
+		excluded := []string{cve.StatusRejected, cve.StatusDisputed}
+
+		// CVEs stored during the iteration neither shift nor repeat the pages
+		var seen []string
+		cursor := ""
+		for page := 0; ; page++ {
+			cves, next, err := db.ListCVEsAfter(cursor, 2, excluded)
+			if err != nil {
+				t.Fatalf("ListCVEsAfter failed: %v", err)
+			}
+			for _, item := range cves {
+				seen = append(seen, item.ID)
+			}
+			if page == 0 {
+				if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0000", VulnStatus: "Analyzed"}); err != nil {
+					t.Fatalf("Failed to save CVE-2024-0000: %v", err)
+				}
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		want := "CVE-2024-0005,CVE-2024-0001,CVE-2024-0003,CVE-2024-0002,CVE-2024-0000"
+		if got := strings.Join(seen, ","); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+
+		if _, _, err := db.ListCVEsAfter("not-a-cursor", 2, nil); err == nil {
+			t.Error("Expected an error for an invalid cursor")
+		}
+	})
+}
+
+func TestSaveCVE_StatusTransitionsOnRefetch(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVE_StatusTransitionsOnRefetch", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_status_transition_cve.db"
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		item := &cve.CVEItem{ID: "CVE-2024-0004", VulnStatus: "Analyzed"}
+		if err := db.SaveCVE(item); err != nil {
+			t.Fatalf("Failed to save CVE: %v", err)
+		}
+
+		// Re-fetch reports the CVE as rejected
+		item = &cve.CVEItem{ID: "CVE-2024-0004", VulnStatus: "Rejected"}
+		if err := db.SaveCVE(item); err != nil {
+			t.Fatalf("Failed to re-save CVE: %v", err)
+		}
+
+		got, err := db.GetCVE("CVE-2024-0004")
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		if got.Status != cve.StatusRejected {
+			t.Errorf("Expected status %q after re-fetch, got %q", cve.StatusRejected, got.Status)
+		}
+	})
+}
+
+func TestSaveCVE_CVSSColumns(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVE_CVSSColumns", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_cvss_columns_cve.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+
+		scored := &cve.CVEItem{
+			ID:        "CVE-2024-0005",
+			Published: cve.NewNVDTime(time.Now()),
+			Metrics: &cve.Metrics{
+				CvssMetricV31: []cve.CVSSMetricV3{{
+					Type:                "Primary",
+					CvssData:            cve.CVSSDataV3{Version: "3.1", VectorString: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", BaseScore: 9.8, BaseSeverity: "CRITICAL"},
+					ExploitabilityScore: 3.9,
+				}},
+				CvssMetricV2: []cve.CVSSMetricV2{{
+					CvssData: cve.CVSSDataV2{Version: "2.0", VectorString: "AV:L/AC:L/Au:N/C:P/I:P/A:P", BaseScore: 4.6},
+				}},
+			},
+		}
+		if err := db.SaveCVE(scored); err != nil {
+			t.Fatalf("Failed to save CVE: %v", err)
+		}
+		if _, _, err := db.SaveCVEsBatch([]cve.CVEItem{{ID: "CVE-2024-0006"}}); err != nil {
+			t.Fatalf("Failed to save CVE batch: %v", err)
+		}
+
+		want := cve.CVSSSummary{CVSSVersion: "3.1", BaseScore: 9.8, BaseSeverity: "CRITICAL", AttackVector: cve.AttackVectorNetwork, ExploitabilityScore: 3.9}
+		raw, err := db.GetCVERaw("CVE-2024-0005")
+		if err != nil {
+			t.Fatalf("GetCVERaw failed: %v", err)
+		}
+		if raw.BaseScore != 9.8 || raw.BaseSeverity != "CRITICAL" || raw.AttackVector != cve.AttackVectorNetwork {
+			t.Errorf("Expected the v3.1 metric in the columns, got %+v", raw)
+		}
+		list, err := db.ListCVEs(0, 10)
+		if err != nil {
+			t.Fatalf("ListCVEs failed: %v", err)
+		}
+		got := map[string]cve.CVSSSummary{}
+		for _, item := range list {
+			got[item.ID] = item.CVSSSummary
+		}
+		if got["CVE-2024-0005"] != want || got["CVE-2024-0006"] != (cve.CVSSSummary{}) {
+			t.Errorf("Unexpected listed CVSS summaries: %+v", got)
+		}
+
+		// Rows stored before the columns existed are derived on open
+		if err := db.GormDB().Exec("UPDATE cve_records SET cvss_version = NULL, base_score = NULL, base_severity = NULL, attack_vector = NULL, exploitability_score = NULL").Error; err != nil {
+			t.Fatalf("Failed to clear CVSS columns: %v", err)
+		}
+		db.Close()
+		db, err = NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		item, err := db.GetCVE("CVE-2024-0005")
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		if item.CVSSSummary != want {
+			t.Errorf("Expected backfilled %+v, got %+v", want, item.CVSSSummary)
+		}
+		var pending int64
+		db.GormDB().Model(&CVERecord{}).Where("cvss_version IS NULL").Count(&pending)
+		if pending != 0 {
+			t.Errorf("Expected every row backfilled, %d left", pending)
+		}
+	})
+}
+
+func TestSaveCVE_EPSSColumns(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVE_EPSSColumns", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_epss_columns_cve.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		epss := &cve.EPSS{Score: 0.912, Percentile: 0.998, Date: "2024-03-01"}
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0007", EPSS: epss}); err != nil {
+			t.Fatalf("Failed to save CVE: %v", err)
+		}
+		if _, _, err := db.SaveCVEsBatch([]cve.CVEItem{{ID: "CVE-2024-0008"}}); err != nil {
+			t.Fatalf("Failed to save CVE batch: %v", err)
+		}
+
+		raw, err := db.GetCVERaw("CVE-2024-0007")
+		if err != nil {
+			t.Fatalf("GetCVERaw failed: %v", err)
+		}
+		if raw.EPSSScore == nil || *raw.EPSSScore != 0.912 || strings.Contains(raw.Data, "epss") {
+			t.Errorf("Expected the score in its columns only, got %+v", raw)
+		}
+		if item, _ := db.GetCVE("CVE-2024-0008"); item == nil || item.EPSS != nil {
+			t.Errorf("Expected no EPSS for an unscored CVE, got %+v", item)
+		}
+
+		// Refreshes without a score, one by one or in batches, keep it
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0007", VulnStatus: "Modified"}); err != nil {
+			t.Fatalf("Failed to resave CVE: %v", err)
+		}
+		if err := db.SaveCVEs([]cve.CVEItem{{ID: "CVE-2024-0007", VulnStatus: "Analyzed"}}); err != nil {
+			t.Fatalf("Failed to resave CVEs: %v", err)
+		}
+		item, err := db.GetCVE("CVE-2024-0007")
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		if item.EPSS == nil || *item.EPSS != *epss || item.VulnStatus != "Analyzed" {
+			t.Errorf("Expected the EPSS kept across refreshes, got %+v", item.EPSS)
+		}
+
+		// A new score replaces it
+		newer := cve.EPSS{Score: 0.5, Percentile: 0.9, Date: "2024-04-01"}
+		if err := db.SaveCVEs([]cve.CVEItem{{ID: "CVE-2024-0007", EPSS: &newer}}); err != nil {
+			t.Fatalf("Failed to resave CVEs: %v", err)
+		}
+		if item, _ := db.GetCVE("CVE-2024-0007"); item == nil || item.EPSS == nil || *item.EPSS != newer {
+			t.Errorf("Expected the newer EPSS, got %+v", item)
+		}
+	})
+}
+
+func TestSaveCVEs_CommitSizeUpsert(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVEs_CommitSizeUpsert", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_commit_size_cve.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+		db.SetCommitSize(3)
+
+		cves := make([]cve.CVEItem, 10)
+		for i := range cves {
+			cves[i] = cve.CVEItem{ID: fmt.Sprintf("CVE-2024-%04d", i), VulnStatus: "Analyzed"}
+		}
+		if err := db.SaveCVEs(cves); err != nil {
+			t.Fatalf("SaveCVEs failed: %v", err)
+		}
+		if count, _ := db.Count(); count != 10 {
+			t.Fatalf("Expected 10 CVEs, got %d", count)
+		}
+
+		// Saving again updates in place instead of failing on the unique index
+		cves[4].VulnStatus = "Rejected"
+		if err := db.DeleteCVE(cves[7].ID); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if err := db.SaveCVEs(cves); err != nil {
+			t.Fatalf("Second SaveCVEs failed: %v", err)
+		}
+		if count, _ := db.Count(); count != 10 {
+			t.Errorf("Expected 10 CVEs after upsert, got %d", count)
+		}
+		got, err := db.GetCVE(cves[4].ID)
+		if err != nil || got.Status != cve.StatusRejected {
+			t.Errorf("Expected %s to be updated to rejected, got %+v, %v", cves[4].ID, got, err)
+		}
+		if _, err := db.GetCVE(cves[7].ID); err != nil {
+			t.Errorf("Expected soft-deleted %s to be restored: %v", cves[7].ID, err)
+		}
+	})
+}
+
+func TestSaveCVEsBatch(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSaveCVEsBatch", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_save_cves_batch.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		cves := make([]cve.CVEItem, 150)
+		for i := range cves {
+			cves[i] = cve.CVEItem{ID: fmt.Sprintf("CVE-2024-%04d", i), VulnStatus: "Analyzed"}
+		}
+		inserted, updated, err := db.SaveCVEsBatch(cves[:120])
+		if err != nil || inserted != 120 || updated != 0 {
+			t.Fatalf("Expected 120 inserted, got %d inserted, %d updated, %v", inserted, updated, err)
+		}
+
+		// Stored and soft-deleted CVEs count as updated; a repeated CVE once
+		if err := db.DeleteCVE(cves[7].ID); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		cves[4].VulnStatus = "Rejected"
+		batch := append(cves[100:], cves[4], cves[7], cves[4])
+		inserted, updated, err = db.SaveCVEsBatch(batch)
+		if err != nil || inserted != 30 || updated != 22 {
+			t.Fatalf("Expected 30 inserted and 22 updated, got %d, %d, %v", inserted, updated, err)
+		}
+		if count, _ := db.Count(); count != 150 {
+			t.Errorf("Expected 150 CVEs, got %d", count)
+		}
+		got, err := db.GetCVE(cves[4].ID)
+		if err != nil || got.Status != cve.StatusRejected {
+			t.Errorf("Expected %s to be updated to rejected, got %+v, %v", cves[4].ID, got, err)
+		}
+		if _, err := db.GetCVE(cves[7].ID); err != nil {
+			t.Errorf("Expected soft-deleted %s to be restored: %v", cves[7].ID, err)
+		}
+
+		if inserted, updated, err := db.SaveCVEsBatch(nil); inserted != 0 || updated != 0 || err != nil {
+			t.Errorf("Expected an empty batch to store nothing, got %d, %d, %v", inserted, updated, err)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package local
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// QueryPlan is SQLite's plan for one statement of a query, as reported by
+// EXPLAIN QUERY PLAN. It is a diagnostic for checking that filters and
+// orderings are served by indexes; it reveals the schema, so it must only be
+// exposed to trusted callers.
+type QueryPlan struct {
+	Name string `json:"name"`
+	// SQL is the generated statement with its parameters inlined
+	SQL   string          `json:"sql"`
+	Steps []QueryPlanStep `json:"steps"`
+	// Text renders the steps as a tree, like the sqlite3 shell does
+	Text string `json:"text"`
+}
+
+// QueryPlanStep is one row of EXPLAIN QUERY PLAN
+type QueryPlanStep struct {
+	ID     int    `json:"id"`
+	Parent int    `json:"parent"`
+	Detail string `json:"detail"`
+}
+
+// ExplainListCVEs returns the plans of the statements ListCVEsFiltered and
+// CountFiltered run for the same arguments
+func (d *DB) ExplainListCVEs(offset, limit int, excludeStatuses []string) ([]QueryPlan, error) {
+	var records []CVERecord
+	var total int64
+	return d.explainListAndCount(
+		d.dryRun(d.listScope(offset, limit, excludeStatuses)).Find(&records).Statement,
+		d.dryRun(d.statusScope(excludeStatuses)).Count(&total).Statement,
+	)
+}
+
+// ExplainListKEVCVEs returns the plans of the statements ListKEVCVEs runs for
+// the same arguments
+func (d *DB) ExplainListKEVCVEs(offset, limit int, excludeStatuses []string) ([]QueryPlan, error) {
+	var records []CVERecord
+	var total int64
+	return d.explainListAndCount(
+		d.dryRun(d.kevListScope(offset, limit, excludeStatuses)).Find(&records).Statement,
+		d.dryRun(d.kevScope(excludeStatuses)).Count(&total).Statement,
+	)
+}
+
+// ExplainSearchCVEs returns the plans of the statements SearchCVEs runs for
+// the same arguments
+func (d *DB) ExplainSearchCVEs(keyword, severity string, offset, limit int, excludeStatuses []string) ([]QueryPlan, error) {
+	if severity != "" && !cvssSeverities[strings.ToUpper(severity)] {
+		return nil, fmt.Errorf("invalid severity %q: must be CRITICAL, HIGH, MEDIUM, LOW or NONE", severity)
+	}
+	var records []CVERecord
+	var total int64
+	return d.explainListAndCount(
+		d.dryRun(d.searchListScope(keyword, severity, offset, limit, excludeStatuses)).Find(&records).Statement,
+		d.dryRun(d.searchScope(keyword, severity, excludeStatuses)).Count(&total).Statement,
+	)
+}
+
+// explainListAndCount returns the plans of a page query and its count query
+func (d *DB) explainListAndCount(list, count *gorm.Statement) ([]QueryPlan, error) {
+	plans := make([]QueryPlan, 0, 2)
+	for _, q := range []struct {
+		name string
+		stmt *gorm.Statement
+	}{{"list", list}, {"count", count}} {
+		plan, err := d.explain(q.name, q.stmt)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// dryRun makes query generate its SQL without executing it
+func (d *DB) dryRun(query *gorm.DB) *gorm.DB {
+	return query.Session(&gorm.Session{DryRun: true})
+}
+
+// explain runs EXPLAIN QUERY PLAN for a statement generated by a dry run
+func (d *DB) explain(name string, stmt *gorm.Statement) (QueryPlan, error) {
+	if stmt.Error != nil {
+		return QueryPlan{}, fmt.Errorf("failed to generate %s query: %w", name, stmt.Error)
+	}
+	sql := stmt.SQL.String()
+	plan := QueryPlan{
+		Name:  name,
+		SQL:   d.db.Dialector.Explain(sql, stmt.Vars...),
+		Steps: []QueryPlanStep{},
+	}
+
+	rows, err := d.db.Raw("EXPLAIN QUERY PLAN "+sql, stmt.Vars...).Rows()
+	if err != nil {
+		return QueryPlan{}, fmt.Errorf("failed to explain %s query: %w", name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var step QueryPlanStep
+		var notUsed int
+		if err := rows.Scan(&step.ID, &step.Parent, &notUsed, &step.Detail); err != nil {
+			return QueryPlan{}, fmt.Errorf("failed to read %s query plan: %w", name, err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return QueryPlan{}, fmt.Errorf("failed to read %s query plan: %w", name, err)
+	}
+	plan.Text = renderQueryPlan(plan.Steps)
+	return plan, nil
+}
+
+// renderQueryPlan indents each step under its parent. Steps arrive parents
+// first, so a parent's depth is known when its children are reached.
+func renderQueryPlan(steps []QueryPlanStep) string {
+	depth := make(map[int]int, len(steps))
+	var b strings.Builder
+	for _, s := range steps {
+		d := 0
+		if pd, ok := depth[s.Parent]; ok {
+			d = pd + 1
+		}
+		depth[s.ID] = d
+		b.WriteString(strings.Repeat("  ", d))
+		b.WriteString(s.Detail)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package local
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestExplainListCVEs(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestExplainListCVEs", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_explain_list_cves.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		plans, err := db.ExplainListCVEs(20, 10, []string{cve.StatusRejected})
+		if err != nil {
+			t.Fatalf("ExplainListCVEs failed: %v", err)
+		}
+		if len(plans) != 2 || plans[0].Name != "list" || plans[1].Name != "count" {
+			t.Fatalf("Expected list and count plans, got %+v", plans)
+		}
+
+		list := plans[0]
+		for _, want := range []string{"cve_records", "rejected", "ORDER BY published desc", "LIMIT 10", "OFFSET 20"} {
+			if !strings.Contains(list.SQL, want) {
+				t.Errorf("Expected list SQL to contain %q, got %s", want, list.SQL)
+			}
+		}
+		if len(list.Steps) == 0 || list.Text == "" {
+			t.Fatalf("Expected a query plan, got %+v", list)
+		}
+		if !strings.Contains(list.Text, "cve_records") {
+			t.Errorf("Expected the plan to access cve_records, got:\n%s", list.Text)
+		}
+		if !strings.Contains(plans[1].SQL, "count(*)") {
+			t.Errorf("Expected a count query, got %s", plans[1].SQL)
+		}
+
+		// A dry run must not have executed anything or altered the DB's session
+		if _, err := db.ListCVEsFiltered(0, 10, nil); err != nil {
+			t.Errorf("ListCVEsFiltered failed after explain: %v", err)
+		}
+	})
+}
+
+func TestExplainFilteredCVELists(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestExplainFilteredCVELists", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_explain_filtered_cves.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		kev, err := db.ExplainListKEVCVEs(0, 10, nil)
+		if err != nil {
+			t.Fatalf("ExplainListKEVCVEs failed: %v", err)
+		}
+		if len(kev) != 2 || !strings.Contains(kev[0].SQL, "JOIN cve_kev") || !strings.Contains(kev[0].SQL, "ORDER BY cve_kev.due_date") {
+			t.Errorf("Expected the KEV join and ordering, got %+v", kev)
+		}
+		if !strings.Contains(kev[1].SQL, "JOIN cve_kev") {
+			t.Errorf("Expected the KEV count to join cve_kev, got %s", kev[1].SQL)
+		}
+
+		search, err := db.ExplainSearchCVEs("", "high", 0, 10, nil)
+		if err != nil {
+			t.Fatalf("ExplainSearchCVEs failed: %v", err)
+		}
+		if !strings.Contains(search[0].SQL, `base_severity = "HIGH"`) {
+			t.Errorf("Expected the severity filter, got %s", search[0].SQL)
+		}
+		if !strings.Contains(search[0].Text, "idx_cve_records_base_severity") {
+			t.Errorf("Expected the severity index in the plan, got:\n%s", search[0].Text)
+		}
+
+		if _, err := db.ExplainSearchCVEs("", "urgent", 0, 10, nil); err == nil {
+			t.Error("Expected an error for an invalid severity")
+		}
+	})
+}
+
+func TestRenderQueryPlan(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRenderQueryPlan", nil, func(t *testing.T, tx *gorm.DB) {
+		got := renderQueryPlan([]QueryPlanStep{
+			{ID: 2, Parent: 0, Detail: "SCAN cve_records"},
+			{ID: 5, Parent: 2, Detail: "LIST SUBQUERY 1"},
+			{ID: 9, Parent: 0, Detail: "USE TEMP B-TREE FOR ORDER BY"},
+		})
+		want := "SCAN cve_records\n  LIST SUBQUERY 1\nUSE TEMP B-TREE FOR ORDER BY\n"
+		if got != want {
+			t.Errorf("renderQueryPlan = %q, want %q", got, want)
+		}
+	})
+}
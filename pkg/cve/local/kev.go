@@ -0,0 +1,190 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CVEKEVRecord is a CVE listed in the CISA Known Exploited Vulnerabilities
+// catalog. The catalog is stored whole, including CVEs that are not stored
+// yet, so a CVE fetched after an import is KEV-listed as soon as it is stored.
+type CVEKEVRecord struct {
+	CVEID                      string `gorm:"primaryKey"`
+	VendorProject              string
+	Product                    string
+	VulnerabilityName          string
+	DateAdded                  string
+	DueDate                    string `gorm:"index"`
+	RequiredAction             string
+	KnownRansomwareCampaignUse string
+	UpdatedAt                  time.Time
+}
+
+// TableName overrides the default table name
+func (CVEKEVRecord) TableName() string {
+	return cveKEVTable
+}
+
+// kev returns the listing of the record as attached to a CVE
+func (r *CVEKEVRecord) kev() *cve.KEV {
+	return &cve.KEV{
+		DateAdded:                  r.DateAdded,
+		DueDate:                    r.DueDate,
+		RequiredAction:             r.RequiredAction,
+		KnownRansomwareCampaignUse: r.KnownRansomwareCampaignUse,
+	}
+}
+
+// ImportKEV stores KEV catalog entries, updating the ones already stored, and
+// returns how many entries were stored and how many of them name a stored
+// CVE. Entries without a CVE ID are skipped. With replace, entries is the
+// whole catalog and stored entries missing from it are removed; otherwise
+// entries are only added or updated, so a catalog can be imported in batches.
+func (d *DB) ImportKEV(entries []cve.KEVEntry, replace bool) (listed, matched int64, err error) {
+	records := make([]CVEKEVRecord, 0, len(entries))
+	ids := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		id := strings.ToUpper(strings.TrimSpace(entry.CVEID))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		records = append(records, CVEKEVRecord{
+			CVEID:                      id,
+			VendorProject:              entry.VendorProject,
+			Product:                    entry.Product,
+			VulnerabilityName:          entry.VulnerabilityName,
+			DateAdded:                  entry.DateAdded,
+			DueDate:                    entry.DueDate,
+			RequiredAction:             entry.RequiredAction,
+			KnownRansomwareCampaignUse: entry.KnownRansomwareCampaignUse,
+		})
+	}
+
+	err = dbretry.Do(func() error {
+		matched = 0
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			if replace {
+				stale := tx.Session(&gorm.Session{AllowGlobalUpdate: true})
+				if len(ids) > 0 {
+					stale = stale.Where("cve_id NOT IN (?)", ids)
+				}
+				if err := stale.Delete(&CVEKEVRecord{}).Error; err != nil {
+					return err
+				}
+			}
+			if len(records) == 0 {
+				return nil
+			}
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(records, insertStatementSize).Error; err != nil {
+				return err
+			}
+			for start := 0; start < len(ids); start += insertStatementSize {
+				end := min(start+insertStatementSize, len(ids))
+				var n int64
+				if err := tx.Model(&CVERecord{}).Where("cve_id IN ?", ids[start:end]).Count(&n).Error; err != nil {
+					return err
+				}
+				matched += n
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(len(records)), matched, nil
+}
+
+// ImportKEVFile imports the CISA KEV catalog JSON file at path as the whole
+// catalog, replacing the stored one
+func (d *DB) ImportKEVFile(path string) (listed, matched int64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var catalog cve.KEVCatalog
+	if err := jsonutil.Unmarshal(data, &catalog); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse KEV catalog: %w", err)
+	}
+	if catalog.Vulnerabilities == nil {
+		return 0, 0, fmt.Errorf("KEV catalog %s has no vulnerabilities", path)
+	}
+	return d.ImportKEV(catalog.Vulnerabilities, true)
+}
+
+// attachKEV sets the KEV listing of CVEs read from the database
+func (d *DB) attachKEV(cves []cve.CVEItem) error {
+	if len(cves) == 0 {
+		return nil
+	}
+	ids := make([]string, len(cves))
+	for i := range cves {
+		ids[i] = cves[i].ID
+	}
+	var records []CVEKEVRecord
+	if err := d.db.Where("cve_id IN ?", ids).Find(&records).Error; err != nil {
+		return err
+	}
+	listed := make(map[string]*CVEKEVRecord, len(records))
+	for i := range records {
+		listed[records[i].CVEID] = &records[i]
+	}
+	for i := range cves {
+		cves[i].KEV = nil
+		if r := listed[cves[i].ID]; r != nil {
+			cves[i].KEV = r.kev()
+		}
+	}
+	return nil
+}
+
+// ListKEVCVEs returns a page of the stored CVEs listed in the KEV catalog,
+// earliest due date first and then newest first, and the number of such
+// CVEs. CVEs whose status is in excludeStatuses are left out.
+func (d *DB) ListKEVCVEs(offset, limit int, excludeStatuses []string) ([]cve.CVEItem, int64, error) {
+	var records []CVERecord
+	var total int64
+	err := dbretry.Do(func() error {
+		if err := d.kevListScope(offset, limit, excludeStatuses).Find(&records).Error; err != nil {
+			return err
+		}
+		return d.kevScope(excludeStatuses).Count(&total).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, 0, err
+		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, 0, err
+	}
+	return cves, total, nil
+}
+
+// kevListScope returns the page query of ListKEVCVEs
+func (d *DB) kevListScope(offset, limit int, excludeStatuses []string) *gorm.DB {
+	return d.kevScope(excludeStatuses).Offset(offset).Limit(limit).Order("cve_kev.due_date, cve_records.published desc")
+}
+
+// kevScope returns a query on the KEV-listed CVE records excluding the given
+// statuses
+func (d *DB) kevScope(excludeStatuses []string) *gorm.DB {
+	return d.statusScope(excludeStatuses).Joins("JOIN cve_kev ON cve_kev.cve_id = cve_records.cve_id")
+}
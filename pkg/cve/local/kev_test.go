@@ -0,0 +1,101 @@
+package local
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestImportKEV(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestImportKEV", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "kev.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		now := time.Now()
+		for _, item := range []*cve.CVEItem{
+			{ID: "CVE-2024-0001", VulnStatus: "Analyzed", Published: cve.NewNVDTime(now.Add(-2 * time.Hour))},
+			{ID: "CVE-2024-0002", VulnStatus: "Analyzed", Published: cve.NewNVDTime(now.Add(-time.Hour))},
+			{ID: "CVE-2024-0003", VulnStatus: "Analyzed", Published: cve.NewNVDTime(now)},
+			{ID: "CVE-2024-0004", VulnStatus: "Rejected", Published: cve.NewNVDTime(now)},
+		} {
+			if err := db.SaveCVE(item); err != nil {
+				t.Fatalf("Failed to save %s: %v", item.ID, err)
+			}
+		}
+
+		entries := []cve.KEVEntry{
+			{CVEID: "CVE-2024-0001", DateAdded: "2024-03-01", DueDate: "2024-03-22", RequiredAction: "Apply updates.", KnownRansomwareCampaignUse: "Known"},
+			{CVEID: "cve-2024-0002", DateAdded: "2024-02-01", DueDate: "2024-02-22"},
+			{CVEID: "CVE-2024-0004", DateAdded: "2024-02-01", DueDate: "2024-02-01"},
+			{CVEID: "CVE-2024-0009", DateAdded: "2024-03-01", DueDate: "2024-03-22"},
+			{CVEID: ""},
+		}
+		listed, matched, err := db.ImportKEV(entries, false)
+		if err != nil {
+			t.Fatalf("ImportKEV failed: %v", err)
+		}
+		if listed != 4 || matched != 3 {
+			t.Errorf("Expected 4 listed and 3 matched, got %d and %d", listed, matched)
+		}
+
+		item, err := db.GetCVE("CVE-2024-0001")
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		want := cve.KEV{DateAdded: "2024-03-01", DueDate: "2024-03-22", RequiredAction: "Apply updates.", KnownRansomwareCampaignUse: "Known"}
+		if item.KEV == nil || *item.KEV != want {
+			t.Errorf("Expected KEV %+v, got %+v", want, item.KEV)
+		}
+		if item, _ := db.GetCVE("CVE-2024-0003"); item == nil || item.KEV != nil {
+			t.Errorf("Expected CVE-2024-0003 not to be KEV-listed, got %+v", item)
+		}
+
+		// Listed CVEs come earliest due date first, without the rejected one
+		cves, total, err := db.ListKEVCVEs(0, 10, []string{cve.StatusRejected, cve.StatusDisputed})
+		if err != nil {
+			t.Fatalf("ListKEVCVEs failed: %v", err)
+		}
+		if total != 2 || len(cves) != 2 || cves[0].ID != "CVE-2024-0002" || cves[1].ID != "CVE-2024-0001" || cves[0].KEV == nil {
+			t.Errorf("Expected CVE-2024-0002 then CVE-2024-0001, got %+v (total %d)", cves, total)
+		}
+		if _, total, _ := db.ListKEVCVEs(0, 10, nil); total != 3 {
+			t.Errorf("Expected 3 listed CVEs with rejected ones, got %d", total)
+		}
+
+		// An NVD refresh keeps the listing, which is not stored in the data
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0001", VulnStatus: "Modified"}); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		raw, err := db.GetCVERaw("CVE-2024-0001")
+		if err != nil || strings.Contains(raw.Data, "due_date") {
+			t.Errorf("Expected no KEV in the stored data, got %+v (%v)", raw, err)
+		}
+		if item, _ := db.GetCVE("CVE-2024-0001"); item == nil || item.KEV == nil || item.VulnStatus != "Modified" {
+			t.Errorf("Expected the refreshed CVE to stay KEV-listed, got %+v", item)
+		}
+
+		// A CVE stored after the import is listed
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-0009", VulnStatus: "Analyzed"}); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if item, _ := db.GetCVE("CVE-2024-0009"); item == nil || item.KEV == nil {
+			t.Errorf("Expected CVE-2024-0009 to be KEV-listed, got %+v", item)
+		}
+
+		// Replacing the catalog drops the entries it no longer lists
+		if listed, matched, err := db.ImportKEV(entries[:1], true); err != nil || listed != 1 || matched != 1 {
+			t.Fatalf("ImportKEV replace: listed %d, matched %d, err %v", listed, matched, err)
+		}
+		if _, total, _ := db.ListKEVCVEs(0, 10, nil); total != 1 {
+			t.Errorf("Expected 1 listed CVE after replace, got %d", total)
+		}
+	})
+}
@@ -0,0 +1,463 @@
+package local
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm/clause"
+)
+
+// ReferenceHealthRecord stores the last probe result for a reference URL.
+// Results are keyed by URL so a reference shared by several CVEs is only
+// probed once per TTL.
+type ReferenceHealthRecord struct {
+	ID         uint      `gorm:"primarykey"`
+	URL        string    `gorm:"uniqueIndex;not null"`
+	Status     string    `gorm:"index"`
+	HTTPStatus int       // HTTP status code of the final probe, 0 if none was received
+	CheckedAt  time.Time `gorm:"index"`
+}
+
+// TableName overrides the default table name
+func (ReferenceHealthRecord) TableName() string {
+	return "cve_reference_health"
+}
+
+// SaveReferenceHealth records the probe result for a reference URL
+func (d *DB) SaveReferenceHealth(refURL string, health cve.ReferenceHealth) error {
+	record := ReferenceHealthRecord{
+		URL:        refURL,
+		Status:     health.Status,
+		HTTPStatus: health.HTTPStatus,
+		CheckedAt:  health.CheckedAt,
+	}
+	return dbretry.Do(func() error {
+		return d.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "url"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "http_status", "checked_at"}),
+		}).Create(&record).Error
+	})
+}
+
+// GetReferenceHealth returns the recorded probe results for the given URLs.
+// URLs that have never been probed are absent from the result.
+func (d *DB) GetReferenceHealth(urls []string) (map[string]cve.ReferenceHealth, error) {
+	result := make(map[string]cve.ReferenceHealth, len(urls))
+	if len(urls) == 0 {
+		return result, nil
+	}
+
+	var records []ReferenceHealthRecord
+	if err := d.db.Where("url IN ?", urls).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		result[r.URL] = cve.ReferenceHealth{
+			Status:     r.Status,
+			HTTPStatus: r.HTTPStatus,
+			CheckedAt:  r.CheckedAt,
+		}
+	}
+	return result, nil
+}
+
+// AttachReferenceHealth fills in the Health field of each reference of
+// cveItem that has a recorded probe result
+func (d *DB) AttachReferenceHealth(cveItem *cve.CVEItem) error {
+	if len(cveItem.References) == 0 {
+		return nil
+	}
+
+	urls := make([]string, 0, len(cveItem.References))
+	for _, ref := range cveItem.References {
+		urls = append(urls, ref.URL)
+	}
+	health, err := d.GetReferenceHealth(urls)
+	if err != nil {
+		return err
+	}
+	for i := range cveItem.References {
+		if h, ok := health[cveItem.References[i].URL]; ok {
+			h := h
+			cveItem.References[i].Health = &h
+		}
+	}
+	return nil
+}
+
+// stripReferenceHealth clears attached health so probe results, which live in
+// their own table, are never persisted into the CVE JSON data
+func stripReferenceHealth(cveItem *cve.CVEItem) {
+	for i := range cveItem.References {
+		cveItem.References[i].Health = nil
+	}
+}
+
+// ReferenceHealthConfig configures the reference health checker
+type ReferenceHealthConfig struct {
+	// Interval is the time between sweeps over all stored CVEs
+	Interval time.Duration
+	// TTL is how long a probe result stays fresh before the URL is probed again
+	TTL time.Duration
+	// RateLimit is the minimum gap between two outgoing requests
+	RateLimit time.Duration
+	// Timeout bounds a single HTTP request
+	Timeout time.Duration
+	// UserAgent is sent with every request and matched against robots.txt
+	UserAgent string
+	// PageSize is the number of CVE records loaded per query during a sweep
+	PageSize int
+	// Window restricts sweeps to maintenance periods; nil means always allowed
+	Window *maintenance.Window
+}
+
+// DefaultReferenceHealthConfig returns the default checker configuration
+func DefaultReferenceHealthConfig() ReferenceHealthConfig {
+	return ReferenceHealthConfig{
+		Interval:  24 * time.Hour,
+		TTL:       7 * 24 * time.Hour,
+		RateLimit: time.Second,
+		Timeout:   10 * time.Second,
+		UserAgent: "v2e-reference-checker",
+		PageSize:  500,
+	}
+}
+
+// robotsRules caches the Disallow prefixes that apply to the checker on one host
+type robotsRules struct {
+	disallow  []string
+	fetchedAt time.Time
+}
+
+// ReferenceHealthChecker periodically probes the reference URLs of stored CVEs
+// and records whether they are still reachable
+type ReferenceHealthChecker struct {
+	db     *DB
+	config ReferenceHealthConfig
+	client *http.Client
+
+	// sweepMu serializes sweeps; robots and lastRequest are only touched
+	// while it is held
+	sweepMu     sync.Mutex
+	robots      map[string]*robotsRules
+	lastRequest time.Time
+	now         func() time.Time
+}
+
+// NewReferenceHealthChecker creates a checker for the CVEs stored in db.
+// Zero fields in config are replaced with defaults.
+func NewReferenceHealthChecker(db *DB, config ReferenceHealthConfig) *ReferenceHealthChecker {
+	defaults := DefaultReferenceHealthConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaults.TTL
+	}
+	if config.RateLimit < 0 {
+		config.RateLimit = defaults.RateLimit
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaults.UserAgent
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaults.PageSize
+	}
+
+	return &ReferenceHealthChecker{
+		db:     db,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		robots: make(map[string]*robotsRules),
+		now:    time.Now,
+	}
+}
+
+// Run sweeps immediately and then once per Interval until ctx is cancelled,
+// deferring each sweep until the maintenance window is open.
+// The error callback, if non-nil, receives sweep failures.
+func (c *ReferenceHealthChecker) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.config.Window.Wait(ctx); err != nil {
+			return
+		}
+		if _, err := c.Sweep(ctx); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep probes every stale reference URL of the stored CVEs once and returns
+// the number of URLs probed. If the maintenance window closes mid-sweep, the
+// sweep stops after the current probe; the remaining URLs are still stale and
+// are picked up by the next sweep.
+func (c *ReferenceHealthChecker) Sweep(ctx context.Context) (int, error) {
+	c.sweepMu.Lock()
+	defer c.sweepMu.Unlock()
+
+	probed := 0
+	seen := make(map[string]struct{})
+	var lastID uint
+	for {
+		var records []CVERecord
+		if err := c.db.db.Select("id", "data").Where("id > ?", lastID).
+			Order("id").Limit(c.config.PageSize).Find(&records).Error; err != nil {
+			return probed, err
+		}
+		if len(records) == 0 {
+			return probed, nil
+		}
+		lastID = records[len(records)-1].ID
+
+		var urls []string
+		for _, record := range records {
+			var item cve.CVEItem
+			if err := jsonutil.Unmarshal([]byte(record.Data), &item); err != nil {
+				continue
+			}
+			for _, ref := range item.References {
+				if _, ok := seen[ref.URL]; ok || ref.URL == "" {
+					continue
+				}
+				seen[ref.URL] = struct{}{}
+				urls = append(urls, ref.URL)
+			}
+		}
+
+		known, err := c.db.GetReferenceHealth(urls)
+		if err != nil {
+			return probed, err
+		}
+		for _, refURL := range urls {
+			if !c.config.Window.Allowed() {
+				return probed, nil
+			}
+			if h, ok := known[refURL]; ok && c.now().Sub(h.CheckedAt) < c.config.TTL {
+				continue
+			}
+			health, err := c.check(ctx, refURL)
+			if err != nil {
+				return probed, err
+			}
+			if err := c.db.SaveReferenceHealth(refURL, health); err != nil {
+				return probed, err
+			}
+			probed++
+		}
+	}
+}
+
+// check probes a single URL. It only returns an error when ctx is cancelled;
+// every other failure is recorded in the returned health.
+func (c *ReferenceHealthChecker) check(ctx context.Context, refURL string) (cve.ReferenceHealth, error) {
+	health := cve.ReferenceHealth{Status: cve.ReferenceHealthError}
+
+	u, err := url.Parse(refURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		health.CheckedAt = c.now()
+		return health, nil
+	}
+
+	allowed, err := c.allowedByRobots(ctx, u)
+	if err != nil {
+		return health, err
+	}
+	if !allowed {
+		health.Status = cve.ReferenceHealthBlocked
+		health.CheckedAt = c.now()
+		return health, nil
+	}
+
+	code, err := c.request(ctx, http.MethodHead, refURL, false)
+	// Some servers refuse HEAD outright; ask for a single byte instead
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusForbidden || code == http.StatusNotImplemented) {
+		code, err = c.request(ctx, http.MethodGet, refURL, true)
+	}
+	if ctx.Err() != nil {
+		return health, ctx.Err()
+	}
+
+	health.CheckedAt = c.now()
+	health.HTTPStatus = code
+	switch {
+	case err != nil && isTimeout(err):
+		health.Status = cve.ReferenceHealthTimeout
+	case err != nil:
+		health.Status = cve.ReferenceHealthError
+	case code == http.StatusNotFound || code == http.StatusGone:
+		health.Status = cve.ReferenceHealthNotFound
+	case code >= 200 && code < 400:
+		health.Status = cve.ReferenceHealthOK
+	default:
+		health.Status = cve.ReferenceHealthError
+	}
+	return health, nil
+}
+
+// request performs one rate-limited request and returns the status code
+func (c *ReferenceHealthChecker) request(ctx context.Context, method, target string, ranged bool) (int, error) {
+	if err := c.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	if ranged {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a bounded amount so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode, nil
+}
+
+// wait blocks until RateLimit has passed since the previous request
+func (c *ReferenceHealthChecker) wait(ctx context.Context) error {
+	if delay := c.config.RateLimit - c.now().Sub(c.lastRequest); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.lastRequest = c.now()
+	return nil
+}
+
+// allowedByRobots reports whether robots.txt on u's host permits the checker
+// to fetch u. A missing or unreadable robots.txt allows everything.
+func (c *ReferenceHealthChecker) allowedByRobots(ctx context.Context, u *url.URL) (bool, error) {
+	origin := u.Scheme + "://" + u.Host
+	rules, ok := c.robots[origin]
+	if !ok || c.now().Sub(rules.fetchedAt) >= c.config.TTL {
+		disallow, err := c.fetchRobots(ctx, origin)
+		if err != nil {
+			return false, err
+		}
+		rules = &robotsRules{disallow: disallow, fetchedAt: c.now()}
+		c.robots[origin] = rules
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	for _, prefix := range rules.disallow {
+		if strings.HasPrefix(path, prefix) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fetchRobots downloads robots.txt for origin and returns the Disallow
+// prefixes of the groups addressed to "*" or to the checker's user agent
+func (c *ReferenceHealthChecker) fetchRobots(ctx context.Context, origin string) ([]string, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, nil
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512*1024), c.config.UserAgent), nil
+}
+
+// parseRobots extracts the Disallow prefixes that apply to agent. Allow lines
+// are ignored, which errs on the side of not probing.
+func parseRobots(r io.Reader, agent string) []string {
+	agent = strings.ToLower(agent)
+	var disallow []string
+	applies := false
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive User-agent lines form one group
+			if !inAgents {
+				applies = false
+				inAgents = true
+			}
+			v := strings.ToLower(value)
+			if v == "*" || (v != "" && strings.Contains(agent, v)) {
+				applies = true
+			}
+		case "disallow":
+			inAgents = false
+			if applies && value != "" {
+				disallow = append(disallow, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return disallow
+}
+
+// isTimeout reports whether err was caused by a request timing out
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,235 @@
+package local
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// newReferenceServer serves a robots.txt that disallows /private and a few
+// paths with fixed behaviour, counting requests per method and path
+func newReferenceServer(t *testing.T) (*httptest.Server, func(string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Range") != "bytes=0-0" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("x"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[key]
+	}
+}
+
+func TestReferenceHealth_SweepRecordsStatuses(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestReferenceHealth_SweepRecordsStatuses", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_reference_health.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		srv, hits := newReferenceServer(t)
+		item := &cve.CVEItem{ID: "CVE-2024-1000", References: []cve.Reference{
+			{URL: srv.URL + "/ok"},
+			{URL: srv.URL + "/gone"},
+			{URL: srv.URL + "/nohead"},
+			{URL: srv.URL + "/slow"},
+			{URL: srv.URL + "/private/advisory"},
+		}}
+		if err := db.SaveCVE(item); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+
+		checker := NewReferenceHealthChecker(db, ReferenceHealthConfig{Timeout: 50 * time.Millisecond})
+		checker.config.RateLimit = 0
+		probed, err := checker.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf("Sweep failed: %v", err)
+		}
+		if probed != 5 {
+			t.Errorf("Probed %d URLs, want 5", probed)
+		}
+
+		got, err := db.GetCVE(item.ID)
+		if err != nil {
+			t.Fatalf("GetCVE failed: %v", err)
+		}
+		if err := db.AttachReferenceHealth(got); err != nil {
+			t.Fatalf("AttachReferenceHealth failed: %v", err)
+		}
+
+		want := []string{
+			cve.ReferenceHealthOK,
+			cve.ReferenceHealthNotFound,
+			cve.ReferenceHealthOK,
+			cve.ReferenceHealthTimeout,
+			cve.ReferenceHealthBlocked,
+		}
+		for i, ref := range got.References {
+			if ref.Health == nil {
+				t.Errorf("%s: no health attached", ref.URL)
+				continue
+			}
+			if ref.Health.Status != want[i] {
+				t.Errorf("%s: status = %s, want %s", ref.URL, ref.Health.Status, want[i])
+			}
+		}
+		if got.References[2].Health != nil && got.References[2].Health.HTTPStatus != http.StatusPartialContent {
+			t.Errorf("Ranged GET fallback status = %d, want 206", got.References[2].Health.HTTPStatus)
+		}
+		if n := hits("HEAD /private/advisory"); n != 0 {
+			t.Errorf("Disallowed path was probed %d times", n)
+		}
+		if n := hits("GET /robots.txt"); n != 1 {
+			t.Errorf("robots.txt fetched %d times, want 1", n)
+		}
+
+		// Fresh results are not probed again
+		probed, err = checker.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf("Second sweep failed: %v", err)
+		}
+		if probed != 0 || hits("HEAD /ok") != 1 {
+			t.Errorf("Second sweep probed %d URLs (HEAD /ok hits %d), want cached results", probed, hits("HEAD /ok"))
+		}
+
+		// Expired results are
+		checker.now = func() time.Time { return time.Now().Add(checker.config.TTL + time.Hour) }
+		if _, err := checker.Sweep(context.Background()); err != nil {
+			t.Fatalf("Third sweep failed: %v", err)
+		}
+		if hits("HEAD /ok") != 2 {
+			t.Errorf("HEAD /ok hits = %d after TTL, want 2", hits("HEAD /ok"))
+		}
+	})
+}
+
+func TestReferenceHealth_NotPersistedInCVEData(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestReferenceHealth_NotPersistedInCVEData", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_reference_health_strip.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		item := &cve.CVEItem{ID: "CVE-2024-1001", References: []cve.Reference{{
+			URL:    "https://example.com/a",
+			Health: &cve.ReferenceHealth{Status: cve.ReferenceHealthOK, CheckedAt: time.Now()},
+		}}}
+		if err := db.SaveCVE(item); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+
+		raw, err := db.GetCVERaw(item.ID)
+		if err != nil {
+			t.Fatalf("GetCVERaw failed: %v", err)
+		}
+		if strings.Contains(raw.Data, "health") {
+			t.Errorf("Reference health leaked into stored data: %s", raw.Data)
+		}
+	})
+}
+
+func TestParseRobots(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseRobots", nil, func(t *testing.T, tx *gorm.DB) {
+		robots := strings.Join([]string{
+			"User-agent: googlebot",
+			"Disallow: /google-only",
+			"",
+			"User-agent: *",
+			"User-agent: v2e",
+			"Disallow: /private # comment",
+			"Disallow:",
+			"Allow: /public",
+		}, "\n")
+
+		got := parseRobots(strings.NewReader(robots), "v2e-reference-checker")
+		if len(got) != 1 || got[0] != "/private" {
+			t.Errorf("parseRobots = %v, want [/private]", got)
+		}
+	})
+}
+
+func TestReferenceHealth_SweepStopsOutsideWindow(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestReferenceHealth_SweepStopsOutsideWindow", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := "/tmp/test_reference_health_window.db"
+		os.Remove(dbPath)
+		defer os.Remove(dbPath)
+
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		srv, hits := newReferenceServer(t)
+		if err := db.SaveCVE(&cve.CVEItem{ID: "CVE-2024-1002", References: []cve.Reference{{URL: srv.URL + "/ok"}}}); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+
+		// A window that is never open on the current day
+		day := strings.ToLower(time.Now().Add(48 * time.Hour).Weekday().String()[:3])
+		window, err := maintenance.Parse(day + " 00:00-00:01")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		checker := NewReferenceHealthChecker(db, ReferenceHealthConfig{Window: window})
+		probed, err := checker.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf("Sweep failed: %v", err)
+		}
+		if probed != 0 || hits("HEAD /ok") != 0 {
+			t.Errorf("Sweep probed %d URLs outside the maintenance window", probed)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"gorm.io/gorm"
+)
+
+// CVSS base severities accepted by SearchCVEs
+var cvssSeverities = map[string]bool{"CRITICAL": true, "HIGH": true, "MEDIUM": true, "LOW": true, "NONE": true}
+
+// cveDescriptionsSQL extracts the text of every description of a CVE row
+// aliased r, space-separated
+const cveDescriptionsSQL = `CASE WHEN json_valid(r.data) THEN (SELECT group_concat(json_extract(value, '$.value'), ' ') FROM json_each(r.data, '$.descriptions')) END`
+
+// cveFTSStatements creates the cve_fts full-text index of CVE IDs and
+// descriptions, with triggers keeping it in step with every write to
+// cve_records, and fills it from existing rows. The docid of an entry is the
+// id of its row; soft-deleted rows are not indexed.
+func cveFTSStatements() []string {
+	descriptions := func(row string) string {
+		return strings.ReplaceAll(cveDescriptionsSQL, "r.data", row+".data")
+	}
+	return []string{
+		`CREATE VIRTUAL TABLE cve_fts USING fts4(cve_id, description)`,
+		`CREATE TRIGGER cve_fts_ai AFTER INSERT ON cve_records WHEN new.deleted_at IS NULL BEGIN
+			INSERT INTO cve_fts(docid, cve_id, description) VALUES (new.id, new.cve_id, ` + descriptions("new") + `);
+		END`,
+		`CREATE TRIGGER cve_fts_au AFTER UPDATE OF cve_id, data, deleted_at ON cve_records BEGIN
+			DELETE FROM cve_fts WHERE docid = old.id;
+			INSERT INTO cve_fts(docid, cve_id, description) SELECT new.id, new.cve_id, ` + descriptions("new") + ` WHERE new.deleted_at IS NULL;
+		END`,
+		`CREATE TRIGGER cve_fts_ad AFTER DELETE ON cve_records BEGIN
+			DELETE FROM cve_fts WHERE docid = old.id;
+		END`,
+		`INSERT INTO cve_fts(docid, cve_id, description) SELECT r.id, r.cve_id, ` + cveDescriptionsSQL + ` FROM cve_records r WHERE r.deleted_at IS NULL`,
+	}
+}
+
+// ensureCVEFTS creates the cve_fts full-text index when it does not exist
+// (see cveFTSStatements). It reports false when SQLite lacks the FTS4
+// module, in which case SearchCVEs falls back to LIKE.
+func ensureCVEFTS(db *gorm.DB) (bool, error) {
+	if db.Migrator().HasTable(cveFTSTable) {
+		return true, nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range cveFTSStatements() {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ErrFTSUnavailable is returned by ReindexCVESearch when SQLite lacks the
+// FTS4 module, so there is no full-text index to rebuild
+var ErrFTSUnavailable = errors.New("full-text search is not available")
+
+// ReindexCVESearch rebuilds the cve_fts full-text index from cve_records,
+// e.g. after it drifted through manual edits, and returns the number of CVEs
+// indexed. The index and its triggers are dropped and created again in one
+// transaction, so searches meanwhile keep reading the old index until the
+// new one is committed, and writes wait for the rebuild.
+func (d *DB) ReindexCVESearch() (int64, error) {
+	if !d.fts {
+		return 0, ErrFTSUnavailable
+	}
+	var indexed int64
+	err := dbretry.Do(func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			drops := []string{
+				`DROP TRIGGER IF EXISTS cve_fts_ai`,
+				`DROP TRIGGER IF EXISTS cve_fts_au`,
+				`DROP TRIGGER IF EXISTS cve_fts_ad`,
+				`DROP TABLE IF EXISTS cve_fts`,
+			}
+			for _, stmt := range append(drops, cveFTSStatements()...) {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Raw(`SELECT count(*) FROM cve_fts`).Scan(&indexed).Error
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild %s: %w", cveFTSTable, err)
+	}
+	return indexed, nil
+}
+
+// ftsMatchQuery turns a keyword into an FTS query matching rows that contain
+// every word as a phrase, so operators and quotes in the keyword are taken
+// literally. It returns "" when the keyword has no searchable words.
+func ftsMatchQuery(keyword string) string {
+	var phrases []string
+	for _, word := range strings.Fields(keyword) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if strings.IndexFunc(word, func(r rune) bool {
+			return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+		}) < 0 {
+			continue
+		}
+		phrases = append(phrases, `"`+word+`"`)
+	}
+	return strings.Join(phrases, " ")
+}
+
+// likePattern returns a LIKE pattern matching s anywhere, with LIKE
+// wildcards in s escaped by a backslash
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// searchScope returns a query on CVE records matching keyword and severity
+// and excluding the given statuses. The keyword matches a part of the CVE ID
+// or, through the full-text index, words of the descriptions; without the
+// index, any part of the stored CVE data.
+func (d *DB) searchScope(keyword, severity string, excludeStatuses []string) *gorm.DB {
+	query := d.statusScope(excludeStatuses)
+	if keyword = strings.TrimSpace(keyword); keyword != "" {
+		idLike := likePattern(strings.ToUpper(keyword))
+		match := ftsMatchQuery(keyword)
+		switch {
+		case d.fts && match != "":
+			query = query.Where(`UPPER(cve_id) LIKE ? ESCAPE '\' OR id IN (SELECT docid FROM cve_fts WHERE cve_fts MATCH ?)`, idLike, match)
+		case d.fts:
+			query = query.Where(`UPPER(cve_id) LIKE ? ESCAPE '\'`, idLike)
+		default:
+			query = query.Where(`UPPER(cve_id) LIKE ? ESCAPE '\' OR data LIKE ? ESCAPE '\'`, idLike, likePattern(keyword))
+		}
+	}
+	if severity != "" {
+		query = query.Where("base_severity = ?", strings.ToUpper(severity))
+	}
+	return query
+}
+
+// searchListScope returns the page query of SearchCVEs
+func (d *DB) searchListScope(keyword, severity string, offset, limit int, excludeStatuses []string) *gorm.DB {
+	return d.searchScope(keyword, severity, excludeStatuses).Offset(offset).Limit(limit).Order("published desc")
+}
+
+// SearchCVEs returns a page of the CVEs matching keyword and severity,
+// newest first, and the number of matches. An empty keyword matches every
+// CVE, as ListCVEsFiltered; an empty severity matches every severity,
+// otherwise CVEs are matched on the severity of their preferred CVSS metric
+// (see cve.DeriveCVSS). CVEs whose status is in excludeStatuses are left
+// out.
+func (d *DB) SearchCVEs(keyword, severity string, offset, limit int, excludeStatuses []string) ([]cve.CVEItem, int64, error) {
+	if severity != "" && !cvssSeverities[strings.ToUpper(severity)] {
+		return nil, 0, fmt.Errorf("invalid severity %q: must be CRITICAL, HIGH, MEDIUM, LOW or NONE", severity)
+	}
+
+	var records []CVERecord
+	var total int64
+	err := dbretry.Do(func() error {
+		if err := d.searchListScope(keyword, severity, offset, limit, excludeStatuses).Find(&records).Error; err != nil {
+			return err
+		}
+		return d.searchScope(keyword, severity, excludeStatuses).Count(&total).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cves := make([]cve.CVEItem, len(records))
+	for i, record := range records {
+		if err := jsonutil.Unmarshal([]byte(record.Data), &cves[i]); err != nil {
+			return nil, 0, err
+		}
+		record.applyDerived(&cves[i])
+	}
+	if err := d.attachKEV(cves); err != nil {
+		return nil, 0, err
+	}
+	return cves, total, nil
+}
@@ -0,0 +1,204 @@
+package local
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// searchTestCVE returns a CVE with a description and, when severity is set,
+// a CVSS v3.1 metric of that severity
+func searchTestCVE(id, description, severity string, published time.Time) *cve.CVEItem {
+	item := &cve.CVEItem{
+		ID:           id,
+		VulnStatus:   "Analyzed",
+		Published:    cve.NewNVDTime(published),
+		Descriptions: []cve.Description{{Lang: "en", Value: description}},
+	}
+	if severity != "" {
+		item.Metrics = &cve.Metrics{CvssMetricV31: []cve.CVSSMetricV3{{CvssData: cve.CVSSDataV3{BaseSeverity: severity}}}}
+	}
+	return item
+}
+
+func searchIDs(cves []cve.CVEItem) []string {
+	ids := make([]string, len(cves))
+	for i, c := range cves {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestSearchCVEs(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSearchCVEs", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "search.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, item := range []*cve.CVEItem{
+			searchTestCVE("CVE-2024-0001", "Remote code execution in Apache Log4j", "CRITICAL", base),
+			searchTestCVE("CVE-2024-0002", "SQL injection in the login form", "HIGH", base.Add(time.Hour)),
+			searchTestCVE("CVE-2024-0003", "Cross-site scripting in Apache Struts", "MEDIUM", base.Add(2*time.Hour)),
+			searchTestCVE("CVE-2023-1234", "Denial of service 100% CPU", "", base.Add(3*time.Hour)),
+		} {
+			if err := db.SaveCVE(item); err != nil {
+				t.Fatalf("SaveCVE %d failed: %v", i, err)
+			}
+		}
+
+		cases := []struct {
+			keyword, severity string
+			want              []string
+		}{
+			{"apache", "", []string{"CVE-2024-0003", "CVE-2024-0001"}},
+			{"APACHE log4j", "", []string{"CVE-2024-0001"}},
+			{"apache", "critical", []string{"CVE-2024-0001"}},
+			{"2024-000", "", []string{"CVE-2024-0003", "CVE-2024-0002", "CVE-2024-0001"}},
+			{"cve-2023-1234", "", []string{"CVE-2023-1234"}},
+			{`"injection"`, "", []string{"CVE-2024-0002"}},
+			{"injection OR scripting", "", nil},
+			{"100%", "", []string{"CVE-2023-1234"}},
+			{"2024_000", "", nil},
+			{"", "high", []string{"CVE-2024-0002"}},
+			{"", "", []string{"CVE-2023-1234", "CVE-2024-0003", "CVE-2024-0002", "CVE-2024-0001"}},
+		}
+		for _, c := range cases {
+			cves, total, err := db.SearchCVEs(c.keyword, c.severity, 0, 10, nil)
+			if err != nil {
+				t.Fatalf("SearchCVEs(%q, %q) failed: %v", c.keyword, c.severity, err)
+			}
+			got := searchIDs(cves)
+			if len(got) != len(c.want) || int(total) != len(c.want) {
+				t.Errorf("SearchCVEs(%q, %q) = %v (total %d), want %v", c.keyword, c.severity, got, total, c.want)
+				continue
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("SearchCVEs(%q, %q) = %v, want %v", c.keyword, c.severity, got, c.want)
+					break
+				}
+			}
+		}
+
+		// Paging keeps the total of all matches
+		cves, total, err := db.SearchCVEs("apache", "", 1, 1, nil)
+		if err != nil || len(cves) != 1 || cves[0].ID != "CVE-2024-0001" || total != 2 {
+			t.Errorf("Expected the second apache match of 2, got %v (total %d), %v", searchIDs(cves), total, err)
+		}
+
+		// Updates and deletes keep the index in step
+		if err := db.SaveCVE(searchTestCVE("CVE-2024-0001", "Deserialization in Jackson", "CRITICAL", base)); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if err := db.DeleteCVE("CVE-2024-0003"); err != nil {
+			t.Fatalf("DeleteCVE failed: %v", err)
+		}
+		if cves, _, _ := db.SearchCVEs("apache", "", 0, 10, nil); len(cves) != 0 {
+			t.Errorf("Expected no apache match after update and delete, got %v", searchIDs(cves))
+		}
+		if cves, _, _ := db.SearchCVEs("jackson", "", 0, 10, nil); len(cves) != 1 {
+			t.Errorf("Expected the updated description to match, got %v", searchIDs(cves))
+		}
+
+		if _, _, err := db.SearchCVEs("", "severe", 0, 10, nil); err == nil {
+			t.Error("Expected an invalid severity to be rejected")
+		}
+	})
+}
+
+func TestFTSMatchQuery(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFTSMatchQuery", nil, func(t *testing.T, tx *gorm.DB) {
+		cases := map[string]string{
+			"log4j rce":       `"log4j" "rce"`,
+			`"sql injection"`: `"sql" "injection"`,
+			"NEAR * OR -- %":  `"NEAR" "OR"`,
+			"   ":             "",
+			"CVE-2024-1234":   `"CVE-2024-1234"`,
+		}
+		for in, want := range cases {
+			if got := ftsMatchQuery(in); got != want {
+				t.Errorf("ftsMatchQuery(%q) = %q, want %q", in, got, want)
+			}
+		}
+	})
+}
+
+func TestSearchCVEs_IndexesExistingRows(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestSearchCVEs_IndexesExistingRows", nil, func(t *testing.T, tx *gorm.DB) {
+		dbPath := filepath.Join(t.TempDir(), "search_existing.db")
+		db, err := NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		if err := db.SaveCVE(searchTestCVE("CVE-2024-0001", "Heap overflow in libpng", "HIGH", time.Now())); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		// A database from before the full-text index
+		for _, stmt := range []string{"DROP TRIGGER cve_fts_ai", "DROP TRIGGER cve_fts_au", "DROP TRIGGER cve_fts_ad", "DROP TABLE cve_fts"} {
+			if err := db.GormDB().Exec(stmt).Error; err != nil {
+				t.Fatalf("%s: %v", stmt, err)
+			}
+		}
+		db.Close()
+
+		db, err = NewDB(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		if cves, total, err := db.SearchCVEs("libpng", "", 0, 10, nil); err != nil || total != 1 || len(cves) != 1 {
+			t.Errorf("Expected the existing CVE to be indexed, got %v (total %d), %v", searchIDs(cves), total, err)
+		}
+	})
+}
+
+func TestReindexCVESearch(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestReindexCVESearch", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "search_reindex.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+		for _, item := range []*cve.CVEItem{
+			searchTestCVE("CVE-2024-0001", "Heap overflow in libpng", "HIGH", time.Now()),
+			searchTestCVE("CVE-2024-0002", "Use after free in libpng", "LOW", time.Now()),
+		} {
+			if err := db.SaveCVE(item); err != nil {
+				t.Fatalf("SaveCVE failed: %v", err)
+			}
+		}
+		// An index that drifted from cve_records
+		if err := db.GormDB().Exec("DELETE FROM cve_fts").Error; err != nil {
+			t.Fatalf("Failed to clear cve_fts: %v", err)
+		}
+		if _, total, err := db.SearchCVEs("libpng", "", 0, 10, nil); err != nil || total != 0 {
+			t.Fatalf("Expected the cleared index to match nothing, got %d, %v", total, err)
+		}
+
+		indexed, err := db.ReindexCVESearch()
+		if err != nil {
+			t.Fatalf("ReindexCVESearch failed: %v", err)
+		}
+		if indexed != 2 {
+			t.Errorf("Expected 2 CVEs indexed, got %d", indexed)
+		}
+		if cves, total, err := db.SearchCVEs("libpng", "", 0, 10, nil); err != nil || total != 2 {
+			t.Errorf("Expected both CVEs to match after the rebuild, got %v (total %d), %v", searchIDs(cves), total, err)
+		}
+
+		// The triggers are back, so later writes are indexed
+		if err := db.SaveCVE(searchTestCVE("CVE-2024-0003", "Race in libtiff", "MEDIUM", time.Now())); err != nil {
+			t.Fatalf("SaveCVE failed: %v", err)
+		}
+		if cves, total, err := db.SearchCVEs("libtiff", "", 0, 10, nil); err != nil || total != 1 {
+			t.Errorf("Expected the new CVE to be indexed, got %v (total %d), %v", searchIDs(cves), total, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package local
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+)
+
+// TimelineUnknown is the month of the CVEs without a usable published date
+const TimelineUnknown = "unknown"
+
+// TimelineBucket is the number of CVEs published in a month ("2024-01"), or
+// without a usable published date (TimelineUnknown)
+type TimelineBucket struct {
+	Month string `json:"month"`
+	Count int64  `json:"count"`
+}
+
+// timelineMonth matches the year-month prefix of a stored published date.
+// Year 0001 is the zero time, stored for CVEs whose date did not parse.
+var timelineMonth = regexp.MustCompile(`^(\d{4})-(0[1-9]|1[0-2])$`)
+
+// CVETimeline returns the number of CVEs by published month, oldest month
+// first, with the CVEs whose published date is absent or unparseable counted
+// in a last TimelineUnknown bucket. cweID restricts the count to the CVEs
+// linked to a CWE ("CWE-79" or "79") and severity to the CVEs of a CVSS
+// severity, as in SearchCVEs; either may be empty. CVEs whose status is in
+// excludeStatuses are left out.
+func (d *DB) CVETimeline(cweID, severity string, excludeStatuses []string) ([]TimelineBucket, error) {
+	if severity != "" && !cvssSeverities[strings.ToUpper(severity)] {
+		return nil, fmt.Errorf("invalid severity %q: must be CRITICAL, HIGH, MEDIUM, LOW or NONE", severity)
+	}
+	query := d.searchScope("", severity, excludeStatuses)
+	if cweID != "" {
+		id := normalizeCWEID(cweID)
+		if id == "" {
+			return nil, fmt.Errorf("invalid cwe_id %q: must be a CWE ID such as CWE-79", cweID)
+		}
+		query = query.Where("cve_id IN (SELECT cve_id FROM cve_cwe WHERE cwe_id = ?)", id)
+	}
+
+	var rows []TimelineBucket
+	err := dbretry.Do(func() error {
+		rows = nil
+		return query.Select("substr(COALESCE(published, ''), 1, 7) AS month, COUNT(*) AS count").
+			Group("month").Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]TimelineBucket, 0, len(rows))
+	var unknown int64
+	for _, row := range rows {
+		m := timelineMonth.FindStringSubmatch(row.Month)
+		if m == nil || m[1] == "0001" {
+			unknown += row.Count
+			continue
+		}
+		buckets = append(buckets, row)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Month < buckets[j].Month })
+	if unknown > 0 {
+		buckets = append(buckets, TimelineBucket{Month: TimelineUnknown, Count: unknown})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,70 @@
+package local
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestCVETimeline(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestCVETimeline", nil, func(t *testing.T, tx *gorm.DB) {
+		db, err := NewDB(filepath.Join(t.TempDir(), "timeline.db"))
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+		mar := time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)
+		dec := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+		xss := searchTestCVE("CVE-2024-0002", "XSS", "MEDIUM", mar)
+		xss.Weaknesses = cveWithCWEs(xss.ID, "CWE-79").Weaknesses
+		for i, item := range []*cve.CVEItem{
+			searchTestCVE("CVE-2024-0001", "RCE", "CRITICAL", jan),
+			searchTestCVE("CVE-2024-0003", "SQLi", "HIGH", jan.Add(time.Hour)),
+			xss,
+			searchTestCVE("CVE-2023-0001", "DoS", "HIGH", dec),
+			cveWithCWEs("CVE-2024-0004", "CWE-79"), // No published date
+			searchTestCVE("CVE-2024-0005", "Garbled", "HIGH", jan),
+		} {
+			if err := db.SaveCVE(item); err != nil {
+				t.Fatalf("SaveCVE %d failed: %v", i, err)
+			}
+		}
+		if err := db.db.Exec("UPDATE cve_records SET published = 'not a date' WHERE cve_id = ?", "CVE-2024-0005").Error; err != nil {
+			t.Fatalf("Failed to garble published date: %v", err)
+		}
+
+		cases := []struct {
+			cweID, severity string
+			want            []TimelineBucket
+		}{
+			{"", "", []TimelineBucket{{"2023-12", 1}, {"2024-01", 2}, {"2024-03", 1}, {TimelineUnknown, 2}}},
+			{"", "high", []TimelineBucket{{"2023-12", 1}, {"2024-01", 1}, {TimelineUnknown, 1}}},
+			{"79", "", []TimelineBucket{{"2024-03", 1}, {TimelineUnknown, 1}}},
+			{"CWE-79", "MEDIUM", []TimelineBucket{{"2024-03", 1}}},
+			{"CWE-89", "", []TimelineBucket{}},
+		}
+		for _, c := range cases {
+			got, err := db.CVETimeline(c.cweID, c.severity, nil)
+			if err != nil {
+				t.Fatalf("CVETimeline(%q, %q) failed: %v", c.cweID, c.severity, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("CVETimeline(%q, %q) = %v, want %v", c.cweID, c.severity, got, c.want)
+			}
+		}
+
+		if _, err := db.CVETimeline("", "SEVERE", nil); err == nil {
+			t.Error("Expected an error for an invalid severity")
+		}
+		if _, err := db.CVETimeline("XSS", "", nil); err == nil {
+			t.Error("Expected an error for an invalid CWE ID")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package cve
+
+import "strings"
+
+// Reference categories derived locally from the NVD reference tags. They are
+// stored with each reference so queries can find, e.g., CVEs with a public
+// exploit.
+const (
+	// ReferenceCategoryExploit is a reference tagged "Exploit", such as a
+	// proof of concept
+	ReferenceCategoryExploit = "exploit"
+	// ReferenceCategoryPatch is a reference tagged "Patch"
+	ReferenceCategoryPatch = "patch"
+	// ReferenceCategoryAdvisory is a reference tagged "Vendor Advisory" or
+	// "Third Party Advisory"
+	ReferenceCategoryAdvisory = "advisory"
+	// ReferenceCategoryMitigation is a reference tagged "Mitigation"
+	ReferenceCategoryMitigation = "mitigation"
+	// ReferenceCategoryOther is a reference with no tags or only other tags
+	ReferenceCategoryOther = "other"
+)
+
+// ClassifyReference returns the category of a reference from its tags. A
+// reference with several tags gets the most actionable one: an exploit over
+// a patch, a patch over an advisory and an advisory over a mitigation.
+func ClassifyReference(tags []string) string {
+	rank := map[string]int{
+		ReferenceCategoryExploit:    4,
+		ReferenceCategoryPatch:      3,
+		ReferenceCategoryAdvisory:   2,
+		ReferenceCategoryMitigation: 1,
+	}
+	category := ReferenceCategoryOther
+	for _, tag := range tags {
+		var c string
+		switch strings.ToLower(strings.TrimSpace(tag)) {
+		case "exploit":
+			c = ReferenceCategoryExploit
+		case "patch":
+			c = ReferenceCategoryPatch
+		case "vendor advisory", "third party advisory":
+			c = ReferenceCategoryAdvisory
+		case "mitigation":
+			c = ReferenceCategoryMitigation
+		default:
+			continue
+		}
+		if rank[c] > rank[category] {
+			category = c
+		}
+	}
+	return category
+}
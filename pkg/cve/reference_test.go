@@ -0,0 +1,37 @@
+package cve
+
+import (
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestClassifyReference(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestClassifyReference", nil, func(t *testing.T, tx *gorm.DB) {
+		tests := []struct {
+			name string
+			tags []string
+			want string
+		}{
+			{name: "no tags", tags: nil, want: ReferenceCategoryOther},
+			{name: "unrelated tags", tags: []string{"Issue Tracking", "Mailing List"}, want: ReferenceCategoryOther},
+			{name: "exploit", tags: []string{"Exploit"}, want: ReferenceCategoryExploit},
+			{name: "patch", tags: []string{"Patch"}, want: ReferenceCategoryPatch},
+			{name: "vendor advisory", tags: []string{"Vendor Advisory"}, want: ReferenceCategoryAdvisory},
+			{name: "third party advisory", tags: []string{"Third Party Advisory"}, want: ReferenceCategoryAdvisory},
+			{name: "mitigation", tags: []string{"Mitigation"}, want: ReferenceCategoryMitigation},
+			{name: "case and whitespace", tags: []string{" exploit "}, want: ReferenceCategoryExploit},
+			{name: "exploit wins over patch", tags: []string{"Patch", "Exploit", "Third Party Advisory"}, want: ReferenceCategoryExploit},
+			{name: "patch wins over advisory", tags: []string{"Vendor Advisory", "Patch"}, want: ReferenceCategoryPatch},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := ClassifyReference(tt.tags); got != tt.want {
+					t.Errorf("ClassifyReference(%q) = %q, want %q", tt.tags, got, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EnvCVECacheTTL sets how long fetched CVEs are cached, as a Go duration
+	// such as "10m"; "0" disables the cache
+	EnvCVECacheTTL = "CVE_CACHE_TTL"
+	// DefaultCVECacheTTL is how long a fetched CVE is served from the cache
+	DefaultCVECacheTTL = 10 * time.Minute
+	// DefaultCVECacheSize is the number of CVEs the cache holds before the
+	// least recently used one is evicted
+	DefaultCVECacheSize = 1024
+)
+
+// CacheStats reports the use of the CVE response cache
+type CacheStats struct {
+	Enabled    bool  `json:"enabled"`
+	TTLSeconds int64 `json:"ttl_seconds"`
+	Capacity   int   `json:"capacity"`
+	Entries    int   `json:"entries"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	// Evictions counts entries dropped to make room, not expired ones
+	Evictions int64 `json:"evictions"`
+}
+
+// cveCacheEntry is a raw NVD response body and when it goes stale
+type cveCacheEntry struct {
+	key     string
+	body    []byte
+	expires time.Time
+}
+
+// cveCache is an LRU of NVD responses keyed by CVE ID, each valid for ttl,
+// so a CVE fetched repeatedly within a session is sent to NVD once. Only
+// successful responses are stored. A ttl of zero disables it.
+type cveCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	capacity  int
+	order     *list.List // front is the most recently used entry
+	entries   map[string]*list.Element
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	now       func() time.Time
+}
+
+func newCVECache(ttl time.Duration, capacity int) *cveCache {
+	if ttl < 0 {
+		ttl = 0
+	}
+	if capacity < 1 {
+		capacity = DefaultCVECacheSize
+	}
+	return &cveCache{
+		ttl:      ttl,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// cveCacheKey folds the spellings of a CVE ID to one key
+func cveCacheKey(cveID string) string {
+	return strings.ToUpper(strings.TrimSpace(cveID))
+}
+
+// get returns the cached response body of a CVE, dropping it if stale
+func (c *cveCache) get(cveID string) ([]byte, bool) {
+	if c.ttl == 0 {
+		return nil, false
+	}
+	key := cveCacheKey(cveID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok && c.now().Before(elem.Value.(*cveCacheEntry).expires) {
+		c.order.MoveToFront(elem)
+		c.hits.Add(1)
+		return elem.Value.(*cveCacheEntry).body, true
+	}
+	if ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// put stores the response body of a CVE, evicting the least recently used
+// entry when full
+func (c *cveCache) put(cveID string, body []byte) {
+	if c.ttl == 0 {
+		return
+	}
+	key := cveCacheKey(cveID)
+	entry := &cveCacheEntry{key: key, body: body, expires: c.now().Add(c.ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cveCacheEntry).key)
+		c.evictions.Add(1)
+	}
+}
+
+// stats returns the configuration and counters of the cache
+func (c *cveCache) stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Enabled:    c.ttl > 0,
+		TTLSeconds: int64(c.ttl / time.Second),
+		Capacity:   c.capacity,
+		Entries:    entries,
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  c.evictions.Load(),
+	}
+}
+
+// WithCVECacheTTL sets how long a CVE fetched by ID is served from the
+// fetcher's cache instead of NVD; zero disables the cache
+func WithCVECacheTTL(ttl time.Duration) FetcherOption {
+	return func(f *Fetcher) {
+		f.cache = newCVECache(ttl, DefaultCVECacheSize)
+	}
+}
+
+// ParseCVECacheTTL parses a cache TTL given in configuration: a Go duration,
+// or "0" to disable the cache
+func ParseCVECacheTTL(raw string) (time.Duration, error) {
+	ttl, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid CVE cache TTL %q: %w", raw, err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("invalid CVE cache TTL %q: must not be negative", raw)
+	}
+	return ttl, nil
+}
+
+// CacheStats returns the hit and miss counts of the fetcher's CVE cache
+func (f *Fetcher) CacheStats() CacheStats {
+	return f.cache.stats()
+}
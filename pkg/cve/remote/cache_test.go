@@ -0,0 +1,143 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestFetchCVEByID_Cache(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetchCVEByID_Cache", nil, func(t *testing.T, tx *gorm.DB) {
+		var requests, limited int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.LoadInt32(&limited) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON(r.URL.Query().Get("cveId"), 1))
+		}))
+		defer server.Close()
+
+		now := time.Unix(1700000000, 0)
+		f := NewFetcher("", WithCVECacheTTL(time.Minute), WithRateLimitMaxAttempts(1))
+		f.baseURL = server.URL
+		f.cache.now = func() time.Time { return now }
+
+		// Rate-limited responses are not cached
+		atomic.StoreInt32(&limited, 1)
+		if _, err := f.FetchCVEByID("CVE-TEST-1"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got %v", err)
+		}
+		atomic.StoreInt32(&limited, 0)
+
+		for _, id := range []string{"CVE-TEST-1", "CVE-TEST-1", "cve-test-1"} {
+			resp, err := f.FetchCVEByID(id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Vulnerabilities) != 1 || resp.Vulnerabilities[0].CVE.ID != "CVE-TEST-1" {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("expected 2 requests, got %d", got)
+		}
+
+		// A stale entry is fetched again
+		now = now.Add(time.Minute)
+		if _, err := f.FetchCVEByID("CVE-TEST-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 3 {
+			t.Errorf("expected the stale entry refetched, got %d requests", got)
+		}
+
+		stats := f.CacheStats()
+		if !stats.Enabled || stats.TTLSeconds != 60 || stats.Entries != 1 || stats.Hits != 2 || stats.Misses != 3 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+
+		// A fresh fetch skips the cached entry without counting a lookup
+		if _, err := f.FetchCVEByIDFresh(context.Background(), "CVE-TEST-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 4 {
+			t.Errorf("expected the fresh fetch sent to NVD, got %d requests", got)
+		}
+		if stats := f.CacheStats(); stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 1 {
+			t.Errorf("unexpected stats after a fresh fetch %+v", stats)
+		}
+	})
+}
+
+func TestFetchCVEByID_CacheDisabled(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetchCVEByID_CacheDisabled", nil, func(t *testing.T, tx *gorm.DB) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+		}))
+		defer server.Close()
+
+		f := NewFetcher("", WithCVECacheTTL(0))
+		f.baseURL = server.URL
+		for i := 0; i < 2; i++ {
+			if _, err := f.FetchCVEByID("CVE-TEST-1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("expected 2 requests, got %d", got)
+		}
+		if stats := f.CacheStats(); stats.Enabled || stats.Hits != 0 || stats.Misses != 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+}
+
+func TestCVECache_Eviction(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCVECache_Eviction", nil, func(t *testing.T, tx *gorm.DB) {
+		c := newCVECache(time.Minute, 2)
+		c.put("CVE-1", []byte("1"))
+		c.put("CVE-2", []byte("2"))
+		c.get("CVE-1")
+		c.put("CVE-3", []byte("3"))
+
+		if _, ok := c.get("CVE-2"); ok {
+			t.Error("expected the least recently used entry evicted")
+		}
+		for _, id := range []string{"CVE-1", "CVE-3"} {
+			if _, ok := c.get(id); !ok {
+				t.Errorf("expected %s cached", id)
+			}
+		}
+		if stats := c.stats(); stats.Entries != 2 || stats.Evictions != 1 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+}
+
+func TestParseCVECacheTTL(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseCVECacheTTL", nil, func(t *testing.T, tx *gorm.DB) {
+		for raw, want := range map[string]time.Duration{"5m": 5 * time.Minute, "0": 0, " 30s ": 30 * time.Second} {
+			if got, err := ParseCVECacheTTL(raw); err != nil || got != want {
+				t.Errorf("ParseCVECacheTTL(%q) = %v, %v; want %v", raw, got, err, want)
+			}
+		}
+		for _, raw := range []string{"", "ten", "-1m"} {
+			if _, err := ParseCVECacheTTL(raw); err == nil {
+				t.Errorf("expected an error for %q", raw)
+			}
+		}
+	})
+}
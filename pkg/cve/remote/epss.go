@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/go-resty/resty/v2"
+)
+
+// epssResponse is the body of a FIRST EPSS API response. Scores are sent as
+// decimal strings.
+type epssResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		CVE        string `json:"cve"`
+		EPSS       string `json:"epss"`
+		Percentile string `json:"percentile"`
+		Date       string `json:"date"`
+	} `json:"data"`
+}
+
+// WithEPSSBaseURL sets the FIRST EPSS API endpoint, e.g. a test server; empty
+// keeps the public API
+func WithEPSSBaseURL(baseURL string) FetcherOption {
+	return func(f *Fetcher) {
+		if baseURL != "" {
+			f.epssURL = baseURL
+		}
+	}
+}
+
+// EPSSBaseURL returns the FIRST EPSS API endpoint EPSS requests are sent to
+func (f *Fetcher) EPSSBaseURL() string {
+	return f.epssURL
+}
+
+// FetchEPSS fetches the EPSS score of a CVE from the FIRST EPSS API. It
+// returns nil without an error if FIRST has no score for the CVE, e.g. one
+// published too recently to be scored.
+func (f *Fetcher) FetchEPSS(cveID string) (*cve.EPSS, error) {
+	return f.FetchEPSSContext(context.Background(), cveID)
+}
+
+// FetchEPSSContext is FetchEPSS, aborting the request and any backoff when
+// ctx is done
+func (f *Fetcher) FetchEPSSContext(ctx context.Context, cveID string) (*cve.EPSS, error) {
+	if cveID == "" {
+		return nil, fmt.Errorf("CVE ID cannot be empty")
+	}
+
+	key, err := epssFixtureKey(cveID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := f.fetch(ctx, key, UpstreamEPSS, "EPSS", func() (*resty.Response, error) {
+		return f.client.R().
+			SetContext(ctx).
+			SetQueryParam("cve", cveID).
+			Get(f.epssURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result epssResponse
+	if err := jsonutil.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal EPSS response: %w", err)
+	}
+	if err := f.record(key, body); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range result.Data {
+		if entry.CVE != cveID {
+			continue
+		}
+		score, err := strconv.ParseFloat(entry.EPSS, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPSS score %q for %s: %w", entry.EPSS, cveID, err)
+		}
+		percentile, err := strconv.ParseFloat(entry.Percentile, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPSS percentile %q for %s: %w", entry.Percentile, cveID, err)
+		}
+		return &cve.EPSS{Score: score, Percentile: percentile, Date: entry.Date}, nil
+	}
+	return nil, nil
+}
+
+// epssFixtureKey is the fixture of FetchEPSS
+func epssFixtureKey(cveID string) (string, error) {
+	key, err := cveFixtureKey(cveID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join("epss", filepath.Base(key)), nil
+}
@@ -0,0 +1,57 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestFetchEPSS(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetchEPSS", nil, func(t *testing.T, tx *gorm.DB) {
+		if got := NewFetcher("").EPSSBaseURL(); got != cve.EPSSAPIURL {
+			t.Errorf("expected the public EPSS API by default, got %s", got)
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Query().Get("cve") {
+			case "CVE-2022-27225":
+				w.Write([]byte(`{"status":"OK","status-code":200,"total":1,"data":[{"cve":"CVE-2022-27225","epss":"0.000750000","percentile":"0.308040000","date":"2024-03-01"}]}`))
+			case "CVE-2099-0001":
+				w.Write([]byte(`{"status":"OK","status-code":200,"total":0,"data":[]}`))
+			case "CVE-2099-0002":
+				w.Write([]byte(`{"status":"OK","total":1,"data":[{"cve":"CVE-2099-0002","epss":"high","percentile":"0.1","date":"2024-03-01"}]}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+		f := NewFetcher("", WithEPSSBaseURL(server.URL))
+
+		epss, err := f.FetchEPSS("CVE-2022-27225")
+		if err != nil {
+			t.Fatalf("FetchEPSS failed: %v", err)
+		}
+		if epss == nil || epss.Score != 0.00075 || epss.Percentile != 0.30804 || epss.Date != "2024-03-01" {
+			t.Errorf("Unexpected EPSS %+v", epss)
+		}
+
+		// A CVE FIRST has not scored is not an error
+		if epss, err := f.FetchEPSS("CVE-2099-0001"); err != nil || epss != nil {
+			t.Errorf("Expected no score without an error, got %+v %v", epss, err)
+		}
+		if _, err := f.FetchEPSS("CVE-2099-0002"); err == nil {
+			t.Error("Expected an error for a malformed score")
+		}
+		if _, err := f.FetchEPSS("CVE-2099-0003"); err == nil {
+			t.Error("Expected an error for a failed request")
+		}
+		if _, err := f.FetchEPSS(""); err == nil {
+			t.Error("Expected an error for an empty CVE ID")
+		}
+	})
+}
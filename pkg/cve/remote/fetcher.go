@@ -1,9 +1,13 @@
 package remote
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,111 +19,433 @@ import (
 // ErrRateLimited is returned when the NVD API returns a 429 status
 var ErrRateLimited = errors.New("NVD API rate limit exceeded")
 
+// Defaults of the backoff between rate-limited attempts. The waits of the
+// default attempts add up to 14s, well within the 30s RPC timeout of callers.
+const (
+	DefaultRateLimitMaxAttempts = 4
+	DefaultRateLimitBackoffBase = 2 * time.Second
+	DefaultRateLimitBackoffMax  = 16 * time.Second
+)
+
+// RateLimitError is returned when a request is still rate limited after the
+// last attempt. It matches ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	// Attempts is the number of requests sent
+	Attempts int
+	// Waited is the total time spent backing off between them
+	Waited time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v after %d attempts (waited %s)", ErrRateLimited, e.Attempts, e.Waited)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// DefaultHTTPTimeout bounds a single request of a fetcher, from dialing to
+// reading the whole body
+const DefaultHTTPTimeout = 30 * time.Second
+
+// newTransport returns the transport of a fetcher's client. It is shared by
+// all requests of the fetcher, so a large import reuses a few kept-alive
+// connections instead of paying a TCP and TLS handshake per page. The resty
+// client reads and closes every response body, error statuses included, so
+// connections go back to the pool.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// MaxModifiedWindow is the longest lastModStartDate..lastModEndDate range
+// the NVD API accepts in one request
+const MaxModifiedWindow = 120 * 24 * time.Hour
+
+// nvdDateLayout is the extended ISO-8601 format of the NVD API's date
+// parameters
+const nvdDateLayout = "2006-01-02T15:04:05.000-07:00"
+
 // Fetcher handles fetching CVE data from the NVD API
 type Fetcher struct {
 	client  *resty.Client
 	baseURL string
 	apiKey  string
+	// epssURL is the FIRST EPSS API endpoint (see epss.go)
+	epssURL string
+	// kevURL is the CISA KEV feed (see kev.go)
+	kevURL string
 	// bufferPool reuses temporary byte slices for response bodies
 	bufferPool *sync.Pool
+	// mode and fixturesDir configure recording and replaying of responses
+	// (see fixtures.go)
+	mode        FetcherMode
+	fixturesDir string
+	// maxAttempts, backoffBase and backoffMax configure retries of
+	// rate-limited requests
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	// sleep waits between rate-limited attempts and for a token of the
+	// upstream's limiter, returning early with the error of ctx when it is
+	// done
+	sleep func(ctx context.Context, d time.Duration) error
+	// cache holds recent responses of FetchCVEByID (see cache.go)
+	cache *cveCache
+	// limiters rate limit the requests to each upstream (see ratelimit.go)
+	limiters map[string]*rateLimiter
+}
+
+// FetcherOption configures a Fetcher
+type FetcherOption func(*Fetcher)
+
+// WithRateLimitBackoff sets the wait before the first retry of a
+// rate-limited request, doubled on every further retry up to max
+func WithRateLimitBackoff(base, max time.Duration) FetcherOption {
+	return func(f *Fetcher) {
+		f.backoffBase = base
+		f.backoffMax = max
+	}
+}
+
+// WithRateLimitMaxAttempts sets how many times a rate-limited request is
+// sent before giving up; 1 disables retries
+func WithRateLimitMaxAttempts(n int) FetcherOption {
+	return func(f *Fetcher) {
+		if n < 1 {
+			n = 1
+		}
+		f.maxAttempts = n
+	}
+}
+
+// WithBaseURL sets the NVD CVE API endpoint requests are sent to, e.g. an
+// internal mirror or a test server; empty keeps the public NVD API
+func WithBaseURL(baseURL string) FetcherOption {
+	return func(f *Fetcher) {
+		if baseURL != "" {
+			f.baseURL = baseURL
+		}
+	}
 }
 
-// NewFetcher creates a new CVE fetcher
-func NewFetcher(apiKey string) *Fetcher {
+// ParseBaseURL validates an NVD base URL given in configuration: it must be an
+// absolute http or https URL
+func ParseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid NVD base URL %q: %w", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid NVD base URL %q: must be an absolute http or https URL", raw)
+	}
+	return raw, nil
+}
+
+// NewFetcher creates a new CVE fetcher. Its mode is taken from the
+// FETCHER_MODE and FETCHER_FIXTURES_DIR environment variables.
+func NewFetcher(apiKey string, opts ...FetcherOption) *Fetcher {
 	client := resty.New()
-	client.SetTimeout(30 * time.Second)
-
-	// Enable HTTP/2 and connection pooling for better performance
-	client.SetTransport(&http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		DisableCompression:  false, // Enable compression
-	})
+	client.SetTimeout(DefaultHTTPTimeout)
+	client.SetTransport(newTransport())
 
-	return &Fetcher{
-		client:  client,
-		baseURL: cve.NVDAPIURL,
-		apiKey:  apiKey,
+	mode, fixturesDir := fetcherConfigFromEnv()
+
+	f := &Fetcher{
+		client:      client,
+		baseURL:     cve.NVDAPIURL,
+		epssURL:     cve.EPSSAPIURL,
+		kevURL:      cve.KEVFeedURL,
+		apiKey:      apiKey,
+		mode:        mode,
+		fixturesDir: fixturesDir,
 		bufferPool: &sync.Pool{
 			New: func() interface{} {
 				b := make([]byte, 0, 32*1024) // 32KB initial capacity
 				return &b
 			},
 		},
+		maxAttempts: DefaultRateLimitMaxAttempts,
+		backoffBase: DefaultRateLimitBackoffBase,
+		backoffMax:  DefaultRateLimitBackoffMax,
+		sleep:       sleepContext,
+		cache:       newCVECache(DefaultCVECacheTTL, DefaultCVECacheSize),
+		limiters: map[string]*rateLimiter{
+			UpstreamNVD: newRateLimiter(UpstreamNVD, DefaultNVDRateLimit(apiKey != ""), NVDRateWindow),
+		},
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+	return f
+}
+
+// BaseURL returns the NVD CVE API endpoint requests are sent to
+func (f *Fetcher) BaseURL() string {
+	return f.baseURL
 }
 
 // FetchCVEByID fetches a specific CVE by its ID
 func (f *Fetcher) FetchCVEByID(cveID string) (*cve.CVEResponse, error) {
+	return f.FetchCVEByIDContext(context.Background(), cveID)
+}
+
+// FetchCVEByIDContext is FetchCVEByID, aborting the request and any backoff
+// when ctx is done. A CVE fetched within the cache TTL is served from the
+// cache; errors, rate limiting included, are never cached.
+func (f *Fetcher) FetchCVEByIDContext(ctx context.Context, cveID string) (*cve.CVEResponse, error) {
+	return f.fetchCVEByID(ctx, cveID, true)
+}
+
+// FetchCVEByIDFresh is FetchCVEByIDContext bypassing the cache, for callers
+// that must see the current NVD data. The response refreshes the cache.
+func (f *Fetcher) FetchCVEByIDFresh(ctx context.Context, cveID string) (*cve.CVEResponse, error) {
+	return f.fetchCVEByID(ctx, cveID, false)
+}
+
+// fetchCVEByID fetches a CVE, serving it from the cache if useCache is set
+func (f *Fetcher) fetchCVEByID(ctx context.Context, cveID string, useCache bool) (*cve.CVEResponse, error) {
 	if cveID == "" {
 		return nil, fmt.Errorf("CVE ID cannot be empty")
 	}
 
-	req := f.client.R()
-	if f.apiKey != "" {
-		req.SetHeader("apiKey", f.apiKey)
+	key, err := cveFixtureKey(cveID)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := req.Get(f.baseURL + "?cveId=" + cveID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch CVE: %w", err)
+	if useCache {
+		if body, ok := f.cache.get(cveID); ok {
+			var result cve.CVEResponse
+			if err := jsonutil.Unmarshal(body, &result); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal CVE response: %w", err)
+			}
+			return &result, nil
+		}
 	}
 
-	if resp.IsError() {
-		if resp.StatusCode() == 429 {
-			return nil, ErrRateLimited
+	body, err := f.fetch(ctx, key, UpstreamNVD, "CVE", func() (*resty.Response, error) {
+		req := f.client.R().SetContext(ctx)
+		if f.apiKey != "" {
+			req.SetHeader("apiKey", f.apiKey)
 		}
-		return nil, fmt.Errorf("API returned error status: %d", resp.StatusCode())
+		return req.Get(f.baseURL + "?cveId=" + cveID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Prefer using sonic for faster unmarshalling on hot paths
-	body := resp.Body()
 	var result cve.CVEResponse
 	if err := jsonutil.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal CVE response: %w", err)
 	}
 
+	if err := f.record(key, body); err != nil {
+		return nil, err
+	}
+	f.cache.put(cveID, body)
 	return &result, nil
 }
 
 // FetchCVEs fetches CVEs with optional filters
 func (f *Fetcher) FetchCVEs(startIndex, resultsPerPage int) (*cve.CVEResponse, error) {
-	if startIndex < 0 {
-		return nil, fmt.Errorf("startIndex must be non-negative")
+	return f.FetchCVEsContext(context.Background(), startIndex, resultsPerPage)
+}
+
+// FetchCVEsContext is FetchCVEs, aborting the request and any backoff when
+// ctx is done
+func (f *Fetcher) FetchCVEsContext(ctx context.Context, startIndex, resultsPerPage int) (*cve.CVEResponse, error) {
+	if err := validatePage(startIndex, resultsPerPage); err != nil {
+		return nil, err
 	}
-	if resultsPerPage < 1 || resultsPerPage > 2000 {
-		return nil, fmt.Errorf("resultsPerPage must be between 1 and 2000")
+	return f.fetchCVEsPage(ctx, cvesFixtureKey(startIndex, resultsPerPage), startIndex, resultsPerPage, nil)
+}
+
+// FetchCVEsModifiedSince fetches a page of the CVEs last modified between
+// since and until, for incremental syncs. A zero until means now. The window
+// must not exceed MaxModifiedWindow, NVD's limit; longer ranges have to be
+// fetched window by window.
+func (f *Fetcher) FetchCVEsModifiedSince(startIndex, resultsPerPage int, since, until time.Time) (*cve.CVEResponse, error) {
+	return f.FetchCVEsModifiedSinceContext(context.Background(), startIndex, resultsPerPage, since, until)
+}
+
+// FetchCVEsModifiedSinceContext is FetchCVEsModifiedSince, aborting the
+// request and any backoff when ctx is done
+func (f *Fetcher) FetchCVEsModifiedSinceContext(ctx context.Context, startIndex, resultsPerPage int, since, until time.Time) (*cve.CVEResponse, error) {
+	if err := validatePage(startIndex, resultsPerPage); err != nil {
+		return nil, err
 	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if err := ValidateModifiedWindow(since, until); err != nil {
+		return nil, err
+	}
+
+	since, until = since.UTC(), until.UTC()
+	key := cvesModifiedFixtureKey(since, until, startIndex, resultsPerPage)
+	return f.fetchCVEsPage(ctx, key, startIndex, resultsPerPage, map[string]string{
+		"lastModStartDate": since.Format(nvdDateLayout),
+		"lastModEndDate":   until.Format(nvdDateLayout),
+	})
+}
 
-	req := f.client.R().
-		SetQueryParam("startIndex", fmt.Sprintf("%d", startIndex)).
-		SetQueryParam("resultsPerPage", fmt.Sprintf("%d", resultsPerPage))
-	if f.apiKey != "" {
-		req.SetHeader("apiKey", f.apiKey)
+// ValidateModifiedWindow checks that since..until is a range the NVD API
+// accepts for lastModStartDate and lastModEndDate
+func ValidateModifiedWindow(since, until time.Time) error {
+	if since.IsZero() {
+		return fmt.Errorf("lastModStartDate is required")
+	}
+	if until.Before(since) {
+		return fmt.Errorf("lastModEndDate %s is before lastModStartDate %s", until.Format(time.RFC3339), since.Format(time.RFC3339))
 	}
+	if window := until.Sub(since); window > MaxModifiedWindow {
+		return fmt.Errorf("modified window of %.1f days exceeds the NVD limit of %d days", window.Hours()/24, int(MaxModifiedWindow.Hours()/24))
+	}
+	return nil
+}
 
-	resp, err := req.Get(f.baseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch CVEs: %w", err)
+// validatePage checks the paging parameters of a CVE list request
+func validatePage(startIndex, resultsPerPage int) error {
+	if startIndex < 0 {
+		return fmt.Errorf("startIndex must be non-negative")
 	}
+	if resultsPerPage < 1 || resultsPerPage > 2000 {
+		return fmt.Errorf("resultsPerPage must be between 1 and 2000")
+	}
+	return nil
+}
 
-	if resp.IsError() {
-		if resp.StatusCode() == 429 {
-			return nil, ErrRateLimited
+// fetchCVEsPage fetches one page of the CVE list with extra query parameters
+func (f *Fetcher) fetchCVEsPage(ctx context.Context, key string, startIndex, resultsPerPage int, params map[string]string) (*cve.CVEResponse, error) {
+	body, err := f.fetch(ctx, key, UpstreamNVD, "CVEs", func() (*resty.Response, error) {
+		req := f.client.R().
+			SetContext(ctx).
+			SetQueryParam("startIndex", fmt.Sprintf("%d", startIndex)).
+			SetQueryParam("resultsPerPage", fmt.Sprintf("%d", resultsPerPage)).
+			SetQueryParams(params)
+		if f.apiKey != "" {
+			req.SetHeader("apiKey", f.apiKey)
 		}
-		return nil, fmt.Errorf("API returned error status: %d", resp.StatusCode())
+		return req.Get(f.baseURL)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	body := resp.Body()
 	var result cve.CVEResponse
 	if err := jsonutil.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal CVE response: %w", err)
 	}
 
+	if err := f.record(key, body); err != nil {
+		return nil, err
+	}
 	return &result, nil
 }
 
+// fetch returns the response body of a request: from its fixture in replay
+// mode, otherwise by sending it to upstream. Each attempt first waits for
+// the rate limiter of upstream, if it has one. A rate-limited request is
+// sent again after a backoff, up to maxAttempts times in all; a
+// *RateLimitError is returned if it is still rate limited. Once ctx is done
+// no further attempt is made and the error of ctx is returned.
+func (f *Fetcher) fetch(ctx context.Context, key, upstream, what string, send func() (*resty.Response, error)) ([]byte, error) {
+	if f.mode == ModeReplay {
+		return f.readFixture(key)
+	}
+
+	limiter := f.limiters[upstream]
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", what, err)
+		}
+		if limiter != nil {
+			if err := limiter.wait(ctx, f.sleep); err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", what, err)
+			}
+		}
+		resp, err := send()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", what, err)
+		}
+
+		if !resp.IsError() {
+			return resp.Body(), nil
+		}
+		if resp.StatusCode() != http.StatusTooManyRequests {
+			return nil, fmt.Errorf("API returned error status: %d", resp.StatusCode())
+		}
+		if attempt >= f.maxAttempts {
+			return nil, &RateLimitError{Attempts: attempt, Waited: waited}
+		}
+
+		wait := f.rateLimitBackoff(attempt, resp.Header().Get("Retry-After"))
+		if err := f.sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", what, err)
+		}
+		waited += wait
+	}
+}
+
+// rateLimitBackoff returns the wait after the given rate-limited attempt:
+// NVD's Retry-After (seconds or an HTTP date) when present, otherwise
+// backoffBase doubled per attempt. Either way it is capped at backoffMax.
+func (f *Fetcher) rateLimitBackoff(attempt int, retryAfter string) time.Duration {
+	wait := f.backoffBase << uint(attempt-1)
+	if wait <= 0 || wait > f.backoffMax {
+		wait = f.backoffMax
+	}
+	if retryAfter != "" {
+		if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+			wait = time.Duration(secs) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			wait = time.Until(at)
+		}
+		if wait < 0 {
+			wait = 0
+		}
+		if wait > f.backoffMax {
+			wait = f.backoffMax
+		}
+	}
+	return wait
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record saves a successful response as a fixture in record mode
+func (f *Fetcher) record(key string, body []byte) error {
+	if f.mode != ModeRecord {
+		return nil
+	}
+	return f.writeFixture(key, body)
+}
+
 // FetchCVEsConcurrent fetches multiple CVE IDs concurrently using a worker pool
 // Principle 11: Worker pool pattern for parallel processing
 func (f *Fetcher) FetchCVEsConcurrent(cveIDs []string, workers int) ([]*cve.CVEResponse, []error) {
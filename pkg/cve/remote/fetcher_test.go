@@ -1,11 +1,18 @@
 package remote
 
 import (
+	"context"
+	"errors"
 	"gorm.io/gorm"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/cve"
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 )
 
@@ -42,16 +49,81 @@ func TestFetchCVEByID_RateLimited(t *testing.T) {
 		}))
 		defer server.Close()
 
-		f := NewFetcher("")
+		f := NewFetcher("", WithRateLimitBackoff(time.Millisecond, time.Millisecond))
 		f.baseURL = server.URL
 		_, err := f.FetchCVEByID("CVE-TEST-2")
-		if err == nil || err != ErrRateLimited {
+		if !errors.Is(err, ErrRateLimited) {
 			t.Fatalf("expected ErrRateLimited, got %v", err)
 		}
 	})
 
 }
 
+func TestFetchCVEs_RateLimitBackoff(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetchCVEs_RateLimitBackoff", nil, func(t *testing.T, tx *gorm.DB) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) < 3 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		f := NewFetcher("", WithRateLimitBackoff(time.Millisecond, 3*time.Millisecond))
+		f.baseURL = server.URL
+		f.sleep = func(ctx context.Context, d time.Duration) error { waits = append(waits, d); return nil }
+
+		// Recovers once the rate limit lifts, doubling the wait
+		if _, err := f.FetchCVEs(0, 10); err != nil {
+			t.Fatalf("expected the third attempt to succeed, got %v", err)
+		}
+		if len(waits) != 2 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+			t.Errorf("unexpected waits %v", waits)
+		}
+
+		// Gives up after the last attempt with the attempts and total wait
+		atomic.StoreInt32(&requests, -100)
+		waits = nil
+		f.maxAttempts = 3
+		_, err := f.FetchCVEs(0, 10)
+		var rateErr *RateLimitError
+		if !errors.As(err, &rateErr) || !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected a RateLimitError, got %v", err)
+		}
+		if rateErr.Attempts != 3 || rateErr.Waited != 3*time.Millisecond || atomic.LoadInt32(&requests) != -97 {
+			t.Errorf("unexpected %+v after %d requests", rateErr, atomic.LoadInt32(&requests)+100)
+		}
+	})
+}
+
+func TestFetcher_RateLimitBackoffRetryAfter(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetcher_RateLimitBackoffRetryAfter", nil, func(t *testing.T, tx *gorm.DB) {
+		f := NewFetcher("", WithRateLimitBackoff(time.Second, 10*time.Second))
+		cases := []struct {
+			attempt    int
+			retryAfter string
+			want       time.Duration
+		}{
+			{1, "", time.Second},
+			{3, "", 4 * time.Second},
+			{10, "", 10 * time.Second},
+			{1, "5", 5 * time.Second},
+			{1, "3600", 10 * time.Second},
+			{1, time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+			{2, "soon", 2 * time.Second},
+		}
+		for _, c := range cases {
+			if got := f.rateLimitBackoff(c.attempt, c.retryAfter); got != c.want {
+				t.Errorf("rateLimitBackoff(%d, %q) = %v, want %v", c.attempt, c.retryAfter, got, c.want)
+			}
+		}
+	})
+}
+
 func TestFetchCVEs_ParamValidation(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestFetchCVEs_ParamValidation", nil, func(t *testing.T, tx *gorm.DB) {
 		f := NewFetcher("")
@@ -146,6 +218,58 @@ func TestFetchCVEs_Success(t *testing.T) {
 	})
 }
 
+func TestFetchCVEsModifiedSince(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestFetchCVEsModifiedSince", nil, func(t *testing.T, tx *gorm.DB) {
+		var query url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+		}))
+		defer server.Close()
+
+		f := NewFetcher("")
+		f.baseURL = server.URL
+		since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+		until := since.Add(30 * 24 * time.Hour)
+		if _, err := f.FetchCVEsModifiedSince(200, 100, since, until); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]string{
+			"startIndex":       "200",
+			"resultsPerPage":   "100",
+			"lastModStartDate": "2024-03-01T11:00:00.000+00:00",
+			"lastModEndDate":   "2024-03-31T11:00:00.000+00:00",
+		}
+		for k, v := range want {
+			if got := query.Get(k); got != v {
+				t.Errorf("query %s = %q, want %q", k, got, v)
+			}
+		}
+
+		// Without until the window ends now
+		if _, err := f.FetchCVEsModifiedSince(0, 10, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if query.Get("lastModEndDate") == "" {
+			t.Error("expected lastModEndDate to be set")
+		}
+
+		for name, window := range map[string][2]time.Time{
+			"no since":      {{}, until},
+			"reversed":      {until, since},
+			"over 120 days": {since, since.Add(121 * 24 * time.Hour)},
+		} {
+			if _, err := f.FetchCVEsModifiedSince(0, 10, window[0], window[1]); err == nil {
+				t.Errorf("%s: expected the window to be rejected", name)
+			}
+		}
+		if err := ValidateModifiedWindow(since, since.Add(MaxModifiedWindow)); err != nil {
+			t.Errorf("expected a window of exactly 120 days to be accepted, got %v", err)
+		}
+	})
+}
+
 func TestFetchCVEs_StatusCodeError(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestFetchCVEs_StatusCodeError", nil, func(t *testing.T, tx *gorm.DB) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -223,3 +347,124 @@ func TestFetcher_NetworkError(t *testing.T) {
 		}
 	})
 }
+
+func TestWithBaseURL(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWithBaseURL", nil, func(t *testing.T, tx *gorm.DB) {
+		if got := NewFetcher("").BaseURL(); got != cve.NVDAPIURL {
+			t.Errorf("expected the public NVD API by default, got %s", got)
+		}
+		if got := NewFetcher("", WithBaseURL("")).BaseURL(); got != cve.NVDAPIURL {
+			t.Errorf("expected an empty base URL to keep the default, got %s", got)
+		}
+
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+		}))
+		defer server.Close()
+
+		// Every fetch method goes to the configured mirror
+		f := NewFetcher("", WithBaseURL(server.URL+"/nvd/cves"))
+		if _, err := f.FetchCVEByID("CVE-TEST-1"); err != nil {
+			t.Fatalf("FetchCVEByID failed: %v", err)
+		}
+		if _, err := f.FetchCVEs(0, 10); err != nil {
+			t.Fatalf("FetchCVEs failed: %v", err)
+		}
+		if len(paths) != 2 || paths[0] != "/nvd/cves" || paths[1] != "/nvd/cves" {
+			t.Errorf("expected both requests sent to the mirror, got %v", paths)
+		}
+
+		for _, raw := range []string{"https://nvd.mirror.internal/rest/json/cves/2.0", "http://127.0.0.1:8080"} {
+			if _, err := ParseBaseURL(raw); err != nil {
+				t.Errorf("ParseBaseURL(%q) failed: %v", raw, err)
+			}
+		}
+		for _, raw := range []string{"nvd.mirror.internal", "ftp://mirror/cves", "http://", "://bad"} {
+			if _, err := ParseBaseURL(raw); err == nil {
+				t.Errorf("ParseBaseURL(%q) expected an error", raw)
+			}
+		}
+	})
+}
+
+func TestFetchCVEsContext_Cancel(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestFetchCVEsContext_Cancel", nil, func(t *testing.T, tx *gorm.DB) {
+		// The request in flight is aborted: the server only returns once the
+		// client went away
+		hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer hang.Close()
+
+		f := NewFetcher("")
+		f.baseURL = hang.URL
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := f.FetchCVEsContext(ctx, 0, 10); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("cancellation took %v", elapsed)
+		}
+
+		// The backoff after a rate-limited attempt is cut short as well
+		var requests int32
+		limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer limited.Close()
+
+		f = NewFetcher("", WithRateLimitBackoff(time.Hour, time.Hour))
+		f.baseURL = limited.URL
+		ctx, cancel = context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		if _, err := f.FetchCVEsModifiedSinceContext(ctx, 0, 10, time.Now().Add(-time.Hour), time.Time{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled during backoff, got %v", err)
+		}
+		if n := atomic.LoadInt32(&requests); n != 1 {
+			t.Errorf("expected no request after cancellation, got %d", n)
+		}
+	})
+}
+
+func TestFetcher_ReusesConnections(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestFetcher_ReusesConnections", nil, func(t *testing.T, tx *gorm.DB) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("cveId") {
+			case "CVE-FAIL":
+				http.Error(w, "internal error with a body to drain", http.StatusInternalServerError)
+			case "CVE-LIMITED":
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+			}
+		}))
+		var conns int32
+		server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		}
+		server.Start()
+		defer server.Close()
+
+		f := NewFetcher("", WithBaseURL(server.URL), WithRateLimitMaxAttempts(1))
+		// Error responses must be drained and closed too, or each one would
+		// leave its connection unusable
+		for _, id := range []string{"CVE-TEST-1", "CVE-FAIL", "CVE-TEST-1", "CVE-LIMITED", "CVE-FAIL", "CVE-TEST-1"} {
+			_, err := f.FetchCVEByID(id)
+			if (err != nil) != (id != "CVE-TEST-1") {
+				t.Fatalf("FetchCVEByID(%s) error = %v", id, err)
+			}
+		}
+		if got := atomic.LoadInt32(&conns); got != 1 {
+			t.Errorf("Expected sequential requests to share 1 connection, opened %d", got)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FetcherMode selects where a Fetcher gets NVD responses from
+type FetcherMode string
+
+const (
+	// ModeLive fetches from the NVD API (default)
+	ModeLive FetcherMode = "live"
+	// ModeRecord fetches from the NVD API and saves every successful
+	// response as a fixture
+	ModeRecord FetcherMode = "record"
+	// ModeReplay serves responses from fixtures and never touches the network
+	ModeReplay FetcherMode = "replay"
+)
+
+const (
+	// EnvFetcherMode selects the mode of new fetchers: live, record or replay
+	EnvFetcherMode = "FETCHER_MODE"
+	// EnvFetcherFixturesDir overrides the fixtures directory
+	EnvFetcherFixturesDir = "FETCHER_FIXTURES_DIR"
+	// DefaultFixturesDir is where fixtures are recorded and replayed from
+	DefaultFixturesDir = "fixtures/nvd"
+)
+
+// ErrFixtureNotFound is returned in replay mode when no fixture was recorded
+// for a request
+var ErrFixtureNotFound = errors.New("NVD fixture not found")
+
+// ParseFetcherMode parses a mode name; empty means live
+func ParseFetcherMode(s string) (FetcherMode, error) {
+	switch mode := FetcherMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return ModeLive, nil
+	case ModeLive, ModeRecord, ModeReplay:
+		return mode, nil
+	default:
+		return ModeLive, fmt.Errorf("invalid fetcher mode %q: must be live, record or replay", s)
+	}
+}
+
+// fetcherConfigFromEnv returns the mode and fixtures directory configured by
+// the environment. An invalid mode falls back to live.
+func fetcherConfigFromEnv() (FetcherMode, string) {
+	mode, _ := ParseFetcherMode(os.Getenv(EnvFetcherMode))
+	dir := os.Getenv(EnvFetcherFixturesDir)
+	if dir == "" {
+		dir = DefaultFixturesDir
+	}
+	return mode, dir
+}
+
+// Fixture layout, relative to the fixtures directory:
+//
+//	cve/<CVE ID>.json                                  FetchCVEByID
+//	cves/start-<startIndex>_count-<resultsPerPage>.json FetchCVEs
+//	cves-modified/<since>_<until>_start-<startIndex>_count-<resultsPerPage>.json
+//	                                                   FetchCVEsModifiedSince
+//	epss/<CVE ID>.json                                 FetchEPSS
+//	kev/known_exploited_vulnerabilities.json           FetchKEV
+//
+// Each file holds the raw NVD (or FIRST EPSS, or CISA KEV) response body, so fixtures can
+// be committed and inspected as-is.
+
+// cveFixtureKey is the fixture of FetchCVEByID
+func cveFixtureKey(cveID string) (string, error) {
+	if strings.ContainsAny(cveID, `/\`) || strings.Contains(cveID, "..") {
+		return "", fmt.Errorf("invalid CVE ID for fixture: %q", cveID)
+	}
+	return filepath.Join("cve", cveID+".json"), nil
+}
+
+// cvesFixtureKey is the fixture of FetchCVEs
+func cvesFixtureKey(startIndex, resultsPerPage int) string {
+	return filepath.Join("cves", fmt.Sprintf("start-%d_count-%d.json", startIndex, resultsPerPage))
+}
+
+// SetMode changes the fetcher's mode and fixtures directory (DefaultFixturesDir
+// if empty)
+func (f *Fetcher) SetMode(mode FetcherMode, fixturesDir string) {
+	if fixturesDir == "" {
+		fixturesDir = DefaultFixturesDir
+	}
+	f.mode = mode
+	f.fixturesDir = fixturesDir
+}
+
+// Mode returns the fetcher's mode
+func (f *Fetcher) Mode() FetcherMode {
+	return f.mode
+}
+
+// FixturesDir returns the directory fixtures are recorded to and replayed from
+func (f *Fetcher) FixturesDir() string {
+	return f.fixturesDir
+}
+
+// readFixture returns the recorded response for key
+func (f *Fetcher) readFixture(key string) ([]byte, error) {
+	path := filepath.Join(f.fixturesDir, key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s (record it with %s=%s)", ErrFixtureNotFound, path, EnvFetcherMode, ModeRecord)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// writeFixture saves a response under key. The file is written atomically
+// so a concurrent replay never reads a partial fixture.
+func (f *Fetcher) writeFixture(key string, body []byte) error {
+	path := filepath.Join(f.fixturesDir, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return fmt.Errorf("failed to record fixture %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record fixture %s: %w", path, err)
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record fixture %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record fixture %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to record fixture %s: %w", path, err)
+	}
+	return nil
+}
+
+// cvesModifiedFixtureKey is the fixture of FetchCVEsModifiedSince; the
+// window bounds are Unix seconds
+func cvesModifiedFixtureKey(since, until time.Time, startIndex, resultsPerPage int) string {
+	return filepath.Join("cves-modified", fmt.Sprintf("%d_%d_start-%d_count-%d.json", since.Unix(), until.Unix(), startIndex, resultsPerPage))
+}
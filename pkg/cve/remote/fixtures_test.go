@@ -0,0 +1,101 @@
+package remote
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestFetcher_RecordThenReplay(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestFetcher_RecordThenReplay", nil, func(t *testing.T, tx *gorm.DB) {
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			if r.URL.Query().Get("startIndex") == "5" {
+				w.WriteHeader(429)
+				return
+			}
+			id := r.URL.Query().Get("cveId")
+			if id == "" {
+				id = "CVE-PAGE-1"
+			}
+			w.Write(testutils.MakeCVEResponseJSON(id, 1))
+		}))
+		defer server.Close()
+
+		dir := t.TempDir()
+		recorder := NewFetcher("", WithRateLimitMaxAttempts(1))
+		recorder.baseURL = server.URL
+		recorder.SetMode(ModeRecord, dir)
+		if _, err := recorder.FetchCVEByID("CVE-2021-44228"); err != nil {
+			t.Fatalf("Record FetchCVEByID failed: %v", err)
+		}
+		if _, err := recorder.FetchCVEs(0, 10); err != nil {
+			t.Fatalf("Record FetchCVEs failed: %v", err)
+		}
+		if _, err := recorder.FetchCVEs(5, 10); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected the rate limit to pass through, got %v", err)
+		}
+
+		for _, name := range []string{"cve/CVE-2021-44228.json", "cves/start-0_count-10.json"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("Expected fixture %s: %v", name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "cves/start-5_count-10.json")); err == nil {
+			t.Error("Expected the failed response not to be recorded")
+		}
+
+		// Replay never reaches the server
+		before := hits.Load()
+		replayer := NewFetcher("")
+		replayer.baseURL = server.URL
+		replayer.SetMode(ModeReplay, dir)
+		resp, err := replayer.FetchCVEByID("CVE-2021-44228")
+		if err != nil || len(resp.Vulnerabilities) != 1 || resp.Vulnerabilities[0].CVE.ID != "CVE-2021-44228" {
+			t.Fatalf("Unexpected replayed response %+v (%v)", resp, err)
+		}
+		if resp, err := replayer.FetchCVEs(0, 10); err != nil || resp.Vulnerabilities[0].CVE.ID != "CVE-PAGE-1" {
+			t.Fatalf("Unexpected replayed page %+v (%v)", resp, err)
+		}
+		if hits.Load() != before {
+			t.Errorf("Replay mode sent %d requests", hits.Load()-before)
+		}
+
+		_, err = replayer.FetchCVEByID("CVE-2024-0001")
+		if !errors.Is(err, ErrFixtureNotFound) || !strings.Contains(err.Error(), filepath.Join(dir, "cve", "CVE-2024-0001.json")) {
+			t.Errorf("Expected a missing fixture error naming the file, got %v", err)
+		}
+	})
+}
+
+func TestFetcher_FixtureKeysRejectPaths(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetcher_FixtureKeysRejectPaths", nil, func(t *testing.T, tx *gorm.DB) {
+		f := NewFetcher("")
+		f.SetMode(ModeReplay, t.TempDir())
+		if _, err := f.FetchCVEByID("../../etc/passwd"); err == nil || errors.Is(err, ErrFixtureNotFound) {
+			t.Errorf("Expected a path-like CVE ID to be rejected, got %v", err)
+		}
+	})
+}
+
+func TestParseFetcherMode(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseFetcherMode", nil, func(t *testing.T, tx *gorm.DB) {
+		for in, want := range map[string]FetcherMode{"": ModeLive, "live": ModeLive, "Record": ModeRecord, " replay ": ModeReplay} {
+			if got, err := ParseFetcherMode(in); err != nil || got != want {
+				t.Errorf("ParseFetcherMode(%q) = %v, %v; want %v", in, got, err, want)
+			}
+		}
+		if mode, err := ParseFetcherMode("playback"); err == nil || mode != ModeLive {
+			t.Errorf("Expected an invalid mode to fail and fall back to live, got %v, %v", mode, err)
+		}
+	})
+}
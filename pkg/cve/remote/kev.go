@@ -0,0 +1,60 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/go-resty/resty/v2"
+)
+
+// kevFixtureKey is the fixture of FetchKEV
+var kevFixtureKey = filepath.Join("kev", "known_exploited_vulnerabilities.json")
+
+// WithKEVFeedURL sets the CISA KEV feed URL, e.g. a mirror or a test server;
+// empty keeps the CISA feed
+func WithKEVFeedURL(feedURL string) FetcherOption {
+	return func(f *Fetcher) {
+		if feedURL != "" {
+			f.kevURL = feedURL
+		}
+	}
+}
+
+// KEVFeedURL returns the URL the KEV catalog is fetched from
+func (f *Fetcher) KEVFeedURL() string {
+	return f.kevURL
+}
+
+// FetchKEV fetches the CISA Known Exploited Vulnerabilities catalog. The feed
+// is a single document of all listed CVEs, about a megabyte.
+func (f *Fetcher) FetchKEV() (*cve.KEVCatalog, error) {
+	return f.FetchKEVContext(context.Background())
+}
+
+// FetchKEVContext is FetchKEV, aborting the request and any backoff when ctx
+// is done
+func (f *Fetcher) FetchKEVContext(ctx context.Context) (*cve.KEVCatalog, error) {
+	body, err := f.fetch(ctx, kevFixtureKey, UpstreamKEV, "KEV catalog", func() (*resty.Response, error) {
+		return f.client.R().
+			SetContext(ctx).
+			Get(f.kevURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog cve.KEVCatalog
+	if err := jsonutil.Unmarshal(body, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal KEV catalog: %w", err)
+	}
+	if catalog.Vulnerabilities == nil {
+		return nil, fmt.Errorf("invalid KEV catalog: no vulnerabilities list")
+	}
+	if err := f.record(kevFixtureKey, body); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
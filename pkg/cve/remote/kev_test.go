@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestFetchKEV(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetchKEV", nil, func(t *testing.T, tx *gorm.DB) {
+		if got := NewFetcher("").KEVFeedURL(); got != cve.KEVFeedURL {
+			t.Errorf("expected the CISA feed by default, got %s", got)
+		}
+
+		body := `{"title":"CISA Catalog of Known Exploited Vulnerabilities","catalogVersion":"2024.03.01","dateReleased":"2024-03-01T15:00:00.000Z","count":1,
+			"vulnerabilities":[{"cveID":"CVE-2021-44228","vendorProject":"Apache","product":"Log4j2","vulnerabilityName":"Apache Log4j2 Remote Code Execution Vulnerability","dateAdded":"2021-12-10","shortDescription":"JNDI features do not protect against attacker-controlled endpoints.","requiredAction":"Apply updates per vendor instructions.","dueDate":"2021-12-24","knownRansomwareCampaignUse":"Known","notes":""}]}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/kev.json":
+				w.Write([]byte(body))
+			case "/empty.json":
+				w.Write([]byte(`{"title":"not a catalog"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		catalog, err := NewFetcher("", WithKEVFeedURL(server.URL+"/kev.json")).FetchKEV()
+		if err != nil {
+			t.Fatalf("FetchKEV failed: %v", err)
+		}
+		if catalog.CatalogVersion != "2024.03.01" || len(catalog.Vulnerabilities) != 1 {
+			t.Fatalf("Unexpected catalog %+v", catalog)
+		}
+		entry := catalog.Vulnerabilities[0]
+		if entry.CVEID != "CVE-2021-44228" || entry.DueDate != "2021-12-24" || entry.DateAdded != "2021-12-10" || entry.KnownRansomwareCampaignUse != "Known" {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+
+		if _, err := NewFetcher("", WithKEVFeedURL(server.URL+"/empty.json")).FetchKEV(); err == nil {
+			t.Error("Expected an error for a document without vulnerabilities")
+		}
+		if _, err := NewFetcher("", WithKEVFeedURL(server.URL+"/missing.json")).FetchKEV(); err == nil {
+			t.Error("Expected an error for a failed request")
+		}
+	})
+}
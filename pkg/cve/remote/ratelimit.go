@@ -0,0 +1,190 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upstreams a fetcher sends requests to. Rate limits are set per upstream,
+// so every request to one upstream shares its limiter whichever run or
+// handler sends it.
+const (
+	UpstreamNVD  = "nvd"
+	UpstreamEPSS = "epss"
+	UpstreamKEV  = "kev"
+)
+
+const (
+	// EnvNVDRateLimit overrides the NVD rate limit, as a number of requests
+	// per NVDRateWindow ("50") or per a Go duration ("50/30s"); "0" disables
+	// the limiter, e.g. for a mirror without quota
+	EnvNVDRateLimit = "NVD_RATE_LIMIT"
+	// NVDRateWindow is the rolling window of NVD's published rate limits
+	NVDRateWindow = 30 * time.Second
+	// NVDRateLimitWithKey is the number of requests NVD allows per window
+	// with an API key
+	NVDRateLimitWithKey = 50
+	// NVDRateLimitWithoutKey is the number of requests NVD allows per window
+	// without an API key
+	NVDRateLimitWithoutKey = 5
+)
+
+// DefaultNVDRateLimit returns NVD's published limit, in requests per
+// NVDRateWindow, with or without an API key
+func DefaultNVDRateLimit(hasAPIKey bool) int {
+	if hasAPIKey {
+		return NVDRateLimitWithKey
+	}
+	return NVDRateLimitWithoutKey
+}
+
+// ParseRateLimit parses a rate limit given in configuration: a number of
+// requests per NVDRateWindow, or requests per a Go duration such as
+// "50/30s". A limit of 0 means no limit.
+func ParseRateLimit(raw string) (int, time.Duration, error) {
+	count, window := strings.TrimSpace(raw), NVDRateWindow
+	if i := strings.Index(count, "/"); i >= 0 {
+		d, err := time.ParseDuration(strings.TrimSpace(count[i+1:]))
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("invalid rate limit %q: window must be a positive duration", raw)
+		}
+		count, window = strings.TrimSpace(count[:i]), d
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: must be a non-negative number of requests", raw)
+	}
+	return limit, window, nil
+}
+
+// RateLimiterStats reports the state of the rate limiter of an upstream
+type RateLimiterStats struct {
+	Upstream string `json:"upstream"`
+	// Limit is the number of requests allowed per window, also the most
+	// that can be sent in a burst
+	Limit         int     `json:"limit"`
+	WindowSeconds float64 `json:"window_seconds"`
+	// Available is the number of requests that can be sent right now
+	Available int `json:"available"`
+	// Waiting is the number of requests waiting for their turn
+	Waiting int `json:"waiting"`
+	// Throttled counts the requests that had to wait since the start
+	Throttled int64 `json:"throttled"`
+}
+
+// rateLimiter is a sliding-window log allowing at most limit requests in
+// any window. Each request records the time it is sent; when limit requests
+// were sent within the last window it reserves the earliest time the oldest
+// of them leaves the window and waits for it, so waiting requests go in
+// order and no rolling window ever sees more than limit requests.
+type rateLimiter struct {
+	upstream string
+	limit    int
+	window   time.Duration
+
+	mu sync.Mutex
+	// sent holds the send times of the requests within the last window,
+	// ascending, including the future times reserved by waiting requests
+	sent      []time.Time
+	waiting   int
+	throttled int64
+	now       func() time.Time
+}
+
+func newRateLimiter(upstream string, limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{upstream: upstream, limit: limit, window: window, now: time.Now}
+}
+
+// prune drops the send times that have left the window (caller must hold
+// lock)
+func (l *rateLimiter) prune(now time.Time) {
+	n := 0
+	for n < len(l.sent) && !l.sent[n].Add(l.window).After(now) {
+		n++
+	}
+	l.sent = l.sent[n:]
+}
+
+// wait reserves the next send time, sleeping until it comes. If ctx is done
+// first the reservation is given back and the error of ctx returned.
+func (l *rateLimiter) wait(ctx context.Context, sleep func(ctx context.Context, d time.Duration) error) error {
+	l.mu.Lock()
+	now := l.now()
+	l.prune(now)
+	at := now
+	if n := len(l.sent); n > 0 && l.sent[n-1].After(at) {
+		at = l.sent[n-1]
+	}
+	if n := len(l.sent); n >= l.limit {
+		if next := l.sent[n-l.limit].Add(l.window); next.After(at) {
+			at = next
+		}
+	}
+	l.sent = append(l.sent, at)
+	if !at.After(now) {
+		l.mu.Unlock()
+		return nil
+	}
+	l.waiting++
+	l.throttled++
+	l.mu.Unlock()
+
+	err := sleep(ctx, at.Sub(now))
+
+	l.mu.Lock()
+	l.waiting--
+	if err != nil {
+		for i := len(l.sent) - 1; i >= 0; i-- {
+			if l.sent[i].Equal(at) {
+				l.sent = append(l.sent[:i], l.sent[i+1:]...)
+				break
+			}
+		}
+	}
+	l.mu.Unlock()
+	return err
+}
+
+func (l *rateLimiter) stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(l.now())
+	return RateLimiterStats{
+		Upstream:      l.upstream,
+		Limit:         l.limit,
+		WindowSeconds: l.window.Seconds(),
+		Available:     max(0, l.limit-len(l.sent)),
+		Waiting:       l.waiting,
+		Throttled:     l.throttled,
+	}
+}
+
+// WithRateLimit limits the requests of the fetcher to upstream to limit per
+// window, shared by all its callers; a limit of 0 removes the limit. By
+// default NVD requests are limited to NVD's published limit (see
+// DefaultNVDRateLimit) and other upstreams are not limited.
+func WithRateLimit(upstream string, limit int, window time.Duration) FetcherOption {
+	return func(f *Fetcher) {
+		if limit <= 0 || window <= 0 {
+			delete(f.limiters, upstream)
+			return
+		}
+		f.limiters[upstream] = newRateLimiter(upstream, limit, window)
+	}
+}
+
+// RateLimiterStats returns the state of the rate limiter of each limited
+// upstream, ordered by upstream
+func (f *Fetcher) RateLimiterStats() []RateLimiterStats {
+	stats := make([]RateLimiterStats, 0, len(f.limiters))
+	for _, l := range f.limiters {
+		stats = append(stats, l.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Upstream < stats[j].Upstream })
+	return stats
+}
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRateLimiter_Wait", nil, func(t *testing.T, tx *gorm.DB) {
+		now := time.Unix(1700000000, 0)
+		l := newRateLimiter(UpstreamNVD, 5, 30*time.Second)
+		l.now = func() time.Time { return now }
+		var waits []time.Duration
+		sleep := func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			now = now.Add(d)
+			return nil
+		}
+		ctx := context.Background()
+
+		// A burst up to the limit goes through, then the next request waits
+		// for the burst to leave the window
+		for i := 0; i < 7; i++ {
+			if err := l.wait(ctx, sleep); err != nil {
+				t.Fatalf("wait failed: %v", err)
+			}
+		}
+		if len(waits) != 1 || waits[0] != 30*time.Second {
+			t.Errorf("Expected one wait of 30s, got %v", waits)
+		}
+		if s := l.stats(); s.Available != 3 || s.Throttled != 1 || s.Waiting != 0 {
+			t.Errorf("Unexpected stats %+v", s)
+		}
+
+		// Requests leave the window as time passes
+		now = now.Add(time.Hour)
+		if s := l.stats(); s.Available != 5 || s.Limit != 5 || s.WindowSeconds != 30 {
+			t.Errorf("Expected the whole limit available, got %+v", s)
+		}
+
+		// A cancelled wait gives its reservation back
+		for i := 0; i < 5; i++ {
+			l.wait(ctx, sleep)
+		}
+		cancelled := func(ctx context.Context, d time.Duration) error { return context.Canceled }
+		if err := l.wait(ctx, cancelled); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if len(l.sent) != 5 {
+			t.Errorf("Expected the reservation of the cancelled wait dropped, got %v", l.sent)
+		}
+		now = now.Add(30 * time.Second)
+		if s := l.stats(); s.Available != 5 || s.Waiting != 0 {
+			t.Errorf("Expected the whole limit available again, got %+v", s)
+		}
+	})
+}
+
+func TestRateLimiter_NoWindowExceedsLimit(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRateLimiter_NoWindowExceedsLimit", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, limit := range []int{NVDRateLimitWithoutKey, NVDRateLimitWithKey} {
+			now := time.Unix(1700000000, 0)
+			l := newRateLimiter(UpstreamNVD, limit, NVDRateWindow)
+			l.now = func() time.Time { return now }
+			sleep := func(ctx context.Context, d time.Duration) error {
+				now = now.Add(d)
+				return nil
+			}
+			cancelled := func(ctx context.Context, d time.Duration) error { return context.Canceled }
+
+			// Bursts, idle gaps and cancelled waits mixed, recording the time
+			// each request is sent
+			var sent []time.Time
+			for i := 0; i < 20*limit; i++ {
+				now = now.Add(time.Duration(i%7) * 700 * time.Millisecond)
+				if i%11 == 0 {
+					l.wait(context.Background(), cancelled)
+				}
+				if err := l.wait(context.Background(), sleep); err != nil {
+					t.Fatalf("wait failed: %v", err)
+				}
+				sent = append(sent, now)
+			}
+			for i := 0; i+limit < len(sent); i++ {
+				if d := sent[i+limit].Sub(sent[i]); d < NVDRateWindow {
+					t.Fatalf("Limit %d: requests %d to %d were sent within %v", limit, i, i+limit, d)
+				}
+			}
+		}
+	})
+}
+
+func TestFetcher_RateLimitSharedByRequests(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFetcher_RateLimitSharedByRequests", nil, func(t *testing.T, tx *gorm.DB) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(testutils.MakeCVEResponseJSON("CVE-TEST-1", 1))
+		}))
+		defer server.Close()
+
+		if s := NewFetcher("").RateLimiterStats(); len(s) != 1 || s[0].Upstream != UpstreamNVD || s[0].Limit != NVDRateLimitWithoutKey {
+			t.Errorf("Expected the keyless NVD limit by default, got %+v", s)
+		}
+		if s := NewFetcher("key").RateLimiterStats(); len(s) != 1 || s[0].Limit != NVDRateLimitWithKey {
+			t.Errorf("Expected the NVD limit with a key, got %+v", s)
+		}
+		if s := NewFetcher("", WithRateLimit(UpstreamNVD, 0, 0)).RateLimiterStats(); len(s) != 0 {
+			t.Errorf("Expected the limit removed, got %+v", s)
+		}
+
+		// CVE lookups and list pages draw from the same NVD bucket
+		f := NewFetcher("", WithRateLimit(UpstreamNVD, 2, time.Hour), WithCVECacheTTL(0))
+		f.baseURL = server.URL
+		if _, err := f.FetchCVEByID("CVE-TEST-1"); err != nil {
+			t.Fatalf("FetchCVEByID failed: %v", err)
+		}
+		if _, err := f.FetchCVEs(0, 10); err != nil {
+			t.Fatalf("FetchCVEs failed: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := f.FetchCVEByIDContext(ctx, "CVE-TEST-1"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the third request to wait past its deadline, got %v", err)
+		}
+		if n := atomic.LoadInt32(&requests); n != 2 {
+			t.Errorf("Expected 2 requests sent, got %d", n)
+		}
+		if s := f.RateLimiterStats(); s[0].Available != 0 || s[0].Throttled != 1 {
+			t.Errorf("Unexpected stats %+v", s)
+		}
+
+		// The limiter waits through the fetcher's sleep, like the backoff
+		var waits []time.Duration
+		f.sleep = func(ctx context.Context, d time.Duration) error { waits = append(waits, d); return nil }
+		if _, err := f.FetchCVEByID("CVE-TEST-1"); err != nil {
+			t.Fatalf("FetchCVEByID failed: %v", err)
+		}
+		if len(waits) != 1 || waits[0] <= 0 {
+			t.Errorf("Expected one limiter wait through f.sleep, got %v", waits)
+		}
+	})
+}
+
+func TestParseRateLimit(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseRateLimit", nil, func(t *testing.T, tx *gorm.DB) {
+		for raw, want := range map[string]struct {
+			limit  int
+			window time.Duration
+		}{
+			"50":       {50, NVDRateWindow},
+			" 0 ":      {0, NVDRateWindow},
+			"100/1m":   {100, time.Minute},
+			"10 / 10s": {10, 10 * time.Second},
+		} {
+			limit, window, err := ParseRateLimit(raw)
+			if err != nil || limit != want.limit || window != want.window {
+				t.Errorf("ParseRateLimit(%q) = %d, %s, %v", raw, limit, window, err)
+			}
+		}
+		for _, raw := range []string{"", "-1", "fast", "5/0s", "5/soon"} {
+			if _, _, err := ParseRateLimit(raw); err == nil {
+				t.Errorf("Expected an error for %q", raw)
+			}
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package cve
+
+import "strings"
+
+// CVE status values derived locally from the NVD vulnStatus and cveTags.
+// They are stored alongside each record so list queries can filter on them.
+const (
+	// StatusActive is a normal, published CVE
+	StatusActive = "active"
+	// StatusRejected marks a CVE that NVD has rejected (vulnStatus "Rejected")
+	StatusRejected = "rejected"
+	// StatusDisputed marks a CVE tagged "disputed" by a CNA
+	StatusDisputed = "disputed"
+)
+
+// DeriveStatus computes the local status of a CVE. Rejection takes precedence
+// over a dispute tag since a rejected CVE should not be treated as valid at all.
+func DeriveStatus(item *CVEItem) string {
+	if item == nil {
+		return StatusActive
+	}
+	if strings.EqualFold(item.VulnStatus, "Rejected") {
+		return StatusRejected
+	}
+	for _, tag := range item.CVETags {
+		for _, t := range tag.Tags {
+			if strings.EqualFold(t, "disputed") {
+				return StatusDisputed
+			}
+		}
+	}
+	return StatusActive
+}
@@ -0,0 +1,45 @@
+package cve
+
+import (
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestDeriveStatus(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDeriveStatus", nil, func(t *testing.T, tx *gorm.DB) {
+		tests := []struct {
+			name string
+			item *CVEItem
+			want string
+		}{
+			{name: "nil item", item: nil, want: StatusActive},
+			{name: "analyzed", item: &CVEItem{VulnStatus: "Analyzed"}, want: StatusActive},
+			{name: "rejected", item: &CVEItem{VulnStatus: "Rejected"}, want: StatusRejected},
+			{
+				name: "disputed tag",
+				item: &CVEItem{VulnStatus: "Modified", CVETags: []CVETag{{SourceIdentifier: "cve@mitre.org", Tags: []string{"disputed"}}}},
+				want: StatusDisputed,
+			},
+			{
+				name: "rejected wins over disputed",
+				item: &CVEItem{VulnStatus: "Rejected", CVETags: []CVETag{{Tags: []string{"disputed"}}}},
+				want: StatusRejected,
+			},
+			{
+				name: "unrelated tag",
+				item: &CVEItem{VulnStatus: "Analyzed", CVETags: []CVETag{{Tags: []string{"unsupported-when-assigned"}}}},
+				want: StatusActive,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := DeriveStatus(tt.item); got != tt.want {
+					t.Errorf("DeriveStatus() = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+}
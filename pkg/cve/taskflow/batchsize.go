@@ -0,0 +1,141 @@
+package taskflow
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run parameters bounding the adaptive batch size of CVE runs
+const (
+	// ParamMinBatchSize is the smallest batch a run shrinks to after
+	// timeouts and rate limits (default: a quarter of results_per_batch)
+	ParamMinBatchSize = "min_batch_size"
+	// ParamMaxBatchSize is the largest batch a run grows to after fast
+	// fetches (default: maxCVEBatchSize)
+	ParamMaxBatchSize = "max_batch_size"
+)
+
+const (
+	// maxCVEBatchSize is the largest page the NVD API returns
+	maxCVEBatchSize = 2000
+	// batchGrowAfter is the number of consecutive fast fetches after which
+	// the batch size doubles
+	batchGrowAfter = 3
+	// fastFetchDuration is the longest a successful fetch may take to count
+	// as fast
+	fastFetchDuration = 5 * time.Second
+)
+
+// adaptiveBatch tunes the batch size of a run within bounds: it doubles after
+// batchGrowAfter consecutive fast fetches and halves after a timeout or rate
+// limit
+type adaptiveBatch struct {
+	mu       sync.Mutex
+	size     int
+	min, max int
+	fast     int // Consecutive fast fetches since the last change
+}
+
+// newAdaptiveBatch returns the adaptive batch size of a run, bounded by its
+// params. It starts from the size recorded on the run's progress, so a
+// resumed run carries on where it paused, or else from results_per_batch.
+func newAdaptiveBatch(run *JobRun) (*adaptiveBatch, error) {
+	b := &adaptiveBatch{min: max(1, run.ResultsPerBatch/4), max: maxCVEBatchSize}
+	var err error
+	if b.min, err = batchSizeParam(run.Params, ParamMinBatchSize, b.min); err != nil {
+		return nil, err
+	}
+	if b.max, err = batchSizeParam(run.Params, ParamMaxBatchSize, b.max); err != nil {
+		return nil, err
+	}
+	if b.min > b.max {
+		return nil, fmt.Errorf("%s %d is above %s %d", ParamMinBatchSize, b.min, ParamMaxBatchSize, b.max)
+	}
+
+	b.size = run.ResultsPerBatch
+	if progress, ok := run.Progress[run.DataType]; ok && progress.BatchSize > 0 {
+		b.size = progress.BatchSize
+	}
+	b.size = b.clamp(b.size)
+	return b, nil
+}
+
+// clamp bounds a batch size by the minimum and maximum
+func (b *adaptiveBatch) clamp(size int) int {
+	if size < b.min {
+		return b.min
+	}
+	if size > b.max {
+		return b.max
+	}
+	return size
+}
+
+// batchSizeParam returns a positive integer run parameter, or def if unset
+func batchSizeParam(params map[string]interface{}, name string, def int) (int, error) {
+	v, ok := params[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	// Params decoded from JSON hold numbers as float64
+	var n int
+	switch x := v.(type) {
+	case float64:
+		n = int(x)
+		if float64(n) != x {
+			return 0, fmt.Errorf("%s must be an integer", name)
+		}
+	case int:
+		n = x
+	default:
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return n, nil
+}
+
+// current returns the size of the next batch
+func (b *adaptiveBatch) current() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// observe adjusts the batch size to the outcome of a fetch and returns the
+// size before and after
+func (b *adaptiveBatch) observe(elapsed time.Duration, err error) (from, to int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from = b.size
+	switch {
+	case err != nil && (isTimeoutError(err) || isRateLimitError(err)):
+		b.size = b.clamp(b.size / 2)
+		b.fast = 0
+	case err != nil || elapsed > fastFetchDuration:
+		b.fast = 0
+	default:
+		b.fast++
+		if b.fast >= batchGrowAfter {
+			b.size = b.clamp(b.size * 2)
+			b.fast = 0
+		}
+	}
+	return from, b.size
+}
+
+// isTimeoutError checks if an error is a timeout of the fetch or of the RPC
+// carrying it
+func isTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := strings.ToLower(err.Error())
+	return strings.Contains(errStr, "timeout") ||
+		strings.Contains(errStr, "timed out") ||
+		strings.Contains(errStr, "deadline exceeded")
+}
@@ -0,0 +1,113 @@
+package taskflow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestAdaptiveBatch(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestAdaptiveBatch", nil, func(t *testing.T, tx *gorm.DB) {
+		run := &JobRun{DataType: DataTypeCVE, ResultsPerBatch: 100, Params: map[string]interface{}{ParamMaxBatchSize: float64(300)}}
+		b, err := newAdaptiveBatch(run)
+		if err != nil {
+			t.Fatalf("newAdaptiveBatch failed: %v", err)
+		}
+		if b.current() != 100 || b.min != 25 || b.max != 300 {
+			t.Fatalf("Unexpected initial batch %+v", b)
+		}
+
+		// A slow fetch or an ordinary error restarts the count of fast ones
+		b.observe(time.Millisecond, nil)
+		b.observe(time.Millisecond, nil)
+		b.observe(time.Minute, nil)
+		b.observe(time.Millisecond, nil)
+		b.observe(time.Millisecond, errors.New("error from remote: bad gateway"))
+		if b.current() != 100 {
+			t.Fatalf("Expected no growth without %d fast fetches in a row, got %d", batchGrowAfter, b.current())
+		}
+		for i := 0; i < 2*batchGrowAfter; i++ {
+			b.observe(time.Millisecond, nil)
+		}
+		if b.current() != 300 {
+			t.Fatalf("Expected growth capped at the maximum, got %d", b.current())
+		}
+
+		// Timeouts and rate limits halve it, down to the minimum
+		if from, to := b.observe(time.Minute, errors.New("RPC timeout waiting for response from remote")); from != 300 || to != 150 {
+			t.Errorf("Expected a timeout to halve the batch, got %d -> %d", from, to)
+		}
+		b.observe(time.Millisecond, errors.New("error from remote: 429 Too Many Requests"))
+		b.observe(time.Millisecond, errors.New("context deadline exceeded"))
+		b.observe(time.Millisecond, errors.New("context deadline exceeded"))
+		if b.current() != 25 {
+			t.Errorf("Expected the batch shrunk to the minimum, got %d", b.current())
+		}
+
+		// A resumed run starts from the size recorded on its progress
+		run.Progress = map[DataType]DataProgress{DataTypeCVE: {BatchSize: 150}}
+		if b, _ := newAdaptiveBatch(run); b.current() != 150 {
+			t.Errorf("Expected the recorded batch size, got %d", b.current())
+		}
+		run.Progress[DataTypeCVE] = DataProgress{BatchSize: 1000}
+		if b, _ := newAdaptiveBatch(run); b.current() != 300 {
+			t.Errorf("Expected the recorded batch size within bounds, got %d", b.current())
+		}
+
+		for _, params := range []map[string]interface{}{
+			{ParamMinBatchSize: float64(0)},
+			{ParamMinBatchSize: 2.5},
+			{ParamMaxBatchSize: "many"},
+			{ParamMinBatchSize: float64(50), ParamMaxBatchSize: float64(40)},
+		} {
+			if _, err := newAdaptiveBatch(&JobRun{ResultsPerBatch: 100, Params: params}); err == nil {
+				t.Errorf("Expected params %v to be rejected", params)
+			}
+		}
+	})
+}
+
+func TestJobExecutor_AdaptiveBatchSize(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_AdaptiveBatchSize", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		invoker := &checkpointRPCInvoker{total: 60}
+		executor := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+
+		if err := executor.StartTypedWithParams(context.Background(), "invalid", 0, 5, DataTypeCVE, PriorityNormal, map[string]interface{}{ParamMaxBatchSize: float64(-1)}); err == nil {
+			t.Fatal("Expected an invalid max_batch_size to be rejected")
+		}
+		params := map[string]interface{}{ParamMaxBatchSize: float64(20)}
+		if err := executor.StartTypedWithParams(context.Background(), "adaptive", 0, 5, DataTypeCVE, PriorityNormal, params); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+
+		var run *JobRun
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if run, _ = store.GetRun("adaptive"); run.State == StateCompleted {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run.State != StateCompleted || run.FetchedCount != 60 || run.StoredCount != 60 {
+			t.Fatalf("Expected the run completed with each CVE counted once, got %+v", run)
+		}
+
+		// The fast mock doubles the batch every third fetch, up to 20
+		invoker.mu.Lock()
+		defer invoker.mu.Unlock()
+		want := []int{0, 5, 10, 15, 25, 35, 45, 65}
+		if !reflect.DeepEqual(invoker.starts, want) {
+			t.Errorf("Expected fetches at %v, got %v", want, invoker.starts)
+		}
+		if progress := run.Progress[DataTypeCVE]; progress.BatchSize != 20 {
+			t.Errorf("Expected the batch size recorded on the progress, got %+v", run.Progress)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Outcomes of a run in PauseAll and ResumeAll
+const (
+	BulkOutcomePaused  = "paused"
+	BulkOutcomeResumed = "resumed"
+	BulkOutcomeSkipped = "skipped"
+	BulkOutcomeFailed  = "failed"
+)
+
+// BulkControlResult is the outcome of pausing or resuming one run in
+// PauseAll or ResumeAll
+type BulkControlResult struct {
+	RunID    string   `json:"run_id"`
+	DataType DataType `json:"data_type"`
+	// State is the state of the run after the operation
+	State   JobState `json:"state"`
+	Outcome string   `json:"outcome"`
+	// Reason says why a run was skipped
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PauseAll pauses every running run, e.g. when NVD rate-limits the whole
+// pipeline, and returns the outcome for each run that is not finished,
+// oldest first. Runs that are already paused, or not running yet, are
+// skipped; completed, failed and stopped runs are left out.
+func (e *JobExecutor) PauseAll() ([]BulkControlResult, error) {
+	runs, err := e.unfinishedRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkControlResult, 0, len(runs))
+	for _, run := range runs {
+		result := BulkControlResult{RunID: run.ID, DataType: run.DataType, State: run.State}
+		switch run.State {
+		case StatePaused:
+			result.Outcome, result.Reason = BulkOutcomeSkipped, "already paused"
+		case StateRunning:
+			if err := e.Pause(run.ID); err != nil {
+				if !e.bulkFailure(&result, err) {
+					continue
+				}
+			} else {
+				result.Outcome, result.State = BulkOutcomePaused, StatePaused
+			}
+		default:
+			result.Outcome, result.Reason = BulkOutcomeSkipped, fmt.Sprintf("run is %s", run.State)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ResumeAll resumes the paused runs and returns the outcome for each run
+// that is not finished, oldest first. As at most one run of a data type is
+// active, only the most recently paused run of each type is resumed, and
+// only if no run of the type is active; the others are skipped, as are runs
+// that are not paused. Completed, failed and stopped runs are left out.
+func (e *JobExecutor) ResumeAll(ctx context.Context) ([]BulkControlResult, error) {
+	runs, err := e.unfinishedRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the run of each data type to resume, as GetCurrentRun would
+	active := make(map[DataType]string)
+	resume := make(map[DataType]*JobRun)
+	for _, run := range runs {
+		switch {
+		case run.State == StateRunning:
+			active[run.DataType] = run.ID
+		case run.State == StatePaused:
+			if current := resume[run.DataType]; current == nil || run.UpdatedAt.After(current.UpdatedAt) {
+				resume[run.DataType] = run
+			}
+		}
+	}
+	e.mu.RLock()
+	for dataType, job := range e.active {
+		active[dataType] = job.run.ID
+	}
+	e.mu.RUnlock()
+
+	results := make([]BulkControlResult, 0, len(runs))
+	for _, run := range runs {
+		result := BulkControlResult{RunID: run.ID, DataType: run.DataType, State: run.State}
+		switch {
+		case run.State == StateRunning:
+			result.Outcome, result.Reason = BulkOutcomeSkipped, "already running"
+		case run.State != StatePaused:
+			result.Outcome, result.Reason = BulkOutcomeSkipped, fmt.Sprintf("run is %s", run.State)
+		case active[run.DataType] != "":
+			result.Outcome, result.Reason = BulkOutcomeSkipped, fmt.Sprintf("another %s run is active: %s", run.DataType, active[run.DataType])
+		case resume[run.DataType] != run:
+			result.Outcome, result.Reason = BulkOutcomeSkipped, fmt.Sprintf("a later paused %s run is resumed: %s", run.DataType, resume[run.DataType].ID)
+		default:
+			if err := e.Resume(ctx, run.ID); err != nil {
+				if !e.bulkFailure(&result, err) {
+					continue
+				}
+			} else {
+				result.Outcome, result.State = BulkOutcomeResumed, StateRunning
+				active[run.DataType] = run.ID
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// unfinishedRuns returns the stored runs that are not in a terminal state,
+// oldest first
+func (e *JobExecutor) unfinishedRuns() ([]*JobRun, error) {
+	runs, _, err := e.runStore.ListRuns(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	unfinished := make([]*JobRun, 0, len(runs))
+	for _, run := range runs {
+		if !run.State.IsTerminal() {
+			unfinished = append(unfinished, run)
+		}
+	}
+	sort.SliceStable(unfinished, func(i, j int) bool {
+		return unfinished[i].CreatedAt.Before(unfinished[j].CreatedAt)
+	})
+	return unfinished, nil
+}
+
+// bulkFailure records the error of pausing or resuming a run in result. It
+// returns false if the run finished in the meantime, in which case it is
+// left out like any finished run.
+func (e *JobExecutor) bulkFailure(result *BulkControlResult, err error) bool {
+	if run, getErr := e.runStore.GetRun(result.RunID); getErr == nil && run != nil {
+		if run.State.IsTerminal() {
+			return false
+		}
+		result.State = run.State
+	}
+	result.Outcome, result.Error = BulkOutcomeFailed, err.Error()
+	return true
+}
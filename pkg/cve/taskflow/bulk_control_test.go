@@ -0,0 +1,100 @@
+package taskflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestJobExecutor_PauseResumeAll(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_PauseResumeAll", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(newMockRPCInvoker(), store, newTestLogger(), 4, nil)
+		ctx := context.Background()
+		if err := executor.RegisterProvider(DataTypeCWE, newCVEProvider); err != nil {
+			t.Fatalf("RegisterProvider failed: %v", err)
+		}
+
+		// An older paused CVE run and a finished one, next to two running runs
+		for _, id := range []string{"cve-old", "cve-done"} {
+			if _, err := store.CreateRun(id, 0, 10, DataTypeCVE); err != nil {
+				t.Fatalf("CreateRun failed: %v", err)
+			}
+			if err := store.UpdateState(id, StateRunning); err != nil {
+				t.Fatalf("UpdateState failed: %v", err)
+			}
+		}
+		if err := store.UpdateState("cve-old", StatePaused); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+		if err := store.UpdateState("cve-done", StateCompleted); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+		for _, run := range []struct {
+			id       string
+			dataType DataType
+		}{{"cve-run", DataTypeCVE}, {"cwe-run", DataTypeCWE}} {
+			if err := executor.StartTyped(ctx, run.id, 0, 10, run.dataType, PriorityNormal); err != nil {
+				t.Fatalf("Failed to start %s: %v", run.id, err)
+			}
+			defer executor.Stop(run.id)
+		}
+
+		check := func(op string, results []BulkControlResult, err error, want map[string]string) {
+			t.Helper()
+			if err != nil {
+				t.Fatalf("%s failed: %v", op, err)
+			}
+			if len(results) != len(want) {
+				t.Fatalf("%s: expected %d results, got %+v", op, len(want), results)
+			}
+			for _, r := range results {
+				if r.Outcome != want[r.RunID] {
+					t.Errorf("%s: expected %s %s, got %+v", op, r.RunID, want[r.RunID], r)
+				}
+				if r.Outcome == BulkOutcomeSkipped && r.Reason == "" {
+					t.Errorf("%s: expected a reason for skipping %s", op, r.RunID)
+				}
+			}
+		}
+
+		results, err := executor.PauseAll()
+		check("PauseAll", results, err, map[string]string{
+			"cve-old": BulkOutcomeSkipped,
+			"cve-run": BulkOutcomePaused,
+			"cwe-run": BulkOutcomePaused,
+		})
+		if results[0].RunID != "cve-old" || results[0].Reason != "already paused" {
+			t.Errorf("Expected the oldest run first and skipped as paused, got %+v", results[0])
+		}
+		if runs, _ := executor.GetActiveRuns(); len(runs) != 0 {
+			t.Errorf("Expected no active runs, got %v", runs)
+		}
+
+		// Only the latest paused run of each data type is resumed
+		results, err = executor.ResumeAll(ctx)
+		check("ResumeAll", results, err, map[string]string{
+			"cve-old": BulkOutcomeSkipped,
+			"cve-run": BulkOutcomeResumed,
+			"cwe-run": BulkOutcomeResumed,
+		})
+		for _, id := range []string{"cve-run", "cwe-run"} {
+			if run, _ := store.GetRun(id); run == nil || run.State != StateRunning {
+				t.Errorf("Expected %s running, got %+v", id, run)
+			}
+		}
+
+		results, err = executor.ResumeAll(ctx)
+		check("ResumeAll again", results, err, map[string]string{
+			"cve-old": BulkOutcomeSkipped,
+			"cve-run": BulkOutcomeSkipped,
+			"cwe-run": BulkOutcomeSkipped,
+		})
+		if !strings.Contains(results[0].Reason, "another cve run is active: cve-run") {
+			t.Errorf("Expected cve-old skipped for the active run, got %+v", results[0])
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package taskflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ParamCallbackURL is the run parameter naming an http or https URL that is
+// POSTed a CallbackPayload when the run reaches a terminal state
+const ParamCallbackURL = "callback_url"
+
+// Outcomes of a completion callback
+const (
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// Defaults of completion callback delivery
+const (
+	DefaultCallbackAttempts = 5
+	DefaultCallbackBackoff  = time.Second
+	DefaultCallbackTimeout  = 10 * time.Second
+	maxCallbackBackoff      = 30 * time.Second
+)
+
+// CallbackPayload is the JSON body POSTed to the callback URL of a run
+type CallbackPayload struct {
+	RunID        string    `json:"run_id"`
+	DataType     DataType  `json:"data_type"`
+	State        JobState  `json:"state"`
+	FetchedCount int64     `json:"fetched_count"`
+	StoredCount  int64     `json:"stored_count"`
+	ErrorCount   int64     `json:"error_count"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// CallbackResult is the outcome of the completion callback of a run
+type CallbackResult struct {
+	URL    string `json:"url"`
+	Status string `json:"status"` // CallbackDelivered or CallbackFailed
+	// Attempts is the number of POSTs made, including the successful one
+	Attempts int `json:"attempts"`
+	// StatusCode is the HTTP status of the last response, 0 if none came
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// callbackURL returns the callback_url parameter of a run, or ok=false if the
+// run has none
+func callbackURL(params map[string]interface{}) (u string, ok bool, err error) {
+	v, ok := params[ParamCallbackURL]
+	if !ok || v == nil || v == "" {
+		return "", false, nil
+	}
+	s, isString := v.(string)
+	if !isString {
+		return "", false, fmt.Errorf("%s must be a URL string", ParamCallbackURL)
+	}
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid %s: %w", ParamCallbackURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", false, fmt.Errorf("invalid %s %q: must be an absolute http or https URL", ParamCallbackURL, s)
+	}
+	return s, true, nil
+}
+
+// callbackSender delivers completion callbacks, retrying failed POSTs with
+// exponential backoff
+type callbackSender struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration // Before the second attempt, doubling after each
+
+	mu sync.Mutex
+	// sent holds the runs whose callback is in flight. An entry is removed
+	// once the outcome is recorded on the run, which then keeps the
+	// callback from being sent again.
+	sent map[string]bool
+	wg   sync.WaitGroup
+}
+
+func newCallbackSender() *callbackSender {
+	return &callbackSender{
+		client:   &http.Client{Timeout: DefaultCallbackTimeout},
+		attempts: DefaultCallbackAttempts,
+		backoff:  DefaultCallbackBackoff,
+		sent:     make(map[string]bool),
+	}
+}
+
+// claim reports whether the callback of runID is still to be sent, marking
+// it sent
+func (c *callbackSender) claim(runID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sent[runID] {
+		return false
+	}
+	c.sent[runID] = true
+	return true
+}
+
+// release forgets runID once the outcome of its callback is recorded
+func (c *callbackSender) release(runID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sent, runID)
+}
+
+// pending returns the number of runs whose callback is in flight
+func (c *callbackSender) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sent)
+}
+
+// deliver POSTs payload to u until it is answered with a 2xx status or the
+// attempts run out
+func (c *callbackSender) deliver(u string, payload CallbackPayload) CallbackResult {
+	result := CallbackResult{URL: u, Status: CallbackFailed}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		result.Error = err.Error()
+		result.FinishedAt = time.Now()
+		return result
+	}
+
+	backoff := c.backoff
+	for result.Attempts < c.attempts {
+		if result.Attempts > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > maxCallbackBackoff {
+				backoff = maxCallbackBackoff
+			}
+		}
+		result.Attempts++
+
+		resp, err := c.client.Post(u, "application/json", bytes.NewReader(body))
+		if err != nil {
+			result.StatusCode = 0
+			result.Error = err.Error()
+			continue
+		}
+		resp.Body.Close()
+		result.StatusCode = resp.StatusCode
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			result.Status = CallbackDelivered
+			result.Error = ""
+			break
+		}
+		result.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	result.FinishedAt = time.Now()
+	return result
+}
+
+// notifyCompletion starts the completion callback of a run that reached a
+// terminal state, if it has a callback URL. The callback is sent once per
+// run, in the background; its outcome is recorded on the run.
+func (e *JobExecutor) notifyCompletion(runID string) {
+	run, err := e.runStore.GetRun(runID)
+	if err != nil || !run.State.IsTerminal() || run.Callback != nil {
+		return
+	}
+	u, ok, err := callbackURL(run.Params)
+	if err != nil {
+		e.logger.Warn("Not sending the completion callback of run %s: %v", runID, err)
+		return
+	}
+	if !ok || !e.callbacks.claim(runID) {
+		return
+	}
+
+	payload := CallbackPayload{
+		RunID:        run.ID,
+		DataType:     run.DataType,
+		State:        run.State,
+		FetchedCount: run.FetchedCount,
+		StoredCount:  run.StoredCount,
+		ErrorCount:   run.ErrorCount,
+		ErrorMessage: run.ErrorMessage,
+		FinishedAt:   run.UpdatedAt,
+	}
+	e.callbacks.wg.Add(1)
+	go func() {
+		defer e.callbacks.wg.Done()
+		result := e.callbacks.deliver(u, payload)
+		if result.Status == CallbackDelivered {
+			e.logger.Info("Completion callback of run %s delivered to %s after %d attempt(s)", runID, u, result.Attempts)
+		} else {
+			e.logger.Warn("Completion callback of run %s to %s failed after %d attempt(s): %s", runID, u, result.Attempts, result.Error)
+		}
+		if err := e.runStore.SetCallbackResult(runID, result); err != nil {
+			// Kept claimed: without a recorded outcome the run would send
+			// its callback again
+			e.logger.Warn("Failed to record the completion callback of run %s: %v", runID, err)
+			return
+		}
+		e.callbacks.release(runID)
+	}()
+}
+
+// WaitCallbacks blocks until the completion callbacks in flight are done.
+// Call it on shutdown so pending callbacks are delivered and recorded.
+func (e *JobExecutor) WaitCallbacks() {
+	e.callbacks.wg.Wait()
+}
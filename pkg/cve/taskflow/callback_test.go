@@ -0,0 +1,123 @@
+package taskflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// callbackServer answers the first fail POSTs with 500 and the rest with 200,
+// recording the payloads
+type callbackServer struct {
+	fail int
+
+	mu       sync.Mutex
+	payloads []CallbackPayload
+}
+
+func (s *callbackServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var p CallbackPayload
+	json.NewDecoder(r.Body).Decode(&p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = append(s.payloads, p)
+	if len(s.payloads) <= s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runWithCallback runs a CVE run with a callback URL to completion and waits
+// for its callback
+func runWithCallback(t *testing.T, runID, callback string, attempts int) *JobRun {
+	t.Helper()
+	store := NewTempRunStore(t)
+	executor := NewJobExecutor(&batchRPCInvoker{page: []string{"CVE-2024-0001", "CVE-2024-0002"}}, store, newTestLogger(), 4, nil)
+	executor.scheduler = NewFairScheduler(4, time.Millisecond)
+	executor.callbacks.backoff = time.Millisecond
+	executor.callbacks.attempts = attempts
+
+	params := map[string]interface{}{ParamCallbackURL: callback}
+	if err := executor.StartTypedWithParams(context.Background(), runID, 0, 10, DataTypeCVE, PriorityNormal, params); err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if run, _ := store.GetRun(runID); run != nil && run.State == StateCompleted {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	executor.WaitCallbacks()
+	if n := executor.callbacks.pending(); n != 0 {
+		t.Errorf("Expected no callback left pending once recorded, got %d", n)
+	}
+	// The recorded outcome keeps the released run from being sent again
+	executor.notifyCompletion(runID)
+	executor.WaitCallbacks()
+	run, err := store.GetRun(runID)
+	if err != nil {
+		t.Fatalf("GetRun failed: %v", err)
+	}
+	return run
+}
+
+func TestJobExecutor_CompletionCallback(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_CompletionCallback", nil, func(t *testing.T, tx *gorm.DB) {
+		srv := &callbackServer{fail: 1}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		// The first POST fails and is retried
+		run := runWithCallback(t, "callback", ts.URL, 3)
+		if run.Callback == nil || run.Callback.Status != CallbackDelivered || run.Callback.Attempts != 2 || run.Callback.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected the callback delivered on the second attempt, got %+v", run.Callback)
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if len(srv.payloads) != 2 {
+			t.Fatalf("Expected 2 POSTs, got %d", len(srv.payloads))
+		}
+		p := srv.payloads[1]
+		if p.RunID != "callback" || p.State != StateCompleted || p.DataType != DataTypeCVE || p.FetchedCount != 2 || p.StoredCount != 2 {
+			t.Errorf("Unexpected payload %+v", p)
+		}
+	})
+}
+
+func TestJobExecutor_CompletionCallbackFailure(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_CompletionCallbackFailure", nil, func(t *testing.T, tx *gorm.DB) {
+		srv := &callbackServer{fail: 10}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		run := runWithCallback(t, "callback-failure", ts.URL, 2)
+		if run.Callback == nil || run.Callback.Status != CallbackFailed || run.Callback.Attempts != 2 ||
+			run.Callback.StatusCode != http.StatusInternalServerError || run.Callback.Error == "" {
+			t.Fatalf("Expected the callback to fail after 2 attempts, got %+v", run.Callback)
+		}
+		if run.State != StateCompleted {
+			t.Errorf("A failed callback must not change the run state, got %s", run.State)
+		}
+	})
+}
+
+func TestJobExecutor_CallbackURLValidated(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_CallbackURLValidated", nil, func(t *testing.T, tx *gorm.DB) {
+		executor := NewJobExecutor(&batchRPCInvoker{}, NewTempRunStore(t), newTestLogger(), 1, nil)
+		for _, bad := range []interface{}{"ftp://example.com/hook", "/relative", 42} {
+			params := map[string]interface{}{ParamCallbackURL: bad}
+			if err := executor.StartTypedWithParams(context.Background(), "bad", 0, 10, DataTypeCVE, PriorityNormal, params); err == nil {
+				t.Errorf("Expected %v to be rejected", bad)
+			}
+		}
+	})
+}
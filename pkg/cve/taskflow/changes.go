@@ -0,0 +1,23 @@
+package taskflow
+
+// Changes returns a channel that is closed the next time a run is created,
+// updated or deleted. Watchers re-read the runs they care about and call
+// Changes again; changes made in between are coalesced into one wake-up.
+func (s *RunStore) Changes() <-chan struct{} {
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+// notifyChange wakes up the watchers of Changes
+func (s *RunStore) notifyChange() {
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
@@ -0,0 +1,250 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+)
+
+// cveSaveRetries is how many times a CVE is saved before it is quarantined
+const cveSaveRetries = 3
+
+// cveProvider fetches CVEs from the remote service and stores them in the
+// local one. A run given ParamLastModStartDate is an incremental sync: it
+// fetches only the CVEs modified since then, window by window. The batch
+// size adapts to how fast NVD answers, within ParamMinBatchSize and
+// ParamMaxBatchSize.
+type cveProvider struct {
+	runID   string
+	invoker RPCInvoker
+	logger  *common.Logger
+	window  *modifiedWindow // nil for a full sync
+	batch   *adaptiveBatch
+	fanOut  FanOut
+}
+
+// newCVEProvider is the ProviderFactory of DataTypeCVE
+func newCVEProvider(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+	batch, err := newAdaptiveBatch(run)
+	if err != nil {
+		return nil, err
+	}
+	p := &cveProvider{runID: run.ID, invoker: invoker, logger: logger, batch: batch, fanOut: sequentialFanOut}
+	since, ok, err := lastModStartDate(run.Params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return p, nil
+	}
+	p.window = newModifiedWindow(since, time.Now())
+	logger.Info("Incremental sync of run %s from %s to %s", run.ID, p.window.since.Format(time.RFC3339), p.window.until.Format(time.RFC3339))
+	return &windowedCVEProvider{p}, nil
+}
+
+// BatchSize returns the adapted size of the next batch
+func (p *cveProvider) BatchSize() int {
+	return p.batch.current()
+}
+
+// SetFanOut sets how the CVEs of a failed batch save are saved one by one
+func (p *cveProvider) SetFanOut(fanOut FanOut) {
+	p.fanOut = fanOut
+}
+
+// Fetch fetches a page of CVEs, of the current window for an incremental
+// sync, and adapts the batch size to how long it took or why it failed
+func (p *cveProvider) Fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error) {
+	start := time.Now()
+	records, err := p.fetch(ctx, startIndex, batchSize)
+	if from, to := p.batch.observe(time.Since(start), err); from != to {
+		p.logger.Info("Batch size of run %s changed from %d to %d", p.runID, from, to)
+	}
+	return records, err
+}
+
+// fetch makes one attempt at Fetch
+func (p *cveProvider) fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error) {
+	var result interface{}
+	var err error
+	if p.window != nil {
+		result, err = p.invoker.InvokeRPC(ctx, "remote", "RPCFetchCVEsModified", &rpc.FetchCVEsModifiedParams{
+			StartIndex:       startIndex,
+			ResultsPerPage:   batchSize,
+			LastModStartDate: p.window.since,
+			LastModEndDate:   p.window.until,
+		})
+	} else {
+		result, err = p.invoker.InvokeRPC(ctx, "remote", "RPCFetchCVEs", &rpc.FetchCVEsParams{
+			StartIndex:     startIndex,
+			ResultsPerPage: batchSize,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the RPC response (it's a subprocess.Message)
+	msg, ok := result.(*subprocess.Message)
+	if !ok {
+		return nil, fmt.Errorf("invalid response type from remote")
+	}
+	if msg.Type == subprocess.MessageTypeError {
+		rpcErr := fmt.Errorf("error from remote: %s", msg.Error)
+		if isRateLimitError(rpcErr) {
+			p.logger.Warn("Rate limit detected, will retry with backoff")
+		}
+		return nil, rpcErr
+	}
+
+	var response cve.CVEResponse
+	if err := jsonutil.Unmarshal(msg.Payload, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CVE response: %w", err)
+	}
+	records := make([]Record, len(response.Vulnerabilities))
+	for i, vuln := range response.Vulnerabilities {
+		records[i] = vuln.CVE
+	}
+	return records, nil
+}
+
+// Store saves several CVEs in one transaction; if the batch fails, it falls
+// back to one by one so a bad CVE fails alone
+func (p *cveProvider) Store(ctx context.Context, records []Record) (StoreResult, error) {
+	var result StoreResult
+	items := make([]cve.CVEItem, 0, len(records))
+	for _, record := range records {
+		item, ok := record.(cve.CVEItem)
+		if !ok {
+			return result, fmt.Errorf("unexpected record type %T", record)
+		}
+		items = append(items, item)
+	}
+
+	pending := items
+	if len(items) > 1 {
+		if stored, err := p.saveCVEBatch(ctx, items); err == nil {
+			result.Stored = stored
+			pending = nil
+		} else {
+			p.logger.Warn("Batch save of %d CVEs failed, saving them one by one: %v", len(items), err)
+		}
+	}
+
+	// Store each remaining CVE with retry logic, in parallel within the
+	// concurrency limit of CVE runs
+	var mu sync.Mutex
+	started := make([]bool, len(pending))
+	fanErr := p.fanOut(ctx, len(pending), func(i int) {
+		stored, failed := p.saveCVE(ctx, pending[i])
+		mu.Lock()
+		defer mu.Unlock()
+		started[i] = true
+		if stored {
+			result.Stored++
+		} else {
+			result.Errors++
+			result.Failed = append(result.Failed, failed)
+		}
+	})
+	if fanErr != nil {
+		// Quarantine the CVEs never tried rather than lose them
+		for i, item := range pending {
+			if !started[i] {
+				result.Errors++
+				result.Failed = append(result.Failed, FailedRecord{
+					ID:     item.ID,
+					Target: "local",
+					Method: "RPCSaveCVEByID",
+					Params: &rpc.SaveCVEByIDParams{CVE: item},
+					Err:    fanErr,
+				})
+			}
+		}
+	}
+
+	p.logger.Info(cve.LogMsgTFStoredCVEsSuccess, result.Stored, len(items))
+	return result, nil
+}
+
+// saveCVE saves one CVE with RPCSaveCVEByID, retrying with backoff. It
+// returns false with the record to quarantine if every attempt failed.
+func (p *cveProvider) saveCVE(ctx context.Context, item cve.CVEItem) (bool, FailedRecord) {
+	params := &rpc.SaveCVEByIDParams{CVE: item}
+	var lastErr error
+
+	for attempt := 0; attempt < cveSaveRetries; attempt++ {
+		err := rpcResultError(p.invoker.InvokeRPC(ctx, "local", "RPCSaveCVEByID", params))
+		if err == nil {
+			return true, FailedRecord{}
+		}
+
+		lastErr = err
+		if attempt < cveSaveRetries-1 {
+			// Exponential backoff before retry
+			backoff := time.Duration(1<<uint(attempt)) * 100 * time.Millisecond
+			p.logger.Debug(cve.LogMsgTFFailedStoreCVE, item.ID, err)
+			p.logger.Debug("Retrying save for %s after %v (attempt %d/%d)", item.ID, backoff, attempt+1, cveSaveRetries)
+			select {
+			case <-ctx.Done():
+				p.logger.Warn("Context cancelled while retrying save for %s", item.ID)
+			case <-time.After(backoff):
+			}
+		}
+	}
+
+	p.logger.Warn(cve.LogMsgTFFailedStoreCVE, item.ID, lastErr)
+	return false, FailedRecord{
+		ID:       item.ID,
+		Target:   "local",
+		Method:   "RPCSaveCVEByID",
+		Params:   params,
+		Err:      lastErr,
+		Attempts: cveSaveRetries,
+	}
+}
+
+// saveCVEBatch stores CVEs with a single RPCSaveCVEsBatch call and returns
+// how many were inserted or updated
+func (p *cveProvider) saveCVEBatch(ctx context.Context, items []cve.CVEItem) (int64, error) {
+	result, err := p.invoker.InvokeRPC(ctx, "local", "RPCSaveCVEsBatch", &rpc.SaveCVEsBatchParams{CVEs: items})
+	if err := rpcResultError(result, err); err != nil {
+		return 0, err
+	}
+	msg, ok := result.(*subprocess.Message)
+	if !ok {
+		return 0, fmt.Errorf("invalid response type from local")
+	}
+	var saved rpc.SaveCVEsBatchResult
+	if err := jsonutil.Unmarshal(msg.Payload, &saved); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal batch save result: %w", err)
+	}
+	return int64(saved.Inserted + saved.Updated), nil
+}
+
+// windowedCVEProvider is the cveProvider of an incremental sync
+type windowedCVEProvider struct {
+	*cveProvider
+}
+
+// NextWindow opens the window after the exhausted one, up to now
+func (p *windowedCVEProvider) NextWindow() (map[string]interface{}, bool) {
+	if p.window.final {
+		return nil, false
+	}
+	p.window = newModifiedWindow(p.window.until, time.Now())
+	return map[string]interface{}{ParamLastModStartDate: p.window.since.Format(time.RFC3339)}, true
+}
+
+// CompletionParams records the end of the last window as the watermark the
+// next incremental sync starts from
+func (p *windowedCVEProvider) CompletionParams() map[string]interface{} {
+	return map[string]interface{}{ParamWatermark: p.window.until.Format(time.RFC3339)}
+}
@@ -131,7 +131,7 @@ func (smc *StateMachineController) TransitTo(runID string, newState JobState) er
 	run.State = newState
 	run.UpdatedAt = time.Now()
 
-	if err := smc.runStore.saveRun(run); err != nil {
+	if err := smc.runStore.saveRunWithEvent(run); err != nil {
 		// Restore old state on failure
 		run.State = oldState
 		return err
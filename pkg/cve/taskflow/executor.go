@@ -2,16 +2,18 @@ package taskflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
 	"github.com/cyw0ng95/v2e/pkg/cve"
-	"github.com/cyw0ng95/v2e/pkg/jsonutil"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
-	"github.com/cyw0ng95/v2e/pkg/rpc"
 	gotaskflow "github.com/noneback/go-taskflow"
 )
 
@@ -30,15 +32,33 @@ type JobExecutor struct {
 	localCircuitBreaker  *CircuitBreaker
 	tieredPool           *TieredPool
 	poolMetrics          *PoolMetrics
+	scheduler            *FairScheduler
+	queue                *workQueue
+	concurrency          int
+	throughput           *throughputTracker
+	callbacks            *callbackSender
+	// window gates batches to the maintenance window; nil means always open
+	window atomic.Pointer[maintenance.Window]
+
+	mu        sync.RWMutex
+	active    map[DataType]*activeJob // At most one active run per data type
+	providers map[DataType]ProviderFactory
+}
 
-	mu         sync.RWMutex
-	activeRun  *JobRun
-	cancelFunc context.CancelFunc
-	doneChan   chan struct{} // Signals when executeJob goroutine completes
+// activeJob is a run the executor is driving
+type activeJob struct {
+	run    *JobRun
+	cancel context.CancelFunc
+	drain  context.CancelFunc // Stops the loop after the in-flight batch
+	done   chan struct{}      // Closed when the executeJob goroutine returns
 }
 
-// NewJobExecutor creates a new job executor with Taskflow and persistent storage
-func NewJobExecutor(rpcInvoker RPCInvoker, runStore *RunStore, logger *common.Logger, concurrency uint) *JobExecutor {
+// NewJobExecutor creates a new job executor with Taskflow and persistent
+// storage. concurrency is the global worker pool shared by the batches of all
+// runs; typeLimits caps the concurrent work of a data type (see
+// SetConcurrencyLimit). Data types without a limit fan out to at most
+// concurrency calls.
+func NewJobExecutor(rpcInvoker RPCInvoker, runStore *RunStore, logger *common.Logger, concurrency uint, typeLimits map[DataType]int) *JobExecutor {
 	// Create circuit breakers: 5 failures triggers open, 60s to reset
 	remoteCB := NewCircuitBreaker(5, 60*time.Second)
 	localCB := NewCircuitBreaker(10, 30*time.Second)
@@ -47,6 +67,11 @@ func NewJobExecutor(rpcInvoker RPCInvoker, runStore *RunStore, logger *common.Lo
 	tp := NewTieredPoolWithDefaults()
 	metrics := NewPoolMetrics()
 
+	scheduler := NewFairScheduler(int(concurrency), DefaultTokenInterval)
+	for dataType, limit := range typeLimits {
+		scheduler.SetTypeLimit(dataType, limit)
+	}
+
 	return &JobExecutor{
 		rpcInvoker:           rpcInvoker,
 		runStore:             runStore,
@@ -56,31 +81,75 @@ func NewJobExecutor(rpcInvoker RPCInvoker, runStore *RunStore, logger *common.Lo
 		localCircuitBreaker:  localCB,
 		tieredPool:           tp,
 		poolMetrics:          metrics,
+		scheduler:            scheduler,
+		queue:                newWorkQueue(int(concurrency)),
+		concurrency:          int(concurrency),
+		throughput:           newThroughputTracker(),
+		callbacks:            newCallbackSender(),
+		active:               make(map[DataType]*activeJob),
+		providers:            map[DataType]ProviderFactory{DataTypeCVE: newCVEProvider, DataTypeKEV: newKEVProvider},
 	}
 }
 
-// Start starts a new CVE job run (enforces single active run)
+// Start starts a new CVE job run (enforces a single active CVE run)
 func (e *JobExecutor) Start(ctx context.Context, runID string, startIndex, resultsPerBatch int) error {
-	return e.StartTyped(ctx, runID, startIndex, resultsPerBatch, DataTypeCVE)
+	return e.StartTyped(ctx, runID, startIndex, resultsPerBatch, DataTypeCVE, PriorityNormal)
 }
 
-// StartTyped starts a new job run with a specific data type (enforces single active run)
-func (e *JobExecutor) StartTyped(ctx context.Context, runID string, startIndex, resultsPerBatch int, dataType DataType) error {
+// StartTyped starts a new job run with a specific data type and scheduling
+// priority. The run fetches and stores through the provider registered for
+// its data type (see RegisterProvider). Runs of different data types run
+// concurrently, but only one run per data type can be active. An empty
+// priority means normal.
+func (e *JobExecutor) StartTyped(ctx context.Context, runID string, startIndex, resultsPerBatch int, dataType DataType, priority Priority) error {
+	return e.StartTypedWithParams(ctx, runID, startIndex, resultsPerBatch, dataType, priority, nil)
+}
+
+// StartTypedWithParams is StartTyped with configuration parameters stored on
+// the run. A CVE run given ParamLastModStartDate is an incremental sync: it
+// fetches only the CVEs modified since then, window by window, and records
+// ParamWatermark when it completes. A run given ParamCallbackURL POSTs its
+// outcome there when it completes, fails or is stopped.
+func (e *JobExecutor) StartTypedWithParams(ctx context.Context, runID string, startIndex, resultsPerBatch int, dataType DataType, priority Priority, params map[string]interface{}) error {
+	if since, ok, err := lastModStartDate(params); err != nil {
+		return err
+	} else if ok && since.After(time.Now()) {
+		return fmt.Errorf("%s %s is in the future", ParamLastModStartDate, since.Format(time.RFC3339))
+	}
+	if _, _, err := callbackURL(params); err != nil {
+		return err
+	}
+	if _, err := newAdaptiveBatch(&JobRun{ResultsPerBatch: resultsPerBatch, Params: params}); err != nil {
+		return err
+	}
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	if _, err := ParsePriority(string(priority)); err != nil {
+		return err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// First check in-memory activeRun (faster)
-	if e.activeRun != nil {
-		return fmt.Errorf("job already running: %s (state: %s)", e.activeRun.ID, e.activeRun.State)
+	if _, err := e.providerFactoryLocked(dataType); err != nil {
+		return err
+	}
+
+	// First check the in-memory active runs (faster)
+	if job := e.active[dataType]; job != nil {
+		return fmt.Errorf("%s job already running: %s (state: %s)", dataType, job.run.ID, job.run.State)
 	}
 
 	// Double-check persisted store for active runs
-	activeRun, err := e.runStore.GetActiveRun()
+	activeRuns, err := e.runStore.GetActiveRuns()
 	if err != nil {
-		return fmt.Errorf("failed to check active run: %w", err)
+		return fmt.Errorf("failed to check active runs: %w", err)
 	}
-	if activeRun != nil {
-		return fmt.Errorf("job already running: %s (state: %s)", activeRun.ID, activeRun.State)
+	for _, activeRun := range activeRuns {
+		if activeRun.DataType == dataType {
+			return fmt.Errorf("%s job already running: %s (state: %s)", dataType, activeRun.ID, activeRun.State)
+		}
 	}
 
 	// Create new run
@@ -88,22 +157,27 @@ func (e *JobExecutor) StartTyped(ctx context.Context, runID string, startIndex,
 	if err != nil {
 		return fmt.Errorf("failed to create run: %w", err)
 	}
+	if err := e.runStore.SetPriority(runID, priority); err != nil {
+		return fmt.Errorf("failed to set run priority: %w", err)
+	}
+	run.Priority = priority
+	if len(params) > 0 {
+		if err := e.runStore.SetParams(runID, params); err != nil {
+			return fmt.Errorf("failed to set run params: %w", err)
+		}
+		for k, v := range params {
+			run.Params[k] = v
+		}
+	}
 
 	// Transition to running with validation
 	if err := e.transitionStateLocked(runID, StateQueued, StateRunning); err != nil {
 		return fmt.Errorf("failed to transition to running: %w", err)
 	}
 
-	// Set activeRun and doneChan BEFORE starting goroutine (prevents race)
-	e.activeRun = run
-	e.doneChan = make(chan struct{})
-
-	// Create cancellable context
-	jobCtx, cancel := context.WithCancel(ctx)
-	e.cancelFunc = cancel
-
-	// Start job in background (lock is released after defer, but activeRun is already set)
-	go e.executeJob(jobCtx, runID)
+	// Start job in background (lock is released after defer, but the run is
+	// already active)
+	e.startJobLocked(ctx, run)
 
 	e.logger.Info(cve.LogMsgTFJobStarted,
 		runID, startIndex, resultsPerBatch, dataType)
@@ -116,17 +190,17 @@ func (e *JobExecutor) Resume(ctx context.Context, runID string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// Validate no active run (prevents double-resume)
-	if e.activeRun != nil {
-		return fmt.Errorf("cannot resume: another job is active: %s", e.activeRun.ID)
-	}
-
 	// Get and validate the run
 	run, err := e.runStore.GetRun(runID)
 	if err != nil {
 		return fmt.Errorf("failed to get run: %w", err)
 	}
 
+	// Validate no active run of the same type (prevents double-resume)
+	if job := e.active[run.DataType]; job != nil {
+		return fmt.Errorf("cannot resume: another %s job is active: %s", run.DataType, job.run.ID)
+	}
+
 	if run.State != StatePaused {
 		return fmt.Errorf("run is not paused (current state: %s)", run.State)
 	}
@@ -136,22 +210,58 @@ func (e *JobExecutor) Resume(ctx context.Context, runID string) error {
 		return err
 	}
 
-	// Set active run before starting goroutine
-	e.activeRun = run
-	e.doneChan = make(chan struct{})
-
-	// Create cancellable context
-	jobCtx, cancel := context.WithCancel(ctx)
-	e.cancelFunc = cancel
-
 	// Start job in background
-	go e.executeJob(jobCtx, runID)
+	e.startJobLocked(ctx, run)
 
 	e.logger.Info(cve.LogMsgTFJobResumed, runID)
 
 	return nil
 }
 
+// startJobLocked marks a run active and starts its job loop (caller must
+// hold lock)
+func (e *JobExecutor) startJobLocked(ctx context.Context, run *JobRun) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	loopCtx, drain := context.WithCancel(jobCtx)
+	job := &activeJob{run: run, cancel: cancel, drain: drain, done: make(chan struct{})}
+	// Set the active run before starting the goroutine (prevents race)
+	e.active[run.DataType] = job
+	go e.executeJob(jobCtx, loopCtx, job)
+}
+
+// activeJobLocked returns the active job of a run, or nil (caller must hold
+// lock)
+func (e *JobExecutor) activeJobLocked(runID string) *activeJob {
+	for _, job := range e.active {
+		if job.run.ID == runID {
+			return job
+		}
+	}
+	return nil
+}
+
+// waitJob waits for the goroutine of a cancelled job to finish, then clears
+// the job. It must be called without holding the lock, which the goroutine
+// takes on its way out.
+func (e *JobExecutor) waitJob(job *activeJob, op string) {
+	select {
+	case <-job.done:
+		// OK, goroutine finished
+	case <-time.After(10 * time.Second):
+		e.logger.Warn("%s: goroutine did not finish within timeout", op)
+	}
+	e.clearJob(job)
+}
+
+// clearJob removes a job from the active runs unless it was already replaced
+func (e *JobExecutor) clearJob(job *activeJob) {
+	e.mu.Lock()
+	if e.active[job.run.DataType] == job {
+		delete(e.active, job.run.DataType)
+	}
+	e.mu.Unlock()
+}
+
 // Pause pauses the running job
 func (e *JobExecutor) Pause(runID string) error {
 	e.mu.Lock()
@@ -169,31 +279,25 @@ func (e *JobExecutor) Pause(runID string) error {
 	}
 
 	// Then verify we own this run
-	if e.activeRun == nil || e.activeRun.ID != runID {
+	job := e.activeJobLocked(runID)
+	if job == nil {
 		return fmt.Errorf("run not active: %s", runID)
 	}
 
 	// Cancel the job context
-	if e.cancelFunc != nil {
-		e.cancelFunc()
-		e.cancelFunc = nil
-	}
+	job.cancel()
 
 	// Transition with validation
 	if err := e.transitionStateLocked(runID, StateRunning, StatePaused); err != nil {
 		return err
 	}
 
-	// Wait for goroutine to finish (with timeout)
-	select {
-	case <-e.doneChan:
-		// OK, goroutine finished
-	case <-time.After(10 * time.Second):
-		e.logger.Warn("Pause: goroutine did not finish within timeout")
-	}
+	// Wait for goroutine to finish (with timeout). The job stays active until
+	// then, so the run cannot be resumed while its old loop is winding down.
+	e.mu.Unlock()
+	e.waitJob(job, "Pause")
+	e.mu.Lock()
 
-	e.activeRun = nil
-	e.doneChan = nil
 	e.logger.Info(cve.LogMsgTFJobPaused, runID)
 
 	return nil
@@ -217,22 +321,18 @@ func (e *JobExecutor) Stop(runID string) error {
 
 	// For running jobs, verify we own it and cancel
 	if run.State == StateRunning {
-		if e.activeRun == nil || e.activeRun.ID != runID {
+		job := e.activeJobLocked(runID)
+		if job == nil {
 			return fmt.Errorf("run not active: %s", runID)
 		}
 
 		// Cancel the job context
-		if e.cancelFunc != nil {
-			e.cancelFunc()
-			e.cancelFunc = nil
-		}
+		job.cancel()
 
 		// Wait for goroutine to finish
-		select {
-		case <-e.doneChan:
-		case <-time.After(10 * time.Second):
-			e.logger.Warn("Stop: goroutine did not finish within timeout")
-		}
+		e.mu.Unlock()
+		e.waitJob(job, "Stop")
+		e.mu.Lock()
 	}
 
 	// Transition to stopped
@@ -240,14 +340,112 @@ func (e *JobExecutor) Stop(runID string) error {
 	if err := e.transitionStateLocked(runID, fromState, StateStopped); err != nil {
 		return err
 	}
+	e.notifyCompletion(runID)
 
-	e.activeRun = nil
-	e.doneChan = nil
 	e.logger.Info(cve.LogMsgTFJobStopped, runID)
 
 	return nil
 }
 
+// SetRunPriority changes the scheduling priority of a run. An active run's
+// share of worker slots and rate-limit tokens changes from its next batch.
+func (e *JobExecutor) SetRunPriority(runID string, priority Priority) error {
+	if _, err := ParsePriority(string(priority)); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, err := e.runStore.GetRun(runID)
+	if err != nil {
+		return fmt.Errorf("run not found: %w", err)
+	}
+	if run.State.IsTerminal() {
+		return fmt.Errorf("run cannot be reprioritized in state: %s", run.State)
+	}
+
+	if err := e.runStore.SetPriority(runID, priority); err != nil {
+		return err
+	}
+	e.scheduler.SetPriority(runID, priority)
+	e.logger.Info("Run %s priority set to %s", runID, priority)
+
+	return nil
+}
+
+// SetConcurrencyLimit caps the concurrent work of a data type; 0 removes the
+// cap. The cap bounds the store calls a run of the type fans out within a
+// batch (see ConcurrentProvider) and the jobs of the type run outside the
+// executor that take a slot with AcquireSlot, such as CWE and CAPEC imports.
+// Lowering it does not interrupt work already holding a slot.
+func (e *JobExecutor) SetConcurrencyLimit(dataType DataType, limit int) error {
+	if _, err := ParseDataType(string(dataType)); err != nil {
+		return err
+	}
+	if limit < 0 {
+		return fmt.Errorf("limit must be non-negative")
+	}
+	e.scheduler.SetTypeLimit(dataType, limit)
+	e.logger.Info("Concurrency limit of %s runs set to %d (global pool: %d)", dataType, limit, e.concurrency)
+	return nil
+}
+
+// SetMaintenanceWindow restricts the batches of every run to the periods of
+// window; nil allows them at any time. A batch in flight when the window
+// closes finishes, and its run then waits at the next batch boundary until
+// the window reopens, staying in the running state.
+func (e *JobExecutor) SetMaintenanceWindow(window *maintenance.Window) {
+	e.window.Store(window)
+}
+
+// ConcurrencyLimits returns the size of the global worker pool and the caps
+// of the capped data types
+func (e *JobExecutor) ConcurrencyLimits() (int, map[DataType]int) {
+	return e.concurrency, e.scheduler.TypeLimits()
+}
+
+// AcquireSlot blocks until a slot of dataType is free under its concurrency
+// limit, for a job of the type that does not run on the executor. The
+// returned function releases the slot.
+func (e *JobExecutor) AcquireSlot(ctx context.Context, dataType DataType) (func(), error) {
+	return e.scheduler.AcquireTypeSlot(ctx, dataType)
+}
+
+// typeFanOut returns the FanOut of the runs of dataType: each call holds a
+// slot of the type, and no more calls than the global pool run at once
+func (e *JobExecutor) typeFanOut(dataType DataType) FanOut {
+	return func(ctx context.Context, n int, fn func(i int)) error {
+		size := e.concurrency
+		if size < 1 {
+			size = 1
+		}
+		pool := make(chan struct{}, size)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		for i := 0; i < n; i++ {
+			select {
+			case pool <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			release, err := e.scheduler.AcquireTypeSlot(ctx, dataType)
+			if err != nil {
+				<-pool
+				return err
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-pool }()
+				defer release()
+				fn(i)
+			}(i)
+		}
+		return nil
+	}
+}
+
 // GetPoolStats returns pool utilization statistics
 func (e *JobExecutor) GetPoolStats() map[string]interface{} {
 	if e.tieredPool == nil || e.poolMetrics == nil {
@@ -286,30 +484,102 @@ func (e *JobExecutor) transitionStateLocked(runID string, from, to JobState) err
 	return e.runStore.UpdateState(runID, to)
 }
 
-// GetActiveRun returns the currently active run (if any)
-func (e *JobExecutor) GetActiveRun() (*JobRun, error) {
-	// First check in-memory active run to avoid races with persistence
+// GetActiveRuns returns the currently active runs, at most one per data
+// type, oldest first
+func (e *JobExecutor) GetActiveRuns() ([]*JobRun, error) {
+	// First snapshot the in-memory active runs to avoid races with persistence
 	e.mu.RLock()
-	if e.activeRun != nil {
-		// attempt to return the persisted run so counters reflect latest updates
-		runID := e.activeRun.ID
-		e.mu.RUnlock()
+	inMemory := make([]JobRun, 0, len(e.active))
+	for _, job := range e.active {
+		inMemory = append(inMemory, *job.run)
+	}
+	e.mu.RUnlock()
+
+	// The persisted runs carry the latest counters
+	runs, err := e.runStore.GetActiveRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fall back to a copy of an in-memory run the store no longer reports
+	for i := range inMemory {
+		found := false
+		for _, run := range runs {
+			if run.ID == inMemory[i].ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if run, err := e.runStore.GetRun(inMemory[i].ID); err == nil && run != nil {
+				runs = append(runs, run)
+			} else {
+				runs = append(runs, &inMemory[i])
+			}
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
 
-		run, err := e.runStore.GetRun(runID)
-		if err == nil && run != nil {
+// GetCurrentRun returns the run of a data type that session control applies
+// to when no run is named: the active run of the type, or else its most
+// recently updated paused run. It returns nil if there is neither.
+func (e *JobExecutor) GetCurrentRun(dataType DataType) (*JobRun, error) {
+	runs, err := e.GetActiveRuns()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.DataType == dataType {
 			return run, nil
 		}
+	}
 
-		// fall back to a copy of the in-memory run
-		e.mu.RLock()
-		runCopy := *e.activeRun
-		e.mu.RUnlock()
-		return &runCopy, nil
+	paused, _, err := e.runStore.ListRuns(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	var current *JobRun
+	for _, run := range paused {
+		if run.DataType == dataType && run.State == StatePaused && (current == nil || run.UpdatedAt.After(current.UpdatedAt)) {
+			current = run
+		}
+	}
+	return current, nil
+}
+
+// RunRecord is a run of the run history, flagged when it is a run the
+// executor is currently driving
+type RunRecord struct {
+	*JobRun
+	Active bool `json:"active"`
+}
+
+// ListRuns returns a page of the run history, newest first, and the number
+// of runs
+func (e *JobExecutor) ListRuns(limit, offset int) ([]RunRecord, int, error) {
+	runs, total, err := e.runStore.ListRuns(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	e.mu.RLock()
+	records := make([]RunRecord, len(runs))
+	for i, run := range runs {
+		records[i] = RunRecord{JobRun: run, Active: e.activeJobLocked(run.ID) != nil}
 	}
 	e.mu.RUnlock()
+	return records, total, nil
+}
 
-	// Fall back to persisted store
-	return e.runStore.GetActiveRun()
+// GetProviderMetrics returns the throughput of the active runs, one per data
+// type, each with its last limit samples (all kept samples if limit <= 0)
+func (e *JobExecutor) GetProviderMetrics(limit int) []ProviderMetrics {
+	return e.throughput.snapshot(limit)
 }
 
 // GetLatestRun returns the most recently updated run from the store
@@ -317,52 +587,114 @@ func (e *JobExecutor) GetLatestRun() (*JobRun, error) {
 	return e.runStore.GetLatestRun()
 }
 
-// RecoverRuns attempts to recover runs left in running state after restart
+// RecoverRuns attempts to recover runs left in running state after restart,
+// one per data type. Paused runs stay paused.
 func (e *JobExecutor) RecoverRuns(ctx context.Context) error {
-	activeRun, err := e.runStore.GetActiveRun()
+	activeRuns, err := e.runStore.GetActiveRuns()
 	if err != nil {
 		return err
 	}
 
-	if activeRun == nil {
+	if len(activeRuns) == 0 {
 		e.logger.Info(cve.LogMsgTFNoActiveRuns)
 		return nil
 	}
 
-	e.logger.Info(cve.LogMsgTFFoundRun, activeRun.ID, activeRun.State)
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-	// Only auto-recover running jobs (paused jobs stay paused)
-	if activeRun.State == StateRunning {
+	var firstErr error
+	for _, activeRun := range activeRuns {
+		e.logger.Info(cve.LogMsgTFFoundRun, activeRun.ID, activeRun.State)
+		// The run is still running in the store: restart its loop, which
+		// resumes from the last checkpoint
+		if job := e.active[activeRun.DataType]; job != nil {
+			err := fmt.Errorf("cannot recover: another %s job is active: %s", activeRun.DataType, job.run.ID)
+			e.logger.Warn("Failed to recover run %s: %v", activeRun.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
 		e.logger.Info(cve.LogMsgTFAutoRecover, activeRun.ID)
-		return e.Resume(ctx, activeRun.ID)
+		e.startJobLocked(ctx, activeRun)
 	}
+	return firstErr
+}
 
-	e.logger.Info(cve.LogMsgTFManualResume, activeRun.State)
-	return nil
+// CheckpointAll stops every active run at a batch boundary before shutdown,
+// leaving it running in the store so RecoverRuns resumes it on restart. A
+// batch in flight is allowed to finish, which persists its counters together
+// with the index after it; a batch that does not finish in time persists
+// nothing and is fetched again after the restart. Either way no batch is
+// counted twice.
+func (e *JobExecutor) CheckpointAll() error {
+	e.mu.Lock()
+	jobs := make([]*activeJob, 0, len(e.active))
+	for _, job := range e.active {
+		job.drain()
+		jobs = append(jobs, job)
+	}
+	e.mu.Unlock()
+
+	var firstErr error
+	for _, job := range jobs {
+		e.waitJob(job, "CheckpointAll")
+		run, err := e.runStore.GetRun(job.run.ID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to checkpoint run %s: %w", job.run.ID, err)
+			}
+			continue
+		}
+		e.logger.Info(cve.LogMsgTFJobCheckpointed, run.ID, run.StartIndex, run.FetchedCount)
+	}
+	return firstErr
 }
 
-// executeJob runs the actual fetch-and-store loop using Taskflow
-func (e *JobExecutor) executeJob(ctx context.Context, runID string) {
+// executeJob runs the fetch-and-store loop of a run with the provider of its
+// data type, using Taskflow. Batches run under ctx; the loop waits for
+// rate-limit tokens and workers under loopCtx, so draining the job stops it
+// between batches without aborting the one in flight.
+func (e *JobExecutor) executeJob(ctx, loopCtx context.Context, job *activeJob) {
+	runID := job.run.ID
 	// Signal completion when done (Pause/Stop will wait for this)
-	defer func() {
-		e.mu.Lock()
-		if e.doneChan != nil {
-			close(e.doneChan)
-		}
-		e.mu.Unlock()
-	}()
+	defer close(job.done)
 
 	// Get run details
 	run, err := e.runStore.GetRun(runID)
 	if err != nil {
 		e.logger.Error(cve.LogMsgTFFailedGetRun, err)
 		e.runStore.SetError(runID, fmt.Sprintf("failed to get run: %v", err))
+		e.notifyCompletion(runID)
 		return
 	}
 
 	currentIndex := run.StartIndex
 	batchSize := run.ResultsPerBatch
 
+	provider, err := e.newProvider(run)
+	if err != nil {
+		e.logger.Error("Invalid params of run %s: %v", runID, err)
+		e.runStore.SetError(runID, err.Error())
+		e.notifyCompletion(runID)
+		e.clearJob(job)
+		return
+	}
+	windowed, _ := provider.(WindowedProvider)
+	adaptive, _ := provider.(AdaptiveProvider)
+	if concurrent, ok := provider.(ConcurrentProvider); ok {
+		concurrent.SetFanOut(e.typeFanOut(run.DataType))
+	}
+
+	// Share workers and rate-limit tokens with other runs by priority
+	e.scheduler.Register(runID, run.Priority)
+	defer e.scheduler.Unregister(runID)
+
+	// Sample the counters as batches complete for RPCGetProviderMetrics
+	e.throughput.register(run)
+	defer e.throughput.unregister(runID)
+
 	e.logger.Info(cve.LogMsgTFJobLoopStarting,
 		runID, currentIndex, batchSize)
 
@@ -370,147 +702,114 @@ func (e *JobExecutor) executeJob(ctx context.Context, runID string) {
 	// Each iteration is a simple linear flow: fetch -> store
 	for {
 		select {
-		case <-ctx.Done():
+		case <-loopCtx.Done():
 			e.logger.Info(cve.LogMsgTFJobLoopCancelled, runID)
-			// Clear activeRun only on cancellation (Pause/Stop handle their own cleanup)
-			e.mu.Lock()
-			e.activeRun = nil
-			e.cancelFunc = nil
-			e.mu.Unlock()
+			// Clear the active run on cancellation
+			e.clearJob(job)
 			return
 		default:
-			tf := gotaskflow.NewTaskFlow(fmt.Sprintf("cve-batch-%d", currentIndex))
-
-			var fetchedVulns []struct {
-				CVE cve.CVEItem `json:"cve"`
+			// Heavy work only runs inside the maintenance window
+			if window := e.window.Load(); !window.Allowed() {
+				e.logger.Info("Run %s waiting for the maintenance window %q", runID, window.Spec())
+				if err := window.Wait(loopCtx); err != nil {
+					continue
+				}
+			}
+			// Rate limiting: one remote fetch per token, shared across runs
+			if err := e.scheduler.WaitToken(loopCtx, runID); err != nil {
+				continue
+			}
+			if adaptive != nil {
+				batchSize = adaptive.BatchSize()
 			}
+
+			tf := gotaskflow.NewTaskFlow(fmt.Sprintf("%s-batch-%d", run.DataType, currentIndex))
+
+			var records []Record
 			var fetchErr error
 
-			// Task 1: Fetch batch from remote
+			// Task 1: Fetch batch from the source
 			fetchTask := tf.NewTask("fetch", func() {
 				e.logger.Debug(cve.LogMsgTFFetchingBatch, runID, currentIndex, batchSize)
-
-				params := &rpc.FetchCVEsParams{
-					StartIndex:     currentIndex,
-					ResultsPerPage: batchSize,
-				}
-				result, err := e.rpcInvoker.InvokeRPC(ctx, "remote", "RPCFetchCVEs", params)
-
-				if err != nil {
-					fetchErr = err
-					return
-				}
-
-				// Parse the RPC response (it's a subprocess.Message)
-				msg, ok := result.(*subprocess.Message)
-				if !ok {
-					fetchErr = fmt.Errorf("invalid response type from remote")
-					return
-				}
-
-				// Check if it's an error message
-				if msg.Type == subprocess.MessageTypeError {
-					rpcErr := fmt.Errorf("error from remote: %s", msg.Error)
-					// Check for rate limit - should be handled differently
-					if isRateLimitError(rpcErr) {
-						e.logger.Warn("Rate limit detected, will retry with backoff")
-						fetchErr = rpcErr
-						return
-					}
-					fetchErr = rpcErr
-					return
-				}
-
-				// Parse the CVE response from payload
-				var response cve.CVEResponse
-				if err := jsonutil.Unmarshal(msg.Payload, &response); err != nil {
-					fetchErr = fmt.Errorf("failed to unmarshal CVE response: %w", err)
-					return
-				}
-
-				fetchedVulns = response.Vulnerabilities
+				e.runLog(runID, RunLogEvent{Type: RunLogBatchStarted, Index: currentIndex})
+				records, fetchErr = provider.Fetch(ctx, currentIndex, batchSize)
 			})
 
-			// Task 2: Store batch to local
+			// Task 2: Store batch
 			storeTask := tf.NewTask("store", func() {
 				if fetchErr != nil {
 					e.logger.Warn(cve.LogMsgTFSkippingStore, fetchErr)
 					return
 				}
-
-				if len(fetchedVulns) == 0 {
-					e.logger.Info(cve.LogMsgTFNoMoreCVEs, runID)
-					e.runStore.UpdateState(runID, StateCompleted)
+				if len(records) == 0 {
 					return
 				}
 
-				// Store each CVE with retry logic
-				storedCount := int64(0)
-				errorCount := int64(0)
-				maxRetries := 3
-
-				for _, vuln := range fetchedVulns {
-					params := &rpc.SaveCVEByIDParams{CVE: vuln.CVE}
-					var lastErr error
-
-					// Retry failed saves up to maxRetries times
-					for attempt := 0; attempt < maxRetries; attempt++ {
-						_, err := e.rpcInvoker.InvokeRPC(ctx, "local", "RPCSaveCVEByID", params)
-
-						if err == nil {
-							storedCount++
-							lastErr = nil
-							break
-						}
-
-						lastErr = err
-						if attempt < maxRetries-1 {
-							// Exponential backoff before retry
-							backoff := time.Duration(1<<uint(attempt)) * 100 * time.Millisecond
-							e.logger.Debug(cve.LogMsgTFFailedStoreCVE, vuln.CVE.ID, err)
-							e.logger.Debug("Retrying save for %s after %v (attempt %d/%d)", vuln.CVE.ID, backoff, attempt+1, maxRetries)
-							select {
-							case <-ctx.Done():
-								e.logger.Warn("Context cancelled while retrying save for %s", vuln.CVE.ID)
-								break
-							case <-time.After(backoff):
-							}
-						}
-					}
-
-					if lastErr != nil {
-						e.logger.Warn(cve.LogMsgTFFailedStoreCVE, vuln.CVE.ID, lastErr)
-						errorCount++
-					}
+				result, err := provider.Store(ctx, records)
+				if err != nil {
+					e.logger.Warn("Failed to store batch of run %s at index %d: %v", runID, currentIndex, err)
+					e.runLog(runID, RunLogEvent{Type: RunLogError, Index: currentIndex, Message: fmt.Sprintf("store batch: %v", err)})
+					result = StoreResult{Errors: int64(len(records))}
+				}
+				for _, failed := range result.Failed {
+					e.runLog(runID, RunLogEvent{Type: RunLogError, Index: currentIndex, ItemID: failed.ID, Message: fmt.Sprintf("store: %v", failed.Err)})
+					// Park the record rather than lose it
+					e.quarantine(runID, run.DataType, failed.ID, failed.Target, failed.Method, failed.Params, failed.Err, failed.Attempts)
 				}
 
-				e.logger.Info(cve.LogMsgTFStoredCVEsSuccess, storedCount, len(fetchedVulns))
-
-				// Update progress
-				e.runStore.UpdateProgress(runID, int64(len(fetchedVulns)), storedCount, errorCount)
+				// Update progress and move the resume point past the batch
+				e.runStore.SaveCheckpoint(runID, Checkpoint{
+					StartIndex: currentIndex + batchSize,
+					Fetched:    int64(len(records)),
+					Stored:     result.Stored,
+					Errors:     result.Errors,
+					BatchSize:  batchSize,
+				})
+				e.throughput.add(runID, int64(len(records)), result.Stored, result.Errors)
+				e.runLog(runID, RunLogEvent{
+					Type:    RunLogBatchCompleted,
+					Index:   currentIndex,
+					Fetched: int64(len(records)),
+					Stored:  result.Stored,
+					Errors:  result.Errors,
+				})
 			})
 
 			// Define task dependency: fetch must complete before store
 			fetchTask.Precede(storeTask)
 
-			// Execute the taskflow
-			e.executor.Run(tf).Wait()
+			// Execute the taskflow once the run is granted a worker. While
+			// the pool stays saturated the batch is rejected and retried
+			// after the next token, rather than piling up behind it.
+			err := e.queue.submit(loopCtx, func(ctx context.Context) (func(), error) {
+				return e.scheduler.AcquireWorker(ctx, runID)
+			}, func() {
+				e.executor.Run(tf).Wait()
+			})
+			if errors.Is(err, ErrExecutorSaturated) {
+				e.logger.Warn("Batch of run %s at index %d rejected: %v", runID, currentIndex, err)
+				e.runLog(runID, RunLogEvent{Type: RunLogError, Index: currentIndex, Message: fmt.Sprintf("submit: %v", err)})
+				continue
+			}
+			if err != nil {
+				continue
+			}
 
 			// Check if we should continue
 			if fetchErr != nil {
 				e.logger.Warn(cve.LogMsgTFFetchFailed, fetchErr)
+				e.runLog(runID, RunLogEvent{Type: RunLogError, Index: currentIndex, Message: fmt.Sprintf("fetch: %v", fetchErr)})
 				e.runStore.UpdateProgress(runID, 0, 0, 1)
+				e.throughput.add(runID, 0, 0, 1)
 
 				// Check if error is unrecoverable
 				if shouldGiveUp(fetchErr) {
 					e.logger.Error("Job failed after unrecoverable error: %v", fetchErr)
 					e.runStore.UpdateState(runID, StateFailed)
 					e.runStore.SetError(runID, fetchErr.Error())
-					// Clear activeRun on failure
-					e.mu.Lock()
-					e.activeRun = nil
-					e.cancelFunc = nil
-					e.mu.Unlock()
+					e.notifyCompletion(runID)
+					// Clear the active run on failure
+					e.clearJob(job)
 					return
 				}
 
@@ -527,48 +826,69 @@ func (e *JobExecutor) executeJob(ctx context.Context, runID string) {
 
 				// Wait before retrying
 				select {
-				case <-ctx.Done():
-					// Clear activeRun on cancellation during backoff wait
-					e.mu.Lock()
-					e.activeRun = nil
-					e.cancelFunc = nil
-					e.mu.Unlock()
+				case <-loopCtx.Done():
+					// Clear the active run on cancellation during backoff wait
+					e.clearJob(job)
 					return
 				case <-time.After(backoff):
 					continue
 				}
 			}
 
-			if len(fetchedVulns) == 0 {
+			if len(records) == 0 && windowed != nil {
+				if params, ok := windowed.NextWindow(); ok {
+					// Window exhausted: move on to the next one
+					currentIndex = 0
+					e.runStore.SaveCheckpoint(runID, Checkpoint{
+						StartIndex: currentIndex,
+						Params:     params,
+					})
+					continue
+				}
+			}
+
+			if len(records) == 0 {
 				// Job completed naturally
 				e.logger.Info(cve.LogMsgTFJobCompleted, runID)
-				e.runStore.UpdateState(runID, StateCompleted)
-				// Clear activeRun on completion
-				e.mu.Lock()
-				e.activeRun = nil
-				e.cancelFunc = nil
-				e.mu.Unlock()
+				var params map[string]interface{}
+				if windowed != nil {
+					params = windowed.CompletionParams()
+				}
+				e.completeRun(runID, params)
+				// Clear the active run on completion
+				e.clearJob(job)
 				return
 			}
 
 			// Move to next batch
 			currentIndex += batchSize
-
-			// Rate limiting
-			select {
-			case <-ctx.Done():
-				// Clear activeRun on cancellation during rate limit wait
-				e.mu.Lock()
-				e.activeRun = nil
-				e.cancelFunc = nil
-				e.mu.Unlock()
-				return
-			case <-time.After(1 * time.Second):
-			}
 		}
 	}
 }
 
+// completeRun marks a run completed, recording params such as the watermark
+// the next incremental sync starts from
+func (e *JobExecutor) completeRun(runID string, params map[string]interface{}) {
+	if len(params) > 0 {
+		e.runStore.SetParams(runID, params)
+	}
+	if err := e.runStore.UpdateState(runID, StateCompleted); err == nil {
+		e.notifyCompletion(runID)
+	}
+}
+
+// rpcResultError returns the error of an RPC call, treating an error reply
+// from the target service as a failure
+func rpcResultError(result interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if msg, ok := result.(*subprocess.Message); ok && msg.Type == subprocess.MessageTypeError {
+		return fmt.Errorf("%s failed: %s", msg.ID, msg.Error)
+	}
+	return nil
+}
+
 // isRateLimitError checks if an error is related to API rate limiting
 func isRateLimitError(err error) bool {
 	if err == nil {
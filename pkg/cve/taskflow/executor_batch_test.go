@@ -0,0 +1,222 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// batchRPCInvoker serves one page of CVEs and stores them by batch, or one
+// by one when failBatch is set
+type batchRPCInvoker struct {
+	page      []string
+	failBatch bool
+
+	mu       sync.Mutex
+	batches  int
+	singles  []string
+	existing map[string]bool
+}
+
+func (m *batchRPCInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch method {
+	case "RPCFetchCVEs":
+		var resp cve.CVEResponse
+		if params.(*rpc.FetchCVEsParams).StartIndex == 0 {
+			for _, id := range m.page {
+				resp.Vulnerabilities = append(resp.Vulnerabilities, struct {
+					CVE cve.CVEItem `json:"cve"`
+				}{CVE: cve.CVEItem{ID: id}})
+			}
+		}
+		return subprocess.NewSuccessResponse(req, resp)
+	case "RPCSaveCVEsBatch":
+		m.batches++
+		if m.failBatch {
+			return subprocess.NewErrorResponse(req, "database is locked"), nil
+		}
+		var result rpc.SaveCVEsBatchResult
+		for _, item := range params.(*rpc.SaveCVEsBatchParams).CVEs {
+			if m.existing[item.ID] {
+				result.Updated++
+			} else {
+				result.Inserted++
+			}
+		}
+		result.Success = true
+		return subprocess.NewSuccessResponse(req, result)
+	case "RPCSaveCVEByID":
+		m.singles = append(m.singles, params.(*rpc.SaveCVEByIDParams).CVE.ID)
+		return subprocess.NewSuccessResponse(req, map[string]interface{}{"success": true})
+	}
+	return nil, fmt.Errorf("unexpected method %s", method)
+}
+
+// runToCompletion starts a CVE run and waits for it to complete
+func runToCompletion(t *testing.T, invoker RPCInvoker, runID string) *JobRun {
+	t.Helper()
+	store := NewTempRunStore(t)
+	executor := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+	executor.scheduler = NewFairScheduler(4, time.Millisecond)
+	if err := executor.Start(context.Background(), runID, 0, 10); err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if run, _ := store.GetRun(runID); run != nil && run.State == StateCompleted {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Run %s did not complete", runID)
+	return nil
+}
+
+func TestJobExecutor_BatchSave(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_BatchSave", nil, func(t *testing.T, tx *gorm.DB) {
+		invoker := &batchRPCInvoker{
+			page:     []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"},
+			existing: map[string]bool{"CVE-2024-0002": true},
+		}
+		run := runToCompletion(t, invoker, "batch")
+		if run.FetchedCount != 3 || run.StoredCount != 3 || run.ErrorCount != 0 {
+			t.Errorf("Expected 3 fetched and stored, got %+v", run)
+		}
+		if invoker.batches != 1 || len(invoker.singles) != 0 {
+			t.Errorf("Expected one batch save, got %d batches and singles %v", invoker.batches, invoker.singles)
+		}
+
+		// A single CVE is saved on its own
+		single := &batchRPCInvoker{page: []string{"CVE-2024-0001"}}
+		runToCompletion(t, single, "single")
+		if single.batches != 0 || len(single.singles) != 1 {
+			t.Errorf("Expected one single save, got %d batches and singles %v", single.batches, single.singles)
+		}
+	})
+}
+
+func TestJobExecutor_BatchSaveFallback(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_BatchSaveFallback", nil, func(t *testing.T, tx *gorm.DB) {
+		invoker := &batchRPCInvoker{page: []string{"CVE-2024-0001", "CVE-2024-0002"}, failBatch: true}
+		run := runToCompletion(t, invoker, "fallback")
+		if run.StoredCount != 2 || len(invoker.singles) != 2 {
+			t.Errorf("Expected both CVEs saved one by one, got %+v and singles %v", run, invoker.singles)
+		}
+	})
+}
+
+// checkpointRPCInvoker serves total CVEs in pages and holds the batch save of
+// the page at block until release is closed
+type checkpointRPCInvoker struct {
+	total   int
+	block   string
+	saving  chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	starts []int
+}
+
+func (m *checkpointRPCInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	switch method {
+	case "RPCFetchCVEs":
+		p := params.(*rpc.FetchCVEsParams)
+		m.mu.Lock()
+		m.starts = append(m.starts, p.StartIndex)
+		m.mu.Unlock()
+		var resp cve.CVEResponse
+		for i := p.StartIndex; i < p.StartIndex+p.ResultsPerPage && i < m.total; i++ {
+			resp.Vulnerabilities = append(resp.Vulnerabilities, struct {
+				CVE cve.CVEItem `json:"cve"`
+			}{CVE: cve.CVEItem{ID: fmt.Sprintf("CVE-2024-%04d", i)}})
+		}
+		return subprocess.NewSuccessResponse(req, resp)
+	case "RPCSaveCVEsBatch":
+		items := params.(*rpc.SaveCVEsBatchParams).CVEs
+		if items[0].ID == m.block {
+			close(m.saving)
+			<-m.release
+		}
+		return subprocess.NewSuccessResponse(req, rpc.SaveCVEsBatchResult{Success: true, Inserted: len(items)})
+	}
+	return nil, fmt.Errorf("unexpected method %s", method)
+}
+
+func TestJobExecutor_CheckpointAll(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_CheckpointAll", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		invoker := &checkpointRPCInvoker{
+			total:   30,
+			block:   "CVE-2024-0010",
+			saving:  make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		executor := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+		if err := executor.Start(context.Background(), "checkpoint", 0, 10); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+
+		// Checkpoint while the second batch is being stored
+		select {
+		case <-invoker.saving:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Second batch was not stored")
+		}
+		done := make(chan error, 1)
+		go func() { done <- executor.CheckpointAll() }()
+		time.Sleep(20 * time.Millisecond)
+		close(invoker.release)
+		if err := <-done; err != nil {
+			t.Fatalf("CheckpointAll failed: %v", err)
+		}
+
+		run, _ := store.GetRun("checkpoint")
+		if run.State != StateRunning || run.StartIndex != 20 || run.FetchedCount != 20 || run.StoredCount != 20 {
+			t.Fatalf("Expected a running run checkpointed after the in-flight batch, got %+v", run)
+		}
+		executor.mu.RLock()
+		active := len(executor.active)
+		executor.mu.RUnlock()
+		if active != 0 {
+			t.Fatalf("Expected no job loops after CheckpointAll, got %d", active)
+		}
+
+		// A restarted executor resumes from the checkpoint
+		invoker.mu.Lock()
+		invoker.starts = nil
+		invoker.mu.Unlock()
+		restarted := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+		restarted.scheduler = NewFairScheduler(4, time.Millisecond)
+		if err := restarted.RecoverRuns(context.Background()); err != nil {
+			t.Fatalf("RecoverRuns failed: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if run, _ = store.GetRun("checkpoint"); run.State == StateCompleted {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run.State != StateCompleted || run.FetchedCount != 30 || run.StoredCount != 30 {
+			t.Fatalf("Expected the run completed with each CVE counted once, got %+v", run)
+		}
+		invoker.mu.Lock()
+		defer invoker.mu.Unlock()
+		if len(invoker.starts) == 0 || invoker.starts[0] != 20 {
+			t.Errorf("Expected the restart to fetch from index 20, got %v", invoker.starts)
+		}
+	})
+}
@@ -37,7 +37,7 @@ func newTestLogger() *common.Logger {
 	return common.NewLogger(io.Discard, "", common.InfoLevel)
 }
 
-// TestJobExecutor_ConcurrentStartPrevention verifies that only one job can start at a time
+// TestJobExecutor_ConcurrentStartPrevention verifies that only one job of a data type can start at a time
 func TestJobExecutor_ConcurrentStartPrevention(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestJobExecutor_ConcurrentStartPrevention", nil, func(t *testing.T, tx *gorm.DB) {
 		invoker := newMockRPCInvoker()
@@ -49,7 +49,7 @@ func TestJobExecutor_ConcurrentStartPrevention(t *testing.T) {
 		defer store.Close()
 		defer os.Remove("test_concurrent_start.db")
 
-		executor := NewJobExecutor(invoker, store, logger, 100)
+		executor := NewJobExecutor(invoker, store, logger, 100, nil)
 		ctx := context.Background()
 
 		var wg sync.WaitGroup
@@ -62,7 +62,7 @@ func TestJobExecutor_ConcurrentStartPrevention(t *testing.T) {
 			go func(idx int) {
 				defer wg.Done()
 				runID := "concurrent-" + string(rune('0'+idx))
-				err := executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE)
+				err := executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE, PriorityNormal)
 				if err == nil {
 					mu.Lock()
 					successCount++
@@ -79,14 +79,68 @@ func TestJobExecutor_ConcurrentStartPrevention(t *testing.T) {
 		}
 
 		// Clean up
-		activeRun, _ := executor.GetActiveRun()
-		if activeRun != nil {
+		activeRuns, _ := executor.GetActiveRuns()
+		for _, activeRun := range activeRuns {
 			executor.Stop(activeRun.ID)
 		}
 	})
 
 }
 
+// TestJobExecutor_ConcurrentDataTypes verifies runs of different data types
+// are active at the same time and controlled independently
+func TestJobExecutor_ConcurrentDataTypes(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_ConcurrentDataTypes", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(newMockRPCInvoker(), store, newTestLogger(), 4, nil)
+		ctx := context.Background()
+
+		// CWE runs need a provider; the CVE one pages the mock just as well
+		if err := executor.StartTyped(ctx, "cwe-run", 0, 10, DataTypeCWE, PriorityNormal); err == nil {
+			t.Fatal("Expected a CWE run without a provider to be rejected")
+		}
+		if err := executor.RegisterProvider(DataTypeCWE, newCVEProvider); err != nil {
+			t.Fatalf("RegisterProvider failed: %v", err)
+		}
+
+		if err := executor.StartTyped(ctx, "cve-run", 0, 10, DataTypeCVE, PriorityNormal); err != nil {
+			t.Fatalf("Failed to start CVE run: %v", err)
+		}
+		if err := executor.StartTyped(ctx, "cwe-run", 0, 10, DataTypeCWE, PriorityNormal); err != nil {
+			t.Fatalf("Failed to start CWE run next to the CVE run: %v", err)
+		}
+		defer executor.Stop("cve-run")
+		if err := executor.StartTyped(ctx, "cve-run-2", 0, 10, DataTypeCVE, PriorityNormal); err == nil {
+			t.Fatal("Expected a second CVE run to be rejected")
+		}
+
+		runs, err := executor.GetActiveRuns()
+		if err != nil || len(runs) != 2 || runs[0].ID != "cve-run" || runs[1].ID != "cwe-run" {
+			t.Fatalf("Expected both runs active, got %v (%v)", runs, err)
+		}
+
+		if err := executor.Pause("cwe-run"); err != nil {
+			t.Fatalf("Pause failed: %v", err)
+		}
+		if runs, _ := executor.GetActiveRuns(); len(runs) != 1 || runs[0].ID != "cve-run" {
+			t.Errorf("Expected only the CVE run to stay active, got %v", runs)
+		}
+		if run, err := executor.GetCurrentRun(DataTypeCWE); err != nil || run == nil || run.ID != "cwe-run" || run.State != StatePaused {
+			t.Errorf("Expected the paused CWE run to be current, got %+v (%v)", run, err)
+		}
+		if run, _ := executor.GetCurrentRun(DataTypeCAPEC); run != nil {
+			t.Errorf("Expected no current CAPEC run, got %+v", run)
+		}
+
+		if err := executor.Resume(ctx, "cwe-run"); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+		if err := executor.Stop("cwe-run"); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+	})
+}
+
 // TestJobExecutor_PauseResumeStateTransitions verifies pause/resume works
 func TestJobExecutor_PauseResumeStateTransitions(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestJobExecutor_PauseResumeStateTransitions", nil, func(t *testing.T, tx *gorm.DB) {
@@ -99,12 +153,12 @@ func TestJobExecutor_PauseResumeStateTransitions(t *testing.T) {
 		defer store.Close()
 		defer os.Remove("test_pause_resume.db")
 
-		executor := NewJobExecutor(invoker, store, logger, 100)
+		executor := NewJobExecutor(invoker, store, logger, 100, nil)
 		ctx := context.Background()
 		runID := "test-pause-resume"
 
 		// Start job
-		err = executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE)
+		err = executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE, PriorityNormal)
 		if err != nil {
 			t.Fatalf("StartTyped failed: %v", err)
 		}
@@ -162,12 +216,12 @@ func TestJobExecutor_StopFromPaused(t *testing.T) {
 		defer store.Close()
 		defer os.Remove("test_stop_paused.db")
 
-		executor := NewJobExecutor(invoker, store, logger, 100)
+		executor := NewJobExecutor(invoker, store, logger, 100, nil)
 		ctx := context.Background()
 		runID := "test-stop-paused"
 
 		// Start job
-		err = executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE)
+		err = executor.StartTyped(ctx, runID, 0, 100, DataTypeCVE, PriorityNormal)
 		if err != nil {
 			t.Fatalf("StartTyped failed: %v", err)
 		}
@@ -230,7 +284,7 @@ func TestJobExecutor_RecoveryAfterCrash(t *testing.T) {
 		}
 
 		// Create new executor (simulating restart)
-		executor := NewJobExecutor(invoker, store, logger, 100)
+		executor := NewJobExecutor(invoker, store, logger, 100, nil)
 		ctx := context.Background()
 
 		// Run recovery - should NOT auto-resume paused jobs
@@ -240,11 +294,11 @@ func TestJobExecutor_RecoveryAfterCrash(t *testing.T) {
 		}
 
 		// Verify the run was NOT auto-resumed (paused jobs stay paused)
-		activeRun, err := executor.GetActiveRun()
+		activeRuns, err := executor.GetActiveRuns()
 		if err != nil {
-			t.Fatalf("GetActiveRun failed: %v", err)
+			t.Fatalf("GetActiveRuns failed: %v", err)
 		}
-		if activeRun != nil {
+		if len(activeRuns) != 0 {
 			t.Error("Expected no active run after recovery (paused job should stay paused)")
 		}
 
@@ -290,7 +344,7 @@ func TestJobExecutor_PausedRunStaysPaused(t *testing.T) {
 		}
 
 		// Create new executor and run recovery
-		executor := NewJobExecutor(invoker, store, logger, 100)
+		executor := NewJobExecutor(invoker, store, logger, 100, nil)
 		ctx := context.Background()
 
 		err = executor.RecoverRuns(ctx)
@@ -299,11 +353,11 @@ func TestJobExecutor_PausedRunStaysPaused(t *testing.T) {
 		}
 
 		// Verify the run was NOT auto-resumed
-		activeRun, err := executor.GetActiveRun()
+		activeRuns, err := executor.GetActiveRuns()
 		if err != nil {
-			t.Fatalf("GetActiveRun failed: %v", err)
+			t.Fatalf("GetActiveRuns failed: %v", err)
 		}
-		if activeRun != nil {
+		if len(activeRuns) != 0 {
 			t.Error("Expected no active run after recovery (paused run should stay paused)")
 		}
 
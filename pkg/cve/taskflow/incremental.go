@@ -0,0 +1,60 @@
+package taskflow
+
+import (
+	"fmt"
+	"time"
+)
+
+// Run parameters of incremental CVE syncs
+const (
+	// ParamLastModStartDate makes a CVE run fetch only the CVEs modified
+	// since the given time (RFC 3339) instead of the whole catalog. It
+	// advances as windows are exhausted, so a resumed run continues from the
+	// window it was in.
+	ParamLastModStartDate = "last_mod_start_date"
+	// ParamWatermark is set on a completed incremental run to the end of its
+	// last window (RFC 3339): the last_mod_start_date of the next sync
+	ParamWatermark = "watermark"
+)
+
+// maxModifiedWindow is the longest lastModStartDate..lastModEndDate range
+// the NVD API accepts (remote.MaxModifiedWindow)
+const maxModifiedWindow = 120 * 24 * time.Hour
+
+// modifiedWindow is the lastModStartDate..lastModEndDate range an
+// incremental run is fetching
+type modifiedWindow struct {
+	since time.Time
+	until time.Time
+	// final is true for the window that reaches the time it was opened at
+	final bool
+}
+
+// newModifiedWindow opens the window starting at since, as long as NVD
+// allows but not past now
+func newModifiedWindow(since, now time.Time) *modifiedWindow {
+	w := &modifiedWindow{since: since, until: since.Add(maxModifiedWindow)}
+	if !w.until.Before(now) {
+		w.until = now
+		w.final = true
+	}
+	return w
+}
+
+// lastModStartDate returns the last_mod_start_date parameter of a run, or
+// ok=false for a full sync
+func lastModStartDate(params map[string]interface{}) (since time.Time, ok bool, err error) {
+	v, ok := params[ParamLastModStartDate]
+	if !ok || v == nil || v == "" {
+		return time.Time{}, false, nil
+	}
+	s, isString := v.(string)
+	if !isString {
+		return time.Time{}, false, fmt.Errorf("%s must be an RFC 3339 time string", ParamLastModStartDate)
+	}
+	since, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s: %w", ParamLastModStartDate, err)
+	}
+	return since, true, nil
+}
@@ -0,0 +1,127 @@
+package taskflow
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// windowRPCInvoker serves RPCFetchCVEsModified with one CVE on the first page
+// of each window and records the windows requested
+type windowRPCInvoker struct {
+	mu      sync.Mutex
+	windows []rpc.FetchCVEsModifiedParams
+	saved   []string
+}
+
+func (m *windowRPCInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	req := &subprocess.Message{Type: subprocess.MessageTypeRequest, ID: method}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch method {
+	case "RPCFetchCVEsModified":
+		p := *params.(*rpc.FetchCVEsModifiedParams)
+		m.windows = append(m.windows, p)
+		var resp cve.CVEResponse
+		if p.StartIndex == 0 {
+			resp.Vulnerabilities = append(resp.Vulnerabilities, struct {
+				CVE cve.CVEItem `json:"cve"`
+			}{CVE: cve.CVEItem{ID: "CVE-2024-" + p.LastModStartDate.Format("0102")}})
+		}
+		return subprocess.NewSuccessResponse(req, resp)
+	case "RPCSaveCVEByID":
+		m.saved = append(m.saved, params.(*rpc.SaveCVEByIDParams).CVE.ID)
+		return subprocess.NewSuccessResponse(req, map[string]interface{}{"success": true})
+	}
+	return subprocess.NewErrorResponse(req, "unexpected method "+method), nil
+}
+
+func TestModifiedWindow(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestModifiedWindow", nil, func(t *testing.T, tx *gorm.DB) {
+		now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		w := newModifiedWindow(now.Add(-200*24*time.Hour), now)
+		if w.final || w.until.Sub(w.since) != maxModifiedWindow {
+			t.Errorf("Expected a full non-final window, got %+v", w)
+		}
+		w = newModifiedWindow(w.until, now)
+		if !w.final || !w.until.Equal(now) {
+			t.Errorf("Expected the final window to end now, got %+v", w)
+		}
+
+		if _, ok, err := lastModStartDate(map[string]interface{}{}); ok || err != nil {
+			t.Errorf("Expected a full sync without the parameter, got ok=%v err=%v", ok, err)
+		}
+		since, ok, err := lastModStartDate(map[string]interface{}{ParamLastModStartDate: "2024-01-02T03:04:05Z"})
+		if !ok || err != nil || !since.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("Unexpected parse result %v ok=%v err=%v", since, ok, err)
+		}
+		for _, bad := range []interface{}{"yesterday", 42} {
+			if _, _, err := lastModStartDate(map[string]interface{}{ParamLastModStartDate: bad}); err == nil {
+				t.Errorf("Expected %v to be rejected", bad)
+			}
+		}
+	})
+}
+
+func TestJobExecutor_IncrementalSync(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_IncrementalSync", nil, func(t *testing.T, tx *gorm.DB) {
+		invoker := &windowRPCInvoker{}
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+
+		future := map[string]interface{}{ParamLastModStartDate: time.Now().Add(time.Hour).Format(time.RFC3339)}
+		if err := executor.StartTypedWithParams(context.Background(), "future", 0, 10, DataTypeCVE, PriorityNormal, future); err == nil {
+			t.Fatal("Expected a start date in the future to be rejected")
+		}
+
+		since := time.Now().Add(-150 * 24 * time.Hour).UTC().Truncate(time.Second)
+		params := map[string]interface{}{ParamLastModStartDate: since.Format(time.RFC3339)}
+		if err := executor.StartTypedWithParams(context.Background(), "incremental", 0, 10, DataTypeCVE, PriorityNormal, params); err != nil {
+			t.Fatalf("Failed to start incremental run: %v", err)
+		}
+
+		var run *JobRun
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			run, _ = store.GetRun("incremental")
+			if run != nil && run.State == StateCompleted && run.Params[ParamWatermark] != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run == nil || run.State != StateCompleted {
+			t.Fatalf("Expected the run to complete, got %+v", run)
+		}
+
+		invoker.mu.Lock()
+		defer invoker.mu.Unlock()
+		// Two windows, each fetched until an empty page
+		if len(invoker.windows) != 4 {
+			t.Fatalf("Expected 4 fetches over two windows, got %+v", invoker.windows)
+		}
+		first, second := invoker.windows[0], invoker.windows[2]
+		if !first.LastModStartDate.Equal(since) || first.LastModEndDate.Sub(first.LastModStartDate) != maxModifiedWindow {
+			t.Errorf("Unexpected first window %+v", first)
+		}
+		if invoker.windows[1].StartIndex != 10 || second.StartIndex != 0 || !second.LastModStartDate.Equal(first.LastModEndDate) {
+			t.Errorf("Expected the second window to start where the first ended, got %+v", invoker.windows)
+		}
+		if len(invoker.saved) != 2 {
+			t.Errorf("Expected one CVE saved per window, got %v", invoker.saved)
+		}
+
+		watermark, err := time.Parse(time.RFC3339, run.Params[ParamWatermark].(string))
+		if err != nil || watermark.Before(second.LastModEndDate.Truncate(time.Second)) {
+			t.Errorf("Expected the watermark to be the end of the last window %v, got %v (%v)", second.LastModEndDate, watermark, err)
+		}
+	})
+}
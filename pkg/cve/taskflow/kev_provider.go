@@ -0,0 +1,105 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+)
+
+// kevProvider imports the CISA KEV catalog: it fetches the catalog once from
+// the remote service and stores it in the local one batch by batch, so a run
+// is checkpointed and resumed like a CVE run. Batches only add or update
+// entries; RPCImportKEV with a catalog path replaces the whole catalog.
+type kevProvider struct {
+	invoker RPCInvoker
+	logger  *common.Logger
+
+	once    sync.Once
+	entries []cve.KEVEntry
+	err     error
+}
+
+// newKEVProvider is the ProviderFactory of DataTypeKEV
+func newKEVProvider(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+	return &kevProvider{invoker: invoker, logger: logger}, nil
+}
+
+// Fetch returns a page of the catalog entries, fetching the catalog on the
+// first call
+func (p *kevProvider) Fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error) {
+	p.once.Do(func() {
+		p.entries, p.err = p.fetchCatalog(ctx)
+	})
+	if p.err != nil {
+		return nil, p.err
+	}
+	var records []Record
+	for i := startIndex; i < len(p.entries) && i < startIndex+batchSize; i++ {
+		records = append(records, p.entries[i])
+	}
+	return records, nil
+}
+
+// fetchCatalog fetches the KEV catalog with RPCFetchKEV
+func (p *kevProvider) fetchCatalog(ctx context.Context) ([]cve.KEVEntry, error) {
+	result, err := p.invoker.InvokeRPC(ctx, "remote", "RPCFetchKEV", nil)
+	if err := rpcResultError(result, err); err != nil {
+		return nil, err
+	}
+	msg, ok := result.(*subprocess.Message)
+	if !ok {
+		return nil, fmt.Errorf("invalid response type from remote")
+	}
+	var catalog cve.KEVCatalog
+	if err := jsonutil.Unmarshal(msg.Payload, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal KEV catalog: %w", err)
+	}
+	p.logger.Info("Fetched KEV catalog %s with %d entries", catalog.CatalogVersion, len(catalog.Vulnerabilities))
+	return catalog.Vulnerabilities, nil
+}
+
+// Store imports a batch of catalog entries with RPCImportKEV. The import is a
+// single transaction, so if it fails every entry of the batch is quarantined.
+func (p *kevProvider) Store(ctx context.Context, records []Record) (StoreResult, error) {
+	var result StoreResult
+	entries := make([]cve.KEVEntry, 0, len(records))
+	for _, record := range records {
+		entry, ok := record.(cve.KEVEntry)
+		if !ok {
+			return result, fmt.Errorf("unexpected record type %T", record)
+		}
+		entries = append(entries, entry)
+	}
+
+	resp, err := p.invoker.InvokeRPC(ctx, "local", "RPCImportKEV", &rpc.ImportKEVParams{Vulnerabilities: entries})
+	if err = rpcResultError(resp, err); err == nil {
+		var imported rpc.ImportKEVResult
+		if msg, ok := resp.(*subprocess.Message); !ok {
+			err = fmt.Errorf("invalid response type from local")
+		} else if err = jsonutil.Unmarshal(msg.Payload, &imported); err == nil {
+			p.logger.Info("Imported %d KEV entries, %d of them stored CVEs", imported.Listed, imported.Matched)
+			result.Stored = int64(len(entries))
+			return result, nil
+		}
+	}
+
+	p.logger.Warn("Failed to import %d KEV entries: %v", len(entries), err)
+	for _, entry := range entries {
+		result.Errors++
+		result.Failed = append(result.Failed, FailedRecord{
+			ID:       entry.CVEID,
+			Target:   "local",
+			Method:   "RPCImportKEV",
+			Params:   &rpc.ImportKEVParams{Vulnerabilities: []cve.KEVEntry{entry}},
+			Err:      err,
+			Attempts: 1,
+		})
+	}
+	return result, nil
+}
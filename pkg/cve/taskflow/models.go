@@ -1,6 +1,9 @@
 package taskflow
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // DataType represents the type of data being populated
 type DataType string
@@ -11,8 +14,28 @@ const (
 	DataTypeCAPEC  DataType = "capec"
 	DataTypeATTACK DataType = "attack"
 	DataTypeCCE    DataType = "cce"
+	DataTypeKEV    DataType = "kev"
 )
 
+// dataTypes are the known data types
+var dataTypes = map[DataType]bool{
+	DataTypeCVE:    true,
+	DataTypeCWE:    true,
+	DataTypeCAPEC:  true,
+	DataTypeATTACK: true,
+	DataTypeCCE:    true,
+	DataTypeKEV:    true,
+}
+
+// ParseDataType validates a data type name
+func ParseDataType(s string) (DataType, error) {
+	d := DataType(s)
+	if !dataTypes[d] {
+		return "", fmt.Errorf("invalid data type %q (must be cve, cwe, capec, attack, cce or kev)", s)
+	}
+	return d, nil
+}
+
 // DataProgress tracks progress for each data type
 type DataProgress struct {
 	TotalCount     int64     `json:"total_count"`
@@ -21,6 +44,9 @@ type DataProgress struct {
 	StartTime      time.Time `json:"start_time"`
 	LastUpdate     time.Time `json:"last_update"`
 	ErrorMessage   string    `json:"error_message,omitempty"`
+	// BatchSize is the size of the last checkpointed batch, which an
+	// AdaptiveProvider tunes
+	BatchSize int `json:"batch_size,omitempty"`
 }
 
 // JobRun represents a single job execution instance with full state
@@ -30,6 +56,7 @@ type JobRun struct {
 	DataType        DataType  `json:"data_type"`
 	StartIndex      int       `json:"start_index"`
 	ResultsPerBatch int       `json:"results_per_batch"`
+	Priority        Priority  `json:"priority,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 	// Overall progress
@@ -41,4 +68,6 @@ type JobRun struct {
 	Progress map[DataType]DataProgress `json:"progress"`
 	// Configuration parameters
 	Params map[string]interface{} `json:"params,omitempty"`
+	// Outcome of the completion callback, once sent (see ParamCallbackURL)
+	Callback *CallbackResult `json:"callback,omitempty"`
 }
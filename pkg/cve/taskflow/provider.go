@@ -0,0 +1,135 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+)
+
+// Record is an item a provider fetched, such as a CVE. The job loop passes
+// records from Fetch to Store without looking into them.
+type Record interface{}
+
+// Provider fetches the records of a data type from its source in batches and
+// stores them. The job loop drives it: it fetches the batch at the run's
+// index, stores it, checkpoints the index after it, and completes the run
+// when a fetch returns no records.
+type Provider interface {
+	// Fetch returns up to batchSize records from startIndex. No records
+	// means the source is exhausted.
+	Fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error)
+	// Store saves fetched records. An error fails the whole batch; records
+	// that fail on their own are reported in the result instead.
+	Store(ctx context.Context, records []Record) (StoreResult, error)
+}
+
+// WindowedProvider is a provider that fetches its source window by window,
+// each paged from index 0, such as an incremental sync over modification
+// dates
+type WindowedProvider interface {
+	Provider
+	// NextWindow moves to the window after the exhausted one and returns
+	// the run params to checkpoint, so a resumed run starts from it. It
+	// returns false if the exhausted window was the last.
+	NextWindow() (map[string]interface{}, bool)
+	// CompletionParams returns the run params to record when the run
+	// completes
+	CompletionParams() map[string]interface{}
+}
+
+// AdaptiveProvider is a provider that tunes the size of its batches to how
+// its source responds, such as the CVE provider growing them while NVD is
+// fast and shrinking them after timeouts
+type AdaptiveProvider interface {
+	Provider
+	// BatchSize returns the size of the next batch. The job loop records it
+	// on the run's progress with each checkpoint.
+	BatchSize() int
+}
+
+// ConcurrentProvider is a provider that stores the records of a batch in
+// parallel, such as the CVE provider saving CVEs one by one after a failed
+// batch save. The job loop hands it a FanOut bounded by the concurrency limit
+// of the data type (see JobExecutor.SetConcurrencyLimit).
+type ConcurrentProvider interface {
+	Provider
+	SetFanOut(fanOut FanOut)
+}
+
+// FanOut calls fn for every index below n, in parallel as far as the slots
+// allow, and returns once all calls returned. It returns ctx's error if ctx
+// was done before every call started.
+type FanOut func(ctx context.Context, n int, fn func(i int)) error
+
+// sequentialFanOut is the FanOut of a provider used outside the job loop
+func sequentialFanOut(ctx context.Context, n int, fn func(i int)) error {
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fn(i)
+	}
+	return nil
+}
+
+// StoreResult is the outcome of storing a batch of records
+type StoreResult struct {
+	Stored int64
+	Errors int64
+	// Failed are the records that could not be stored; they are quarantined
+	// so RPCRetryQuarantined can replay them
+	Failed []FailedRecord
+}
+
+// FailedRecord is a record whose store call failed, with what is needed to
+// replay it
+type FailedRecord struct {
+	ID       string      // e.g. "CVE-2024-0001"
+	Target   string      // service of the store call, e.g. "local"
+	Method   string      // RPC method of the store call
+	Params   interface{} // params of the store call
+	Err      error
+	Attempts int
+}
+
+// ProviderFactory creates the provider of a run from its params. An error
+// fails the run.
+type ProviderFactory func(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error)
+
+// RegisterProvider sets the provider factory of a data type, replacing any
+// previous one. Runs of a data type can only start once its provider is
+// registered; the CVE and KEV providers are registered by NewJobExecutor.
+func (e *JobExecutor) RegisterProvider(dataType DataType, factory ProviderFactory) error {
+	if _, err := ParseDataType(string(dataType)); err != nil {
+		return err
+	}
+	if factory == nil {
+		return fmt.Errorf("provider factory of %s is nil", dataType)
+	}
+	e.mu.Lock()
+	e.providers[dataType] = factory
+	e.mu.Unlock()
+	return nil
+}
+
+// providerFactoryLocked returns the provider factory of a data type (caller
+// must hold lock)
+func (e *JobExecutor) providerFactoryLocked(dataType DataType) (ProviderFactory, error) {
+	factory := e.providers[dataType]
+	if factory == nil {
+		return nil, fmt.Errorf("no provider registered for data type %s", dataType)
+	}
+	return factory, nil
+}
+
+// newProvider creates the provider of a run
+func (e *JobExecutor) newProvider(run *JobRun) (Provider, error) {
+	e.mu.RLock()
+	factory, err := e.providerFactoryLocked(run.DataType)
+	e.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return factory(run, e.rpcInvoker, e.logger)
+}
@@ -0,0 +1,222 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/maintenance"
+	"github.com/cyw0ng95/v2e/pkg/cve"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// listProvider serves a fixed list of records and fails to store the ones
+// listed in fail
+type listProvider struct {
+	records []string
+	fail    map[string]bool
+
+	mu     sync.Mutex
+	stored []string
+}
+
+func (p *listProvider) Fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error) {
+	var batch []Record
+	for i := startIndex; i < len(p.records) && i < startIndex+batchSize; i++ {
+		batch = append(batch, p.records[i])
+	}
+	return batch, nil
+}
+
+func (p *listProvider) Store(ctx context.Context, records []Record) (StoreResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var result StoreResult
+	for _, record := range records {
+		id := record.(string)
+		if p.fail[id] {
+			result.Errors++
+			result.Failed = append(result.Failed, FailedRecord{
+				ID: id, Target: "local", Method: "RPCSaveCAPEC", Params: map[string]string{"id": id}, Err: fmt.Errorf("constraint failed"), Attempts: 1,
+			})
+			continue
+		}
+		p.stored = append(p.stored, id)
+		result.Stored++
+	}
+	return result, nil
+}
+
+func TestJobExecutor_RegisteredProvider(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_RegisteredProvider", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(nil, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+		ctx := context.Background()
+
+		if err := executor.StartTyped(ctx, "capec-run", 0, 2, DataTypeCAPEC, PriorityNormal); err == nil {
+			t.Fatal("Expected a run without a provider to be rejected")
+		}
+		if err := executor.RegisterProvider("nvd", nil); err == nil {
+			t.Fatal("Expected an unknown data type to be rejected")
+		}
+
+		provider := &listProvider{
+			records: []string{"CAPEC-1", "CAPEC-2", "CAPEC-3", "CAPEC-4", "CAPEC-5"},
+			fail:    map[string]bool{"CAPEC-4": true},
+		}
+		var gotRun *JobRun
+		err := executor.RegisterProvider(DataTypeCAPEC, func(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+			gotRun = run
+			return provider, nil
+		})
+		if err != nil {
+			t.Fatalf("RegisterProvider failed: %v", err)
+		}
+
+		if err := executor.StartTyped(ctx, "capec-run", 0, 2, DataTypeCAPEC, PriorityNormal); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+		var run *JobRun
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if run, _ = store.GetRun("capec-run"); run != nil && run.State == StateCompleted {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run == nil || run.State != StateCompleted {
+			t.Fatalf("Run did not complete: %+v", run)
+		}
+		if gotRun == nil || gotRun.ID != "capec-run" {
+			t.Errorf("Expected the factory to get the run, got %+v", gotRun)
+		}
+		if run.FetchedCount != 5 || run.StoredCount != 4 || run.ErrorCount != 1 {
+			t.Errorf("Expected 5 fetched, 4 stored and 1 error, got %+v", run)
+		}
+		if len(provider.stored) != 4 {
+			t.Errorf("Expected 4 records stored, got %v", provider.stored)
+		}
+
+		items, total, _, err := executor.ListQuarantined(DataTypeCAPEC, 0, 10)
+		if err != nil || total != 1 || items[0].ItemID != "CAPEC-4" || items[0].Method != "RPCSaveCAPEC" {
+			t.Errorf("Expected CAPEC-4 quarantined, got %+v (total %d, err %v)", items, total, err)
+		}
+	})
+}
+
+func TestJobExecutor_MaintenanceWindow(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_MaintenanceWindow", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(nil, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+		ctx := context.Background()
+
+		provider := &listProvider{records: []string{"CAPEC-1", "CAPEC-2", "CAPEC-3"}}
+		if err := executor.RegisterProvider(DataTypeCAPEC, func(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+			return provider, nil
+		}); err != nil {
+			t.Fatalf("RegisterProvider failed: %v", err)
+		}
+
+		// A window open only on a day three days from now is closed today
+		closed, err := maintenance.Parse(time.Now().AddDate(0, 0, 3).Weekday().String()[:3])
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		executor.SetMaintenanceWindow(closed)
+		if err := executor.StartTyped(ctx, "capec-run", 0, 2, DataTypeCAPEC, PriorityNormal); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if run, _ := store.GetRun("capec-run"); run == nil || run.State != StateRunning || run.FetchedCount != 0 {
+			t.Fatalf("Expected the run to wait for the window without fetching, got %+v", run)
+		}
+
+		// A waiting run can still be paused, and runs once the window is open
+		if err := executor.Pause("capec-run"); err != nil {
+			t.Fatalf("Pause failed: %v", err)
+		}
+		executor.SetMaintenanceWindow(nil)
+		if err := executor.Resume(ctx, "capec-run"); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+		var run *JobRun
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if run, _ = store.GetRun("capec-run"); run != nil && run.State == StateCompleted {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run == nil || run.State != StateCompleted || run.StoredCount != 3 {
+			t.Fatalf("Expected the run to complete once the window opened, got %+v", run)
+		}
+	})
+}
+
+// kevInvoker serves a KEV catalog and records the entries imported
+type kevInvoker struct {
+	mu       sync.Mutex
+	fetches  int
+	imported []string
+}
+
+func (k *kevInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var payload interface{}
+	switch method {
+	case "RPCFetchKEV":
+		k.fetches++
+		payload = cve.KEVCatalog{CatalogVersion: "2024.03.01", Vulnerabilities: []cve.KEVEntry{
+			{CVEID: "CVE-2024-0001"}, {CVEID: "CVE-2024-0002"}, {CVEID: "CVE-2024-0003"},
+		}}
+	case "RPCImportKEV":
+		req := params.(*rpc.ImportKEVParams)
+		for _, entry := range req.Vulnerabilities {
+			k.imported = append(k.imported, entry.CVEID)
+		}
+		payload = rpc.ImportKEVResult{Listed: int64(len(req.Vulnerabilities))}
+	default:
+		return nil, fmt.Errorf("unexpected call %s", method)
+	}
+	data, _ := subprocess.MarshalFast(payload)
+	return &subprocess.Message{Type: subprocess.MessageTypeResponse, ID: method, Payload: data}, nil
+}
+
+func TestJobExecutor_KEVProvider(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_KEVProvider", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		invoker := &kevInvoker{}
+		executor := NewJobExecutor(invoker, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+
+		if err := executor.StartTyped(context.Background(), "kev-run", 0, 2, DataTypeKEV, PriorityNormal); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+		var run *JobRun
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if run, _ = store.GetRun("kev-run"); run != nil && run.State == StateCompleted {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if run == nil || run.State != StateCompleted {
+			t.Fatalf("Run did not complete: %+v", run)
+		}
+		if run.FetchedCount != 3 || run.StoredCount != 3 || run.ErrorCount != 0 {
+			t.Errorf("Expected 3 fetched and stored, got %+v", run)
+		}
+		if invoker.fetches != 1 || len(invoker.imported) != 3 {
+			t.Errorf("Expected one catalog fetch and 3 entries imported, got %d fetches and %v", invoker.fetches, invoker.imported)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package taskflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultQuarantineCap is the maximum number of quarantined items kept. Once
+// reached, the oldest item is evicted to make room for a new one.
+const DefaultQuarantineCap = 10000
+
+var quarantineBucket = []byte("quarantine")
+
+// QuarantinedItem is an item that could not be stored after retries. It keeps
+// the RPC call that failed so the item can be replayed once the cause (e.g. a
+// schema mismatch) is fixed. ID is "<data_type>/<item_id>", so an item is
+// quarantined at most once however often it fails.
+type QuarantinedItem struct {
+	ID            string          `json:"id"`
+	DataType      DataType        `json:"data_type"`
+	ItemID        string          `json:"item_id"`  // e.g. the CVE ID
+	RunID         string          `json:"run_id"`   // Run that last failed to store it
+	Target        string          `json:"target"`   // Service the item was sent to
+	Method        string          `json:"method"`   // RPC method that failed
+	Payload       json.RawMessage `json:"payload"`  // Original RPC params
+	Error         string          `json:"error"`    // Last storage error
+	Attempts      int             `json:"attempts"` // Store attempts so far, including retries
+	QuarantinedAt time.Time       `json:"quarantined_at"`
+	LastAttemptAt time.Time       `json:"last_attempt_at"`
+}
+
+func quarantineKey(dataType DataType, itemID string) string {
+	return string(dataType) + "/" + itemID
+}
+
+// Quarantine parks an item that failed to store. Quarantining an item that is
+// already parked updates its error and adds to its attempts, keeping the time
+// it was first quarantined. It returns the ID of an item evicted to respect
+// the cap, if any.
+func (s *RunStore) Quarantine(item QuarantinedItem) (evicted string, err error) {
+	now := time.Now()
+	item.ID = quarantineKey(item.DataType, item.ItemID)
+	item.LastAttemptAt = now
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(quarantineBucket)
+		if err != nil {
+			return err
+		}
+
+		if data := b.Get([]byte(item.ID)); data != nil {
+			var existing QuarantinedItem
+			if err := json.Unmarshal(data, &existing); err == nil {
+				item.QuarantinedAt = existing.QuarantinedAt
+				item.Attempts += existing.Attempts
+			}
+		} else {
+			item.QuarantinedAt = now
+			if b.Stats().KeyN >= s.quarantineCapOrDefault() {
+				if evicted, err = evictOldest(b); err != nil {
+					return err
+				}
+			}
+		}
+
+		data, err := json.Marshal(&item)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(item.ID), data)
+	})
+	if err == nil && evicted != "" {
+		s.logger.Warn("Quarantine full, evicted oldest item %s", evicted)
+	}
+	return evicted, err
+}
+
+// evictOldest deletes the item that was quarantined first
+func evictOldest(b *bolt.Bucket) (string, error) {
+	var oldestKey []byte
+	var oldest time.Time
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var item QuarantinedItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			// An unreadable entry is the first to go
+			oldestKey = append([]byte(nil), k...)
+			break
+		}
+		if oldestKey == nil || item.QuarantinedAt.Before(oldest) {
+			oldestKey = append([]byte(nil), k...)
+			oldest = item.QuarantinedAt
+		}
+	}
+	if oldestKey == nil {
+		return "", nil
+	}
+	return string(oldestKey), b.Delete(oldestKey)
+}
+
+// ListQuarantined returns quarantined items of the given data type (all types
+// if empty), oldest first, along with the total number matching
+func (s *RunStore) ListQuarantined(dataType DataType, offset, limit int) ([]QuarantinedItem, int, error) {
+	var items []QuarantinedItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(quarantineBucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var item QuarantinedItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return nil
+			}
+			if dataType == "" || item.DataType == dataType {
+				items = append(items, item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].QuarantinedAt.Equal(items[j].QuarantinedAt) {
+			return items[i].QuarantinedAt.Before(items[j].QuarantinedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items, total, nil
+}
+
+// GetQuarantined returns a quarantined item by ID
+func (s *RunStore) GetQuarantined(id string) (*QuarantinedItem, error) {
+	var item *QuarantinedItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(quarantineBucket)
+		if b == nil {
+			return fmt.Errorf("quarantined item not found: %s", id)
+		}
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("quarantined item not found: %s", id)
+		}
+		item = &QuarantinedItem{}
+		return json.Unmarshal(data, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// RemoveQuarantined deletes a quarantined item, e.g. after it was stored
+func (s *RunStore) RemoveQuarantined(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(quarantineBucket)
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// SetQuarantineCap sets the maximum number of quarantined items kept
+func (s *RunStore) SetQuarantineCap(n int) {
+	s.quarantineCap = n
+}
+
+// QuarantineCap returns the maximum number of quarantined items kept
+func (s *RunStore) QuarantineCap() int {
+	return s.quarantineCapOrDefault()
+}
+
+func (s *RunStore) quarantineCapOrDefault() int {
+	if s.quarantineCap > 0 {
+		return s.quarantineCap
+	}
+	return DefaultQuarantineCap
+}
+
+// QuarantineRetryResult reports the outcome of RetryQuarantined
+type QuarantineRetryResult struct {
+	Retried   int               `json:"retried"`
+	Succeeded []string          `json:"succeeded"`
+	Failed    map[string]string `json:"failed"` // Item ID to the new error
+}
+
+// quarantine parks an item that exhausted its store attempts. Failing to
+// quarantine is logged but does not stop the run.
+func (e *JobExecutor) quarantine(runID string, dataType DataType, itemID, target, method string, params interface{}, storeErr error, attempts int) {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		e.logger.Error("Failed to encode %s %s for quarantine: %v", dataType, itemID, err)
+		return
+	}
+	_, err = e.runStore.Quarantine(QuarantinedItem{
+		DataType: dataType,
+		ItemID:   itemID,
+		RunID:    runID,
+		Target:   target,
+		Method:   method,
+		Payload:  payload,
+		Error:    storeErr.Error(),
+		Attempts: attempts,
+	})
+	if err != nil {
+		e.logger.Error("Failed to quarantine %s %s: %v", dataType, itemID, err)
+		return
+	}
+	e.logger.Warn("Quarantined %s %s after %d attempts: %v", dataType, itemID, attempts, storeErr)
+}
+
+// ListQuarantined returns quarantined items of the given data type (all types
+// if empty), oldest first, with the total matching and the quarantine cap
+func (e *JobExecutor) ListQuarantined(dataType DataType, offset, limit int) ([]QuarantinedItem, int, int, error) {
+	items, total, err := e.runStore.ListQuarantined(dataType, offset, limit)
+	return items, total, e.runStore.QuarantineCap(), err
+}
+
+// RetryQuarantined replays the failed store call of quarantined items of the
+// given data type (all types if empty). If ids is non-empty only those items
+// are retried. Items that store successfully leave the quarantine; the others
+// stay with their error updated.
+func (e *JobExecutor) RetryQuarantined(ctx context.Context, dataType DataType, ids []string) (*QuarantineRetryResult, error) {
+	var items []QuarantinedItem
+	if len(ids) > 0 {
+		for _, id := range ids {
+			item, err := e.runStore.GetQuarantined(id)
+			if err != nil {
+				return nil, err
+			}
+			if dataType != "" && item.DataType != dataType {
+				return nil, fmt.Errorf("quarantined item %s is not of type %s", id, dataType)
+			}
+			items = append(items, *item)
+		}
+	} else {
+		var err error
+		if items, _, err = e.runStore.ListQuarantined(dataType, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &QuarantineRetryResult{Succeeded: []string{}, Failed: map[string]string{}}
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Retried++
+
+		err := rpcResultError(e.rpcInvoker.InvokeRPC(ctx, item.Target, item.Method, item.Payload))
+		if err != nil {
+			result.Failed[item.ID] = err.Error()
+			item.Error = err.Error()
+			item.Attempts = 1
+			if _, qErr := e.runStore.Quarantine(item); qErr != nil {
+				e.logger.Error("Failed to update quarantined item %s: %v", item.ID, qErr)
+			}
+			continue
+		}
+
+		if err := e.runStore.RemoveQuarantined(item.ID); err != nil {
+			e.logger.Error("Failed to remove stored item %s from quarantine: %v", item.ID, err)
+		}
+		result.Succeeded = append(result.Succeeded, item.ID)
+	}
+
+	e.logger.Info("Retried %d quarantined items: %d stored, %d still failing",
+		result.Retried, len(result.Succeeded), len(result.Failed))
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package taskflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// saveInvoker answers store calls with an error reply while broken is set,
+// recording the params of every call
+type saveInvoker struct {
+	mu     sync.Mutex
+	broken bool
+	calls  []string
+}
+
+func (s *saveInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, _ := subprocess.MarshalFast(params)
+	s.calls = append(s.calls, string(data))
+	if s.broken {
+		return &subprocess.Message{Type: subprocess.MessageTypeError, ID: method, Error: "no such column: cvss_v40"}, nil
+	}
+	return &subprocess.Message{Type: subprocess.MessageTypeResponse, ID: method}, nil
+}
+
+func TestRunStore_QuarantineUpsertAndList(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_QuarantineUpsertAndList", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := rs.Quarantine(QuarantinedItem{DataType: DataTypeCVE, ItemID: "CVE-2024-0001", Error: fmt.Sprintf("failure %d", i), Attempts: 3}); err != nil {
+				t.Fatalf("Quarantine failed: %v", err)
+			}
+		}
+		rs.Quarantine(QuarantinedItem{DataType: DataTypeCWE, ItemID: "CWE-79", Error: "bad"})
+
+		items, total, err := rs.ListQuarantined(DataTypeCVE, 0, 0)
+		if err != nil {
+			t.Fatalf("ListQuarantined failed: %v", err)
+		}
+		if total != 1 || len(items) != 1 {
+			t.Fatalf("Expected the repeated CVE to be quarantined once, got %+v", items)
+		}
+		if items[0].ID != "cve/CVE-2024-0001" || items[0].Attempts != 6 || items[0].Error != "failure 1" {
+			t.Errorf("Expected attempts to add up and the latest error to win, got %+v", items[0])
+		}
+
+		if _, total, _ := rs.ListQuarantined("", 0, 0); total != 2 {
+			t.Errorf("Expected 2 items across all types, got %d", total)
+		}
+	})
+}
+
+func TestRunStore_QuarantineCapEvictsOldest(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_QuarantineCapEvictsOldest", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+		rs.SetQuarantineCap(3)
+
+		var evicted []string
+		for i := 0; i < 5; i++ {
+			e, err := rs.Quarantine(QuarantinedItem{DataType: DataTypeCVE, ItemID: fmt.Sprintf("CVE-2024-%04d", i)})
+			if err != nil {
+				t.Fatalf("Quarantine failed: %v", err)
+			}
+			if e != "" {
+				evicted = append(evicted, e)
+			}
+		}
+
+		items, total, _ := rs.ListQuarantined(DataTypeCVE, 0, 0)
+		if total != 3 || items[0].ItemID != "CVE-2024-0002" {
+			t.Errorf("Expected the 3 newest items to remain, got %+v", items)
+		}
+		if len(evicted) != 2 || evicted[0] != "cve/CVE-2024-0000" {
+			t.Errorf("Expected the 2 oldest items evicted, got %v", evicted)
+		}
+	})
+}
+
+func TestJobExecutor_RetryQuarantined(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_RetryQuarantined", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+		invoker := &saveInvoker{broken: true}
+		executor := NewJobExecutor(invoker, rs, newTestLogger(), 1, nil)
+
+		params := &rpc.SaveCVEByIDParams{}
+		params.CVE.ID = "CVE-2024-1234"
+		storeErr := rpcResultError(invoker.InvokeRPC(context.Background(), "local", "RPCSaveCVEByID", params))
+		if storeErr == nil {
+			t.Fatal("Expected an error reply to count as a failure")
+		}
+		executor.quarantine("run-1", DataTypeCVE, params.CVE.ID, "local", "RPCSaveCVEByID", params, storeErr, 3)
+
+		// Still broken: the item stays quarantined with its error refreshed
+		result, err := executor.RetryQuarantined(context.Background(), DataTypeCVE, nil)
+		if err != nil {
+			t.Fatalf("RetryQuarantined failed: %v", err)
+		}
+		if result.Retried != 1 || len(result.Failed) != 1 {
+			t.Errorf("Expected 1 failed retry, got %+v", result)
+		}
+		item, err := rs.GetQuarantined("cve/CVE-2024-1234")
+		if err != nil || item.Attempts != 4 || item.RunID != "run-1" {
+			t.Fatalf("Expected the item kept with 4 attempts, got %+v (%v)", item, err)
+		}
+
+		// After the fix the original payload is replayed and the item released
+		invoker.broken = false
+		result, err = executor.RetryQuarantined(context.Background(), DataTypeCVE, []string{"cve/CVE-2024-1234"})
+		if err != nil || len(result.Succeeded) != 1 {
+			t.Fatalf("Expected the retry to succeed, got %+v (%v)", result, err)
+		}
+		if last := invoker.calls[len(invoker.calls)-1]; last != invoker.calls[0] {
+			t.Errorf("Replayed payload %s differs from the original %s", last, invoker.calls[0])
+		}
+		if _, err := rs.GetQuarantined("cve/CVE-2024-1234"); err == nil {
+			t.Error("Expected the stored item to leave the quarantine")
+		}
+	})
+}
+
+func TestRPCResultError(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRPCResultError", nil, func(t *testing.T, tx *gorm.DB) {
+		if err := rpcResultError(&subprocess.Message{Type: subprocess.MessageTypeResponse}, nil); err != nil {
+			t.Errorf("Success reply reported as %v", err)
+		}
+		transport := errors.New("RPC timeout")
+		if err := rpcResultError(nil, transport); err != transport {
+			t.Errorf("Transport error = %v, want %v", err, transport)
+		}
+	})
+}
@@ -3,6 +3,8 @@ package taskflow
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
@@ -16,6 +18,15 @@ type RunStore struct {
 	db         *bolt.DB
 	bucketName []byte
 	logger     *common.Logger
+
+	// quarantineCap bounds the quarantine bucket (see quarantine.go)
+	quarantineCap int
+	// runLogCap bounds the events kept per run (see runlog.go)
+	runLogCap int
+
+	// changed is closed on the next change to a run (see changes.go)
+	changeMu sync.Mutex
+	changed  chan struct{}
 }
 
 // NewRunStore creates a new run store backed by BoltDB
@@ -61,7 +72,7 @@ func (s *RunStore) CreateRun(runID string, startIndex, resultsPerBatch int, data
 		Params:          make(map[string]interface{}),
 	}
 
-	if err := s.saveRun(run); err != nil {
+	if err := s.saveRunWithEvent(run); err != nil {
 		return nil, err
 	}
 
@@ -131,6 +142,36 @@ func (s *RunStore) GetActiveRun() (*JobRun, error) {
 	return activeRun, nil
 }
 
+// GetActiveRuns retrieves every running run, oldest first. Each data type has
+// at most one.
+func (s *RunStore) GetActiveRuns() ([]*JobRun, error) {
+	var runs []*JobRun
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucketName)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var run JobRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return nil
+			}
+			if run.State == StateRunning {
+				runs = append(runs, &run)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
 // GetLatestRun returns the most recently updated run (if any)
 func (s *RunStore) GetLatestRun() (*JobRun, error) {
 	var latest *JobRun
@@ -164,6 +205,47 @@ func (s *RunStore) GetLatestRun() (*JobRun, error) {
 	return latest, nil
 }
 
+// ListRuns returns a page of every stored run, newest first, and the number
+// of runs. Completed, stopped and failed runs stay in the store with their
+// final state and counts, so this is the run history.
+func (s *RunStore) ListRuns(limit, offset int) ([]*JobRun, int, error) {
+	var runs []*JobRun
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucketName)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var run JobRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return nil
+			}
+			runs = append(runs, &run)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].CreatedAt.Equal(runs[j].CreatedAt) {
+			return runs[i].CreatedAt.After(runs[j].CreatedAt)
+		}
+		return runs[i].ID < runs[j].ID
+	})
+
+	total := len(runs)
+	if offset > total {
+		offset = total
+	}
+	runs = runs[offset:]
+	if limit > 0 && limit < len(runs) {
+		runs = runs[:limit]
+	}
+	return runs, total, nil
+}
+
 // UpdateState updates the run state
 func (s *RunStore) UpdateState(runID string, state JobState) error {
 	run, err := s.GetRun(runID)
@@ -179,14 +261,14 @@ func (s *RunStore) UpdateState(runID string, state JobState) error {
 	run.UpdatedAt = time.Now()
 	s.logger.Debug("Updating run %s state to: %s", runID, state)
 
-	return s.saveRun(run)
+	return s.saveRunWithEvent(run)
 }
 
 // UpdateProgress updates the run progress counters
 func (s *RunStore) UpdateProgress(runID string, fetched, stored, errors int64) error {
 	// Perform read-modify-write inside a single DB update transaction to avoid
 	// lost updates when multiple goroutines call UpdateProgress concurrently.
-	return s.db.Update(func(tx *bolt.Tx) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucketName)
 		if b == nil {
 			return fmt.Errorf("bucket not found")
@@ -214,6 +296,125 @@ func (s *RunStore) UpdateProgress(runID string, fetched, stored, errors int64) e
 
 		return b.Put([]byte(run.ID), newData)
 	})
+	if err == nil {
+		s.notifyChange()
+	}
+	return err
+}
+
+// Checkpoint is the progress of a run since its last checkpoint
+type Checkpoint struct {
+	// StartIndex is the index the run resumes from after a restart
+	StartIndex int
+	// Fetched, Stored and Errors are added to the run's counters
+	Fetched int64
+	Stored  int64
+	Errors  int64
+	// Params are set on the run, keeping the others
+	Params map[string]interface{}
+	// BatchSize, if positive, is recorded on the run's progress
+	BatchSize int
+}
+
+// SaveCheckpoint records the index a run resumes from together with the
+// progress made to get there, in one transaction. The counters of a batch are
+// therefore persisted if and only if the batch is past the resume point, so a
+// restart neither counts a batch twice nor skips one.
+func (s *RunStore) SaveCheckpoint(runID string, cp Checkpoint) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucketName)
+		if b == nil {
+			return fmt.Errorf("bucket not found")
+		}
+
+		data := b.Get([]byte(runID))
+		if data == nil {
+			return fmt.Errorf("run not found: %s", runID)
+		}
+
+		var run JobRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			return err
+		}
+
+		run.StartIndex = cp.StartIndex
+		run.FetchedCount += cp.Fetched
+		run.StoredCount += cp.Stored
+		run.ErrorCount += cp.Errors
+		if len(cp.Params) > 0 {
+			if run.Params == nil {
+				run.Params = make(map[string]interface{})
+			}
+			for k, v := range cp.Params {
+				run.Params[k] = v
+			}
+		}
+		run.UpdatedAt = time.Now()
+		if cp.BatchSize > 0 {
+			if run.Progress == nil {
+				run.Progress = make(map[DataType]DataProgress)
+			}
+			progress := run.Progress[run.DataType]
+			progress.BatchSize = cp.BatchSize
+			progress.LastUpdate = run.UpdatedAt
+			run.Progress[run.DataType] = progress
+		}
+
+		newData, err := json.Marshal(&run)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(run.ID), newData)
+	})
+	if err == nil {
+		s.notifyChange()
+	}
+	return err
+}
+
+// SetPriority updates the scheduling priority of a run
+func (s *RunStore) SetPriority(runID string, priority Priority) error {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return err
+	}
+
+	run.Priority = priority
+	run.UpdatedAt = time.Now()
+
+	return s.saveRun(run)
+}
+
+// SetParams sets configuration parameters of a run, keeping the others
+func (s *RunStore) SetParams(runID string, params map[string]interface{}) error {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return err
+	}
+
+	if run.Params == nil {
+		run.Params = make(map[string]interface{})
+	}
+	for k, v := range params {
+		run.Params[k] = v
+	}
+	run.UpdatedAt = time.Now()
+
+	return s.saveRun(run)
+}
+
+// SetCallbackResult records the outcome of the completion callback of a run
+func (s *RunStore) SetCallbackResult(runID string, result CallbackResult) error {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return err
+	}
+
+	run.Callback = &result
+	run.UpdatedAt = time.Now()
+
+	return s.saveRun(run)
 }
 
 // SetError marks the run as failed with an error message
@@ -227,19 +428,26 @@ func (s *RunStore) SetError(runID string, errMsg string) error {
 	run.ErrorMessage = errMsg
 	run.UpdatedAt = time.Now()
 
-	return s.saveRun(run)
+	return s.saveRunWithEvent(run)
 }
 
 // DeleteRun deletes a job run
 func (s *RunStore) DeleteRun(runID string) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucketName)
 		if b == nil {
 			return fmt.Errorf("bucket not found")
 		}
 
+		if err := deleteRunLogs(tx, runID); err != nil {
+			return err
+		}
 		return b.Delete([]byte(runID))
 	})
+	if err == nil {
+		s.notifyChange()
+	}
+	return err
 }
 
 // saveRun saves the run to the database
@@ -249,7 +457,7 @@ func (s *RunStore) saveRun(run *JobRun) error {
 		return err
 	}
 
-	return s.db.Update(func(tx *bolt.Tx) error {
+	err = s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucketName)
 		if b == nil {
 			return fmt.Errorf("bucket not found")
@@ -257,6 +465,39 @@ func (s *RunStore) saveRun(run *JobRun) error {
 
 		return b.Put([]byte(run.ID), data)
 	})
+	if err == nil {
+		s.notifyChange()
+	}
+	return err
+}
+
+// saveRunWithEvent saves the run and records its state on the timeline in
+// the same transaction
+func (s *RunStore) saveRunWithEvent(run *JobRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	event, err := runEvent(run)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucketName)
+		if b == nil {
+			return fmt.Errorf("bucket not found")
+		}
+
+		if err := b.Put([]byte(run.ID), data); err != nil {
+			return err
+		}
+		return s.putEvent(tx, EventTypeRun, event)
+	})
+	if err == nil {
+		s.notifyChange()
+	}
+	return err
 }
 
 // Close closes the database connection
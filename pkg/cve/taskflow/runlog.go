@@ -0,0 +1,166 @@
+package taskflow
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Run log event types
+const (
+	// RunLogBatchStarted is recorded before a batch is fetched
+	RunLogBatchStarted = "batch_started"
+	// RunLogBatchCompleted is recorded once a batch is stored
+	RunLogBatchCompleted = "batch_completed"
+	// RunLogError is recorded when a fetch, a batch store or the store of one
+	// item fails
+	RunLogError = "error"
+)
+
+// DefaultRunLogCap is the maximum number of events kept per run. Once
+// reached, the oldest events of the run are dropped.
+const DefaultRunLogCap = 1000
+
+// runLogsBucket holds one nested bucket of events per run ID
+var runLogsBucket = []byte("run_logs")
+
+// RunLogEvent is one structured entry of a run's event log
+type RunLogEvent struct {
+	Seq        uint64    `json:"seq"` // Position in the run's log, increasing
+	Type       string    `json:"type"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Index      int       `json:"index"`             // Start index of the batch
+	ItemID     string    `json:"item_id,omitempty"` // e.g. the CVE ID an error is about
+	Fetched    int64     `json:"fetched,omitempty"`
+	Stored     int64     `json:"stored,omitempty"`
+	Errors     int64     `json:"errors,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+// AppendRunLog appends an event to the log of a run, dropping the run's
+// oldest events beyond the run log cap
+func (s *RunStore) AppendRunLog(runID string, event RunLogEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		logs, err := tx.CreateBucketIfNotExists(runLogsBucket)
+		if err != nil {
+			return err
+		}
+		b, err := logs.CreateBucketIfNotExists([]byte(runID))
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		event.Seq = seq
+		event.RecordedAt = time.Now().UTC()
+		data, err := json.Marshal(&event)
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		if err := b.Put(key, data); err != nil {
+			return err
+		}
+
+		// Sequences are contiguous from the oldest kept event, as only the
+		// oldest are ever deleted, so the first key tells how many are kept
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && int(seq-binary.BigEndian.Uint64(k)) >= s.RunLogCap(); k, _ = c.First() {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TailRunLogs returns the latest limit events of a run (50 if limit is not
+// positive), oldest first, and the number of events kept for the run. A run
+// without events yields none; an unknown run is an error.
+func (s *RunStore) TailRunLogs(runID string, limit int) ([]RunLogEvent, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	events := []RunLogEvent{}
+	total := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if runs := tx.Bucket(s.bucketName); runs == nil || runs.Get([]byte(runID)) == nil {
+			return fmt.Errorf("run not found: %s", runID)
+		}
+		logs := tx.Bucket(runLogsBucket)
+		if logs == nil {
+			return nil
+		}
+		b := logs.Bucket([]byte(runID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		first, _ := c.First()
+		last, _ := c.Last()
+		if first == nil {
+			return nil
+		}
+		total = int(binary.BigEndian.Uint64(last)-binary.BigEndian.Uint64(first)) + 1
+
+		// Step back from the end, then reverse into recording order
+		for k, v := c.Last(); k != nil && len(events) < limit; k, v = c.Prev() {
+			var event RunLogEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				continue
+			}
+			events = append(events, event)
+		}
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// SetRunLogCap sets the maximum number of events kept per run
+func (s *RunStore) SetRunLogCap(n int) {
+	s.runLogCap = n
+}
+
+// RunLogCap returns the maximum number of events kept per run
+func (s *RunStore) RunLogCap() int {
+	if s.runLogCap > 0 {
+		return s.runLogCap
+	}
+	return DefaultRunLogCap
+}
+
+// deleteRunLogs removes the log of a run within tx
+func deleteRunLogs(tx *bolt.Tx, runID string) error {
+	logs := tx.Bucket(runLogsBucket)
+	if logs == nil || logs.Bucket([]byte(runID)) == nil {
+		return nil
+	}
+	return logs.DeleteBucket([]byte(runID))
+}
+
+// runLog appends an event to the log of a run. Failing to log is reported
+// but does not stop the run.
+func (e *JobExecutor) runLog(runID string, event RunLogEvent) {
+	if err := e.runStore.AppendRunLog(runID, event); err != nil {
+		e.logger.Warn("Failed to append %s event to the log of run %s: %v", event.Type, runID, err)
+	}
+}
+
+// GetRunLogs returns the latest limit events of a run, oldest first, with
+// the number of events kept for it
+func (e *JobExecutor) GetRunLogs(runID string, limit int) ([]RunLogEvent, int, error) {
+	return e.runStore.TailRunLogs(runID, limit)
+}
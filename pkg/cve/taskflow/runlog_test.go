@@ -0,0 +1,131 @@
+package taskflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestRunStore_RunLogs(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_RunLogs", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+		rs.SetRunLogCap(3)
+		for _, id := range []string{"run-1", "run-2"} {
+			if _, err := rs.CreateRun(id, 0, 10, DataTypeCVE); err != nil {
+				t.Fatalf("CreateRun failed: %v", err)
+			}
+		}
+
+		for i := 0; i < 5; i++ {
+			if err := rs.AppendRunLog("run-1", RunLogEvent{Type: RunLogBatchStarted, Index: i * 10}); err != nil {
+				t.Fatalf("AppendRunLog failed: %v", err)
+			}
+		}
+		rs.AppendRunLog("run-2", RunLogEvent{Type: RunLogError, ItemID: "CVE-2024-0001"})
+
+		// The oldest events are rotated out; the tail is in recording order
+		events, total, err := rs.TailRunLogs("run-1", 2)
+		if err != nil {
+			t.Fatalf("TailRunLogs failed: %v", err)
+		}
+		if total != 3 || len(events) != 2 || events[0].Index != 30 || events[1].Index != 40 || events[0].Seq >= events[1].Seq {
+			t.Errorf("Expected the last 2 of 3 kept events, got %+v (total %d)", events, total)
+		}
+		if events, _, _ := rs.TailRunLogs("run-1", 0); len(events) != 3 || events[0].Index != 20 {
+			t.Errorf("Expected all 3 kept events, got %+v", events)
+		}
+		if events, _, _ := rs.TailRunLogs("run-2", 10); len(events) != 1 || events[0].ItemID != "CVE-2024-0001" {
+			t.Errorf("Expected the logs of runs kept apart, got %+v", events)
+		}
+
+		if _, _, err := rs.TailRunLogs("missing", 10); err == nil {
+			t.Error("Expected an error for an unknown run")
+		}
+
+		// Deleting a run deletes its log
+		if err := rs.DeleteRun("run-2"); err != nil {
+			t.Fatalf("DeleteRun failed: %v", err)
+		}
+		rs.CreateRun("run-2", 0, 10, DataTypeCVE)
+		if events, total, _ := rs.TailRunLogs("run-2", 10); len(events) != 0 || total != 0 {
+			t.Errorf("Expected a recreated run to start with an empty log, got %+v", events)
+		}
+	})
+}
+
+// flakyProvider serves pages of records numbered from 0 up to total and fails
+// to store the records listed in failing
+type flakyProvider struct {
+	total   int
+	failing map[string]bool
+}
+
+func (p *flakyProvider) Fetch(ctx context.Context, startIndex, batchSize int) ([]Record, error) {
+	var records []Record
+	for i := startIndex; i < startIndex+batchSize && i < p.total; i++ {
+		records = append(records, fmt.Sprintf("CVE-2024-%04d", i))
+	}
+	return records, nil
+}
+
+func (p *flakyProvider) Store(ctx context.Context, records []Record) (StoreResult, error) {
+	var result StoreResult
+	for _, r := range records {
+		id := r.(string)
+		if p.failing[id] {
+			result.Errors++
+			result.Failed = append(result.Failed, FailedRecord{ID: id, Target: "local", Method: "RPCSaveCVEByID", Err: errors.New("no such column: cvss_v40"), Attempts: 1})
+			continue
+		}
+		result.Stored++
+	}
+	return result, nil
+}
+
+func TestJobExecutor_RunLogs(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_RunLogs", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+		executor := NewJobExecutor(&saveInvoker{}, store, newTestLogger(), 4, nil)
+		executor.scheduler = NewFairScheduler(4, time.Millisecond)
+		provider := &flakyProvider{total: 15, failing: map[string]bool{"CVE-2024-0012": true}}
+		executor.RegisterProvider(DataTypeCVE, func(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+			return provider, nil
+		})
+		if err := executor.Start(context.Background(), "logged", 0, 10); err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for run, _ := store.GetRun("logged"); run == nil || run.State != StateCompleted; run, _ = store.GetRun("logged") {
+			if time.Now().After(deadline) {
+				t.Fatal("Run did not complete")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		events, _, err := executor.GetRunLogs("logged", 0)
+		if err != nil {
+			t.Fatalf("GetRunLogs failed: %v", err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, fmt.Sprintf("%s@%d%s", e.Type, e.Index, e.ItemID))
+		}
+		want := []string{
+			"batch_started@0", "batch_completed@0",
+			"batch_started@10", "error@10CVE-2024-0012", "batch_completed@10",
+			"batch_started@20",
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Expected events %v, got %v", want, got)
+		}
+		if completed := events[4]; completed.Fetched != 5 || completed.Stored != 4 || completed.Errors != 1 {
+			t.Errorf("Expected the second batch to report 5 fetched, 4 stored and 1 error, got %+v", completed)
+		}
+	})
+}
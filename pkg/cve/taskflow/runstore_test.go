@@ -4,6 +4,7 @@ import (
 "gorm.io/gorm"
 "github.com/cyw0ng95/v2e/pkg/testutils"
 	"testing"
+	"time"
 )
 
 func TestRunStore_UpdateState_ValidAndInvalid(t *testing.T) {
@@ -96,3 +97,119 @@ func TestRunStore_StoppedFromQueuedAndPaused(t *testing.T) {
 	})
 
 }
+
+func TestRunStore_ListRuns(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_ListRuns", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+
+		if runs, total, err := rs.ListRuns(10, 0); err != nil || total != 0 || len(runs) != 0 {
+			t.Fatalf("Expected an empty history, got %v, %d, %v", runs, total, err)
+		}
+
+		for _, id := range []string{"run-1", "run-2", "run-3"} {
+			if _, err := rs.CreateRun(id, 0, 10, DataTypeCVE); err != nil {
+				t.Fatalf("CreateRun failed: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// Finished runs keep their final state and counts
+		if err := rs.UpdateState("run-1", StateRunning); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+		if err := rs.UpdateProgress("run-1", 5, 4, 1); err != nil {
+			t.Fatalf("UpdateProgress failed: %v", err)
+		}
+		if err := rs.UpdateState("run-1", StateCompleted); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+
+		runs, total, err := rs.ListRuns(0, 0)
+		if err != nil || total != 3 || len(runs) != 3 {
+			t.Fatalf("Expected 3 runs, got %d of %d (%v)", len(runs), total, err)
+		}
+		if runs[0].ID != "run-3" || runs[2].ID != "run-1" {
+			t.Errorf("Expected newest first, got %s, %s, %s", runs[0].ID, runs[1].ID, runs[2].ID)
+		}
+		if old := runs[2]; old.State != StateCompleted || old.FetchedCount != 5 || old.StoredCount != 4 || old.ErrorCount != 1 {
+			t.Errorf("Expected the completed run with its counts, got %+v", old)
+		}
+
+		runs, total, err = rs.ListRuns(1, 1)
+		if err != nil || total != 3 || len(runs) != 1 || runs[0].ID != "run-2" {
+			t.Errorf("Expected page [run-2] of 3, got %v of %d (%v)", runs, total, err)
+		}
+		if runs, _, _ := rs.ListRuns(10, 5); len(runs) != 0 {
+			t.Errorf("Expected no runs past the end, got %v", runs)
+		}
+	})
+}
+
+func TestRunStore_Changes(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_Changes", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+
+		changed := rs.Changes()
+		if _, err := rs.CreateRun("run-watch", 0, 10, DataTypeCVE); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+			t.Fatal("expected CreateRun to close the changes channel")
+		}
+
+		changed = rs.Changes()
+		select {
+		case <-changed:
+			t.Fatal("expected a fresh changes channel to stay open")
+		default:
+		}
+		if err := rs.UpdateProgress("run-watch", 5, 5, 0); err != nil {
+			t.Fatalf("UpdateProgress failed: %v", err)
+		}
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+			t.Fatal("expected UpdateProgress to close the changes channel")
+		}
+
+		// Failed writes are not changes
+		changed = rs.Changes()
+		if err := rs.UpdateProgress("missing", 1, 1, 0); err == nil {
+			t.Fatal("expected UpdateProgress of a missing run to fail")
+		}
+		select {
+		case <-changed:
+			t.Fatal("expected a failed write to leave the changes channel open")
+		default:
+		}
+	})
+}
+
+func TestRunStore_SaveCheckpoint(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_SaveCheckpoint", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+		if _, err := rs.CreateRun("run-1", 0, 10, DataTypeCVE); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+
+		if err := rs.SaveCheckpoint("run-1", Checkpoint{StartIndex: 10, Fetched: 10, Stored: 9, Errors: 1}); err != nil {
+			t.Fatalf("SaveCheckpoint failed: %v", err)
+		}
+		if err := rs.SaveCheckpoint("run-1", Checkpoint{StartIndex: 0, Params: map[string]interface{}{"k": "v"}}); err != nil {
+			t.Fatalf("SaveCheckpoint failed: %v", err)
+		}
+
+		r, err := rs.GetRun("run-1")
+		if err != nil {
+			t.Fatalf("GetRun failed: %v", err)
+		}
+		if r.StartIndex != 0 || r.FetchedCount != 10 || r.StoredCount != 9 || r.ErrorCount != 1 || r.Params["k"] != "v" {
+			t.Fatalf("unexpected run after checkpoints: %+v", r)
+		}
+
+		if err := rs.SaveCheckpoint("missing", Checkpoint{}); err == nil {
+			t.Fatalf("expected an error for a missing run")
+		}
+	})
+}
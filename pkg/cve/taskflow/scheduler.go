@@ -0,0 +1,369 @@
+package taskflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority is the scheduling priority of a job run. Runs share worker slots
+// and rate-limit tokens in proportion to the weight of their priority, so an
+// urgent targeted fetch gets ahead of a long background backfill without
+// starving it.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorityWeights is the share each priority gets relative to PriorityLow
+var priorityWeights = map[Priority]int{
+	PriorityLow:    1,
+	PriorityNormal: 2,
+	PriorityHigh:   4,
+	PriorityUrgent: 8,
+}
+
+// ParsePriority validates a priority name; empty means PriorityNormal
+func ParsePriority(s string) (Priority, error) {
+	if s == "" {
+		return PriorityNormal, nil
+	}
+	p := Priority(s)
+	if _, ok := priorityWeights[p]; !ok {
+		return "", fmt.Errorf("invalid priority %q (must be low, normal, high or urgent)", s)
+	}
+	return p, nil
+}
+
+// Weight returns the scheduling weight of the priority
+func (p Priority) Weight() int {
+	if w, ok := priorityWeights[p]; ok {
+		return w
+	}
+	return priorityWeights[PriorityNormal]
+}
+
+// DefaultTokenInterval is the minimum spacing between remote fetches across
+// all runs, matching the one batch per second a single run used to sleep for
+const DefaultTokenInterval = time.Second
+
+// FairScheduler allocates worker slots and rate-limit tokens across active
+// runs using weighted fair queuing: whenever a slot or token frees up it goes
+// to the waiting run that has received the least service relative to its
+// weight. Separately, a data type can be capped to a number of concurrent
+// slots, taken by the work a run fans out (see AcquireTypeSlot).
+type FairScheduler struct {
+	workers *fairQueue
+	tokens  *fairQueue
+
+	tokenInterval time.Duration
+
+	typeMu    sync.Mutex
+	typeSlots map[DataType]*typeSemaphore
+}
+
+// NewFairScheduler creates a scheduler with the given number of worker slots
+// that hands out one rate-limit token per tokenInterval
+func NewFairScheduler(workers int, tokenInterval time.Duration) *FairScheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &FairScheduler{
+		workers:       newFairQueue(workers),
+		tokens:        newFairQueue(1),
+		tokenInterval: tokenInterval,
+		typeSlots:     make(map[DataType]*typeSemaphore),
+	}
+}
+
+// Register adds a run with the given priority. Its virtual time starts at
+// the current one, so a newly started run neither jumps the queue with
+// credit it never earned nor waits behind service it missed.
+func (s *FairScheduler) Register(runID string, priority Priority) {
+	s.workers.register(runID, priority.Weight())
+	s.tokens.register(runID, priority.Weight())
+}
+
+// Unregister removes a run from scheduling
+func (s *FairScheduler) Unregister(runID string) {
+	s.workers.unregister(runID)
+	s.tokens.unregister(runID)
+}
+
+// SetTypeLimit caps the slots of a data type held at once; 0 removes the
+// cap. Lowering it does not take slots back: holders over the new limit wait
+// as they release theirs.
+func (s *FairScheduler) SetTypeLimit(dataType DataType, limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	s.typeSemaphore(dataType).setLimit(limit)
+}
+
+// TypeLimits returns the slot caps by data type, leaving out the uncapped
+// ones
+func (s *FairScheduler) TypeLimits() map[DataType]int {
+	s.typeMu.Lock()
+	defer s.typeMu.Unlock()
+	limits := make(map[DataType]int)
+	for dataType, sem := range s.typeSlots {
+		if limit := sem.getLimit(); limit > 0 {
+			limits[dataType] = limit
+		}
+	}
+	return limits
+}
+
+// typeSemaphore returns the slot semaphore of a data type
+func (s *FairScheduler) typeSemaphore(dataType DataType) *typeSemaphore {
+	s.typeMu.Lock()
+	defer s.typeMu.Unlock()
+	sem, ok := s.typeSlots[dataType]
+	if !ok {
+		sem = newTypeSemaphore()
+		s.typeSlots[dataType] = sem
+	}
+	return sem
+}
+
+// SetPriority changes the weight of a registered run. It returns false if
+// the run is not registered.
+func (s *FairScheduler) SetPriority(runID string, priority Priority) bool {
+	ok := s.workers.setWeight(runID, priority.Weight())
+	s.tokens.setWeight(runID, priority.Weight())
+	return ok
+}
+
+// AcquireWorker blocks until runID is granted a worker slot. The returned
+// function releases the slot.
+func (s *FairScheduler) AcquireWorker(ctx context.Context, runID string) (func(), error) {
+	if err := s.workers.acquire(ctx, runID); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(s.workers.release) }, nil
+}
+
+// AcquireTypeSlot blocks until a slot of dataType is free, which is at once
+// for an uncapped type. The returned function releases the slot.
+func (s *FairScheduler) AcquireTypeSlot(ctx context.Context, dataType DataType) (func(), error) {
+	sem := s.typeSemaphore(dataType)
+	if err := sem.acquire(ctx); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(sem.release) }, nil
+}
+
+// WaitToken blocks until runID is granted a rate-limit token. Tokens are not
+// returned; the next one becomes available tokenInterval later.
+func (s *FairScheduler) WaitToken(ctx context.Context, runID string) error {
+	if err := s.tokens.acquire(ctx, runID); err != nil {
+		return err
+	}
+	if s.tokenInterval <= 0 {
+		s.tokens.release()
+		return nil
+	}
+	time.AfterFunc(s.tokenInterval, s.tokens.release)
+	return nil
+}
+
+// typeSemaphore is a counting semaphore whose limit can change while it is
+// held; a limit of 0 means unlimited
+type typeSemaphore struct {
+	mu    sync.Mutex
+	limit int
+	inUse int
+	wake  chan struct{} // Closed and replaced when a slot may have freed up
+}
+
+func newTypeSemaphore() *typeSemaphore {
+	return &typeSemaphore{wake: make(chan struct{})}
+}
+
+func (t *typeSemaphore) acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if t.limit == 0 || t.inUse < t.limit {
+			t.inUse++
+			t.mu.Unlock()
+			return nil
+		}
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *typeSemaphore) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inUse--
+	t.wakeLocked()
+}
+
+func (t *typeSemaphore) setLimit(limit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = limit
+	t.wakeLocked()
+}
+
+func (t *typeSemaphore) getLimit() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit
+}
+
+// wakeLocked wakes every waiter to retry
+func (t *typeSemaphore) wakeLocked() {
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+// fairRun is the per-run accounting of a fairQueue
+type fairRun struct {
+	weight int
+	pass   float64 // virtual finish time of the run's last grant
+}
+
+// fairWaiter is a blocked acquire
+type fairWaiter struct {
+	runID string
+	seq   uint64
+	ready chan struct{}
+}
+
+// fairQueue is a counting semaphore that grants to waiters in weighted fair
+// order (start-time fair queuing)
+type fairQueue struct {
+	mu       sync.Mutex
+	capacity int
+	inUse    int
+	vtime    float64 // virtual start time of the latest grant
+	seq      uint64
+	runs     map[string]*fairRun
+	waiters  []*fairWaiter
+}
+
+func newFairQueue(capacity int) *fairQueue {
+	return &fairQueue{capacity: capacity, runs: make(map[string]*fairRun)}
+}
+
+func (q *fairQueue) register(runID string, weight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.runs[runID] = &fairRun{weight: weight, pass: q.vtime}
+}
+
+func (q *fairQueue) unregister(runID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.runs, runID)
+}
+
+func (q *fairQueue) setWeight(runID string, weight int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, ok := q.runs[runID]
+	if ok {
+		run.weight = weight
+	}
+	return ok
+}
+
+// runLocked returns the accounting for runID, or nil if it is not
+// registered, e.g. because it was unregistered while a grant was pending
+func (q *fairQueue) runLocked(runID string) *fairRun {
+	return q.runs[runID]
+}
+
+// startLocked returns the virtual time at which runID's next grant would
+// start. A run that fell idle resumes at the current virtual time rather than
+// cashing in the service it did not ask for; an unregistered run always
+// starts at it.
+func (q *fairQueue) startLocked(runID string) float64 {
+	if run := q.runLocked(runID); run != nil && run.pass > q.vtime {
+		return run.pass
+	}
+	return q.vtime
+}
+
+// chargeLocked records a grant to runID. An unregistered run is granted
+// without accounting, so a late grant or release does not bring it back.
+func (q *fairQueue) chargeLocked(runID string) {
+	start := q.startLocked(runID)
+	q.vtime = start
+	if run := q.runLocked(runID); run != nil {
+		run.pass = start + 1/float64(run.weight)
+	}
+	q.inUse++
+}
+
+func (q *fairQueue) acquire(ctx context.Context, runID string) error {
+	q.mu.Lock()
+	if q.inUse < q.capacity && len(q.waiters) == 0 {
+		q.chargeLocked(runID)
+		q.mu.Unlock()
+		return nil
+	}
+	q.seq++
+	w := &fairWaiter{runID: runID, seq: q.seq, ready: make(chan struct{})}
+	q.waiters = append(q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, other := range q.waiters {
+			if other == w {
+				q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// Granted while giving up; hand the slot on
+		q.inUse--
+		q.dispatchLocked()
+		return ctx.Err()
+	}
+}
+
+func (q *fairQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inUse--
+	q.dispatchLocked()
+}
+
+// dispatchLocked grants free capacity to the waiters whose runs are furthest
+// behind in virtual time, oldest request first among equals
+func (q *fairQueue) dispatchLocked() {
+	for q.inUse < q.capacity && len(q.waiters) > 0 {
+		best := 0
+		bestPass := q.startLocked(q.waiters[0].runID)
+		for i, w := range q.waiters[1:] {
+			pass := q.startLocked(w.runID)
+			if pass < bestPass || (pass == bestPass && w.seq < q.waiters[best].seq) {
+				best, bestPass = i+1, pass
+			}
+		}
+		w := q.waiters[best]
+		q.waiters = append(q.waiters[:best], q.waiters[best+1:]...)
+		q.chargeLocked(w.runID)
+		close(w.ready)
+	}
+}
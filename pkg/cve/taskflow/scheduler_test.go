@@ -0,0 +1,337 @@
+package taskflow
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// contend runs perRun busy goroutines for each run against the scheduler
+// until total grants have been handed out, returning the grants each run
+// received. Runs need more than one outstanding request for their weights to
+// matter: a lone waiter always gets the next grant.
+func contend(t *testing.T, total, perRun int, acquire func(ctx context.Context, runID string) error, runIDs ...string) map[string]int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	granted := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < perRun*len(runIDs); i++ {
+		wg.Add(1)
+		go func(runID string) {
+			defer wg.Done()
+			for {
+				if err := acquire(ctx, runID); err != nil {
+					return
+				}
+				mu.Lock()
+				done := granted >= total
+				if !done {
+					granted++
+					counts[runID]++
+					if granted == total {
+						cancel()
+					}
+				}
+				mu.Unlock()
+				if done {
+					return
+				}
+			}
+		}(runIDs[i%len(runIDs)])
+	}
+	wg.Wait()
+
+	if granted < total {
+		t.Fatalf("Only %d of %d grants handed out before timeout", granted, total)
+	}
+	return counts
+}
+
+func TestFairScheduler_HighPriorityGetsMoreWorkers(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_HighPriorityGetsMoreWorkers", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(1, 0)
+		s.Register("backfill", PriorityLow)
+		s.Register("targeted", PriorityUrgent)
+
+		counts := contend(t, 900, 4, func(ctx context.Context, runID string) error {
+			release, err := s.AcquireWorker(ctx, runID)
+			if err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Microsecond)
+			release()
+			return nil
+		}, "backfill", "targeted")
+
+		// Weights 8:1 give the targeted run 8/9 of the slots
+		if counts["targeted"] < 6*counts["backfill"] {
+			t.Errorf("Worker grants = %v, want targeted to get about 8x backfill", counts)
+		}
+		if counts["backfill"] == 0 {
+			t.Error("Low priority run was starved")
+		}
+	})
+}
+
+func TestFairScheduler_TokensFollowPriority(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_TokensFollowPriority", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(1, 200*time.Microsecond)
+		s.Register("backfill", PriorityNormal)
+		s.Register("targeted", PriorityHigh)
+
+		counts := contend(t, 300, 1, s.WaitToken, "backfill", "targeted")
+
+		// Weights 4:2 give the targeted run 2/3 of the tokens
+		if counts["targeted"] < counts["backfill"]*3/2 {
+			t.Errorf("Token grants = %v, want targeted to get about 2x backfill", counts)
+		}
+	})
+}
+
+func TestFairScheduler_SetPriority(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_SetPriority", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(1, 0)
+		s.Register("a", PriorityUrgent)
+		s.Register("b", PriorityLow)
+		if !s.SetPriority("a", PriorityLow) || !s.SetPriority("b", PriorityUrgent) {
+			t.Fatal("SetPriority on registered runs should succeed")
+		}
+		if s.SetPriority("missing", PriorityHigh) {
+			t.Error("SetPriority on an unregistered run should report false")
+		}
+
+		counts := contend(t, 450, 4, func(ctx context.Context, runID string) error {
+			release, err := s.AcquireWorker(ctx, runID)
+			if err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Microsecond)
+			release()
+			return nil
+		}, "a", "b")
+		if counts["b"] < 6*counts["a"] {
+			t.Errorf("Worker grants = %v, want b to get about 8x a after swapping priorities", counts)
+		}
+	})
+}
+
+func TestFairScheduler_AcquireHonoursContext(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_AcquireHonoursContext", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(1, 0)
+		release, err := s.AcquireWorker(context.Background(), "holder")
+		if err != nil {
+			t.Fatalf("AcquireWorker failed: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := s.AcquireWorker(ctx, "waiter"); err != context.DeadlineExceeded {
+			t.Fatalf("AcquireWorker = %v, want deadline exceeded", err)
+		}
+
+		// The abandoned wait must not leak the slot
+		release()
+		release2, err := s.AcquireWorker(context.Background(), "next")
+		if err != nil {
+			t.Fatalf("AcquireWorker after release failed: %v", err)
+		}
+		release2()
+	})
+}
+
+func TestFairScheduler_UnregisterLeavesNoEntry(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_UnregisterLeavesNoEntry", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(1, 0)
+		s.Register("run", PriorityHigh)
+		release, err := s.AcquireWorker(context.Background(), "run")
+		if err != nil {
+			t.Fatalf("AcquireWorker failed: %v", err)
+		}
+
+		// A second batch of the run queues behind the first
+		granted := make(chan func(), 1)
+		go func() {
+			release2, err := s.AcquireWorker(context.Background(), "run")
+			if err != nil {
+				t.Errorf("Queued AcquireWorker failed: %v", err)
+				close(granted)
+				return
+			}
+			granted <- release2
+		}()
+		for {
+			s.workers.mu.Lock()
+			queued := len(s.workers.waiters)
+			s.workers.mu.Unlock()
+			if queued == 1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		// The run goes away; the release hands the slot to its queued
+		// batch, which must not register it again
+		s.Unregister("run")
+		release()
+		if release2 := <-granted; release2 != nil {
+			release2()
+		}
+		if err := s.WaitToken(context.Background(), "run"); err != nil {
+			t.Fatalf("WaitToken failed: %v", err)
+		}
+
+		for name, q := range map[string]*fairQueue{"workers": s.workers, "tokens": s.tokens} {
+			q.mu.Lock()
+			if len(q.runs) != 0 || q.inUse != 0 {
+				t.Errorf("%s: expected no runs and no slots in use, got %d runs and %d in use", name, len(q.runs), q.inUse)
+			}
+			q.mu.Unlock()
+		}
+	})
+}
+
+func TestParsePriority(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParsePriority", nil, func(t *testing.T, tx *gorm.DB) {
+		if p, err := ParsePriority(""); err != nil || p != PriorityNormal {
+			t.Errorf("ParsePriority(\"\") = %q, %v; want normal", p, err)
+		}
+		if p, err := ParsePriority("urgent"); err != nil || p.Weight() != 8 {
+			t.Errorf("ParsePriority(urgent) = %q (weight %d), %v", p, p.Weight(), err)
+		}
+		if _, err := ParsePriority("critical"); err == nil {
+			t.Error("ParsePriority(critical) should fail")
+		}
+	})
+}
+
+func TestFairScheduler_TypeLimit(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFairScheduler_TypeLimit", nil, func(t *testing.T, tx *gorm.DB) {
+		s := NewFairScheduler(3, 0)
+		s.SetTypeLimit(DataTypeCVE, 1)
+		if limits := s.TypeLimits(); len(limits) != 1 || limits[DataTypeCVE] != 1 {
+			t.Errorf("TypeLimits = %v, want only cve capped at 1", limits)
+		}
+
+		release, err := s.AcquireTypeSlot(context.Background(), DataTypeCVE)
+		if err != nil {
+			t.Fatalf("AcquireTypeSlot failed: %v", err)
+		}
+
+		// CVE is at its limit
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := s.AcquireTypeSlot(ctx, DataTypeCVE); err != context.DeadlineExceeded {
+			t.Fatalf("AcquireTypeSlot over the type limit = %v, want deadline exceeded", err)
+		}
+
+		// Uncapped data types are not held back
+		for i := 0; i < 5; i++ {
+			r, err := s.AcquireTypeSlot(context.Background(), DataTypeCWE)
+			if err != nil {
+				t.Fatalf("AcquireTypeSlot for cwe failed: %v", err)
+			}
+			defer r()
+		}
+
+		// Raising the limit wakes a waiting CVE acquire
+		granted := make(chan func(), 1)
+		go func() {
+			r, err := s.AcquireTypeSlot(context.Background(), DataTypeCVE)
+			if err == nil {
+				granted <- r
+			}
+		}()
+		select {
+		case <-granted:
+			t.Fatal("Expected the CVE acquire to wait for a slot")
+		case <-time.After(20 * time.Millisecond):
+		}
+		s.SetTypeLimit(DataTypeCVE, 2)
+		select {
+		case r := <-granted:
+			r()
+		case <-time.After(time.Second):
+			t.Fatal("Expected the CVE acquire to be granted after the limit was raised")
+		}
+		release()
+
+		s.SetTypeLimit(DataTypeCVE, 0)
+		if limits := s.TypeLimits(); len(limits) != 0 {
+			t.Errorf("TypeLimits = %v, want none after removing the cap", limits)
+		}
+	})
+}
+
+func TestJobExecutor_TypeFanOut(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_TypeFanOut", nil, func(t *testing.T, tx *gorm.DB) {
+		e := NewJobExecutor(nil, nil, newTestLogger(), 10, map[DataType]int{DataTypeCVE: 3})
+		fanOut := e.typeFanOut(DataTypeCVE)
+
+		var mu sync.Mutex
+		inFlight, peak, calls := 0, 0, 0
+		err := fanOut(context.Background(), 20, func(i int) {
+			mu.Lock()
+			inFlight++
+			calls++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("fan-out failed: %v", err)
+		}
+		if calls != 20 {
+			t.Errorf("calls = %d, want 20", calls)
+		}
+		if peak < 2 || peak > 3 {
+			t.Errorf("peak concurrency = %d, want the cve limit of 3 to bind", peak)
+		}
+	})
+}
+
+func TestParseDataType(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestParseDataType", nil, func(t *testing.T, tx *gorm.DB) {
+		if d, err := ParseDataType("cwe"); err != nil || d != DataTypeCWE {
+			t.Errorf("ParseDataType(cwe) = %q, %v", d, err)
+		}
+		if _, err := ParseDataType("nvd"); err == nil {
+			t.Error("ParseDataType(nvd) should fail")
+		}
+	})
+}
+
+func TestJobExecutor_SetConcurrencyLimit(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_SetConcurrencyLimit", nil, func(t *testing.T, tx *gorm.DB) {
+		e := NewJobExecutor(nil, nil, newTestLogger(), 10, map[DataType]int{DataTypeCVE: 8})
+		if global, limits := e.ConcurrencyLimits(); global != 10 || limits[DataTypeCVE] != 8 {
+			t.Errorf("ConcurrencyLimits = %d, %v; want 10 with cve capped at 8", global, limits)
+		}
+
+		if err := e.SetConcurrencyLimit(DataTypeCWE, 2); err != nil {
+			t.Fatalf("SetConcurrencyLimit failed: %v", err)
+		}
+		if _, limits := e.ConcurrencyLimits(); limits[DataTypeCWE] != 2 || limits[DataTypeCVE] != 8 {
+			t.Errorf("ConcurrencyLimits = %v, want cwe 2 and cve 8", limits)
+		}
+		if err := e.SetConcurrencyLimit("nvd", 2); err == nil {
+			t.Error("Expected an unknown data type to be rejected")
+		}
+		if err := e.SetConcurrencyLimit(DataTypeCWE, -1); err == nil {
+			t.Error("Expected a negative limit to be rejected")
+		}
+	})
+}
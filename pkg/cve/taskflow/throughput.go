@@ -0,0 +1,153 @@
+package taskflow
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxThroughputSamples is the number of samples kept per active run
+	maxThroughputSamples = 120
+	// throughputWindow is the sliding window the current rates of a run are
+	// computed over
+	throughputWindow = time.Minute
+)
+
+// ThroughputSample is a snapshot of a run's counters, taken when it starts
+// and whenever a batch completes. The rates are those since the previous
+// sample.
+type ThroughputSample struct {
+	Time             time.Time `json:"time"`
+	FetchedCount     int64     `json:"fetched_count"`
+	StoredCount      int64     `json:"stored_count"`
+	ErrorCount       int64     `json:"error_count"`
+	FetchedPerSecond float64   `json:"fetched_per_second"`
+	StoredPerSecond  float64   `json:"stored_per_second"`
+}
+
+// ProviderMetrics is the throughput of an active run
+type ProviderMetrics struct {
+	RunID    string   `json:"session_id"`
+	DataType DataType `json:"data_type"`
+	// Rates over the samples of the last throughputWindow
+	FetchedPerSecond float64 `json:"fetched_per_second"`
+	StoredPerSecond  float64 `json:"stored_per_second"`
+	WindowSeconds    float64 `json:"window_seconds"`
+	// Samples, oldest first
+	Samples []ThroughputSample `json:"samples"`
+}
+
+// runThroughput holds the samples of one run
+type runThroughput struct {
+	dataType DataType
+	samples  []ThroughputSample
+}
+
+// throughputTracker records timestamped counter snapshots of the active runs
+type throughputTracker struct {
+	mu   sync.Mutex
+	runs map[string]*runThroughput
+	now  func() time.Time
+}
+
+func newThroughputTracker() *throughputTracker {
+	return &throughputTracker{
+		runs: make(map[string]*runThroughput),
+		now:  time.Now,
+	}
+}
+
+// register starts tracking a run from its current counters
+func (t *throughputTracker) register(run *JobRun) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.runs[run.ID] = &runThroughput{
+		dataType: run.DataType,
+		samples: []ThroughputSample{{
+			Time:         t.now(),
+			FetchedCount: run.FetchedCount,
+			StoredCount:  run.StoredCount,
+			ErrorCount:   run.ErrorCount,
+		}},
+	}
+}
+
+// unregister stops tracking a run and drops its samples
+func (t *throughputTracker) unregister(runID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.runs, runID)
+}
+
+// add records a sample with the counters of a run advanced by the given
+// deltas, the ones persisted to the RunStore
+func (t *throughputTracker) add(runID string, fetched, stored, errors int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.runs[runID]
+	if r == nil {
+		return
+	}
+
+	prev := r.samples[len(r.samples)-1]
+	sample := ThroughputSample{
+		Time:         t.now(),
+		FetchedCount: prev.FetchedCount + fetched,
+		StoredCount:  prev.StoredCount + stored,
+		ErrorCount:   prev.ErrorCount + errors,
+	}
+	sample.FetchedPerSecond, sample.StoredPerSecond = rates(prev, sample)
+
+	r.samples = append(r.samples, sample)
+	if len(r.samples) > maxThroughputSamples {
+		r.samples = append(r.samples[:0], r.samples[len(r.samples)-maxThroughputSamples:]...)
+	}
+}
+
+// snapshot returns the metrics of the tracked runs, ordered by data type,
+// each with at most limit samples (all if limit <= 0)
+func (t *throughputTracker) snapshot(limit int) []ProviderMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	metrics := make([]ProviderMetrics, 0, len(t.runs))
+	for runID, r := range t.runs {
+		m := ProviderMetrics{RunID: runID, DataType: r.dataType}
+
+		// Rates between the oldest sample in the window and the latest one
+		last := r.samples[len(r.samples)-1]
+		first := last
+		for i := len(r.samples) - 2; i >= 0 && now.Sub(r.samples[i].Time) <= throughputWindow; i-- {
+			first = r.samples[i]
+		}
+		m.FetchedPerSecond, m.StoredPerSecond = rates(first, last)
+		m.WindowSeconds = last.Time.Sub(first.Time).Seconds()
+
+		samples := r.samples
+		if limit > 0 && len(samples) > limit {
+			samples = samples[len(samples)-limit:]
+		}
+		m.Samples = append([]ThroughputSample(nil), samples...)
+		metrics = append(metrics, m)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].DataType != metrics[j].DataType {
+			return metrics[i].DataType < metrics[j].DataType
+		}
+		return metrics[i].RunID < metrics[j].RunID
+	})
+	return metrics
+}
+
+// rates returns the fetched and stored items per second from one sample to
+// a later one
+func rates(from, to ThroughputSample) (fetched, stored float64) {
+	elapsed := to.Time.Sub(from.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(to.FetchedCount-from.FetchedCount) / elapsed, float64(to.StoredCount-from.StoredCount) / elapsed
+}
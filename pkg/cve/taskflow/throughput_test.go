@@ -0,0 +1,75 @@
+package taskflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestThroughputTracker(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestThroughputTracker", nil, func(t *testing.T, tx *gorm.DB) {
+		now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		tracker := newThroughputTracker()
+		tracker.now = func() time.Time { return now }
+
+		tracker.register(&JobRun{ID: "cve-run", DataType: DataTypeCVE, FetchedCount: 100, StoredCount: 90})
+		tracker.register(&JobRun{ID: "cwe-run", DataType: DataTypeCWE})
+
+		// Two batches of the CVE run 10s apart, then a failed fetch
+		now = now.Add(10 * time.Second)
+		tracker.add("cve-run", 50, 40, 0)
+		now = now.Add(10 * time.Second)
+		tracker.add("cve-run", 100, 100, 0)
+		now = now.Add(5 * time.Second)
+		tracker.add("cve-run", 0, 0, 1)
+		tracker.add("unknown", 10, 10, 0)
+
+		metrics := tracker.snapshot(0)
+		if len(metrics) != 2 || metrics[0].DataType != DataTypeCVE || metrics[1].RunID != "cwe-run" {
+			t.Fatalf("Expected the CVE then the CWE run, got %+v", metrics)
+		}
+		m := metrics[0]
+		if len(m.Samples) != 4 {
+			t.Fatalf("Expected 4 samples, got %+v", m.Samples)
+		}
+		if s := m.Samples[2]; s.FetchedCount != 250 || s.StoredCount != 230 || s.FetchedPerSecond != 10 || s.StoredPerSecond != 10 {
+			t.Errorf("Unexpected second batch sample %+v", s)
+		}
+		if s := m.Samples[3]; s.ErrorCount != 1 || s.FetchedPerSecond != 0 {
+			t.Errorf("Unexpected failed fetch sample %+v", s)
+		}
+		// 150 fetched and 140 stored over 25s
+		if m.FetchedPerSecond != 6 || m.StoredPerSecond != 5.6 || m.WindowSeconds != 25 {
+			t.Errorf("Unexpected window rates %+v", m)
+		}
+		if c := metrics[1]; len(c.Samples) != 1 || c.FetchedPerSecond != 0 {
+			t.Errorf("Expected an idle CWE run, got %+v", c)
+		}
+
+		// Only the last samples are returned, and the window slides
+		now = now.Add(50 * time.Second)
+		tracker.add("cve-run", 60, 60, 0)
+		m = tracker.snapshot(2)[0]
+		if len(m.Samples) != 2 || m.Samples[1].FetchedCount != 310 {
+			t.Errorf("Expected the last 2 samples, got %+v", m.Samples)
+		}
+		if m.WindowSeconds != 55 || m.FetchedPerSecond != 60.0/55 {
+			t.Errorf("Expected rates since the first sample within a minute, got %+v", m)
+		}
+
+		// Samples are bounded
+		for i := 0; i < maxThroughputSamples+10; i++ {
+			tracker.add("cwe-run", 1, 1, 0)
+		}
+		if c := tracker.snapshot(0)[1]; len(c.Samples) != maxThroughputSamples || c.Samples[len(c.Samples)-1].FetchedCount != maxThroughputSamples+10 {
+			t.Errorf("Expected %d samples, got %d", maxThroughputSamples, len(c.Samples))
+		}
+
+		tracker.unregister("cve-run")
+		if metrics := tracker.snapshot(0); len(metrics) != 1 {
+			t.Errorf("Expected the unregistered run to be dropped, got %+v", metrics)
+		}
+	})
+}
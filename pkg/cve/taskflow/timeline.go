@@ -0,0 +1,208 @@
+package taskflow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Timeline event types
+const (
+	// EventTypeRun is recorded when a run is created or changes state
+	EventTypeRun = "run"
+	// EventTypeCVEChange is recorded when a CVE is created, updated, deleted
+	// or cached from remote
+	EventTypeCVEChange = "cve_change"
+)
+
+// DefaultTimelineCap is the maximum number of timeline events kept. Once
+// reached, the oldest events are dropped.
+const DefaultTimelineCap = 10000
+
+var timelineBucket = []byte("timeline")
+
+// TimelineEvent is one entry of the activity feed. Events are ordered by
+// RecordedAt, the time the store recorded them, never by a time carried in
+// the payload, so clocks of other services cannot reorder the feed.
+type TimelineEvent struct {
+	// Cursor identifies the event's position; pass it as since to get the
+	// events recorded after it
+	Cursor     string          `json:"cursor"`
+	Type       string          `json:"type"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// RunEvent is the payload of an EventTypeRun event
+type RunEvent struct {
+	RunID        string   `json:"run_id"`
+	DataType     DataType `json:"data_type"`
+	State        JobState `json:"state"`
+	FetchedCount int64    `json:"fetched_count"`
+	StoredCount  int64    `json:"stored_count"`
+	ErrorCount   int64    `json:"error_count"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// CVEChangeEvent is the payload of an EventTypeCVEChange event
+type CVEChangeEvent struct {
+	CVEID  string `json:"cve_id"`
+	Action string `json:"action"` // created, updated, deleted or cached
+}
+
+// RecordEvent appends an event of the given type to the timeline
+func (s *RunStore) RecordEvent(eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.putEvent(tx, eventType, data)
+	})
+}
+
+// putEvent appends an event within tx. The key is the recorded time followed
+// by a sequence number, both big-endian, so byte order is time order. If the
+// wall clock stepped back since the last event, the last event's time is
+// reused: the feed stays in recording order and a cursor never skips events.
+func (s *RunStore) putEvent(tx *bolt.Tx, eventType string, payload []byte) error {
+	b, err := tx.CreateBucketIfNotExists(timelineBucket)
+	if err != nil {
+		return err
+	}
+
+	recorded := time.Now().UTC()
+	if last, _ := b.Cursor().Last(); len(last) == 16 {
+		if lastNanos := int64(binary.BigEndian.Uint64(last[:8])); recorded.UnixNano() < lastNanos {
+			recorded = time.Unix(0, lastNanos).UTC()
+		}
+	}
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(recorded.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+
+	data, err := json.Marshal(&TimelineEvent{Type: eventType, RecordedAt: recorded, Payload: payload})
+	if err != nil {
+		return err
+	}
+	if err := b.Put(key, data); err != nil {
+		return err
+	}
+
+	// Drop the oldest events beyond the cap
+	over := b.Stats().KeyN - DefaultTimelineCap
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && over > 0; k, _ = c.First() {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		over--
+	}
+	return nil
+}
+
+// runEvent builds the timeline payload of a run's current state
+func runEvent(run *JobRun) ([]byte, error) {
+	return json.Marshal(&RunEvent{
+		RunID:        run.ID,
+		DataType:     run.DataType,
+		State:        run.State,
+		FetchedCount: run.FetchedCount,
+		StoredCount:  run.StoredCount,
+		ErrorCount:   run.ErrorCount,
+		Error:        run.ErrorMessage,
+	})
+}
+
+// ListTimeline returns up to limit events recorded after since, oldest
+// first, and the cursor to pass as since to continue. since is a cursor from
+// an earlier call or an RFC 3339 timestamp; when empty the latest limit
+// events are returned. With no newer events the returned cursor is since.
+func (s *RunStore) ListTimeline(since string, limit int) ([]TimelineEvent, string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var start []byte
+	if since != "" {
+		var err error
+		if start, err = timelineSeekKey(since); err != nil {
+			return nil, "", err
+		}
+	}
+
+	events := []TimelineEvent{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(timelineBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+
+		var k, v []byte
+		if start == nil {
+			// Step back limit events from the end to return the latest ones
+			k, v = c.Last()
+			for i := 1; i < limit && k != nil; i++ {
+				if pk, pv := c.Prev(); pk != nil {
+					k, v = pk, pv
+				} else {
+					break
+				}
+			}
+		} else {
+			k, v = c.Seek(start)
+		}
+
+		for ; k != nil && len(events) < limit; k, v = c.Next() {
+			var event TimelineEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				continue
+			}
+			event.Cursor = hex.EncodeToString(k)
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := since
+	if len(events) > 0 {
+		next = events[len(events)-1].Cursor
+	}
+	return events, next, nil
+}
+
+// timelineSeekKey returns the first key after since
+func timelineSeekKey(since string) ([]byte, error) {
+	if key, err := hex.DecodeString(since); err == nil && len(key) == 16 {
+		// Seek lands on the cursor itself, so start right after it
+		seq := binary.BigEndian.Uint64(key[8:])
+		if seq == ^uint64(0) {
+			return timeKey(int64(binary.BigEndian.Uint64(key[:8])) + 1), nil
+		}
+		binary.BigEndian.PutUint64(key[8:], seq+1)
+		return key, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since %q: must be a cursor or an RFC 3339 timestamp", since)
+	}
+	return timeKey(t.UnixNano() + 1), nil
+}
+
+// timeKey is the smallest key recorded at nanos
+func timeKey(nanos int64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(nanos))
+	return key
+}
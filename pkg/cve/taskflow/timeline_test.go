@@ -0,0 +1,151 @@
+package taskflow
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	bolt "go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+func TestTimeline_MergesRunAndCVEEvents(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestTimeline_MergesRunAndCVEEvents", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+
+		if _, err := rs.CreateRun("run-1", 0, 100, DataTypeCVE); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+		if err := rs.UpdateState("run-1", StateRunning); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+		if err := rs.RecordEvent(EventTypeCVEChange, CVEChangeEvent{CVEID: "CVE-2024-0001", Action: "updated"}); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+		if err := rs.UpdateProgress("run-1", 200, 200, 0); err != nil {
+			t.Fatalf("UpdateProgress failed: %v", err)
+		}
+		if err := rs.UpdateState("run-1", StateCompleted); err != nil {
+			t.Fatalf("UpdateState failed: %v", err)
+		}
+
+		events, next, err := rs.ListTimeline("", 10)
+		if err != nil {
+			t.Fatalf("ListTimeline failed: %v", err)
+		}
+		wantTypes := []string{EventTypeRun, EventTypeRun, EventTypeCVEChange, EventTypeRun}
+		if len(events) != len(wantTypes) {
+			t.Fatalf("Expected %d events, got %d", len(wantTypes), len(events))
+		}
+		for i, e := range events {
+			if e.Type != wantTypes[i] {
+				t.Errorf("Event %d: expected type %s, got %s", i, wantTypes[i], e.Type)
+			}
+			if i > 0 && e.RecordedAt.Before(events[i-1].RecordedAt) {
+				t.Errorf("Event %d recorded before its predecessor", i)
+			}
+		}
+		if next != events[len(events)-1].Cursor {
+			t.Errorf("Expected next cursor of the last event, got %s", next)
+		}
+
+		var done RunEvent
+		if err := json.Unmarshal(events[3].Payload, &done); err != nil {
+			t.Fatalf("Failed to decode run event: %v", err)
+		}
+		if done.State != StateCompleted || done.StoredCount != 200 {
+			t.Errorf("Expected a completed run with 200 stored, got %+v", done)
+		}
+
+		// Nothing newer than the last cursor yet
+		newer, cursor, err := rs.ListTimeline(next, 10)
+		if err != nil || len(newer) != 0 || cursor != next {
+			t.Fatalf("Expected no newer events, got %d, %s, %v", len(newer), cursor, err)
+		}
+	})
+}
+
+func TestTimeline_CursorPagination(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestTimeline_CursorPagination", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+		for i := 0; i < 5; i++ {
+			if err := rs.RecordEvent(EventTypeCVEChange, map[string]int{"n": i}); err != nil {
+				t.Fatalf("RecordEvent failed: %v", err)
+			}
+		}
+
+		// Without since, the latest events are returned
+		latest, _, err := rs.ListTimeline("", 2)
+		if err != nil {
+			t.Fatalf("ListTimeline failed: %v", err)
+		}
+		if len(latest) != 2 || string(latest[0].Payload) != `{"n":3}` || string(latest[1].Payload) != `{"n":4}` {
+			t.Fatalf("Expected the last two events, got %v", latest)
+		}
+
+		// Paging from the start by timestamp sees every event exactly once
+		var seen []string
+		cursor := time.Unix(0, 0).UTC().Format(time.RFC3339Nano)
+		for {
+			page, next, err := rs.ListTimeline(cursor, 2)
+			if err != nil {
+				t.Fatalf("ListTimeline failed: %v", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			for _, e := range page {
+				seen = append(seen, string(e.Payload))
+			}
+			cursor = next
+		}
+		if len(seen) != 5 || seen[0] != `{"n":0}` || seen[4] != `{"n":4}` {
+			t.Errorf("Expected all five events in order, got %v", seen)
+		}
+
+		if _, _, err := rs.ListTimeline("not-a-cursor", 2); err == nil {
+			t.Error("Expected an invalid since to be rejected")
+		}
+	})
+}
+
+func TestTimeline_ClockStepBackKeepsOrder(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestTimeline_ClockStepBackKeepsOrder", nil, func(t *testing.T, tx *gorm.DB) {
+		rs := NewTempRunStore(t)
+
+		// An event recorded an hour ahead, as if the clock later stepped back
+		future := time.Now().Add(time.Hour).UTC()
+		err := rs.db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists(timelineBucket)
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 16)
+			binary.BigEndian.PutUint64(key[:8], uint64(future.UnixNano()))
+			data, _ := json.Marshal(&TimelineEvent{Type: EventTypeCVEChange, RecordedAt: future, Payload: json.RawMessage(`{}`)})
+			return b.Put(key, data)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, _, _ := rs.ListTimeline("", 1)
+
+		if err := rs.RecordEvent(EventTypeCVEChange, CVEChangeEvent{CVEID: "CVE-2024-0002", Action: "created"}); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+
+		// The new event still follows the earlier one and a poller sees it
+		newer, _, err := rs.ListTimeline(first[0].Cursor, 10)
+		if err != nil {
+			t.Fatalf("ListTimeline failed: %v", err)
+		}
+		if len(newer) != 1 || newer[0].Type != EventTypeCVEChange {
+			t.Fatalf("Expected the new event after the cursor, got %v", newer)
+		}
+		if newer[0].RecordedAt.Before(first[0].RecordedAt) {
+			t.Errorf("Expected recorded times to stay monotonic, got %v before %v", newer[0].RecordedAt, first[0].RecordedAt)
+		}
+	})
+}
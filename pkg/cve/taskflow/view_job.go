@@ -0,0 +1,108 @@
+package taskflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var viewJobBucket = []byte("cwe_view_jobs")
+
+// States of a CWE view job checkpoint
+const (
+	ViewJobRunning   = "running"
+	ViewJobStopped   = "stopped"
+	ViewJobCompleted = "completed"
+)
+
+// ViewJobCheckpoint is the progress of a CWE view job, keyed by its session
+// ID, so a stopped or interrupted job can go on from the next page
+type ViewJobCheckpoint struct {
+	SessionID string `json:"session_id"`
+	// State is running while the job runs or if it was interrupted by a
+	// restart, stopped if it was stopped, and completed once the last page
+	// was stored
+	State      string    `json:"state"`
+	StartIndex int       `json:"start_index"`
+	PageSize   int       `json:"page_size"`
+	NextIndex  int       `json:"next_index"` // Start index of the next page to fetch
+	Fetched    int       `json:"fetched"`
+	Stored     int       `json:"stored"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SaveViewJobCheckpoint stores the checkpoint of a CWE view job, replacing
+// the previous one of the session
+func (s *RunStore) SaveViewJobCheckpoint(cp ViewJobCheckpoint) error {
+	if cp.SessionID == "" {
+		return fmt.Errorf("view job checkpoint has no session ID")
+	}
+	cp.UpdatedAt = time.Now()
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = cp.UpdatedAt
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal view job checkpoint: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(viewJobBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(cp.SessionID), data)
+	})
+}
+
+// GetViewJobCheckpoint returns the checkpoint of a CWE view job session, or
+// nil if there is none
+func (s *RunStore) GetViewJobCheckpoint(sessionID string) (*ViewJobCheckpoint, error) {
+	var cp *ViewJobCheckpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(viewJobBucket)
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(sessionID))
+		if data == nil {
+			return nil
+		}
+		cp = &ViewJobCheckpoint{}
+		return json.Unmarshal(data, cp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// ListViewJobCheckpoints returns the checkpoints of all CWE view job
+// sessions, newest first
+func (s *RunStore) ListViewJobCheckpoints() ([]ViewJobCheckpoint, error) {
+	var checkpoints []ViewJobCheckpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(viewJobBucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var cp ViewJobCheckpoint
+			if err := json.Unmarshal(v, &cp); err != nil {
+				return nil
+			}
+			checkpoints = append(checkpoints, cp)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].CreatedAt.After(checkpoints[j].CreatedAt)
+	})
+	return checkpoints, nil
+}
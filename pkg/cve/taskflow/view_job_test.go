@@ -0,0 +1,51 @@
+package taskflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestRunStore_ViewJobCheckpoints(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRunStore_ViewJobCheckpoints", nil, func(t *testing.T, tx *gorm.DB) {
+		store := NewTempRunStore(t)
+
+		if cp, err := store.GetViewJobCheckpoint("missing"); err != nil || cp != nil {
+			t.Fatalf("Expected no checkpoint, got %+v, %v", cp, err)
+		}
+		if err := store.SaveViewJobCheckpoint(ViewJobCheckpoint{}); err == nil {
+			t.Error("Expected an error for a checkpoint without session ID")
+		}
+
+		for _, id := range []string{"older", "newer"} {
+			if err := store.SaveViewJobCheckpoint(ViewJobCheckpoint{SessionID: id, State: ViewJobRunning, PageSize: 100}); err != nil {
+				t.Fatalf("SaveViewJobCheckpoint failed: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// Updating a checkpoint keeps its creation time
+		older, _ := store.GetViewJobCheckpoint("older")
+		older.NextIndex, older.State = 100, ViewJobStopped
+		if err := store.SaveViewJobCheckpoint(*older); err != nil {
+			t.Fatalf("SaveViewJobCheckpoint failed: %v", err)
+		}
+
+		cp, err := store.GetViewJobCheckpoint("older")
+		if err != nil || cp == nil {
+			t.Fatalf("GetViewJobCheckpoint failed: %+v, %v", cp, err)
+		}
+		if cp.NextIndex != 100 || cp.State != ViewJobStopped || !cp.CreatedAt.Equal(older.CreatedAt) {
+			t.Errorf("Unexpected updated checkpoint %+v", cp)
+		}
+
+		list, err := store.ListViewJobCheckpoints()
+		if err != nil {
+			t.Fatalf("ListViewJobCheckpoints failed: %v", err)
+		}
+		if len(list) != 2 || list[0].SessionID != "newer" || list[1].SessionID != "older" {
+			t.Errorf("Expected checkpoints newest first, got %+v", list)
+		}
+	})
+}
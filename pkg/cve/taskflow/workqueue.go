@@ -0,0 +1,153 @@
+package taskflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSubmitTimeout is how long a batch waits for a worker of a saturated
+// pool before it is rejected
+const DefaultSubmitTimeout = 30 * time.Second
+
+// ErrExecutorSaturated is returned when a batch is rejected because the
+// worker pool stayed saturated: the submit queue was full or no worker freed
+// up within the submit timeout. The job loop retries the batch after its next
+// rate-limit token.
+var ErrExecutorSaturated = errors.New("executor saturated")
+
+// ExecutorStats reports how saturated the worker pool of the executor is
+type ExecutorStats struct {
+	Workers int `json:"workers"`
+	// InFlight is the number of batches running on a worker
+	InFlight int `json:"in_flight"`
+	// QueueDepth is the number of batches waiting for a worker
+	QueueDepth    int `json:"queue_depth"`
+	QueueCapacity int `json:"queue_capacity"`
+	// Peaks since the executor was created
+	PeakInFlight   int `json:"peak_in_flight"`
+	PeakQueueDepth int `json:"peak_queue_depth"`
+	// Cumulative counts of batches
+	Submitted int64 `json:"submitted"`
+	Completed int64 `json:"completed"`
+	Rejected  int64 `json:"rejected"`
+	// Rejected split by cause
+	RejectedQueueFull    int64   `json:"rejected_queue_full"`
+	RejectedTimeout      int64   `json:"rejected_timeout"`
+	SubmitTimeoutSeconds float64 `json:"submit_timeout_seconds"`
+}
+
+// workQueue bounds the batches waiting for a worker and counts them. A batch
+// is queued until the scheduler grants it a worker, and rejected instead of
+// queued when capacity batches are already waiting or when the grant takes
+// longer than timeout.
+type workQueue struct {
+	mu       sync.Mutex
+	workers  int
+	capacity int
+	timeout  time.Duration
+
+	queued, inFlight         int
+	peakQueued, peakInFlight int
+	submitted, completed     int64
+	rejectedFull             int64
+	rejectedTimeout          int64
+}
+
+// newWorkQueue returns a queue in front of a pool of workers that holds
+// twice as many waiting batches as there are workers, so that a batch
+// handed a freed worker is not counted against the next one submitted
+func newWorkQueue(workers int) *workQueue {
+	return &workQueue{workers: workers, capacity: 2 * max(workers, 1), timeout: DefaultSubmitTimeout}
+}
+
+// setTimeout sets how long a batch waits for a worker; 0 restores the default
+func (q *workQueue) setTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultSubmitTimeout
+	}
+	q.mu.Lock()
+	q.timeout = timeout
+	q.mu.Unlock()
+}
+
+// submit queues a batch until acquire grants it a worker, then runs it and
+// releases the worker. It returns ErrExecutorSaturated if the batch was
+// rejected and ctx's error if ctx ended while the batch waited; run is not
+// called then.
+func (q *workQueue) submit(ctx context.Context, acquire func(ctx context.Context) (func(), error), run func()) error {
+	q.mu.Lock()
+	if q.queued >= q.capacity {
+		q.rejectedFull++
+		q.mu.Unlock()
+		return fmt.Errorf("%w: %d batches already waiting for a worker", ErrExecutorSaturated, q.capacity)
+	}
+	q.queued++
+	q.peakQueued = max(q.peakQueued, q.queued)
+	timeout := q.timeout
+	q.mu.Unlock()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	release, err := acquire(waitCtx)
+	cancel()
+
+	q.mu.Lock()
+	q.queued--
+	if err != nil {
+		if ctx.Err() != nil {
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+		q.rejectedTimeout++
+		q.mu.Unlock()
+		return fmt.Errorf("%w: no worker freed up within %v", ErrExecutorSaturated, timeout)
+	}
+	q.inFlight++
+	q.peakInFlight = max(q.peakInFlight, q.inFlight)
+	q.submitted++
+	q.mu.Unlock()
+
+	defer func() {
+		release()
+		q.mu.Lock()
+		q.inFlight--
+		q.completed++
+		q.mu.Unlock()
+	}()
+	run()
+	return nil
+}
+
+// stats returns a snapshot of the gauges and counters
+func (q *workQueue) stats() ExecutorStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return ExecutorStats{
+		Workers:              q.workers,
+		InFlight:             q.inFlight,
+		QueueDepth:           q.queued,
+		QueueCapacity:        q.capacity,
+		PeakInFlight:         q.peakInFlight,
+		PeakQueueDepth:       q.peakQueued,
+		Submitted:            q.submitted,
+		Completed:            q.completed,
+		Rejected:             q.rejectedFull + q.rejectedTimeout,
+		RejectedQueueFull:    q.rejectedFull,
+		RejectedTimeout:      q.rejectedTimeout,
+		SubmitTimeoutSeconds: q.timeout.Seconds(),
+	}
+}
+
+// SetSubmitTimeout sets how long a batch waits for a worker of a saturated
+// pool before it is rejected and retried; 0 restores DefaultSubmitTimeout
+func (e *JobExecutor) SetSubmitTimeout(timeout time.Duration) {
+	e.queue.setTimeout(timeout)
+}
+
+// GetExecutorStats returns the queue depth, in-flight batches and rejected
+// batch counts of the worker pool
+func (e *JobExecutor) GetExecutorStats() ExecutorStats {
+	return e.queue.stats()
+}
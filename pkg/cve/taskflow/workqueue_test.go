@@ -0,0 +1,139 @@
+package taskflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestWorkQueue_RejectsWhenSaturated(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestWorkQueue_RejectsWhenSaturated", nil, func(t *testing.T, tx *gorm.DB) {
+		q := newWorkQueue(1)
+		q.capacity = 1
+		q.setTimeout(50 * time.Millisecond)
+		worker := make(chan struct{}, 1)
+		acquire := func(ctx context.Context) (func(), error) {
+			select {
+			case worker <- struct{}{}:
+				return func() { <-worker }, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		// A holds the only worker until unblocked
+		unblock := make(chan struct{})
+		running := make(chan struct{})
+		doneA := make(chan error)
+		go func() {
+			doneA <- q.submit(context.Background(), acquire, func() {
+				close(running)
+				<-unblock
+			})
+		}()
+		<-running
+
+		// B waits in the queue and times out; C finds the queue full
+		doneB := make(chan error)
+		go func() {
+			doneB <- q.submit(context.Background(), acquire, func() { t.Error("B should not run") })
+		}()
+		for q.stats().QueueDepth != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		if err := q.submit(context.Background(), acquire, func() { t.Error("C should not run") }); !errors.Is(err, ErrExecutorSaturated) {
+			t.Errorf("Expected C rejected with a full queue, got %v", err)
+		}
+		if stats := q.stats(); stats.InFlight != 1 || stats.QueueDepth != 1 {
+			t.Errorf("Expected 1 in flight and 1 queued, got %+v", stats)
+		}
+		if err := <-doneB; !errors.Is(err, ErrExecutorSaturated) {
+			t.Errorf("Expected B rejected after the timeout, got %v", err)
+		}
+
+		// A cancelled wait is not a rejection
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := q.submit(ctx, acquire, func() { t.Error("D should not run") }); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected D cancelled, got %v", err)
+		}
+
+		close(unblock)
+		if err := <-doneA; err != nil {
+			t.Fatalf("A failed: %v", err)
+		}
+		if err := q.submit(context.Background(), acquire, func() {}); err != nil {
+			t.Fatalf("E failed once the worker freed up: %v", err)
+		}
+
+		stats := q.stats()
+		if stats.Submitted != 2 || stats.Completed != 2 || stats.Rejected != 2 ||
+			stats.RejectedQueueFull != 1 || stats.RejectedTimeout != 1 {
+			t.Errorf("Unexpected counters %+v", stats)
+		}
+		if stats.InFlight != 0 || stats.QueueDepth != 0 || stats.PeakInFlight != 1 || stats.PeakQueueDepth != 1 {
+			t.Errorf("Unexpected gauges %+v", stats)
+		}
+	})
+}
+
+func TestJobExecutor_BackpressureUnderLargeImport(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestJobExecutor_BackpressureUnderLargeImport", nil, func(t *testing.T, tx *gorm.DB) {
+		const records, batchSize = 10000, 100
+		store := NewTempRunStore(t)
+		// Two imports share a single worker
+		executor := NewJobExecutor(nil, store, newTestLogger(), 1, nil)
+		executor.scheduler = NewFairScheduler(1, 0)
+
+		providers := make(map[DataType]*listProvider)
+		for _, dataType := range []DataType{DataTypeCAPEC, DataTypeCWE} {
+			provider := &listProvider{records: make([]string, records)}
+			for i := range provider.records {
+				provider.records[i] = fmt.Sprintf("%s-%d", dataType, i)
+			}
+			providers[dataType] = provider
+			err := executor.RegisterProvider(dataType, func(run *JobRun, invoker RPCInvoker, logger *common.Logger) (Provider, error) {
+				return provider, nil
+			})
+			if err != nil {
+				t.Fatalf("RegisterProvider failed: %v", err)
+			}
+			if err := executor.StartTyped(context.Background(), string(dataType)+"-run", 0, batchSize, dataType, PriorityNormal); err != nil {
+				t.Fatalf("Failed to start run: %v", err)
+			}
+		}
+
+		for dataType := range providers {
+			runID := string(dataType) + "-run"
+			var run *JobRun
+			deadline := time.Now().Add(30 * time.Second)
+			for time.Now().Before(deadline) {
+				if run, _ = store.GetRun(runID); run != nil && run.State == StateCompleted {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if run == nil || run.State != StateCompleted || run.StoredCount != records {
+				t.Fatalf("Run %s did not store %d records: %+v", runID, records, run)
+			}
+		}
+
+		// 100 batches and the final empty fetch of each run
+		stats := executor.GetExecutorStats()
+		if stats.Submitted != 2*(records/batchSize+1) || stats.Completed != stats.Submitted {
+			t.Errorf("Expected every batch submitted and completed, got %+v", stats)
+		}
+		if stats.Workers != 1 || stats.PeakInFlight != 1 || stats.PeakQueueDepth > stats.QueueCapacity {
+			t.Errorf("Expected at most one batch in flight and a bounded queue, got %+v", stats)
+		}
+		if stats.InFlight != 0 || stats.QueueDepth != 0 || stats.Rejected != 0 {
+			t.Errorf("Expected an idle pool without rejections, got %+v", stats)
+		}
+	})
+}
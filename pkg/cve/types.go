@@ -8,6 +8,11 @@ import (
 const (
 	// NVDAPIURL is the base URL for the NVD CVE API v2.0
 	NVDAPIURL = "https://services.nvd.nist.gov/rest/json/cves/2.0"
+	// EPSSAPIURL is the base URL for the FIRST EPSS API
+	EPSSAPIURL = "https://api.first.org/data/v1/epss"
+	// KEVFeedURL is the JSON feed of the CISA Known Exploited
+	// Vulnerabilities catalog
+	KEVFeedURL = "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json"
 	// nvdTimeFormat is the NVD timestamp format: "2021-12-10T10:15:09.143"
 	nvdTimeFormat = "2006-01-02T15:04:05.999"
 )
@@ -85,6 +90,71 @@ type CVEItem struct {
 	Configurations        []Config        `json:"configurations,omitempty"`
 	References            []Reference     `json:"references,omitempty"`
 	VendorComments        []VendorComment `json:"vendorComments,omitempty"`
+
+	// Status is derived locally from VulnStatus and CVETags (see DeriveStatus);
+	// it is not part of the NVD payload
+	Status string `json:"status,omitempty"`
+
+	// CVSSSummary is derived locally from Metrics (see DeriveCVSS); it is
+	// not part of the NVD payload
+	CVSSSummary
+
+	// EPSS is the exploit prediction score from FIRST, nil if it was not
+	// fetched or FIRST has no score for the CVE; it is not part of the NVD
+	// payload
+	EPSS *EPSS `json:"epss,omitempty"`
+
+	// KEV is the CISA Known Exploited Vulnerabilities listing of the CVE,
+	// nil if it is not listed; it is not part of the NVD payload
+	KEV *KEV `json:"kev,omitempty"`
+}
+
+// EPSS is the Exploit Prediction Scoring System entry of a CVE
+type EPSS struct {
+	// Score is the probability of exploitation activity in the next 30 days
+	Score float64 `json:"score"`
+	// Percentile is the share of scored CVEs with a lower or equal score
+	Percentile float64 `json:"percentile"`
+	// Date is the day the score was computed, as YYYY-MM-DD
+	Date string `json:"date"`
+}
+
+// KEV is the CISA Known Exploited Vulnerabilities listing of a CVE
+type KEV struct {
+	// DateAdded is the day the CVE was added to the catalog, as YYYY-MM-DD
+	DateAdded string `json:"date_added"`
+	// DueDate is the day by which federal agencies must remediate the CVE,
+	// as YYYY-MM-DD
+	DueDate string `json:"due_date"`
+	// RequiredAction is the remediation CISA requires
+	RequiredAction string `json:"required_action,omitempty"`
+	// KnownRansomwareCampaignUse is "Known" or "Unknown"
+	KnownRansomwareCampaignUse string `json:"known_ransomware_campaign_use,omitempty"`
+}
+
+// KEVCatalog is the CISA Known Exploited Vulnerabilities catalog as
+// published in its JSON feed
+type KEVCatalog struct {
+	Title           string     `json:"title"`
+	CatalogVersion  string     `json:"catalogVersion"`
+	DateReleased    string     `json:"dateReleased"`
+	Count           int        `json:"count"`
+	Vulnerabilities []KEVEntry `json:"vulnerabilities"`
+}
+
+// KEVEntry is a vulnerability of the KEV catalog, with the field names of
+// the feed
+type KEVEntry struct {
+	CVEID                      string `json:"cveID"`
+	VendorProject              string `json:"vendorProject"`
+	Product                    string `json:"product"`
+	VulnerabilityName          string `json:"vulnerabilityName"`
+	DateAdded                  string `json:"dateAdded"`
+	ShortDescription           string `json:"shortDescription"`
+	RequiredAction             string `json:"requiredAction"`
+	DueDate                    string `json:"dueDate"`
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+	Notes                      string `json:"notes"`
 }
 
 // Description represents a CVE description
@@ -357,4 +427,28 @@ type Reference struct {
 	URL    string   `json:"url"`
 	Source string   `json:"source,omitempty"`
 	Tags   []string `json:"tags,omitempty"`
+
+	// Category is derived locally from Tags (see ClassifyReference) when the
+	// CVE is read from the local store; it is not part of the NVD payload
+	Category string `json:"category,omitempty"`
+
+	// Health is the last probe result for URL, attached locally when the
+	// reference health checker is enabled; it is not part of the NVD payload
+	Health *ReferenceHealth `json:"health,omitempty"`
+}
+
+// Reference health statuses recorded by the reference health checker
+const (
+	ReferenceHealthOK       = "ok"
+	ReferenceHealthNotFound = "404"
+	ReferenceHealthTimeout  = "timeout"
+	ReferenceHealthError    = "error"
+	ReferenceHealthBlocked  = "robots_disallowed"
+)
+
+// ReferenceHealth describes the reachability of a reference URL
+type ReferenceHealth struct {
+	Status     string    `json:"status"`
+	HTTPStatus int       `json:"httpStatus,omitempty"`
+	CheckedAt  time.Time `json:"checkedAt"`
 }
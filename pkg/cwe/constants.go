@@ -14,6 +14,10 @@ const (
 	LogMsgNoMoreViews             = "No more views to fetch. Job completed."
 	LogMsgFailedSaveView          = "Failed to save view %s: %v"
 	LogMsgFetchedViews            = "Fetched %d views and stored %d"
+	LogMsgJobResumed              = "CWE view job resumed: session_id=%s, next_index=%d"
+	LogMsgJobRecovering           = "Recovering interrupted CWE view job: session_id=%s"
+	LogMsgShortLastPage           = "Got %d views for a page of %d; treating it as the last page"
+	LogMsgFailedSaveCheckpoint    = "Failed to save CWE view job checkpoint: %v"
 
 	// Local Store Log Messages
 	LogMsgImportingJSON         = "Importing CWE data from JSON file: %s"
@@ -0,0 +1,179 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
+	"github.com/cyw0ng95/v2e/pkg/cwe"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// pagedViewsInvoker serves a fixed list of views page by page and records
+// the views saved. Fetches from blockAt on wait until the job is cancelled.
+type pagedViewsInvoker struct {
+	mu      sync.Mutex
+	views   []cwe.CWEView
+	blockAt int
+	saved   []string
+}
+
+func (m *pagedViewsInvoker) InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error) {
+	if method == "RPCSaveCWEView" {
+		m.mu.Lock()
+		m.saved = append(m.saved, params.(cwe.CWEView).ID)
+		m.mu.Unlock()
+		return &subprocess.Message{Type: subprocess.MessageTypeResponse}, nil
+	}
+
+	p := params.(*rpc.FetchCVEsParams)
+	m.mu.Lock()
+	blockAt := m.blockAt
+	m.mu.Unlock()
+	if blockAt >= 0 && p.StartIndex >= blockAt {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	start, end := p.StartIndex, p.StartIndex+p.ResultsPerPage
+	if start > len(m.views) {
+		start = len(m.views)
+	}
+	if end > len(m.views) {
+		end = len(m.views)
+	}
+	data, _ := subprocess.MarshalFast(map[string]interface{}{"views": m.views[start:end]})
+	return &subprocess.Message{Type: subprocess.MessageTypeResponse, Payload: data}, nil
+}
+
+func (m *pagedViewsInvoker) savedViews() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.saved...)
+}
+
+func newPagedViewsInvoker(n, blockAt int) *pagedViewsInvoker {
+	m := &pagedViewsInvoker{blockAt: blockAt}
+	for i := 0; i < n; i++ {
+		m.views = append(m.views, cwe.CWEView{ID: fmt.Sprintf("%d", 1000+i)})
+	}
+	return m
+}
+
+// waitForCheckpoint polls the store until the session checkpoint satisfies
+// cond
+func waitForCheckpoint(t *testing.T, store *taskflow.RunStore, sessionID string, cond func(*taskflow.ViewJobCheckpoint) bool) *taskflow.ViewJobCheckpoint {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cp, err := store.GetViewJobCheckpoint(sessionID)
+		if err != nil {
+			t.Fatalf("GetViewJobCheckpoint failed: %v", err)
+		}
+		if cp != nil && cond(cp) {
+			return cp
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for checkpoint of %s, last %+v", sessionID, cp)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestController_ResumeFromCheckpoint(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestController_ResumeFromCheckpoint", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testCWEWriter{t}, "test", common.ErrorLevel)
+		store := taskflow.NewTempRunStore(t)
+		mock := newPagedViewsInvoker(5, 2)
+		c := NewController(mock, logger)
+		c.SetCheckpointStore(store)
+		ctx := context.Background()
+
+		// Params decoded from JSON are float64
+		sid, err := c.Start(ctx, map[string]interface{}{"results_per_page": float64(2)})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		waitForCheckpoint(t, store, sid, func(cp *taskflow.ViewJobCheckpoint) bool { return cp.NextIndex == 2 })
+		if err := c.Stop(ctx, sid); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		cp, _ := store.GetViewJobCheckpoint(sid)
+		if cp.State != taskflow.ViewJobStopped || cp.NextIndex != 2 || cp.PageSize != 2 {
+			t.Fatalf("Expected a stopped checkpoint at index 2, got %+v", cp)
+		}
+
+		// Resuming goes on from the next page; the short tail page ends the job
+		mock.mu.Lock()
+		mock.blockAt = -1
+		mock.mu.Unlock()
+		if _, err := c.Start(ctx, map[string]interface{}{"session_id": sid}); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+		cp = waitForCheckpoint(t, store, sid, func(cp *taskflow.ViewJobCheckpoint) bool { return cp.State == taskflow.ViewJobCompleted })
+		if cp.NextIndex != 5 || cp.Fetched != 5 || cp.Stored != 5 {
+			t.Errorf("Expected all 5 views fetched and stored, got %+v", cp)
+		}
+		if saved := mock.savedViews(); len(saved) != 5 || saved[2] != "1002" {
+			t.Errorf("Expected each view saved once in order, got %v", saved)
+		}
+
+		if _, err := c.Start(ctx, map[string]interface{}{"session_id": sid}); !errors.Is(err, ErrJobCompleted) {
+			t.Errorf("Expected ErrJobCompleted resuming a completed job, got %v", err)
+		}
+		if _, err := c.Start(ctx, map[string]interface{}{"session_id": "missing"}); !errors.Is(err, ErrNoCheckpoint) {
+			t.Errorf("Expected ErrNoCheckpoint for an unknown session, got %v", err)
+		}
+	})
+}
+
+func TestController_Recover(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestController_Recover", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(testCWEWriter{t}, "test", common.ErrorLevel)
+		store := taskflow.NewTempRunStore(t)
+		ctx := context.Background()
+
+		// A job interrupted by a restart is still running in the store; a
+		// stopped job stays stopped
+		for _, cp := range []taskflow.ViewJobCheckpoint{
+			{SessionID: "stopped", State: taskflow.ViewJobStopped, PageSize: 2},
+			{SessionID: "older", State: taskflow.ViewJobRunning, PageSize: 2, CreatedAt: time.Now().Add(-time.Hour)},
+			{SessionID: "interrupted", State: taskflow.ViewJobRunning, PageSize: 2, NextIndex: 4, Fetched: 4, CreatedAt: time.Now()},
+		} {
+			if err := store.SaveViewJobCheckpoint(cp); err != nil {
+				t.Fatalf("SaveViewJobCheckpoint failed: %v", err)
+			}
+		}
+
+		mock := newPagedViewsInvoker(5, -1)
+		c := NewController(mock, logger)
+		if sid, err := c.Recover(ctx); err != nil || sid != "" {
+			t.Fatalf("Expected no recovery without a store, got %q, %v", sid, err)
+		}
+		c.SetCheckpointStore(store)
+		sid, err := c.Recover(ctx)
+		if err != nil || sid != "interrupted" {
+			t.Fatalf("Expected the interrupted job recovered, got %q, %v", sid, err)
+		}
+		cp := waitForCheckpoint(t, store, sid, func(cp *taskflow.ViewJobCheckpoint) bool { return cp.State == taskflow.ViewJobCompleted })
+		if cp.NextIndex != 5 || cp.Fetched != 5 {
+			t.Errorf("Expected the job to fetch only the tail, got %+v", cp)
+		}
+		if saved := mock.savedViews(); len(saved) != 1 || saved[0] != "1004" {
+			t.Errorf("Expected only the last view saved, got %v", saved)
+		}
+		if cp, _ := store.GetViewJobCheckpoint("stopped"); cp.State != taskflow.ViewJobStopped {
+			t.Errorf("Expected the stopped job left alone, got %+v", cp)
+		}
+		if cp, _ := store.GetViewJobCheckpoint("older"); cp.State != taskflow.ViewJobStopped {
+			t.Errorf("Expected the older running job marked stopped, got %+v", cp)
+		}
+	})
+}
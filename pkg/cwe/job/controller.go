@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/cve/taskflow"
 	"github.com/cyw0ng95/v2e/pkg/cwe"
 	"github.com/cyw0ng95/v2e/pkg/jsonutil"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
@@ -17,8 +18,22 @@ import (
 var (
 	ErrJobRunning    = errors.New("job is already running")
 	ErrJobNotRunning = errors.New("job is not running")
+	// ErrNoCheckpoint is returned when resuming a session without a
+	// checkpoint, or without a checkpoint store
+	ErrNoCheckpoint = errors.New("no checkpoint for session")
+	// ErrJobCompleted is returned when resuming a session that already
+	// stored its last page
+	ErrJobCompleted = errors.New("job already completed")
 )
 
+// CheckpointStore persists the progress of view jobs; taskflow.RunStore
+// implements it
+type CheckpointStore interface {
+	SaveViewJobCheckpoint(cp taskflow.ViewJobCheckpoint) error
+	GetViewJobCheckpoint(sessionID string) (*taskflow.ViewJobCheckpoint, error)
+	ListViewJobCheckpoints() ([]taskflow.ViewJobCheckpoint, error)
+}
+
 // RPCInvoker is an interface for making RPC calls to other services
 type RPCInvoker interface {
 	InvokeRPC(ctx context.Context, target, method string, params interface{}) (interface{}, error)
@@ -29,10 +44,14 @@ type Controller struct {
 	rpcInvoker RPCInvoker
 	logger     *common.Logger
 
-	mu         sync.RWMutex
-	cancelFunc context.CancelFunc
-	running    bool
-	started    time.Time
+	mu          sync.RWMutex
+	cancelFunc  context.CancelFunc
+	jobCtx      context.Context // Context of the current job, identifying it
+	running     bool
+	started     time.Time
+	sessionID   string
+	checkpoints CheckpointStore
+	checkpoint  taskflow.ViewJobCheckpoint
 }
 
 // NewController creates a new CWE job controller
@@ -43,8 +62,19 @@ func NewController(rpcInvoker RPCInvoker, logger *common.Logger) *Controller {
 	}
 }
 
+// SetCheckpointStore makes jobs record their progress after every page in
+// store, so a stopped or interrupted job can be resumed (see Start and
+// Recover). Without a store, progress is lost when a job stops.
+func (c *Controller) SetCheckpointStore(store CheckpointStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints = store
+}
+
 // Start launches the background job to fetch and store CWE views.
 // Returns a session ID string which is a timestamp-based identifier.
+// With a "session_id" param, it resumes that session from the page after
+// the last one stored instead (see SetCheckpointStore).
 func (c *Controller) Start(ctx context.Context, params map[string]interface{}) (string, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -53,20 +83,118 @@ func (c *Controller) Start(ctx context.Context, params map[string]interface{}) (
 		return "", ErrJobRunning
 	}
 
+	if sessionID, _ := params["session_id"].(string); sessionID != "" {
+		return sessionID, c.resumeLocked(ctx, sessionID)
+	}
+
+	cp := taskflow.ViewJobCheckpoint{
+		SessionID: fmt.Sprintf("cwe-session-%d", time.Now().UnixNano()),
+		State:     taskflow.ViewJobRunning,
+		PageSize:  100,
+	}
+	if v, ok := intParam(params["start_index"]); ok && v >= 0 {
+		cp.StartIndex = v
+	}
+	if v, ok := intParam(params["results_per_page"]); ok && v > 0 {
+		cp.PageSize = v
+	}
+	cp.NextIndex = cp.StartIndex
+	c.saveCheckpointLocked(cp)
+
+	c.startLocked(ctx, cp)
+	c.logger.Info(cwe.LogMsgJobStarted, cp.SessionID)
+	return cp.SessionID, nil
+}
+
+// Recover resumes the most recent job that was running when the service
+// last stopped, as taskflow.JobExecutor.RecoverRuns does for CVE runs. Older
+// jobs left running are marked stopped, so they can still be resumed with
+// Start; jobs stopped with Stop stay stopped. It returns the resumed session
+// ID, or "" if there was none to resume.
+func (c *Controller) Recover(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checkpoints == nil || c.running {
+		return "", nil
+	}
+	checkpoints, err := c.checkpoints.ListViewJobCheckpoints()
+	if err != nil {
+		return "", err
+	}
+	resumed := ""
+	for _, cp := range checkpoints {
+		if cp.State != taskflow.ViewJobRunning {
+			continue
+		}
+		if resumed != "" {
+			cp.State = taskflow.ViewJobStopped
+			if err := c.checkpoints.SaveViewJobCheckpoint(cp); err != nil {
+				c.logger.Warn(cwe.LogMsgFailedSaveCheckpoint, err)
+			}
+			continue
+		}
+		c.logger.Info(cwe.LogMsgJobRecovering, cp.SessionID)
+		if err := c.resumeLocked(ctx, cp.SessionID); err != nil {
+			return cp.SessionID, err
+		}
+		resumed = cp.SessionID
+	}
+	return resumed, nil
+}
+
+// resumeLocked restarts a session from its checkpoint (caller must hold lock)
+func (c *Controller) resumeLocked(ctx context.Context, sessionID string) error {
+	if c.checkpoints == nil {
+		return fmt.Errorf("%w %s: checkpoints are not enabled", ErrNoCheckpoint, sessionID)
+	}
+	cp, err := c.checkpoints.GetViewJobCheckpoint(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	if cp == nil {
+		return fmt.Errorf("%w %s", ErrNoCheckpoint, sessionID)
+	}
+	if cp.State == taskflow.ViewJobCompleted {
+		return fmt.Errorf("%w: %s", ErrJobCompleted, sessionID)
+	}
+
+	cp.State = taskflow.ViewJobRunning
+	c.saveCheckpointLocked(*cp)
+	c.startLocked(ctx, *cp)
+	c.logger.Info(cwe.LogMsgJobResumed, sessionID, cp.NextIndex)
+	return nil
+}
+
+// startLocked launches the job loop of a session (caller must hold lock)
+func (c *Controller) startLocked(ctx context.Context, cp taskflow.ViewJobCheckpoint) {
 	jobCtx, cancel := context.WithCancel(ctx)
 	c.cancelFunc = cancel
+	c.jobCtx = jobCtx
 	c.running = true
 	c.started = time.Now()
+	c.sessionID = cp.SessionID
+	c.checkpoint = cp
 
 	// Launch background worker
-	go c.runJob(jobCtx, params)
+	go c.runJob(jobCtx, cp)
+}
 
-	sessionID := fmt.Sprintf("cwe-session-%d", time.Now().UnixNano())
-	c.logger.Info(cwe.LogMsgJobStarted, sessionID)
-	return sessionID, nil
+// saveCheckpointLocked records the progress of the current session, if
+// checkpoints are enabled (caller must hold lock). A failure is logged: the
+// job goes on and at worst refetches pages when resumed.
+func (c *Controller) saveCheckpointLocked(cp taskflow.ViewJobCheckpoint) {
+	c.checkpoint = cp
+	if c.checkpoints == nil {
+		return
+	}
+	if err := c.checkpoints.SaveViewJobCheckpoint(cp); err != nil {
+		c.logger.Warn(cwe.LogMsgFailedSaveCheckpoint, err)
+	}
 }
 
-// Stop cancels the running job
+// Stop cancels the running job. Its checkpoint is kept, so the session can
+// be resumed with Start.
 func (c *Controller) Stop(ctx context.Context, sessionID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -79,18 +207,47 @@ func (c *Controller) Stop(ctx context.Context, sessionID string) error {
 		c.cancelFunc = nil
 	}
 	c.running = false
+	cp := c.checkpoint
+	cp.State = taskflow.ViewJobStopped
+	c.saveCheckpointLocked(cp)
 	c.logger.Info(cwe.LogMsgJobStopped, sessionID)
 	return nil
 }
 
-// Status returns a minimal status map for the job session
+// Status returns a minimal status map for the job session, with its
+// progress when checkpoints are enabled
 func (c *Controller) Status(ctx context.Context, sessionID string) (map[string]interface{}, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return map[string]interface{}{
-		"running": c.running,
-		"started": c.started.String(),
-	}, nil
+	status := map[string]interface{}{
+		"running":    c.running,
+		"started":    c.started.String(),
+		"session_id": c.sessionID,
+	}
+	if c.checkpoints != nil && sessionID != "" {
+		cp, err := c.checkpoints.GetViewJobCheckpoint(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if cp != nil {
+			status["checkpoint"] = cp
+		}
+	}
+	return status, nil
+}
+
+// intParam converts a numeric job param, which is a float64 when it was
+// decoded from JSON
+func intParam(v interface{}) (int, bool) {
+	switch x := v.(type) {
+	case int:
+		return x, true
+	case int64:
+		return int(x), true
+	case float64:
+		return int(x), true
+	}
+	return 0, false
 }
 
 // IsRunning reports whether a job is active
@@ -100,29 +257,25 @@ func (c *Controller) IsRunning() bool {
 	return c.running
 }
 
-// runJob executes the fetch-and-store loop. It expects the remote service to
-// provide `RPCFetchViews` which returns a subprocess.Message whose payload is
-// JSON with a top-level `views` array.
-func (c *Controller) runJob(ctx context.Context, params map[string]interface{}) {
+// runJob executes the fetch-and-store loop from the next index of cp. It
+// expects the remote service to provide `RPCFetchViews` which returns a
+// subprocess.Message whose payload is JSON with a top-level `views` array.
+// A page with fewer views than the page size is the last one.
+func (c *Controller) runJob(ctx context.Context, cp taskflow.ViewJobCheckpoint) {
 	defer func() {
 		c.mu.Lock()
-		c.running = false
-		c.cancelFunc = nil
+		// Only clear the state if a later job has not replaced this one
+		if c.jobCtx == ctx {
+			c.running = false
+			c.cancelFunc = nil
+		}
 		c.mu.Unlock()
 	}()
 
-	startIndex := 0
-	pageSize := 100
-	if v, ok := params["start_index"].(int); ok {
-		startIndex = v
-	}
-	if v, ok := params["results_per_page"].(int); ok {
-		pageSize = v
-	}
-
-	c.logger.Info(cwe.LogMsgJobLoopStarting, startIndex, pageSize)
+	pageSize := cp.PageSize
+	c.logger.Info(cwe.LogMsgJobLoopStarting, cp.NextIndex, pageSize)
 
-	current := startIndex
+	current := cp.NextIndex
 
 	for {
 		select {
@@ -184,14 +337,7 @@ func (c *Controller) runJob(ctx context.Context, params map[string]interface{})
 
 			if len(resp.Views) == 0 {
 				c.logger.Info(cwe.LogMsgNoMoreViews)
-				// stop gracefully
-				c.mu.Lock()
-				if c.cancelFunc != nil {
-					c.cancelFunc()
-					c.cancelFunc = nil
-				}
-				c.running = false
-				c.mu.Unlock()
+				c.finish(ctx, cp)
 				return
 			}
 
@@ -208,7 +354,21 @@ func (c *Controller) runJob(ctx context.Context, params map[string]interface{})
 
 			c.logger.Info(cwe.LogMsgFetchedViews, len(resp.Views), stored)
 
-			current += pageSize
+			// Advance by the views actually returned, so a short page
+			// never skips views when the job is resumed
+			current += len(resp.Views)
+			cp.NextIndex = current
+			cp.Fetched += len(resp.Views)
+			cp.Stored += stored
+			if !c.checkpointPage(ctx, cp) {
+				return
+			}
+			if len(resp.Views) < pageSize {
+				c.logger.Info(cwe.LogMsgShortLastPage, len(resp.Views), pageSize)
+				c.logger.Info(cwe.LogMsgNoMoreViews)
+				c.finish(ctx, cp)
+				return
+			}
 
 			select {
 			case <-ctx.Done():
@@ -218,3 +378,31 @@ func (c *Controller) runJob(ctx context.Context, params map[string]interface{})
 		}
 	}
 }
+
+// checkpointPage records the progress after a stored page. It returns false
+// if the job was stopped meanwhile, so Stop's checkpoint is not overwritten.
+func (c *Controller) checkpointPage(ctx context.Context, cp taskflow.ViewJobCheckpoint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ctx.Err() != nil || c.jobCtx != ctx {
+		return false
+	}
+	c.saveCheckpointLocked(cp)
+	return true
+}
+
+// finish marks the job completed once its last page is stored
+func (c *Controller) finish(ctx context.Context, cp taskflow.ViewJobCheckpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ctx.Err() != nil || c.jobCtx != ctx {
+		return
+	}
+	cp.State = taskflow.ViewJobCompleted
+	c.saveCheckpointLocked(cp)
+	if c.cancelFunc != nil {
+		c.cancelFunc()
+		c.cancelFunc = nil
+	}
+	c.running = false
+}
@@ -5,12 +5,14 @@ import (
 "github.com/cyw0ng95/v2e/pkg/testutils"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/cwe"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/rpc"
 )
 
 // mockCWERPCInvoker simulates RPC calls for CWE job testing.
@@ -35,10 +37,18 @@ func (m *mockCWERPCInvoker) InvokeRPC(ctx context.Context, target, method string
 		return &subprocess.Message{Type: subprocess.MessageTypeResponse, Payload: []byte(`{"views":[]}`)}, nil
 	}
 
-	view := cwe.CWEView{ID: "1000", Name: "Research Concepts"}
+	// Serve full pages, so a job never reaches its last page and stays
+	// running until stopped
+	views := []cwe.CWEView{{ID: "1000", Name: "Research Concepts"}}
+	if p, ok := params.(*rpc.FetchCVEsParams); ok && p.ResultsPerPage > 0 {
+		views = make([]cwe.CWEView, p.ResultsPerPage)
+		for i := range views {
+			views[i] = cwe.CWEView{ID: fmt.Sprintf("%d", 1000+p.StartIndex+i), Name: "Research Concepts"}
+		}
+	}
 	payload := struct {
 		Views []cwe.CWEView `json:"views"`
-	}{Views: []cwe.CWEView{view}}
+	}{Views: views}
 
 	data, _ := subprocess.MarshalFast(payload)
 	return &subprocess.Message{Type: subprocess.MessageTypeResponse, Payload: data}, nil
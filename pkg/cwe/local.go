@@ -3,9 +3,14 @@ package cwe
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/common/capability"
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -32,9 +37,9 @@ type CWEItemModel struct {
 type RelatedWeaknessModel struct {
 	ID      uint   `gorm:"primaryKey"`
 	CWEID   string `gorm:"column:cwe_id;index"` // Foreign key to parent CWE item
-	Nature  string `gorm:"column:nature"`
+	Nature  string `gorm:"column:nature;index:idx_related_weakness_view"`
 	CweID   string `gorm:"column:related_cwe_id"` // ID of the related CWE
-	ViewID  string `gorm:"column:view_id"`
+	ViewID  string `gorm:"column:view_id;index:idx_related_weakness_view"`
 	Ordinal string `gorm:"column:ordinal"`
 }
 
@@ -138,9 +143,18 @@ type ContentHistoryModel struct {
 	Version                  string `gorm:"column:version"`
 }
 
+// CWECatalogMeta stores metadata about the imported CWE catalog
+type CWECatalogMeta struct {
+	ID            uint `gorm:"primaryKey"`
+	Version       string
+	VersionSource string // catalog or file_mtime, see catalog.ResolveVersion
+	Source        string
+	ImportedAtUTC int64
+}
+
 // NewLocalCWEStore creates or opens a local CWE database at dbPath.
 func NewLocalCWEStore(dbPath string) (*LocalCWEStore, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(dbretry.DSN(dbPath)), &gorm.Config{
 		PrepareStmt: false,
 	})
 	if err != nil {
@@ -167,6 +181,7 @@ func NewLocalCWEStore(dbPath string) (*LocalCWEStore, error) {
 		&TaxonomyMappingModel{},
 		&NoteModel{},
 		&ContentHistoryModel{},
+		&CWECatalogMeta{},
 	); err != nil {
 		return nil, err
 	}
@@ -198,173 +213,253 @@ func (s *LocalCWEStore) ImportFromJSON(jsonPath string) error {
 	if err := s.db.First(&first, "id = ?", items[0].ID).Error; err == nil {
 		if err := s.db.First(&last, "id = ?", items[len(items)-1].ID).Error; err == nil {
 			common.Info(LogMsgImportSkipped, items[0].ID, items[len(items)-1].ID)
+			// Databases imported before catalog metadata existed get it now
+			var meta CWECatalogMeta
+			if err := s.db.First(&meta).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return s.saveCatalogMeta(jsonPath)
+			}
 			return nil
 		}
 	}
 	for _, item := range items {
-		m := CWEItemModel{
-			ID:                  item.ID,
-			Name:                item.Name,
-			Abstraction:         item.Abstraction,
-			Structure:           item.Structure,
-			Status:              item.Status,
-			Description:         item.Description,
-			ExtendedDescription: item.ExtendedDescription,
-			LikelihoodOfExploit: item.LikelihoodOfExploit,
+		if err := dbretry.Do(func() error { return s.saveItem(item) }); err != nil {
+			return err
+		}
+	}
+	return s.saveCatalogMeta(jsonPath)
+}
+
+// saveCatalogMeta records the catalog imported from jsonPath. The CWE JSON
+// is a bare array without a version, so the file's modification time stands
+// in for it.
+func (s *LocalCWEStore) saveCatalogMeta(jsonPath string) error {
+	version, source := catalog.ResolveVersion("", jsonPath)
+	meta := CWECatalogMeta{ID: 1, Version: version, VersionSource: source, Source: jsonPath, ImportedAtUTC: time.Now().UTC().Unix()}
+	return s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&meta).Error
+}
+
+// CatalogVersion returns the version, source, import time and entry count
+// of the loaded CWE catalog
+func (s *LocalCWEStore) CatalogVersion(ctx context.Context) (*catalog.Version, error) {
+	v := &catalog.Version{Taxonomy: catalog.TaxonomyCWE}
+	var meta CWECatalogMeta
+	err := s.db.WithContext(ctx).First(&meta).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		v.Version = meta.Version
+		v.VersionSource = meta.VersionSource
+		v.Source = meta.Source
+		v.ImportedAt = meta.ImportedAtUTC
+	}
+	if err := s.db.WithContext(ctx).Model(&CWEItemModel{}).Count(&v.EntryCount).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DataCapabilities reports which CWE features are usable: whether CWEs are
+// stored, whether a full-text index exists and whether related weaknesses
+// link them to each other
+func (s *LocalCWEStore) DataCapabilities(ctx context.Context) (*capability.Capabilities, error) {
+	c := &capability.Capabilities{DataType: "cwe"}
+	var err error
+	if c.HasData, err = capability.HasRows(ctx, s.db, &CWEItemModel{}); err != nil {
+		return nil, err
+	}
+	if c.FTSAvailable, err = capability.HasFTSTable(ctx, s.db, "cwe_fts"); err != nil {
+		return nil, err
+	}
+	if c.MappingsBuilt, err = capability.HasRows(ctx, s.db, &RelatedWeaknessModel{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// saveItem upserts a CWE and replaces its nested records. It is safe to
+// repeat, so a write that hits a lock can be retried as a whole.
+func (s *LocalCWEStore) saveItem(item CWEItem) error {
+	m := CWEItemModel{
+		ID:                  item.ID,
+		Name:                item.Name,
+		Abstraction:         item.Abstraction,
+		Structure:           item.Structure,
+		Status:              item.Status,
+		Description:         item.Description,
+		ExtendedDescription: item.ExtendedDescription,
+		LikelihoodOfExploit: item.LikelihoodOfExploit,
+	}
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&m).Error; err != nil {
+		return err
+	}
+	// RelatedWeaknesses
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&RelatedWeaknessModel{}).Error; err != nil {
+		return err
+	}
+	for _, rw := range item.RelatedWeaknesses {
+		rwm := RelatedWeaknessModel{
+			CWEID:   item.ID,
+			Nature:  rw.Nature,
+			CweID:   rw.CweID,
+			ViewID:  rw.ViewID,
+			Ordinal: rw.Ordinal,
 		}
-		if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&m).Error; err != nil {
+		if err := s.db.Create(&rwm).Error; err != nil {
 			return err
 		}
-		// RelatedWeaknesses
-		s.db.Where("cwe_id = ?", item.ID).Delete(&RelatedWeaknessModel{})
-		for _, rw := range item.RelatedWeaknesses {
-			rwm := RelatedWeaknessModel{
-				CWEID:   item.ID,
-				Nature:  rw.Nature,
-				CweID:   rw.CweID,
-				ViewID:  rw.ViewID,
-				Ordinal: rw.Ordinal,
-			}
-			if err := s.db.Create(&rwm).Error; err != nil {
-				return err
-			}
+	}
+	// WeaknessOrdinalities
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&WeaknessOrdinalityModel{}).Error; err != nil {
+		return err
+	}
+	for _, wo := range item.WeaknessOrdinalities {
+		wom := WeaknessOrdinalityModel{
+			CWEID:       item.ID,
+			Ordinality:  wo.Ordinality,
+			Description: wo.Description,
 		}
-		// WeaknessOrdinalities
-		s.db.Where("cwe_id = ?", item.ID).Delete(&WeaknessOrdinalityModel{})
-		for _, wo := range item.WeaknessOrdinalities {
-			wom := WeaknessOrdinalityModel{
-				CWEID:       item.ID,
-				Ordinality:  wo.Ordinality,
-				Description: wo.Description,
-			}
-			if err := s.db.Create(&wom).Error; err != nil {
-				return err
-			}
+		if err := s.db.Create(&wom).Error; err != nil {
+			return err
 		}
-		// DetectionMethods
-		s.db.Where("cwe_id = ?", item.ID).Delete(&DetectionMethodModel{})
-		for _, dm := range item.DetectionMethods {
-			dmm := DetectionMethodModel{
-				CWEID:              item.ID,
-				DetectionMethodID:  dm.DetectionMethodID,
-				Method:             dm.Method,
-				Description:        dm.Description,
-				Effectiveness:      dm.Effectiveness,
-				EffectivenessNotes: dm.EffectivenessNotes,
-			}
-			if err := s.db.Create(&dmm).Error; err != nil {
-				return err
-			}
+	}
+	// DetectionMethods
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&DetectionMethodModel{}).Error; err != nil {
+		return err
+	}
+	for _, dm := range item.DetectionMethods {
+		dmm := DetectionMethodModel{
+			CWEID:              item.ID,
+			DetectionMethodID:  dm.DetectionMethodID,
+			Method:             dm.Method,
+			Description:        dm.Description,
+			Effectiveness:      dm.Effectiveness,
+			EffectivenessNotes: dm.EffectivenessNotes,
 		}
-		// Mitigations
-		s.db.Where("cwe_id = ?", item.ID).Delete(&MitigationModel{})
-		for _, mt := range item.PotentialMitigations {
-			mtm := MitigationModel{
-				CWEID:              item.ID,
-				MitigationID:       mt.MitigationID,
-				Phase:              "", // flatten []string as needed
-				Strategy:           mt.Strategy,
-				Description:        mt.Description,
-				Effectiveness:      mt.Effectiveness,
-				EffectivenessNotes: mt.EffectivenessNotes,
-			}
-			if err := s.db.Create(&mtm).Error; err != nil {
-				return err
-			}
+		if err := s.db.Create(&dmm).Error; err != nil {
+			return err
 		}
-		// DemonstrativeExamples
-		s.db.Where("cwe_id = ?", item.ID).Delete(&DemonstrativeExampleModel{})
-		for _, de := range item.DemonstrativeExamples {
-			for _, entry := range de.Entries {
-				dem := DemonstrativeExampleModel{
-					CWEID:       item.ID,
-					EntryID:     de.ID,
-					IntroText:   entry.IntroText,
-					BodyText:    entry.BodyText,
-					Nature:      entry.Nature,
-					Language:    entry.Language,
-					ExampleCode: entry.ExampleCode,
-					Reference:   entry.Reference,
-				}
-				if err := s.db.Create(&dem).Error; err != nil {
-					return err
-				}
-			}
+	}
+	// Mitigations
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&MitigationModel{}).Error; err != nil {
+		return err
+	}
+	for _, mt := range item.PotentialMitigations {
+		mtm := MitigationModel{
+			CWEID:              item.ID,
+			MitigationID:       mt.MitigationID,
+			Phase:              "", // flatten []string as needed
+			Strategy:           mt.Strategy,
+			Description:        mt.Description,
+			Effectiveness:      mt.Effectiveness,
+			EffectivenessNotes: mt.EffectivenessNotes,
 		}
-		// ObservedExamples
-		s.db.Where("cwe_id = ?", item.ID).Delete(&ObservedExampleModel{})
-		for _, oe := range item.ObservedExamples {
-			oem := ObservedExampleModel{
+		if err := s.db.Create(&mtm).Error; err != nil {
+			return err
+		}
+	}
+	// DemonstrativeExamples
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&DemonstrativeExampleModel{}).Error; err != nil {
+		return err
+	}
+	for _, de := range item.DemonstrativeExamples {
+		for _, entry := range de.Entries {
+			dem := DemonstrativeExampleModel{
 				CWEID:       item.ID,
-				Reference:   oe.Reference,
-				Description: oe.Description,
-				Link:        oe.Link,
+				EntryID:     de.ID,
+				IntroText:   entry.IntroText,
+				BodyText:    entry.BodyText,
+				Nature:      entry.Nature,
+				Language:    entry.Language,
+				ExampleCode: entry.ExampleCode,
+				Reference:   entry.Reference,
 			}
-			if err := s.db.Create(&oem).Error; err != nil {
+			if err := s.db.Create(&dem).Error; err != nil {
 				return err
 			}
 		}
-		// TaxonomyMappings
-		s.db.Where("cwe_id = ?", item.ID).Delete(&TaxonomyMappingModel{})
-		for _, tm := range item.TaxonomyMappings {
-			tmm := TaxonomyMappingModel{
-				CWEID:        item.ID,
-				TaxonomyName: tm.TaxonomyName,
-				EntryName:    tm.EntryName,
-				EntryID:      tm.EntryID,
-				MappingFit:   tm.MappingFit,
-			}
-			if err := s.db.Create(&tmm).Error; err != nil {
-				return err
-			}
+	}
+	// ObservedExamples
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&ObservedExampleModel{}).Error; err != nil {
+		return err
+	}
+	for _, oe := range item.ObservedExamples {
+		oem := ObservedExampleModel{
+			CWEID:       item.ID,
+			Reference:   oe.Reference,
+			Description: oe.Description,
+			Link:        oe.Link,
 		}
-		// Notes
-		s.db.Where("cwe_id = ?", item.ID).Delete(&NoteModel{})
-		for _, n := range item.Notes {
-			nm := NoteModel{
-				CWEID: item.ID,
-				Type:  n.Type,
-				Note:  n.Note,
-			}
-			if err := s.db.Create(&nm).Error; err != nil {
-				return err
-			}
+		if err := s.db.Create(&oem).Error; err != nil {
+			return err
 		}
-		// ContentHistory
-		s.db.Where("cwe_id = ?", item.ID).Delete(&ContentHistoryModel{})
-		for _, ch := range item.ContentHistory {
-			chm := ContentHistoryModel{
-				CWEID:                    item.ID,
-				Type:                     ch.Type,
-				SubmissionName:           ch.SubmissionName,
-				SubmissionOrganization:   ch.SubmissionOrganization,
-				SubmissionDate:           ch.SubmissionDate,
-				SubmissionVersion:        ch.SubmissionVersion,
-				SubmissionReleaseDate:    ch.SubmissionReleaseDate,
-				SubmissionComment:        ch.SubmissionComment,
-				ModificationName:         ch.ModificationName,
-				ModificationOrganization: ch.ModificationOrganization,
-				ModificationDate:         ch.ModificationDate,
-				ModificationVersion:      ch.ModificationVersion,
-				ModificationReleaseDate:  ch.ModificationReleaseDate,
-				ModificationComment:      ch.ModificationComment,
-				ContributionName:         ch.ContributionName,
-				ContributionOrganization: ch.ContributionOrganization,
-				ContributionDate:         ch.ContributionDate,
-				ContributionVersion:      ch.ContributionVersion,
-				ContributionReleaseDate:  ch.ContributionReleaseDate,
-				ContributionComment:      ch.ContributionComment,
-				ContributionType:         ch.ContributionType,
-				PreviousEntryName:        ch.PreviousEntryName,
-				Date:                     ch.Date,
-				Version:                  ch.Version,
-			}
-			if err := s.db.Create(&chm).Error; err != nil {
-				return err
-			}
+	}
+	// TaxonomyMappings
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&TaxonomyMappingModel{}).Error; err != nil {
+		return err
+	}
+	for _, tm := range item.TaxonomyMappings {
+		tmm := TaxonomyMappingModel{
+			CWEID:        item.ID,
+			TaxonomyName: tm.TaxonomyName,
+			EntryName:    tm.EntryName,
+			EntryID:      tm.EntryID,
+			MappingFit:   tm.MappingFit,
 		}
-		// Add similar logic for other nested fields as needed
+		if err := s.db.Create(&tmm).Error; err != nil {
+			return err
+		}
+	}
+	// Notes
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&NoteModel{}).Error; err != nil {
+		return err
 	}
+	for _, n := range item.Notes {
+		nm := NoteModel{
+			CWEID: item.ID,
+			Type:  n.Type,
+			Note:  n.Note,
+		}
+		if err := s.db.Create(&nm).Error; err != nil {
+			return err
+		}
+	}
+	// ContentHistory
+	if err := s.db.Where("cwe_id = ?", item.ID).Delete(&ContentHistoryModel{}).Error; err != nil {
+		return err
+	}
+	for _, ch := range item.ContentHistory {
+		chm := ContentHistoryModel{
+			CWEID:                    item.ID,
+			Type:                     ch.Type,
+			SubmissionName:           ch.SubmissionName,
+			SubmissionOrganization:   ch.SubmissionOrganization,
+			SubmissionDate:           ch.SubmissionDate,
+			SubmissionVersion:        ch.SubmissionVersion,
+			SubmissionReleaseDate:    ch.SubmissionReleaseDate,
+			SubmissionComment:        ch.SubmissionComment,
+			ModificationName:         ch.ModificationName,
+			ModificationOrganization: ch.ModificationOrganization,
+			ModificationDate:         ch.ModificationDate,
+			ModificationVersion:      ch.ModificationVersion,
+			ModificationReleaseDate:  ch.ModificationReleaseDate,
+			ModificationComment:      ch.ModificationComment,
+			ContributionName:         ch.ContributionName,
+			ContributionOrganization: ch.ContributionOrganization,
+			ContributionDate:         ch.ContributionDate,
+			ContributionVersion:      ch.ContributionVersion,
+			ContributionReleaseDate:  ch.ContributionReleaseDate,
+			ContributionComment:      ch.ContributionComment,
+			ContributionType:         ch.ContributionType,
+			PreviousEntryName:        ch.PreviousEntryName,
+			Date:                     ch.Date,
+			Version:                  ch.Version,
+		}
+		if err := s.db.Create(&chm).Error; err != nil {
+			return err
+		}
+	}
+	// Add similar logic for other nested fields as needed
 	return nil
 }
 
@@ -513,6 +608,15 @@ func (s *LocalCWEStore) GetByID(ctx context.Context, id string) (*CWEItem, error
 	return item, nil
 }
 
+// Count returns the total number of CWE items
+func (s *LocalCWEStore) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&CWEItemModel{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // ListCWEsPaginated returns a paginated list of CWEItems.
 func (s *LocalCWEStore) ListCWEsPaginated(ctx context.Context, offset, limit int) ([]CWEItem, int64, error) {
 	var models []CWEItemModel
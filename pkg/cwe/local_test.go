@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common/catalog"
 )
 
 func TestLocalCWEStore_ImportAndQuery(t *testing.T) {
@@ -103,3 +106,66 @@ func TestLocalCWEStore_ImportAndQuery(t *testing.T) {
 	})
 
 }
+
+func TestLocalCWEStore_CatalogVersion(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestLocalCWEStore_CatalogVersion", nil, func(t *testing.T, tx *gorm.DB) {
+		tmp := t.TempDir()
+		store, err := NewLocalCWEStore(filepath.Join(tmp, "cwe_version.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCWEStore failed: %v", err)
+		}
+		ctx := context.Background()
+
+		jsonPath := filepath.Join(tmp, "cwe.json")
+		if err := os.WriteFile(jsonPath, []byte(`[{"ID": "CWE-1", "Name": "One"}, {"ID": "CWE-2", "Name": "Two"}]`), 0644); err != nil {
+			t.Fatalf("failed to write json file: %v", err)
+		}
+		mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(jsonPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ImportFromJSON(jsonPath); err != nil {
+			t.Fatalf("ImportFromJSON failed: %v", err)
+		}
+
+		// The CWE JSON carries no version, so the file mtime is recorded
+		v, err := store.CatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("CatalogVersion failed: %v", err)
+		}
+		if v.Taxonomy != catalog.TaxonomyCWE || v.Version != "2024-05-01T12:00:00Z" || v.VersionSource != catalog.VersionSourceFileMtime {
+			t.Fatalf("unexpected version: %+v", v)
+		}
+		if v.Source != jsonPath || v.EntryCount != 2 || v.ImportedAt == 0 {
+			t.Fatalf("unexpected source, count or import time: %+v", v)
+		}
+
+		// A skipped import backfills metadata missing from older databases
+		if err := store.db.Where("1 = 1").Delete(&CWECatalogMeta{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ImportFromJSON(jsonPath); err != nil {
+			t.Fatalf("second ImportFromJSON failed: %v", err)
+		}
+		if v, err := store.CatalogVersion(ctx); err != nil || v.Source != jsonPath {
+			t.Fatalf("expected the metadata to be backfilled, got %+v, %v", v, err)
+		}
+	})
+}
+
+func TestLocalCWEStore_SaveItemReportsDeleteErrors(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestLocalCWEStore_SaveItemReportsDeleteErrors", nil, func(t *testing.T, tx *gorm.DB) {
+		store, err := NewLocalCWEStore(filepath.Join(t.TempDir(), "cwe_test.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCWEStore failed: %v", err)
+		}
+		if err := store.db.Migrator().DropTable(&NoteModel{}); err != nil {
+			t.Fatalf("DropTable failed: %v", err)
+		}
+		// Replacing the notes fails, so the save must fail rather than leave
+		// the old notes in place
+		if err := store.saveItem(CWEItem{ID: "CWE-1", Name: "Test CWE"}); err == nil {
+			t.Error("Expected an error when the nested records cannot be replaced")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package cwe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTreeViewID is the view whose ChildOf relationships form the tree
+// when none is given: the Research Concepts view
+const DefaultTreeViewID = "1000"
+
+// relationChildOf is the nature of a related weakness naming the parent of a
+// CWE within a view
+const relationChildOf = "ChildOf"
+
+// CWETreeNode is a CWE in the weakness hierarchy of a view, with the CWEs
+// whose ChildOf relationship names it as their parent. A CWE with several
+// parents appears under each of them.
+type CWETreeNode struct {
+	ID          string         `json:"ID"`
+	Name        string         `json:"Name,omitempty"`
+	Abstraction string         `json:"Abstraction,omitempty"`
+	Children    []*CWETreeNode `json:"Children,omitempty"`
+	// Cycle marks a CWE that is already one of its own ancestors; it is not
+	// expanded again, which breaks the cycle
+	Cycle bool `json:"Cycle,omitempty"`
+}
+
+// CWETree is the weakness hierarchy below a root CWE or view
+type CWETree struct {
+	ViewID    string       `json:"ViewID"`
+	Root      *CWETreeNode `json:"Root"`
+	NodeCount int          `json:"NodeCount"`
+}
+
+// GetCWETree returns the hierarchy below rootID formed by the ChildOf
+// relationships of viewID (DefaultTreeViewID when empty). rootID may be a CWE
+// or the view itself, e.g. "1000", in which case the top-level CWEs of the
+// view, those without a parent in it such as the pillars, are its children.
+// IDs may be given with or without the "CWE-" prefix.
+func (s *LocalCWEStore) GetCWETree(ctx context.Context, rootID, viewID string) (*CWETree, error) {
+	if viewID == "" {
+		viewID = DefaultTreeViewID
+	}
+
+	var links []RelatedWeaknessModel
+	if err := s.db.WithContext(ctx).
+		Where("nature = ? AND view_id IN ?", relationChildOf, idVariants(viewID)).
+		Find(&links).Error; err != nil {
+		return nil, err
+	}
+	var items []CWEItemModel
+	if err := s.db.WithContext(ctx).Select("id", "name", "abstraction").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]CWEItemModel, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	children := make(map[string][]string)
+	hasParent := make(map[string]bool)
+	for _, l := range links {
+		children[l.CweID] = append(children[l.CweID], l.CWEID)
+		hasParent[l.CWEID] = true
+	}
+
+	var root *CWETreeNode
+	for _, id := range idVariants(rootID) {
+		if item, ok := byID[id]; ok {
+			root = &CWETreeNode{ID: item.ID, Name: item.Name, Abstraction: item.Abstraction}
+			break
+		}
+	}
+	if root == nil {
+		// Not a CWE: the root is the view itself, if it has any relationships
+		if !containsID(idVariants(viewID), idVariants(rootID)) || len(links) == 0 {
+			return nil, fmt.Errorf("CWE or view %s not found", rootID)
+		}
+		root = &CWETreeNode{ID: viewID}
+		var view ViewModel
+		if err := s.db.WithContext(ctx).Where("id IN ?", idVariants(viewID)).Limit(1).Find(&view).Error; err == nil {
+			root.Name = view.Name
+		}
+		var top []string
+		for parent := range children {
+			if !hasParent[parent] {
+				top = append(top, parent)
+			}
+		}
+		children[root.ID] = top
+	}
+
+	tree := &CWETree{ViewID: viewID, Root: root, NodeCount: 1}
+	onPath := map[string]bool{root.ID: true}
+	var expand func(node *CWETreeNode)
+	expand = func(node *CWETreeNode) {
+		childIDs := dedupIDs(children[node.ID])
+		sortIDs(childIDs)
+		for _, id := range childIDs {
+			child := &CWETreeNode{ID: id, Name: byID[id].Name, Abstraction: byID[id].Abstraction}
+			node.Children = append(node.Children, child)
+			tree.NodeCount++
+			if onPath[id] {
+				child.Cycle = true
+				continue
+			}
+			onPath[id] = true
+			expand(child)
+			delete(onPath, id)
+		}
+	}
+	expand(root)
+	return tree, nil
+}
+
+// idVariants returns id as given and with the "CWE-" prefix added or removed,
+// as catalogs store CWE IDs in either form
+func idVariants(id string) []string {
+	id = strings.TrimSpace(id)
+	upper := strings.ToUpper(id)
+	if strings.HasPrefix(upper, "CWE-") {
+		return []string{id, id[len("CWE-"):]}
+	}
+	return []string{id, "CWE-" + id}
+}
+
+func containsID(ids, candidates []string) bool {
+	for _, id := range ids {
+		for _, c := range candidates {
+			if id == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dedupIDs removes repeated IDs, e.g. a CWE listed twice as the child of the
+// same parent, keeping the first occurrence
+func dedupIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// sortIDs orders CWE IDs numerically, so CWE-79 comes before CWE-116
+func sortIDs(ids []string) {
+	num := func(id string) int {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(id), "CWE-"))
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		ni, nj := num(ids[i]), num(ids[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return ids[i] < ids[j]
+	})
+}
@@ -0,0 +1,86 @@
+package cwe
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestLocalCWEStore_GetCWETree(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestLocalCWEStore_GetCWETree", nil, func(t *testing.T, tx *gorm.DB) {
+		store, err := NewLocalCWEStore(filepath.Join(t.TempDir(), "cwe_tree.db"))
+		if err != nil {
+			t.Fatalf("NewLocalCWEStore failed: %v", err)
+		}
+
+		childOf := func(parent string) []RelatedWeakness {
+			return []RelatedWeakness{{Nature: "ChildOf", CweID: parent, ViewID: "1000", Ordinal: "Primary"}}
+		}
+		// Pillar 664 has classes 118 and 400; base 119 is a child of 118 and,
+		// in another view only, of 400. 900 and 901 form a cycle below 400.
+		items := []CWEItem{
+			{ID: "664", Name: "Improper Control of a Resource", Abstraction: "Pillar"},
+			{ID: "707", Name: "Improper Neutralization", Abstraction: "Pillar"},
+			{ID: "118", Name: "Incorrect Access of Indexable Resource", Abstraction: "Class", RelatedWeaknesses: childOf("664")},
+			{ID: "400", Name: "Uncontrolled Resource Consumption", Abstraction: "Class", RelatedWeaknesses: childOf("664")},
+			{ID: "119", Name: "Improper Restriction of Operations", Abstraction: "Base", RelatedWeaknesses: append(childOf("118"),
+				RelatedWeakness{Nature: "ChildOf", CweID: "400", ViewID: "699"},
+				RelatedWeakness{Nature: "CanPrecede", CweID: "400", ViewID: "1000"})},
+			{ID: "900", Name: "Cycle A", RelatedWeaknesses: append(childOf("400"), childOf("901")...)},
+			{ID: "901", Name: "Cycle B", RelatedWeaknesses: childOf("900")},
+		}
+		for _, item := range items {
+			if err := store.saveItem(item); err != nil {
+				t.Fatalf("saveItem failed: %v", err)
+			}
+		}
+		ctx := context.Background()
+
+		tree, err := store.GetCWETree(ctx, "CWE-664", "")
+		if err != nil {
+			t.Fatalf("GetCWETree failed: %v", err)
+		}
+		root := tree.Root
+		if tree.ViewID != DefaultTreeViewID || root.ID != "664" || root.Abstraction != "Pillar" || len(root.Children) != 2 {
+			t.Fatalf("Unexpected root %+v", root)
+		}
+		if c := root.Children[0]; c.ID != "118" || len(c.Children) != 1 || c.Children[0].ID != "119" {
+			t.Errorf("Expected 118 with child 119, got %+v", c)
+		}
+		// ChildOf of another view and other natures are not followed
+		class400 := root.Children[1]
+		if class400.ID != "400" || len(class400.Children) != 1 || class400.Children[0].ID != "900" {
+			t.Fatalf("Expected 400 with child 900 only, got %+v", class400)
+		}
+		// 900 -> 901 -> 900 is cut where 900 repeats
+		cycleB := class400.Children[0].Children[0]
+		if cycleB.ID != "901" || len(cycleB.Children) != 1 || !cycleB.Children[0].Cycle || cycleB.Children[0].Children != nil {
+			t.Errorf("Expected the cycle broken below 901, got %+v", cycleB)
+		}
+		if tree.NodeCount != 7 {
+			t.Errorf("Expected 7 nodes, got %d", tree.NodeCount)
+		}
+
+		// The view itself lists the CWEs without a parent in it
+		tree, err = store.GetCWETree(ctx, "CWE-1000", "1000")
+		if err != nil {
+			t.Fatalf("GetCWETree for the view failed: %v", err)
+		}
+		if tree.Root.ID != "1000" || len(tree.Root.Children) != 1 || tree.Root.Children[0].ID != "664" {
+			t.Errorf("Expected view 1000 rooted at pillar 664, got %+v", tree.Root)
+		}
+
+		// A CWE without children in the view is a leaf
+		tree, err = store.GetCWETree(ctx, "707", "")
+		if err != nil || len(tree.Root.Children) != 0 || tree.NodeCount != 1 {
+			t.Errorf("Expected a leaf for 707, got %+v (err %v)", tree, err)
+		}
+
+		if _, err := store.GetCWETree(ctx, "99999", ""); err == nil {
+			t.Error("Expected an error for an unknown root")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cyw0ng95/v2e/pkg/urn"
+)
+
+// NodeInput is a node to add with AddNodes
+type NodeInput struct {
+	URN        *urn.URN
+	Properties map[string]interface{}
+}
+
+// EdgeInput is an edge to add with AddEdges
+type EdgeInput struct {
+	From       *urn.URN
+	To         *urn.URN
+	Type       EdgeType
+	Properties map[string]interface{}
+}
+
+// BatchItemError is the error of one item of a batch, at Index in the input
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult is the outcome of AddNodes or AddEdges: the number of items
+// added, the number skipped as already present, and the errors of the items
+// that failed
+type BatchResult struct {
+	Added   int
+	Skipped int
+	Errors  []BatchItemError
+}
+
+// AddNodes adds nodes under a single lock acquisition, for bulk loads. Unlike
+// AddNode it does not update nodes that already exist: those, and repeats
+// within nodes, are skipped. An item without URN fails without aborting the
+// others.
+func (g *Graph) AddNodes(nodes []NodeInput) BatchResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var result BatchResult
+	for i, n := range nodes {
+		if n.URN == nil {
+			result.Errors = append(result.Errors, BatchItemError{Index: i, Err: errors.New("node has no URN")})
+			continue
+		}
+		key := n.URN.Key()
+		if _, exists := g.nodes[key]; exists {
+			result.Skipped++
+			continue
+		}
+		properties := n.Properties
+		if properties == nil {
+			properties = make(map[string]interface{})
+		}
+		g.nodes[key] = &Node{URN: n.URN, Properties: properties}
+		g.nodeTypeCounts[n.URN.Type]++
+		result.Added++
+	}
+	return result
+}
+
+// AddEdges adds edges under a single lock acquisition, for bulk loads. Each
+// edge is checked as by AddEdge, or as by AddCustomEdge with allowCustom;
+// one that fails is reported without aborting the others. Unlike AddEdge it
+// skips an edge whose endpoints and type match an edge already in the graph,
+// including one added earlier in the batch.
+func (g *Graph) AddEdges(edges []EdgeInput, allowCustom bool) BatchResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var result BatchResult
+	// present holds the endpoints and type of the outgoing edges of each
+	// source node seen so far, built on first use
+	present := make(map[string]map[string]bool)
+	for i, e := range edges {
+		if err := g.checkEdgeInput(e, allowCustom); err != nil {
+			result.Errors = append(result.Errors, BatchItemError{Index: i, Err: err})
+			continue
+		}
+		fromKey, toKey := e.From.Key(), e.To.Key()
+		seen, ok := present[fromKey]
+		if !ok {
+			seen = make(map[string]bool, len(g.edges[fromKey]))
+			for _, edge := range g.edges[fromKey] {
+				seen[edge.To.Key()+"|"+string(edge.Type)] = true
+			}
+			present[fromKey] = seen
+		}
+		if seen[toKey+"|"+string(e.Type)] {
+			result.Skipped++
+			continue
+		}
+		seen[toKey+"|"+string(e.Type)] = true
+
+		properties := e.Properties
+		if properties == nil {
+			properties = make(map[string]interface{})
+		}
+		edge := &Edge{From: e.From, To: e.To, Type: e.Type, Properties: properties}
+		g.edges[fromKey] = append(g.edges[fromKey], edge)
+		g.reverseEdges[toKey] = append(g.reverseEdges[toKey], edge)
+		g.edgeTypeCounts[e.Type]++
+		result.Added++
+	}
+	return result
+}
+
+// checkEdgeInput validates an edge of AddEdges (caller must hold lock)
+func (g *Graph) checkEdgeInput(e EdgeInput, allowCustom bool) error {
+	if e.From == nil || e.To == nil {
+		return errors.New("edge needs both a from and a to URN")
+	}
+	if !allowCustom && !IsKnownEdgeType(e.Type) {
+		return fmt.Errorf("%w %q (known types: %v)", ErrUnknownEdgeType, e.Type, KnownEdgeTypes())
+	}
+	if err := validateEdgeWeight(e.Properties); err != nil {
+		return err
+	}
+	if _, exists := g.nodes[e.From.Key()]; !exists {
+		return fmt.Errorf("from node %s does not exist", e.From.Key())
+	}
+	if _, exists := g.nodes[e.To.Key()]; !exists {
+		return fmt.Errorf("to node %s does not exist", e.To.Key())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/cyw0ng95/v2e/pkg/urn"
+	"gorm.io/gorm"
+)
+
+func TestGraphAddNodesAndEdges(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "AddNodesAndEdges", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0002")
+		xss, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		missing, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-89")
+		g.AddNode(xss, map[string]interface{}{"name": "XSS"})
+
+		result := g.AddNodes([]NodeInput{
+			{URN: cve1},
+			{URN: xss, Properties: map[string]interface{}{"name": "overwritten"}},
+			{},
+			{URN: cve2, Properties: map[string]interface{}{"score": 9.8}},
+			{URN: cve1},
+		})
+		if result.Added != 2 || result.Skipped != 2 || len(result.Errors) != 1 || result.Errors[0].Index != 2 {
+			t.Fatalf("Unexpected AddNodes result %+v", result)
+		}
+		if node, _ := g.GetNode(xss); node.Properties["name"] != "XSS" {
+			t.Errorf("Expected the existing node left unchanged, got %v", node.Properties)
+		}
+		if g.NodeCount() != 3 || g.CountsByNodeType()[urn.TypeCVE] != 2 {
+			t.Errorf("Expected 3 nodes with 2 CVEs, got %d, %v", g.NodeCount(), g.CountsByNodeType())
+		}
+
+		if err := g.AddEdge(cve1, xss, EdgeTypeReferences, nil); err != nil {
+			t.Fatalf("AddEdge failed: %v", err)
+		}
+		result = g.AddEdges([]EdgeInput{
+			{From: cve1, To: xss, Type: EdgeTypeReferences},
+			{From: cve2, To: xss, Type: EdgeTypeReferences},
+			{From: cve2, To: xss, Type: EdgeTypeReferences},
+			{From: cve2, To: xss, Type: "affects"},
+			{From: cve2, To: missing, Type: EdgeTypeReferences},
+			{From: cve2, To: xss, Type: EdgeTypeRelatedTo, Properties: map[string]interface{}{WeightProperty: -1}},
+			{From: cve2, To: cve1, Type: EdgeTypeRelatedTo},
+		}, false)
+		if result.Added != 2 || result.Skipped != 2 || len(result.Errors) != 3 {
+			t.Fatalf("Unexpected AddEdges result %+v", result)
+		}
+		if !errors.Is(result.Errors[0], ErrUnknownEdgeType) || result.Errors[0].Index != 3 {
+			t.Errorf("Expected an unknown edge type at index 3, got %v", result.Errors[0])
+		}
+		if result.Errors[1].Index != 4 || result.Errors[2].Index != 5 {
+			t.Errorf("Expected the missing node and bad weight at 4 and 5, got %v", result.Errors)
+		}
+		if g.EdgeCount() != 3 || len(g.GetIncomingEdges(xss)) != 2 || g.CountsByEdgeType()[EdgeTypeReferences] != 2 {
+			t.Errorf("Expected 3 edges, 2 into CWE-79, got %d, %v", g.EdgeCount(), g.CountsByEdgeType())
+		}
+
+		// Custom types are accepted on request
+		result = g.AddEdges([]EdgeInput{{From: cve2, To: xss, Type: "affects"}}, true)
+		if result.Added != 1 || len(result.Errors) != 0 {
+			t.Errorf("Expected the custom edge added, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cyw0ng95/v2e/pkg/urn"
+)
+
+// WeightProperty is the edge property holding the weight of an edge
+const WeightProperty = "weight"
+
+// ErrInvalidEdgeWeight is returned by AddEdge and AddCustomEdge when the
+// weight property is not a finite, non-negative number
+var ErrInvalidEdgeWeight = errors.New("invalid edge weight")
+
+// ErrUnknownDirection is returned for centrality directions other than
+// DirectionIn, DirectionOut and DirectionBoth
+var ErrUnknownDirection = errors.New("unknown direction")
+
+// Direction selects which edges of a node count towards its centrality
+type Direction string
+
+const (
+	// DirectionIn counts incoming edges (e.g. CVEs referencing a CWE)
+	DirectionIn Direction = "in"
+	// DirectionOut counts outgoing edges
+	DirectionOut Direction = "out"
+	// DirectionBoth counts incoming and outgoing edges
+	DirectionBoth Direction = "both"
+)
+
+// CentralityScore is the centrality of one node
+type CentralityScore struct {
+	URN   string  `json:"urn"`
+	Score float64 `json:"score"`
+}
+
+// Weight returns the weight property of the edge, or 1 if it has none
+func (e *Edge) Weight() float64 {
+	if w, ok := edgeWeight(e.Properties[WeightProperty]); ok {
+		return w
+	}
+	return 1
+}
+
+// edgeWeight converts a weight property value to a float. Values decoded from
+// JSON are float64; other numeric types come from in-process callers.
+func edgeWeight(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+// validateEdgeWeight checks the weight property, if set
+func validateEdgeWeight(properties map[string]interface{}) error {
+	v, ok := properties[WeightProperty]
+	if !ok {
+		return nil
+	}
+	w, ok := edgeWeight(v)
+	if !ok || math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+		return fmt.Errorf("%w %v: must be a non-negative number", ErrInvalidEdgeWeight, v)
+	}
+	return nil
+}
+
+// DegreeCentrality returns the weighted degree of every node, keyed by URN:
+// the sum of the weights of its incoming and outgoing edges, where an edge
+// without a weight counts as 1. Scores are not normalized, so with unweighted
+// edges a score is the number of edges. Nodes without edges score 0.
+func (g *Graph) DegreeCentrality() map[string]float64 {
+	scores, _ := g.DirectedDegreeCentrality(DirectionBoth)
+	return scores
+}
+
+// DirectedDegreeCentrality is DegreeCentrality counting only the edges in the
+// given direction. The in-degree of a CWE is how much the CVEs referencing it
+// weigh.
+func (g *Graph) DirectedDegreeCentrality(direction Direction) (map[string]float64, error) {
+	in, out, err := directionEdges(direction)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scored on a snapshot, so writers are not held up for the whole pass
+	snap := g.Snapshot()
+	scores := make(map[string]float64, len(snap.nodes))
+	for key := range snap.nodes {
+		scores[key] = snap.weightedDegree(key, in, out)
+	}
+	return scores, nil
+}
+
+// TopCentrality returns the nodes with the highest weighted degree in the
+// given direction, highest first and ties in URN order. Only nodes of
+// nodeType are ranked, unless it is empty; at most k are returned, all if
+// k <= 0.
+func (g *Graph) TopCentrality(direction Direction, nodeType urn.ResourceType, k int) ([]CentralityScore, error) {
+	in, out, err := directionEdges(direction)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := g.Snapshot()
+	result := make([]CentralityScore, 0)
+	for key, node := range snap.nodes {
+		if nodeType != "" && node.URN.Type != nodeType {
+			continue
+		}
+		result = append(result, CentralityScore{URN: key, Score: snap.weightedDegree(key, in, out)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].URN < result[j].URN
+	})
+	if k > 0 && len(result) > k {
+		result = result[:k]
+	}
+	return result, nil
+}
+
+// directionEdges returns whether incoming and outgoing edges count in the
+// given direction
+func directionEdges(direction Direction) (in, out bool, err error) {
+	switch direction {
+	case DirectionIn:
+		return true, false, nil
+	case DirectionOut:
+		return false, true, nil
+	case DirectionBoth:
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("%w %q: must be %q, %q or %q", ErrUnknownDirection, direction, DirectionIn, DirectionOut, DirectionBoth)
+}
+
+// weightedDegree sums the weights of a node's edges. Callers must hold g.mu
+// or be the only users of g, as with a snapshot.
+func (g *Graph) weightedDegree(key string, in, out bool) float64 {
+	var score float64
+	if in {
+		for _, edge := range g.reverseEdges[key] {
+			score += edge.Weight()
+		}
+	}
+	if out {
+		for _, edge := range g.edges[key] {
+			score += edge.Weight()
+		}
+	}
+	return score
+}
@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/cyw0ng95/v2e/pkg/urn"
+	"gorm.io/gorm"
+)
+
+func TestDegreeCentrality(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "DegreeCentrality", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0002")
+		cve3, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0003")
+		xss, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		sqli, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-89")
+		lone, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-20")
+		for _, u := range []*urn.URN{cve1, cve2, cve3, xss, sqli, lone} {
+			g.AddNode(u, nil)
+		}
+		g.AddEdge(cve1, xss, EdgeTypeReferences, nil)
+		g.AddEdge(cve2, xss, EdgeTypeReferences, nil)
+		g.AddEdge(cve3, sqli, EdgeTypeReferences, map[string]interface{}{WeightProperty: 2.5})
+		g.AddEdge(cve1, sqli, EdgeTypeReferences, map[string]interface{}{WeightProperty: 0})
+		g.AddEdge(xss, sqli, EdgeTypeRelatedTo, nil)
+
+		scores := g.DegreeCentrality()
+		for u, want := range map[*urn.URN]float64{cve1: 1, cve3: 2.5, xss: 3, sqli: 3.5, lone: 0} {
+			if got, ok := scores[u.Key()]; !ok || got != want {
+				t.Errorf("Expected %s to score %v, got %v", u, want, got)
+			}
+		}
+		if len(scores) != 6 {
+			t.Errorf("Expected every node to be scored, got %v", scores)
+		}
+
+		in, err := g.DirectedDegreeCentrality(DirectionIn)
+		if err != nil || in[xss.Key()] != 2 || in[sqli.Key()] != 3.5 || in[cve1.Key()] != 0 {
+			t.Errorf("Unexpected in-degree %v (%v)", in, err)
+		}
+		out, _ := g.DirectedDegreeCentrality(DirectionOut)
+		if out[cve1.Key()] != 1 || out[xss.Key()] != 1 || out[sqli.Key()] != 0 {
+			t.Errorf("Unexpected out-degree %v", out)
+		}
+
+		// The CWEs referenced the most, highest first
+		top, err := g.TopCentrality(DirectionIn, urn.TypeCWE, 2)
+		if err != nil || len(top) != 2 || top[0] != (CentralityScore{URN: sqli.Key(), Score: 3.5}) || top[1] != (CentralityScore{URN: xss.Key(), Score: 2}) {
+			t.Errorf("Unexpected top CWEs %+v (%v)", top, err)
+		}
+		// Ties are in URN order
+		all, _ := g.TopCentrality(DirectionBoth, "", 0)
+		if len(all) != 6 || all[3].URN != cve1.Key() || all[4].URN != cve2.Key() || all[5].URN != lone.Key() {
+			t.Errorf("Unexpected ranking %+v", all)
+		}
+
+		if _, err := g.TopCentrality("sideways", "", 0); !errors.Is(err, ErrUnknownDirection) {
+			t.Errorf("Expected ErrUnknownDirection, got %v", err)
+		}
+	})
+}
+
+func TestAddEdgeWeight(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "AddEdgeWeight", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		g.AddNode(cve, nil)
+		g.AddNode(cwe, nil)
+
+		for _, weight := range []interface{}{-1, "heavy", math.NaN(), math.Inf(1), nil} {
+			if err := g.AddEdge(cve, cwe, EdgeTypeReferences, map[string]interface{}{WeightProperty: weight}); !errors.Is(err, ErrInvalidEdgeWeight) {
+				t.Errorf("Expected weight %v to be rejected, got %v", weight, err)
+			}
+			if err := g.AddCustomEdge(cve, cwe, "custom", map[string]interface{}{WeightProperty: weight}); !errors.Is(err, ErrInvalidEdgeWeight) {
+				t.Errorf("Expected weight %v to be rejected for custom edges, got %v", weight, err)
+			}
+		}
+		if g.EdgeCount() != 0 {
+			t.Fatalf("Expected no edges, got %d", g.EdgeCount())
+		}
+
+		if err := g.AddEdge(cve, cwe, EdgeTypeReferences, map[string]interface{}{WeightProperty: int64(3)}); err != nil {
+			t.Fatalf("AddEdge failed: %v", err)
+		}
+		g.AddEdge(cve, cwe, EdgeTypeRelatedTo, nil)
+		edges := g.GetOutgoingEdges(cve)
+		if len(edges) != 2 || edges[0].Weight() != 3 || edges[1].Weight() != 1 {
+			t.Errorf("Expected weights 3 and the default 1, got %+v", edges)
+		}
+	})
+}
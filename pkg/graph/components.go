@@ -0,0 +1,57 @@
+package graph
+
+import "sort"
+
+// ConnectedComponents returns the connected components of the graph,
+// treating edges as undirected: each component is the URNs of a group of
+// nodes joined by edges, with no edge to a node outside the group. A node
+// without edges is a component of its own. Components come largest first,
+// ties ordered by their first URN, and the URNs of a component are sorted.
+func (g *Graph) ConnectedComponents() [][]string {
+	g.mu.RLock()
+	parent := make(map[string]string, len(g.nodes))
+	for key := range g.nodes {
+		parent[key] = key
+	}
+	find := func(key string) string {
+		root := key
+		for parent[root] != root {
+			root = parent[root]
+		}
+		for parent[key] != root {
+			parent[key], key = root, parent[key]
+		}
+		return root
+	}
+	for from, edges := range g.edges {
+		for _, edge := range edges {
+			a, b := find(from), find(edge.To.Key())
+			if a == b {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+	g.mu.RUnlock()
+
+	groups := make(map[string][]string)
+	for key := range parent {
+		root := find(key)
+		groups[root] = append(groups[root], key)
+	}
+	components := make([][]string, 0, len(groups))
+	for _, members := range groups {
+		sort.Strings(members)
+		components = append(components, members)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/cyw0ng95/v2e/pkg/urn"
+	"gorm.io/gorm"
+)
+
+func TestConnectedComponents(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ConnectedComponents", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		if got := g.ConnectedComponents(); len(got) != 0 {
+			t.Errorf("Expected no components in an empty graph, got %v", got)
+		}
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0002")
+		cve3, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0003")
+		cve4, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0004")
+		xss, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		sqli, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-89")
+		lone, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-20")
+		for _, u := range []*urn.URN{cve1, cve2, cve3, cve4, xss, sqli, lone} {
+			g.AddNode(u, nil)
+		}
+		// Two CVEs joined only through the CWE they both reference, whatever
+		// the edge direction
+		g.AddEdge(cve1, xss, EdgeTypeReferences, nil)
+		g.AddEdge(cve2, xss, EdgeTypeReferences, nil)
+		g.AddEdge(cve3, sqli, EdgeTypeReferences, nil)
+		g.AddEdge(sqli, cve3, EdgeTypeRelatedTo, nil)
+
+		want := [][]string{
+			{xss.Key(), cve1.Key(), cve2.Key()},
+			{sqli.Key(), cve3.Key()},
+			{lone.Key()},
+			{cve4.Key()},
+		}
+		if got := g.ConnectedComponents(); !reflect.DeepEqual(got, want) {
+			t.Errorf("ConnectedComponents() = %v, want %v", got, want)
+		}
+
+		// An edge between two components merges them
+		g.AddEdge(xss, sqli, EdgeTypeRelatedTo, nil)
+		got := g.ConnectedComponents()
+		if len(got) != 3 || len(got[0]) != 5 {
+			t.Errorf("Expected the CWE edge to merge the two components, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Export formats accepted by Export
+const (
+	ExportFormatGraphML = "graphml"
+	ExportFormatJSON    = "json"
+)
+
+// ErrUnknownExportFormat is returned by Export for formats other than
+// ExportFormatGraphML and ExportFormatJSON
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// Export writes the whole graph to w in the given format. Nodes are written
+// in URN order and edges grouped by source node, so exports of the same graph
+// are identical. The output is written as it is produced rather than built
+// in memory first; it is taken from a Snapshot, so the graph stays writable
+// while a slow writer is drained and the export is consistent.
+func (g *Graph) Export(w io.Writer, format string) error {
+	nodes, edges := g.Snapshot().sorted()
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case ExportFormatGraphML:
+		err = writeGraphML(bw, nodes, edges)
+	case ExportFormatJSON:
+		err = writeJSON(bw, nodes, edges)
+	default:
+		return fmt.Errorf("%w %q: must be %q or %q", ErrUnknownExportFormat, format, ExportFormatGraphML, ExportFormatJSON)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// sorted returns the nodes sorted by URN and their outgoing edges in the
+// same order
+func (g *Graph) sorted() ([]*Node, []*Edge) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	keys := make([]string, 0, len(g.nodes))
+	for key := range g.nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	nodes := make([]*Node, len(keys))
+	var edges []*Edge
+	for i, key := range keys {
+		nodes[i] = g.nodes[key]
+		edges = append(edges, g.edges[key]...)
+	}
+	return nodes, edges
+}
+
+// writeJSON writes {"node_count", "edge_count", "nodes", "edges"} with nodes as
+// {"urn", "properties"} and edges as {"from", "to", "type", "properties"},
+// the shape the graph store persists, one element at a time
+func writeJSON(w *bufio.Writer, nodes []*Node, edges []*Edge) error {
+	type jsonNode struct {
+		URN        string                 `json:"urn"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	type jsonEdge struct {
+		From       string                 `json:"from"`
+		To         string                 `json:"to"`
+		Type       string                 `json:"type"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+
+	writeItem := func(i int, v interface{}) error {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	w.WriteString(`{"node_count":` + strconv.Itoa(len(nodes)) + `,"edge_count":` + strconv.Itoa(len(edges)) + `,"nodes":[`)
+	for i, node := range nodes {
+		if err := writeItem(i, jsonNode{URN: node.URN.String(), Properties: node.Properties}); err != nil {
+			return fmt.Errorf("failed to encode node %s: %w", node.URN, err)
+		}
+	}
+	w.WriteString(`],"edges":[`)
+	for i, edge := range edges {
+		if err := writeItem(i, jsonEdge{From: edge.From.String(), To: edge.To.String(), Type: string(edge.Type), Properties: edge.Properties}); err != nil {
+			return fmt.Errorf("failed to encode edge %s -> %s: %w", edge.From, edge.To, err)
+		}
+	}
+	_, err := w.WriteString("]}")
+	return err
+}
+
+// graphMLKey is a GraphML attribute declaration
+type graphMLKey struct {
+	id, name, typ string
+}
+
+// graphMLKeys declares the attributes of nodes or edges: label first, then
+// one per property name in sorted order. A property is typed boolean, double
+// or string by its values; values of mixed types, and nested values encoded
+// as JSON, are strings. A property named label is exported as
+// property_label so it does not shadow the built-in label.
+func graphMLKeys(prefix string, props []map[string]interface{}) (keys []graphMLKey, byName map[string]graphMLKey) {
+	types := make(map[string]string)
+	for _, p := range props {
+		for name, v := range p {
+			typ := "string"
+			switch v.(type) {
+			case bool:
+				typ = "boolean"
+			case float64, float32, int, int64, int32, uint, uint64, uint32:
+				typ = "double"
+			}
+			if prev, ok := types[name]; ok && prev != typ {
+				typ = "string"
+			}
+			types[name] = typ
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys = []graphMLKey{{id: prefix + "label", name: "label", typ: "string"}}
+	byName = make(map[string]graphMLKey, len(names))
+	for i, name := range names {
+		attr := name
+		if attr == "label" {
+			attr = "property_label"
+		}
+		key := graphMLKey{id: prefix + strconv.Itoa(i), name: attr, typ: types[name]}
+		keys = append(keys, key)
+		byName[name] = key
+	}
+	return keys, byName
+}
+
+// graphMLValue formats a property value as GraphML data
+func graphMLValue(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// writeGraphML writes a directed GraphML document, as read by Gephi and
+// other graph tools. Nodes are identified by URN and labelled with it; edges
+// are labelled with their type.
+func writeGraphML(w *bufio.Writer, nodes []*Node, edges []*Edge) error {
+	nodeProps := make([]map[string]interface{}, len(nodes))
+	for i, node := range nodes {
+		nodeProps[i] = node.Properties
+	}
+	edgeProps := make([]map[string]interface{}, len(edges))
+	for i, edge := range edges {
+		edgeProps[i] = edge.Properties
+	}
+	nodeKeys, nodeKeyOf := graphMLKeys("n_", nodeProps)
+	edgeKeys, edgeKeyOf := graphMLKeys("e_", edgeProps)
+
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	writeData := func(id, value string) {
+		w.WriteString(`<data key="` + id + `">` + esc(value) + "</data>")
+	}
+	writeProps := func(props map[string]interface{}, keyOf map[string]graphMLKey) error {
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := graphMLValue(props[name])
+			if err != nil {
+				return fmt.Errorf("failed to encode property %s: %w", name, err)
+			}
+			writeData(keyOf[name].id, value)
+		}
+		return nil
+	}
+
+	w.WriteString(xml.Header)
+	w.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">` + "\n")
+	for _, key := range nodeKeys {
+		w.WriteString(`<key id="` + key.id + `" for="node" attr.name="` + esc(key.name) + `" attr.type="` + key.typ + `"/>` + "\n")
+	}
+	for _, key := range edgeKeys {
+		w.WriteString(`<key id="` + key.id + `" for="edge" attr.name="` + esc(key.name) + `" attr.type="` + key.typ + `"/>` + "\n")
+	}
+	w.WriteString(`<graph id="v2e" edgedefault="directed">` + "\n")
+	for _, node := range nodes {
+		id := esc(node.URN.String())
+		w.WriteString(`<node id="` + id + `">`)
+		writeData("n_label", node.URN.String())
+		if err := writeProps(node.Properties, nodeKeyOf); err != nil {
+			return fmt.Errorf("node %s: %w", node.URN, err)
+		}
+		w.WriteString("</node>\n")
+	}
+	for i, edge := range edges {
+		w.WriteString(`<edge id="e` + strconv.Itoa(i) + `" source="` + esc(edge.From.String()) + `" target="` + esc(edge.To.String()) + `">`)
+		writeData("e_label", string(edge.Type))
+		if err := writeProps(edge.Properties, edgeKeyOf); err != nil {
+			return fmt.Errorf("edge %s -> %s: %w", edge.From, edge.To, err)
+		}
+		w.WriteString("</edge>\n")
+	}
+	_, err := w.WriteString("</graph>\n</graphml>\n")
+	return err
+}
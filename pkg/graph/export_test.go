@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/cyw0ng95/v2e/pkg/urn"
+	"gorm.io/gorm"
+)
+
+func exportTestGraph(t *testing.T) *Graph {
+	t.Helper()
+	g := New()
+	cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+	cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+	g.AddNode(cve, map[string]interface{}{"severity": "HIGH", "score": 9.8, "kev": true})
+	g.AddNode(cwe, map[string]interface{}{"name": `Improper <Neutralization> & "XSS"`, "label": "xss", "score": "n/a"})
+	if err := g.AddEdge(cve, cwe, EdgeTypeReferences, map[string]interface{}{"source": "nvd", "tags": []string{"primary"}}); err != nil {
+		t.Fatalf("AddEdge failed: %v", err)
+	}
+	return g
+}
+
+func TestExportJSON(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ExportJSON", nil, func(t *testing.T, tx *gorm.DB) {
+		var out strings.Builder
+		if err := exportTestGraph(t).Export(&out, ExportFormatJSON); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		var doc struct {
+			NodeCount int `json:"node_count"`
+			EdgeCount int `json:"edge_count"`
+			Nodes     []struct {
+				URN        string                 `json:"urn"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"nodes"`
+			Edges []struct {
+				From, To, Type string
+				Properties     map[string]interface{} `json:"properties"`
+			} `json:"edges"`
+		}
+		if err := json.Unmarshal([]byte(out.String()), &doc); err != nil {
+			t.Fatalf("Export is not valid JSON: %v\n%s", err, out.String())
+		}
+		if doc.NodeCount != 2 || len(doc.Nodes) != 2 || doc.EdgeCount != 1 || len(doc.Edges) != 1 {
+			t.Fatalf("Unexpected export %+v", doc)
+		}
+		// Nodes are in URN order
+		if doc.Nodes[0].URN != "v2e::mitre::cwe::CWE-79" || doc.Nodes[1].Properties["severity"] != "HIGH" {
+			t.Errorf("Unexpected nodes %+v", doc.Nodes)
+		}
+		if e := doc.Edges[0]; e.From != "v2e::nvd::cve::CVE-2024-1234" || e.Type != string(EdgeTypeReferences) || e.Properties["source"] != "nvd" {
+			t.Errorf("Unexpected edge %+v", e)
+		}
+
+		// An empty graph is still a valid document
+		out.Reset()
+		if err := New().Export(&out, ExportFormatJSON); err != nil || out.String() != `{"node_count":0,"edge_count":0,"nodes":[],"edges":[]}` {
+			t.Errorf("Unexpected empty export %q (%v)", out.String(), err)
+		}
+	})
+}
+
+func TestExportGraphML(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ExportGraphML", nil, func(t *testing.T, tx *gorm.DB) {
+		var out strings.Builder
+		if err := exportTestGraph(t).Export(&out, ExportFormatGraphML); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		type data struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		}
+		var doc struct {
+			XMLName xml.Name `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+			Keys    []struct {
+				ID   string `xml:"id,attr"`
+				For  string `xml:"for,attr"`
+				Name string `xml:"attr.name,attr"`
+				Type string `xml:"attr.type,attr"`
+			} `xml:"key"`
+			Graph struct {
+				EdgeDefault string `xml:"edgedefault,attr"`
+				Nodes       []struct {
+					ID   string `xml:"id,attr"`
+					Data []data `xml:"data"`
+				} `xml:"node"`
+				Edges []struct {
+					Source string `xml:"source,attr"`
+					Target string `xml:"target,attr"`
+					Data   []data `xml:"data"`
+				} `xml:"edge"`
+			} `xml:"graph"`
+		}
+		if err := xml.Unmarshal([]byte(out.String()), &doc); err != nil {
+			t.Fatalf("Export is not valid XML: %v\n%s", err, out.String())
+		}
+		if doc.Graph.EdgeDefault != "directed" || len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+			t.Fatalf("Unexpected graph %+v", doc.Graph)
+		}
+
+		keys := make(map[string]string) // for/name -> type
+		keyNames := make(map[string]string)
+		for _, k := range doc.Keys {
+			keys[k.For+"/"+k.Name] = k.Type
+			keyNames[k.ID] = k.Name
+		}
+		for name, typ := range map[string]string{
+			"node/label": "string", "node/severity": "string", "node/kev": "boolean",
+			"node/score": "string", "node/property_label": "string", "edge/label": "string", "edge/tags": "string",
+		} {
+			if keys[name] != typ {
+				t.Errorf("Expected key %s of type %s, got %q", name, typ, keys[name])
+			}
+		}
+
+		cwe := doc.Graph.Nodes[0]
+		values := make(map[string]string)
+		for _, d := range cwe.Data {
+			values[keyNames[d.Key]] = d.Value
+		}
+		if cwe.ID != "v2e::mitre::cwe::CWE-79" || values["label"] != cwe.ID || values["name"] != `Improper <Neutralization> & "XSS"` || values["property_label"] != "xss" {
+			t.Errorf("Unexpected CWE node %+v", values)
+		}
+		edge := doc.Graph.Edges[0]
+		values = make(map[string]string)
+		for _, d := range edge.Data {
+			values[keyNames[d.Key]] = d.Value
+		}
+		if edge.Source != "v2e::nvd::cve::CVE-2024-1234" || edge.Target != cwe.ID || values["label"] != "references" || values["tags"] != `["primary"]` {
+			t.Errorf("Unexpected edge %+v", values)
+		}
+	})
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "ExportUnknownFormat", nil, func(t *testing.T, tx *gorm.DB) {
+		var out strings.Builder
+		if err := New().Export(&out, "dot"); !errors.Is(err, ErrUnknownExportFormat) || out.Len() != 0 {
+			t.Errorf("Expected ErrUnknownExportFormat and no output, got %v, %q", err, out.String())
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package graph
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"sort"
 	"sync"
 
 	"github.com/cyw0ng95/v2e/pkg/urn"
@@ -21,8 +24,47 @@ const (
 	EdgeTypeExploits EdgeType = "exploits"
 	// EdgeTypeContains indicates a containment relationship
 	EdgeTypeContains EdgeType = "contains"
+	// EdgeTypeChildOf indicates a hierarchy relationship (e.g., CWE child of CWE)
+	EdgeTypeChildOf EdgeType = "child_of"
+	// EdgeTypeMapsTo indicates a cross-framework mapping (e.g., CAPEC maps to ATT&CK)
+	EdgeTypeMapsTo EdgeType = "maps_to"
+	// EdgeTypeFixedBy indicates a remediation relationship (e.g., CVE fixed by a rule)
+	EdgeTypeFixedBy EdgeType = "fixed_by"
+	// EdgeTypeUses indicates a usage relationship (e.g., group uses technique)
+	EdgeTypeUses EdgeType = "uses"
 )
 
+// ErrUnknownEdgeType is returned by AddEdge for edge types outside the taxonomy
+var ErrUnknownEdgeType = errors.New("unknown edge type")
+
+// knownEdgeTypes is the edge type taxonomy accepted by AddEdge
+var knownEdgeTypes = map[EdgeType]bool{
+	EdgeTypeReferences: true,
+	EdgeTypeRelatedTo:  true,
+	EdgeTypeMitigates:  true,
+	EdgeTypeExploits:   true,
+	EdgeTypeContains:   true,
+	EdgeTypeChildOf:    true,
+	EdgeTypeMapsTo:     true,
+	EdgeTypeFixedBy:    true,
+	EdgeTypeUses:       true,
+}
+
+// KnownEdgeTypes returns the edge type taxonomy in sorted order
+func KnownEdgeTypes() []EdgeType {
+	types := make([]EdgeType, 0, len(knownEdgeTypes))
+	for t := range knownEdgeTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// IsKnownEdgeType reports whether t is part of the edge type taxonomy
+func IsKnownEdgeType(t EdgeType) bool {
+	return knownEdgeTypes[t]
+}
+
 // Node represents a graph node identified by a URN
 type Node struct {
 	URN        *urn.URN
@@ -43,14 +85,20 @@ type Graph struct {
 	nodes        map[string]*Node   // key is URN.Key()
 	edges        map[string][]*Edge // key is from URN.Key()
 	reverseEdges map[string][]*Edge // key is to URN.Key(), for reverse lookups
+
+	// Per-type counts, maintained on every add and remove
+	nodeTypeCounts map[urn.ResourceType]int
+	edgeTypeCounts map[EdgeType]int
 }
 
 // New creates a new empty graph
 func New() *Graph {
 	return &Graph{
-		nodes:        make(map[string]*Node),
-		edges:        make(map[string][]*Edge),
-		reverseEdges: make(map[string][]*Edge),
+		nodes:          make(map[string]*Node),
+		edges:          make(map[string][]*Edge),
+		reverseEdges:   make(map[string][]*Edge),
+		nodeTypeCounts: make(map[urn.ResourceType]int),
+		edgeTypeCounts: make(map[EdgeType]int),
 	}
 }
 
@@ -63,6 +111,10 @@ func (g *Graph) AddNode(u *urn.URN, properties map[string]interface{}) {
 	if properties == nil {
 		properties = make(map[string]interface{})
 	}
+	if existing, ok := g.nodes[key]; ok {
+		g.nodeTypeCounts[existing.URN.Type]--
+	}
+	g.nodeTypeCounts[u.Type]++
 	g.nodes[key] = &Node{
 		URN:        u,
 		Properties: properties,
@@ -78,8 +130,72 @@ func (g *Graph) GetNode(u *urn.URN) (*Node, bool) {
 	return node, exists
 }
 
-// AddEdge adds a directed edge from one URN to another
+// UpdateNodeProperties updates the properties of an existing node with patch
+// and returns the updated node. With merge, the keys of patch are merged into
+// the properties: nested maps are merged key by key at every depth, and any
+// other value replaces the stored one. Without merge, patch replaces the
+// properties as AddNode does. It reports false if the node does not exist.
+func (g *Graph) UpdateNodeProperties(u *urn.URN, patch map[string]interface{}, merge bool) (*Node, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := u.Key()
+	existing, ok := g.nodes[key]
+	if !ok {
+		return nil, false
+	}
+
+	var properties map[string]interface{}
+	if merge {
+		properties = mergeProperties(existing.Properties, patch)
+	} else {
+		properties = maps.Clone(patch)
+		if properties == nil {
+			properties = make(map[string]interface{})
+		}
+	}
+	// A new node is stored, as callers of GetNode may hold the old one
+	node := &Node{URN: existing.URN, Properties: properties}
+	g.nodes[key] = node
+	return node, true
+}
+
+// mergeProperties returns a deep merge of patch into base. Neither is
+// modified; maps along merged paths are copied.
+func mergeProperties(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	maps.Copy(merged, base)
+	for k, v := range patch {
+		patchMap, isMap := v.(map[string]interface{})
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		if isMap && baseIsMap {
+			merged[k] = mergeProperties(baseMap, patchMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// AddEdge adds a directed edge from one URN to another. The edge type must be
+// part of the taxonomy (see KnownEdgeTypes); use AddCustomEdge for others.
 func (g *Graph) AddEdge(from, to *urn.URN, edgeType EdgeType, properties map[string]interface{}) error {
+	if !IsKnownEdgeType(edgeType) {
+		return fmt.Errorf("%w %q (known types: %v)", ErrUnknownEdgeType, edgeType, KnownEdgeTypes())
+	}
+	return g.AddCustomEdge(from, to, edgeType, properties)
+}
+
+// AddCustomEdge adds a directed edge without checking the edge type against
+// the taxonomy. It is meant for callers that explicitly opt in to custom
+// types and for restoring previously persisted graphs. Like AddEdge it
+// rejects a weight property (see WeightProperty) that is not a non-negative
+// number.
+func (g *Graph) AddCustomEdge(from, to *urn.URN, edgeType EdgeType, properties map[string]interface{}) error {
+	if err := validateEdgeWeight(properties); err != nil {
+		return err
+	}
+
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -107,10 +223,200 @@ func (g *Graph) AddEdge(from, to *urn.URN, edgeType EdgeType, properties map[str
 
 	g.edges[fromKey] = append(g.edges[fromKey], edge)
 	g.reverseEdges[toKey] = append(g.reverseEdges[toKey], edge)
+	g.edgeTypeCounts[edgeType]++
 
 	return nil
 }
 
+// RemoveNode removes a node and every edge to or from it. It reports whether
+// the node existed.
+func (g *Graph) RemoveNode(u *urn.URN) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := u.Key()
+	node, exists := g.nodes[key]
+	if !exists {
+		return false
+	}
+
+	for _, edge := range g.edges[key] {
+		unlinkEdge(g.reverseEdges, edge.To.Key(), edge)
+		g.edgeTypeCounts[edge.Type]--
+	}
+	delete(g.edges, key)
+
+	// Self-loops were already removed with the outgoing edges
+	for _, edge := range g.reverseEdges[key] {
+		unlinkEdge(g.edges, edge.From.Key(), edge)
+		g.edgeTypeCounts[edge.Type]--
+	}
+	delete(g.reverseEdges, key)
+
+	delete(g.nodes, key)
+	g.nodeTypeCounts[node.URN.Type]--
+	g.pruneCounts()
+	return true
+}
+
+// RemoveEdge removes the edges of the given type from one URN to another and
+// returns how many were removed
+func (g *Graph) RemoveEdge(from, to *urn.URN, edgeType EdgeType) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fromKey, toKey := from.Key(), to.Key()
+	removed := 0
+	for _, edge := range g.edges[fromKey] {
+		if edge.To.Key() != toKey || edge.Type != edgeType {
+			continue
+		}
+		unlinkEdge(g.edges, fromKey, edge)
+		unlinkEdge(g.reverseEdges, toKey, edge)
+		g.edgeTypeCounts[edgeType]--
+		removed++
+	}
+	g.pruneCounts()
+	return removed
+}
+
+// MergeResult describes the outcome of MergeNodes
+type MergeResult struct {
+	EdgesMoved        int // Edges of the removed node now attached to the kept one
+	SelfEdgesDropped  int // Edges between the two nodes, which would have become self-edges
+	DuplicatesDropped int // Edges of the kept node left identical to another by the merge
+}
+
+// MergeNodes folds the node remove into the node keep, for duplicates such as
+// CWE-79 and CWE-079 created by inconsistent ID formatting. The edges of
+// remove are moved to keep, except edges between the two nodes, which are
+// dropped rather than turned into self-edges. The properties are unioned,
+// those of keep winning (nested maps are merged as by UpdateNodeProperties).
+// After the move, edges of keep with the same endpoints and type are
+// collapsed into one, whose properties are unioned likewise with the earliest
+// edge winning. Finally remove is deleted. Both nodes must exist and differ.
+func (g *Graph) MergeNodes(keep, remove *urn.URN) (MergeResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var result MergeResult
+	keepKey, removeKey := keep.Key(), remove.Key()
+	if keepKey == removeKey {
+		return result, fmt.Errorf("cannot merge node %s into itself", keepKey)
+	}
+	kept, exists := g.nodes[keepKey]
+	if !exists {
+		return result, fmt.Errorf("node %s does not exist", keepKey)
+	}
+	removed, exists := g.nodes[removeKey]
+	if !exists {
+		return result, fmt.Errorf("node %s does not exist", removeKey)
+	}
+
+	// Self-loops of remove are among its outgoing edges, so only incoming
+	// edges from elsewhere are moved from the reverse index
+	var moved []*Edge
+	for _, edge := range g.edges[removeKey] {
+		if edge.To.Key() != removeKey {
+			unlinkEdge(g.reverseEdges, edge.To.Key(), edge)
+		}
+		moved = append(moved, edge)
+	}
+	for _, edge := range g.reverseEdges[removeKey] {
+		if edge.From.Key() != removeKey {
+			unlinkEdge(g.edges, edge.From.Key(), edge)
+			moved = append(moved, edge)
+		}
+	}
+	delete(g.edges, removeKey)
+	delete(g.reverseEdges, removeKey)
+
+	for _, edge := range moved {
+		from, to := edge.From, edge.To
+		if from.Key() == removeKey {
+			from = keep
+		}
+		if to.Key() == removeKey {
+			to = keep
+		}
+		if from.Key() == keepKey && to.Key() == keepKey {
+			g.edgeTypeCounts[edge.Type]--
+			result.SelfEdgesDropped++
+			continue
+		}
+		// A new edge is stored, as callers may hold the old one
+		e := &Edge{From: from, To: to, Type: edge.Type, Properties: edge.Properties}
+		g.edges[from.Key()] = append(g.edges[from.Key()], e)
+		g.reverseEdges[to.Key()] = append(g.reverseEdges[to.Key()], e)
+		result.EdgesMoved++
+	}
+
+	// The incoming edges are read once the outgoing ones are deduplicated,
+	// so a self-loop dropped from both lists is counted once
+	result.DuplicatesDropped = g.dedupEdges(g.edges[keepKey])
+	result.DuplicatesDropped += g.dedupEdges(g.reverseEdges[keepKey])
+
+	g.nodes[keepKey] = &Node{URN: kept.URN, Properties: mergeProperties(removed.Properties, kept.Properties)}
+	delete(g.nodes, removeKey)
+	g.nodeTypeCounts[removed.URN.Type]--
+	g.pruneCounts()
+	return result, nil
+}
+
+// dedupEdges collapses the edges among edges that share their endpoints and
+// type into the earliest one, merging the properties of the others into it,
+// and returns how many edges were removed
+func (g *Graph) dedupEdges(edges []*Edge) int {
+	first := make(map[string]*Edge)
+	removed := 0
+	for _, edge := range edges {
+		key := edge.From.Key() + "|" + edge.To.Key() + "|" + string(edge.Type)
+		existing, ok := first[key]
+		if !ok {
+			first[key] = edge
+			continue
+		}
+		existing.Properties = mergeProperties(edge.Properties, existing.Properties)
+		unlinkEdge(g.edges, edge.From.Key(), edge)
+		unlinkEdge(g.reverseEdges, edge.To.Key(), edge)
+		g.edgeTypeCounts[edge.Type]--
+		removed++
+	}
+	return removed
+}
+
+// unlinkEdge removes edge from the edge list under key in index, dropping
+// the key once its list is empty. A new slice is built, as callers may be
+// iterating over the old one.
+func unlinkEdge(index map[string][]*Edge, key string, edge *Edge) {
+	edges := index[key]
+	result := make([]*Edge, 0, len(edges))
+	for _, e := range edges {
+		if e != edge {
+			result = append(result, e)
+		}
+	}
+	if len(result) == 0 {
+		delete(index, key)
+		return
+	}
+	index[key] = result
+}
+
+// pruneCounts drops types whose count fell to zero
+func (g *Graph) pruneCounts() {
+	for t, n := range g.nodeTypeCounts {
+		if n <= 0 {
+			delete(g.nodeTypeCounts, t)
+		}
+	}
+	for t, n := range g.edgeTypeCounts {
+		if n <= 0 {
+			delete(g.edgeTypeCounts, t)
+		}
+	}
+}
+
 // GetOutgoingEdges returns all edges originating from a URN
 func (g *Graph) GetOutgoingEdges(u *urn.URN) []*Edge {
 	g.mu.RLock()
@@ -161,6 +467,96 @@ func (g *Graph) GetNeighbors(u *urn.URN) []*urn.URN {
 	return neighbors
 }
 
+// Subgraph returns a new graph of the nodes within depth hops of center,
+// following edges in either direction as GetNeighbors does, and every edge
+// among them. A depth of zero or less yields only the center node. It
+// reports false when center is not in the graph. Node and edge properties
+// are copied, so the subgraph can be changed independently.
+func (g *Graph) Subgraph(center *urn.URN, depth int) (*Graph, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	centerKey := center.Key()
+	if _, exists := g.nodes[centerKey]; !exists {
+		return nil, false
+	}
+
+	// Breadth-first, one hop per round
+	included := map[string]bool{centerKey: true}
+	frontier := []string{centerKey}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, key := range frontier {
+			for _, edge := range g.edges[key] {
+				if toKey := edge.To.Key(); !included[toKey] {
+					included[toKey] = true
+					next = append(next, toKey)
+				}
+			}
+			for _, edge := range g.reverseEdges[key] {
+				if fromKey := edge.From.Key(); !included[fromKey] {
+					included[fromKey] = true
+					next = append(next, fromKey)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	sub := New()
+	for key := range included {
+		node := g.nodes[key]
+		sub.nodes[key] = &Node{URN: node.URN, Properties: maps.Clone(node.Properties)}
+		sub.nodeTypeCounts[node.URN.Type]++
+	}
+	for key := range included {
+		for _, edge := range g.edges[key] {
+			toKey := edge.To.Key()
+			if !included[toKey] {
+				continue
+			}
+			e := &Edge{From: edge.From, To: edge.To, Type: edge.Type, Properties: maps.Clone(edge.Properties)}
+			sub.edges[key] = append(sub.edges[key], e)
+			sub.reverseEdges[toKey] = append(sub.reverseEdges[toKey], e)
+			sub.edgeTypeCounts[edge.Type]++
+		}
+	}
+	return sub, true
+}
+
+// Snapshot returns a copy of the whole graph as of one point in time, for
+// reads that must see a consistent graph, such as exports and centrality,
+// while writers carry on. The read lock is held only while copying. Nodes
+// and edges are copied with their top-level properties; nested property
+// maps are shared, as the graph never modifies them in place. The snapshot
+// is a separate graph: later changes to g do not show in it.
+func (g *Graph) Snapshot() *Graph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	snap := &Graph{
+		nodes:          make(map[string]*Node, len(g.nodes)),
+		edges:          make(map[string][]*Edge, len(g.edges)),
+		reverseEdges:   make(map[string][]*Edge, len(g.reverseEdges)),
+		nodeTypeCounts: maps.Clone(g.nodeTypeCounts),
+		edgeTypeCounts: maps.Clone(g.edgeTypeCounts),
+	}
+	for key, node := range g.nodes {
+		snap.nodes[key] = &Node{URN: node.URN, Properties: maps.Clone(node.Properties)}
+	}
+	for key, edges := range g.edges {
+		copied := make([]*Edge, len(edges))
+		for i, edge := range edges {
+			e := &Edge{From: edge.From, To: edge.To, Type: edge.Type, Properties: maps.Clone(edge.Properties)}
+			copied[i] = e
+			toKey := edge.To.Key()
+			snap.reverseEdges[toKey] = append(snap.reverseEdges[toKey], e)
+		}
+		snap.edges[key] = copied
+	}
+	return snap
+}
+
 // NodeCount returns the total number of nodes in the graph
 func (g *Graph) NodeCount() int {
 	g.mu.RLock()
@@ -188,6 +584,35 @@ func (g *Graph) Clear() {
 	g.nodes = make(map[string]*Node)
 	g.edges = make(map[string][]*Edge)
 	g.reverseEdges = make(map[string][]*Edge)
+	g.nodeTypeCounts = make(map[urn.ResourceType]int)
+	g.edgeTypeCounts = make(map[EdgeType]int)
+}
+
+// CountsByNodeType returns the number of nodes of each resource type. The
+// counts are maintained as nodes are added and removed, so this does not
+// scan the graph.
+func (g *Graph) CountsByNodeType() map[urn.ResourceType]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	result := make(map[urn.ResourceType]int, len(g.nodeTypeCounts))
+	for t, n := range g.nodeTypeCounts {
+		result[t] = n
+	}
+	return result
+}
+
+// CountsByEdgeType returns the number of edges of each type, including types
+// outside the taxonomy. Like CountsByNodeType it does not scan the graph.
+func (g *Graph) CountsByEdgeType() map[EdgeType]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	result := make(map[EdgeType]int, len(g.edgeTypeCounts))
+	for t, n := range g.edgeTypeCounts {
+		result[t] = n
+	}
+	return result
 }
 
 // GetNodesByType returns all nodes of a specific resource type
@@ -220,6 +645,24 @@ func (g *Graph) GetNodesByProvider(provider urn.Provider) []*Node {
 
 // FindPath finds a path between two URNs using breadth-first search
 func (g *Graph) FindPath(from, to *urn.URN) ([]*urn.URN, bool) {
+	return g.findPath(from, to, nil)
+}
+
+// FindPathFiltered finds a shortest path between two URNs that only follows
+// edges whose type is in allowed. With no allowed types only a node's path
+// to itself is found.
+func (g *Graph) FindPathFiltered(from, to *urn.URN, allowed []EdgeType) ([]*urn.URN, bool) {
+	allowedSet := make(map[EdgeType]bool, len(allowed))
+	for _, t := range allowed {
+		allowedSet[t] = true
+	}
+	return g.findPath(from, to, allowedSet)
+}
+
+// findPath runs a breadth-first search along outgoing edges, following only
+// edges whose type is in allowed unless allowed is nil. Each node is visited
+// once, so cycles end the search rather than loop.
+func (g *Graph) findPath(from, to *urn.URN, allowed map[EdgeType]bool) ([]*urn.URN, bool) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -252,6 +695,9 @@ func (g *Graph) FindPath(from, to *urn.URN) ([]*urn.URN, bool) {
 
 		// Explore neighbors (only outgoing edges for directed path)
 		for _, edge := range g.edges[currentKey] {
+			if allowed != nil && !allowed[edge.Type] {
+				continue
+			}
 			neighborKey := edge.To.Key()
 			if !visited[neighborKey] {
 				visited[neighborKey] = true
@@ -289,3 +735,20 @@ func (g *Graph) GetAllEdges() []*Edge {
 	}
 	return result
 }
+
+// UnknownEdgeTypes returns the number of edges of each type outside the
+// taxonomy, e.g. edges added with AddCustomEdge or loaded from older graphs
+func (g *Graph) UnknownEdgeTypes() map[EdgeType]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	result := make(map[EdgeType]int)
+	for _, edges := range g.edges {
+		for _, edge := range edges {
+			if !IsKnownEdgeType(edge.Type) {
+				result[edge.Type]++
+			}
+		}
+	}
+	return result
+}
@@ -1,6 +1,10 @@
 package graph
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/cyw0ng95/v2e/pkg/testutils"
@@ -62,6 +66,117 @@ func TestGraphBasicOperations(t *testing.T) {
 	})
 }
 
+func TestGraphUpdateNodeProperties(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "UpdateNodeProperties", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		missing, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-5678")
+
+		cvss := map[string]interface{}{"score": 7.5, "vector": "AV:N"}
+		g.AddNode(cve1, map[string]interface{}{"severity": "HIGH", "cvss": cvss})
+		before, _ := g.GetNode(cve1)
+
+		node, ok := g.UpdateNodeProperties(cve1, map[string]interface{}{
+			"kev":  true,
+			"cvss": map[string]interface{}{"score": 9.8, "epss": map[string]interface{}{"score": 0.97}},
+		}, true)
+		if !ok {
+			t.Fatal("Expected the node to be updated")
+		}
+		merged := node.Properties["cvss"].(map[string]interface{})
+		if node.Properties["severity"] != "HIGH" || node.Properties["kev"] != true {
+			t.Errorf("Expected the patch to be merged into the properties, got %v", node.Properties)
+		}
+		if merged["score"] != 9.8 || merged["vector"] != "AV:N" || merged["epss"] == nil {
+			t.Errorf("Expected nested maps to be deep merged, got %v", merged)
+		}
+		if cvss["score"] != 7.5 || len(before.Properties) != 2 {
+			t.Errorf("Expected the previous properties to be left untouched, got %v and %v", cvss, before.Properties)
+		}
+		if got, _ := g.GetNode(cve1); got.Properties["kev"] != true {
+			t.Errorf("Expected the graph to hold the updated node, got %v", got.Properties)
+		}
+
+		node, _ = g.UpdateNodeProperties(cve1, map[string]interface{}{"severity": "LOW"}, false)
+		if len(node.Properties) != 1 || node.Properties["severity"] != "LOW" {
+			t.Errorf("Expected the properties to be replaced, got %v", node.Properties)
+		}
+
+		if _, ok := g.UpdateNodeProperties(missing, map[string]interface{}{"kev": true}, true); ok || g.NodeCount() != 1 {
+			t.Error("Expected updating a missing node to fail without adding it")
+		}
+	})
+}
+
+func TestGraphMergeNodes(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "MergeNodes", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		keep, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		dup, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-079")
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-5678")
+		capec, _ := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-63")
+
+		g.AddNode(keep, map[string]interface{}{"name": "XSS", "meta": map[string]interface{}{"source": "cwe"}})
+		g.AddNode(dup, map[string]interface{}{"name": "Cross-site Scripting", "abstraction": "Base", "meta": map[string]interface{}{"version": "4.14"}})
+		g.AddNode(cve1, nil)
+		g.AddNode(cve2, nil)
+		g.AddNode(capec, nil)
+
+		g.AddEdge(cve1, keep, EdgeTypeReferences, map[string]interface{}{"source": "nvd"})
+		g.AddEdge(cve1, dup, EdgeTypeReferences, map[string]interface{}{"source": "import", "weight": 2})
+		g.AddEdge(cve2, dup, EdgeTypeReferences, nil)
+		g.AddEdge(dup, capec, EdgeTypeRelatedTo, nil)
+		g.AddEdge(dup, keep, EdgeTypeRelatedTo, nil)
+		g.AddEdge(keep, dup, EdgeTypeChildOf, nil)
+
+		result, err := g.MergeNodes(keep, dup)
+		if err != nil {
+			t.Fatalf("MergeNodes failed: %v", err)
+		}
+		if result.EdgesMoved != 3 || result.SelfEdgesDropped != 2 || result.DuplicatesDropped != 1 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+
+		if _, exists := g.GetNode(dup); exists || g.NodeCount() != 4 || g.CountsByNodeType()[urn.TypeCWE] != 1 {
+			t.Error("Expected the duplicate node to be removed")
+		}
+		node, _ := g.GetNode(keep)
+		meta := node.Properties["meta"].(map[string]interface{})
+		if node.Properties["name"] != "XSS" || node.Properties["abstraction"] != "Base" || meta["source"] != "cwe" || meta["version"] != "4.14" {
+			t.Errorf("Expected the properties to be unioned with the kept ones winning, got %v", node.Properties)
+		}
+
+		// cve1's two edges collapse into one; no edge is left on the duplicate
+		// and none loops back on the kept node
+		if g.EdgeCount() != 3 || g.CountsByEdgeType()[EdgeTypeReferences] != 2 || g.CountsByEdgeType()[EdgeTypeChildOf] != 0 {
+			t.Errorf("Expected 3 edges left, got %d (%v)", g.EdgeCount(), g.CountsByEdgeType())
+		}
+		out := g.GetOutgoingEdges(cve1)
+		if len(out) != 1 || !out[0].To.Equal(keep) || out[0].Properties["source"] != "nvd" || out[0].Properties["weight"] != 2 {
+			t.Errorf("Expected one merged edge from CVE-2024-1234, got %+v", out)
+		}
+		if len(g.GetIncomingEdges(keep)) != 2 || len(g.GetIncomingEdges(capec)) != 1 || !g.GetIncomingEdges(capec)[0].From.Equal(keep) {
+			t.Error("Expected the edges of the duplicate to be moved to the kept node")
+		}
+		for _, edge := range g.GetAllEdges() {
+			if edge.From.Equal(dup) || edge.To.Equal(dup) || (edge.From.Equal(keep) && edge.To.Equal(keep)) {
+				t.Errorf("Unexpected edge %s -> %s", edge.From, edge.To)
+			}
+		}
+		if path, found := g.FindPath(cve2, capec); !found || len(path) != 3 {
+			t.Errorf("Expected a path through the kept node, got %v", path)
+		}
+
+		if _, err := g.MergeNodes(keep, keep); err == nil {
+			t.Error("Expected merging a node into itself to fail")
+		}
+		if _, err := g.MergeNodes(keep, dup); err == nil {
+			t.Error("Expected merging a missing node to fail")
+		}
+	})
+}
+
 func TestGraphEdgeRequiresNodes(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "EdgeRequiresNodes", nil, func(t *testing.T, tx *gorm.DB) {
 		g := New()
@@ -164,6 +279,53 @@ func TestGraphFindPath(t *testing.T) {
 	})
 }
 
+func TestGraphFindPathFiltered(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "FindPathFiltered", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe1, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		cwe2, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-80")
+		capec1, _ := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-66")
+
+		for _, u := range []*urn.URN{cve1, cwe1, cwe2, capec1} {
+			g.AddNode(u, nil)
+		}
+
+		// A spurious shortcut, and a longer CVE→CWE→CWE→CAPEC path with cycles
+		g.AddEdge(cve1, capec1, EdgeTypeRelatedTo, nil)
+		g.AddEdge(cve1, cwe1, EdgeTypeReferences, nil)
+		g.AddEdge(cwe1, cwe2, EdgeTypeChildOf, nil)
+		g.AddEdge(cwe2, cwe1, EdgeTypeChildOf, nil)
+		g.AddEdge(cwe1, cwe1, EdgeTypeChildOf, nil)
+		g.AddEdge(cwe2, capec1, EdgeTypeMapsTo, nil)
+
+		if path, found := g.FindPath(cve1, capec1); !found || len(path) != 2 {
+			t.Errorf("Expected the unfiltered path to take the shortcut, got %v", path)
+		}
+
+		path, found := g.FindPathFiltered(cve1, capec1, []EdgeType{EdgeTypeReferences, EdgeTypeChildOf, EdgeTypeMapsTo})
+		if !found || len(path) != 4 || !path[1].Equal(cwe1) || !path[2].Equal(cwe2) || !path[3].Equal(capec1) {
+			t.Errorf("Expected the path through CWE-79 and CWE-80, got %v", path)
+		}
+
+		// Cycles of allowed edges end the search when the target is unreachable
+		if path, found := g.FindPathFiltered(cve1, capec1, []EdgeType{EdgeTypeReferences, EdgeTypeChildOf}); found {
+			t.Errorf("Expected no path without maps_to edges, got %v", path)
+		}
+
+		// No allowed edge type, or only types absent from the graph
+		for _, allowed := range [][]EdgeType{nil, {EdgeTypeExploits}} {
+			if path, found := g.FindPathFiltered(cve1, cwe1, allowed); found || path != nil {
+				t.Errorf("Expected no path with allowed types %v, got %v", allowed, path)
+			}
+		}
+		if path, found := g.FindPathFiltered(cve1, cve1, nil); !found || len(path) != 1 {
+			t.Errorf("Expected a node to reach itself, got %v", path)
+		}
+	})
+}
+
 func TestGraphClear(t *testing.T) {
 	testutils.Run(t, testutils.Level1, "Clear", nil, func(t *testing.T, tx *gorm.DB) {
 		g := New()
@@ -213,3 +375,286 @@ func TestGraphConcurrentAccess(t *testing.T) {
 		}
 	})
 }
+
+func TestGraphEdgeTypeTaxonomy(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "EdgeTypeTaxonomy", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cwe1, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		g.AddNode(cve1, nil)
+		g.AddNode(cwe1, nil)
+
+		err := g.AddEdge(cve1, cwe1, EdgeType("referances"), nil)
+		if !errors.Is(err, ErrUnknownEdgeType) {
+			t.Fatalf("Expected ErrUnknownEdgeType, got %v", err)
+		}
+		if g.EdgeCount() != 0 {
+			t.Errorf("Rejected edge was added")
+		}
+
+		for _, edgeType := range KnownEdgeTypes() {
+			if err := g.AddEdge(cve1, cwe1, edgeType, nil); err != nil {
+				t.Errorf("AddEdge(%s) failed: %v", edgeType, err)
+			}
+		}
+		if len(g.UnknownEdgeTypes()) != 0 {
+			t.Errorf("Known edge types reported as unknown: %v", g.UnknownEdgeTypes())
+		}
+
+		if err := g.AddCustomEdge(cve1, cwe1, EdgeType("referances"), nil); err != nil {
+			t.Fatalf("AddCustomEdge failed: %v", err)
+		}
+		unknown := g.UnknownEdgeTypes()
+		if len(unknown) != 1 || unknown["referances"] != 1 {
+			t.Errorf("UnknownEdgeTypes = %v, want map[referances:1]", unknown)
+		}
+	})
+}
+
+func TestGraphCountsByType(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "CountsByType", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0001")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-0002")
+		cwe, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		capec, _ := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-63")
+
+		g.AddNode(cve1, nil)
+		g.AddNode(cve2, nil)
+		g.AddNode(cwe, nil)
+		g.AddNode(capec, nil)
+		// Updating a node does not count it twice
+		g.AddNode(cve1, map[string]interface{}{"severity": "HIGH"})
+
+		g.AddEdge(cve1, cwe, EdgeTypeReferences, nil)
+		g.AddEdge(cve2, cwe, EdgeTypeReferences, nil)
+		g.AddEdge(capec, cwe, EdgeTypeRelatedTo, nil)
+		g.AddEdge(cwe, capec, EdgeTypeRelatedTo, nil)
+		g.AddCustomEdge(cve1, cve1, "self", nil)
+
+		expectCounts := func(step string, nodes map[urn.ResourceType]int, edges map[EdgeType]int) {
+			t.Helper()
+			gotNodes, gotEdges := g.CountsByNodeType(), g.CountsByEdgeType()
+			if len(gotNodes) != len(nodes) || len(gotEdges) != len(edges) {
+				t.Fatalf("%s: got nodes %v edges %v, want %v %v", step, gotNodes, gotEdges, nodes, edges)
+			}
+			for k, v := range nodes {
+				if gotNodes[k] != v {
+					t.Errorf("%s: %s nodes = %d, want %d", step, k, gotNodes[k], v)
+				}
+			}
+			for k, v := range edges {
+				if gotEdges[k] != v {
+					t.Errorf("%s: %s edges = %d, want %d", step, k, gotEdges[k], v)
+				}
+			}
+
+			// The incremental counts must agree with a full scan
+			total := 0
+			for _, v := range gotEdges {
+				total += v
+			}
+			if total != g.EdgeCount() || len(g.GetNodesByType(urn.TypeCVE)) != gotNodes[urn.TypeCVE] {
+				t.Errorf("%s: counts %v %v disagree with the graph", step, gotNodes, gotEdges)
+			}
+		}
+
+		expectCounts("built",
+			map[urn.ResourceType]int{urn.TypeCVE: 2, urn.TypeCWE: 1, urn.TypeCAPEC: 1},
+			map[EdgeType]int{EdgeTypeReferences: 2, EdgeTypeRelatedTo: 2, "self": 1})
+
+		if n := g.RemoveEdge(capec, cwe, EdgeTypeRelatedTo); n != 1 {
+			t.Errorf("Expected 1 edge removed, got %d", n)
+		}
+		if n := g.RemoveEdge(capec, cwe, EdgeTypeRelatedTo); n != 0 {
+			t.Errorf("Expected nothing left to remove, got %d", n)
+		}
+		expectCounts("edge removed",
+			map[urn.ResourceType]int{urn.TypeCVE: 2, urn.TypeCWE: 1, urn.TypeCAPEC: 1},
+			map[EdgeType]int{EdgeTypeReferences: 2, EdgeTypeRelatedTo: 1, "self": 1})
+
+		// Removing a node takes its incoming, outgoing and self edges with it
+		if !g.RemoveNode(cve1) || g.RemoveNode(cve1) {
+			t.Error("Expected the node to be removed exactly once")
+		}
+		if len(g.GetIncomingEdges(cwe)) != 1 {
+			t.Errorf("Expected the removed node's edge to be unlinked from its target")
+		}
+		expectCounts("cve removed",
+			map[urn.ResourceType]int{urn.TypeCVE: 1, urn.TypeCWE: 1, urn.TypeCAPEC: 1},
+			map[EdgeType]int{EdgeTypeReferences: 1, EdgeTypeRelatedTo: 1})
+
+		g.RemoveNode(cwe)
+		expectCounts("cwe removed",
+			map[urn.ResourceType]int{urn.TypeCVE: 1, urn.TypeCAPEC: 1},
+			map[EdgeType]int{})
+		if len(g.GetOutgoingEdges(cve2)) != 0 || len(g.GetOutgoingEdges(capec)) != 0 {
+			t.Error("Expected edges into the removed node to be unlinked from their sources")
+		}
+
+		g.Clear()
+		expectCounts("cleared", map[urn.ResourceType]int{}, map[EdgeType]int{})
+		g.AddNode(cwe, nil)
+		expectCounts("after clear", map[urn.ResourceType]int{urn.TypeCWE: 1}, map[EdgeType]int{})
+	})
+}
+
+func TestGraphSubgraph(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "Subgraph", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+
+		cve1, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-1234")
+		cve2, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2024-5678")
+		cwe79, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		cwe74, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-74")
+		capec, _ := urn.New(urn.ProviderMITRE, urn.TypeCAPEC, "CAPEC-86")
+		for _, u := range []*urn.URN{cve1, cve2, cwe79, cwe74, capec} {
+			g.AddNode(u, map[string]interface{}{"id": u.AtomicID})
+		}
+		// cve1 -> cwe79 -> cwe74, capec -> cwe79 (incoming), cve2 -> cwe74
+		g.AddEdge(cve1, cwe79, EdgeTypeReferences, nil)
+		g.AddEdge(cwe79, cwe74, EdgeTypeChildOf, nil)
+		g.AddEdge(capec, cwe79, EdgeTypeExploits, nil)
+		g.AddEdge(cve2, cwe74, EdgeTypeReferences, nil)
+
+		missing, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, "CVE-2000-0001")
+		if _, ok := g.Subgraph(missing, 1); ok {
+			t.Error("Expected no subgraph around a missing node")
+		}
+
+		// Depth 0 is only the center
+		sub, ok := g.Subgraph(cwe79, 0)
+		if !ok || sub.NodeCount() != 1 || sub.EdgeCount() != 0 {
+			t.Fatalf("Expected only the center at depth 0, got %d nodes, %d edges", sub.NodeCount(), sub.EdgeCount())
+		}
+
+		// Depth 1 follows edges in both directions
+		sub, _ = g.Subgraph(cwe79, 1)
+		if sub.NodeCount() != 4 || sub.EdgeCount() != 3 {
+			t.Errorf("Expected 4 nodes and 3 edges at depth 1, got %d and %d", sub.NodeCount(), sub.EdgeCount())
+		}
+		if _, exists := sub.GetNode(cve2); exists {
+			t.Error("Expected CVE-2024-5678 two hops away to be left out at depth 1")
+		}
+		if counts := sub.CountsByEdgeType(); counts[EdgeTypeChildOf] != 1 || counts[EdgeTypeExploits] != 1 || counts[EdgeTypeReferences] != 1 {
+			t.Errorf("Unexpected edge types %v", counts)
+		}
+
+		// Depth 2 reaches the rest, including the edge between two outer nodes
+		sub, _ = g.Subgraph(cwe79, 2)
+		if sub.NodeCount() != 5 || sub.EdgeCount() != 4 {
+			t.Errorf("Expected the whole graph at depth 2, got %d nodes and %d edges", sub.NodeCount(), sub.EdgeCount())
+		}
+
+		// The subgraph is independent of the graph
+		node, _ := sub.GetNode(cwe79)
+		node.Properties["id"] = "changed"
+		sub.RemoveNode(cve1)
+		if orig, _ := g.GetNode(cwe79); orig.Properties["id"] != "CWE-79" || g.NodeCount() != 5 {
+			t.Error("Expected changes to the subgraph to leave the graph untouched")
+		}
+	})
+}
+
+func TestGraphSnapshot(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "Snapshot", nil, func(t *testing.T, tx *gorm.DB) {
+		g := New()
+		cwe79, _ := urn.New(urn.ProviderMITRE, urn.TypeCWE, "CWE-79")
+		g.AddNode(cwe79, map[string]interface{}{"name": "XSS"})
+
+		// Writers keep adding CVEs referencing CWE-79 and merging duplicates
+		// while snapshots are taken, exported and ranked
+		const writers, perWriter = 4, 200
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWriter; i++ {
+					cve, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, fmt.Sprintf("CVE-2024-%d%04d", w, i))
+					g.AddNode(cve, map[string]interface{}{"i": i})
+					g.AddEdge(cve, cwe79, EdgeTypeReferences, map[string]interface{}{WeightProperty: 1.0})
+					if i%10 == 9 {
+						dup, _ := urn.New(urn.ProviderNVD, urn.TypeCVE, fmt.Sprintf("CVE-2024-%d%04d", w, i-1))
+						g.MergeNodes(cve, dup)
+					}
+				}
+			}(w)
+		}
+
+		stop := make(chan struct{})
+		var readers sync.WaitGroup
+		for r := 0; r < 2; r++ {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					snap := g.Snapshot()
+					checkSnapshotConsistent(t, snap)
+					if err := g.Export(io.Discard, ExportFormatJSON); err != nil {
+						t.Errorf("Export failed: %v", err)
+					}
+					if _, err := g.TopCentrality(DirectionIn, urn.TypeCWE, 1); err != nil {
+						t.Errorf("TopCentrality failed: %v", err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(stop)
+		readers.Wait()
+
+		snap := g.Snapshot()
+		checkSnapshotConsistent(t, snap)
+		want := 1 + writers*(perWriter-perWriter/10)
+		if snap.NodeCount() != want || snap.EdgeCount() != want-1 {
+			t.Errorf("Expected %d nodes and %d edges, got %d and %d", want, want-1, snap.NodeCount(), snap.EdgeCount())
+		}
+
+		// The snapshot is independent of the graph
+		node, _ := snap.GetNode(cwe79)
+		node.Properties["name"] = "changed"
+		snap.RemoveNode(cwe79)
+		if orig, _ := g.GetNode(cwe79); orig == nil || orig.Properties["name"] != "XSS" || g.EdgeCount() != want-1 {
+			t.Error("Expected changes to the snapshot to leave the graph untouched")
+		}
+	})
+}
+
+// checkSnapshotConsistent checks that the counts, indexes and edges of a
+// snapshot agree with each other
+func checkSnapshotConsistent(t *testing.T, snap *Graph) {
+	t.Helper()
+	nodes := 0
+	for _, n := range snap.CountsByNodeType() {
+		nodes += n
+	}
+	edges := 0
+	for _, n := range snap.CountsByEdgeType() {
+		edges += n
+	}
+	if nodes != snap.NodeCount() || edges != snap.EdgeCount() {
+		t.Errorf("Counts by type (%d nodes, %d edges) disagree with the graph (%d nodes, %d edges)", nodes, edges, snap.NodeCount(), snap.EdgeCount())
+	}
+	reverse := 0
+	for _, edges := range snap.reverseEdges {
+		reverse += len(edges)
+	}
+	if reverse != snap.EdgeCount() {
+		t.Errorf("Reverse index holds %d edges, expected %d", reverse, snap.EdgeCount())
+	}
+	for _, edge := range snap.GetAllEdges() {
+		_, fromOK := snap.GetNode(edge.From)
+		_, toOK := snap.GetNode(edge.To)
+		if !fromOK || !toOK {
+			t.Errorf("Edge %s -> %s has a missing endpoint", edge.From, edge.To)
+		}
+	}
+}
@@ -8,6 +8,13 @@ import (
 
 var sonicFast = sonic.ConfigFastest
 
+// sonicNumber is sonicFast decoding numbers into interface{} as json.Number
+var sonicNumber = sonic.Config{
+	NoValidateJSONMarshaler: true,
+	NoValidateJSONSkip:      true,
+	UseNumber:               true,
+}.Froze()
+
 func Marshal(v interface{}) ([]byte, error) {
 	return sonicFast.Marshal(v)
 }
@@ -16,6 +23,13 @@ func Unmarshal(data []byte, v interface{}) error {
 	return sonicFast.Unmarshal(data, v)
 }
 
+// UnmarshalUseNumber is Unmarshal, except that numbers decoded into an
+// interface{} become json.Number rather than float64, so integers above 2^53
+// pass through untyped payloads without losing precision
+func UnmarshalUseNumber(data []byte, v interface{}) error {
+	return sonicNumber.Unmarshal(data, v)
+}
+
 func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 	return sonicFast.MarshalIndent(v, prefix, indent)
 }
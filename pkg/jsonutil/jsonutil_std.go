@@ -2,7 +2,10 @@
 
 package jsonutil
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 func Marshal(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
@@ -12,6 +15,15 @@ func Unmarshal(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
 
+// UnmarshalUseNumber is Unmarshal, except that numbers decoded into an
+// interface{} become json.Number rather than float64, so integers above 2^53
+// pass through untyped payloads without losing precision
+func UnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
 func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 	return json.MarshalIndent(v, prefix, indent)
 }
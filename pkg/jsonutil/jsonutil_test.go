@@ -58,3 +58,21 @@ func TestUnmarshalInvalidJSON(t *testing.T) {
 	})
 
 }
+
+func TestUnmarshalUseNumber_KeepsLargeIntegers(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestUnmarshalUseNumber_KeepsLargeIntegers", nil, func(t *testing.T, tx *gorm.DB) {
+		data := []byte(`{"count":9007199254740993,"ratio":0.25}`)
+
+		var v map[string]interface{}
+		if err := UnmarshalUseNumber(data, &v); err != nil {
+			t.Fatalf("UnmarshalUseNumber failed: %v", err)
+		}
+		out, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if !bytes.Contains(out, []byte(`"count":9007199254740993`)) || !bytes.Contains(out, []byte(`"ratio":0.25`)) {
+			t.Errorf("Round trip changed numbers: %s", out)
+		}
+	})
+}
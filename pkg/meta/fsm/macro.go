@@ -81,7 +81,7 @@ func (m *MacroFSMManager) Transition(newState MacroState) error {
 	m.updatedAt = time.Now()
 
 	// Log FSM transition (Requirement 6: Log FSM Transitions)
-	m.logTransition(oldState, newState)
+	m.logTransition(oldState, newState, "manual")
 
 	// Persist state to storage
 	if m.storage != nil {
@@ -176,6 +176,7 @@ func (m *MacroFSMManager) handleEventInternal(event *Event) {
 			// Auto-transition to orchestrating when first provider starts
 			m.state = MacroOrchestrating
 			m.updatedAt = time.Now()
+			m.logTransition(MacroBootstrapping, MacroOrchestrating, string(event.Type))
 		}
 
 	case EventProviderCompleted:
@@ -191,6 +192,7 @@ func (m *MacroFSMManager) handleEventInternal(event *Event) {
 			// Transition to stabilizing when all providers complete
 			m.state = MacroStabilizing
 			m.updatedAt = time.Now()
+			m.logTransition(MacroOrchestrating, MacroStabilizing, string(event.Type))
 		}
 
 	case EventProviderFailed:
@@ -307,9 +309,20 @@ func (m *MacroFSMManager) loadState() error {
 	return nil
 }
 
-// logTransition logs a macro FSM state transition
+// logTransition logs a macro FSM state transition and records it in
+// DefaultTransitionLog
 // Implements Requirement 6: Log FSM Transitions
-func (m *MacroFSMManager) logTransition(oldState, newState MacroState) {
+func (m *MacroFSMManager) logTransition(oldState, newState MacroState, trigger string) {
+	now := time.Now()
+	DefaultTransitionLog.Record(TransitionRecord{
+		Timestamp: now,
+		Kind:      TransitionKindMacro,
+		FSMID:     m.id,
+		OldState:  string(oldState),
+		NewState:  string(newState),
+		Trigger:   trigger,
+	})
+
 	providerCount := len(m.providers)
 
 	// Count providers by state
@@ -320,11 +333,12 @@ func (m *MacroFSMManager) logTransition(oldState, newState MacroState) {
 	}
 
 	// Log structured transition
-	fmt.Printf("[MACRO_FSM_TRANSITION] macro_id=%s old_state=%s new_state=%s timestamp=%s provider_count=%d provider_states=%+v\n",
+	fmt.Printf("[MACRO_FSM_TRANSITION] macro_id=%s old_state=%s new_state=%s trigger=%s timestamp=%s provider_count=%d provider_states=%+v\n",
 		m.id,
 		oldState,
 		newState,
-		time.Now().Format(time.RFC3339),
+		trigger,
+		now.Format(time.RFC3339),
 		providerCount,
 		stateCounts,
 	)
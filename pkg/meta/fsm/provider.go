@@ -100,6 +100,11 @@ func (p *BaseProviderFSM) GetState() ProviderState {
 
 // Transition attempts to transition to a new state
 func (p *BaseProviderFSM) Transition(newState ProviderState) error {
+	return p.transition(newState, "manual")
+}
+
+// transition moves to newState, recording trigger as what caused it
+func (p *BaseProviderFSM) transition(newState ProviderState, trigger string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -113,7 +118,7 @@ func (p *BaseProviderFSM) Transition(newState ProviderState) error {
 	p.updatedAt = time.Now()
 
 	// Log FSM transition (Requirement 6: Log FSM Transitions)
-	p.logTransition(oldState, newState, "manual")
+	p.logTransition(oldState, newState, trigger)
 
 	// Persist state to storage
 	if p.storage != nil {
@@ -149,7 +154,7 @@ func (p *BaseProviderFSM) Start() error {
 	}
 
 	// Transition to ACQUIRING (waiting for permits)
-	if err := p.Transition(ProviderAcquiring); err != nil {
+	if err := p.transition(ProviderAcquiring, "start"); err != nil {
 		return err
 	}
 
@@ -167,7 +172,7 @@ func (p *BaseProviderFSM) Pause() error {
 		return fmt.Errorf("cannot pause from state %s, must be RUNNING", currentState)
 	}
 
-	if err := p.Transition(ProviderPaused); err != nil {
+	if err := p.transition(ProviderPaused, "pause"); err != nil {
 		return err
 	}
 
@@ -186,7 +191,7 @@ func (p *BaseProviderFSM) Resume() error {
 	}
 
 	// Transition back to ACQUIRING to request permits again
-	if err := p.Transition(ProviderAcquiring); err != nil {
+	if err := p.transition(ProviderAcquiring, "resume"); err != nil {
 		return err
 	}
 
@@ -198,7 +203,7 @@ func (p *BaseProviderFSM) Resume() error {
 
 // Stop terminates execution (any state -> TERMINATED)
 func (p *BaseProviderFSM) Stop() error {
-	if err := p.Transition(ProviderTerminated); err != nil {
+	if err := p.transition(ProviderTerminated, "stop"); err != nil {
 		return err
 	}
 
@@ -220,7 +225,7 @@ func (p *BaseProviderFSM) OnQuotaRevoked(revokedCount int) error {
 
 	// If running, transition to WAITING_QUOTA
 	if currentState == ProviderRunning {
-		if err := p.Transition(ProviderWaitingQuota); err != nil {
+		if err := p.transition(ProviderWaitingQuota, "quota_revoked"); err != nil {
 			return err
 		}
 
@@ -239,7 +244,7 @@ func (p *BaseProviderFSM) OnQuotaGranted(grantedCount int) error {
 
 	// If acquiring, transition to RUNNING
 	if currentState == ProviderAcquiring {
-		if err := p.Transition(ProviderRunning); err != nil {
+		if err := p.transition(ProviderRunning, "quota_granted"); err != nil {
 			return err
 		}
 
@@ -250,7 +255,7 @@ func (p *BaseProviderFSM) OnQuotaGranted(grantedCount int) error {
 		go p.executeAsync()
 	} else if currentState == ProviderWaitingQuota {
 		// Retry acquisition
-		if err := p.Transition(ProviderAcquiring); err != nil {
+		if err := p.transition(ProviderAcquiring, "quota_granted"); err != nil {
 			return err
 		}
 	}
@@ -263,7 +268,7 @@ func (p *BaseProviderFSM) OnRateLimited(retryAfter time.Duration) error {
 	currentState := p.GetState()
 
 	if currentState == ProviderRunning {
-		if err := p.Transition(ProviderWaitingBackoff); err != nil {
+		if err := p.transition(ProviderWaitingBackoff, "rate_limited"); err != nil {
 			return err
 		}
 
@@ -275,7 +280,7 @@ func (p *BaseProviderFSM) OnRateLimited(retryAfter time.Duration) error {
 			time.Sleep(retryAfter)
 			// Transition back to ACQUIRING
 			if p.GetState() == ProviderWaitingBackoff {
-				p.Transition(ProviderAcquiring)
+				p.transition(ProviderAcquiring, "backoff_elapsed")
 			}
 		}()
 	}
@@ -410,9 +415,21 @@ func (p *BaseProviderFSM) loadState() error {
 	return nil
 }
 
-// logTransition logs an FSM state transition
+// logTransition logs an FSM state transition and records it in
+// DefaultTransitionLog
 // Implements Requirement 6: Log FSM Transitions
 func (p *BaseProviderFSM) logTransition(oldState, newState ProviderState, trigger string) {
+	now := time.Now()
+	DefaultTransitionLog.Record(TransitionRecord{
+		Timestamp: now,
+		Kind:      TransitionKindProvider,
+		FSMID:     p.id,
+		URN:       p.lastCheckpoint,
+		OldState:  string(oldState),
+		NewState:  string(newState),
+		Trigger:   trigger,
+	})
+
 	// Get current checkpoint/URN if available
 	checkpoint := p.lastCheckpoint
 	if checkpoint == "" {
@@ -429,7 +446,7 @@ func (p *BaseProviderFSM) logTransition(oldState, newState ProviderState, trigge
 		newState,
 		trigger,
 		checkpoint,
-		time.Now().Format(time.RFC3339),
+		now.Format(time.RFC3339),
 		p.processedCount,
 		p.errorCount,
 	)
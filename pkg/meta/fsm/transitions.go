@@ -0,0 +1,97 @@
+package fsm
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTransitionLogSize is the number of transitions the default log keeps
+	DefaultTransitionLogSize = 1000
+	// DefaultTransitionsLimit is the number of transitions returned by default
+	DefaultTransitionsLimit = 50
+)
+
+// Kinds of state machines recorded in the transition log
+const (
+	TransitionKindMacro    = "macro"
+	TransitionKindProvider = "provider"
+)
+
+// TransitionRecord is one state change of a macro or provider FSM
+type TransitionRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"` // TransitionKindMacro or TransitionKindProvider
+	FSMID     string    `json:"fsm_id"`
+	// URN is the last checkpoint of a provider at the time of the transition
+	URN      string `json:"urn,omitempty"`
+	OldState string `json:"old_state"`
+	NewState string `json:"new_state"`
+	Trigger  string `json:"trigger"`
+}
+
+// TransitionLog is a bounded in-memory ring buffer of the most recent FSM
+// transitions. Once full, each new transition overwrites the oldest.
+type TransitionLog struct {
+	mu      sync.Mutex
+	records []TransitionRecord
+	next    int // Index the next record is written at
+	full    bool
+}
+
+// NewTransitionLog creates a transition log keeping the last size
+// transitions (DefaultTransitionLogSize if size is not positive)
+func NewTransitionLog(size int) *TransitionLog {
+	if size <= 0 {
+		size = DefaultTransitionLogSize
+	}
+	return &TransitionLog{records: make([]TransitionRecord, size)}
+}
+
+// DefaultTransitionLog records the transitions of every FSM of the process
+var DefaultTransitionLog = NewTransitionLog(DefaultTransitionLogSize)
+
+// Record appends a transition, evicting the oldest one if the log is full
+func (l *TransitionLog) Record(record TransitionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records[l.next] = record
+	l.next = (l.next + 1) % len(l.records)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Recent returns up to limit of the most recent transitions, newest first. A
+// limit that is not positive or exceeds the capacity returns all of them.
+func (l *TransitionLog) Recent(limit int) []TransitionRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.records)
+	}
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	out := make([]TransitionRecord, 0, limit)
+	for i := 1; i <= limit; i++ {
+		out = append(out, l.records[(l.next-i+len(l.records))%len(l.records)])
+	}
+	return out
+}
+
+// Capacity returns the number of transitions the log keeps
+func (l *TransitionLog) Capacity() int {
+	return len(l.records)
+}
+
+// Len returns the number of transitions in the log
+func (l *TransitionLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.full {
+		return len(l.records)
+	}
+	return l.next
+}
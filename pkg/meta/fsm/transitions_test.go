@@ -0,0 +1,87 @@
+package fsm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/urn"
+)
+
+func TestTransitionLog_RingBuffer(t *testing.T) {
+	log := NewTransitionLog(3)
+	if got := log.Recent(10); len(got) != 0 {
+		t.Fatalf("Expected an empty log, got %+v", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		log.Record(TransitionRecord{FSMID: fmt.Sprintf("fsm-%d", i)})
+	}
+	if log.Len() != 3 || log.Capacity() != 3 {
+		t.Fatalf("Len = %d, Capacity = %d, want 3 and 3", log.Len(), log.Capacity())
+	}
+
+	got := log.Recent(0)
+	if len(got) != 3 || got[0].FSMID != "fsm-4" || got[1].FSMID != "fsm-3" || got[2].FSMID != "fsm-2" {
+		t.Errorf("Expected the last 3 transitions newest first, got %+v", got)
+	}
+	if got := log.Recent(2); len(got) != 2 || got[0].FSMID != "fsm-4" {
+		t.Errorf("Expected the limit to apply, got %+v", got)
+	}
+}
+
+func TestBaseProviderFSM_RecordsTransitions(t *testing.T) {
+	saved := DefaultTransitionLog
+	DefaultTransitionLog = NewTransitionLog(10)
+	defer func() { DefaultTransitionLog = saved }()
+
+	provider, err := NewBaseProviderFSM(ProviderConfig{ID: "provider-log", ProviderType: "cve"})
+	if err != nil {
+		t.Fatalf("Failed to create BaseProviderFSM: %v", err)
+	}
+	itemURN, _ := urn.Parse("v2e::nvd::cve::CVE-2024-0001")
+	provider.SaveCheckpoint(itemURN, true, "")
+	provider.Start()
+	provider.OnQuotaGranted(1)
+	provider.Pause()
+
+	got := DefaultTransitionLog.Recent(0)
+	want := []struct {
+		old, new ProviderState
+		trigger  string
+	}{
+		{ProviderRunning, ProviderPaused, "pause"},
+		{ProviderAcquiring, ProviderRunning, "quota_granted"},
+		{ProviderIdle, ProviderAcquiring, "start"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d transitions, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		r := got[i]
+		if r.Kind != TransitionKindProvider || r.FSMID != "provider-log" || r.OldState != string(w.old) ||
+			r.NewState != string(w.new) || r.Trigger != w.trigger || r.URN != itemURN.Key() || r.Timestamp.IsZero() {
+			t.Errorf("Transition %d = %+v, want %s -> %s by %s", i, r, w.old, w.new, w.trigger)
+		}
+	}
+}
+
+func TestMacroFSMManager_RecordsTransitions(t *testing.T) {
+	saved := DefaultTransitionLog
+	DefaultTransitionLog = NewTransitionLog(10)
+	defer func() { DefaultTransitionLog = saved }()
+
+	macro, err := NewMacroFSMManager("macro-log", nil)
+	if err != nil {
+		t.Fatalf("Failed to create MacroFSMManager: %v", err)
+	}
+	if err := macro.Transition(MacroOrchestrating); err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	macro.Stop()
+
+	got := DefaultTransitionLog.Recent(0)
+	if len(got) != 2 || got[1].NewState != string(MacroOrchestrating) || got[0].NewState != string(MacroDraining) ||
+		got[0].Kind != TransitionKindMacro || got[0].FSMID != "macro-log" || got[0].Trigger != "manual" {
+		t.Errorf("Unexpected macro transitions %+v", got)
+	}
+}
@@ -2,6 +2,7 @@ package notes
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -37,6 +38,8 @@ type MemoryCardServiceInterface interface {
 	GetCardsForReview(ctx context.Context) ([]*MemoryCardModel, error)
 	GetCardsByLearningState(ctx context.Context, state LearningState) ([]*MemoryCardModel, error)
 	UpdateCardAfterReview(ctx context.Context, cardID uint, rating CardRating) error
+	GetDueMemoryCards(ctx context.Context, now time.Time, limit int) ([]*MemoryCardModel, error)
+	ReviewMemoryCard(ctx context.Context, cardID uint, quality int) (*MemoryCardModel, error)
 	UpdateMemoryCardFields(ctx context.Context, fields map[string]any) (*MemoryCardModel, error)
 	DeleteMemoryCard(ctx context.Context, id uint) error
 	TransitionCardStatus(ctx context.Context, cardID uint, expectedVersion *int, next CardStatus) error
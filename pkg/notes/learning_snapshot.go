@@ -0,0 +1,392 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LearningSnapshotVersion is the format version of exported learning snapshots
+const LearningSnapshotVersion = 1
+
+// LearningSnapshot is a portable copy of the learning progress: the
+// bookmarked items with their learning state and the cross references
+// linking items. Items are keyed by URN, so a snapshot can be imported on
+// another machine whose bookmark IDs differ.
+type LearningSnapshot struct {
+	Version    int                    `json:"version"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Items      []LearningSnapshotItem `json:"items"`
+	Links      []LearningSnapshotLink `json:"links"`
+}
+
+// LearningSnapshotItem is the learning progress on one bookmarked item.
+// Metadata carries the view statistics (view_count, last_viewed, ...); Card
+// carries the review progress of the item's memory card.
+type LearningSnapshotItem struct {
+	URN           string                 `json:"urn"`
+	GlobalItemID  string                 `json:"global_item_id"`
+	ItemType      string                 `json:"item_type"`
+	ItemID        string                 `json:"item_id"`
+	Title         string                 `json:"title"`
+	Description   string                 `json:"description,omitempty"`
+	LearningState string                 `json:"learning_state"`
+	MasteryLevel  float32                `json:"mastery_level"`
+	LastReviewed  *time.Time             `json:"last_reviewed,omitempty"`
+	NextReview    *time.Time             `json:"next_review,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Card          *LearningSnapshotCard  `json:"card,omitempty"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// LearningSnapshotCard is the spaced repetition (SM-2) state of the memory
+// card of an item
+type LearningSnapshotCard struct {
+	Status     string     `json:"status"`
+	EaseFactor float32    `json:"ease_factor"`
+	Interval   int        `json:"interval"`
+	Repetition int        `json:"repetition"`
+	NextReview *time.Time `json:"next_review_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// LearningSnapshotLink is a cross reference between two items
+type LearningSnapshotLink struct {
+	SourceItemID     string    `json:"source_item_id"`
+	TargetItemID     string    `json:"target_item_id"`
+	SourceType       string    `json:"source_type"`
+	TargetType       string    `json:"target_type"`
+	RelationshipType string    `json:"relationship_type"`
+	Strength         float32   `json:"strength"`
+	Description      *string   `json:"description,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// LearningImportResult counts what an import changed
+type LearningImportResult struct {
+	Created      int `json:"created"`       // Items bookmarked by the import
+	Updated      int `json:"updated"`       // Items whose snapshot progress was newer
+	Kept         int `json:"kept"`          // Items whose local progress was as recent or newer
+	CardsUpdated int `json:"cards_updated"` // Memory cards whose snapshot review progress was newer
+	LinksAdded   int `json:"links_added"`   // Cross references not yet present
+}
+
+// ExportLearningSnapshot returns the learning progress of every bookmark,
+// with the review progress of its memory card, and every cross reference
+func (s *BookmarkService) ExportLearningSnapshot(ctx context.Context) (*LearningSnapshot, error) {
+	var bookmarks []BookmarkModel
+	if err := s.db.WithContext(ctx).Order("id").Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bookmarks: %w", err)
+	}
+	// A bookmark's card is the first one created for it
+	var cards []MemoryCardModel
+	if err := s.db.WithContext(ctx).Order("id DESC").Find(&cards).Error; err != nil {
+		return nil, fmt.Errorf("failed to list memory cards: %w", err)
+	}
+	cardByBookmark := make(map[uint]*MemoryCardModel, len(cards))
+	for i := range cards {
+		cardByBookmark[cards[i].BookmarkID] = &cards[i]
+	}
+	var refs []CrossReferenceModel
+	if err := s.db.WithContext(ctx).Order("id").Find(&refs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list cross references: %w", err)
+	}
+
+	snapshot := &LearningSnapshot{
+		Version:    LearningSnapshotVersion,
+		ExportedAt: time.Now().UTC(),
+		Items:      make([]LearningSnapshotItem, 0, len(bookmarks)),
+		Links:      make([]LearningSnapshotLink, 0, len(refs)),
+	}
+	for i := range bookmarks {
+		b := &bookmarks[i]
+		snapshot.Items = append(snapshot.Items, LearningSnapshotItem{
+			URN:           b.GetURN(),
+			GlobalItemID:  b.GlobalItemID,
+			ItemType:      b.ItemType,
+			ItemID:        b.ItemID,
+			Title:         b.Title,
+			Description:   b.Description,
+			LearningState: b.LearningState,
+			MasteryLevel:  b.MasteryLevel,
+			LastReviewed:  b.LastReviewed,
+			NextReview:    b.NextReview,
+			Metadata:      b.Metadata,
+			Card:          snapshotCard(cardByBookmark[b.ID]),
+			UpdatedAt:     b.UpdatedAt,
+		})
+	}
+	for _, r := range refs {
+		snapshot.Links = append(snapshot.Links, LearningSnapshotLink{
+			SourceItemID:     r.SourceItemID,
+			TargetItemID:     r.TargetItemID,
+			SourceType:       r.SourceType,
+			TargetType:       r.TargetType,
+			RelationshipType: r.RelationshipType,
+			Strength:         r.Strength,
+			Description:      r.Description,
+			CreatedAt:        r.CreatedAt,
+		})
+	}
+	return snapshot, nil
+}
+
+// ImportLearningSnapshot merges a snapshot into the local progress in one
+// transaction. An item not bookmarked locally is bookmarked, with its memory
+// card as RPCCreateBookmark would create it and the snapshot's review
+// progress. For an item bookmarked on both sides the most recently updated
+// progress wins, for the bookmark and its card separately, so importing a
+// stale snapshot never undoes newer learning. Cross references are added
+// unless an identical one exists.
+func (s *BookmarkService) ImportLearningSnapshot(ctx context.Context, snapshot *LearningSnapshot) (*LearningImportResult, error) {
+	if snapshot.Version != LearningSnapshotVersion {
+		return nil, fmt.Errorf("unsupported learning snapshot version %d (want %d)", snapshot.Version, LearningSnapshotVersion)
+	}
+	for i, item := range snapshot.Items {
+		if item.ItemType == "" || item.ItemID == "" {
+			return nil, fmt.Errorf("learning snapshot item %d: item_type and item_id are required", i)
+		}
+		if item.LearningState != "" && !isLearningState(item.LearningState) {
+			return nil, fmt.Errorf("learning snapshot item %d: invalid learning_state %q", i, item.LearningState)
+		}
+	}
+
+	result := &LearningImportResult{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range snapshot.Items {
+			if err := importLearningItem(tx, &snapshot.Items[i], result); err != nil {
+				return err
+			}
+		}
+		for i := range snapshot.Links {
+			if err := importLearningLink(tx, &snapshot.Links[i], result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// snapshotCard returns the review progress of card, or nil without a card
+func snapshotCard(card *MemoryCardModel) *LearningSnapshotCard {
+	if card == nil {
+		return nil
+	}
+	return &LearningSnapshotCard{
+		Status:     card.Status,
+		EaseFactor: card.EaseFactor,
+		Interval:   card.Interval,
+		Repetition: card.Repetition,
+		NextReview: card.NextReview,
+		UpdatedAt:  card.UpdatedAt,
+	}
+}
+
+// isLearningState reports whether state is one of the bookmark learning states
+func isLearningState(state string) bool {
+	switch LearningState(state) {
+	case LearningStateToReview, LearningStateLearning, LearningStateMastered, LearningStateArchived:
+		return true
+	}
+	return false
+}
+
+// importLearningItem merges one snapshot item, matching bookmarks by URN
+func importLearningItem(tx *gorm.DB, item *LearningSnapshotItem, result *LearningImportResult) error {
+	urn := item.URN
+	if urn == "" {
+		urn = GenerateURN(item.ItemType, item.ItemID, "")
+	}
+	state := item.LearningState
+	if state == "" {
+		state = string(LearningStateToReview)
+	}
+	updatedAt := item.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+
+	var existing BookmarkModel
+	err := tx.Where("urn = ?", urn).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		title := item.Title
+		if title == "" {
+			title = item.ItemID
+		}
+		bookmark := &BookmarkModel{
+			GlobalItemID:  item.GlobalItemID,
+			ItemType:      item.ItemType,
+			ItemID:        item.ItemID,
+			URN:           urn,
+			Title:         title,
+			Description:   item.Description,
+			LearningState: state,
+			LastReviewed:  item.LastReviewed,
+			NextReview:    item.NextReview,
+			MasteryLevel:  item.MasteryLevel,
+			Metadata:      item.Metadata,
+			UpdatedAt:     updatedAt,
+		}
+		if bookmark.GlobalItemID == "" {
+			bookmark.GlobalItemID = urn
+		}
+		if err := tx.Create(bookmark).Error; err != nil {
+			return fmt.Errorf("failed to create bookmark %s: %w", urn, err)
+		}
+		history := &BookmarkHistoryModel{
+			BookmarkID: bookmark.ID,
+			Action:     string(BookmarkActionCreated),
+			NewValue:   state,
+			Timestamp:  time.Now(),
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return fmt.Errorf("failed to create bookmark history: %w", err)
+		}
+		card := &MemoryCardModel{
+			BookmarkID: bookmark.ID,
+			URN:        urn,
+			Front:      title,
+			Back:       item.Description,
+			EaseFactor: 2.5,
+			Interval:   1,
+		}
+		if c := item.Card; c != nil {
+			card.Status = c.Status
+			card.EaseFactor = c.EaseFactor
+			card.Interval = c.Interval
+			card.Repetition = c.Repetition
+			card.NextReview = c.NextReview
+			if !c.UpdatedAt.IsZero() {
+				card.UpdatedAt = c.UpdatedAt
+			}
+		}
+		if err := tx.Create(card).Error; err != nil {
+			return fmt.Errorf("failed to create memory card: %w", err)
+		}
+		result.Created++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up bookmark %s: %w", urn, err)
+	}
+
+	if err := importLearningCard(tx, &existing, item, result); err != nil {
+		return err
+	}
+	if !updatedAt.After(existing.UpdatedAt) {
+		result.Kept++
+		return nil
+	}
+	// UpdateColumns keeps the snapshot's updated_at, so the merge stays
+	// consistent when the snapshot is imported again elsewhere
+	err = tx.Model(&BookmarkModel{ID: existing.ID}).
+		Select("learning_state", "mastery_level", "last_reviewed", "next_review", "metadata", "updated_at").
+		UpdateColumns(&BookmarkModel{
+			LearningState: state,
+			MasteryLevel:  item.MasteryLevel,
+			LastReviewed:  item.LastReviewed,
+			NextReview:    item.NextReview,
+			Metadata:      item.Metadata,
+			UpdatedAt:     updatedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update bookmark %s: %w", urn, err)
+	}
+	if state != existing.LearningState {
+		history := &BookmarkHistoryModel{
+			BookmarkID: existing.ID,
+			Action:     string(BookmarkActionLearningStateChanged),
+			OldValue:   existing.LearningState,
+			NewValue:   state,
+			Timestamp:  time.Now(),
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return fmt.Errorf("failed to create bookmark history: %w", err)
+		}
+	}
+	result.Updated++
+	return nil
+}
+
+// importLearningCard merges the review progress of an item into the memory
+// card of its local bookmark when the snapshot's is more recent. A bookmark
+// without a card gets one.
+func importLearningCard(tx *gorm.DB, bookmark *BookmarkModel, item *LearningSnapshotItem, result *LearningImportResult) error {
+	c := item.Card
+	if c == nil {
+		return nil
+	}
+	var card MemoryCardModel
+	err := tx.Where("bookmark_id = ?", bookmark.ID).Order("id").First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		card = MemoryCardModel{
+			BookmarkID: bookmark.ID,
+			URN:        bookmark.URN,
+			Front:      bookmark.Title,
+			Back:       bookmark.Description,
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to look up memory card of %s: %w", bookmark.URN, err)
+	} else if !c.UpdatedAt.After(card.UpdatedAt) {
+		return nil
+	}
+
+	card.Status = c.Status
+	card.EaseFactor = c.EaseFactor
+	card.Interval = c.Interval
+	card.Repetition = c.Repetition
+	card.NextReview = c.NextReview
+	card.UpdatedAt = c.UpdatedAt
+	if card.UpdatedAt.IsZero() {
+		card.UpdatedAt = time.Now().UTC()
+	}
+	// UpdateColumns keeps the snapshot's updated_at, as for bookmarks
+	if card.ID == 0 {
+		err = tx.Create(&card).Error
+	} else {
+		err = tx.Model(&MemoryCardModel{ID: card.ID}).
+			Select("status", "ease_factor", "interval", "repetition", "next_review", "updated_at").
+			UpdateColumns(&card).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update memory card of %s: %w", bookmark.URN, err)
+	}
+	result.CardsUpdated++
+	return nil
+}
+
+// importLearningLink adds a snapshot cross reference unless it exists
+func importLearningLink(tx *gorm.DB, link *LearningSnapshotLink, result *LearningImportResult) error {
+	var count int64
+	err := tx.Model(&CrossReferenceModel{}).
+		Where("source_item_id = ? AND target_item_id = ? AND relationship_type = ?", link.SourceItemID, link.TargetItemID, link.RelationshipType).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to look up cross reference: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	ref := &CrossReferenceModel{
+		SourceItemID:     link.SourceItemID,
+		TargetItemID:     link.TargetItemID,
+		SourceType:       link.SourceType,
+		TargetType:       link.TargetType,
+		RelationshipType: link.RelationshipType,
+		Strength:         link.Strength,
+		Description:      link.Description,
+		CreatedAt:        link.CreatedAt,
+	}
+	if err := tx.Create(ref).Error; err != nil {
+		return fmt.Errorf("failed to create cross reference: %w", err)
+	}
+	result.LinksAdded++
+	return nil
+}
@@ -0,0 +1,107 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestLearningSnapshot(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestLearningSnapshot", nil, func(t *testing.T, tx *gorm.DB) {
+		ctx := context.Background()
+		srcDB := setupTestDB(t)
+		defer cleanupTestDB(srcDB)
+		dstDB := setupTestDB(t)
+		defer cleanupTestDB(dstDB)
+		src := NewBookmarkService(srcDB)
+		dst := NewBookmarkService(dstDB)
+
+		// Progress on the source machine: one item being learned, one mastered
+		learning, learningCard, err := src.CreateBookmark(ctx, "g-cve", "CVE", "CVE-2024-0001", "CVE one", "first")
+		require.NoError(t, err)
+		require.NoError(t, src.UpdateLearningState(ctx, learning.ID, LearningStateLearning))
+		require.NoError(t, srcDB.Model(&MemoryCardModel{ID: learningCard.ID}).UpdateColumn("status", StatusLearning).Error)
+		srcCards := NewMemoryCardService(srcDB)
+		require.NoError(t, srcCards.UpdateCardAfterReview(ctx, learningCard.ID, CardRatingEasy))
+		require.NoError(t, srcCards.UpdateCardAfterReview(ctx, learningCard.ID, CardRatingGood))
+		reviewed, err := srcCards.GetMemoryCardByID(ctx, learningCard.ID)
+		require.NoError(t, err)
+		mastered, _, err := src.CreateBookmark(ctx, "g-cwe", "CWE", "CWE-79", "XSS", "second")
+		require.NoError(t, err)
+		require.NoError(t, src.UpdateLearningState(ctx, mastered.ID, LearningStateMastered))
+		_, err = NewCrossReferenceService(srcDB).CreateCrossReference(ctx, "g-cve", "g-cwe", "CVE", "CWE", "related-to", 0.8, nil)
+		require.NoError(t, err)
+
+		// The destination already knows CWE-79, with more recent progress
+		local, _, err := dst.CreateBookmark(ctx, "g-cwe", "CWE", "CWE-79", "XSS", "second")
+		require.NoError(t, err)
+		require.NoError(t, dstDB.Model(&BookmarkModel{ID: local.ID}).UpdateColumn("updated_at", time.Now().Add(time.Hour)).Error)
+
+		snapshot, err := src.ExportLearningSnapshot(ctx)
+		require.NoError(t, err)
+		assert.Len(t, snapshot.Items, 2)
+		assert.Len(t, snapshot.Links, 1)
+
+		// The snapshot survives a JSON round trip, as it does over RPC
+		data, err := json.Marshal(snapshot)
+		require.NoError(t, err)
+		var decoded LearningSnapshot
+		require.NoError(t, json.Unmarshal(data, &decoded))
+
+		result, err := dst.ImportLearningSnapshot(ctx, &decoded)
+		require.NoError(t, err)
+		assert.Equal(t, &LearningImportResult{Created: 1, Kept: 1, LinksAdded: 1}, result)
+
+		var imported BookmarkModel
+		require.NoError(t, dstDB.Where("item_id = ?", "CVE-2024-0001").First(&imported).Error)
+		assert.Equal(t, string(LearningStateLearning), imported.LearningState)
+		assert.Equal(t, learning.GetURN(), imported.URN)
+		var importedCard MemoryCardModel
+		require.NoError(t, dstDB.Where("bookmark_id = ?", imported.ID).First(&importedCard).Error)
+		assert.Equal(t, reviewed.EaseFactor, importedCard.EaseFactor, "review progress must survive the round trip")
+		assert.Equal(t, reviewed.Interval, importedCard.Interval)
+		assert.Equal(t, reviewed.Repetition, importedCard.Repetition)
+		assert.Equal(t, reviewed.Status, importedCard.Status)
+		require.NotNil(t, importedCard.NextReview)
+		assert.True(t, reviewed.NextReview.Equal(*importedCard.NextReview))
+		var kept BookmarkModel
+		require.NoError(t, dstDB.First(&kept, local.ID).Error)
+		assert.Equal(t, string(LearningStateToReview), kept.LearningState, "newer local progress must win")
+
+		// A newer snapshot overrides the local progress; links are not duplicated
+		decoded.Items[1].UpdatedAt = time.Now().Add(2 * time.Hour)
+		result, err = dst.ImportLearningSnapshot(ctx, &decoded)
+		require.NoError(t, err)
+		assert.Equal(t, &LearningImportResult{Updated: 1, Kept: 1}, result)
+		require.NoError(t, dstDB.First(&kept, local.ID).Error)
+		assert.Equal(t, string(LearningStateMastered), kept.LearningState)
+		// Newer review progress of a card is merged independently
+		decoded.Items[1].Card.Repetition = 4
+		decoded.Items[1].Card.Interval = 12
+		decoded.Items[1].Card.UpdatedAt = time.Now().Add(3 * time.Hour)
+		result, err = dst.ImportLearningSnapshot(ctx, &decoded)
+		require.NoError(t, err)
+		assert.Equal(t, &LearningImportResult{Kept: 2, CardsUpdated: 1}, result)
+		var keptCard MemoryCardModel
+		require.NoError(t, dstDB.Where("bookmark_id = ?", local.ID).First(&keptCard).Error)
+		assert.Equal(t, 4, keptCard.Repetition)
+		assert.Equal(t, 12, keptCard.Interval)
+
+		var changes int64
+		dstDB.Model(&BookmarkHistoryModel{}).Where("bookmark_id = ? AND action = ?", local.ID, BookmarkActionLearningStateChanged).Count(&changes)
+		assert.Equal(t, int64(1), changes)
+
+		// Malformed snapshots are rejected before anything is written
+		_, err = dst.ImportLearningSnapshot(ctx, &LearningSnapshot{Version: 99})
+		assert.Error(t, err)
+		_, err = dst.ImportLearningSnapshot(ctx, &LearningSnapshot{Version: LearningSnapshotVersion,
+			Items: []LearningSnapshotItem{{ItemType: "CVE", ItemID: "CVE-2024-0002", LearningState: "forgotten"}}})
+		assert.Error(t, err)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/proc"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
@@ -117,6 +118,7 @@ func (c *RPCClient) InvokeRPC(ctx context.Context, target, method string, params
 		Target:        target,
 		CorrelationID: correlationID,
 		Source:        c.sp.ID,
+		Priority:      proc.PriorityForMethod(method),
 	}
 
 	// Send request to broker (which will route to target)
@@ -733,6 +735,60 @@ func (s *MemoryCardServiceRPCClient) UpdateCardAfterReview(ctx context.Context,
 	return nil
 }
 
+// GetDueMemoryCards retrieves up to limit cards whose next review is due via RPC.
+// The remote side evaluates due dates against its own clock, so now is not sent.
+func (s *MemoryCardServiceRPCClient) GetDueMemoryCards(ctx context.Context, now time.Time, limit int) ([]*MemoryCardModel, error) {
+	params := map[string]interface{}{
+		"limit": limit,
+	}
+
+	var result struct {
+		Cards []*MemoryCardModel `json:"cards"`
+	}
+
+	response, err := s.Client.InvokeRPC(ctx, "local", "RPCGetDueMemoryCards", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due memory cards via RPC: %w", err)
+	}
+
+	if response.Type == subprocess.MessageTypeError {
+		return nil, fmt.Errorf("remote error: %s", response.Error)
+	}
+
+	if err := subprocess.UnmarshalPayload(response, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return result.Cards, nil
+}
+
+// ReviewMemoryCard records an SM-2 review of a card via RPC
+func (s *MemoryCardServiceRPCClient) ReviewMemoryCard(ctx context.Context, cardID uint, quality int) (*MemoryCardModel, error) {
+	params := map[string]interface{}{
+		"card_id": cardID,
+		"quality": quality,
+	}
+
+	var result struct {
+		Card *MemoryCardModel `json:"card"`
+	}
+
+	response, err := s.Client.InvokeRPC(ctx, "local", "RPCReviewMemoryCard", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review memory card via RPC: %w", err)
+	}
+
+	if response.Type == subprocess.MessageTypeError {
+		return nil, fmt.Errorf("remote error: %s", response.Error)
+	}
+
+	if err := subprocess.UnmarshalPayload(response, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return result.Card, nil
+}
+
 // UpdateMemoryCardFields updates a memory card by ID with arbitrary fields via RPC
 func (s *MemoryCardServiceRPCClient) UpdateMemoryCardFields(ctx context.Context, fields map[string]any) (*MemoryCardModel, error) {
 	// Convert fields to map[string]interface{} for JSON marshaling
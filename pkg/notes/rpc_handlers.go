@@ -3,6 +3,7 @@ package notes
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
@@ -46,6 +47,8 @@ func NewRPCHandlers(container *ServiceContainer, sp *subprocess.Subprocess, logg
 	sp.RegisterHandler("RPCUpdateCardAfterReview", handlers.handleRPCUpdateCardAfterReview)
 	// Alias for frontend compatibility
 	sp.RegisterHandler("RPCRateMemoryCard", handlers.handleRPCUpdateCardAfterReview)
+	sp.RegisterHandler("RPCGetDueMemoryCards", handlers.handleRPCGetDueMemoryCards)
+	sp.RegisterHandler("RPCReviewMemoryCard", handlers.handleRPCReviewMemoryCard)
 	sp.RegisterHandler("RPCUpdateMemoryCard", handlers.handleRPCUpdateMemoryCard)
 	sp.RegisterHandler("RPCDeleteMemoryCard", handlers.handleRPCDeleteMemoryCard)
 	sp.RegisterHandler("RPCGetMemoryCardByID", handlers.handleRPCGetMemoryCardByID)
@@ -894,6 +897,99 @@ func (h *RPCHandlers) handleRPCUpdateCardAfterReview(ctx context.Context, msg *s
 	}, nil
 }
 
+// handleRPCGetDueMemoryCards handles RPC request to get the memory cards due for review
+func (h *RPCHandlers) handleRPCGetDueMemoryCards(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+	var params map[string]interface{}
+	if msg.Payload != nil {
+		if err := subprocess.UnmarshalPayload(msg, &params); err != nil {
+			return h.createErrorResponse(msg, fmt.Sprintf("Failed to unmarshal params: %v", err)), nil
+		}
+	}
+
+	limit := DefaultDueCardsLimit
+	if limitParam, exists := params["limit"]; exists && limitParam != nil {
+		limitFloat, ok := limitParam.(float64)
+		if !ok {
+			return h.createErrorResponse(msg, "Invalid limit parameter"), nil
+		}
+		limit = int(limitFloat)
+	}
+	if limit <= 0 || limit > MaxDueCardsLimit {
+		limit = DefaultDueCardsLimit
+	}
+
+	cards, err := h.container.MemoryCardService.GetDueMemoryCards(ctx, time.Now(), limit)
+	if err != nil {
+		return h.createErrorResponse(msg, fmt.Sprintf("Failed to get due memory cards: %v", err)), nil
+	}
+
+	result := struct {
+		Cards []*MemoryCardModel `json:"cards"`
+		Total int                `json:"total"`
+	}{
+		Cards: cards,
+		Total: len(cards),
+	}
+
+	payload, err := subprocess.MarshalFast(result)
+	if err != nil {
+		return h.createErrorResponse(msg, fmt.Sprintf("Failed to marshal result: %v", err)), nil
+	}
+
+	return &subprocess.Message{
+		Type:          subprocess.MessageTypeResponse,
+		ID:            msg.ID,
+		Payload:       payload,
+		Target:        msg.Source,
+		CorrelationID: msg.CorrelationID,
+		Source:        h.sp.ID,
+	}, nil
+}
+
+// handleRPCReviewMemoryCard handles RPC request to record an SM-2 review of a memory card
+func (h *RPCHandlers) handleRPCReviewMemoryCard(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
+	var params map[string]interface{}
+	if err := subprocess.UnmarshalPayload(msg, &params); err != nil {
+		return h.createErrorResponse(msg, fmt.Sprintf("Failed to unmarshal params: %v", err)), nil
+	}
+
+	cardIDFloat, ok := params["card_id"].(float64)
+	if !ok {
+		return h.createErrorResponse(msg, "Missing or invalid card_id"), nil
+	}
+	cardID := uint(cardIDFloat)
+
+	qualityFloat, ok := params["quality"].(float64)
+	if !ok || qualityFloat != float64(int(qualityFloat)) {
+		return h.createErrorResponse(msg, "Missing or invalid quality"), nil
+	}
+
+	card, err := h.container.MemoryCardService.ReviewMemoryCard(ctx, cardID, int(qualityFloat))
+	if err != nil {
+		return h.createErrorResponse(msg, fmt.Sprintf("Failed to review memory card: %v", err)), nil
+	}
+
+	result := struct {
+		Card *MemoryCardModel `json:"card"`
+	}{
+		Card: card,
+	}
+
+	payload, err := subprocess.MarshalFast(result)
+	if err != nil {
+		return h.createErrorResponse(msg, fmt.Sprintf("Failed to marshal result: %v", err)), nil
+	}
+
+	return &subprocess.Message{
+		Type:          subprocess.MessageTypeResponse,
+		ID:            msg.ID,
+		Payload:       payload,
+		Target:        msg.Source,
+		CorrelationID: msg.CorrelationID,
+		Source:        h.sp.ID,
+	}, nil
+}
+
 // handleRPCCreateCrossReference handles RPC request to create a cross-reference
 func (h *RPCHandlers) handleRPCCreateCrossReference(ctx context.Context, msg *subprocess.Message) (*subprocess.Message, error) {
 	var params map[string]interface{}
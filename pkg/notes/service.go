@@ -327,23 +327,25 @@ func (s *BookmarkService) DeleteBookmark(ctx context.Context, id uint) error {
 		return fmt.Errorf("failed to find bookmark: %w", err)
 	}
 
-	// Create history entry before deletion
-	history := &BookmarkHistoryModel{
-		BookmarkID: bookmark.ID,
-		Action:     string(BookmarkActionDeleted),
-		OldValue:   bookmark.LearningState,
-		Timestamp:  time.Now(),
-	}
-	if err := s.db.WithContext(ctx).Create(history).Error; err != nil {
-		return fmt.Errorf("failed to create bookmark history: %w", err)
-	}
-
-	// Delete the bookmark (soft delete)
-	if err := s.db.WithContext(ctx).Delete(bookmark).Error; err != nil {
-		return fmt.Errorf("failed to delete bookmark: %w", err)
-	}
+	// Use a transaction so the history entry and the deletion are atomic
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Create history entry before deletion
+		history := &BookmarkHistoryModel{
+			BookmarkID: bookmark.ID,
+			Action:     string(BookmarkActionDeleted),
+			OldValue:   bookmark.LearningState,
+			Timestamp:  time.Now(),
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return fmt.Errorf("failed to create bookmark history: %w", err)
+		}
 
-	return nil
+		// Delete the bookmark (soft delete)
+		if err := tx.Delete(bookmark).Error; err != nil {
+			return fmt.Errorf("failed to delete bookmark: %w", err)
+		}
+		return nil
+	})
 }
 
 // UpdateLearningState updates the learning state of a bookmark
@@ -525,21 +527,20 @@ func (s *HistoryService) RevertBookmarkState(ctx context.Context, bookmarkID uin
 	}
 
 	var timestampTime time.Time
+	if t, ok := timestamp.(*time.Time); timestamp == nil || (ok && t == nil) {
+		// Get the most recent history entry before current state
+		var history BookmarkHistoryModel
+		err := s.db.WithContext(ctx).Where("bookmark_id = ?", bookmarkID).Order("timestamp DESC").First(&history).Error
+		if err != nil {
+			return fmt.Errorf("failed to get most recent history: %w", err)
+		}
+		timestamp = history.Timestamp
+	}
 	switch t := timestamp.(type) {
 	case time.Time:
 		timestampTime = t
 	case *time.Time:
-		if t != nil {
-			timestampTime = *t
-		} else {
-			// Get the most recent history entry before current state
-			var history BookmarkHistoryModel
-			err := s.db.WithContext(ctx).Where("bookmark_id = ?", bookmarkID).Order("timestamp DESC").First(&history).Error
-			if err != nil {
-				return fmt.Errorf("failed to get most recent history: %w", err)
-			}
-			timestampTime = history.Timestamp
-		}
+		timestampTime = *t
 	case string:
 		var err error
 		timestampTime, err = time.Parse(time.RFC3339, t)
@@ -780,38 +781,35 @@ func (s *MemoryCardService) UpdateCardAfterReview(ctx context.Context, cardID ui
 	nextReview := time.Now().AddDate(0, 0, card.Interval)
 	card.NextReview = &nextReview
 
-	// Determine logical next status from current state and repetition
-	var nextStatus CardStatus
-	currentStatus := CardStatus(card.Status)
-
-	// For new cards, move to learning state
-	if currentStatus == StatusNew {
-		nextStatus = StatusLearning
-	} else if currentStatus == StatusLearning {
-		// Cards in learning transition to reviewed or mastered if enough repetitions
-		if card.Repetition >= 5 {
-			nextStatus = StatusMastered
-		} else {
-			nextStatus = StatusReviewed
-		}
-	} else if currentStatus == StatusReviewed {
-		// Reviewed cards can go back to learning (more practice) or mastered (if enough repetitions)
-		if card.Repetition >= 5 {
-			nextStatus = StatusMastered
-		} else {
-			nextStatus = StatusLearning
-		}
-	} else {
-		// Default fallback for other states
-		if card.Repetition >= 5 {
-			nextStatus = StatusMastered
-		} else {
-			nextStatus = StatusReviewed
-		}
+	return s.saveReview(ctx, card, &bookmark, rating)
+}
+
+// reviewedStatus returns the status a card moves to after a review that left
+// it with the given repetition count. Mastered cards stay mastered.
+func reviewedStatus(current CardStatus, repetition int) CardStatus {
+	switch {
+	case current == StatusMastered:
+		return StatusMastered
+	case current == StatusNew:
+		// For new cards, move to learning state
+		return StatusLearning
+	case repetition >= 5:
+		return StatusMastered
+	case current == StatusReviewed:
+		// Reviewed cards go back to learning for more practice
+		return StatusLearning
+	default:
+		return StatusReviewed
 	}
+}
+
+// saveReview persists the schedule of a reviewed card and its status after
+// the review, then updates the mastery of its bookmark
+func (s *MemoryCardService) saveReview(ctx context.Context, card *MemoryCardModel, bookmark *BookmarkModel, rating CardRating) error {
+	nextStatus := reviewedStatus(CardStatus(card.Status), card.Repetition)
 
 	// Persist changes transactionally using optimistic version bump
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Refresh within transaction
 		var cur MemoryCardModel
 		if err := tx.First(&cur, card.ID).Error; err != nil {
@@ -840,17 +838,63 @@ func (s *MemoryCardService) UpdateCardAfterReview(ctx context.Context, cardID ui
 		if res.RowsAffected == 0 {
 			return fmt.Errorf("concurrent update detected")
 		}
+		card.Status = string(nextStatus)
+		card.Version = cur.Version + 1
 
 		// Update bookmark mastery (no error return value)
-		updateBookmarkMastery(ctx, tx, &bookmark, rating)
+		updateBookmarkMastery(ctx, tx, bookmark, rating)
 
 		return nil
 	})
+}
+
+// GetDueMemoryCards returns up to limit memory cards due for review at now:
+// cards never reviewed and cards whose next review is not later than now,
+// the longest overdue first. Archived cards are never due. A limit of zero
+// or less returns all due cards.
+func (s *MemoryCardService) GetDueMemoryCards(ctx context.Context, now time.Time, limit int) ([]*MemoryCardModel, error) {
+	query := s.db.WithContext(ctx).
+		Where("status <> ?", string(StatusArchived)).
+		Where("next_review IS NULL OR next_review <= ?", now.UTC()).
+		Order("next_review IS NOT NULL, next_review ASC, id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var cards []*MemoryCardModel
+	if err := query.Find(&cards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get due memory cards: %w", err)
+	}
+	return cards, nil
+}
+
+// ReviewMemoryCard records a review of a memory card with an SM-2 quality
+// rating from QualityMin to QualityMax (see NextSM2) and schedules its next
+// review. It returns the updated card.
+func (s *MemoryCardService) ReviewMemoryCard(ctx context.Context, cardID uint, quality int) (*MemoryCardModel, error) {
+	card, err := s.GetMemoryCardByID(ctx, cardID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	schedule, err := NextSM2(SM2Schedule{EaseFactor: card.EaseFactor, Interval: card.Interval, Repetition: card.Repetition}, quality)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	var bookmark BookmarkModel
+	if err := s.db.WithContext(ctx).First(&bookmark, card.BookmarkID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	card.EaseFactor = schedule.EaseFactor
+	card.Interval = schedule.Interval
+	card.Repetition = schedule.Repetition
+	nextReview := nextReviewAt(time.Now(), schedule.Interval)
+	card.NextReview = &nextReview
+
+	if err := s.saveReview(ctx, card, &bookmark, qualityRating(quality)); err != nil {
+		return nil, err
+	}
+	return card, nil
 }
 
 // transitionCardStatusTx performs a versioned status transition within an existing transaction.
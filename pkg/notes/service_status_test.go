@@ -3,6 +3,7 @@ package notes
 import (
 "github.com/cyw0ng95/v2e/pkg/testutils"
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -95,6 +96,67 @@ func TestUpdateCardAfterReview_Transition(t *testing.T) {
 
 }
 
+func TestReviewMemoryCard_SchedulesDueCards(t *testing.T) {
+	testutils.Run(t, testutils.Level2, "TestReviewMemoryCard_SchedulesDueCards", nil, func(t *testing.T, tx *gorm.DB) {
+		db := setupTestDBForSvc(t)
+		svc := NewMemoryCardService(db)
+		ctx := context.Background()
+
+		bm := &BookmarkModel{GlobalItemID: "g-sm2", ItemType: "test", ItemID: "i-sm2", Title: "sm2"}
+		if err := db.Create(bm).Error; err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().UTC().Add(-time.Hour)
+		future := time.Now().UTC().Add(24 * time.Hour)
+		newCard := &MemoryCardModel{BookmarkID: bm.ID, Front: "new", Back: "a", Status: string(StatusNew), Content: "{}", EaseFactor: 2.5, Interval: 1}
+		overdue := &MemoryCardModel{BookmarkID: bm.ID, Front: "overdue", Back: "a", Status: string(StatusReviewed), Content: "{}", EaseFactor: 2.5, Interval: 6, Repetition: 2, NextReview: &past}
+		later := &MemoryCardModel{BookmarkID: bm.ID, Front: "later", Back: "a", Status: string(StatusReviewed), Content: "{}", EaseFactor: 2.5, Interval: 1, NextReview: &future}
+		archived := &MemoryCardModel{BookmarkID: bm.ID, Front: "archived", Back: "a", Status: string(StatusArchived), Content: "{}", EaseFactor: 2.5, Interval: 1}
+		for _, c := range []*MemoryCardModel{newCard, overdue, later, archived} {
+			if err := db.Create(c).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		due, err := svc.GetDueMemoryCards(ctx, time.Now(), 0)
+		if err != nil {
+			t.Fatalf("GetDueMemoryCards failed: %v", err)
+		}
+		if len(due) != 2 || due[0].ID != newCard.ID || due[1].ID != overdue.ID {
+			t.Fatalf("expected the new and the overdue card, got %d cards", len(due))
+		}
+		if due, _ := svc.GetDueMemoryCards(ctx, time.Now(), 1); len(due) != 1 {
+			t.Errorf("expected the limit applied, got %d cards", len(due))
+		}
+
+		reviewed, err := svc.ReviewMemoryCard(ctx, overdue.ID, 4)
+		if err != nil {
+			t.Fatalf("ReviewMemoryCard failed: %v", err)
+		}
+		if reviewed.Interval != 15 || reviewed.Repetition != 3 || reviewed.Status != string(StatusLearning) {
+			t.Errorf("unexpected schedule after review: %+v", reviewed)
+		}
+		if reviewed.NextReview == nil || reviewed.NextReview.Sub(time.Now()) < 14*24*time.Hour {
+			t.Errorf("expected the next review in 15 days, got %v", reviewed.NextReview)
+		}
+
+		// The reviewed card is no longer due, but is again once its time comes
+		if due, _ := svc.GetDueMemoryCards(ctx, time.Now(), 0); len(due) != 1 || due[0].ID != newCard.ID {
+			t.Errorf("expected only the new card due after the review, got %d cards", len(due))
+		}
+		if due, _ := svc.GetDueMemoryCards(ctx, time.Now().AddDate(0, 0, 16), 0); len(due) != 3 {
+			t.Errorf("expected 3 cards due in 16 days, got %d", len(due))
+		}
+
+		if _, err := svc.ReviewMemoryCard(ctx, newCard.ID, 7); !errors.Is(err, ErrInvalidQuality) {
+			t.Errorf("expected ErrInvalidQuality, got %v", err)
+		}
+		if _, err := svc.ReviewMemoryCard(ctx, archived.ID, 4); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("expected an archived card not to be reviewable, got %v", err)
+		}
+	})
+}
+
 func TestConcurrentStatusTransitions(t *testing.T) {
 	testutils.Run(t, testutils.Level2, "TestConcurrentStatusTransitions", nil, func(t *testing.T, tx *gorm.DB) {
 		db := setupTestDBForSvc(t)
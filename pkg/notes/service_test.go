@@ -923,8 +923,13 @@ func TestHistoryService(t *testing.T) {
 	})
 
 	t.Run("RevertBookmarkState", func(t *testing.T) {
-		originalState := bookmark.LearningState
-		err := bookmarkService.UpdateLearningState(ctx, bookmark.ID, LearningStateMastered)
+		// Reverting undoes the last change, back to the state before it
+		current, err := bookmarkService.GetBookmarkByID(ctx, bookmark.ID)
+		if err != nil {
+			t.Fatalf("Failed to get bookmark: %v", err)
+		}
+		originalState := current.LearningState
+		err = bookmarkService.UpdateLearningState(ctx, bookmark.ID, LearningStateMastered)
 		if err != nil {
 			t.Fatalf("Failed to update learning state: %v", err)
 		}
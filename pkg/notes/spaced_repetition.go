@@ -0,0 +1,91 @@
+package notes
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Review quality ratings of the SM-2 algorithm, from a complete blackout to
+// a perfect response. A rating below QualityPass counts as a failed recall.
+const (
+	QualityMin  = 0
+	QualityPass = 3
+	QualityMax  = 5
+)
+
+// MinEaseFactor is the lowest ease factor SM-2 lets a card reach
+const MinEaseFactor = 1.3
+
+// Bounds on the number of cards RPCGetDueMemoryCards returns
+const (
+	DefaultDueCardsLimit = 100
+	MaxDueCardsLimit     = 1000
+)
+
+// ErrInvalidQuality is returned for a review quality outside QualityMin..QualityMax
+var ErrInvalidQuality = fmt.Errorf("review quality must be between %d and %d", QualityMin, QualityMax)
+
+// SM2Schedule is the spaced repetition state of a card
+type SM2Schedule struct {
+	EaseFactor float32
+	Interval   int // days
+	Repetition int
+}
+
+// NextSM2 applies an SM-2 review of the given quality to s. A failed recall
+// restarts the card at a one-day interval; a successful one moves it to one,
+// then six days, then the previous interval times the ease factor. The ease
+// factor is adjusted by the quality either way and never drops below
+// MinEaseFactor.
+func NextSM2(s SM2Schedule, quality int) (SM2Schedule, error) {
+	if quality < QualityMin || quality > QualityMax {
+		return s, ErrInvalidQuality
+	}
+	if s.EaseFactor < MinEaseFactor {
+		s.EaseFactor = 2.5
+	}
+
+	next := s
+	if quality < QualityPass {
+		next.Repetition = 0
+		next.Interval = 1
+	} else {
+		switch s.Repetition {
+		case 0:
+			next.Interval = 1
+		case 1:
+			next.Interval = 6
+		default:
+			interval := s.Interval
+			if interval < 1 {
+				interval = 1
+			}
+			next.Interval = int(math.Round(float64(interval) * float64(s.EaseFactor)))
+		}
+		next.Repetition = s.Repetition + 1
+	}
+
+	q := float32(QualityMax - quality)
+	next.EaseFactor = max(MinEaseFactor, s.EaseFactor+0.1-q*(0.08+q*0.02))
+	return next, nil
+}
+
+// qualityRating maps an SM-2 quality to the closest CardRating
+func qualityRating(quality int) CardRating {
+	switch {
+	case quality < QualityPass:
+		return CardRatingAgain
+	case quality == QualityPass:
+		return CardRatingHard
+	case quality < QualityMax:
+		return CardRatingGood
+	default:
+		return CardRatingEasy
+	}
+}
+
+// nextReviewAt returns when a card reviewed at now with interval days is due
+func nextReviewAt(now time.Time, interval int) time.Time {
+	return now.UTC().AddDate(0, 0, interval)
+}
@@ -0,0 +1,43 @@
+package notes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestNextSM2(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestNextSM2", nil, func(t *testing.T, tx *gorm.DB) {
+		fresh := SM2Schedule{EaseFactor: 2.5, Interval: 1}
+		tests := []struct {
+			name    string
+			in      SM2Schedule
+			quality int
+			want    SM2Schedule
+		}{
+			{name: "first pass", in: fresh, quality: 4, want: SM2Schedule{EaseFactor: 2.5, Interval: 1, Repetition: 1}},
+			{name: "second pass", in: SM2Schedule{EaseFactor: 2.5, Interval: 1, Repetition: 1}, quality: 5, want: SM2Schedule{EaseFactor: 2.6, Interval: 6, Repetition: 2}},
+			{name: "third pass multiplies", in: SM2Schedule{EaseFactor: 2.5, Interval: 6, Repetition: 2}, quality: 3, want: SM2Schedule{EaseFactor: 2.36, Interval: 15, Repetition: 3}},
+			{name: "failure restarts", in: SM2Schedule{EaseFactor: 2.5, Interval: 15, Repetition: 3}, quality: 1, want: SM2Schedule{EaseFactor: 1.96, Interval: 1, Repetition: 0}},
+			{name: "ease factor floor", in: SM2Schedule{EaseFactor: 1.4, Interval: 3, Repetition: 2}, quality: 0, want: SM2Schedule{EaseFactor: MinEaseFactor, Interval: 1, Repetition: 0}},
+			{name: "unset ease factor", in: SM2Schedule{}, quality: 4, want: SM2Schedule{EaseFactor: 2.5, Interval: 1, Repetition: 1}},
+		}
+		for _, tt := range tests {
+			got, err := NextSM2(tt.in, tt.quality)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if got.Interval != tt.want.Interval || got.Repetition != tt.want.Repetition || got.EaseFactor-tt.want.EaseFactor > 1e-5 || tt.want.EaseFactor-got.EaseFactor > 1e-5 {
+				t.Errorf("%s: NextSM2() = %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+
+		for _, quality := range []int{-1, 6} {
+			if _, err := NextSM2(fresh, quality); !errors.Is(err, ErrInvalidQuality) {
+				t.Errorf("quality %d: expected ErrInvalidQuality, got %v", quality, err)
+			}
+		}
+	})
+}
@@ -42,6 +42,8 @@ const (
 	BinaryMessageTypeEvent BinaryMessageType = 2
 	// BinaryMessageTypeError represents an error message
 	BinaryMessageTypeError BinaryMessageType = 3
+	// BinaryMessageTypeFragment represents a message fragment
+	BinaryMessageTypeFragment BinaryMessageType = 4
 )
 
 // BinaryHeader represents the fixed-size binary message header
@@ -254,6 +256,8 @@ func ConvertMessageTypeToBinary(t MessageType) BinaryMessageType {
 		return BinaryMessageTypeEvent
 	case MessageTypeError:
 		return BinaryMessageTypeError
+	case MessageTypeFragment:
+		return BinaryMessageTypeFragment
 	default:
 		return BinaryMessageTypeRequest
 	}
@@ -270,6 +274,8 @@ func ConvertBinaryMessageTypeToString(t BinaryMessageType) MessageType {
 		return MessageTypeEvent
 	case BinaryMessageTypeError:
 		return MessageTypeError
+	case BinaryMessageTypeFragment:
+		return MessageTypeFragment
 	default:
 		return MessageTypeRequest
 	}
@@ -0,0 +1,99 @@
+package proc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Payload compression keeps large payloads, such as a batch of thousands of
+// CVEs, from crossing the pipes as multi-megabyte JSON. A compressed payload
+// is the gzip of the JSON payload as a base64 JSON string, so the message
+// stays one valid JSON line, and Compressed is set. Routers forward it as is;
+// only the final receiver decompresses it.
+
+// DefaultCompressThreshold is the payload size in bytes from which outgoing
+// payloads are compressed. Smaller payloads are sent as is: they gain little
+// and would pay the gzip and base64 cost on both ends.
+const DefaultCompressThreshold = 64 * 1024
+
+// gzipWriterPool reuses gzip writers, whose allocation dominates the cost of
+// compressing a single payload
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+		return w
+	},
+}
+
+// CompressPayload returns msg with its payload compressed if the payload is
+// at least threshold bytes and compression makes it smaller. msg itself is
+// left unchanged: a compressed copy is returned, or msg when it is sent as is
+// (threshold <= 0 disables compression).
+func CompressPayload(msg *Message, threshold int) (*Message, error) {
+	if threshold <= 0 || msg.Compressed || len(msg.Payload) < threshold {
+		return msg, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(msg.Payload); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	encodedLen := base64.StdEncoding.EncodedLen(buf.Len())
+	if encodedLen+2 >= len(msg.Payload) {
+		return msg, nil
+	}
+	payload := make([]byte, encodedLen+2)
+	payload[0], payload[len(payload)-1] = '"', '"'
+	base64.StdEncoding.Encode(payload[1:len(payload)-1], buf.Bytes())
+
+	compressed := *msg
+	compressed.Payload = payload
+	compressed.Compressed = true
+	return &compressed, nil
+}
+
+// DecompressPayload restores the JSON payload of a compressed message in
+// place and clears Compressed. Other messages are left unchanged.
+func DecompressPayload(msg *Message) error {
+	if !msg.Compressed {
+		return nil
+	}
+	encoded := msg.Payload
+	if len(encoded) < 2 || encoded[0] != '"' || encoded[len(encoded)-1] != '"' {
+		return fmt.Errorf("invalid compressed payload: not a base64 string")
+	}
+	encoded = encoded[1 : len(encoded)-1]
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(data, encoded)
+	if err != nil {
+		return fmt.Errorf("invalid compressed payload: %w", err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data[:n]))
+	if err != nil {
+		return fmt.Errorf("invalid compressed payload: %w", err)
+	}
+	defer r.Close()
+	payload, err := io.ReadAll(io.LimitReader(r, int64(MaxMessageSize)+1))
+	if err != nil {
+		return fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	if len(payload) > MaxMessageSize {
+		return fmt.Errorf("decompressed payload exceeds %d bytes", MaxMessageSize)
+	}
+
+	msg.Payload = payload
+	msg.Compressed = false
+	return nil
+}
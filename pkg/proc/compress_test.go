@@ -0,0 +1,116 @@
+package proc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// cveBatchPayload builds a JSON payload resembling a batch of n CVEs
+func cveBatchPayload(n int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"vulnerabilities":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"cve":{"id":"CVE-2024-%05d","descriptions":[{"lang":"en","value":"A vulnerability in the component allows remote attackers to execute arbitrary code."}]}}`, i)
+	}
+	sb.WriteString(`]}`)
+	return []byte(sb.String())
+}
+
+func TestCompressPayload(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCompressPayload", nil, func(t *testing.T, tx *gorm.DB) {
+		payload := cveBatchPayload(2000)
+		msg := &Message{Type: MessageTypeResponse, ID: "RPCFetchCVEs", Payload: payload, Source: "remote", Target: "meta", CorrelationID: "corr-1"}
+
+		compressed, err := CompressPayload(msg, DefaultCompressThreshold)
+		if err != nil {
+			t.Fatalf("CompressPayload failed: %v", err)
+		}
+		if !compressed.Compressed || len(compressed.Payload) >= len(payload)/4 {
+			t.Fatalf("Expected a much smaller compressed payload, got %d of %d bytes", len(compressed.Payload), len(payload))
+		}
+		if msg.Compressed || string(msg.Payload) != string(payload) {
+			t.Error("Expected the original message to be left unchanged")
+		}
+		if compressed.Target != "meta" || compressed.CorrelationID != "corr-1" {
+			t.Errorf("Expected routing fields to be kept, got %+v", compressed)
+		}
+
+		// The compressed message survives the JSON wire format
+		data, err := jsonutil.Marshal(compressed)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		received, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		var result struct {
+			Vulnerabilities []interface{} `json:"vulnerabilities"`
+		}
+		if err := received.UnmarshalPayload(&result); err != nil {
+			t.Fatalf("UnmarshalPayload failed: %v", err)
+		}
+		if len(result.Vulnerabilities) != 2000 || received.Compressed || string(received.Payload) != string(payload) {
+			t.Errorf("Expected the payload restored, got %d CVEs", len(result.Vulnerabilities))
+		}
+	})
+}
+
+func TestCompressPayload_SentAsIs(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCompressPayload_SentAsIs", nil, func(t *testing.T, tx *gorm.DB) {
+		small := &Message{Type: MessageTypeRequest, ID: "RPCGetCVE", Payload: []byte(`{"cve_id":"CVE-2024-0001"}`)}
+		random := make([]byte, 2*DefaultCompressThreshold)
+		rand.Read(random)
+		incompressible := &Message{Type: MessageTypeResponse, ID: "RPCGetBlob", Payload: []byte(`"` + base64.StdEncoding.EncodeToString(random) + `"`)}
+		large := &Message{Type: MessageTypeResponse, ID: "RPCFetchCVEs", Payload: cveBatchPayload(2000)}
+
+		for name, tc := range map[string]struct {
+			msg       *Message
+			threshold int
+		}{
+			"below threshold":    {small, DefaultCompressThreshold},
+			"not smaller":        {incompressible, DefaultCompressThreshold},
+			"disabled":           {large, 0},
+			"already compressed": {&Message{Payload: large.Payload, Compressed: true}, 1},
+		} {
+			got, err := CompressPayload(tc.msg, tc.threshold)
+			if err != nil || got != tc.msg {
+				t.Errorf("%s: expected the message sent as is, got %+v %v", name, got, err)
+			}
+		}
+
+		// Decompressing an uncompressed message is a no-op
+		if err := DecompressPayload(small); err != nil || string(small.Payload) != `{"cve_id":"CVE-2024-0001"}` {
+			t.Errorf("Expected an uncompressed payload unchanged, got %s %v", small.Payload, err)
+		}
+	})
+}
+
+func TestDecompressPayload_Invalid(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestDecompressPayload_Invalid", nil, func(t *testing.T, tx *gorm.DB) {
+		for name, payload := range map[string]string{
+			"not a string": `{"a":1}`,
+			"not base64":   `"!!!"`,
+			"not gzip":     `"` + base64.StdEncoding.EncodeToString([]byte("plain")) + `"`,
+		} {
+			msg := &Message{Payload: []byte(payload), Compressed: true}
+			if err := DecompressPayload(msg); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			var v interface{}
+			if err := msg.UnmarshalPayload(&v); err == nil {
+				t.Errorf("%s: expected UnmarshalPayload to fail", name)
+			}
+		}
+	})
+}
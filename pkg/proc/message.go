@@ -20,8 +20,46 @@ const (
 	MessageTypeEvent MessageType = "event"
 	// MessageTypeError represents an error message
 	MessageTypeError MessageType = "error"
+	// MessageTypeFragment carries one ordered piece of a message that was
+	// too large to send in one line; receivers reassemble before dispatch
+	MessageTypeFragment MessageType = "fragment"
 )
 
+// MessagePriority selects the dispatch lane of a message in the broker
+type MessagePriority string
+
+const (
+	// MessagePriorityNormal is the lane of data-plane traffic. It is the
+	// zero value, so normal messages carry no priority on the wire.
+	MessagePriorityNormal MessagePriority = ""
+	// MessagePriorityHigh is the lane of control-plane messages, which the
+	// broker dispatches ahead of normal ones
+	MessagePriorityHigh MessagePriority = "high"
+)
+
+// highPriorityMethods are the control-plane RPCs sent with high priority:
+// health checks, cancellations, pauses and log level changes
+var highPriorityMethods = map[string]bool{
+	"RPCHealthCheck":       true,
+	"RPCPing":              true,
+	"RPCCancelRPC":         true,
+	"RPCPauseJob":          true,
+	"RPCPauseAllProviders": true,
+	"RPCPauseAnalysis":     true,
+	"RPCSSGPauseImportJob": true,
+	"RPCSetLogLevel":       true,
+	"RPCSetLogLevelAll":    true,
+}
+
+// PriorityForMethod returns the priority requests of an RPC method are sent
+// with
+func PriorityForMethod(method string) MessagePriority {
+	if highPriorityMethods[method] {
+		return MessagePriorityHigh
+	}
+	return MessagePriorityNormal
+}
+
 // MaxMessageSize is adjustable at runtime via configuration (default 50MB)
 // Increased to handle large SSG guides with many rules and references
 var MaxMessageSize = 50 * 1024 * 1024 // 50MB
@@ -49,6 +87,22 @@ type Message struct {
 	Target string `json:"target,omitempty"`
 	// CorrelationID is used to match responses to requests
 	CorrelationID string `json:"correlation_id,omitempty"`
+	// TraceID identifies the external request a message was sent on behalf
+	// of. It is assigned once by the access service and copied unchanged onto
+	// every request and response that follows from it, across broker hops,
+	// while each hop gets a fresh CorrelationID.
+	TraceID string `json:"trace_id,omitempty"`
+	// Compressed marks a payload compressed by CompressPayload; receivers
+	// restore it with DecompressPayload (see compress.go)
+	Compressed bool `json:"compressed,omitempty"`
+	// Priority is the dispatch lane in the broker. Requests get it from their method (see PriorityForMethod) and replies
+	// inherit it from the request.
+	Priority MessagePriority `json:"priority,omitempty"`
+}
+
+// IsHighPriority reports whether a message is dispatched in the high lane
+func (m *Message) IsHighPriority() bool {
+	return m.Priority == MessagePriorityHigh
 }
 
 // Simple message pool for reusing Message objects
@@ -69,6 +123,9 @@ func GetMessage() *Message {
 	msg.Source = ""
 	msg.Target = ""
 	msg.CorrelationID = ""
+	msg.TraceID = ""
+	msg.Compressed = false
+	msg.Priority = ""
 	return msg
 }
 
@@ -95,6 +152,7 @@ func NewRequestMessage(id string, payload interface{}) (*Message, error) {
 	msg := GetMessage()
 	msg.Type = MessageTypeRequest
 	msg.ID = id
+	msg.Priority = PriorityForMethod(id)
 	if payload != nil {
 		data, err := jsonutil.Marshal(payload)
 		if err != nil {
@@ -149,11 +207,15 @@ func NewErrorMessage(id string, err error) *Message {
 	return msg
 }
 
-// UnmarshalPayload unmarshals the message payload into the given value
+// UnmarshalPayload unmarshals the message payload into the given value,
+// decompressing it first if needed
 func (m *Message) UnmarshalPayload(v interface{}) error {
 	if m.Payload == nil {
 		return fmt.Errorf("no payload to unmarshal")
 	}
+	if err := DecompressPayload(m); err != nil {
+		return err
+	}
 	return jsonutil.Unmarshal(m.Payload, v)
 }
 
@@ -204,3 +204,54 @@ func BenchmarkMessageWithRouting(b *testing.B) {
 		_, _ = msg.Marshal()
 	}
 }
+
+// BenchmarkSendPayload_Small measures the cost of the compression check on
+// payloads below the threshold, which are sent as is
+func BenchmarkSendPayload_Small(b *testing.B) {
+	msg := &Message{Type: MessageTypeRequest, ID: "RPCGetCVE", Payload: []byte(`{"cve_id":"CVE-2024-0001"}`)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, _ := CompressPayload(msg, DefaultCompressThreshold)
+		_, _ = out.Marshal()
+	}
+}
+
+// BenchmarkSendPayload_LargeUncompressed marshals a 2000-CVE batch as is
+func BenchmarkSendPayload_LargeUncompressed(b *testing.B) {
+	msg := &Message{Type: MessageTypeResponse, ID: "RPCFetchCVEs", Payload: cveBatchPayload(2000)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _ := msg.Marshal()
+		b.SetBytes(int64(len(data)))
+	}
+}
+
+// BenchmarkSendPayload_LargeCompressed compresses and marshals a 2000-CVE
+// batch
+func BenchmarkSendPayload_LargeCompressed(b *testing.B) {
+	msg := &Message{Type: MessageTypeResponse, ID: "RPCFetchCVEs", Payload: cveBatchPayload(2000)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, _ := CompressPayload(msg, DefaultCompressThreshold)
+		data, _ := out.Marshal()
+		b.SetBytes(int64(len(data)))
+	}
+}
+
+// BenchmarkDecompressPayload restores a compressed 2000-CVE batch
+func BenchmarkDecompressPayload(b *testing.B) {
+	compressed, _ := CompressPayload(&Message{Payload: cveBatchPayload(2000)}, DefaultCompressThreshold)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg := *compressed
+		_ = DecompressPayload(&msg)
+	}
+}
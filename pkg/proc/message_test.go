@@ -411,3 +411,41 @@ func TestPutMessage(t *testing.T) {
 	})
 
 }
+
+func TestMessagePriority(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestMessagePriority", nil, func(t *testing.T, tx *gorm.DB) {
+		for _, method := range []string{"RPCHealthCheck", "RPCCancelRPC", "RPCPauseJob", "RPCPauseAllProviders"} {
+			if PriorityForMethod(method) != MessagePriorityHigh {
+				t.Errorf("Expected %s to be high priority", method)
+			}
+		}
+		if PriorityForMethod("RPCSaveCVEByID") != MessagePriorityNormal {
+			t.Error("Expected a data-plane method to be normal priority")
+		}
+
+		msg, err := NewRequestMessage("RPCPauseJob", nil)
+		if err != nil {
+			t.Fatalf("NewRequestMessage failed: %v", err)
+		}
+		if !msg.IsHighPriority() {
+			t.Errorf("Expected RPCPauseJob request to be high priority, got %q", msg.Priority)
+		}
+
+		// Normal messages carry no priority on the wire
+		msg, err = NewRequestMessage("RPCGetCVEByID", nil)
+		if err != nil {
+			t.Fatalf("NewRequestMessage failed: %v", err)
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if _, ok := fields["priority"]; ok {
+			t.Errorf("Expected no priority field on a normal message, got %s", data)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package proc
+
+import "time"
+
+// Typed payloads of the broker statistics RPCs. Counters are int64 on both
+// ends so that decoding a response never routes them through float64, which
+// silently rounds counts above 2^53.
+
+// MessageStats holds message counters, broker-wide or for one process
+type MessageStats struct {
+	TotalSent        int64     `json:"total_sent"`
+	TotalReceived    int64     `json:"total_received"`
+	RequestCount     int64     `json:"request_count"`
+	ResponseCount    int64     `json:"response_count"`
+	EventCount       int64     `json:"event_count"`
+	ErrorCount       int64     `json:"error_count"`
+	FirstMessageTime time.Time `json:"first_message_time"`
+	LastMessageTime  time.Time `json:"last_message_time"`
+}
+
+// WireStats holds transport-level counters of the messages the broker
+// exchanged with its subprocesses
+type WireStats struct {
+	TotalMessages        int64            `json:"total_messages"`
+	SentMessages         int64            `json:"sent_messages"`
+	ReceivedMessages     int64            `json:"received_messages"`
+	TotalWireBytes       int64            `json:"total_wire_bytes"`
+	EncodingDistribution map[string]int64 `json:"encoding_distribution"`
+}
+
+// TraceStats holds the message counters of one trace and the processes its
+// messages were sent to, in the order they were first reached
+type TraceStats struct {
+	MessageStats
+	Processes []string `json:"processes"`
+}
+
+// DedupStats holds the counters of the broker's duplicate request filter.
+// A Window of zero means the filter is disabled.
+type DedupStats struct {
+	Window  int   `json:"window"`
+	Dropped int64 `json:"dropped"`
+}
+
+// MessageStatsResponse is the payload of RPCGetMessageStats. Total and
+// PerProcess count the current broker session; Lifetime and
+// LifetimePerProcess add the sessions before it when the broker persists its
+// stats. PerTrace is only filled in when the request groups by trace.
+type MessageStatsResponse struct {
+	Total              MessageStats            `json:"total"`
+	PerProcess         map[string]MessageStats `json:"per_process"`
+	Lifetime           MessageStats            `json:"lifetime"`
+	LifetimePerProcess map[string]MessageStats `json:"lifetime_per_process"`
+	PerTrace           map[string]TraceStats   `json:"per_trace,omitempty"`
+	Wire               WireStats               `json:"wire"`
+	Dedup              DedupStats              `json:"dedup"`
+}
+
+// ProcessHealth is the outcome of pinging one process. LastError and
+// LastErrorAt describe the most recent failed ping, which may predate a
+// successful one.
+type ProcessHealth struct {
+	Alive       bool       `json:"alive"`
+	LatencyMs   float64    `json:"latency_ms"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// HealthCheckResponse is the payload of RPCHealthCheck, keyed by process ID.
+// Healthy is true when every process answered.
+type HealthCheckResponse struct {
+	Healthy   bool                     `json:"healthy"`
+	Processes map[string]ProcessHealth `json:"processes"`
+}
+
+// ProcessLogLevel is the outcome of changing the log level of one process.
+// Previous is its level before the change; Error is set when it failed.
+type ProcessLogLevel struct {
+	Previous string `json:"previous,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SetLogLevelAllResponse is the payload of RPCSetLogLevelAll, keyed by
+// process ID with the broker itself under "broker". Failed counts the
+// processes whose level could not be changed.
+type SetLogLevelAllResponse struct {
+	Level     string                     `json:"level"`
+	Processes map[string]ProcessLogLevel `json:"processes"`
+	Failed    int                        `json:"failed"`
+}
+
+// MessageCountResponse is the payload of RPCGetMessageCount
+type MessageCountResponse struct {
+	Count int64 `json:"count"`
+}
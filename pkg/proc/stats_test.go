@@ -0,0 +1,43 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestMessageStatsResponse_LargeCountsRoundTrip(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestMessageStatsResponse_LargeCountsRoundTrip", nil, func(t *testing.T, tx *gorm.DB) {
+		// 2^53 + 1 is the smallest integer float64 cannot represent
+		const large = int64(1)<<53 + 1
+		sent := MessageStatsResponse{
+			Total:      MessageStats{TotalSent: large, ErrorCount: large + 2},
+			PerProcess: map[string]MessageStats{"local": {TotalReceived: large}},
+			Wire:       WireStats{TotalMessages: large, EncodingDistribution: map[string]int64{"gob": large}},
+		}
+		msg, err := NewResponseMessage("RPCGetMessageStats", sent)
+		if err != nil {
+			t.Fatalf("NewResponseMessage failed: %v", err)
+		}
+
+		var got MessageStatsResponse
+		if err := msg.UnmarshalPayload(&got); err != nil {
+			t.Fatalf("UnmarshalPayload failed: %v", err)
+		}
+		if got.Total.TotalSent != large || got.Total.ErrorCount != large+2 ||
+			got.PerProcess["local"].TotalReceived != large ||
+			got.Wire.TotalMessages != large || got.Wire.EncodingDistribution["gob"] != large {
+			t.Errorf("Counts lost precision: got %+v", got)
+		}
+
+		// The untyped decoding this replaces rounds the same payload
+		var untyped map[string]interface{}
+		if err := msg.UnmarshalPayload(&untyped); err != nil {
+			t.Fatalf("UnmarshalPayload failed: %v", err)
+		}
+		if f := untyped["total"].(map[string]interface{})["total_sent"].(float64); int64(f) == large {
+			t.Errorf("Expected float64 decoding to round %d, got %v", large, f)
+		}
+	})
+}
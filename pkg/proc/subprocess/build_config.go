@@ -1,9 +1,14 @@
 package subprocess
 
+import "strconv"
+
 // These variables are injected at build time via ldflags
 var (
 	buildProcAutoExit    = "true" // Default auto-exit behavior
 	buildProcUDSBasePath = ""     // Default UDS base path
+	// Marshaled size in bytes above which outgoing messages are
+	// fragmented; 0 disables fragmentation
+	buildProcMaxFragmentSize = "8388608"
 )
 
 // DefaultProcAutoExit returns whether subprocesses should auto-exit when broker exits
@@ -24,3 +29,12 @@ func DefaultProcUDSBasePath() string {
 func DefaultProcCommType() string {
 	return "uds"
 }
+
+// DefaultProcMaxFragmentSize returns the marshaled message size above which
+// outgoing messages are fragmented, or 0 if fragmentation is disabled
+func DefaultProcMaxFragmentSize() int {
+	if val, err := strconv.Atoi(buildProcMaxFragmentSize); err == nil && val >= 0 {
+		return val
+	}
+	return 8 * 1024 * 1024
+}
@@ -0,0 +1,87 @@
+package subprocess
+
+import (
+	"context"
+	"fmt"
+)
+
+// RPCCancelRPC is the built-in RPC cancelling the context of a request this
+// subprocess is still handling. The broker sends it on behalf of the client
+// that gave up on the request (see the broker's RPCCancelRPC).
+const RPCCancelRPC = "RPCCancelRPC"
+
+// CancelRPCRequest is the payload of RPCCancelRPC
+type CancelRPCRequest struct {
+	CorrelationID string `json:"correlation_id"`
+}
+
+// CancelRPCResponse is the reply of RPCCancelRPC. Cancelled is false if no
+// request with that correlation ID was in flight, e.g. it already finished.
+type CancelRPCResponse struct {
+	Service       string `json:"service"`
+	CorrelationID string `json:"correlation_id"`
+	Cancelled     bool   `json:"cancelled"`
+}
+
+// inflightRequest is the cancel function of a request being handled
+type inflightRequest struct {
+	cancel context.CancelFunc
+}
+
+// requestContext derives the context a request is handled with. Requests
+// carrying a correlation ID get a context of their own, registered so
+// CancelRequest can cancel it; done must be called once the handler returns.
+func (s *Subprocess) requestContext(msg *Message) (ctx context.Context, done func()) {
+	if msg.Type != MessageTypeRequest || msg.CorrelationID == "" || msg.ID == RPCCancelRPC {
+		return s.ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	req := &inflightRequest{cancel: cancel}
+
+	s.inflightMu.Lock()
+	if s.inflight == nil {
+		s.inflight = make(map[string]*inflightRequest)
+	}
+	s.inflight[msg.CorrelationID] = req
+	s.inflightMu.Unlock()
+
+	return ctx, func() {
+		s.inflightMu.Lock()
+		// A duplicate correlation ID may have replaced the entry
+		if s.inflight[msg.CorrelationID] == req {
+			delete(s.inflight, msg.CorrelationID)
+		}
+		s.inflightMu.Unlock()
+		cancel()
+	}
+}
+
+// CancelRequest cancels the context of the in-flight request with the given
+// correlation ID. It reports whether such a request was found.
+func (s *Subprocess) CancelRequest(correlationID string) bool {
+	s.inflightMu.Lock()
+	req, ok := s.inflight[correlationID]
+	if ok {
+		delete(s.inflight, correlationID)
+	}
+	s.inflightMu.Unlock()
+	if ok {
+		req.cancel()
+	}
+	return ok
+}
+
+func (s *Subprocess) handleCancelRPC(ctx context.Context, msg *Message) (*Message, error) {
+	var req CancelRPCRequest
+	if err := UnmarshalPayload(msg, &req); err != nil {
+		return NewErrorResponse(msg, fmt.Sprintf("invalid parameters: %v", err)), nil
+	}
+	if errResp := RequireField(msg, req.CorrelationID, "correlation_id"); errResp != nil {
+		return errResp, nil
+	}
+	return NewSuccessResponse(msg, CancelRPCResponse{
+		Service:       s.ID,
+		CorrelationID: req.CorrelationID,
+		Cancelled:     s.CancelRequest(req.CorrelationID),
+	})
+}
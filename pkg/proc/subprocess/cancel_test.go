@@ -0,0 +1,104 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of handlers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func cancelCall(t *testing.T, sp *Subprocess, payload string) CancelRPCResponse {
+	t.Helper()
+	resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCCancelRPC, Payload: []byte(payload)})
+	if err != nil || resp.Type == MessageTypeError {
+		t.Fatalf("RPCCancelRPC failed: %v %+v", err, resp)
+	}
+	var out CancelRPCResponse
+	if err := UnmarshalPayload(resp, &out); err != nil {
+		t.Fatalf("Invalid RPCCancelRPC payload: %v", err)
+	}
+	return out
+}
+
+func TestCancelRPC_CancelsInFlightRequest(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCancelRPC_CancelsInFlightRequest", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("cancel")
+		output := &syncBuffer{}
+		sp.SetOutput(output)
+
+		started := make(chan struct{})
+		sp.RegisterHandler("RPCSlow", func(ctx context.Context, msg *Message) (*Message, error) {
+			close(started)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return NewSuccessResponse(msg, nil)
+			}
+		})
+
+		sp.wg.Add(1)
+		go sp.handleMessage(&Message{Type: MessageTypeRequest, ID: "RPCSlow", CorrelationID: "corr-1", Source: "client"})
+		<-started
+
+		if out := cancelCall(t, sp, `{"correlation_id": "corr-1"}`); !out.Cancelled || out.CorrelationID != "corr-1" || out.Service != "cancel" {
+			t.Fatalf("Expected corr-1 to be cancelled, got %+v", out)
+		}
+		done := make(chan struct{})
+		go func() {
+			sp.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("The cancelled handler did not return")
+		}
+		if got := output.String(); !strings.Contains(got, "context canceled") || !strings.Contains(got, "corr-1") {
+			t.Errorf("Expected a cancellation error for corr-1, got %s", got)
+		}
+
+		// The request finished, so there is nothing left to cancel
+		if out := cancelCall(t, sp, `{"correlation_id": "corr-1"}`); out.Cancelled {
+			t.Errorf("Expected a finished request not to be cancelled, got %+v", out)
+		}
+	})
+}
+
+func TestCancelRPC_Validation(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCancelRPC_Validation", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("cancel")
+		for _, payload := range []string{`{}`, `not json`} {
+			resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCCancelRPC, Payload: []byte(payload)})
+			if err != nil || resp.Type != MessageTypeError {
+				t.Errorf("Expected %s to be rejected, got %v %+v", payload, err, resp)
+			}
+		}
+		if sp.CancelRequest("unknown") {
+			t.Error("Expected an unknown correlation ID not to be cancelled")
+		}
+	})
+}
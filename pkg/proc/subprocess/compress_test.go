@@ -0,0 +1,91 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestCompress_RunRoundTrip(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCompress_RunRoundTrip", nil, func(t *testing.T, tx *gorm.DB) {
+		large := []byte(`{"text":"` + strings.Repeat("cve ", proc.DefaultCompressThreshold) + `"}`)
+		small := []byte(`{"text":"hello"}`)
+		compressedReq, err := proc.CompressPayload(&Message{Type: MessageTypeRequest, ID: "RPCEcho", Source: "broker", CorrelationID: "corr-large", Payload: large}, proc.DefaultCompressThreshold)
+		if err != nil || !compressedReq.Compressed {
+			t.Fatalf("CompressPayload = %+v, %v", compressedReq, err)
+		}
+
+		var input bytes.Buffer
+		for _, req := range []*Message{compressedReq, {Type: MessageTypeRequest, ID: "RPCEcho", Source: "broker", CorrelationID: "corr-small", Payload: small}} {
+			line, _ := MarshalFast(req)
+			input.Write(line)
+			input.WriteByte('\n')
+		}
+
+		sp := New("test")
+		output := &bytes.Buffer{}
+		sp.SetInput(&input)
+		sp.SetOutput(output)
+		sp.RegisterHandler("RPCEcho", func(ctx context.Context, msg *Message) (*Message, error) {
+			// The handler sees the decompressed payload
+			if msg.Compressed || msg.Payload[0] != '{' {
+				t.Errorf("Handler saw a compressed payload for %s", msg.CorrelationID)
+			}
+			return &Message{Type: MessageTypeResponse, ID: msg.ID, Payload: msg.Payload}, nil
+		})
+		if err := sp.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var responses []*Message
+		for _, msg := range sentLines(t, output) {
+			if msg.Type == MessageTypeResponse {
+				responses = append(responses, msg)
+			}
+		}
+		if len(responses) != 2 {
+			t.Fatalf("Expected 2 responses, got %d", len(responses))
+		}
+		for _, resp := range responses {
+			switch resp.CorrelationID {
+			case "corr-large":
+				if !resp.Compressed || len(resp.Payload) >= len(large) {
+					t.Errorf("Expected the large response compressed, got %d bytes", len(resp.Payload))
+				}
+				var v struct {
+					Text string `json:"text"`
+				}
+				if err := UnmarshalPayload(resp, &v); err != nil || len(v.Text) != proc.DefaultCompressThreshold*4 {
+					t.Errorf("Expected the large payload restored, got %d chars, %v", len(v.Text), err)
+				}
+			case "corr-small":
+				if resp.Compressed || string(resp.Payload) != string(small) {
+					t.Errorf("Expected the small response sent as is, got %s", resp.Payload)
+				}
+			}
+		}
+	})
+}
+
+func TestCompress_Disabled(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestCompress_Disabled", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("test")
+		output := &bytes.Buffer{}
+		sp.SetOutput(output)
+		sp.SetCompressThreshold(0)
+
+		payload := []byte(`{"text":"` + strings.Repeat("a", 2*proc.DefaultCompressThreshold) + `"}`)
+		if err := sp.SendMessage(&Message{Type: MessageTypeEvent, ID: "big", Payload: payload}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		sent := sentLines(t, output)
+		if len(sent) != 1 || sent[0].Compressed || len(sent[0].Payload) != len(payload) {
+			t.Error("Expected the payload sent uncompressed")
+		}
+	})
+}
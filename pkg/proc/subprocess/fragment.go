@@ -0,0 +1,214 @@
+package subprocess
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+)
+
+// Fragmentation splits a single large message into ordered MessageTypeFragment
+// messages so no single line on the wire exceeds the configured size. It is
+// independent of streaming: the producer still builds one complete response,
+// and the receiver reassembles it before dispatch.
+
+// FragmentPayload is the payload of a MessageTypeFragment message
+type FragmentPayload struct {
+	// FragmentID is shared by every fragment of one original message
+	FragmentID string `json:"fragment_id"`
+	// Seq is the zero-based position of this fragment
+	Seq int `json:"seq"`
+	// Total is the number of fragments making up the original message
+	Total int `json:"total"`
+	// Data is a slice of the marshaled original message
+	Data []byte `json:"data"`
+}
+
+// fragmentSeq generates process-unique fragment IDs
+var fragmentSeq uint64
+
+// FragmentMessage splits the marshaled form of msg into fragments carrying at
+// most maxSize bytes of data each. Routing fields are copied onto every
+// fragment so intermediaries can forward them without reassembling.
+// It returns nil when data already fits in maxSize or maxSize is disabled.
+func FragmentMessage(msg *Message, data []byte, maxSize int) ([]*Message, error) {
+	if maxSize <= 0 || len(data) <= maxSize {
+		return nil, nil
+	}
+
+	total := (len(data) + maxSize - 1) / maxSize
+	fragmentID := fmt.Sprintf("%s-%d", msg.Source, atomic.AddUint64(&fragmentSeq, 1))
+
+	fragments := make([]*Message, 0, total)
+	for seq := 0; seq < total; seq++ {
+		start := seq * maxSize
+		end := start + maxSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		payload, err := jsonutil.Marshal(&FragmentPayload{
+			FragmentID: fragmentID,
+			Seq:        seq,
+			Total:      total,
+			Data:       data[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fragment %d/%d: %w", seq+1, total, err)
+		}
+
+		fragments = append(fragments, &Message{
+			Type:          MessageTypeFragment,
+			ID:            msg.ID,
+			Payload:       payload,
+			Source:        msg.Source,
+			Target:        msg.Target,
+			CorrelationID: msg.CorrelationID,
+			TraceID:       msg.TraceID,
+		})
+	}
+
+	return fragments, nil
+}
+
+// partialMessage collects the fragments of one message as they arrive
+type partialMessage struct {
+	header    Message // routing fields of the first fragment seen
+	chunks    [][]byte
+	received  int
+	size      int
+	firstSeen time.Time
+}
+
+// Reassembler rebuilds fragmented messages. Fragments may arrive out of order;
+// a message whose fragments do not all arrive within the timeout is dropped
+// and reported by Expire.
+type Reassembler struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	pending map[string]*partialMessage
+	now     func() time.Time
+}
+
+// NewReassembler creates a Reassembler that gives up on incomplete messages
+// after timeout
+func NewReassembler(timeout time.Duration) *Reassembler {
+	if timeout <= 0 {
+		timeout = defaultFragmentTimeout
+	}
+	return &Reassembler{
+		timeout: timeout,
+		pending: make(map[string]*partialMessage),
+		now:     time.Now,
+	}
+}
+
+// Add records one fragment. It returns the reassembled message once every
+// fragment has arrived, nil while fragments are still outstanding, or an
+// error for a malformed or inconsistent fragment.
+func (r *Reassembler) Add(frag *Message) (*Message, error) {
+	if frag.Type != MessageTypeFragment {
+		return nil, fmt.Errorf("message %s is not a fragment", frag.ID)
+	}
+
+	var fp FragmentPayload
+	if err := jsonutil.Unmarshal(frag.Payload, &fp); err != nil {
+		return nil, fmt.Errorf("failed to parse fragment of %s: %w", frag.ID, err)
+	}
+	if fp.FragmentID == "" || fp.Total <= 0 || fp.Seq < 0 || fp.Seq >= fp.Total {
+		return nil, fmt.Errorf("invalid fragment %d/%d of %s", fp.Seq+1, fp.Total, frag.ID)
+	}
+
+	r.mu.Lock()
+	pm, ok := r.pending[fp.FragmentID]
+	if !ok {
+		pm = &partialMessage{
+			header:    *frag,
+			chunks:    make([][]byte, fp.Total),
+			firstSeen: r.now(),
+		}
+		pm.header.Payload = nil
+		r.pending[fp.FragmentID] = pm
+	}
+	if len(pm.chunks) != fp.Total {
+		delete(r.pending, fp.FragmentID)
+		r.mu.Unlock()
+		return nil, fmt.Errorf("fragment %s of %s: total changed from %d to %d", fp.FragmentID, frag.ID, len(pm.chunks), fp.Total)
+	}
+	if pm.chunks[fp.Seq] != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("duplicate fragment %d/%d of %s", fp.Seq+1, fp.Total, frag.ID)
+	}
+	pm.chunks[fp.Seq] = fp.Data
+	pm.received++
+	pm.size += len(fp.Data)
+	if pm.received < fp.Total {
+		r.mu.Unlock()
+		return nil, nil
+	}
+	delete(r.pending, fp.FragmentID)
+	r.mu.Unlock()
+
+	data := make([]byte, 0, pm.size)
+	for _, chunk := range pm.chunks {
+		data = append(data, chunk...)
+	}
+
+	var msg Message
+	if err := jsonutil.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode reassembled message %s: %w", frag.ID, err)
+	}
+	return &msg, nil
+}
+
+// Expire drops messages that have been incomplete for longer than the timeout.
+// For each one it returns an error message with the original routing fields,
+// so the caller can report the loss to whoever was waiting on it.
+func (r *Reassembler) Expire() []*Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []*Message
+	now := r.now()
+	for id, pm := range r.pending {
+		if now.Sub(pm.firstSeen) < r.timeout {
+			continue
+		}
+		delete(r.pending, id)
+
+		errMsg := pm.header
+		errMsg.Type = MessageTypeError
+		errMsg.Error = fmt.Sprintf("fragmented message %s incomplete: received %d/%d fragments within %v",
+			id, pm.received, len(pm.chunks), r.timeout)
+		expired = append(expired, &errMsg)
+	}
+	return expired
+}
+
+// Sweep runs Expire every interval until ctx is done and passes each dropped
+// message to report, so a message whose sender died mid-way is not held
+// until the next message happens to arrive
+func (r *Reassembler) Sweep(ctx context.Context, interval time.Duration, report func(lost *Message)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, lost := range r.Expire() {
+				report(lost)
+			}
+		}
+	}
+}
+
+// Pending returns the number of messages still being reassembled
+func (r *Reassembler) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
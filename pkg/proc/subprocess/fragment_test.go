@@ -0,0 +1,218 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// sentLines splits the output buffer of a subprocess into parsed messages
+func sentLines(t *testing.T, output *bytes.Buffer) []*Message {
+	t.Helper()
+	var msgs []*Message
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var msg Message
+		if err := UnmarshalFast([]byte(line), &msg); err != nil {
+			t.Fatalf("Failed to parse output line: %v", err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+func TestFragment_RoundTripMultiFragment(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_RoundTripMultiFragment", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("test")
+		output := &bytes.Buffer{}
+		sp.SetOutput(output)
+		sp.SetMaxFragmentSize(64)
+
+		payload := map[string]interface{}{"items": strings.Repeat("x", 500)}
+		if err := sp.SendResponse("RPCList", payload); err != nil {
+			t.Fatalf("SendResponse failed: %v", err)
+		}
+
+		frags := sentLines(t, output)
+		if len(frags) < 2 {
+			t.Fatalf("Expected multiple fragments, got %d", len(frags))
+		}
+		for _, f := range frags {
+			if f.Type != MessageTypeFragment || f.ID != "RPCList" {
+				t.Fatalf("Unexpected fragment header: type=%s id=%s", f.Type, f.ID)
+			}
+		}
+
+		// Deliver in reverse order to exercise out-of-order reassembly
+		r := NewReassembler(time.Second)
+		var complete *Message
+		for i := len(frags) - 1; i >= 0; i-- {
+			msg, err := r.Add(frags[i])
+			if err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			if msg != nil {
+				if i != 0 {
+					t.Fatalf("Message completed early at fragment %d", i)
+				}
+				complete = msg
+			}
+		}
+		if complete == nil {
+			t.Fatal("Message was not reassembled")
+		}
+		if complete.Type != MessageTypeResponse || complete.ID != "RPCList" {
+			t.Errorf("Reassembled header = %s/%s, want response/RPCList", complete.Type, complete.ID)
+		}
+
+		var got map[string]interface{}
+		if err := UnmarshalPayload(complete, &got); err != nil {
+			t.Fatalf("Failed to decode reassembled payload: %v", err)
+		}
+		if got["items"] != payload["items"] {
+			t.Error("Reassembled payload does not match original")
+		}
+		if r.Pending() != 0 {
+			t.Errorf("Pending = %d after completion, want 0", r.Pending())
+		}
+	})
+}
+
+func TestFragment_SmallMessageNotFragmented(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_SmallMessageNotFragmented", nil, func(t *testing.T, tx *gorm.DB) {
+		frags, err := FragmentMessage(&Message{Type: MessageTypeEvent, ID: "e"}, []byte(`{"id":"e"}`), 1024)
+		if err != nil {
+			t.Fatalf("FragmentMessage failed: %v", err)
+		}
+		if frags != nil {
+			t.Errorf("Expected no fragments, got %d", len(frags))
+		}
+	})
+}
+
+func TestFragment_MissingFragmentTimesOut(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_MissingFragmentTimesOut", nil, func(t *testing.T, tx *gorm.DB) {
+		msg := &Message{Type: MessageTypeResponse, ID: "RPCList", Source: "local", Target: "access", CorrelationID: "corr-1"}
+		frags, err := FragmentMessage(msg, []byte(`{"type":"response","id":"RPCList","payload":{"a":"bcdefghijklmnop"}}`), 16)
+		if err != nil || len(frags) < 3 {
+			t.Fatalf("FragmentMessage = %d fragments, err %v", len(frags), err)
+		}
+
+		now := time.Now()
+		r := NewReassembler(time.Second)
+		r.now = func() time.Time { return now }
+
+		// Drop the last fragment
+		for _, f := range frags[:len(frags)-1] {
+			if complete, err := r.Add(f); err != nil || complete != nil {
+				t.Fatalf("Add = %v, %v; want nil, nil", complete, err)
+			}
+		}
+		if expired := r.Expire(); len(expired) != 0 {
+			t.Fatalf("Expired before timeout: %d", len(expired))
+		}
+
+		now = now.Add(2 * time.Second)
+		expired := r.Expire()
+		if len(expired) != 1 {
+			t.Fatalf("Expected 1 expired message, got %d", len(expired))
+		}
+		lost := expired[0]
+		if lost.Type != MessageTypeError || lost.CorrelationID != "corr-1" || lost.Target != "access" {
+			t.Errorf("Expired message lost routing: %+v", lost)
+		}
+		if !strings.Contains(lost.Error, "incomplete") {
+			t.Errorf("Unexpected error text: %s", lost.Error)
+		}
+		if r.Pending() != 0 {
+			t.Errorf("Pending = %d after expiry, want 0", r.Pending())
+		}
+	})
+}
+
+func TestFragment_SweepReportsExpiredWithoutNewMessages(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_SweepReportsExpiredWithoutNewMessages", nil, func(t *testing.T, tx *gorm.DB) {
+		msg := &Message{Type: MessageTypeRequest, ID: "RPCList", Source: "access", Target: "local", CorrelationID: "corr-2"}
+		frags, _ := FragmentMessage(msg, []byte(`{"type":"request","id":"RPCList","payload":{"a":"bcdefghijklmnop"}}`), 16)
+		r := NewReassembler(10 * time.Millisecond)
+		if _, err := r.Add(frags[0]); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lostCh := make(chan *Message, 1)
+		go r.Sweep(ctx, 5*time.Millisecond, func(lost *Message) { lostCh <- lost })
+
+		select {
+		case lost := <-lostCh:
+			if lost.CorrelationID != "corr-2" {
+				t.Errorf("Unexpected lost message: %+v", lost)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Sweep did not report the incomplete message")
+		}
+	})
+}
+
+func TestFragment_DuplicateFragmentRejected(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_DuplicateFragmentRejected", nil, func(t *testing.T, tx *gorm.DB) {
+		frags, _ := FragmentMessage(&Message{ID: "x", Source: "s"}, []byte(`{"type":"event","id":"x"}`), 8)
+		r := NewReassembler(time.Second)
+		if _, err := r.Add(frags[0]); err != nil {
+			t.Fatalf("First add failed: %v", err)
+		}
+		if _, err := r.Add(frags[0]); err == nil {
+			t.Error("Expected error for duplicate fragment")
+		}
+	})
+}
+
+func TestFragment_RunDispatchesReassembledRequest(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFragment_RunDispatchesReassembledRequest", nil, func(t *testing.T, tx *gorm.DB) {
+		req := &Message{Type: MessageTypeRequest, ID: "RPCEcho", Source: "broker", Payload: []byte(`{"text":"` + strings.Repeat("y", 200) + `"}`)}
+		data, _ := MarshalFast(req)
+		frags, err := FragmentMessage(req, data, 50)
+		if err != nil {
+			t.Fatalf("FragmentMessage failed: %v", err)
+		}
+
+		var input bytes.Buffer
+		for _, f := range frags {
+			line, _ := MarshalFast(f)
+			input.Write(line)
+			input.WriteByte('\n')
+		}
+
+		sp := New("test")
+		output := &bytes.Buffer{}
+		sp.SetInput(&input)
+		sp.SetOutput(output)
+
+		received := make(chan int, 1)
+		sp.RegisterHandler("RPCEcho", func(ctx context.Context, msg *Message) (*Message, error) {
+			received <- len(msg.Payload)
+			return nil, nil
+		})
+
+		if err := sp.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		select {
+		case n := <-received:
+			if n != len(req.Payload) {
+				t.Errorf("Handler saw %d payload bytes, want %d", n, len(req.Payload))
+			}
+		default:
+			t.Fatal("Handler was not invoked with the reassembled request")
+		}
+	})
+}
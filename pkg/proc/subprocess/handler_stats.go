@@ -0,0 +1,179 @@
+package subprocess
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+)
+
+// Built-in RPCs served by every subprocess. They are answered even though no
+// service registers them, and a service may override them by registering a
+// handler under the same name.
+const (
+	RPCGetHandlerStats   = "RPCGetHandlerStats"
+	RPCResetHandlerStats = "RPCResetHandlerStats"
+	// RPCPing answers at once, for liveness checks (see the broker's
+	// RPCHealthCheck)
+	RPCPing = "RPCPing"
+)
+
+// HandlerStats is a snapshot of the call counters of one RPC method
+type HandlerStats struct {
+	Method        string     `json:"method"`
+	Calls         int64      `json:"calls"`
+	Errors        int64      `json:"errors"`
+	LastCalled    *time.Time `json:"last_called,omitempty"`
+	AvgDurationMs float64    `json:"avg_duration_ms"`
+}
+
+// HandlerStatsReport is the payload of RPCGetHandlerStats
+type HandlerStatsReport struct {
+	Service  string         `json:"service"`
+	Since    time.Time      `json:"since"`
+	Handlers []HandlerStats `json:"handlers"`
+}
+
+// handlerCounter accumulates the calls of one method. All fields are updated
+// atomically so recording a call never takes a lock on the dispatch path.
+type handlerCounter struct {
+	calls      atomic.Int64
+	errors     atomic.Int64
+	totalNanos atomic.Int64
+	lastCalled atomic.Int64 // Unix nanoseconds, 0 if never called
+}
+
+// handlerStats holds the counters of a subprocess, keyed by method
+type handlerStats struct {
+	counters sync.Map     // method -> *handlerCounter
+	since    atomic.Int64 // Unix nanoseconds of the last reset, 0 for start-up
+}
+
+// record adds one call of method that took d and failed if failed is set
+func (h *handlerStats) record(method string, start time.Time, d time.Duration, failed bool) {
+	v, ok := h.counters.Load(method)
+	if !ok {
+		v, _ = h.counters.LoadOrStore(method, &handlerCounter{})
+	}
+	c := v.(*handlerCounter)
+	c.calls.Add(1)
+	if failed {
+		c.errors.Add(1)
+	}
+	c.totalNanos.Add(int64(d))
+	c.lastCalled.Store(start.UnixNano())
+}
+
+// snapshot returns the counters of every method called since the last reset,
+// busiest first
+func (h *handlerStats) snapshot() []HandlerStats {
+	stats := make([]HandlerStats, 0)
+	h.counters.Range(func(k, v interface{}) bool {
+		c := v.(*handlerCounter)
+		s := HandlerStats{
+			Method: k.(string),
+			Calls:  c.calls.Load(),
+			Errors: c.errors.Load(),
+		}
+		if s.Calls > 0 {
+			s.AvgDurationMs = float64(c.totalNanos.Load()) / float64(s.Calls) / float64(time.Millisecond)
+		}
+		if last := c.lastCalled.Load(); last > 0 {
+			t := time.Unix(0, last)
+			s.LastCalled = &t
+		}
+		stats = append(stats, s)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Calls != stats[j].Calls {
+			return stats[i].Calls > stats[j].Calls
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// reset drops all counters
+func (h *handlerStats) reset() {
+	h.counters.Range(func(k, _ interface{}) bool {
+		h.counters.Delete(k)
+		return true
+	})
+	h.since.Store(time.Now().UnixNano())
+}
+
+// processStart is reported as the start of the counting window until the
+// first reset
+var processStart = time.Now()
+
+// HandlerStats returns the call counters of every RPC method this subprocess
+// has served since start-up or the last ResetHandlerStats
+func (s *Subprocess) HandlerStats() []HandlerStats {
+	return s.stats.snapshot()
+}
+
+// ResetHandlerStats clears the call counters
+func (s *Subprocess) ResetHandlerStats() {
+	s.stats.reset()
+}
+
+// builtinHandler returns the built-in handler for method, if there is one
+func (s *Subprocess) builtinHandler(method string) (Handler, bool) {
+	switch method {
+	case RPCGetHandlerStats:
+		return s.handleGetHandlerStats, true
+	case RPCResetHandlerStats:
+		return s.handleResetHandlerStats, true
+	case RPCTailLog:
+		return s.handleTailLog, true
+	case RPCPing:
+		return s.handlePing, true
+	case RPCCancelRPC:
+		return s.handleCancelRPC, true
+	case RPCSetLogLevel:
+		return s.handleSetLogLevel, true
+	}
+	return nil, false
+}
+
+func (s *Subprocess) handlePing(ctx context.Context, msg *Message) (*Message, error) {
+	return NewSuccessResponse(msg, map[string]interface{}{"service": s.ID})
+}
+
+func (s *Subprocess) handleGetHandlerStats(ctx context.Context, msg *Message) (*Message, error) {
+	since := processStart
+	if ns := s.stats.since.Load(); ns > 0 {
+		since = time.Unix(0, ns)
+	}
+	return NewSuccessResponse(msg, HandlerStatsReport{
+		Service:  s.ID,
+		Since:    since,
+		Handlers: s.HandlerStats(),
+	})
+}
+
+func (s *Subprocess) handleResetHandlerStats(ctx context.Context, msg *Message) (*Message, error) {
+	s.ResetHandlerStats()
+	return NewSuccessResponse(msg, map[string]interface{}{"service": s.ID, "reset": true})
+}
+
+// invokeHandler calls handler with the trace ID of msg in ctx, and records
+// the call against the requested method. Only requests are counted: responses and events routed to handlers
+// are not RPC calls, and the built-in stats and ping RPCs are left out so
+// polling them does not skew the numbers.
+func (s *Subprocess) invokeHandler(ctx context.Context, handler Handler, msg *Message) (*Message, error) {
+	ctx = proc.WithTraceID(ctx, msg.TraceID)
+	if msg.Type != MessageTypeRequest || msg.ID == RPCGetHandlerStats || msg.ID == RPCResetHandlerStats || msg.ID == RPCPing {
+		return handler(ctx, msg)
+	}
+
+	start := time.Now()
+	response, err := handler(ctx, msg)
+	failed := err != nil || (response != nil && response.Type == MessageTypeError)
+	s.stats.record(msg.ID, start, time.Since(start), failed)
+	return response, err
+}
@@ -0,0 +1,138 @@
+package subprocess
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestHandlerStats_CountsCallsAndErrors(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandlerStats_CountsCallsAndErrors", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("stats")
+		sp.RegisterHandler("RPCOk", func(ctx context.Context, msg *Message) (*Message, error) {
+			time.Sleep(2 * time.Millisecond)
+			return NewSuccessResponse(msg, nil)
+		})
+		sp.RegisterHandler("RPCFail", func(ctx context.Context, msg *Message) (*Message, error) {
+			return nil, errors.New("boom")
+		})
+		sp.RegisterHandler("RPCErrorResponse", func(ctx context.Context, msg *Message) (*Message, error) {
+			return NewErrorResponse(msg, "not found"), nil
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: "RPCOk"})
+			}()
+		}
+		wg.Wait()
+		sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: "RPCFail"})
+		sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: "RPCErrorResponse"})
+		// Events are not RPC calls
+		sp.HandleMessage(context.Background(), &Message{Type: MessageTypeEvent, ID: "RPCOk"})
+
+		stats := sp.HandlerStats()
+		if len(stats) != 3 {
+			t.Fatalf("Expected 3 methods, got %+v", stats)
+		}
+		if ok := stats[0]; ok.Method != "RPCOk" || ok.Calls != 10 || ok.Errors != 0 || ok.LastCalled == nil || ok.AvgDurationMs < 2 {
+			t.Errorf("Unexpected RPCOk stats %+v", ok)
+		}
+		for _, s := range stats[1:] {
+			if s.Calls != 1 || s.Errors != 1 {
+				t.Errorf("Expected %s to count one failed call, got %+v", s.Method, s)
+			}
+		}
+	})
+}
+
+func TestHandlerStats_BuiltinRPCs(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestHandlerStats_BuiltinRPCs", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("stats")
+		sp.RegisterHandler("RPCOk", func(ctx context.Context, msg *Message) (*Message, error) {
+			return NewSuccessResponse(msg, nil)
+		})
+		sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: "RPCOk"})
+
+		resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCGetHandlerStats})
+		if err != nil {
+			t.Fatalf("RPCGetHandlerStats failed: %v", err)
+		}
+		var report HandlerStatsReport
+		if err := UnmarshalPayload(resp, &report); err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		// The stats RPC itself is not counted
+		if report.Service != "stats" || len(report.Handlers) != 1 || report.Handlers[0].Method != "RPCOk" {
+			t.Errorf("Unexpected report %+v", report)
+		}
+
+		before := time.Now()
+		if _, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCResetHandlerStats}); err != nil {
+			t.Fatalf("RPCResetHandlerStats failed: %v", err)
+		}
+		resp, _ = sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCGetHandlerStats})
+		if err := UnmarshalPayload(resp, &report); err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		if len(report.Handlers) != 0 || report.Since.Before(before) {
+			t.Errorf("Expected empty report since the reset, got %+v", report)
+		}
+	})
+}
+
+func TestInvokeHandler_PropagatesTraceID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestInvokeHandler_PropagatesTraceID", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("trace")
+		var seen string
+		sp.RegisterHandler("RPCOk", func(ctx context.Context, msg *Message) (*Message, error) {
+			seen = proc.TraceIDFromContext(ctx)
+			return NewSuccessResponse(msg, nil)
+		})
+
+		req := &Message{Type: MessageTypeRequest, ID: "RPCOk", TraceID: "trace-1"}
+		resp, err := sp.HandleMessage(context.Background(), req)
+		if err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+		if seen != "trace-1" {
+			t.Errorf("Expected the handler context to carry trace-1, got %q", seen)
+		}
+		if resp.TraceID != "trace-1" {
+			t.Errorf("Expected the response to carry trace-1, got %q", resp.TraceID)
+		}
+		if errResp := sp.newErrorResponse(req, "boom"); errResp.TraceID != "trace-1" {
+			t.Errorf("Expected the error response to carry trace-1, got %q", errResp.TraceID)
+		}
+	})
+}
+
+func TestPing_Builtin(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestPing_Builtin", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("pinged")
+		resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCPing, CorrelationID: "corr-1"})
+		if err != nil {
+			t.Fatalf("RPCPing failed: %v", err)
+		}
+		var result map[string]string
+		if err := UnmarshalPayload(resp, &result); err != nil {
+			t.Fatalf("Failed to parse ping response: %v", err)
+		}
+		if resp.Type != MessageTypeResponse || resp.CorrelationID != "corr-1" || result["service"] != "pinged" {
+			t.Errorf("Unexpected ping response %+v %v", resp, result)
+		}
+		// Pings are not counted in the handler stats
+		if stats := sp.HandlerStats(); len(stats) != 0 {
+			t.Errorf("Expected no handler stats after a ping, got %+v", stats)
+		}
+	})
+}
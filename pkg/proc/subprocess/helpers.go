@@ -3,7 +3,7 @@ package subprocess
 import "fmt"
 
 // NewErrorResponse creates an error response message from a request message.
-// It sets Type to MessageTypeError, copies the ID and CorrelationID from the original message,
+// It sets Type to MessageTypeError, copies the ID, CorrelationID and TraceID from the original message,
 // sets the Error field to errMsg, and sets Target to the original message's Source.
 func NewErrorResponse(msg *Message, errMsg string) *Message {
 	return &Message{
@@ -11,7 +11,9 @@ func NewErrorResponse(msg *Message, errMsg string) *Message {
 		ID:            msg.ID,
 		Error:         errMsg,
 		CorrelationID: msg.CorrelationID,
+		TraceID:       msg.TraceID,
 		Target:        msg.Source,
+		Priority:      msg.Priority,
 	}
 }
 
@@ -25,12 +27,14 @@ func NewErrorResponseWithPrefix(msg *Message, prefix, errMsg string) *Message {
 		ID:            msg.ID,
 		Error:         prefixedErrMsg,
 		CorrelationID: msg.CorrelationID,
+		TraceID:       msg.TraceID,
 		Target:        msg.Source,
+		Priority:      msg.Priority,
 	}
 }
 
 // NewSuccessResponse creates a success response message from a request message.
-// It sets Type to MessageTypeResponse, copies the ID and CorrelationID from the original message,
+// It sets Type to MessageTypeResponse, copies the ID, CorrelationID and TraceID from the original message,
 // marshals the result as the Payload, sets Target to the original message's Source,
 // and sets Source to the original message's Target.
 // Returns an error if marshaling the result fails.
@@ -39,7 +43,9 @@ func NewSuccessResponse(msg *Message, result interface{}) (*Message, error) {
 		Type:          MessageTypeResponse,
 		ID:            msg.ID,
 		CorrelationID: msg.CorrelationID,
+		TraceID:       msg.TraceID,
 		Target:        msg.Source,
+		Priority:      msg.Priority,
 		Source:        msg.Target,
 	}
 
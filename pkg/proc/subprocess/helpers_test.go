@@ -701,3 +701,18 @@ func TestRequireField(t *testing.T) {
 
 }
 
+
+func TestResponsesInheritPriority(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestResponsesInheritPriority", nil, func(t *testing.T, tx *gorm.DB) {
+		req := &Message{Type: MessageTypeRequest, ID: "RPCPing", Source: "broker", Priority: MessagePriorityHigh}
+		resp, err := NewSuccessResponse(req, nil)
+		if err != nil {
+			t.Fatalf("NewSuccessResponse failed: %v", err)
+		}
+		for _, m := range []*Message{resp, NewErrorResponse(req, "failed"), NewErrorResponseWithPrefix(req, "local", "failed")} {
+			if !m.IsHighPriority() {
+				t.Errorf("Expected %s reply to keep high priority, got %q", m.Type, m.Priority)
+			}
+		}
+	})
+}
@@ -46,11 +46,11 @@ func RunWithDefaults(sp *Subprocess, logger *common.Logger) {
 				os.Exit(1)
 			}
 		}
-	case <-sigChan:
 	case <-sigChan:
 		if logger != nil {
 			logger.Info("Signal received, shutting down...")
 		}
+		sp.runShutdownHooks()
 		sp.SendEvent("subprocess_shutdown", map[string]string{
 			"id":     sp.ID,
 			"reason": "signal received",
@@ -58,3 +58,23 @@ func RunWithDefaults(sp *Subprocess, logger *common.Logger) {
 		sp.Stop()
 	}
 }
+
+// OnShutdown registers a function RunWithDefaults calls when a signal is
+// received, before the subprocess stops. The broker is still reachable, so
+// hooks can make RPCs, e.g. to persist progress. Hooks run in registration
+// order.
+func (s *Subprocess) OnShutdown(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, fn)
+}
+
+// runShutdownHooks calls the functions registered with OnShutdown
+func (s *Subprocess) runShutdownHooks() {
+	s.mu.RLock()
+	hooks := append([]func(){}, s.shutdownHooks...)
+	s.mu.RUnlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
@@ -0,0 +1,75 @@
+package subprocess
+
+import (
+	"context"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+)
+
+// RPCSetLogLevel is the built-in RPC changing the log level of a subprocess
+// at runtime, e.g. to debug an incident without a rebuild or restart
+const RPCSetLogLevel = "RPCSetLogLevel"
+
+// SetLogLevelRequest is the payload of RPCSetLogLevel
+type SetLogLevelRequest struct {
+	// Level is debug, info, warn or error
+	Level string `json:"level"`
+}
+
+// SetLogLevelResponse is the reply of RPCSetLogLevel
+type SetLogLevelResponse struct {
+	Service  string `json:"service"`
+	Level    string `json:"level"`
+	Previous string `json:"previous"`
+}
+
+// SetLogger sets the logger RPCSetLogLevel adjusts along with the default
+// logger. StandardStartup sets it to the logger it returns.
+func (s *Subprocess) SetLogger(logger *common.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = logger
+}
+
+// SetLogLevel sets the level of the subprocess's logger, if any, and of the
+// default logger, and returns the previous level
+func (s *Subprocess) SetLogLevel(level common.LogLevel) common.LogLevel {
+	s.mu.RLock()
+	logger := s.logger
+	s.mu.RUnlock()
+
+	previous := common.GetLevel()
+	if logger != nil {
+		previous = logger.GetLevel()
+		logger.SetLevel(level)
+	}
+	common.SetLevel(level)
+	return previous
+}
+
+func (s *Subprocess) handleSetLogLevel(ctx context.Context, msg *Message) (*Message, error) {
+	var req SetLogLevelRequest
+	if errResp := ParseRequest(msg, &req); errResp != nil {
+		return errResp, nil
+	}
+	if errResp := RequireField(msg, req.Level, "level"); errResp != nil {
+		return errResp, nil
+	}
+	level, err := common.ParseLogLevel(req.Level)
+	if err != nil {
+		return NewErrorResponse(msg, err.Error()), nil
+	}
+
+	previous := s.SetLogLevel(level)
+	s.mu.RLock()
+	logger := s.logger
+	s.mu.RUnlock()
+	if logger != nil {
+		logger.Info("Log level changed from %s to %s", previous, level)
+	}
+	return NewSuccessResponse(msg, SetLogLevelResponse{
+		Service:  s.ID,
+		Level:    level.String(),
+		Previous: previous.String(),
+	})
+}
@@ -0,0 +1,58 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestSetLogLevel_Builtin(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSetLogLevel_Builtin", nil, func(t *testing.T, tx *gorm.DB) {
+		defer common.SetLevel(common.GetLevel())
+
+		var out bytes.Buffer
+		logger := common.NewLogger(&out, "", common.InfoLevel)
+		sp := New("levels")
+		sp.SetLogger(logger)
+
+		setLevel := func(payload string) *Message {
+			resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCSetLogLevel, Payload: []byte(payload)})
+			if err != nil {
+				t.Fatalf("RPCSetLogLevel failed: %v", err)
+			}
+			return resp
+		}
+
+		var result SetLogLevelResponse
+		if err := UnmarshalPayload(setLevel(`{"level": "debug"}`), &result); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if result.Service != "levels" || result.Level != "DEBUG" || result.Previous != "INFO" {
+			t.Errorf("Unexpected response %+v", result)
+		}
+		if logger.GetLevel() != common.DebugLevel || common.GetLevel() != common.DebugLevel {
+			t.Errorf("Expected both loggers at DEBUG, got %s and %s", logger.GetLevel(), common.GetLevel())
+		}
+		logger.Debug("now visible")
+		if !strings.Contains(out.String(), "now visible") {
+			t.Error("Expected debug messages to be logged after the change")
+		}
+
+		for payload, want := range map[string]string{
+			`{"level": "trace"}`: "unknown log level",
+			`{}`:                 "level is required",
+		} {
+			if resp := setLevel(payload); resp.Type != MessageTypeError || !strings.Contains(resp.Error, want) {
+				t.Errorf("Expected %q for %s, got %+v", want, payload, resp)
+			}
+		}
+		if logger.GetLevel() != common.DebugLevel {
+			t.Errorf("Expected a rejected level to leave DEBUG, got %s", logger.GetLevel())
+		}
+	})
+}
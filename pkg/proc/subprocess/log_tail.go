@@ -0,0 +1,199 @@
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RPCTailLog is the built-in RPC returning the last lines of a subprocess's
+// own log file
+const RPCTailLog = "RPCTailLog"
+
+const (
+	// DefaultTailLogLines is the number of lines RPCTailLog returns by default
+	DefaultTailLogLines = 100
+	// MaxTailLogLines caps the number of lines of one RPCTailLog call
+	MaxTailLogLines = 10000
+
+	tailChunkSize = 64 * 1024
+	// maxFollowRead bounds the bytes one follow-mode call reads
+	maxFollowRead = 4 * 1024 * 1024
+)
+
+// TailLogRequest is the payload of RPCTailLog. With Offset set (follow mode)
+// the lines appended since that byte offset are returned instead of the last
+// Lines lines; pass the Offset of the previous response to poll for new lines.
+type TailLogRequest struct {
+	Lines  int    `json:"lines,omitempty"`
+	Offset *int64 `json:"offset,omitempty"`
+}
+
+// TailLogResponse is the reply of RPCTailLog
+type TailLogResponse struct {
+	Service string   `json:"service"`
+	Path    string   `json:"path"`
+	Lines   []string `json:"lines"`
+	// Offset is the byte offset the returned lines end at, to continue from
+	Offset int64 `json:"offset"`
+	// Rotated is set in follow mode when the file is now smaller than the
+	// requested offset, i.e. it was rotated or truncated and is read anew
+	Rotated bool `json:"rotated,omitempty"`
+}
+
+// LogFilePath returns the log file of a process in logsDir
+func LogFilePath(logsDir, processID string) string {
+	return filepath.Join(logsDir, fmt.Sprintf("%s.log", processID))
+}
+
+// SetLogFile sets the log file RPCTailLog reads. It defaults to the
+// process's file in the build-time log directory.
+func (s *Subprocess) SetLogFile(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logFile = path
+}
+
+// LogFile returns the log file RPCTailLog reads
+func (s *Subprocess) LogFile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.logFile != "" {
+		return s.logFile
+	}
+	return LogFilePath(DefaultBuildLogDir(), s.ID)
+}
+
+func (s *Subprocess) handleTailLog(ctx context.Context, msg *Message) (*Message, error) {
+	var req TailLogRequest
+	if len(msg.Payload) > 0 {
+		if err := UnmarshalPayload(msg, &req); err != nil {
+			return NewErrorResponse(msg, fmt.Sprintf("invalid parameters: %v", err)), nil
+		}
+	}
+	if req.Lines <= 0 {
+		req.Lines = DefaultTailLogLines
+	}
+	if req.Lines > MaxTailLogLines {
+		req.Lines = MaxTailLogLines
+	}
+
+	path := s.LogFile()
+	resp := TailLogResponse{Service: s.ID, Path: path}
+	var err error
+	if req.Offset != nil {
+		resp.Lines, resp.Offset, resp.Rotated, err = readLogFrom(path, *req.Offset, req.Lines)
+	} else {
+		resp.Lines, resp.Offset, err = tailLog(path, req.Lines)
+	}
+	if err != nil {
+		return NewErrorResponse(msg, fmt.Sprintf("failed to read log file: %v", err)), nil
+	}
+	return NewSuccessResponse(msg, resp)
+}
+
+// tailLog returns the last n complete lines of the file at path and the
+// offset they end at. The file is opened on every call, so after a rotation
+// the current file is read.
+func tailLog(path string, n int) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+
+	// Read chunks backwards until n+1 newlines are seen; the extra one marks
+	// the start of the first wanted line
+	var data []byte
+	pos := size
+	for pos > 0 && bytes.Count(data, []byte{'\n'}) <= n {
+		chunk := int64(tailChunkSize)
+		if chunk > pos {
+			chunk = pos
+		}
+		pos -= chunk
+		buf := make([]byte, chunk)
+		if _, err := f.ReadAt(buf, pos); err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		data = append(buf, data...)
+	}
+
+	// A line still being written is left for follow mode to pick up
+	end := bytes.LastIndexByte(data, '\n') + 1
+	data = data[:end]
+
+	lines := splitLogLines(data)
+	if pos > 0 && len(lines) > 0 {
+		// The first line may have been cut by the chunk boundary
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, pos + int64(end), nil
+}
+
+// readLogFrom returns up to max complete lines written after offset and the
+// offset they end at. A partial last line is left for the next call.
+func readLogFrom(path string, offset int64, max int) (lines []string, next int64, rotated bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if offset < 0 || offset > info.Size() {
+		offset, rotated = 0, offset > info.Size()
+	}
+
+	n := info.Size() - offset
+	if n > maxFollowRead {
+		n = maxFollowRead
+	}
+	data := make([]byte, n)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, 0, false, err
+	}
+
+	// Only consume complete lines
+	end := bytes.LastIndexByte(data, '\n') + 1
+	data = data[:end]
+	lines = splitLogLines(data)
+	if len(lines) > max {
+		// Hand out the first max lines and continue after them next time
+		consumed := 0
+		for _, line := range lines[:max] {
+			consumed += len(line) + 1
+		}
+		lines, end = lines[:max], consumed
+	}
+	return lines, offset + int64(end), rotated, nil
+}
+
+// splitLogLines splits data into lines, dropping a trailing empty line
+func splitLogLines(data []byte) []string {
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	if len(data) == 0 {
+		return []string{}
+	}
+	parts := bytes.Split(data, []byte{'\n'})
+	lines := make([]string, len(parts))
+	for i, p := range parts {
+		lines[i] = string(p)
+	}
+	return lines
+}
@@ -0,0 +1,97 @@
+package subprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func tailLogCall(t *testing.T, sp *Subprocess, payload string) TailLogResponse {
+	t.Helper()
+	resp, err := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCTailLog, Payload: []byte(payload)})
+	if err != nil || resp.Type == MessageTypeError {
+		t.Fatalf("RPCTailLog failed: %v %+v", err, resp)
+	}
+	var out TailLogResponse
+	if err := UnmarshalPayload(resp, &out); err != nil {
+		t.Fatalf("Invalid RPCTailLog payload: %v", err)
+	}
+	return out
+}
+
+func TestTailLog_LastLines(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestTailLog_LastLines", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "tail.log")
+		var sb strings.Builder
+		// Enough lines to span several read chunks
+		for i := 0; i < 20000; i++ {
+			fmt.Fprintf(&sb, "line %d\n", i)
+		}
+		sb.WriteString("partial")
+		os.WriteFile(path, []byte(sb.String()), 0644)
+
+		sp := New("tail")
+		sp.SetLogFile(path)
+		out := tailLogCall(t, sp, `{"lines": 3}`)
+		if strings.Join(out.Lines, ",") != "line 19997,line 19998,line 19999" {
+			t.Errorf("Unexpected lines %q", out.Lines)
+		}
+		if out.Service != "tail" || out.Offset != int64(sb.Len()-len("partial")) {
+			t.Errorf("Expected the offset to stop before the partial line, got %+v", out)
+		}
+
+		if out := tailLogCall(t, sp, `{"lines": 15000}`); len(out.Lines) != MaxTailLogLines || out.Lines[0] != "line 10000" {
+			t.Errorf("Expected the line cap to apply, got %d lines from %q", len(out.Lines), out.Lines[0])
+		}
+	})
+}
+
+func TestTailLog_FollowAndRotation(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestTailLog_FollowAndRotation", nil, func(t *testing.T, tx *gorm.DB) {
+		path := filepath.Join(t.TempDir(), "follow.log")
+		os.WriteFile(path, []byte("one\ntwo\n"), 0644)
+		sp := New("follow")
+		sp.SetLogFile(path)
+
+		out := tailLogCall(t, sp, `{}`)
+		if len(out.Lines) != 2 || out.Offset != 8 {
+			t.Fatalf("Unexpected tail %+v", out)
+		}
+
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString("three\nfour\nfi")
+		f.Close()
+		out = tailLogCall(t, sp, fmt.Sprintf(`{"offset": %d, "lines": 1}`, out.Offset))
+		if strings.Join(out.Lines, ",") != "three" || out.Offset != 14 {
+			t.Fatalf("Expected one new line, got %+v", out)
+		}
+		out = tailLogCall(t, sp, fmt.Sprintf(`{"offset": %d}`, out.Offset))
+		if strings.Join(out.Lines, ",") != "four" || out.Rotated {
+			t.Fatalf("Expected the next complete line, got %+v", out)
+		}
+
+		// Rotation replaces the file with a smaller one
+		os.WriteFile(path, []byte("fresh\n"), 0644)
+		out = tailLogCall(t, sp, fmt.Sprintf(`{"offset": %d}`, out.Offset))
+		if !out.Rotated || strings.Join(out.Lines, ",") != "fresh" || out.Offset != 6 {
+			t.Errorf("Expected the rotated file to be read anew, got %+v", out)
+		}
+	})
+}
+
+func TestTailLog_MissingFile(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestTailLog_MissingFile", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("missing")
+		sp.SetLogFile(filepath.Join(t.TempDir(), "none.log"))
+		resp, _ := sp.HandleMessage(context.Background(), &Message{Type: MessageTypeRequest, ID: RPCTailLog})
+		if resp.Type != MessageTypeError || !strings.Contains(resp.Error, "failed to read log file") {
+			t.Errorf("Expected an error response, got %+v", resp)
+		}
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
 )
@@ -27,7 +26,7 @@ func SetupLogging(processID string, logsDir string, logLevel common.LogLevel) (*
 	}
 
 	// Create log file path
-	logFile := filepath.Join(logsDir, fmt.Sprintf("%s.log", processID))
+	logFile := LogFilePath(logsDir, processID)
 
 	// Open log file
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 )
 
 // sonicFast is a shared instance of sonic configured for fastest parsing/marshalling.
@@ -18,6 +19,13 @@ func UnmarshalFast(data []byte, v interface{}) error {
 	return jsonutil.Unmarshal(data, v)
 }
 
+// UnmarshalUseNumber unmarshals data like UnmarshalFast, but numbers decoded
+// into interface{} values become json.Number so large integers are kept exact
+// when a payload is passed through without a typed struct.
+func UnmarshalUseNumber(data []byte, v interface{}) error {
+	return jsonutil.UnmarshalUseNumber(data, v)
+}
+
 // batchJoinPool provides temporary buffers for joining batched messages
 // to avoid allocating large temporary slices on each flush.
 var batchJoinPool = sync.Pool{
@@ -37,4 +45,18 @@ var (
 	// zeroCopyThreshold is the minimum payload size (bytes) to attempt
 	// a zero-copy direct-write path (only used when batching is disabled)
 	zeroCopyThreshold = 4 * 1024 // 4KB
+
+	// defaultMaxFragmentSize is the marshaled size above which outgoing
+	// messages are fragmented; 0 disables fragmentation. It comes from
+	// CONFIG_PROC_MAX_FRAGMENT_SIZE.
+	defaultMaxFragmentSize = DefaultProcMaxFragmentSize()
+	// defaultCompressThreshold is the payload size from which outgoing
+	// payloads are compressed; 0 disables compression
+	defaultCompressThreshold = proc.DefaultCompressThreshold
+	// defaultFragmentTimeout bounds how long an incomplete fragmented
+	// message is kept before it is reported as lost
+	defaultFragmentTimeout = 30 * time.Second
+	// fragmentSweepInterval is how often incomplete fragmented messages
+	// are checked for expiry while no message arrives
+	fragmentSweepInterval = 5 * time.Second
 )
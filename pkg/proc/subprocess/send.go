@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 )
 
 // SendMessage sends a message to the broker via stdout
@@ -21,12 +22,46 @@ func (s *Subprocess) sendMessage(msg *Message) error {
 	if msg.Source == "" {
 		msg.Source = s.ID
 	}
+
+	s.mu.RLock()
+	maxFragmentSize := s.maxFragmentSize
+	compressThreshold := s.compressThreshold
+	s.mu.RUnlock()
+
+	// Compress large payloads; msg itself is left as the caller built it
+	msg, err := proc.CompressPayload(msg, compressThreshold)
+	if err != nil {
+		return err
+	}
 	// Use shared fast marshal helper for performance
 	data, err := jsonutil.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	// Split oversized messages into ordered fragments
+	fragments, err := FragmentMessage(msg, data, maxFragmentSize)
+	if err != nil {
+		return err
+	}
+	if fragments == nil {
+		return s.writeData(data)
+	}
+	for _, frag := range fragments {
+		fragData, err := jsonutil.Marshal(frag)
+		if err != nil {
+			return fmt.Errorf("failed to marshal fragment: %w", err)
+		}
+		if err := s.writeData(fragData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeData writes one marshaled message line, either directly or through
+// the batching writer
+func (s *Subprocess) writeData(data []byte) error {
 	// If batching is disabled (for tests), write directly
 	if s.disableBatching {
 		// Zero-copy optimization: if payload is large, avoid extra copies by
@@ -42,6 +42,16 @@ func (s *Subprocess) Run() error {
 	buf := *bufPtr
 	scanner.Buffer(buf, proc.MaxMessageSize)
 
+	s.mu.Lock()
+	if s.reassembler == nil {
+		s.reassembler = NewReassembler(defaultFragmentTimeout)
+	}
+	reassembler := s.reassembler
+	s.mu.Unlock()
+	go reassembler.Sweep(s.ctx, fragmentSweepInterval, func(lost *Message) {
+		_ = s.sendMessage(s.newErrorResponse(lost, lost.Error))
+	})
+
 	for scanner.Scan() {
 		select {
 		case <-s.ctx.Done():
@@ -69,6 +79,33 @@ func (s *Subprocess) Run() error {
 			continue
 		}
 
+		// Report messages whose remaining fragments never arrived
+		if reassembler.Pending() > 0 {
+			for _, lost := range reassembler.Expire() {
+				_ = s.sendMessage(s.newErrorResponse(lost, lost.Error))
+			}
+		}
+
+		// Hold fragments until the whole message is available
+		if msg.Type == MessageTypeFragment {
+			complete, err := reassembler.Add(&msg)
+			if err != nil {
+				_ = s.sendMessage(s.newErrorResponse(&msg, err.Error()))
+				continue
+			}
+			if complete == nil {
+				continue
+			}
+			msg = *complete
+		}
+
+		// Restore a compressed payload, so handlers and RPC callers reading
+		// Payload directly see JSON
+		if err := proc.DecompressPayload(&msg); err != nil {
+			_ = s.sendMessage(s.newErrorResponse(&msg, err.Error()))
+			continue
+		}
+
 		// Process the message
 		s.wg.Add(1)
 		go s.handleMessage(&msg)
@@ -111,13 +148,16 @@ func (s *Subprocess) lookupHandler(msg *Message) (Handler, bool) {
 			// Fallback to type-based lookup
 			handler, exists = s.handlers[string(msg.Type)]
 		}
+		if !exists && msg.Type == MessageTypeRequest {
+			handler, exists = s.builtinHandler(msg.ID)
+		}
 	}
 
 	return handler, exists
 }
 
 // newErrorResponse creates an error message response for a given request message.
-// It copies CorrelationID from the original message and sets Target to the original Source.
+// It copies CorrelationID and TraceID from the original message and sets Target to the original Source.
 func (s *Subprocess) newErrorResponse(originalMsg *Message, errMsg string) *Message {
 	return &Message{
 		Type:          MessageTypeError,
@@ -125,6 +165,7 @@ func (s *Subprocess) newErrorResponse(originalMsg *Message, errMsg string) *Mess
 		Error:         errMsg,
 		Source:        s.ID,
 		CorrelationID: originalMsg.CorrelationID,
+		TraceID:       originalMsg.TraceID,
 		Target:        originalMsg.Source,
 	}
 }
@@ -141,8 +182,10 @@ func (s *Subprocess) handleMessage(msg *Message) {
 		return
 	}
 
-	// Call the handler
-	response, err := handler(s.ctx, msg)
+	// Call the handler, with a context RPCCancelRPC can cancel
+	ctx, done := s.requestContext(msg)
+	defer done()
+	response, err := s.invokeHandler(ctx, handler, msg)
 	if err != nil {
 		// Send error response
 		errMsg := s.newErrorResponse(msg, err.Error())
@@ -156,6 +199,9 @@ func (s *Subprocess) handleMessage(msg *Message) {
 		if response.CorrelationID == "" {
 			response.CorrelationID = msg.CorrelationID
 		}
+		if response.TraceID == "" {
+			response.TraceID = msg.TraceID
+		}
 		if response.Target == "" {
 			response.Target = msg.Source
 		}
@@ -173,7 +219,7 @@ func (s *Subprocess) HandleMessage(ctx context.Context, msg *Message) (*Message,
 		return nil, fmt.Errorf("no handler found for message: %s", msg.ID)
 	}
 
-	return handler(ctx, msg)
+	return s.invokeHandler(ctx, handler, msg)
 }
 
 // messageWriter and flushBatch have been moved to writer.go to improve
@@ -215,6 +261,7 @@ func StandardStartup(config StandardStartupConfig) (*Subprocess, *common.Logger)
 	// Construct deterministic socket path so broker and subprocess agree without env vars
 	socketPath := fmt.Sprintf("%s_%s.sock", DefaultProcUDSBasePath(), processID)
 	sp := NewWithUDS(processID, socketPath)
+	sp.SetLogger(logger)
 
 	logger.Info("%sSubprocess created with ID: %s", config.LogPrefix, processID)
 
@@ -231,11 +278,14 @@ func StandardStartup(config StandardStartupConfig) (*Subprocess, *common.Logger)
 func NewWithUDS(id string, socketPath string) *Subprocess {
 	ctx, cancel := context.WithCancel(context.Background())
 	sp := &Subprocess{
-		ID:       id,
-		handlers: make(map[string]Handler),
-		ctx:      ctx,
-		cancel:   cancel,
-		outChan:  make(chan []byte, defaultOutChanBufSize),
+		ID:                id,
+		handlers:          make(map[string]Handler),
+		ctx:               ctx,
+		cancel:            cancel,
+		outChan:           make(chan []byte, defaultOutChanBufSize),
+		maxFragmentSize:   defaultMaxFragmentSize,
+		reassembler:       NewReassembler(defaultFragmentTimeout),
+		compressThreshold: defaultCompressThreshold,
 	}
 
 	// Retry logic: 3 attempts with exponential backoff (100ms, 200ms, 400ms)
@@ -280,6 +330,40 @@ func NewWithUDS(id string, socketPath string) *Subprocess {
 	return sp
 }
 
+// DialUDS creates a new Subprocess connected to the Unix Domain Socket at
+// socketPath. Unlike NewWithUDS it makes a single attempt and returns the
+// error instead of exiting, so callers can reconnect with their own backoff.
+func DialUDS(id string, socketPath string) (*Subprocess, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sp := &Subprocess{
+		ID:                id,
+		handlers:          make(map[string]Handler),
+		ctx:               ctx,
+		cancel:            cancel,
+		outChan:           make(chan []byte, defaultOutChanBufSize),
+		maxFragmentSize:   defaultMaxFragmentSize,
+		reassembler:       NewReassembler(defaultFragmentTimeout),
+		compressThreshold: defaultCompressThreshold,
+		input:             conn,
+		output:            conn,
+	}
+	return sp, nil
+}
+
+// CloseTransport closes the socket of a UDS-backed subprocess, releasing it
+// once Run has returned. It is a no-op for other transports.
+func (s *Subprocess) CloseTransport() error {
+	if conn, ok := s.input.(net.Conn); ok {
+		return conn.Close()
+	}
+	return nil
+}
+
 // startAutoExitMonitor is intentionally lightweight and does not rely on
 // runtime environment variables (like BROKER_PID). The preferred mechanism
 // for subprocess shutdown is transport EOF detection (the main Run loop
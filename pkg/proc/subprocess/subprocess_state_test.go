@@ -316,3 +316,18 @@ func TestMessage_MarshalUnmarshalEdgeCases(t *testing.T) {
 	})
 
 }
+
+// TestSubprocess_ShutdownHooks verifies hooks run once each, in registration order.
+func TestSubprocess_ShutdownHooks(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSubprocess_ShutdownHooks", nil, func(t *testing.T, tx *gorm.DB) {
+		sp := New("hooks")
+		var order []int
+		sp.OnShutdown(func() { order = append(order, 1) })
+		sp.OnShutdown(func() { order = append(order, 2) })
+
+		sp.runShutdownHooks()
+		if fmt.Sprint(order) != "[1 2]" {
+			t.Fatalf("expected hooks to run in order [1 2], got %v", order)
+		}
+	})
+}
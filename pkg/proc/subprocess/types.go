@@ -6,13 +6,16 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/cyw0ng95/v2e/pkg/common"
 	"github.com/cyw0ng95/v2e/pkg/proc"
 )
 
 // Re-export Message and MessageType from parent proc package
 type Message = proc.Message
 type MessageType = proc.MessageType
+type MessagePriority = proc.MessagePriority
 type Handler func(ctx context.Context, msg *Message) (*Message, error)
 
 // Re-export MessageType constants for convenience
@@ -21,6 +24,13 @@ const (
 	MessageTypeResponse = proc.MessageTypeResponse
 	MessageTypeEvent    = proc.MessageTypeEvent
 	MessageTypeError    = proc.MessageTypeError
+	MessageTypeFragment = proc.MessageTypeFragment
+)
+
+// Re-export MessagePriority constants for convenience
+const (
+	MessagePriorityNormal = proc.MessagePriorityNormal
+	MessagePriorityHigh   = proc.MessagePriorityHigh
 )
 
 // bufferPool is a sync.Pool for scanner buffers to reduce allocations
@@ -72,19 +82,50 @@ type Subprocess struct {
 
 	// disableBatching disables message batching (for tests)
 	disableBatching bool
+
+	// maxFragmentSize is the marshaled size above which outgoing messages
+	// are split into fragments (0 disables fragmentation)
+	maxFragmentSize int
+
+	// compressThreshold is the payload size from which outgoing payloads
+	// are compressed (0 disables compression)
+	compressThreshold int
+
+	// reassembler rebuilds incoming fragmented messages before dispatch
+	reassembler *Reassembler
+
+	// stats counts calls per RPC method (see handler_stats.go)
+	stats handlerStats
+
+	// logFile is the log file RPCTailLog reads (see log_tail.go)
+	logFile string
+
+	// logger is the logger RPCSetLogLevel adjusts (see log_level.go)
+	logger *common.Logger
+
+	// shutdownHooks run on signal-triggered shutdown (see lifecycle.go)
+	shutdownHooks []func()
+
+	// inflight holds the cancel functions of the requests being handled,
+	// keyed by correlation ID (see cancel.go)
+	inflight   map[string]*inflightRequest
+	inflightMu sync.Mutex
 }
 
 // New creates a new Subprocess instance using Stdin/Stdout
 func New(id string) *Subprocess {
 	ctx, cancel := context.WithCancel(context.Background())
 	sp := &Subprocess{
-		ID:       id,
-		handlers: make(map[string]Handler),
-		ctx:      ctx,
-		cancel:   cancel,
-		outChan:  make(chan []byte, defaultOutChanBufSize), // Optimized buffer size (Principle 12)
-		input:    os.Stdin,
-		output:   os.Stdout,
+		ID:                id,
+		handlers:          make(map[string]Handler),
+		ctx:               ctx,
+		cancel:            cancel,
+		outChan:           make(chan []byte, defaultOutChanBufSize), // Optimized buffer size (Principle 12)
+		input:             os.Stdin,
+		output:            os.Stdout,
+		maxFragmentSize:   defaultMaxFragmentSize,
+		reassembler:       NewReassembler(defaultFragmentTimeout),
+		compressThreshold: defaultCompressThreshold,
 	}
 	return sp
 }
@@ -105,6 +146,30 @@ func (s *Subprocess) SetOutput(w io.Writer) {
 	s.disableBatching = true
 }
 
+// SetMaxFragmentSize sets the marshaled message size above which outgoing
+// messages are split into fragments. A value <= 0 disables fragmentation.
+func (s *Subprocess) SetMaxFragmentSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxFragmentSize = size
+}
+
+// SetCompressThreshold sets the payload size in bytes from which outgoing
+// payloads are gzip-compressed. A value <= 0 disables compression.
+func (s *Subprocess) SetCompressThreshold(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compressThreshold = size
+}
+
+// SetFragmentTimeout sets how long an incoming fragmented message may stay
+// incomplete before it is dropped and reported as an error
+func (s *Subprocess) SetFragmentTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reassembler = NewReassembler(timeout)
+}
+
 // RegisterHandler registers a handler for a specific message type or pattern
 func (s *Subprocess) RegisterHandler(pattern string, handler Handler) {
 	s.mu.Lock()
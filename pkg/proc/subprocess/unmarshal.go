@@ -4,12 +4,17 @@ import (
 	"fmt"
 
 	"github.com/cyw0ng95/v2e/pkg/jsonutil"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 )
 
-// UnmarshalPayload is a helper to unmarshal message payload
+// UnmarshalPayload is a helper to unmarshal message payload. A compressed
+// payload is decompressed in place first.
 func UnmarshalPayload(msg *Message, v interface{}) error {
 	if msg.Payload == nil {
 		return fmt.Errorf("no payload to unmarshal")
 	}
+	if err := proc.DecompressPayload(msg); err != nil {
+		return err
+	}
 	return jsonutil.Unmarshal(msg.Payload, v)
 }
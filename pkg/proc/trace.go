@@ -0,0 +1,38 @@
+package proc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// TraceIDHeader is the HTTP header carrying the trace ID of a request
+const TraceIDHeader = "X-Trace-ID"
+
+// traceIDKey is the context key of the trace ID
+type traceIDKey struct{}
+
+// NewTraceID returns a random trace ID of 32 hex digits
+func NewTraceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithTraceID returns a context carrying traceID. RPCs invoked with it are
+// tagged with the trace ID. An empty traceID leaves ctx unchanged.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx, or "" if none
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
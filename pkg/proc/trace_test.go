@@ -0,0 +1,40 @@
+package proc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+func TestTraceID_Context(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestTraceID_Context", nil, func(t *testing.T, tx *gorm.DB) {
+		ctx := context.Background()
+		if got := TraceIDFromContext(ctx); got != "" {
+			t.Errorf("Expected no trace ID, got %q", got)
+		}
+		if WithTraceID(ctx, "") != ctx {
+			t.Error("Expected an empty trace ID to leave the context unchanged")
+		}
+		if got := TraceIDFromContext(WithTraceID(ctx, "abc")); got != "abc" {
+			t.Errorf("Expected trace ID abc, got %q", got)
+		}
+
+		a, b := NewTraceID(), NewTraceID()
+		if len(a) != 32 || a == b {
+			t.Errorf("Expected distinct 32-digit trace IDs, got %q and %q", a, b)
+		}
+	})
+}
+
+func TestMessage_GetMessageResetsTraceID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestMessage_GetMessageResetsTraceID", nil, func(t *testing.T, tx *gorm.DB) {
+		msg := GetMessage()
+		msg.TraceID = "abc"
+		PutMessage(msg)
+		if got := GetMessage(); got.TraceID != "" {
+			t.Errorf("Expected a pooled message without trace ID, got %q", got.TraceID)
+		}
+	})
+}
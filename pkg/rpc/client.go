@@ -2,11 +2,13 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
@@ -19,10 +21,19 @@ func GetDefaultTimeout() time.Duration {
 	return common.DefaultRPCTimeout
 }
 
+// MaxRPCTimeout is the longest per-call timeout InvokeRPCWithTimeout accepts
+const MaxRPCTimeout = common.MaxRPCTimeout
+
+// ErrConnectionReset is returned for requests that were in flight when the
+// connection to the broker was lost. The request may or may not have been
+// handled, so callers retry only what is safe to repeat.
+var ErrConnectionReset = errors.New("connection reset")
+
 // RequestEntry represents a pending request in the RPC client
 type RequestEntry struct {
 	resp chan *subprocess.Message
 	once sync.Once
+	err  error // set by Fail before resp is closed
 }
 
 // Signal signals the request entry with a message
@@ -40,6 +51,14 @@ func (e *RequestEntry) Close() {
 	})
 }
 
+// Fail closes the request entry, making the waiting caller return err
+func (e *RequestEntry) Fail(err error) {
+	e.once.Do(func() {
+		e.err = err
+		close(e.resp)
+	})
+}
+
 // Client handles RPC communication with other services through the broker
 type Client struct {
 	sp              *subprocess.Subprocess
@@ -48,15 +67,26 @@ type Client struct {
 	correlationSeq  uint64
 	rpcTimeout      time.Duration
 	logger          *common.Logger
+	// abandoned holds the correlation IDs of requests given up on and
+	// cancelled, whose late replies are dropped quietly (see cancelRemote)
+	abandoned map[string]struct{}
+	// retry is the policy for requests the broker could not deliver
+	retry RetryPolicy
 }
 
-// NewClient creates a new RPC client for inter-service communication
-func NewClient(sp *subprocess.Subprocess, logger *common.Logger, rpcTimeout time.Duration) *Client {
+// NewClient creates a new RPC client for inter-service communication. Reads
+// the broker could not deliver are retried with DefaultRetryPolicy unless
+// WithRetryPolicy is given.
+func NewClient(sp *subprocess.Subprocess, logger *common.Logger, rpcTimeout time.Duration, opts ...ClientOption) *Client {
 	client := &Client{
 		sp:              sp,
 		pendingRequests: make(map[string]*RequestEntry),
 		rpcTimeout:      rpcTimeout,
 		logger:          logger,
+		retry:           DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(client)
 	}
 
 	// Register handlers for response and error messages
@@ -79,6 +109,8 @@ func (c *Client) HandleResponse(ctx context.Context, msg *subprocess.Message) (*
 	if entry != nil {
 		c.logger.Debug("Found pending request for correlation ID: %s, signaling response", msg.CorrelationID)
 		entry.Signal(msg)
+	} else if c.forgetAbandoned(msg.CorrelationID) {
+		c.logger.Debug("Dropping reply to abandoned request: correlationID=%s, type=%s", msg.CorrelationID, msg.Type)
 	} else {
 		c.logger.Warn("Received response for unknown correlation ID: %s, type=%s, target=%s", msg.CorrelationID, msg.Type, msg.Target)
 	}
@@ -93,14 +125,60 @@ func (c *Client) HandleError(ctx context.Context, msg *subprocess.Message) (*sub
 	return c.HandleResponse(ctx, msg)
 }
 
-// InvokeRPC invokes an RPC method on another service through the broker
-func (c *Client) InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
-	// Generate correlation ID
+// FailPending fails every in-flight request with err and returns how many
+// were failed. Use it when the transport is lost and no response can arrive.
+func (c *Client) FailPending(err error) int {
 	c.mu.Lock()
-	c.correlationSeq++
-	correlationID := fmt.Sprintf("rpc-%s-%d-%d", c.sp.ID, time.Now().UnixNano(), c.correlationSeq)
+	pending := c.pendingRequests
+	c.pendingRequests = make(map[string]*RequestEntry)
 	c.mu.Unlock()
 
+	for _, entry := range pending {
+		entry.Fail(err)
+	}
+	return len(pending)
+}
+
+// InvokeRPC invokes an RPC method on another service through the broker.
+// The request carries the trace ID of ctx, if any (see proc.WithTraceID).
+// If ctx is done or the call times out before the response arrives, the
+// request is cancelled at the target through the broker's RPCCancelRPC.
+func (c *Client) InvokeRPC(ctx context.Context, target, method string, params interface{}) (*subprocess.Message, error) {
+	return c.InvokeRPCWithTimeout(ctx, target, method, params, 0)
+}
+
+// InvokeRPCWithTimeout is InvokeRPC with the call timing out after timeout
+// instead of the client's RPC timeout, e.g. a long fetch that needs minutes.
+// Zero uses the client's timeout; a negative timeout or one above
+// MaxRPCTimeout is an error. The timeout applies to each attempt when the
+// request is retried (see RetryPolicy).
+func (c *Client) InvokeRPCWithTimeout(ctx context.Context, target, method string, params interface{}, timeout time.Duration) (*subprocess.Message, error) {
+	if timeout < 0 || timeout > MaxRPCTimeout {
+		return nil, fmt.Errorf("invalid RPC timeout %v (must be 0 to %v)", timeout, MaxRPCTimeout)
+	}
+	if timeout == 0 {
+		timeout = c.rpcTimeout
+	}
+
+	for retry := 0; ; retry++ {
+		resp, err := c.invoke(ctx, target, method, params, timeout)
+		if err != nil || retry >= c.retry.MaxRetries || !IsRoutingError(resp) || !c.retry.retries(method) {
+			return resp, err
+		}
+		delay := c.retry.delay(retry)
+		c.logger.Warn("RPC request not delivered, retrying in %v (%d/%d): method=%s, target=%s, error: %s", delay, retry+1, c.retry.MaxRetries, method, target, resp.Error)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// invoke sends one request and waits for its response
+func (c *Client) invoke(ctx context.Context, target, method string, params interface{}, timeout time.Duration) (*subprocess.Message, error) {
+	correlationID := c.nextCorrelationID()
+
 	// Create response channel and entry
 	resp := make(chan *subprocess.Message, 1)
 	entry := &RequestEntry{resp: resp}
@@ -134,10 +212,12 @@ func (c *Client) InvokeRPC(ctx context.Context, target, method string, params in
 		Payload:       payload,
 		Target:        target,
 		CorrelationID: correlationID,
+		TraceID:       proc.TraceIDFromContext(ctx),
 		Source:        c.sp.ID,
+		Priority:      proc.PriorityForMethod(method),
 	}
 
-	c.logger.Debug("Sending RPC request: method=%s, target=%s, correlationID=%s", method, target, correlationID)
+	c.logger.Debug("Sending RPC request: method=%s, target=%s, correlationID=%s, traceID=%s", method, target, correlationID, msg.TraceID)
 
 	// Send request to broker (which will route to target)
 	if err := c.sp.SendMessage(msg); err != nil {
@@ -149,14 +229,87 @@ func (c *Client) InvokeRPC(ctx context.Context, target, method string, params in
 	c.logger.Debug("Waiting for RPC response: method=%s, target=%s, correlationID=%s", method, target, correlationID)
 	select {
 	case response := <-resp:
+		if response == nil {
+			c.logger.Warn("RPC request failed while waiting for response: method=%s, target=%s, correlationID=%s, error: %v", method, target, correlationID, entry.err)
+			return nil, entry.err
+		}
 		c.logger.Debug("Received RPC response: correlationID=%s, type=%s", correlationID, response.Type)
 		return response, nil
-	case <-time.After(c.rpcTimeout):
-		c.logger.Warn("RPC timeout waiting for response: method=%s, target=%s, correlationID=%s", method, target, correlationID)
+	case <-time.After(timeout):
+		c.logger.Warn("RPC timeout waiting for response: method=%s, target=%s, correlationID=%s, timeout=%v", method, target, correlationID, timeout)
+		c.cancelRemote(target, correlationID, msg.TraceID)
 		return nil, fmt.Errorf("RPC timeout waiting for response from %s", target)
 	case <-ctx.Done():
 		err := ctx.Err()
 		c.logger.Warn("RPC call context canceled while waiting for response: method=%s, target=%s, correlationID=%s, error: %v", method, target, correlationID, err)
+		c.cancelRemote(target, correlationID, msg.TraceID)
 		return nil, err
 	}
 }
+
+// nextCorrelationID returns a new correlation ID for a request of this client
+func (c *Client) nextCorrelationID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.correlationSeq++
+	return fmt.Sprintf(CorrelationIDFormat, c.sp.ID, time.Now().UnixNano(), c.correlationSeq)
+}
+
+// cancelRemote asks the broker to cancel the request with correlationID the
+// caller gave up on, so its target stops working on it. It does not wait for
+// the outcome: the replies to the cancel and to the abandoned request are
+// dropped when they arrive. Requests to the broker itself are not cancelled.
+func (c *Client) cancelRemote(target, correlationID, traceID string) {
+	if target == "broker" {
+		return
+	}
+	payload, err := subprocess.MarshalFast(subprocess.CancelRPCRequest{CorrelationID: correlationID})
+	if err != nil {
+		c.logger.Warn("Failed to marshal RPCCancelRPC for correlationID=%s: %v", correlationID, err)
+		return
+	}
+	cancelID := c.nextCorrelationID()
+	c.abandon(correlationID)
+	c.abandon(cancelID)
+
+	msg := &subprocess.Message{
+		Type:          subprocess.MessageTypeRequest,
+		ID:            subprocess.RPCCancelRPC,
+		Payload:       payload,
+		Target:        "broker",
+		CorrelationID: cancelID,
+		TraceID:       traceID,
+		Source:        c.sp.ID,
+		Priority:      subprocess.MessagePriorityHigh,
+	}
+	if err := c.sp.SendMessage(msg); err != nil {
+		c.logger.Warn("Failed to send RPCCancelRPC for correlationID=%s: %v", correlationID, err)
+		return
+	}
+	c.logger.Debug("Requested cancellation of correlationID=%s at %s", correlationID, target)
+}
+
+// abandon marks the replies to correlationID as expected but unwanted, for
+// one RPC timeout
+func (c *Client) abandon(correlationID string) {
+	c.mu.Lock()
+	if c.abandoned == nil {
+		c.abandoned = make(map[string]struct{})
+	}
+	c.abandoned[correlationID] = struct{}{}
+	c.mu.Unlock()
+
+	time.AfterFunc(c.rpcTimeout, func() {
+		c.forgetAbandoned(correlationID)
+	})
+}
+
+// forgetAbandoned removes correlationID from the abandoned requests and
+// reports whether it was one
+func (c *Client) forgetAbandoned(correlationID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.abandoned[correlationID]
+	delete(c.abandoned, correlationID)
+	return ok
+}
@@ -1,14 +1,19 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/cyw0ng95/v2e/pkg/testutils"
 	"gorm.io/gorm"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc"
 	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
 )
 
@@ -288,6 +293,153 @@ func TestInvokeRPC_ContextCanceled(t *testing.T) {
 	}
 }
 
+func TestInvokeRPC_CarriesTraceID(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestInvokeRPC_CarriesTraceID", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		sp := subprocess.New("test-service")
+		var out bytes.Buffer
+		sp.SetOutput(&out)
+		client := NewClient(sp, logger, 50*time.Millisecond)
+
+		ctx := proc.WithTraceID(context.Background(), "trace-1")
+		if _, err := client.InvokeRPC(ctx, "local", "RPCGetCVE", nil); err == nil {
+			t.Fatal("Expected a timeout without a broker")
+		}
+		// The request is followed by the RPCCancelRPC sent on timeout
+		var sent subprocess.Message
+		if err := json.NewDecoder(&out).Decode(&sent); err != nil {
+			t.Fatalf("Failed to decode the sent request %q: %v", out.String(), err)
+		}
+		if sent.TraceID != "trace-1" {
+			t.Errorf("Expected the request to carry trace-1, got %q", sent.TraceID)
+		}
+	})
+}
+
+func TestInvokeRPC_CancelsAbandonedRequest(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestInvokeRPC_CancelsAbandonedRequest", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		sp := subprocess.New("test-service")
+		var out bytes.Buffer
+		sp.SetOutput(&out)
+		client := NewClient(sp, logger, 10*time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := client.InvokeRPC(ctx, "remote", "RPCFetchCVEs", nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the context deadline, got %v", err)
+		}
+
+		dec := json.NewDecoder(&out)
+		var req, cancelReq subprocess.Message
+		if err := dec.Decode(&req); err != nil {
+			t.Fatalf("Failed to decode the request: %v", err)
+		}
+		if err := dec.Decode(&cancelReq); err != nil {
+			t.Fatalf("Expected an RPCCancelRPC after the request: %v", err)
+		}
+		var params subprocess.CancelRPCRequest
+		json.Unmarshal(cancelReq.Payload, &params)
+		if cancelReq.ID != subprocess.RPCCancelRPC || cancelReq.Target != "broker" || params.CorrelationID != req.CorrelationID {
+			t.Fatalf("Expected RPCCancelRPC of %s to the broker, got %+v", req.CorrelationID, cancelReq)
+		}
+
+		// The late replies are dropped once each
+		for _, id := range []string{req.CorrelationID, cancelReq.CorrelationID} {
+			if !client.forgetAbandoned(id) {
+				t.Errorf("Expected %s to be abandoned", id)
+			}
+		}
+		if client.forgetAbandoned(req.CorrelationID) {
+			t.Error("Expected an abandoned request to be forgotten after its reply")
+		}
+
+		// Requests to the broker itself are not cancelled
+		out.Reset()
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		client.InvokeRPC(ctx, "broker", "RPCGetMessageStats", nil)
+		if n := bytes.Count(bytes.TrimSpace(out.Bytes()), []byte("\n")); n != 0 {
+			t.Errorf("Expected only the request sent to the broker, got %q", out.String())
+		}
+	})
+}
+
+func TestInvokeRPCWithTimeout(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestInvokeRPCWithTimeout", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		sp := subprocess.New("test-service")
+		var out bytes.Buffer
+		sp.SetOutput(&out)
+		client := NewClient(sp, logger, 10*time.Second)
+
+		// The call timeout overrides the client's
+		start := time.Now()
+		if _, err := client.InvokeRPCWithTimeout(context.Background(), "remote", "RPCGetCVECnt", nil, 30*time.Millisecond); err == nil || !strings.Contains(err.Error(), "RPC timeout") {
+			t.Fatalf("Expected an RPC timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Expected the call to time out after 30ms, took %v", elapsed)
+		}
+
+		out.Reset()
+		for _, timeout := range []time.Duration{-time.Second, MaxRPCTimeout + time.Second} {
+			if _, err := client.InvokeRPCWithTimeout(context.Background(), "remote", "RPCFetchCVEs", nil, timeout); err == nil {
+				t.Errorf("Expected timeout %v to be rejected", timeout)
+			}
+		}
+		if out.Len() != 0 {
+			t.Errorf("Expected no request sent with an invalid timeout, got %q", out.String())
+		}
+	})
+}
+
+func TestFailPending(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestFailPending", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		sp := subprocess.New("test-service")
+		sp.SetOutput(io.Discard)
+		client := NewClient(sp, logger, 10*time.Second)
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := client.InvokeRPC(context.Background(), "broker", "TestMethod", nil)
+			errCh <- err
+		}()
+
+		// Wait for the request to be in flight
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			client.mu.RLock()
+			n := len(client.pendingRequests)
+			client.mu.RUnlock()
+			if n == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("request never became pending")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if failed := client.FailPending(ErrConnectionReset); failed != 1 {
+			t.Errorf("FailPending() = %d, want 1", failed)
+		}
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, ErrConnectionReset) {
+				t.Errorf("InvokeRPC() error = %v, want ErrConnectionReset", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("InvokeRPC() did not return after FailPending")
+		}
+
+		if failed := client.FailPending(ErrConnectionReset); failed != 0 {
+			t.Errorf("FailPending() with nothing in flight = %d, want 0", failed)
+		}
+	})
+}
+
 func BenchmarkHandleResponse(b *testing.B) {
 	logger := common.NewLogger(nil, "", common.InfoLevel)
 	sp := subprocess.New("test-service")
@@ -1,6 +1,10 @@
 package rpc
 
-import "github.com/cyw0ng95/v2e/pkg/cve"
+import (
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/cve"
+)
 
 // FetchCVEsParams are the typed parameters for RPCFetchCVEs
 type FetchCVEsParams struct {
@@ -8,11 +12,49 @@ type FetchCVEsParams struct {
 	ResultsPerPage int `json:"results_per_page"`
 }
 
+// FetchCVEsModifiedParams are the typed parameters for RPCFetchCVEsModified.
+// A zero LastModEndDate means now.
+type FetchCVEsModifiedParams struct {
+	StartIndex       int       `json:"start_index"`
+	ResultsPerPage   int       `json:"results_per_page"`
+	LastModStartDate time.Time `json:"last_mod_start_date"`
+	LastModEndDate   time.Time `json:"last_mod_end_date,omitempty"`
+}
+
 // SaveCVEByIDParams are the typed parameters for RPCSaveCVEByID
 type SaveCVEByIDParams struct {
 	CVE cve.CVEItem `json:"cve"`
 }
 
+// SaveCVEsBatchParams are the typed parameters for RPCSaveCVEsBatch
+type SaveCVEsBatchParams struct {
+	CVEs []cve.CVEItem `json:"cves"`
+}
+
+// SaveCVEsBatchResult is the result of RPCSaveCVEsBatch: how many CVEs were
+// new and how many replaced a stored CVE
+type SaveCVEsBatchResult struct {
+	Success  bool `json:"success"`
+	Inserted int  `json:"inserted"`
+	Updated  int  `json:"updated"`
+}
+
+// ImportKEVParams are the typed parameters for RPCImportKEV. Entries come
+// from Vulnerabilities, or from the CISA catalog file at Path, which is
+// imported as the whole catalog.
+type ImportKEVParams struct {
+	Path            string         `json:"path,omitempty"`
+	Vulnerabilities []cve.KEVEntry `json:"vulnerabilities,omitempty"`
+	Replace         bool           `json:"replace,omitempty"`
+}
+
+// ImportKEVResult is the result of RPCImportKEV: how many catalog entries
+// were stored and how many of them name a stored CVE
+type ImportKEVResult struct {
+	Listed  int64 `json:"listed"`
+	Matched int64 `json:"matched"`
+}
+
 // GetByIDParams is a general typed param for operations by id
 type GetByIDParams struct {
 	ID string `json:"id"`
@@ -21,6 +63,14 @@ type GetByIDParams struct {
 // CVEIDParams is used for RPCs that expect a cve_id field
 type CVEIDParams struct {
 	CVEID string `json:"cve_id"`
+	// Fresh makes RPCGetCVEByID of remote bypass its CVE cache
+	Fresh bool `json:"fresh,omitempty"`
+}
+
+// URNParams is used for RPCs that expect a urn field, such as the graph
+// RPCs of analysis
+type URNParams struct {
+	URN string `json:"urn"`
 }
 
 // ListParams used for pagination-based list RPCs
@@ -34,4 +84,7 @@ type ImportParams struct {
 	Path  string `json:"path"`
 	XSD   string `json:"xsd,omitempty"`
 	Force bool   `json:"force,omitempty"`
+	// DryRun validates the file and reports what would be imported without
+	// storing anything (CAPEC and ATT&CK)
+	DryRun bool `json:"dry_run,omitempty"`
 }
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+)
+
+// RetryPolicy controls how InvokeRPC retries requests the broker could not
+// deliver, e.g. because the target is restarting. Only such routing failures
+// are retried: an error response from the target itself is the outcome of
+// the call and is returned as is, and so is a timeout, since the target may
+// still be working on the request.
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt; 0 disables retries
+	BaseDelay  time.Duration // Delay before the first retry, doubled each time
+	MaxDelay   time.Duration // Upper bound on a single delay
+	// WriteMethods are the methods besides reads (see IsReadMethod) that are
+	// retried. A write is listed only when repeating it is safe.
+	WriteMethods []string
+}
+
+// DefaultRetryPolicy returns the policy of a client created without
+// WithRetryPolicy: reads are retried three times within about a second
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   time.Second,
+	}
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithRetryPolicy sets the retry policy of the client
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// readMethodPrefixes are the method name prefixes of the RPCs that only read,
+// which are safe to repeat
+var readMethodPrefixes = []string{
+	"RPCGet",
+	"RPCList",
+	"RPCCount",
+	"RPCSearch",
+	"RPCFind",
+	"RPCCheck",
+	"RPCExplain",
+	"RPCExport",
+	"RPCHealthCheck",
+}
+
+// IsReadMethod reports whether method only reads, judging by its name
+func IsReadMethod(method string) bool {
+	for _, prefix := range readMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// routingErrors are fragments of the errors the broker replies with when it
+// cannot hand a request to its target
+var routingErrors = []string{
+	"failed to send message to process",
+	"transport for process",
+	"transport not connected",
+	"is not running",
+	"channel full",
+}
+
+// IsRoutingError reports whether resp is the broker's reply to a request it
+// could not deliver, as opposed to an error response from the target
+func IsRoutingError(resp *subprocess.Message) bool {
+	if resp == nil || resp.Type != subprocess.MessageTypeError {
+		return false
+	}
+	if resp.Source != "" && resp.Source != "broker" {
+		return false
+	}
+	for _, fragment := range routingErrors {
+		if strings.Contains(resp.Error, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// retries reports whether a request of method is retried
+func (p RetryPolicy) retries(method string) bool {
+	if p.MaxRetries <= 0 {
+		return false
+	}
+	if IsReadMethod(method) {
+		return true
+	}
+	for _, m := range p.WriteMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the wait before retry number retry (from 0), with jitter so
+// callers that failed together do not retry in lockstep
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
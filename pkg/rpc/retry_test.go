@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyw0ng95/v2e/pkg/common"
+	"github.com/cyw0ng95/v2e/pkg/proc/subprocess"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+	"gorm.io/gorm"
+)
+
+// replyingWriter stands in for the broker: it answers each request written
+// to it with the reply returned by reply for the request's attempt number
+type replyingWriter struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	client   *Client
+	attempts int
+	reply    func(attempt int, req *subprocess.Message) *subprocess.Message
+}
+
+func (w *replyingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			rest := append([]byte(nil), line...)
+			w.buf.Reset()
+			w.buf.Write(rest)
+			return len(p), nil
+		}
+		var req subprocess.Message
+		if json.Unmarshal(line, &req) != nil || req.Type != subprocess.MessageTypeRequest {
+			continue
+		}
+		w.attempts++
+		reply := w.reply(w.attempts, &req)
+		reply.CorrelationID = req.CorrelationID
+		go w.client.HandleResponse(context.Background(), reply)
+	}
+}
+
+func (w *replyingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.attempts
+}
+
+func TestInvokeRPC_RetriesRoutingErrors(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestInvokeRPC_RetriesRoutingErrors", nil, func(t *testing.T, tx *gorm.DB) {
+		logger := common.NewLogger(io.Discard, "", common.InfoLevel)
+		notDelivered := &subprocess.Message{Type: subprocess.MessageTypeError, Source: "broker",
+			Error: "failed to send message to process local via transport: transport for process 'local' not found"}
+		ok := &subprocess.Message{Type: subprocess.MessageTypeResponse, Source: "local"}
+
+		newClient := func(policy RetryPolicy, failures int) (*Client, *replyingWriter) {
+			sp := subprocess.New("test-service")
+			w := &replyingWriter{reply: func(attempt int, req *subprocess.Message) *subprocess.Message {
+				if attempt <= failures {
+					reply := *notDelivered
+					return &reply
+				}
+				reply := *ok
+				return &reply
+			}}
+			sp.SetOutput(w)
+			client := NewClient(sp, logger, 5*time.Second, WithRetryPolicy(policy))
+			w.client = client
+			return client, w
+		}
+		policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+		// A read is retried until the target is reachable
+		client, w := newClient(policy, 2)
+		resp, err := client.InvokeRPC(context.Background(), "local", "RPCGetCVEByID", nil)
+		if err != nil || resp.Type != subprocess.MessageTypeResponse || w.count() != 3 {
+			t.Errorf("Expected the read to succeed on the third attempt, got %+v, %v after %d", resp, err, w.count())
+		}
+
+		// Retries are bounded; the last routing error is returned
+		client, w = newClient(policy, 10)
+		resp, err = client.InvokeRPC(context.Background(), "local", "RPCListCVEs", nil)
+		if err != nil || !IsRoutingError(resp) || w.count() != 4 {
+			t.Errorf("Expected the routing error after 4 attempts, got %+v, %v after %d", resp, err, w.count())
+		}
+
+		// A write is sent once unless the policy lists it
+		client, w = newClient(policy, 1)
+		if resp, _ := client.InvokeRPC(context.Background(), "local", "RPCSaveCVEByID", nil); !IsRoutingError(resp) || w.count() != 1 {
+			t.Errorf("Expected the write not to be retried, got %+v after %d", resp, w.count())
+		}
+		policy.WriteMethods = []string{"RPCSaveCVEByID"}
+		client, w = newClient(policy, 1)
+		if resp, _ := client.InvokeRPC(context.Background(), "local", "RPCSaveCVEByID", nil); resp.Type != subprocess.MessageTypeResponse || w.count() != 2 {
+			t.Errorf("Expected the listed write to be retried, got %+v after %d", resp, w.count())
+		}
+
+		// An error from the target itself is the outcome of the call
+		sp := subprocess.New("test-service")
+		w = &replyingWriter{reply: func(attempt int, req *subprocess.Message) *subprocess.Message {
+			return &subprocess.Message{Type: subprocess.MessageTypeError, Source: "local", Error: "process 'x' is not running"}
+		}}
+		sp.SetOutput(w)
+		client = NewClient(sp, logger, 5*time.Second, WithRetryPolicy(policy))
+		w.client = client
+		if resp, _ := client.InvokeRPC(context.Background(), "local", "RPCGetCVEByID", nil); resp.Type != subprocess.MessageTypeError || w.count() != 1 {
+			t.Errorf("Expected the target's error without retry, got %+v after %d", resp, w.count())
+		}
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestRetryPolicy", nil, func(t *testing.T, tx *gorm.DB) {
+		for method, want := range map[string]bool{
+			"RPCGetCVEByID":    true,
+			"RPCListCVEs":      true,
+			"RPCCountCVEs":     true,
+			"RPCSaveCVEByID":   false,
+			"RPCFetchCVEs":     false,
+			"RPCDeleteCVEByID": false,
+		} {
+			if got := IsReadMethod(method); got != want {
+				t.Errorf("IsReadMethod(%s) = %v, want %v", method, got, want)
+			}
+		}
+
+		p := RetryPolicy{MaxRetries: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+		for retry := 0; retry < 10; retry++ {
+			if d := p.delay(retry); d < 10*time.Millisecond || d > 60*time.Millisecond {
+				t.Errorf("delay(%d) = %v, want within [10ms, 60ms]", retry, d)
+			}
+		}
+		if (RetryPolicy{}).retries("RPCGetCVEByID") {
+			t.Error("Expected a zero policy not to retry")
+		}
+		if !DefaultRetryPolicy().retries("RPCGetCVEByID") || DefaultRetryPolicy().retries("RPCSaveCVEByID") {
+			t.Error("Expected the default policy to retry reads only")
+		}
+	})
+}
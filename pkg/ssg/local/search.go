@@ -0,0 +1,247 @@
+package local
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
+	"github.com/cyw0ng95/v2e/pkg/ssg"
+)
+
+// MinSearchKeywordLen is the shortest keyword, in characters, SearchRules
+// looks up; shorter keywords would match nearly every rule
+const MinSearchKeywordLen = 2
+
+// snippetRadius is the number of characters kept on each side of the first
+// match in a search snippet
+const snippetRadius = 80
+
+// ensureRuleFTS creates the ssg_rule_fts full-text index of rule titles and
+// bodies (description and rationale), with triggers keeping it in step with
+// every write to ssg_rules, and fills it from existing rules when first
+// created. The docid of an entry is the rowid of its rule. It reports false
+// when SQLite lacks the FTS4 module, in which case SearchRules falls back to
+// LIKE.
+func ensureRuleFTS(db *gorm.DB) (bool, error) {
+	if db.Migrator().HasTable("ssg_rule_fts") {
+		return true, nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			`CREATE VIRTUAL TABLE ssg_rule_fts USING fts4(title, body)`,
+			`CREATE TRIGGER ssg_rule_fts_ai AFTER INSERT ON ssg_rules BEGIN
+				INSERT INTO ssg_rule_fts(docid, title, body) VALUES (new.rowid, new.title, new.description || ' ' || new.rationale);
+			END`,
+			`CREATE TRIGGER ssg_rule_fts_au AFTER UPDATE OF title, description, rationale ON ssg_rules BEGIN
+				DELETE FROM ssg_rule_fts WHERE docid = old.rowid;
+				INSERT INTO ssg_rule_fts(docid, title, body) VALUES (new.rowid, new.title, new.description || ' ' || new.rationale);
+			END`,
+			`CREATE TRIGGER ssg_rule_fts_ad AFTER DELETE ON ssg_rules BEGIN
+				DELETE FROM ssg_rule_fts WHERE docid = old.rowid;
+			END`,
+			`INSERT INTO ssg_rule_fts(docid, title, body) SELECT rowid, title, description || ' ' || rationale FROM ssg_rules`,
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// searchTerms returns the lowercased words of a keyword, without FTS
+// operators and quotes
+func searchTerms(keyword string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(keyword)) {
+		word = strings.Trim(word, `"*()-+:^`)
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// ruleFTSQuery turns search terms into an FTS query matching rules that
+// contain a word starting with each term, so "ssh" also finds "sshd"
+func ruleFTSQuery(terms []string) string {
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		phrases[i] = `"` + strings.ReplaceAll(term, `"`, "") + `*"`
+	}
+	return strings.Join(phrases, " ")
+}
+
+// SearchRules returns a page of the guide rules whose title, description or
+// rationale contain every word of keyword, most relevant first, and the
+// number of matches. Matches in the title weigh more than matches in the
+// body. The results may be narrowed to a guide (benchmark), the guides of a
+// product, a severity, and rules carrying all of the given reference labels
+// (tags such as "cis-csc" or "nist"). A keyword shorter than
+// MinSearchKeywordLen characters matches nothing rather than every rule.
+func (s *Store) SearchRules(keyword, guideID, product, severity string, tags []string, offset, limit int) ([]ssg.SSGRuleSearchHit, int64, error) {
+	hits := []ssg.SSGRuleSearchHit{}
+	terms := searchTerms(keyword)
+	if utf8.RuneCountInString(strings.Join(terms, " ")) < MinSearchKeywordLen {
+		return hits, 0, nil
+	}
+
+	query := s.db.Model(&ssg.SSGRule{})
+	if s.fts {
+		query = query.Where("rowid IN (SELECT docid FROM ssg_rule_fts WHERE ssg_rule_fts MATCH ?)", ruleFTSQuery(terms))
+	} else {
+		for _, term := range terms {
+			pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term) + "%"
+			query = query.Where(`title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR rationale LIKE ? ESCAPE '\'`, pattern, pattern, pattern)
+		}
+	}
+	if guideID != "" {
+		query = query.Where("guide_id = ?", guideID)
+	}
+	if product != "" {
+		query = query.Where("guide_id IN (SELECT id FROM ssg_guides WHERE product = ?)", product)
+	}
+	if severity != "" {
+		query = query.Where("severity = ?", strings.ToLower(severity))
+	}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			query = query.Where("id IN (SELECT rule_id FROM ssg_references WHERE LOWER(label) = ?)", strings.ToLower(tag))
+		}
+	}
+
+	// Ranking needs the text of every match, so matches are scored here
+	// rather than ordered by the database
+	var rules []ssg.SSGRule
+	if err := dbretry.Do(func() error { return query.Find(&rules).Error }); err != nil {
+		return nil, 0, err
+	}
+	for _, rule := range rules {
+		hits = append(hits, ssg.SSGRuleSearchHit{
+			Rule:    rule,
+			Score:   ruleScore(&rule, terms),
+			Snippet: ruleSnippet(&rule, terms),
+		})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Rule.Title < hits[j].Rule.Title
+	})
+
+	total := int64(len(hits))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset > len(hits) {
+		offset = len(hits)
+	}
+	hits = hits[offset:]
+	if limit < len(hits) {
+		hits = hits[:limit]
+	}
+
+	if len(hits) > 0 {
+		ids := make([]string, len(hits))
+		for i := range hits {
+			ids[i] = hits[i].Rule.ID
+		}
+		var refs []ssg.SSGReference
+		if err := s.db.Where("rule_id IN ?", ids).Order("id").Find(&refs).Error; err != nil {
+			return nil, 0, err
+		}
+		byRule := make(map[string][]ssg.SSGReference)
+		for _, ref := range refs {
+			byRule[ref.RuleID] = append(byRule[ref.RuleID], ref)
+		}
+		for i := range hits {
+			hits[i].Rule.References = byRule[hits[i].Rule.ID]
+		}
+	}
+	return hits, total, nil
+}
+
+// ruleScore rates how well a rule matches the search terms: per term, a
+// title match counts 3, a match in the short ID 2, and matches in the
+// description and rationale 1 each
+func ruleScore(rule *ssg.SSGRule, terms []string) float64 {
+	title := strings.ToLower(rule.Title)
+	shortID := strings.ToLower(rule.ShortID)
+	description := strings.ToLower(rule.Description)
+	rationale := strings.ToLower(rule.Rationale)
+
+	var score float64
+	for _, term := range terms {
+		if strings.Contains(title, term) {
+			score += 3
+		}
+		if strings.Contains(shortID, term) {
+			score += 2
+		}
+		if strings.Contains(description, term) {
+			score++
+		}
+		if strings.Contains(rationale, term) {
+			score++
+		}
+	}
+	return score
+}
+
+// ruleSnippet returns the part of a rule's description, or else its
+// rationale, around the first occurrence of a search term. A rule matching
+// only in its title gets the start of its description.
+func ruleSnippet(rule *ssg.SSGRule, terms []string) string {
+	for _, text := range []string{rule.Description, rule.Rationale} {
+		text = strings.Join(strings.Fields(text), " ")
+		lower := strings.ToLower(text)
+		first := -1
+		for _, term := range terms {
+			if i := strings.Index(lower, term); i >= 0 && (first < 0 || i < first) {
+				first = i
+			}
+		}
+		if first >= 0 {
+			return excerpt(text, first)
+		}
+	}
+	return excerpt(strings.Join(strings.Fields(rule.Description), " "), 0)
+}
+
+// excerpt returns text around byte offset at, cut on rune boundaries and
+// marked with ellipses where shortened
+func excerpt(text string, at int) string {
+	if at > len(text) {
+		// Lowercasing may have changed the length of non-ASCII text
+		at = len(text)
+	}
+	runes := []rune(text)
+	center := utf8.RuneCountInString(text[:at])
+	start, end := center-snippetRadius, center+snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
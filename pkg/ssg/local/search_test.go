@@ -0,0 +1,172 @@
+package local
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/cyw0ng95/v2e/pkg/ssg"
+	"github.com/cyw0ng95/v2e/pkg/testutils"
+)
+
+// newSearchStore returns a store with SSH and non-SSH rules in two guides
+func newSearchStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "ssg.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	guides := []ssg.SSGGuide{
+		{ID: "ssg-rhel9-guide-cis", Product: "rhel9", ShortID: "cis", Title: "CIS Red Hat Enterprise Linux 9 Benchmark"},
+		{ID: "ssg-al2023-guide-cis", Product: "al2023", ShortID: "cis", Title: "CIS Amazon Linux 2023 Benchmark"},
+	}
+	for i := range guides {
+		if err := store.SaveGuide(&guides[i]); err != nil {
+			t.Fatalf("SaveGuide() error = %v", err)
+		}
+	}
+
+	rules := []ssg.SSGRule{
+		{
+			ID: "rhel9_sshd_disable_root_login", GuideID: "ssg-rhel9-guide-cis", ShortID: "sshd_disable_root_login",
+			Title:       "Disable SSH Root Login",
+			Description: "The root user should never be allowed to login to a system directly over a network.",
+			Rationale:   "Disallowing root logins over SSH requires administrators to authenticate using their own account.",
+			Severity:    "medium",
+			References:  []ssg.SSGReference{{Label: "cis-csc", Value: "5"}, {Label: "nist", Value: "AC-6(2)"}},
+		},
+		{
+			ID: "rhel9_package_aide_installed", GuideID: "ssg-rhel9-guide-cis", ShortID: "package_aide_installed",
+			Title:       "Install AIDE",
+			Description: "The aide package can be installed with the following command. Remote checks may use ssh.",
+			Rationale:   "The AIDE package must be installed if it is to be available for integrity checking.",
+			Severity:    "medium",
+			References:  []ssg.SSGReference{{Label: "nist", Value: "CM-6(a)"}},
+		},
+		{
+			ID: "rhel9_audit_rules_login", GuideID: "ssg-rhel9-guide-cis", ShortID: "audit_rules_login_events",
+			Title:       "Record Attempts to Alter Logon and Logout Events",
+			Description: "The audit system already collects login information for all users and root.",
+			Rationale:   "Manual editing of these files may indicate nefarious activity.",
+			Severity:    "high",
+		},
+		{
+			ID: "al2023_sshd_disable_root_login", GuideID: "ssg-al2023-guide-cis", ShortID: "sshd_disable_root_login",
+			Title:       "Disable SSH Root Login",
+			Description: "The root user should never be allowed to login to a system directly over a network.",
+			Rationale:   "Disallowing root logins over SSH requires administrators to authenticate using their own account.",
+			Severity:    "medium",
+			References:  []ssg.SSGReference{{Label: "cis-csc", Value: "5"}},
+		},
+	}
+	for i := range rules {
+		if err := store.SaveRule(&rules[i]); err != nil {
+			t.Fatalf("SaveRule() error = %v", err)
+		}
+	}
+	return store
+}
+
+func hitIDs(hits []ssg.SSGRuleSearchHit) []string {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.Rule.ID
+	}
+	return ids
+}
+
+func TestSearchRules(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSearchRules", nil, func(t *testing.T, tx *gorm.DB) {
+		store := newSearchStore(t)
+		if !store.fts {
+			t.Log("FTS4 is unavailable; searching with LIKE")
+		}
+
+		// SSH rules of one benchmark, the title match ranked first
+		hits, total, err := store.SearchRules("SSH", "ssg-rhel9-guide-cis", "", "", nil, 0, 0)
+		if err != nil {
+			t.Fatalf("SearchRules() error = %v", err)
+		}
+		if total != 2 || len(hits) != 2 || hits[0].Rule.ID != "rhel9_sshd_disable_root_login" || hits[1].Rule.ID != "rhel9_package_aide_installed" {
+			t.Fatalf("Expected the 2 rhel9 SSH rules, title match first, got %v (total %d)", hitIDs(hits), total)
+		}
+		if hits[0].Score <= hits[1].Score {
+			t.Errorf("Expected a title match to score higher, got %v and %v", hits[0].Score, hits[1].Score)
+		}
+		if !strings.Contains(hits[1].Snippet, "ssh") || len(hits[0].Rule.References) != 2 {
+			t.Errorf("Expected a snippet around the match and loaded references, got %q and %d references", hits[1].Snippet, len(hits[0].Rule.References))
+		}
+
+		// Tags are ANDed reference labels; product and severity narrow further
+		if hits, total, _ := store.SearchRules("ssh", "", "", "", []string{"CIS-CSC", "nist"}, 0, 0); total != 1 || hits[0].Rule.ID != "rhel9_sshd_disable_root_login" {
+			t.Errorf("Expected the rule carrying both tags, got %v", hitIDs(hits))
+		}
+		if hits, _, _ := store.SearchRules("ssh root", "", "al2023", "Medium", nil, 0, 0); len(hits) != 1 || hits[0].Rule.ID != "al2023_sshd_disable_root_login" {
+			t.Errorf("Expected the al2023 rule, got %v", hitIDs(hits))
+		}
+
+		// Pagination keeps the total
+		if hits, total, _ := store.SearchRules("ssh", "", "", "", nil, 1, 1); total != 3 || len(hits) != 1 {
+			t.Errorf("Expected 1 of 3 hits, got %d of %d", len(hits), total)
+		}
+
+		// Short queries and misses yield empty results, not errors
+		for _, keyword := range []string{"", "s", ` " `, "kerberos"} {
+			hits, total, err := store.SearchRules(keyword, "", "", "", nil, 0, 0)
+			if err != nil || total != 0 || hits == nil || len(hits) != 0 {
+				t.Errorf("Expected no hits for %q, got %v (total %d, err %v)", keyword, hitIDs(hits), total, err)
+			}
+		}
+	})
+}
+
+func TestSearchRules_IndexFollowsUpdates(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSearchRules_IndexFollowsUpdates", nil, func(t *testing.T, tx *gorm.DB) {
+		store := newSearchStore(t)
+
+		rule, err := store.GetRule("rhel9_audit_rules_login")
+		if err != nil {
+			t.Fatalf("GetRule() error = %v", err)
+		}
+		rule.Description = "Logins through the sshd daemon are recorded as well."
+		if err := store.SaveRule(rule); err != nil {
+			t.Fatalf("SaveRule() error = %v", err)
+		}
+		if _, total, _ := store.SearchRules("ssh", "ssg-rhel9-guide-cis", "", "", nil, 0, 0); total != 3 {
+			t.Errorf("Expected the updated rule to be found, got %d hits", total)
+		}
+		if _, total, _ := store.SearchRules("already collects", "", "", "", nil, 0, 0); total != 0 {
+			t.Errorf("Expected the old description to be gone from the index, got %d hits", total)
+		}
+
+		if err := store.db.Delete(&ssg.SSGRule{}, "id = ?", "al2023_sshd_disable_root_login").Error; err != nil {
+			t.Fatalf("Delete error = %v", err)
+		}
+		if _, total, _ := store.SearchRules("ssh", "ssg-al2023-guide-cis", "", "", nil, 0, 0); total != 0 {
+			t.Errorf("Expected a deleted rule to be gone from the index, got %d hits", total)
+		}
+	})
+}
+
+func TestSearchRules_LikeFallback(t *testing.T) {
+	testutils.Run(t, testutils.Level1, "TestSearchRules_LikeFallback", nil, func(t *testing.T, tx *gorm.DB) {
+		store := newSearchStore(t)
+		store.fts = false
+
+		hits, total, err := store.SearchRules("ssh", "ssg-rhel9-guide-cis", "", "", nil, 0, 0)
+		if err != nil {
+			t.Fatalf("SearchRules() error = %v", err)
+		}
+		if total != 2 || hits[0].Rule.ID != "rhel9_sshd_disable_root_login" {
+			t.Errorf("Expected the 2 rhel9 SSH rules, got %v", hitIDs(hits))
+		}
+		// LIKE wildcards in the keyword are literal
+		if _, total, _ := store.SearchRules("%_", "", "", "", nil, 0, 0); total != 0 {
+			t.Errorf("Expected no hits for wildcards, got %d", total)
+		}
+	})
+}
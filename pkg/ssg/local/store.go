@@ -8,12 +8,15 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/cyw0ng95/v2e/pkg/common/dbretry"
 	"github.com/cyw0ng95/v2e/pkg/ssg"
 )
 
 // Store manages SSG data storage in SQLite.
 type Store struct {
 	db *gorm.DB
+	// fts is true when the ssg_rule_fts full-text index is available
+	fts bool
 }
 
 // NewStore creates a new SSG store with the given database path.
@@ -24,7 +27,7 @@ func NewStore(dbPath string) (*Store, error) {
 	}
 
 	// Open database with WAL mode for better performance
-	dsn := fmt.Sprintf("%s?_pragma=journal_mode=WAL&_pragma=synchronous=NORMAL&_pragma=foreign_keys=on", dbPath)
+	dsn := dbretry.DSN(fmt.Sprintf("%s?_pragma=journal_mode=WAL&_pragma=synchronous=NORMAL&_pragma=foreign_keys=on", dbPath))
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
@@ -55,8 +58,17 @@ func NewStore(dbPath string) (*Store, error) {
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
+	fts, err := ensureRuleFTS(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule search index: %w", err)
+	}
+
+	return &Store{db: db, fts: fts}, nil
+}
 
-	return &Store{db: db}, nil
+// transaction runs fn in a transaction, retrying it on lock conflicts.
+func (s *Store) transaction(fn func(tx *gorm.DB) error) error {
+	return dbretry.Do(func() error { return s.db.Transaction(fn) })
 }
 
 // Close closes the database connection.
@@ -70,7 +82,7 @@ func (s *Store) Close() error {
 
 // SaveGuide saves or updates a guide in the database.
 func (s *Store) SaveGuide(guide *ssg.SSGGuide) error {
-	return s.db.Save(guide).Error
+	return dbretry.Do(func() error { return s.db.Save(guide).Error })
 }
 
 // GetGuide retrieves a guide by ID.
@@ -105,7 +117,7 @@ func (s *Store) ListGuides(product, profileID string) ([]ssg.SSGGuide, error) {
 
 // SaveGroup saves or updates a group in the database.
 func (s *Store) SaveGroup(group *ssg.SSGGroup) error {
-	return s.db.Save(group).Error
+	return dbretry.Do(func() error { return s.db.Save(group).Error })
 }
 
 // GetGroup retrieves a group by ID.
@@ -153,7 +165,7 @@ func (s *Store) GetChildRules(groupID string) ([]ssg.SSGRule, error) {
 // SaveRule saves or updates a rule in the database.
 // This will also save associated references.
 func (s *Store) SaveRule(rule *ssg.SSGRule) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return s.transaction(func(tx *gorm.DB) error {
 		// Save the rule
 		if err := tx.Save(rule).Error; err != nil {
 			return err
@@ -303,7 +315,7 @@ func (s *Store) BuildTreeNodes(guideID string) ([]*ssg.TreeNode, error) {
 
 // SaveTable saves or updates a table in the database.
 func (s *Store) SaveTable(table *ssg.SSGTable) error {
-	return s.db.Save(table).Error
+	return dbretry.Do(func() error { return s.db.Save(table).Error })
 }
 
 // GetTable retrieves a table by ID.
@@ -338,7 +350,7 @@ func (s *Store) ListTables(product, tableType string) ([]ssg.SSGTable, error) {
 
 // SaveTableEntry saves or updates a table entry in the database.
 func (s *Store) SaveTableEntry(entry *ssg.SSGTableEntry) error {
-	return s.db.Save(entry).Error
+	return dbretry.Do(func() error { return s.db.Save(entry).Error })
 }
 
 // GetTableEntries retrieves all entries for a table.
@@ -373,7 +385,7 @@ func (s *Store) GetTableEntries(tableID string, offset, limit int) ([]ssg.SSGTab
 // SaveManifest saves a manifest and its associated profiles and profile rules.
 // This is an atomic operation - all or nothing.
 func (s *Store) SaveManifest(manifest *ssg.SSGManifest, profiles []ssg.SSGProfile, profileRules []ssg.SSGProfileRule) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return s.transaction(func(tx *gorm.DB) error {
 		// Save manifest
 		if err := tx.Save(manifest).Error; err != nil {
 			return fmt.Errorf("failed to save manifest: %w", err)
@@ -502,7 +514,7 @@ func (s *Store) SaveDataStream(
 	references []ssg.SSGDSRuleReference,
 	identifiers []ssg.SSGDSRuleIdentifier,
 ) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return s.transaction(func(tx *gorm.DB) error {
 		// Save data stream
 		if err := tx.Save(ds).Error; err != nil {
 			return fmt.Errorf("failed to save data stream: %w", err)
@@ -752,8 +764,12 @@ func (s *Store) SaveCrossReferences(refs []ssg.SSGCrossReference) error {
 		return nil
 	}
 
-	// Use batch insert for performance
-	if err := s.db.CreateInBatches(&refs, 500).Error; err != nil {
+	// Use batch insert for performance, in a transaction so a retry after a
+	// lock conflict does not duplicate the batches that went through
+	err := s.transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&refs, 500).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to save cross-references: %w", err)
 	}
 	return nil
@@ -78,6 +78,14 @@ func (SSGRule) TableName() string {
 	return "ssg_rules"
 }
 
+// SSGRuleSearchHit is a rule matching a content search, with its relevance
+// score and an excerpt of its text around the first match.
+type SSGRuleSearchHit struct {
+	Rule    SSGRule `json:"rule"`
+	Score   float64 `json:"score"`   // Higher is more relevant
+	Snippet string  `json:"snippet"` // e.g., "…disable root login over SSH by setting…"
+}
+
 // SSGReference represents a rule reference (e.g., CIS, NIST, PCI-DSS).
 // References provide external documentation or standards mappings.
 type SSGReference struct {
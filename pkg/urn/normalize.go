@@ -0,0 +1,113 @@
+package urn
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAtomicID indicates an atomic ID not in the format of its type
+var ErrInvalidAtomicID = errors.New("invalid atomic ID")
+
+var (
+	cvePattern    = regexp.MustCompile(`^CVE-(\d{4})-(\d+)$`)
+	numberPattern = regexp.MustCompile(`^\d+$`)
+	attackPattern = regexp.MustCompile(`^(TA|DS|T|M|G|S|C)(\d{1,6})(?:\.(\d{1,6}))?$`)
+)
+
+// Normalize parses a URN written by hand and returns it in canonical form,
+// so URNs that differ only in spelling name the same node:
+//   - surrounding whitespace is trimmed and the prefix, provider and type
+//     are lowercased
+//   - CVE IDs are uppercased with the sequence number zero-padded to four
+//     digits (cve-2024-123 becomes CVE-2024-0123)
+//   - CWE and CAPEC IDs get their prefix and lose leading zeros (79 and
+//     cwe-079 become CWE-79)
+//   - ATT&CK IDs are uppercased with the number zero-padded to four digits
+//     and a sub-technique to three (t1059.1 becomes T1059.001)
+//   - ASVS IDs get an uppercase V (v1.2.1 becomes V1.2.1)
+//
+// The errors wrap ErrInvalidURN, ErrInvalidProvider, ErrInvalidType,
+// ErrEmptyAtomicID or ErrInvalidAtomicID and say which part is at fault.
+func Normalize(s string) (*URN, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "::")
+	if len(parts) != 4 || !strings.EqualFold(parts[0], "v2e") {
+		return nil, fmt.Errorf("%w: expected format 'v2e::<provider>::<type>::<atomic_id>', got '%s'", ErrInvalidURN, s)
+	}
+
+	provider := Provider(strings.ToLower(strings.TrimSpace(parts[1])))
+	if provider == "" {
+		return nil, fmt.Errorf("%w: provider is missing (expected one of nvd, mitre, ssg, owasp)", ErrInvalidProvider)
+	}
+	if !isValidProvider(provider) {
+		return nil, fmt.Errorf("%w: '%s' is not a valid provider (expected one of nvd, mitre, ssg, owasp)", ErrInvalidProvider, parts[1])
+	}
+
+	resourceType := ResourceType(strings.ToLower(strings.TrimSpace(parts[2])))
+	if resourceType == "" {
+		return nil, fmt.Errorf("%w: type is missing (expected one of cve, cwe, capec, attack, ssg, asvs)", ErrInvalidType)
+	}
+	if !isValidResourceType(resourceType) {
+		return nil, fmt.Errorf("%w: '%s' is not a valid resource type (expected one of cve, cwe, capec, attack, ssg, asvs)", ErrInvalidType, parts[2])
+	}
+
+	atomicID, err := NormalizeAtomicID(resourceType, parts[3])
+	if err != nil {
+		return nil, err
+	}
+	return &URN{Provider: provider, Type: resourceType, AtomicID: atomicID}, nil
+}
+
+// NormalizeAtomicID returns the canonical form of an atomic ID of the given
+// type (see Normalize). IDs of types without a fixed format are only
+// trimmed.
+func NormalizeAtomicID(resourceType ResourceType, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", ErrEmptyAtomicID
+	}
+	upper := strings.ToUpper(id)
+
+	switch resourceType {
+	case TypeCVE:
+		m := cvePattern.FindStringSubmatch(upper)
+		if m == nil {
+			return "", fmt.Errorf("%w: '%s' is not a CVE ID (expected CVE-YYYY-NNNN)", ErrInvalidAtomicID, id)
+		}
+		seq := strings.TrimLeft(m[2], "0")
+		if len(seq) < 4 {
+			seq = strings.Repeat("0", 4-len(seq)) + seq
+		}
+		return "CVE-" + m[1] + "-" + seq, nil
+	case TypeCWE, TypeCAPEC:
+		prefix := strings.ToUpper(string(resourceType)) + "-"
+		num := strings.TrimPrefix(upper, prefix)
+		n, err := strconv.ParseUint(num, 10, 32)
+		if !numberPattern.MatchString(num) || err != nil || n == 0 {
+			return "", fmt.Errorf("%w: '%s' is not a %s ID (expected %sN)", ErrInvalidAtomicID, id, strings.ToUpper(string(resourceType)), prefix)
+		}
+		return prefix + strconv.FormatUint(n, 10), nil
+	case TypeATTACK:
+		m := attackPattern.FindStringSubmatch(upper)
+		if m == nil {
+			return "", fmt.Errorf("%w: '%s' is not an ATT&CK ID (expected e.g. T1059, T1059.001, TA0001, M1036, G0007 or S0002)", ErrInvalidAtomicID, id)
+		}
+		n, _ := strconv.ParseUint(m[2], 10, 32)
+		normalized := fmt.Sprintf("%s%04d", m[1], n)
+		if m[3] != "" {
+			sub, _ := strconv.ParseUint(m[3], 10, 32)
+			normalized += fmt.Sprintf(".%03d", sub)
+		}
+		return normalized, nil
+	case TypeASVS:
+		if upper[0] == 'V' {
+			return "V" + id[1:], nil
+		}
+		return id, nil
+	default:
+		return id, nil
+	}
+}
@@ -0,0 +1,69 @@
+package urn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+		errText string
+	}{
+		{name: "canonical CVE", input: "v2e::nvd::cve::CVE-2024-12233", want: "v2e::nvd::cve::CVE-2024-12233"},
+		{name: "padded CVE sequence", input: "v2e::nvd::cve::cve-2024-123", want: "v2e::nvd::cve::CVE-2024-0123"},
+		{name: "extra zeros in CVE sequence", input: "v2e::nvd::cve::CVE-2024-000123", want: "v2e::nvd::cve::CVE-2024-0123"},
+		{name: "case and whitespace", input: "  V2E::NVD::CVE::CVE-2021-44228 ", want: "v2e::nvd::cve::CVE-2021-44228"},
+		{name: "bare CWE number", input: "v2e::mitre::cwe::79", want: "v2e::mitre::cwe::CWE-79"},
+		{name: "zero-padded CWE", input: "v2e::mitre::cwe::cwe-079", want: "v2e::mitre::cwe::CWE-79"},
+		{name: "CAPEC", input: "v2e::mitre::capec::capec-66", want: "v2e::mitre::capec::CAPEC-66"},
+		{name: "ATT&CK sub-technique", input: "v2e::mitre::attack::t1059.1", want: "v2e::mitre::attack::T1059.001"},
+		{name: "ATT&CK tactic", input: "v2e::mitre::attack::ta1", want: "v2e::mitre::attack::TA0001"},
+		{name: "ASVS", input: "v2e::owasp::asvs::v1.2.1", want: "v2e::owasp::asvs::V1.2.1"},
+		{name: "SSG kept as is", input: "v2e::ssg::ssg::rhel9-guide-ospp", want: "v2e::ssg::ssg::rhel9-guide-ospp"},
+		{name: "wrong prefix", input: "urn::nvd::cve::CVE-2024-1", wantErr: ErrInvalidURN},
+		{name: "too few parts", input: "v2e::nvd::CVE-2024-1", wantErr: ErrInvalidURN},
+		{name: "missing provider", input: "v2e::::cve::CVE-2024-1234", wantErr: ErrInvalidProvider, errText: "provider is missing"},
+		{name: "unknown provider", input: "v2e::github::cve::CVE-2024-1234", wantErr: ErrInvalidProvider, errText: "'github' is not a valid provider"},
+		{name: "missing type", input: "v2e::nvd::::CVE-2024-1234", wantErr: ErrInvalidType, errText: "type is missing"},
+		{name: "bad type", input: "v2e::nvd::vuln::CVE-2024-1234", wantErr: ErrInvalidType, errText: "'vuln' is not a valid resource type"},
+		{name: "empty ID", input: "v2e::nvd::cve:: ", wantErr: ErrEmptyAtomicID},
+		{name: "bad CVE", input: "v2e::nvd::cve::CVE-24-1", wantErr: ErrInvalidAtomicID, errText: "expected CVE-YYYY-NNNN"},
+		{name: "bad CWE", input: "v2e::mitre::cwe::CWE-XSS", wantErr: ErrInvalidAtomicID},
+		{name: "zero CWE", input: "v2e::mitre::cwe::CWE-0", wantErr: ErrInvalidAtomicID},
+		{name: "bad ATT&CK", input: "v2e::mitre::attack::X1059", wantErr: ErrInvalidAtomicID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Normalize(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				if tt.errText != "" && !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Normalize(%q) error = %q, want it to mention %q", tt.input, err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q) unexpected error: %v", tt.input, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Normalize(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+			// The canonical form is stable and accepted by Parse
+			again, err := Normalize(got.String())
+			if err != nil || !again.Equal(got) {
+				t.Errorf("Normalize(%s) = %v, %v; want it unchanged", got, again, err)
+			}
+			if _, err := Parse(got.String()); err != nil {
+				t.Errorf("Parse(%s) failed: %v", got, err)
+			}
+		})
+	}
+}
@@ -27,6 +27,8 @@ const (
 	ProviderMITRE Provider = "mitre"
 	// ProviderSSG represents SCAP Security Guide
 	ProviderSSG Provider = "ssg"
+	// ProviderOWASP represents OWASP data sources
+	ProviderOWASP Provider = "owasp"
 )
 
 // ResourceType represents the type of resource
@@ -43,6 +45,8 @@ const (
 	TypeATTACK ResourceType = "attack"
 	// TypeSSG represents SSG guide data
 	TypeSSG ResourceType = "ssg"
+	// TypeASVS represents OWASP ASVS verification requirements
+	TypeASVS ResourceType = "asvs"
 )
 
 // URN represents a hierarchical atomic identifier in the format:
@@ -54,6 +58,7 @@ const (
 //   - v2e::mitre::capec::CAPEC-66
 //   - v2e::mitre::attack::T1566
 //   - v2e::ssg::ssg::rhel9-guide-ospp
+//   - v2e::owasp::asvs::V1.2.1
 type URN struct {
 	Provider Provider
 	Type     ResourceType
@@ -140,7 +145,7 @@ func (u *URN) Equal(other *URN) bool {
 // isValidProvider checks if a provider is supported
 func isValidProvider(p Provider) bool {
 	switch p {
-	case ProviderNVD, ProviderMITRE, ProviderSSG:
+	case ProviderNVD, ProviderMITRE, ProviderSSG, ProviderOWASP:
 		return true
 	default:
 		return false
@@ -150,7 +155,7 @@ func isValidProvider(p Provider) bool {
 // isValidResourceType checks if a resource type is supported
 func isValidResourceType(t ResourceType) bool {
 	switch t {
-	case TypeCVE, TypeCWE, TypeCAPEC, TypeATTACK, TypeSSG:
+	case TypeCVE, TypeCWE, TypeCAPEC, TypeATTACK, TypeSSG, TypeASVS:
 		return true
 	default:
 		return false
@@ -62,6 +62,16 @@ func TestParse(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:  "valid ASVS URN",
+			input: "v2e::owasp::asvs::V1.2.1",
+			want: &URN{
+				Provider: ProviderOWASP,
+				Type:     TypeASVS,
+				AtomicID: "V1.2.1",
+			},
+			wantErr: nil,
+		},
 		{
 			name:    "invalid format - too few parts",
 			input:   "v2e::nvd::cve",
@@ -349,6 +359,7 @@ func TestRoundTrip(t *testing.T) {
 		"v2e::mitre::capec::CAPEC-66",
 		"v2e::mitre::attack::T1566",
 		"v2e::ssg::ssg::rhel9-guide-ospp",
+		"v2e::owasp::asvs::V1.2.1",
 	}
 
 	for _, tt := range tests {
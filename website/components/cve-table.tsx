@@ -64,8 +64,10 @@ const getSeverityColorHex = (score?: number): string => {
   return '#10b981'; // green-500 (low)
 };
 
-// Prefer newer metrics (4.0 -> 3.1 -> 3.0 -> 2.0) and return the highest baseScore available
+// Use the score the local service derived at save time; otherwise prefer newer
+// metrics (4.0 -> 3.1 -> 3.0 -> 2.0) and return the highest baseScore available
 const getCVSSScore = (cve: CVEItem): number | undefined => {
+  if (cve.baseSeverity) return cve.baseScore ?? 0;
   const metricLists = [
     cve.metrics?.cvssMetricV40,
     cve.metrics?.cvssMetricV31,
@@ -89,6 +91,13 @@ const getCVSSScore = (cve: CVEItem): number | undefined => {
   return maxScore;
 };
 
+// Rejected/disputed CVEs get a badge and a muted row so they are not mistaken for normal entries
+const getStatusBadge = (status?: CVEItem['status']): { label: string; variant: "destructive" | "outline" } | null => {
+  if (status === 'rejected') return { label: 'Rejected', variant: 'destructive' };
+  if (status === 'disputed') return { label: 'Disputed', variant: 'outline' };
+  return null;
+};
+
 const getDescription = (cve: CVEItem): string => {
   const desc = cve.descriptions?.find(d => d.lang === 'en') || cve.descriptions?.[0];
   if (!desc) return 'No description available';
@@ -142,6 +151,7 @@ export const CVETable = memo(function CVETable({
       lastModified: cve.lastModified ? new Date(cve.lastModified).toLocaleDateString() : undefined,
       source: cve.sourceIdentifier || (cve as any).source || 'Unknown',
       status: cve.vulnStatus || 'Unknown',
+      statusBadge: getStatusBadge(cve.status),
       references: cve.references || [],
       weaknesses: cve.weaknesses || [],
       raw: cve,
@@ -235,9 +245,9 @@ const handleCopy = async (text: string, key: string) => {
                     width: '100%',
                     borderLeft: `4px solid ${borderColor}`,
                   }}
-                  className="grid grid-cols-[140px_120px_1fr_120px_160px_120px_100px] gap-x-4 items-center px-4 py-3 border-b bg-background z-10 hover:bg-muted/30"
+                  className={`grid grid-cols-[140px_120px_1fr_120px_160px_120px_100px] gap-x-4 items-center px-4 py-3 border-b bg-background z-10 hover:bg-muted/30${row.statusBadge ? ' opacity-60' : ''}`}
                 >
-                  <div className="font-mono font-medium">{row.id}</div>
+                  <div className={`font-mono font-medium${row.statusBadge?.label === 'Rejected' ? ' line-through' : ''}`}>{row.id}</div>
                   <div>
                     <div className="flex flex-col gap-1">
                       <Badge variant={getSeverityVariant(row.score)}>{getSeverityLabel(row.score)}</Badge>
@@ -252,7 +262,11 @@ const handleCopy = async (text: string, key: string) => {
                   <div className="text-sm text-muted-foreground">{row.published}</div>
                   <div className="text-sm">{row.source}</div>
                   <div className="text-sm">
-                    <span className="inline-block align-middle">{row.status}</span>
+                    {row.statusBadge ? (
+                      <Badge variant={row.statusBadge.variant}>{row.statusBadge.label}</Badge>
+                    ) : (
+                      <span className="inline-block align-middle">{row.status}</span>
+                    )}
                   </div>
                   <div>
                     <Button
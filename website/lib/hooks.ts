@@ -708,7 +708,7 @@ export function useSysMetrics() {
 }
 
 // CVE Hooks
-export function useCVEList(offset: number = 0, limit: number = 100) {
+export function useCVEList(offset: number = 0, limit: number = 100, includeRejected: boolean = false) {
   const [data, setData] = useState<any>(null);
   const [isLoading, setIsLoading] = useState<boolean>(true);
   const [error, setError] = useState<Error | null>(null);
@@ -717,7 +717,7 @@ export function useCVEList(offset: number = 0, limit: number = 100) {
     const fetchData = async () => {
       try {
         setIsLoading(true);
-        const response = await rpcClient.listCVEs(offset, limit);
+        const response = await rpcClient.listCVEs(offset, limit, includeRejected);
         
         if (response.retcode !== 0) {
           throw new Error(response.message || 'Failed to fetch CVE list');
@@ -737,7 +737,7 @@ export function useCVEList(offset: number = 0, limit: number = 100) {
 
     // Cleanup function
     return () => {};
-  }, [offset, limit]);
+  }, [offset, limit, includeRejected]);
 
   // Derive derived state to prevent unnecessary re-renders
   const derivedState = useMemo(() => ({
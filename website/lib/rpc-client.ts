@@ -1112,11 +1112,13 @@ export class RPCClient {
 
   async listCVEs(
     offset?: number,
-    limit?: number
+    limit?: number,
+    includeRejected?: boolean
   ): Promise<RPCResponse<ListCVEsResponse>> {
     return this.call<ListCVEsRequest, ListCVEsResponse>('RPCListCVEs', {
       offset,
       limit,
+      includeRejected,
     });
   }
 
@@ -175,8 +175,19 @@ export interface CVEItem {
   configurations?: Config[];
   references?: Reference[];
   vendorComments?: VendorComment[];
+  // Derived locally from vulnStatus/cveTags by the local service
+  status?: CVEStatus;
+  // Derived locally from the preferred CVSS metric (v3.1, v3.0, v4.0, v2) by
+  // the local service; absent when the CVE has no metrics
+  cvssVersion?: string;
+  baseScore?: number;
+  baseSeverity?: string;
+  attackVector?: string;
+  exploitabilityScore?: number;
 }
 
+export type CVEStatus = 'active' | 'rejected' | 'disputed';
+
 export interface CVEResponse {
   resultsPerPage: number;
   startIndex: number;
@@ -197,6 +208,8 @@ export interface RPCRequest<T = unknown> {
   method: string;
   params?: T;
   target?: string;
+  // Seconds the backend may take (0 or unset: the access RPC timeout; max 600)
+  timeout?: number;
 }
 
 export interface RPCResponse<T = unknown> {
@@ -250,6 +263,7 @@ export interface DeleteCVEResponse {
 export interface ListCVEsRequest {
   offset?: number;
   limit?: number;
+  includeRejected?: boolean;
 }
 
 export interface ListCVEsResponse {